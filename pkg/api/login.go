@@ -214,6 +214,21 @@ func (hs *HTTPServer) LoginPost(c *contextmodel.ReqContext) response.Response {
 	return authn.HandleLoginResponse(c.Req, c.Resp, hs.Cfg, identity, hs.ValidateRedirectTo)
 }
 
+// LoginMFAPost completes a login that requires a second authentication factor.
+func (hs *HTTPServer) LoginMFAPost(c *contextmodel.ReqContext) response.Response {
+	identity, err := hs.authnService.Login(c.Req.Context(), authn.ClientMFA, &authn.Request{HTTPRequest: c.Req})
+	if err != nil {
+		tokenErr := &auth.CreateTokenErr{}
+		if errors.As(err, &tokenErr) {
+			return response.Error(tokenErr.StatusCode, tokenErr.ExternalErr, tokenErr.InternalErr)
+		}
+		return response.Err(err)
+	}
+
+	metrics.MApiLoginPost.Inc()
+	return authn.HandleLoginResponse(c.Req, c.Resp, hs.Cfg, identity, hs.ValidateRedirectTo)
+}
+
 func (hs *HTTPServer) loginUserWithUser(user *user.User, c *contextmodel.ReqContext) error {
 	if user == nil {
 		return errors.New("could not login user")
@@ -239,6 +254,24 @@ func (hs *HTTPServer) loginUserWithUser(user *user.User, c *contextmodel.ReqCont
 	return nil
 }
 
+// loginNewUser logs in a user that just signed up or completed an invite.
+// Users that must complete a second factor aren't logged in, they log in with
+// their password to get a challenge and enroll a factor. It reports whether
+// the user was logged in.
+func (hs *HTTPServer) loginNewUser(usr *user.User, c *contextmodel.ReqContext) (bool, error) {
+	if hs.Cfg.AuthMFA.Enabled {
+		required, err := hs.mfaService.IsRequired(c.Req.Context(), usr.ID)
+		if err != nil {
+			return false, err
+		}
+		if required {
+			hs.log.Info("User must complete a second factor to log in", "userId", usr.ID)
+			return false, nil
+		}
+	}
+	return true, hs.loginUserWithUser(usr, c)
+}
+
 func (hs *HTTPServer) Logout(c *contextmodel.ReqContext) {
 	// FIXME: restructure saml client to implement authn.LogoutClient
 	if hs.samlSingleLogoutEnabled() {
@@ -33,12 +33,14 @@ import (
 	"github.com/grafana/grafana/pkg/services/licensing/licensingtest"
 	loginservice "github.com/grafana/grafana/pkg/services/login"
 	"github.com/grafana/grafana/pkg/services/login/authinfotest"
+	"github.com/grafana/grafana/pkg/services/mfa/mfatest"
 	"github.com/grafana/grafana/pkg/services/navtree"
 	"github.com/grafana/grafana/pkg/services/secrets"
 	"github.com/grafana/grafana/pkg/services/secrets/fakes"
 	secretsManager "github.com/grafana/grafana/pkg/services/secrets/manager"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
 )
 
 const loginCookieName = "grafana_session"
@@ -651,6 +653,45 @@ func setupAuthProxyLoginTest(t *testing.T, enableLoginToken bool) *scenarioConte
 	return sc
 }
 
+func TestLoginNewUser(t *testing.T) {
+	loginNewUser := func(t *testing.T, mfaEnabled, mfaRequired bool) (bool, *httptest.ResponseRecorder) {
+		t.Helper()
+		cfg := setting.NewCfg()
+		cfg.AuthMFA.Enabled = mfaEnabled
+		hs := &HTTPServer{
+			Cfg:              cfg,
+			AuthTokenService: authtest.NewFakeUserAuthTokenService(),
+			log:              log.New("test"),
+			mfaService:       &mfatest.FakeService{ExpectedRequired: mfaRequired},
+		}
+		recorder := httptest.NewRecorder()
+		c := &contextmodel.ReqContext{Context: &web.Context{
+			Req:  httptest.NewRequest(http.MethodPost, "/api/user/invite/complete", nil),
+			Resp: web.NewResponseWriter(http.MethodPost, recorder),
+		}}
+		loggedIn, err := hs.loginNewUser(&user.User{ID: 1}, c)
+		require.NoError(t, err)
+		return loggedIn, recorder
+	}
+
+	t.Run("users are logged in", func(t *testing.T) {
+		loggedIn, recorder := loginNewUser(t, true, false)
+		assert.True(t, loggedIn)
+		assert.NotEmpty(t, recorder.Header().Get("Set-Cookie"))
+	})
+
+	t.Run("users that must complete a second factor are not logged in", func(t *testing.T) {
+		loggedIn, recorder := loginNewUser(t, true, true)
+		assert.False(t, loggedIn)
+		assert.Empty(t, recorder.Header().Get("Set-Cookie"))
+	})
+
+	t.Run("second factors are not required when multi-factor authentication is disabled", func(t *testing.T) {
+		loggedIn, _ := loginNewUser(t, false, true)
+		assert.True(t, loggedIn)
+	})
+}
+
 func TestLogoutSaml(t *testing.T) {
 	fakeSetIndexViewData(t)
 	fakeViewIndex(t)
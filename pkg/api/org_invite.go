@@ -297,7 +297,7 @@ func (hs *HTTPServer) CompleteInvite(c *contextmodel.ReqContext) response.Respon
 		return rsp
 	}
 
-	err = hs.loginUserWithUser(usr, c)
+	loggedIn, err := hs.loginNewUser(usr, c)
 	if err != nil {
 		return response.Error(http.StatusInternalServerError, "failed to accept invite", err)
 	}
@@ -305,8 +305,12 @@ func (hs *HTTPServer) CompleteInvite(c *contextmodel.ReqContext) response.Respon
 	metrics.MApiUserSignUpCompleted.Inc()
 	metrics.MApiUserSignUpInvite.Inc()
 
+	message := "User created and logged in"
+	if !loggedIn {
+		message = "User created, log in to set up multi-factor authentication"
+	}
 	return response.JSON(http.StatusOK, util.DynMap{
-		"message": "User created and logged in",
+		"message": message,
 		"id":      usr.ID,
 	})
 }
@@ -141,8 +141,7 @@ func (hs *HTTPServer) SignUpStep2(c *contextmodel.ReqContext) response.Response
 		apiResponse["code"] = "redirect-to-select-org"
 	}
 
-	err = hs.loginUserWithUser(usr, c)
-	if err != nil {
+	if _, err := hs.loginNewUser(usr, c); err != nil {
 		return response.Error(http.StatusInternalServerError, "failed to login user", err)
 	}
 
@@ -0,0 +1,106 @@
+package alertingmigrations
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/services/ngalert/api"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/prom"
+)
+
+// convertOptions are the options of the conversion of a Prometheus rule file to a provisioning file.
+type convertOptions struct {
+	OrgID         int64
+	FolderTitle   string
+	DatasourceUID string
+	RecordTarget  models.RecordTarget
+}
+
+// ConvertPrometheusRules converts the Prometheus or Mimir rule file given as the first argument to a file that
+// provisions the rules as Grafana-managed rules. The file is written to the output path or to stdout.
+func ConvertPrometheusRules(c utils.CommandLine) error {
+	path := c.Args().First()
+	if path == "" {
+		return errors.New("missing path to the Prometheus rule file")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read rule file: %w", err)
+	}
+	orgID := int64(c.Int("org-id"))
+	if orgID == 0 {
+		orgID = 1
+	}
+	out, err := convertPrometheusRules(content, convertOptions{
+		OrgID:         orgID,
+		FolderTitle:   c.String("folder"),
+		DatasourceUID: c.String("datasource-uid"),
+		RecordTarget:  models.RecordTarget(c.String("record-target")),
+	})
+	if err != nil {
+		return err
+	}
+
+	output := c.String("output")
+	if output == "" {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	if err := os.WriteFile(output, out, 0600); err != nil {
+		return fmt.Errorf("failed to write provisioning file: %w", err)
+	}
+	logger.Infof("Rules are written to %s\n", output)
+	return nil
+}
+
+func convertPrometheusRules(content []byte, opts convertOptions) ([]byte, error) {
+	if opts.FolderTitle == "" {
+		return nil, errors.New("folder is required")
+	}
+	groups, err := prom.ParseRuleGroups(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rule file: %w", err)
+	}
+	converter, err := prom.NewConverter(prom.Config{DatasourceUID: opts.DatasourceUID, RecordTarget: opts.RecordTarget})
+	if err != nil {
+		return nil, err
+	}
+	// The folder is created by its title when the file is provisioned, so the rules do not know its UID yet.
+	converted, err := converter.Convert(opts.OrgID, "", groups)
+	if err != nil {
+		return nil, err
+	}
+
+	withFolder := make([]models.AlertRuleGroupWithFolderFullpath, 0, len(converted))
+	for _, group := range converted {
+		for i := range group.Rules {
+			group.Rules[i].UID = ruleUID(opts.FolderTitle, group.Title, group.Rules[i].Title)
+		}
+		key := models.AlertRuleGroupKey{OrgID: opts.OrgID, RuleGroup: group.Title}
+		withFolder = append(withFolder, models.NewAlertRuleGroupWithFolderFullpath(key, group.Rules, opts.FolderTitle))
+	}
+	export, err := api.AlertingFileExportFromAlertRuleGroupWithFolderFullpath(withFolder)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(export)
+}
+
+// ruleUID returns the UID of a rule. File provisioning identifies rules by UID, so the UID is derived from the
+// folder, the group and the title of the rule, and converting the same file again updates the same rules.
+func ruleUID(folder, group, title string) string {
+	sum := fnv.New64()
+	for _, s := range []string{folder, group, title} {
+		_, _ = sum.Write([]byte(s))
+		// Separate the values, so that moving characters from one to another changes the hash.
+		_, _ = sum.Write([]byte{0})
+	}
+	return fmt.Sprintf("prom-%016x", sum.Sum64())
+}
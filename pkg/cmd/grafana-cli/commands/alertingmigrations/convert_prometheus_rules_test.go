@@ -0,0 +1,58 @@
+package alertingmigrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+const testRules = `
+groups:
+  - name: example
+    rules:
+      - alert: HighErrorRate
+        expr: sum(rate(errors_total[5m])) by (job) > 10
+        for: 5m
+      - record: job:errors:rate5m
+        expr: sum(rate(errors_total[5m])) by (job)
+`
+
+func TestConvertPrometheusRules(t *testing.T) {
+	opts := convertOptions{OrgID: 1, FolderTitle: "Prometheus", DatasourceUID: "prom-uid", RecordTarget: models.RecordTargetPrometheus}
+	out, err := convertPrometheusRules([]byte(testRules), opts)
+	require.NoError(t, err)
+
+	var export definitions.AlertingFileExport
+	require.NoError(t, yaml.Unmarshal(out, &export))
+	require.Equal(t, int64(1), export.APIVersion)
+	require.Len(t, export.Groups, 1)
+	group := export.Groups[0]
+	require.Equal(t, "example", group.Name)
+	require.Equal(t, "Prometheus", group.Folder)
+	require.Len(t, group.Rules, 2)
+	require.Equal(t, "HighErrorRate", group.Rules[0].Title)
+	require.Equal(t, "B", group.Rules[0].Condition)
+	require.Equal(t, "job:errors:rate5m", group.Rules[1].Title)
+	require.NotNil(t, group.Rules[1].Record)
+
+	t.Run("UIDs are the same when the file is converted again", func(t *testing.T) {
+		again, err := convertPrometheusRules([]byte(testRules), opts)
+		require.NoError(t, err)
+		require.Equal(t, string(out), string(again))
+		require.NotEqual(t, group.Rules[0].UID, group.Rules[1].UID)
+	})
+
+	t.Run("UIDs depend on the folder", func(t *testing.T) {
+		require.NotEqual(t, ruleUID("Prometheus", "example", "rule"), ruleUID("Other", "example", "rule"))
+		require.NotEqual(t, ruleUID("ab", "c", "rule"), ruleUID("a", "bc", "rule"))
+	})
+
+	t.Run("folder is required", func(t *testing.T) {
+		_, err := convertPrometheusRules([]byte(testRules), convertOptions{OrgID: 1, DatasourceUID: "prom-uid"})
+		require.Error(t, err)
+	})
+}
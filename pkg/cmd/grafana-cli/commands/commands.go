@@ -6,6 +6,7 @@ import (
 
 	"github.com/urfave/cli/v2"
 
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/commands/alertingmigrations"
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/commands/datamigrations"
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/commands/secretsmigrations"
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
@@ -167,6 +168,43 @@ var adminCommands = []*cli.Command{
 			},
 		},
 	},
+	{
+		Name:  "alerting",
+		Usage: "Runs commands that migrate alerting resources",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "convert-prometheus-rules",
+				Usage:     "Converts a Prometheus or Mimir rule file to a provisioning file of Grafana-managed rules. Safe to execute multiple times, the rules get the same UIDs.",
+				ArgsUsage: "<rule file>",
+				Action:    runPluginCommand(alertingmigrations.ConvertPrometheusRules),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "datasource-uid",
+						Usage:    "UID of the Prometheus data source that the queries of the rules read from",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "folder",
+						Usage:    "Title of the folder of the rules",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "record-target",
+						Usage: "Target that recording rules write to: prometheus, influxdb, otlp or sql. The default target of the instance is used if empty",
+					},
+					&cli.IntFlag{
+						Name:  "org-id",
+						Usage: "ID of the organization of the rules",
+						Value: 1,
+					},
+					&cli.StringFlag{
+						Name:  "output",
+						Usage: "Path of the provisioning file. The file is written to stdout if empty",
+					},
+				},
+			},
+		},
+	},
 	{
 		Name:  "user-manager",
 		Usage: "Runs different helpful user commands",
@@ -0,0 +1,135 @@
+package expr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+)
+
+const (
+	// AnomalyLabel is added to every series produced by the anomaly command to tell the score and the bands apart.
+	AnomalyLabel = "anomaly"
+
+	AnomalyScore = "score"
+	AnomalyUpper = "upper"
+	AnomalyLower = "lower"
+)
+
+// AnomalyCommand is an expression command that detects anomalies in time series locally, without the Machine Learning plugin.
+// For every input series it returns three series: the anomaly score, and the upper and lower bands of normal values.
+// The series are distinguished by the label AnomalyLabel.
+type AnomalyCommand struct {
+	VarToAnalyze string
+	Options      mathexp.AnomalyOptions
+	refID        string
+}
+
+// NewAnomalyCommand creates a new AnomalyCommand.
+func NewAnomalyCommand(refID, varToAnalyze string, opts mathexp.AnomalyOptions) (*AnomalyCommand, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid anomaly detection settings: %w", err)
+	}
+	return &AnomalyCommand{
+		VarToAnalyze: varToAnalyze,
+		Options:      opts,
+		refID:        refID,
+	}, nil
+}
+
+// UnmarshalAnomalyCommand creates an AnomalyCommand from Grafana's frontend query.
+func UnmarshalAnomalyCommand(rn *rawNode) (*AnomalyCommand, error) {
+	q := AnomalyQuery{}
+	if err := json.Unmarshal(rn.QueryRaw, &q); err != nil {
+		return nil, fmt.Errorf("failed to parse the anomaly command: %w", err)
+	}
+	referenceVar, err := getReferenceVar(q.Expression, rn.RefID)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := q.options()
+	if err != nil {
+		return nil, err
+	}
+	return NewAnomalyCommand(rn.RefID, referenceVar, opts)
+}
+
+func (q AnomalyQuery) options() (mathexp.AnomalyOptions, error) {
+	opts := mathexp.AnomalyOptions{
+		Algorithm:   q.Algorithm,
+		Sensitivity: q.Sensitivity,
+		Alpha:       q.Alpha,
+		Beta:        q.Beta,
+		Gamma:       q.Gamma,
+	}
+	parse := func(name, raw string) (time.Duration, error) {
+		if raw == "" {
+			return 0, nil
+		}
+		d, err := gtime.ParseDuration(raw)
+		if err != nil {
+			return 0, fmt.Errorf(`failed to parse anomaly %q duration field %q: %w`, name, raw, err)
+		}
+		return d, nil
+	}
+	var err error
+	if opts.Window, err = parse("window", q.Window); err != nil {
+		return opts, err
+	}
+	if opts.Season, err = parse("season", q.Season); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// NeedsVars returns the variable names (refIds) that are dependencies
+// to execute the command and allows the command to fulfill the Command interface.
+func (ac *AnomalyCommand) NeedsVars() []string {
+	return []string{ac.VarToAnalyze}
+}
+
+// Execute runs the command and returns the results or an error if the command
+// failed to execute.
+func (ac *AnomalyCommand) Execute(ctx context.Context, _ time.Time, vars mathexp.Vars, tracer tracing.Tracer) (mathexp.Results, error) {
+	_, span := tracer.Start(ctx, "SSE.ExecuteAnomaly")
+	span.SetAttributes(attribute.String("algorithm", string(ac.Options.Algorithm)))
+	defer span.End()
+
+	newRes := mathexp.Results{}
+	for _, val := range vars[ac.VarToAnalyze].Values {
+		switch v := val.(type) {
+		case mathexp.Series:
+			res, err := v.DetectAnomalies(ac.refID, ac.Options)
+			if err != nil {
+				return newRes, err
+			}
+			res.Score.SetLabels(anomalyLabels(v.GetLabels(), AnomalyScore))
+			res.Upper.SetLabels(anomalyLabels(v.GetLabels(), AnomalyUpper))
+			res.Lower.SetLabels(anomalyLabels(v.GetLabels(), AnomalyLower))
+			newRes.Values = append(newRes.Values, res.Score, res.Upper, res.Lower)
+		case mathexp.NoData:
+			newRes.Values = append(newRes.Values, v.New())
+			return newRes, nil
+		default:
+			return newRes, fmt.Errorf("can only detect anomalies in type series, got type %v", val.Type())
+		}
+	}
+	return newRes, nil
+}
+
+func anomalyLabels(lbls data.Labels, kind string) data.Labels {
+	result := lbls.Copy()
+	result[AnomalyLabel] = kind
+	return result
+}
+
+func (ac *AnomalyCommand) Type() string {
+	return TypeAnomaly.String()
+}
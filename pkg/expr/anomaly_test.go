@@ -0,0 +1,99 @@
+package expr
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func TestUnmarshalAnomalyCommand(t *testing.T) {
+	unmarshal := func(query string) (*AnomalyCommand, error) {
+		var qmap = make(map[string]any)
+		require.NoError(t, json.Unmarshal([]byte(query), &qmap))
+		return UnmarshalAnomalyCommand(&rawNode{
+			RefID:    "B",
+			Query:    qmap,
+			QueryRaw: []byte(query),
+		})
+	}
+
+	t.Run("should parse settings", func(t *testing.T) {
+		cmd, err := unmarshal(`{"expression": "$A", "type": "anomaly", "algorithm": "holt_winters", "season": "1d", "sensitivity": 2, "alpha": 0.3}`)
+		require.NoError(t, err)
+		require.Equal(t, []string{"A"}, cmd.NeedsVars())
+		require.Equal(t, mathexp.AnomalyOptions{
+			Algorithm:   mathexp.AnomalyAlgorithmHoltWinters,
+			Sensitivity: 2,
+			Season:      24 * time.Hour,
+			Alpha:       0.3,
+			Beta:        0.1,
+			Gamma:       0.3,
+		}, cmd.Options)
+		require.Equal(t, "anomaly", cmd.Type())
+	})
+
+	t.Run("should fail if algorithm is not supported", func(t *testing.T) {
+		_, err := unmarshal(`{"expression": "$A", "type": "anomaly", "algorithm": "magic"}`)
+		require.ErrorContains(t, err, "unsupported anomaly detection algorithm")
+	})
+
+	t.Run("should fail if window is invalid", func(t *testing.T) {
+		_, err := unmarshal(`{"expression": "$A", "type": "anomaly", "algorithm": "mad", "window": "one hour"}`)
+		require.ErrorContains(t, err, "window")
+	})
+
+	t.Run("should fail without expression", func(t *testing.T) {
+		_, err := unmarshal(`{"type": "anomaly", "algorithm": "mad"}`)
+		require.ErrorContains(t, err, "no variable specified")
+	})
+}
+
+func TestAnomalyCommandExecute(t *testing.T) {
+	tracer := tracing.InitializeTracerForTest()
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	cmd, err := NewAnomalyCommand("B", "A", mathexp.AnomalyOptions{Algorithm: mathexp.AnomalyAlgorithmZScore, Sensitivity: 1})
+	require.NoError(t, err)
+
+	t.Run("should return score and bands for every series", func(t *testing.T) {
+		s := mathexp.NewSeries("A", data.Labels{"host": "a"}, 4)
+		for i, v := range []float64{1, 3, 1, 3} {
+			s.SetPoint(i, start.Add(time.Duration(i)*time.Minute), util.Pointer(v))
+		}
+		res, err := cmd.Execute(context.Background(), start, mathexp.Vars{"A": mathexp.Results{Values: mathexp.Values{s}}}, tracer)
+		require.NoError(t, err)
+		require.Len(t, res.Values, 3)
+
+		byKind := make(map[string]mathexp.Series)
+		for _, v := range res.Values {
+			series, ok := v.(mathexp.Series)
+			require.True(t, ok)
+			require.Equal(t, "a", series.GetLabels()["host"])
+			byKind[series.GetLabels()[AnomalyLabel]] = series
+		}
+		require.Equal(t, util.Pointer(1.0), byKind[AnomalyScore].GetValue(0))
+		require.Equal(t, util.Pointer(3.0), byKind[AnomalyUpper].GetValue(0))
+		require.Equal(t, util.Pointer(1.0), byKind[AnomalyLower].GetValue(0))
+		// The input series must not be modified.
+		require.Equal(t, data.Labels{"host": "a"}, s.GetLabels())
+	})
+
+	t.Run("should return NoData for NoData", func(t *testing.T) {
+		res, err := cmd.Execute(context.Background(), start, mathexp.Vars{"A": mathexp.Results{Values: mathexp.Values{mathexp.NewNoData()}}}, tracer)
+		require.NoError(t, err)
+		require.True(t, res.IsNoData())
+	})
+
+	t.Run("should fail for numbers", func(t *testing.T) {
+		_, err := cmd.Execute(context.Background(), start, mathexp.Vars{"A": mathexp.Results{Values: mathexp.Values{mathexp.NewNumber("A", nil)}}}, tracer)
+		require.ErrorContains(t, err, "can only detect anomalies in type series")
+	})
+}
@@ -214,14 +214,16 @@ func (gr *ReduceCommand) Type() string {
 type ResampleCommand struct {
 	Window        time.Duration
 	VarToResample string
-	Downsampler   mathexp.ReducerID
+	Downsampler   mathexp.Downsampler
 	Upsampler     mathexp.Upsampler
+	Alignment     mathexp.ResampleAlignment
+	MaxStaleness  time.Duration
 	TimeRange     TimeRange
 	refID         string
 }
 
 // NewResampleCommand creates a new ResampleCMD.
-func NewResampleCommand(refID, rawWindow, varToResample string, downsampler mathexp.ReducerID, upsampler mathexp.Upsampler, tr TimeRange) (*ResampleCommand, error) {
+func NewResampleCommand(refID, rawWindow, varToResample string, downsampler mathexp.Downsampler, upsampler mathexp.Upsampler, tr TimeRange) (*ResampleCommand, error) {
 	// TODO: validate reducer here, before execution
 	window, err := gtime.ParseDuration(rawWindow)
 	if err != nil {
@@ -280,11 +282,52 @@ func UnmarshalResampleCommand(rn *rawNode) (*ResampleCommand, error) {
 		return nil, fmt.Errorf("expected resample downsampler to be a string, got type %T", upsampler)
 	}
 
-	return NewResampleCommand(rn.RefID, window,
+	cmd, err := NewResampleCommand(rn.RefID, window,
 		varToResample,
-		mathexp.ReducerID(downsampler),
+		mathexp.Downsampler(downsampler),
 		mathexp.Upsampler(upsampler),
 		rn.TimeRange)
+	if err != nil {
+		return nil, err
+	}
+
+	var alignment, maxStaleness string
+	if rawAlignment, ok := rn.Query["alignment"]; ok {
+		if alignment, ok = rawAlignment.(string); !ok {
+			return nil, fmt.Errorf("expected resample alignment to be a string, got type %T", rawAlignment)
+		}
+	}
+	if rawMaxStaleness, ok := rn.Query["maxStaleness"]; ok {
+		if maxStaleness, ok = rawMaxStaleness.(string); !ok {
+			return nil, fmt.Errorf("expected resample max staleness to be a string, got type %T", rawMaxStaleness)
+		}
+	}
+	if err := cmd.setAlignment(mathexp.ResampleAlignment(alignment), maxStaleness); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// setAlignment sets the alignment of the resampled points and the max staleness of the upsampler.
+func (gr *ResampleCommand) setAlignment(alignment mathexp.ResampleAlignment, rawMaxStaleness string) error {
+	switch alignment {
+	case "", mathexp.ResampleAlignStart, mathexp.ResampleAlignClock, mathexp.ResampleAlignEnd:
+		gr.Alignment = alignment
+	default:
+		return fmt.Errorf("resample alignment %q is not supported", alignment)
+	}
+	if rawMaxStaleness == "" {
+		return nil
+	}
+	maxStaleness, err := gtime.ParseDuration(rawMaxStaleness)
+	if err != nil {
+		return fmt.Errorf(`failed to parse resample "maxStaleness" duration field %q: %w`, rawMaxStaleness, err)
+	}
+	if maxStaleness < 0 {
+		return fmt.Errorf(`resample "maxStaleness" must not be negative, got %q`, rawMaxStaleness)
+	}
+	gr.MaxStaleness = maxStaleness
+	return nil
 }
 
 // NeedsVars returns the variable names (refIds) that are dependencies
@@ -306,7 +349,13 @@ func (gr *ResampleCommand) Execute(ctx context.Context, now time.Time, vars math
 		}
 		switch v := val.(type) {
 		case mathexp.Series:
-			num, err := v.Resample(gr.refID, gr.Window, gr.Downsampler, gr.Upsampler, timeRange.From, timeRange.To)
+			num, err := v.ResampleWithOptions(gr.refID, mathexp.ResampleOptions{
+				Window:       gr.Window,
+				Downsampler:  gr.Downsampler,
+				Upsampler:    gr.Upsampler,
+				Alignment:    gr.Alignment,
+				MaxStaleness: gr.MaxStaleness,
+			}, timeRange.From, timeRange.To)
 			if err != nil {
 				return newRes, err
 			}
@@ -343,6 +392,8 @@ const (
 	TypeThreshold
 	// TypeSQL is the CMDType for running SQL expressions
 	TypeSQL
+	// TypeAnomaly is the CMDType for local anomaly detection
+	TypeAnomaly
 )
 
 func (gt CommandType) String() string {
@@ -359,6 +410,8 @@ func (gt CommandType) String() string {
 		return "threshold"
 	case TypeSQL:
 		return "sql"
+	case TypeAnomaly:
+		return "anomaly"
 	default:
 		return "unknown"
 	}
@@ -379,6 +432,8 @@ func ParseCommandType(s string) (CommandType, error) {
 		return TypeThreshold, nil
 	case "sql":
 		return TypeSQL, nil
+	case "anomaly":
+		return TypeAnomaly, nil
 	default:
 		return TypeUnknown, fmt.Errorf("'%v' is not a recognized expression type", s)
 	}
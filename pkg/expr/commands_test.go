@@ -273,3 +273,42 @@ func TestResampleCommand_Execute(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func Test_UnmarshalResampleCommand_Alignment(t *testing.T) {
+	unmarshal := func(extra string) (*ResampleCommand, error) {
+		query := fmt.Sprintf(`{"expression": "$A", "type": "resample", "window": "1m", "downsampler": "rate", "upsampler": "linear"%s}`, extra)
+		var qmap = make(map[string]any)
+		require.NoError(t, json.Unmarshal([]byte(query), &qmap))
+		return UnmarshalResampleCommand(&rawNode{
+			RefID:     "B",
+			Query:     qmap,
+			QueryRaw:  []byte(query),
+			TimeRange: RelativeTimeRange{From: -10 * time.Minute},
+		})
+	}
+
+	t.Run("should default to the start of the time range", func(t *testing.T) {
+		cmd, err := unmarshal(``)
+		require.NoError(t, err)
+		require.Equal(t, mathexp.ResampleAlignment(""), cmd.Alignment)
+		require.Zero(t, cmd.MaxStaleness)
+		require.Equal(t, mathexp.DownsamplerRate, cmd.Downsampler)
+	})
+
+	t.Run("should parse alignment and max staleness", func(t *testing.T) {
+		cmd, err := unmarshal(`, "alignment": "clock", "maxStaleness": "5m"`)
+		require.NoError(t, err)
+		require.Equal(t, mathexp.ResampleAlignClock, cmd.Alignment)
+		require.Equal(t, 5*time.Minute, cmd.MaxStaleness)
+	})
+
+	t.Run("should fail for unknown alignment", func(t *testing.T) {
+		_, err := unmarshal(`, "alignment": "noon"`)
+		require.ErrorContains(t, err, `resample alignment "noon" is not supported`)
+	})
+
+	t.Run("should fail for invalid max staleness", func(t *testing.T) {
+		_, err := unmarshal(`, "maxStaleness": "soon"`)
+		require.ErrorContains(t, err, "maxStaleness")
+	})
+}
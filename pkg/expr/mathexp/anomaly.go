@@ -0,0 +1,546 @@
+package mathexp
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// The anomaly detection algorithm
+// +enum
+type AnomalyAlgorithm string
+
+const (
+	// Median absolute deviation from the median
+	AnomalyAlgorithmMAD AnomalyAlgorithm = "mad"
+
+	// Standard deviation from the mean
+	AnomalyAlgorithmZScore AnomalyAlgorithm = "zscore"
+
+	// Additive Holt-Winters (triple exponential smoothing) forecast
+	AnomalyAlgorithmHoltWinters AnomalyAlgorithm = "holt_winters"
+
+	// Seasonal-trend decomposition
+	AnomalyAlgorithmSTL AnomalyAlgorithm = "stl"
+)
+
+const (
+	defaultAnomalySensitivity = 3
+	defaultHoltWintersAlpha   = 0.5
+	defaultHoltWintersBeta    = 0.1
+	defaultHoltWintersGamma   = 0.3
+	// madToStdDev scales the median absolute deviation to be comparable with the standard deviation of normally distributed data.
+	madToStdDev = 1.4826
+	// stlIterations is the number of passes of the seasonal-trend decomposition.
+	stlIterations = 2
+	// maxSeasonPoints and maxGridPoints limit the season and the regular grid of seasonal algorithms, in points of the series.
+	// A season that is long compared to the step of the series, or a series with one very short step, would otherwise
+	// make the algorithms allocate and process arbitrarily large grids.
+	maxSeasonPoints = 10_000
+	maxGridPoints   = 100_000
+)
+
+// AnomalyOptions configures Series.DetectAnomalies.
+type AnomalyOptions struct {
+	Algorithm AnomalyAlgorithm
+	// Sensitivity is the number of deviations from the expected value at which the bands are placed. Defaults to 3.
+	Sensitivity float64
+	// Window is the length of the trailing window the expected value is computed from by MAD and z-score algorithms.
+	// If it is zero, the whole series is used.
+	Window time.Duration
+	// Season is the length of the season for Holt-Winters and STL algorithms.
+	Season time.Duration
+	// Alpha, Beta and Gamma are the smoothing factors of level, trend and season of the Holt-Winters algorithm.
+	// Zero values are replaced by defaults.
+	Alpha float64
+	Beta  float64
+	Gamma float64
+}
+
+// Validate checks the options and sets defaults.
+func (o *AnomalyOptions) Validate() error {
+	switch o.Algorithm {
+	case AnomalyAlgorithmMAD, AnomalyAlgorithmZScore:
+		if o.Window < 0 {
+			return fmt.Errorf("window must not be negative")
+		}
+	case AnomalyAlgorithmHoltWinters, AnomalyAlgorithmSTL:
+		if o.Season <= 0 {
+			return fmt.Errorf("algorithm %s requires season", o.Algorithm)
+		}
+	default:
+		return fmt.Errorf("unsupported anomaly detection algorithm %q", o.Algorithm)
+	}
+	if o.Sensitivity < 0 {
+		return fmt.Errorf("sensitivity must not be negative")
+	}
+	if o.Sensitivity == 0 {
+		o.Sensitivity = defaultAnomalySensitivity
+	}
+	for _, f := range []*float64{&o.Alpha, &o.Beta, &o.Gamma} {
+		if *f < 0 || *f > 1 {
+			return fmt.Errorf("smoothing factors must be between 0 and 1")
+		}
+	}
+	if o.Alpha == 0 {
+		o.Alpha = defaultHoltWintersAlpha
+	}
+	if o.Beta == 0 {
+		o.Beta = defaultHoltWintersBeta
+	}
+	if o.Gamma == 0 {
+		o.Gamma = defaultHoltWintersGamma
+	}
+	return nil
+}
+
+// AnomalyResult contains series that have the same timestamps as the analyzed series.
+type AnomalyResult struct {
+	// Score is the distance of the value from the expected one, relative to the distance of the bands.
+	// Values with a score above 1 are outside the bands.
+	Score Series
+	// Upper and Lower are the bands of values that are considered normal.
+	Upper Series
+	Lower Series
+}
+
+// DetectAnomalies computes the expected value and its deviation at every point of the series using the given algorithm,
+// and returns the anomaly score and the bands. Points for which there is not enough data to compute the expected value are nil.
+func (s Series) DetectAnomalies(refID string, opts AnomalyOptions) (AnomalyResult, error) {
+	if err := opts.Validate(); err != nil {
+		return AnomalyResult{}, err
+	}
+
+	times := make([]time.Time, s.Len())
+	values := make([]float64, s.Len())
+	idx := make([]int, s.Len())
+	for i := 0; i < s.Len(); i++ {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return s.GetTime(idx[i]).Before(s.GetTime(idx[j])) })
+	for i, j := range idx {
+		t, v := s.GetPoint(j)
+		times[i] = t
+		values[i] = math.NaN()
+		if v != nil {
+			values[i] = *v
+		}
+	}
+
+	var expected, deviation []float64
+	var err error
+	switch opts.Algorithm {
+	case AnomalyAlgorithmMAD:
+		expected, deviation = rollingEstimate(times, values, opts.Window, medianDeviation)
+	case AnomalyAlgorithmZScore:
+		expected, deviation = rollingEstimate(times, values, opts.Window, meanDeviation)
+	case AnomalyAlgorithmHoltWinters:
+		expected, deviation, err = seasonalEstimate(times, values, opts.Season, func(y []float64, m int) ([]float64, []float64) {
+			return holtWinters(y, m, opts.Alpha, opts.Beta, opts.Gamma)
+		})
+	case AnomalyAlgorithmSTL:
+		expected, deviation, err = seasonalEstimate(times, values, opts.Season, decompose)
+	}
+	if err != nil {
+		return AnomalyResult{}, err
+	}
+
+	labels := s.GetLabels()
+	result := AnomalyResult{
+		Score: NewSeries(refID, labels.Copy(), len(times)),
+		Upper: NewSeries(refID, labels.Copy(), len(times)),
+		Lower: NewSeries(refID, labels.Copy(), len(times)),
+	}
+	for i, t := range times {
+		var score, upper, lower *float64
+		e, d := expected[i], deviation[i]*opts.Sensitivity
+		if !math.IsNaN(e) && !math.IsNaN(d) {
+			u, l := e+d, e-d
+			upper, lower = &u, &l
+			if v := values[i]; !math.IsNaN(v) {
+				sc := math.Abs(v - e)
+				switch {
+				case d > 0:
+					sc = sc / d
+				case sc > 0:
+					sc = math.Inf(1)
+				}
+				score = &sc
+			}
+		}
+		result.Score.SetPoint(i, t, score)
+		result.Upper.SetPoint(i, t, upper)
+		result.Lower.SetPoint(i, t, lower)
+	}
+	return result, nil
+}
+
+// estimator returns the center and the scale of the values.
+type estimator func(values []float64) (float64, float64)
+
+func meanDeviation(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+func medianDeviation(values []float64) (float64, float64) {
+	m := median(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - m)
+	}
+	return m, madToStdDev * median(deviations)
+}
+
+func median(values []float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func withoutNaN(values []float64) []float64 {
+	result := make([]float64, 0, len(values))
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// rollingEstimate estimates every point from the points in the trailing window before it.
+// If the window is zero, all points are estimated from the whole series.
+func rollingEstimate(times []time.Time, values []float64, window time.Duration, est estimator) ([]float64, []float64) {
+	expected := make([]float64, len(values))
+	deviation := make([]float64, len(values))
+	if window == 0 {
+		e, d := math.NaN(), math.NaN()
+		if valid := withoutNaN(values); len(valid) > 1 {
+			e, d = est(valid)
+		}
+		for i := range values {
+			expected[i], deviation[i] = e, d
+		}
+		return expected, deviation
+	}
+
+	start := 0
+	for i, t := range times {
+		for start < i && !times[start].After(t.Add(-window)) {
+			start++
+		}
+		expected[i], deviation[i] = math.NaN(), math.NaN()
+		if valid := withoutNaN(values[start:i]); len(valid) > 1 {
+			expected[i], deviation[i] = est(valid)
+		}
+	}
+	return expected, deviation
+}
+
+// seasonalEstimate places the points on a regular grid, so that points of the same phase of the season are a fixed number of slots apart,
+// and estimates the grid with the seasonal model. It returns an error if the season or the grid exceed the limits.
+func seasonalEstimate(times []time.Time, values []float64, season time.Duration, model func(y []float64, m int) ([]float64, []float64)) ([]float64, []float64, error) {
+	expected := make([]float64, len(values))
+	deviation := make([]float64, len(values))
+	for i := range values {
+		expected[i], deviation[i] = math.NaN(), math.NaN()
+	}
+	step := medianStep(times)
+	if step <= 0 {
+		return expected, deviation, nil
+	}
+	m := math.Round(float64(season) / float64(step))
+	if m > maxSeasonPoints {
+		return nil, nil, fmt.Errorf("season %s is %.0f points of the series with step %s, more than the limit of %d points", season, m, step, maxSeasonPoints)
+	}
+	n := math.Round(float64(times[len(times)-1].Sub(times[0]))/float64(step)) + 1
+	if n > maxGridPoints {
+		return nil, nil, fmt.Errorf("series spans %.0f steps of %s, more than the limit of %d points", n, step, maxGridPoints)
+	}
+	// The model needs at least two full seasons to be initialized.
+	if m < 2 || n < 2*m {
+		return expected, deviation, nil
+	}
+
+	grid := make([]float64, int(n))
+	for i := range grid {
+		grid[i] = math.NaN()
+	}
+	slots := make([]int, len(times))
+	for i, t := range times {
+		slots[i] = int(math.Round(float64(t.Sub(times[0])) / float64(step)))
+		grid[slots[i]] = values[i]
+	}
+	gridExpected, gridDeviation := model(grid, int(m))
+	for i, slot := range slots {
+		expected[i], deviation[i] = gridExpected[slot], gridDeviation[slot]
+	}
+	return expected, deviation, nil
+}
+
+func medianStep(times []time.Time) time.Duration {
+	if len(times) < 2 {
+		return 0
+	}
+	steps := make([]float64, 0, len(times)-1)
+	for i := 1; i < len(times); i++ {
+		if d := times[i].Sub(times[i-1]); d > 0 {
+			steps = append(steps, float64(d))
+		}
+	}
+	if len(steps) == 0 {
+		return 0
+	}
+	return time.Duration(median(steps))
+}
+
+// holtWinters returns the one-step-ahead forecasts of the additive Holt-Winters model with season of m slots.
+// The first season is used to initialize the model and has no forecasts. The deviation is the standard deviation of the forecast errors.
+func holtWinters(y []float64, m int, alpha, beta, gamma float64) ([]float64, []float64) {
+	expected := make([]float64, len(y))
+	firstMean, _ := meanDeviation(nonEmpty(withoutNaN(y[:m])))
+	secondMean, _ := meanDeviation(nonEmpty(withoutNaN(y[m : 2*m])))
+	level := firstMean
+	trend := (secondMean - firstMean) / float64(m)
+	seasonal := make([]float64, m)
+	for i := 0; i < m; i++ {
+		if !math.IsNaN(y[i]) {
+			seasonal[i] = y[i] - level
+		}
+	}
+
+	errs := make([]float64, 0, len(y))
+	for t, v := range y {
+		phase := t % m
+		expected[t] = math.NaN()
+		if t >= m {
+			expected[t] = level + trend + seasonal[phase]
+			if !math.IsNaN(v) {
+				errs = append(errs, v-expected[t])
+			}
+		}
+		if math.IsNaN(v) {
+			level += trend
+			continue
+		}
+		prevLevel := level
+		level = alpha*(v-seasonal[phase]) + (1-alpha)*(level+trend)
+		trend = beta*(level-prevLevel) + (1-beta)*trend
+		seasonal[phase] = gamma*(v-level) + (1-gamma)*seasonal[phase]
+	}
+	return expected, constantDeviation(len(y), errs, meanDeviation)
+}
+
+// decompose splits the series into trend, seasonal and remainder components, and returns the sum of trend and season as the expected values.
+// This is a simplified STL: the trend is a centered moving median of the season length, and the seasonal component of a phase
+// is the median of the detrended values of that phase. Both are re-estimated on the deseasonalized series.
+// Medians are used instead of the LOESS smoothing of STL, so that a single outlier does not shift the estimate of its neighbours.
+// The deviation is the robust (MAD-based) deviation of the remainder.
+func decompose(y []float64, m int) ([]float64, []float64) {
+	seasonal := make([]float64, len(y))
+	var trend []float64
+	for iter := 0; iter < stlIterations; iter++ {
+		deseasonalized := make([]float64, len(y))
+		for i, v := range y {
+			deseasonalized[i] = v - seasonal[i]
+		}
+		trend = movingMedian(deseasonalized, m)
+
+		phases := make([][]float64, m)
+		for i, v := range y {
+			if d := v - trend[i]; !math.IsNaN(d) {
+				phases[i%m] = append(phases[i%m], d)
+			}
+		}
+		components := make([]float64, m)
+		mean := 0.0
+		for p, values := range phases {
+			if len(values) > 0 {
+				components[p] = median(values)
+			}
+			mean += components[p]
+		}
+		// The seasonal component is centered, so that the level is accounted for by the trend.
+		mean /= float64(m)
+		for i := range seasonal {
+			seasonal[i] = components[i%m] - mean
+		}
+	}
+
+	expected := make([]float64, len(y))
+	remainder := make([]float64, 0, len(y))
+	for i, v := range y {
+		expected[i] = trend[i] + seasonal[i]
+		if r := v - expected[i]; !math.IsNaN(r) {
+			remainder = append(remainder, r)
+		}
+	}
+	return expected, constantDeviation(len(y), remainder, medianDeviation)
+}
+
+// movingMedian returns the median of the values within a centered window of the given width, ignoring NaN.
+// The window slides over the values, so every value is added to and removed from the window once.
+func movingMedian(values []float64, width int) []float64 {
+	result := make([]float64, len(values))
+	half := width / 2
+	window := newSlidingMedian()
+	for i := 0; i < half && i < len(values); i++ {
+		window.add(values[i])
+	}
+	for i := range values {
+		if j := i + half; j < len(values) {
+			window.add(values[j])
+		}
+		if j := i - half - 1; j >= 0 {
+			window.remove(values[j])
+		}
+		result[i] = window.median()
+	}
+	return result
+}
+
+// slidingMedian keeps the lower half of a window of values in a max-heap and the upper half in a min-heap,
+// so that the median is at the top of the heaps. Removed values are deleted lazily, when they reach the top of a heap.
+type slidingMedian struct {
+	low, high         *floatHeap
+	lowSize, highSize int
+	removed           map[float64]int
+}
+
+func newSlidingMedian() *slidingMedian {
+	return &slidingMedian{
+		low:     &floatHeap{less: func(a, b float64) bool { return a > b }},
+		high:    &floatHeap{less: func(a, b float64) bool { return a < b }},
+		removed: map[float64]int{},
+	}
+}
+
+func (w *slidingMedian) add(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	if w.lowSize == 0 || v <= w.low.top() {
+		heap.Push(w.low, v)
+		w.lowSize++
+	} else {
+		heap.Push(w.high, v)
+		w.highSize++
+	}
+	w.balance()
+}
+
+// remove removes a value that was added before.
+func (w *slidingMedian) remove(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	w.removed[v]++
+	if v <= w.low.top() {
+		w.lowSize--
+		w.prune(w.low)
+	} else {
+		w.highSize--
+		w.prune(w.high)
+	}
+	w.balance()
+}
+
+// median returns the median of the values in the window, or NaN if it is empty.
+func (w *slidingMedian) median() float64 {
+	switch {
+	case w.lowSize == 0:
+		return math.NaN()
+	case w.lowSize > w.highSize:
+		return w.low.top()
+	default:
+		return (w.low.top() + w.high.top()) / 2
+	}
+}
+
+// balance keeps the lower half at most one value larger than the upper half.
+func (w *slidingMedian) balance() {
+	switch {
+	case w.lowSize > w.highSize+1:
+		heap.Push(w.high, heap.Pop(w.low))
+		w.lowSize--
+		w.highSize++
+		w.prune(w.low)
+	case w.lowSize < w.highSize:
+		heap.Push(w.low, heap.Pop(w.high))
+		w.lowSize++
+		w.highSize--
+		w.prune(w.high)
+	}
+}
+
+// prune pops removed values from the top of the heap.
+func (w *slidingMedian) prune(h *floatHeap) {
+	for h.Len() > 0 {
+		v := h.top()
+		if w.removed[v] == 0 {
+			return
+		}
+		w.removed[v]--
+		if w.removed[v] == 0 {
+			delete(w.removed, v)
+		}
+		heap.Pop(h)
+	}
+}
+
+type floatHeap struct {
+	values []float64
+	less   func(a, b float64) bool
+}
+
+func (h *floatHeap) Len() int           { return len(h.values) }
+func (h *floatHeap) Less(i, j int) bool { return h.less(h.values[i], h.values[j]) }
+func (h *floatHeap) Swap(i, j int)      { h.values[i], h.values[j] = h.values[j], h.values[i] }
+func (h *floatHeap) Push(x any)         { h.values = append(h.values, x.(float64)) }
+func (h *floatHeap) Pop() any {
+	v := h.values[len(h.values)-1]
+	h.values = h.values[:len(h.values)-1]
+	return v
+}
+
+func (h *floatHeap) top() float64 {
+	if len(h.values) == 0 {
+		return math.NaN()
+	}
+	return h.values[0]
+}
+
+func constantDeviation(n int, residuals []float64, est estimator) []float64 {
+	d := math.NaN()
+	if len(residuals) > 1 {
+		_, d = est(residuals)
+	}
+	result := make([]float64, n)
+	for i := range result {
+		result[i] = d
+	}
+	return result
+}
+
+// nonEmpty returns a slice with a single zero value if values is empty, so that the mean is defined.
+func nonEmpty(values []float64) []float64 {
+	if len(values) == 0 {
+		return []float64{0}
+	}
+	return values
+}
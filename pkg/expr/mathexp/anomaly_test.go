@@ -0,0 +1,179 @@
+package mathexp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectAnomalies(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	step := time.Minute
+
+	series := func(values ...float64) Series {
+		s := NewSeries("A", data.Labels{"host": "a"}, len(values))
+		for i, v := range values {
+			if math.IsNaN(v) {
+				s.SetPoint(i, start.Add(time.Duration(i)*step), nil)
+				continue
+			}
+			s.SetPoint(i, start.Add(time.Duration(i)*step), float64Pointer(v))
+		}
+		return s
+	}
+	// seasonal generates a series with a season of 10 points, a small noise and a spike at the given index.
+	seasonal := func(n, spike int) Series {
+		values := make([]float64, n)
+		for i := range values {
+			values[i] = 100 + 10*math.Sin(2*math.Pi*float64(i)/10) + float64(i%3)*0.1
+			if i == spike {
+				values[i] += 50
+			}
+		}
+		return series(values...)
+	}
+	values := func(s Series) []*float64 {
+		result := make([]*float64, s.Len())
+		for i := range result {
+			result[i] = s.GetValue(i)
+		}
+		return result
+	}
+
+	t.Run("zscore should score points by the deviation from the mean", func(t *testing.T) {
+		res, err := series(1, 3, 1, 3).DetectAnomalies("B", AnomalyOptions{Algorithm: AnomalyAlgorithmZScore, Sensitivity: 1})
+		require.NoError(t, err)
+		// mean is 2, standard deviation is 1.
+		require.Equal(t, []*float64{float64Pointer(1), float64Pointer(1), float64Pointer(1), float64Pointer(1)}, values(res.Score))
+		require.Equal(t, float64Pointer(3), res.Upper.GetValue(0))
+		require.Equal(t, float64Pointer(1), res.Lower.GetValue(0))
+		require.Equal(t, data.Labels{"host": "a"}, res.Score.GetLabels())
+	})
+
+	t.Run("mad should not be affected by outliers", func(t *testing.T) {
+		res, err := series(10, 11, 9, 10, 11, 9, 10, 1000).DetectAnomalies("B", AnomalyOptions{Algorithm: AnomalyAlgorithmMAD})
+		require.NoError(t, err)
+		for i := 0; i < 7; i++ {
+			require.Less(t, *res.Score.GetValue(i), 1.0)
+		}
+		require.Greater(t, *res.Score.GetValue(7), 1.0)
+	})
+
+	t.Run("rolling window should only use preceding points", func(t *testing.T) {
+		res, err := series(1, 2, 3, 4, 100).DetectAnomalies("B", AnomalyOptions{Algorithm: AnomalyAlgorithmZScore, Window: 3 * step})
+		require.NoError(t, err)
+		// The first two points do not have enough history.
+		require.Nil(t, res.Score.GetValue(0))
+		require.Nil(t, res.Upper.GetValue(1))
+		require.InDelta(t, 2.5, (*res.Upper.GetValue(3)+*res.Lower.GetValue(3))/2, 1e-9)
+		require.Greater(t, *res.Score.GetValue(4), 1.0)
+	})
+
+	t.Run("missing values should have bands but no score", func(t *testing.T) {
+		res, err := series(1, math.NaN(), 3).DetectAnomalies("B", AnomalyOptions{Algorithm: AnomalyAlgorithmZScore})
+		require.NoError(t, err)
+		require.Nil(t, res.Score.GetValue(1))
+		require.NotNil(t, res.Upper.GetValue(1))
+	})
+
+	for _, alg := range []AnomalyAlgorithm{AnomalyAlgorithmHoltWinters, AnomalyAlgorithmSTL} {
+		t.Run(string(alg)+" should detect a spike in seasonal data", func(t *testing.T) {
+			res, err := seasonal(60, 45).DetectAnomalies("B", AnomalyOptions{Algorithm: alg, Season: 10 * step})
+			require.NoError(t, err)
+			for i := 20; i < 60; i++ {
+				score := res.Score.GetValue(i)
+				require.NotNil(t, score, "point %d", i)
+				if i == 45 {
+					require.Greater(t, *score, 1.0)
+				} else if i < 45 {
+					require.Less(t, *score, 1.0, "point %d", i)
+				}
+			}
+		})
+
+		t.Run(string(alg)+" should return empty results if there is less than two seasons", func(t *testing.T) {
+			res, err := seasonal(15, -1).DetectAnomalies("B", AnomalyOptions{Algorithm: alg, Season: 10 * step})
+			require.NoError(t, err)
+			for i := 0; i < res.Score.Len(); i++ {
+				require.Nil(t, res.Score.GetValue(i))
+				require.Nil(t, res.Upper.GetValue(i))
+			}
+		})
+
+		t.Run(string(alg)+" should fail if the season is too long", func(t *testing.T) {
+			_, err := seasonal(15, -1).DetectAnomalies("B", AnomalyOptions{Algorithm: alg, Season: (maxSeasonPoints + 1) * step})
+			require.ErrorContains(t, err, "more than the limit")
+		})
+
+		t.Run(string(alg)+" should fail if the series spans too many steps", func(t *testing.T) {
+			// the median step is one second, the last point is far away from the others.
+			s := NewSeries("A", nil, 4)
+			s.SetPoint(0, start, float64Pointer(1))
+			s.SetPoint(1, start.Add(time.Second), float64Pointer(1))
+			s.SetPoint(2, start.Add(2*time.Second), float64Pointer(1))
+			s.SetPoint(3, start.Add(time.Duration(maxGridPoints)*time.Second), float64Pointer(1))
+			_, err := s.DetectAnomalies("B", AnomalyOptions{Algorithm: alg, Season: time.Minute})
+			require.ErrorContains(t, err, "more than the limit")
+		})
+	}
+
+	t.Run("should validate options", func(t *testing.T) {
+		_, err := series(1).DetectAnomalies("B", AnomalyOptions{Algorithm: "foo"})
+		require.ErrorContains(t, err, "unsupported")
+		_, err = series(1).DetectAnomalies("B", AnomalyOptions{Algorithm: AnomalyAlgorithmSTL})
+		require.ErrorContains(t, err, "requires season")
+		_, err = series(1).DetectAnomalies("B", AnomalyOptions{Algorithm: AnomalyAlgorithmHoltWinters, Season: time.Hour, Alpha: 2})
+		require.ErrorContains(t, err, "between 0 and 1")
+	})
+}
+
+func TestMovingMedian(t *testing.T) {
+	naive := func(values []float64, width int) []float64 {
+		result := make([]float64, len(values))
+		half := width / 2
+		for i := range values {
+			result[i] = math.NaN()
+			if valid := withoutNaN(values[max(0, i-half):min(len(values), i+half+1)]); len(valid) > 0 {
+				result[i] = median(valid)
+			}
+		}
+		return result
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for _, width := range []int{1, 2, 3, 4, 7, 10, 50} {
+		values := make([]float64, 200)
+		for i := range values {
+			switch r.Intn(10) {
+			case 0:
+				values[i] = math.NaN()
+			case 1:
+				// duplicates of the same value
+				values[i] = 5
+			default:
+				values[i] = float64(r.Intn(20))
+			}
+		}
+		expected := naive(values, width)
+		actual := movingMedian(values, width)
+		for i := range values {
+			if math.IsNaN(expected[i]) {
+				require.True(t, math.IsNaN(actual[i]), "width %d, point %d", width, i)
+				continue
+			}
+			require.Equal(t, expected[i], actual[i], "width %d, point %d", width, i)
+		}
+	}
+
+	t.Run("should return NaN for windows without values", func(t *testing.T) {
+		actual := movingMedian([]float64{math.NaN(), math.NaN(), math.NaN(), math.NaN(), 1}, 3)
+		require.True(t, math.IsNaN(actual[0]))
+		require.True(t, math.IsNaN(actual[2]))
+		require.Equal(t, 1.0, actual[3])
+		require.Equal(t, 1.0, actual[4])
+	})
+}
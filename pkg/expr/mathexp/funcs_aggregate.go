@@ -0,0 +1,287 @@
+package mathexp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// BucketLabel is the label that holds the upper bound of histogram buckets, as in Prometheus.
+const BucketLabel = "le"
+
+type bucket struct {
+	upperBound float64
+	count      float64
+}
+
+// histogramQuantile calculates the q-quantile from the buckets of a histogram, like Prometheus' histogram_quantile.
+// Buckets are the results in NumberSet or SeriesSet with the label BucketLabel, their values are the cumulative
+// counts. All results with the same labels except BucketLabel belong to the same histogram. For series, the
+// quantile is calculated at every timestamp.
+func histogramQuantile(e *State, varSet Results, qRes Results) (Results, error) {
+	newRes := Results{}
+	q, err := scalarArg("histogram_quantile", "quantile", qRes)
+	if err != nil {
+		return newRes, err
+	}
+
+	type histogram struct {
+		labels data.Labels
+		values []Value
+	}
+	var histograms []*histogram
+	byFingerprint := map[data.Fingerprint]*histogram{}
+	for _, res := range varSet.Values {
+		switch res.(type) {
+		case Number, Series:
+		case NoData:
+			continue
+		default:
+			return newRes, fmt.Errorf("histogram_quantile: expected numbers or time series, got %s", res.Type())
+		}
+		lbls := res.GetLabels().Copy()
+		if _, ok := lbls[BucketLabel]; !ok {
+			return newRes, fmt.Errorf("histogram_quantile: result with labels %s has no %q label", res.GetLabels(), BucketLabel)
+		}
+		delete(lbls, BucketLabel)
+		fp := lbls.Fingerprint()
+		h, ok := byFingerprint[fp]
+		if !ok {
+			h = &histogram{labels: lbls}
+			byFingerprint[fp] = h
+			histograms = append(histograms, h)
+		}
+		h.values = append(h.values, res)
+	}
+	if len(histograms) == 0 {
+		newRes.Values = append(newRes.Values, NewNoData())
+		return newRes, nil
+	}
+
+	for _, h := range histograms {
+		upperBounds := make([]float64, len(h.values))
+		for i, v := range h.values {
+			upperBounds[i], err = strconv.ParseFloat(v.GetLabels()[BucketLabel], 64)
+			if err != nil {
+				return newRes, fmt.Errorf("histogram_quantile: invalid bucket bound in labels %s: %w", v.GetLabels(), err)
+			}
+		}
+
+		if _, ok := h.values[0].(Number); ok {
+			buckets := make([]bucket, 0, len(h.values))
+			for i, v := range h.values {
+				n, ok := v.(Number)
+				if !ok {
+					return newRes, fmt.Errorf("histogram_quantile: buckets with labels %s mix numbers and time series", h.labels)
+				}
+				if f := n.GetFloat64Value(); f != nil {
+					buckets = append(buckets, bucket{upperBound: upperBounds[i], count: *f})
+				}
+			}
+			n := NewNumber(e.RefID, h.labels)
+			quantile := bucketQuantile(q, buckets)
+			n.SetValue(&quantile)
+			newRes.Values = append(newRes.Values, n)
+			continue
+		}
+
+		bucketsAt := map[int64][]bucket{}
+		for i, v := range h.values {
+			s, ok := v.(Series)
+			if !ok {
+				return newRes, fmt.Errorf("histogram_quantile: buckets with labels %s mix numbers and time series", h.labels)
+			}
+			for j := 0; j < s.Len(); j++ {
+				t, f := s.GetPoint(j)
+				if f == nil {
+					continue
+				}
+				bucketsAt[t.UnixNano()] = append(bucketsAt[t.UnixNano()], bucket{upperBound: upperBounds[i], count: *f})
+			}
+		}
+		times := make([]int64, 0, len(bucketsAt))
+		for t := range bucketsAt {
+			times = append(times, t)
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+		s := NewSeries(e.RefID, h.labels, len(times))
+		for i, t := range times {
+			quantile := bucketQuantile(q, bucketsAt[t])
+			s.SetPoint(i, time.Unix(0, t), &quantile)
+		}
+		newRes.Values = append(newRes.Values, s)
+	}
+	return newRes, nil
+}
+
+// bucketQuantile calculates the quantile q from the cumulative buckets, interpolating linearly within a bucket.
+// The buckets must include the +Inf bucket, otherwise the result is NaN.
+func bucketQuantile(q float64, buckets []bucket) float64 {
+	if math.IsNaN(q) {
+		return math.NaN()
+	}
+	if q < 0 {
+		return math.Inf(-1)
+	}
+	if q > 1 {
+		return math.Inf(1)
+	}
+	buckets = coalesceBuckets(buckets)
+	if len(buckets) < 2 || !math.IsInf(buckets[len(buckets)-1].upperBound, 1) {
+		return math.NaN()
+	}
+	// the counts are cumulative, they can only decrease because of scrape or precision issues.
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i].count < buckets[i-1].count {
+			buckets[i].count = buckets[i-1].count
+		}
+	}
+
+	observations := buckets[len(buckets)-1].count
+	if observations == 0 {
+		return math.NaN()
+	}
+	rank := q * observations
+	b := sort.Search(len(buckets)-1, func(i int) bool { return buckets[i].count >= rank })
+
+	if b == len(buckets)-1 {
+		return buckets[len(buckets)-2].upperBound
+	}
+	if b == 0 && buckets[0].upperBound <= 0 {
+		return buckets[0].upperBound
+	}
+	var (
+		start = 0.0
+		end   = buckets[b].upperBound
+		count = buckets[b].count
+	)
+	if b > 0 {
+		start = buckets[b-1].upperBound
+		count -= buckets[b-1].count
+		rank -= buckets[b-1].count
+	}
+	return start + (end-start)*(rank/count)
+}
+
+// coalesceBuckets sorts the buckets by their upper bound and adds up the counts of buckets with the same bound.
+func coalesceBuckets(buckets []bucket) []bucket {
+	sorted := make([]bucket, len(buckets))
+	copy(sorted, buckets)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].upperBound < sorted[j].upperBound })
+	result := sorted[:0]
+	for _, b := range sorted {
+		if len(result) > 0 && result[len(result)-1].upperBound == b.upperBound {
+			result[len(result)-1].count += b.count
+			continue
+		}
+		result = append(result, b)
+	}
+	return result
+}
+
+// topk returns the k results in NumberSet with the largest values. For SeriesSet, the k largest
+// points are selected at every timestamp, other points are set to null and series without any selected
+// point are dropped.
+func topk(e *State, varSet Results, kRes Results) (Results, error) {
+	return selectK(e, "topk", varSet, kRes, func(a, b float64) bool { return a > b })
+}
+
+// bottomk returns the k results in NumberSet with the smallest values. For SeriesSet, the k smallest
+// points are selected at every timestamp, other points are set to null and series without any selected
+// point are dropped.
+func bottomk(e *State, varSet Results, kRes Results) (Results, error) {
+	return selectK(e, "bottomk", varSet, kRes, func(a, b float64) bool { return a < b })
+}
+
+func selectK(e *State, fn string, varSet Results, kRes Results, less func(a, b float64) bool) (Results, error) {
+	newRes := Results{}
+	kf, err := scalarArg(fn, "k", kRes)
+	if err != nil {
+		return newRes, err
+	}
+	if kf < 1 || math.IsInf(kf, 0) || math.IsNaN(kf) {
+		return newRes, fmt.Errorf("%s: k must be a positive number, got %v", fn, kf)
+	}
+	k := int(kf)
+
+	type candidate struct {
+		value int // index of the value in varSet
+		point int
+		float float64
+	}
+	var numbers []*candidate
+	pointsAt := map[int64][]*candidate{}
+	selected := map[int]map[int]bool{}
+	for i, res := range varSet.Values {
+		switch v := res.(type) {
+		case Number:
+			if f := v.GetFloat64Value(); f != nil && !math.IsNaN(*f) {
+				numbers = append(numbers, &candidate{value: i, float: *f})
+			}
+		case Series:
+			for j := 0; j < v.Len(); j++ {
+				t, f := v.GetPoint(j)
+				if f == nil || math.IsNaN(*f) {
+					continue
+				}
+				pointsAt[t.UnixNano()] = append(pointsAt[t.UnixNano()], &candidate{value: i, point: j, float: *f})
+			}
+		case Scalar, NoData:
+		default:
+			return newRes, fmt.Errorf("%s: unsupported type %s", fn, res.Type())
+		}
+	}
+
+	choose := func(candidates []*candidate) {
+		sort.SliceStable(candidates, func(i, j int) bool { return less(candidates[i].float, candidates[j].float) })
+		for i := 0; i < len(candidates) && i < k; i++ {
+			c := candidates[i]
+			if selected[c.value] == nil {
+				selected[c.value] = map[int]bool{}
+			}
+			selected[c.value][c.point] = true
+		}
+	}
+	choose(numbers)
+	for _, candidates := range pointsAt {
+		choose(candidates)
+	}
+
+	// numbers are returned in the order of their rank, series in the order of the input.
+	for _, c := range numbers {
+		if !selected[c.value][0] {
+			continue
+		}
+		n := NewNumber(e.RefID, varSet.Values[c.value].GetLabels())
+		f := c.float
+		n.SetValue(&f)
+		newRes.Values = append(newRes.Values, n)
+	}
+	for i, res := range varSet.Values {
+		switch v := res.(type) {
+		case Series:
+			points, ok := selected[i]
+			if !ok {
+				continue
+			}
+			s := NewSeries(e.RefID, v.GetLabels(), v.Len())
+			for j := 0; j < v.Len(); j++ {
+				t, f := v.GetPoint(j)
+				if !points[j] {
+					f = nil
+				}
+				s.SetPoint(j, t, f)
+			}
+			newRes.Values = append(newRes.Values, s)
+		case Scalar:
+			newRes.Values = append(newRes.Values, NewScalar(e.RefID, v.GetFloat64Value()))
+		case NoData:
+			newRes.Values = append(newRes.Values, v.New())
+		}
+	}
+	return newRes, nil
+}
@@ -0,0 +1,146 @@
+package mathexp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+)
+
+func TestHistogramQuantileFunc(t *testing.T) {
+	buckets := func(job string, counts map[string]float64) []Value {
+		var values []Value
+		for _, le := range []string{"0.1", "0.5", "1", "+Inf"} {
+			if c, ok := counts[le]; ok {
+				values = append(values, makeNumber("", data.Labels{"job": job, "le": le}, float64Pointer(c)))
+			}
+		}
+		return values
+	}
+
+	t.Run("should interpolate within the bucket", func(t *testing.T) {
+		e, err := New("histogram_quantile($A, 0.5)")
+		require.NoError(t, err)
+		values := append(
+			buckets("a", map[string]float64{"0.1": 0, "0.5": 10, "1": 20, "+Inf": 20}),
+			buckets("b", map[string]float64{"0.1": 10, "0.5": 10, "1": 10, "+Inf": 20})...,
+		)
+		res, err := e.Execute("", Vars{"A": resultValuesNoErr(values...)}, tracing.InitializeTracerForTest())
+		require.NoError(t, err)
+		require.Equal(t, resultValuesNoErr(
+			makeNumber("", data.Labels{"job": "a"}, float64Pointer(0.5)),
+			// the quantile is in the +Inf bucket, so the highest finite bound is returned.
+			makeNumber("", data.Labels{"job": "b"}, float64Pointer(0.1)),
+		), res)
+	})
+
+	t.Run("should be NaN without the +Inf bucket", func(t *testing.T) {
+		e, err := New("histogram_quantile($A, 0.9)")
+		require.NoError(t, err)
+		res, err := e.Execute("", Vars{"A": resultValuesNoErr(buckets("a", map[string]float64{"0.1": 1, "1": 2})...)}, tracing.InitializeTracerForTest())
+		require.NoError(t, err)
+		require.Len(t, res.Values, 1)
+		require.True(t, math.IsNaN(*res.Values[0].(Number).GetFloat64Value()))
+	})
+
+	t.Run("should calculate the quantile at every timestamp of series", func(t *testing.T) {
+		e, err := New("histogram_quantile($A, 0.25)")
+		require.NoError(t, err)
+		vars := Vars{"A": resultValuesNoErr(
+			makeSeries("", data.Labels{"le": "1"}, tp{time.Unix(10, 0), float64Pointer(4)}, tp{time.Unix(20, 0), float64Pointer(8)}),
+			makeSeries("", data.Labels{"le": "+Inf"}, tp{time.Unix(10, 0), float64Pointer(8)}, tp{time.Unix(20, 0), float64Pointer(8)}),
+		)}
+		res, err := e.Execute("", vars, tracing.InitializeTracerForTest())
+		require.NoError(t, err)
+		require.Equal(t, resultValuesNoErr(
+			makeSeries("", data.Labels{}, tp{time.Unix(10, 0), float64Pointer(0.5)}, tp{time.Unix(20, 0), float64Pointer(0.25)}),
+		), res)
+	})
+
+	t.Run("should fail for results without bucket label", func(t *testing.T) {
+		e, err := New("histogram_quantile($A, 0.5)")
+		require.NoError(t, err)
+		_, err = e.Execute("", Vars{"A": resultValuesNoErr(makeNumber("", data.Labels{"job": "a"}, float64Pointer(1)))}, tracing.InitializeTracerForTest())
+		require.ErrorContains(t, err, `no "le" label`)
+	})
+}
+
+func TestTopKBottomKFuncs(t *testing.T) {
+	numbers := resultValuesNoErr(
+		makeNumber("", data.Labels{"host": "a"}, float64Pointer(1)),
+		makeNumber("", data.Labels{"host": "b"}, float64Pointer(3)),
+		makeNumber("", data.Labels{"host": "c"}, nil),
+		makeNumber("", data.Labels{"host": "d"}, float64Pointer(2)),
+	)
+
+	t.Run("topk should return the largest numbers", func(t *testing.T) {
+		e, err := New("topk($A, 2)")
+		require.NoError(t, err)
+		res, err := e.Execute("", Vars{"A": numbers}, tracing.InitializeTracerForTest())
+		require.NoError(t, err)
+		require.Equal(t, resultValuesNoErr(
+			makeNumber("", data.Labels{"host": "b"}, float64Pointer(3)),
+			makeNumber("", data.Labels{"host": "d"}, float64Pointer(2)),
+		), res)
+	})
+
+	t.Run("bottomk should return the smallest numbers", func(t *testing.T) {
+		e, err := New("bottomk($A, 1)")
+		require.NoError(t, err)
+		res, err := e.Execute("", Vars{"A": numbers}, tracing.InitializeTracerForTest())
+		require.NoError(t, err)
+		require.Equal(t, resultValuesNoErr(makeNumber("", data.Labels{"host": "a"}, float64Pointer(1))), res)
+	})
+
+	t.Run("topk should select points of series at every timestamp", func(t *testing.T) {
+		e, err := New("topk($A, 1)")
+		require.NoError(t, err)
+		vars := Vars{"A": resultValuesNoErr(
+			makeSeries("", data.Labels{"host": "a"}, tp{time.Unix(10, 0), float64Pointer(1)}, tp{time.Unix(20, 0), float64Pointer(5)}),
+			makeSeries("", data.Labels{"host": "b"}, tp{time.Unix(10, 0), float64Pointer(2)}, tp{time.Unix(20, 0), float64Pointer(4)}),
+			makeSeries("", data.Labels{"host": "c"}, tp{time.Unix(10, 0), float64Pointer(0)}, tp{time.Unix(20, 0), float64Pointer(0)}),
+		)}
+		res, err := e.Execute("", vars, tracing.InitializeTracerForTest())
+		require.NoError(t, err)
+		require.Equal(t, resultValuesNoErr(
+			makeSeries("", data.Labels{"host": "a"}, tp{time.Unix(10, 0), nil}, tp{time.Unix(20, 0), float64Pointer(5)}),
+			makeSeries("", data.Labels{"host": "b"}, tp{time.Unix(10, 0), float64Pointer(2)}, tp{time.Unix(20, 0), nil}),
+		), res)
+	})
+
+	t.Run("should fail if k is less than 1", func(t *testing.T) {
+		e, err := New("topk($A, 0)")
+		require.NoError(t, err)
+		_, err = e.Execute("", Vars{"A": numbers}, tracing.InitializeTracerForTest())
+		require.ErrorContains(t, err, "k must be a positive number")
+	})
+}
+
+func TestClampFunc(t *testing.T) {
+	e, err := New("clamp($A, 0, 10)")
+	require.NoError(t, err)
+	res, err := e.Execute("", Vars{"A": resultValuesNoErr(
+		makeSeries("", nil,
+			tp{time.Unix(5, 0), float64Pointer(-2)},
+			tp{time.Unix(10, 0), float64Pointer(5)},
+			tp{time.Unix(15, 0), float64Pointer(20)},
+		),
+	)}, tracing.InitializeTracerForTest())
+	require.NoError(t, err)
+	require.Equal(t, resultValuesNoErr(
+		makeSeries("", nil,
+			tp{time.Unix(5, 0), float64Pointer(0)},
+			tp{time.Unix(10, 0), float64Pointer(5)},
+			tp{time.Unix(15, 0), float64Pointer(10)},
+		),
+	), res)
+
+	e, err = New("clamp($A, 10, 0)")
+	require.NoError(t, err)
+	_, err = e.Execute("", Vars{"A": resultValuesNoErr(makeNumber("", nil, float64Pointer(1)))}, tracing.InitializeTracerForTest())
+	require.ErrorContains(t, err, "must not be greater than max")
+}
@@ -0,0 +1,97 @@
+package mathexp
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp/parse"
+)
+
+// checkLabelReplace validates the label name and the regular expression of label_replace at parse time.
+func checkLabelReplace(_ *parse.Tree, f *parse.FuncNode) error {
+	if dst, ok := f.Args[1].(*parse.StringNode); ok && !model.LabelName(dst.Text).IsValid() {
+		return fmt.Errorf("label_replace: invalid destination label name %q", dst.Text)
+	}
+	if re, ok := f.Args[4].(*parse.StringNode); ok {
+		if _, err := regexp.Compile("^(?:" + re.Text + ")$"); err != nil {
+			return fmt.Errorf("label_replace: invalid regular expression %q: %w", re.Text, err)
+		}
+	}
+	return nil
+}
+
+// labelReplace matches the regular expression regex against the value of the label src of each result in
+// NumberSet or SeriesSet. If it matches, the label dst is set to replacement, in which $1, $2 or $name
+// refer to the capture groups of regex. An empty result removes the label dst. The regular expression is anchored.
+// Scalars have no labels and are returned as they are.
+func labelReplace(e *State, varSet Results, dst, replacement, src, regex string) (Results, error) {
+	re, err := regexp.Compile("^(?:" + regex + ")$")
+	if err != nil {
+		return Results{}, fmt.Errorf("label_replace: invalid regular expression %q: %w", regex, err)
+	}
+	return perLabels(e, "label_replace", varSet, func(lbls data.Labels) data.Labels {
+		indexes := re.FindStringSubmatchIndex(lbls[src])
+		if indexes == nil {
+			return lbls
+		}
+		value := string(re.ExpandString([]byte{}, replacement, lbls[src], indexes))
+		if value == "" {
+			delete(lbls, dst)
+		} else {
+			lbls[dst] = value
+		}
+		return lbls
+	})
+}
+
+// labelDrop removes the label name from each result in NumberSet or SeriesSet.
+// Scalars have no labels and are returned as they are.
+func labelDrop(e *State, varSet Results, name string) (Results, error) {
+	return perLabels(e, "label_drop", varSet, func(lbls data.Labels) data.Labels {
+		delete(lbls, name)
+		return lbls
+	})
+}
+
+// perLabels passes a copy of the labels of each value in varSet to labelsF and returns copies of the values with
+// the new labels. It fails if two values end up with the same labels.
+func perLabels(e *State, fn string, varSet Results, labelsF func(data.Labels) data.Labels) (Results, error) {
+	newRes := Results{}
+	seen := map[data.Fingerprint]struct{}{}
+	for _, res := range varSet.Values {
+		var newVal Value
+		switch v := res.(type) {
+		case Number:
+			n := NewNumber(e.RefID, nil)
+			n.SetValue(v.GetFloat64Value())
+			newVal = n
+		case Series:
+			s := NewSeries(e.RefID, nil, v.Len())
+			for i := 0; i < v.Len(); i++ {
+				t, f := v.GetPoint(i)
+				s.SetPoint(i, t, f)
+			}
+			newVal = s
+		case Scalar:
+			newRes.Values = append(newRes.Values, NewScalar(e.RefID, v.GetFloat64Value()))
+			continue
+		case NoData:
+			newRes.Values = append(newRes.Values, v.New())
+			continue
+		default:
+			return newRes, fmt.Errorf("%s: unsupported type %s", fn, res.Type())
+		}
+		lbls := labelsF(res.GetLabels().Copy())
+		fp := lbls.Fingerprint()
+		if _, ok := seen[fp]; ok {
+			return newRes, fmt.Errorf("%s: duplicate results with labels %s", fn, lbls)
+		}
+		seen[fp] = struct{}{}
+		newVal.SetLabels(lbls)
+		newRes.Values = append(newRes.Values, newVal)
+	}
+	return newRes, nil
+}
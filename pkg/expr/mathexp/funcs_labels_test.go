@@ -0,0 +1,95 @@
+package mathexp
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+)
+
+func TestLabelFuncs(t *testing.T) {
+	var tests = []struct {
+		name      string
+		expr      string
+		vars      Vars
+		newErrIs  require.ErrorAssertionFunc
+		execErrIs require.ErrorAssertionFunc
+		results   Results
+	}{
+		{
+			name: "label_replace sets destination label from capture groups",
+			expr: `label_replace($A, "host", "$1", "instance", "(.*):\\d+")`,
+			vars: Vars{
+				"A": resultValuesNoErr(
+					makeNumber("", data.Labels{"instance": "web:9090"}, float64Pointer(1)),
+					makeNumber("", data.Labels{"instance": "db"}, float64Pointer(2)),
+				),
+			},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results: resultValuesNoErr(
+				makeNumber("", data.Labels{"instance": "web:9090", "host": "web"}, float64Pointer(1)),
+				makeNumber("", data.Labels{"instance": "db"}, float64Pointer(2)),
+			),
+		},
+		{
+			name: "label_replace with empty replacement removes the label",
+			expr: `label_replace($A, "env", "", "env", "dev")`,
+			vars: Vars{
+				"A": resultValuesNoErr(makeNumber("", data.Labels{"env": "dev", "host": "a"}, float64Pointer(1))),
+			},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results:   resultValuesNoErr(makeNumber("", data.Labels{"host": "a"}, float64Pointer(1))),
+		},
+		{
+			name:     "label_replace with invalid regex should fail to parse",
+			expr:     `label_replace($A, "host", "$1", "instance", "(")`,
+			vars:     Vars{},
+			newErrIs: require.Error,
+		},
+		{
+			name:     "label_replace with invalid label name should fail to parse",
+			expr:     `label_replace($A, "not-valid", "$1", "instance", "(.*)")`,
+			vars:     Vars{},
+			newErrIs: require.Error,
+		},
+		{
+			name: "label_drop removes the label from series",
+			expr: `label_drop($A, "pod")`,
+			vars: Vars{
+				"A": resultValuesNoErr(makeSeries("", data.Labels{"pod": "a", "app": "web"})),
+			},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results:   resultValuesNoErr(makeSeries("", data.Labels{"app": "web"})),
+		},
+		{
+			name: "label_drop should fail if results are no longer unique",
+			expr: `label_drop($A, "pod")`,
+			vars: Vars{
+				"A": resultValuesNoErr(
+					makeNumber("", data.Labels{"pod": "a", "app": "web"}, float64Pointer(1)),
+					makeNumber("", data.Labels{"pod": "b", "app": "web"}, float64Pointer(2)),
+				),
+			},
+			newErrIs:  require.NoError,
+			execErrIs: require.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := New(tt.expr)
+			tt.newErrIs(t, err)
+			if e != nil {
+				res, err := e.Execute("", tt.vars, tracing.InitializeTracerForTest())
+				tt.execErrIs(t, err)
+				if err == nil {
+					require.Equal(t, tt.results, res)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package mathexp
+
+import (
+	"fmt"
+)
+
+// rate returns, for each series in SeriesSet, the per-second rate of increase between consecutive points.
+// A decrease of the value is treated as a counter reset, so the new value is the increase since the reset.
+// The first point of every series has no predecessor and is dropped, null points are skipped.
+func rate(e *State, varSet Results) (Results, error) {
+	return perConsecutivePoints(e, "rate", varSet, func(prev, cur float64, seconds float64) float64 {
+		increase := cur - prev
+		if cur < prev {
+			increase = cur
+		}
+		return increase / seconds
+	})
+}
+
+// delta returns, for each series in SeriesSet, the difference between consecutive points.
+// The first point of every series has no predecessor and is dropped, null points are skipped.
+func delta(e *State, varSet Results) (Results, error) {
+	return perConsecutivePoints(e, "delta", varSet, func(prev, cur float64, _ float64) float64 {
+		return cur - prev
+	})
+}
+
+// perConsecutivePoints calls pointF with every pair of consecutive non-null points of each series in varSet,
+// and the number of seconds between them. The result is set at the time of the later point.
+func perConsecutivePoints(e *State, fn string, varSet Results, pointF func(prev, cur float64, seconds float64) float64) (Results, error) {
+	newRes := Results{}
+	for _, res := range varSet.Values {
+		switch v := res.(type) {
+		case Series:
+			sorted := v
+			if !isSortedByTime(v) {
+				sorted = NewSeries(e.RefID, v.GetLabels(), v.Len())
+				for i := 0; i < v.Len(); i++ {
+					t, f := v.GetPoint(i)
+					sorted.SetPoint(i, t, f)
+				}
+				sorted.SortByTime(false)
+			}
+			newSeries := NewSeries(e.RefID, v.GetLabels(), 0)
+			prevIdx := -1
+			for i := 0; i < sorted.Len(); i++ {
+				t, f := sorted.GetPoint(i)
+				if f == nil {
+					continue
+				}
+				if prevIdx >= 0 {
+					prevT, prevF := sorted.GetPoint(prevIdx)
+					seconds := t.Sub(prevT).Seconds()
+					if seconds > 0 {
+						nF := pointF(*prevF, *f, seconds)
+						newSeries.AppendPoint(t, &nF)
+					}
+				}
+				prevIdx = i
+			}
+			newRes.Values = append(newRes.Values, newSeries)
+		case NoData:
+			newRes.Values = append(newRes.Values, v.New())
+		default:
+			return newRes, fmt.Errorf("%s: expected a time series, got %s", fn, res.Type())
+		}
+	}
+	return newRes, nil
+}
+
+func isSortedByTime(s Series) bool {
+	for i := 1; i < s.Len(); i++ {
+		if s.GetTime(i).Before(s.GetTime(i - 1)) {
+			return false
+		}
+	}
+	return true
+}
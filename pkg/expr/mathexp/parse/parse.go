@@ -314,6 +314,10 @@ func (t *Tree) Func() (f *FuncNode) {
 	}
 	f = newFunc(token.pos, token.val, funcv)
 	t.expect(itemLeftParen, "func")
+	if t.peek().typ == itemRightParen {
+		t.next()
+		return
+	}
 	for {
 		switch token = t.next(); token.typ {
 		default:
@@ -329,8 +333,14 @@ func (t *Tree) Func() (f *FuncNode) {
 				t.errorf("Unquoting error: %s", err)
 			}
 			f.append(newString(token.pos, token.val, s))
+		}
+		// arguments are separated by commas, a comma must be followed by an argument
+		switch token = t.next(); token.typ {
+		case itemComma:
 		case itemRightParen:
 			return
+		default:
+			t.unexpected(token, "func")
 		}
 	}
 }
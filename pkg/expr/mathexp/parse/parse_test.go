@@ -0,0 +1,106 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFunc(t *testing.T) {
+	funcs := map[string]Func{
+		"none": {
+			Return: TypeScalar,
+		},
+		"one": {
+			Args:   []ReturnType{TypeSeriesSet},
+			Return: TypeSeriesSet,
+		},
+		"three": {
+			Args:   []ReturnType{TypeSeriesSet, TypeScalar, TypeString},
+			Return: TypeSeriesSet,
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		expr     string
+		expected string
+		args     int
+		err      string
+	}{
+		{
+			name:     "call with a single argument",
+			expr:     "one($A)",
+			expected: "one($A)",
+			args:     1,
+		},
+		{
+			name:     "call with multiple arguments",
+			expr:     `three($A, 1 + 2, "label")`,
+			expected: `three($A, 1 + 2, "label")`,
+			args:     3,
+		},
+		{
+			name:     "nested calls",
+			expr:     `three(one($A), 2, "label")`,
+			expected: `three(one($A), 2, "label")`,
+			args:     3,
+		},
+		{
+			name:     "call without arguments",
+			expr:     "none()",
+			expected: "none()",
+			args:     0,
+		},
+		{
+			name: "call without arguments of a function with arguments",
+			expr: "one()",
+			err:  "not enough arguments for one",
+		},
+		{
+			name: "trailing comma",
+			expr: "one($A,)",
+			err:  `unexpected ")"`,
+		},
+		{
+			name: "trailing comma after multiple arguments",
+			expr: `three($A, 1, "label",)`,
+			err:  `unexpected ")"`,
+		},
+		{
+			name: "missing comma",
+			expr: `three($A 1, "label")`,
+			err:  `unexpected "1" in func`,
+		},
+		{
+			name: "leading comma",
+			expr: "one(,$A)",
+			err:  `unexpected ","`,
+		},
+		{
+			name: "missing closing parenthesis",
+			expr: "one($A",
+			err:  "unexpected EOF in func",
+		},
+		{
+			name: "too many arguments",
+			expr: "one($A, $B)",
+			err:  "too many arguments for one",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tree, err := Parse(tc.expr, funcs)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, tree.Root.String())
+			f, ok := tree.Root.(*FuncNode)
+			require.True(t, ok)
+			require.Len(t, f.Args, tc.args)
+		})
+	}
+}
@@ -20,80 +20,229 @@ const (
 
 	// Do not fill values (nill)
 	UpsamplerFillNA Upsampler = "fillna"
+
+	// Interpolate linearly between the previous and the next value
+	UpsamplerLinear Upsampler = "linear"
+)
+
+// The downsample function
+// +enum
+type Downsampler string
+
+const (
+	DownsamplerSum   Downsampler = "sum"
+	DownsamplerMean  Downsampler = "mean"
+	DownsamplerMin   Downsampler = "min"
+	DownsamplerMax   Downsampler = "max"
+	DownsamplerCount Downsampler = "count"
+	DownsamplerLast  Downsampler = "last"
+
+	// The per-second rate of increase in the window, counter resets are taken into account
+	DownsamplerRate Downsampler = "rate"
 )
 
+// The alignment of the resampled points
+// +enum
+type ResampleAlignment string
+
+const (
+	// Start at the beginning of the time range
+	ResampleAlignStart ResampleAlignment = "start"
+
+	// Align to multiples of the window in wall-clock time, e.g. to full minutes for a window of 1m
+	ResampleAlignClock ResampleAlignment = "clock"
+
+	// End at the end of the time range, which is the evaluation time for alert rules
+	ResampleAlignEnd ResampleAlignment = "end"
+)
+
+// ResampleOptions describes how a series is resampled.
+type ResampleOptions struct {
+	// Window is the interval between the resampled points.
+	Window      time.Duration
+	Downsampler Downsampler
+	Upsampler   Upsampler
+	// Alignment defines the time of the resampled points. Defaults to ResampleAlignStart.
+	Alignment ResampleAlignment
+	// MaxStaleness is the longest time a value is carried forward by UpsamplerPad, or the longest gap between
+	// two values that is interpolated by UpsamplerLinear. Zero means no limit.
+	MaxStaleness time.Duration
+}
+
+// Validate returns an error if the options are not supported.
+func (o ResampleOptions) Validate() error {
+	if o.Window <= 0 {
+		return fmt.Errorf("resample window must be positive, got %v", o.Window)
+	}
+	if o.MaxStaleness < 0 {
+		return fmt.Errorf("resample max staleness must not be negative, got %v", o.MaxStaleness)
+	}
+	switch o.Downsampler {
+	case DownsamplerSum, DownsamplerMean, DownsamplerMin, DownsamplerMax, DownsamplerCount, DownsamplerLast, DownsamplerRate:
+	default:
+		return fmt.Errorf("downsampling %v not implemented", o.Downsampler)
+	}
+	switch o.Upsampler {
+	case UpsamplerPad, UpsamplerBackfill, UpsamplerFillNA, UpsamplerLinear:
+	default:
+		return fmt.Errorf("upsampling %v not implemented", o.Upsampler)
+	}
+	switch o.Alignment {
+	case "", ResampleAlignStart, ResampleAlignClock, ResampleAlignEnd:
+	default:
+		return fmt.Errorf("resample alignment %v not supported", o.Alignment)
+	}
+	return nil
+}
+
+// firstPoint returns the time of the first resampled point in the time range.
+func (o ResampleOptions) firstPoint(from, to time.Time) time.Time {
+	switch o.Alignment {
+	case ResampleAlignClock:
+		first := from.Truncate(o.Window)
+		if first.Before(from) {
+			first = first.Add(o.Window)
+		}
+		return first
+	case ResampleAlignEnd:
+		return to.Add(-to.Sub(from).Truncate(o.Window))
+	default:
+		return from
+	}
+}
+
 // Resample turns the Series into a Number based on the given reduction function
-func (s Series) Resample(refID string, interval time.Duration, downsampler ReducerID, upsampler Upsampler, from, to time.Time) (Series, error) {
-	newSeriesLength := int(float64(to.Sub(from).Nanoseconds()) / float64(interval.Nanoseconds()))
-	if newSeriesLength <= 0 {
+func (s Series) Resample(refID string, interval time.Duration, downsampler Downsampler, upsampler Upsampler, from, to time.Time) (Series, error) {
+	return s.ResampleWithOptions(refID, ResampleOptions{
+		Window:      interval,
+		Downsampler: downsampler,
+		Upsampler:   upsampler,
+	}, from, to)
+}
+
+// ResampleWithOptions creates a new Series with a point every opts.Window in the time range.
+// Every point gets the values of the series since the previous point, reduced by the downsampler.
+// Points without values are filled by the upsampler, except for DownsamplerCount which returns zero.
+func (s Series) ResampleWithOptions(refID string, opts ResampleOptions, from, to time.Time) (Series, error) {
+	if err := opts.Validate(); err != nil {
+		return s, err
+	}
+	if to.Sub(from) < opts.Window {
 		return s, fmt.Errorf("the series cannot be sampled further; the time range is shorter than the interval")
 	}
+	first := opts.firstPoint(from, to)
+	newSeriesLength := int(to.Sub(first) / opts.Window)
 	resampled := NewSeries(refID, s.GetLabels(), newSeriesLength+1)
+
 	bookmark := 0
 	var lastSeen *float64
-	idx := 0
-	t := from
-	for !t.After(to) && idx <= newSeriesLength {
+	var lastSeenTime time.Time
+	seen := false
+	t := first
+	for idx := 0; idx <= newSeriesLength; idx++ {
+		prev, prevTime, hasPrev := lastSeen, lastSeenTime, seen
 		vals := make([]*float64, 0)
-		sIdx := bookmark
-		for {
-			if sIdx == s.Len() {
-				break
-			}
-			st, v := s.GetPoint(sIdx)
+		for ; bookmark < s.Len(); bookmark++ {
+			st, v := s.GetPoint(bookmark)
 			if st.After(t) {
 				break
 			}
-			bookmark++
-			sIdx++
-			lastSeen = v
+			lastSeen, lastSeenTime, seen = v, st, true
 			vals = append(vals, v)
 		}
+
 		var value *float64
-		if len(vals) == 0 { // upsampling
-			switch upsampler {
-			case UpsamplerPad:
-				if lastSeen != nil {
-					value = lastSeen
-				} else {
-					value = nil
-				}
-			case UpsamplerBackfill:
-				if sIdx == s.Len() { // no vals left
-					value = nil
-				} else {
-					_, value = s.GetPoint(sIdx)
-				}
-			case UpsamplerFillNA:
-				value = nil
-			default:
-				return s, fmt.Errorf("upsampling %v not implemented", upsampler)
+		switch {
+		case opts.Downsampler == DownsamplerCount:
+			count := float64(len(vals))
+			value = &count
+		case len(vals) == 0: // upsampling
+			value = s.upsample(opts, t, bookmark, prev, prevTime, hasPrev)
+		case opts.Downsampler == DownsamplerRate:
+			if hasPrev {
+				vals = append([]*float64{prev}, vals...)
 			}
-		} else if len(vals) == 1 {
+			value = windowRate(vals, opts.Window)
+		case len(vals) == 1:
 			value = vals[0]
-		} else { // downsampling
+		default: // downsampling
 			fVec := data.NewField("", s.GetLabels(), vals)
 			ff := Float64Field(*fVec)
-			var tmp *float64
-			switch downsampler {
-			case ReducerSum:
-				tmp = Sum(&ff)
-			case ReducerMean:
-				tmp = Avg(&ff)
-			case ReducerMin:
-				tmp = Min(&ff)
-			case ReducerMax:
-				tmp = Max(&ff)
-			case ReducerLast:
-				tmp = Last(&ff)
-			default:
-				return s, fmt.Errorf("downsampling %v not implemented", downsampler)
+			switch opts.Downsampler {
+			case DownsamplerSum:
+				value = Sum(&ff)
+			case DownsamplerMean:
+				value = Avg(&ff)
+			case DownsamplerMin:
+				value = Min(&ff)
+			case DownsamplerMax:
+				value = Max(&ff)
+			case DownsamplerLast:
+				value = Last(&ff)
 			}
-			value = tmp
 		}
 		resampled.SetPoint(idx, t, value)
-		t = t.Add(interval)
-		idx++
+		t = t.Add(opts.Window)
 	}
 	return resampled, nil
 }
+
+// upsample returns the value of the point at time t that has no values of its own.
+// next is the index of the first point of the series after t.
+func (s Series) upsample(opts ResampleOptions, t time.Time, next int, prev *float64, prevTime time.Time, hasPrev bool) *float64 {
+	stale := func(from, to time.Time) bool {
+		return opts.MaxStaleness > 0 && to.Sub(from) > opts.MaxStaleness
+	}
+	switch opts.Upsampler {
+	case UpsamplerPad:
+		if !hasPrev || stale(prevTime, t) {
+			return nil
+		}
+		return prev
+	case UpsamplerBackfill:
+		if next == s.Len() { // no vals left
+			return nil
+		}
+		return s.GetValue(next)
+	case UpsamplerLinear:
+		if !hasPrev || prev == nil || next == s.Len() {
+			return nil
+		}
+		nextTime, nextValue := s.GetPoint(next)
+		if nextValue == nil || stale(prevTime, nextTime) {
+			return nil
+		}
+		ratio := float64(t.Sub(prevTime)) / float64(nextTime.Sub(prevTime))
+		value := *prev + (*nextValue-*prev)*ratio
+		return &value
+	default:
+		return nil
+	}
+}
+
+// windowRate returns the per-second increase of the values in the window, treating a decrease as a counter reset.
+// It returns nil if there are less than two non-null values.
+func windowRate(vals []*float64, window time.Duration) *float64 {
+	var increase float64
+	var last *float64
+	count := 0
+	for _, v := range vals {
+		if v == nil {
+			continue
+		}
+		count++
+		if last != nil {
+			if *v < *last {
+				increase += *v
+			} else {
+				increase += *v - *last
+			}
+		}
+		last = v
+	}
+	if count < 2 {
+		return nil
+	}
+	rate := increase / window.Seconds()
+	return &rate
+}
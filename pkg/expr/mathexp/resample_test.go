@@ -13,7 +13,7 @@ func TestResampleSeries(t *testing.T) {
 	var tests = []struct {
 		name             string
 		interval         time.Duration
-		downsampler      ReducerID
+		downsampler      Downsampler
 		upsampler        Upsampler
 		timeRange        backend.TimeRange
 		seriesToResample Series
@@ -290,3 +290,104 @@ func TestResampleSeries(t *testing.T) {
 		})
 	}
 }
+
+func TestResampleWithOptions(t *testing.T) {
+	values := func(s Series) []*float64 {
+		result := make([]*float64, s.Len())
+		for i := range result {
+			result[i] = s.GetValue(i)
+		}
+		return result
+	}
+	times := func(s Series) []time.Time {
+		result := make([]time.Time, s.Len())
+		for i := range result {
+			result[i] = s.GetTime(i)
+		}
+		return result
+	}
+
+	t.Run("should align points to wall-clock time", func(t *testing.T) {
+		s := makeSeries("", nil, tp{time.Unix(65, 0), float64Pointer(1)})
+		res, err := s.ResampleWithOptions("", ResampleOptions{
+			Window: time.Minute, Downsampler: DownsamplerLast, Upsampler: UpsamplerPad, Alignment: ResampleAlignClock,
+		}, time.Unix(30, 0), time.Unix(190, 0))
+		require.NoError(t, err)
+		require.Equal(t, []time.Time{time.Unix(60, 0), time.Unix(120, 0), time.Unix(180, 0)}, times(res))
+		require.Equal(t, []*float64{nil, float64Pointer(1), float64Pointer(1)}, values(res))
+	})
+
+	t.Run("should align points to the end of the time range", func(t *testing.T) {
+		s := makeSeries("", nil, tp{time.Unix(65, 0), float64Pointer(1)})
+		res, err := s.ResampleWithOptions("", ResampleOptions{
+			Window: time.Minute, Downsampler: DownsamplerLast, Upsampler: UpsamplerFillNA, Alignment: ResampleAlignEnd,
+		}, time.Unix(30, 0), time.Unix(190, 0))
+		require.NoError(t, err)
+		require.Equal(t, []time.Time{time.Unix(70, 0), time.Unix(130, 0), time.Unix(190, 0)}, times(res))
+		require.Equal(t, []*float64{float64Pointer(1), nil, nil}, values(res))
+	})
+
+	t.Run("should interpolate linearly", func(t *testing.T) {
+		s := makeSeries("", nil, tp{time.Unix(0, 0), float64Pointer(0)}, tp{time.Unix(40, 0), float64Pointer(4)})
+		res, err := s.ResampleWithOptions("", ResampleOptions{
+			Window: 10 * time.Second, Downsampler: DownsamplerLast, Upsampler: UpsamplerLinear,
+		}, time.Unix(0, 0), time.Unix(50, 0))
+		require.NoError(t, err)
+		require.Equal(t, []*float64{float64Pointer(0), float64Pointer(1), float64Pointer(2), float64Pointer(3), float64Pointer(4), nil}, values(res))
+	})
+
+	t.Run("should not interpolate gaps longer than max staleness", func(t *testing.T) {
+		s := makeSeries("", nil, tp{time.Unix(0, 0), float64Pointer(0)}, tp{time.Unix(40, 0), float64Pointer(4)})
+		res, err := s.ResampleWithOptions("", ResampleOptions{
+			Window: 10 * time.Second, Downsampler: DownsamplerLast, Upsampler: UpsamplerLinear, MaxStaleness: 30 * time.Second,
+		}, time.Unix(0, 0), time.Unix(40, 0))
+		require.NoError(t, err)
+		require.Equal(t, []*float64{float64Pointer(0), nil, nil, nil, float64Pointer(4)}, values(res))
+	})
+
+	t.Run("should carry values forward until they are stale", func(t *testing.T) {
+		s := makeSeries("", nil, tp{time.Unix(0, 0), float64Pointer(7)})
+		res, err := s.ResampleWithOptions("", ResampleOptions{
+			Window: 10 * time.Second, Downsampler: DownsamplerLast, Upsampler: UpsamplerPad, MaxStaleness: 20 * time.Second,
+		}, time.Unix(0, 0), time.Unix(40, 0))
+		require.NoError(t, err)
+		require.Equal(t, []*float64{float64Pointer(7), float64Pointer(7), float64Pointer(7), nil, nil}, values(res))
+	})
+
+	t.Run("should count values in every window", func(t *testing.T) {
+		s := makeSeries("", nil,
+			tp{time.Unix(1, 0), float64Pointer(1)},
+			tp{time.Unix(2, 0), nil},
+			tp{time.Unix(3, 0), float64Pointer(1)},
+			tp{time.Unix(12, 0), float64Pointer(1)},
+		)
+		res, err := s.ResampleWithOptions("", ResampleOptions{
+			Window: 10 * time.Second, Downsampler: DownsamplerCount, Upsampler: UpsamplerPad,
+		}, time.Unix(0, 0), time.Unix(30, 0))
+		require.NoError(t, err)
+		require.Equal(t, []*float64{float64Pointer(0), float64Pointer(3), float64Pointer(1), float64Pointer(0)}, values(res))
+	})
+
+	t.Run("should calculate the rate in every window", func(t *testing.T) {
+		s := makeSeries("", nil,
+			tp{time.Unix(0, 0), float64Pointer(10)},
+			tp{time.Unix(5, 0), float64Pointer(20)},
+			tp{time.Unix(10, 0), float64Pointer(40)},
+			tp{time.Unix(20, 0), float64Pointer(5)},
+		)
+		res, err := s.ResampleWithOptions("", ResampleOptions{
+			Window: 10 * time.Second, Downsampler: DownsamplerRate, Upsampler: UpsamplerFillNA,
+		}, time.Unix(0, 0), time.Unix(30, 0))
+		require.NoError(t, err)
+		// the first window has a single value, the third one contains a counter reset.
+		require.Equal(t, []*float64{nil, float64Pointer(3), float64Pointer(0.5), nil}, values(res))
+	})
+
+	t.Run("should validate options", func(t *testing.T) {
+		s := makeSeries("", nil)
+		_, err := s.ResampleWithOptions("", ResampleOptions{Window: time.Second, Downsampler: DownsamplerLast, Upsampler: UpsamplerPad, Alignment: "noon"}, time.Unix(0, 0), time.Unix(10, 0))
+		require.ErrorContains(t, err, "alignment")
+		_, err = s.ResampleWithOptions("", ResampleOptions{Window: time.Second, Downsampler: "median", Upsampler: UpsamplerPad}, time.Unix(0, 0), time.Unix(10, 0))
+		require.ErrorContains(t, err, "downsampling median not implemented")
+	})
+}
@@ -29,6 +29,9 @@ const (
 
 	// SQL query via DuckDB
 	QueryTypeSQL QueryType = "sql"
+
+	// Detect anomalies in query results
+	QueryTypeAnomaly QueryType = "anomaly"
 )
 
 type MathQuery struct {
@@ -56,10 +59,16 @@ type ResampleQuery struct {
 	Window string `json:"window" jsonschema:"minLength=1,example=1d,example=10m"`
 
 	// The downsample function
-	Downsampler mathexp.ReducerID `json:"downsampler"`
+	Downsampler mathexp.Downsampler `json:"downsampler"`
 
 	// The upsample function
 	Upsampler mathexp.Upsampler `json:"upsampler"`
+
+	// The alignment of the resampled points, defaults to the start of the time range
+	Alignment mathexp.ResampleAlignment `json:"alignment,omitempty"`
+
+	// The longest time a value is carried forward by the pad upsampler, or the longest gap interpolated by the linear upsampler
+	MaxStaleness string `json:"maxStaleness,omitempty" jsonschema:"example=5m"`
 }
 
 type ThresholdQuery struct {
@@ -70,6 +79,29 @@ type ThresholdQuery struct {
 	Conditions []ThresholdConditionJSON `json:"conditions"`
 }
 
+// QueryType = anomaly
+type AnomalyQuery struct {
+	// Reference to single query result
+	Expression string `json:"expression" jsonschema:"minLength=1,example=$A"`
+
+	// The detection algorithm
+	Algorithm mathexp.AnomalyAlgorithm `json:"algorithm"`
+
+	// Number of deviations from the expected value at which the bands are placed (default 3)
+	Sensitivity float64 `json:"sensitivity,omitempty"`
+
+	// Trailing window used by mad and zscore. The whole series is used if empty
+	Window string `json:"window,omitempty" jsonschema:"example=1h"`
+
+	// Season length used by holt_winters and stl
+	Season string `json:"season,omitempty" jsonschema:"example=1d,example=1w"`
+
+	// Holt-Winters smoothing factors of level, trend and season
+	Alpha float64 `json:"alpha,omitempty"`
+	Beta  float64 `json:"beta,omitempty"`
+	Gamma float64 `json:"gamma,omitempty"`
+}
+
 type ClassicQuery struct {
 	Conditions []classic.ConditionJSON `json:"conditions"`
 }
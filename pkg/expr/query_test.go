@@ -24,8 +24,11 @@ func TestQueryTypeDefinitions(t *testing.T) {
 			Enums: []reflect.Type{
 				reflect.TypeOf(mathexp.ReducerSum),   // pick an example value (not the root)
 				reflect.TypeOf(mathexp.UpsamplerPad), // pick an example value (not the root)
-				reflect.TypeOf(ReduceModeDrop),       // pick an example value (not the root)
+				reflect.TypeOf(mathexp.DownsamplerSum),
+				reflect.TypeOf(mathexp.ResampleAlignStart),
+				reflect.TypeOf(ReduceModeDrop), // pick an example value (not the root)
 				reflect.TypeOf(ThresholdIsAbove),
+				reflect.TypeOf(mathexp.AnomalyAlgorithmMAD), // pick an example value (not the root)
 				reflect.TypeOf(classic.ConditionOperatorAnd),
 			},
 		})
@@ -74,10 +77,36 @@ func TestQueryTypeDefinitions(t *testing.T) {
 					SaveModel: data.AsUnstructured(ResampleQuery{
 						Expression:  "$A",
 						Window:      "1d",
-						Downsampler: mathexp.ReducerLast,
+						Downsampler: mathexp.DownsamplerLast,
 						Upsampler:   mathexp.UpsamplerPad,
 					}),
 				},
+				{
+					Name: "resample to wall-clock minutes",
+					SaveModel: data.AsUnstructured(ResampleQuery{
+						Expression:   "$A",
+						Window:       "1m",
+						Downsampler:  mathexp.DownsamplerRate,
+						Upsampler:    mathexp.UpsamplerLinear,
+						Alignment:    mathexp.ResampleAlignClock,
+						MaxStaleness: "5m",
+					}),
+				},
+			},
+		},
+		schemabuilder.QueryTypeInfo{
+			Discriminators: data.NewDiscriminators("type", QueryTypeAnomaly),
+			GoType:         reflect.TypeOf(&AnomalyQuery{}),
+			Examples: []data.QueryExample{
+				{
+					Name: "week-over-week seasonal anomalies",
+					SaveModel: data.AsUnstructured(AnomalyQuery{
+						Expression:  "$A",
+						Algorithm:   mathexp.AnomalyAlgorithmSTL,
+						Season:      "1w",
+						Sensitivity: 3,
+					}),
+				},
 			},
 		},
 		schemabuilder.QueryTypeInfo{
@@ -114,6 +114,24 @@ func (h *ExpressionQueryReader) ReadQuery(
 				},
 			)
 		}
+		if err == nil {
+			err = eq.Command.(*ResampleCommand).setAlignment(q.Alignment, q.MaxStaleness)
+		}
+
+	case QueryTypeAnomaly:
+		q := &AnomalyQuery{}
+		err = iter.ReadVal(q)
+		if err == nil {
+			referenceVar, err = getReferenceVar(q.Expression, common.RefID)
+		}
+		if err == nil {
+			var opts mathexp.AnomalyOptions
+			opts, err = q.options()
+			if err == nil {
+				eq.Properties = q
+				eq.Command, err = NewAnomalyCommand(common.RefID, referenceVar, opts)
+			}
+		}
 
 	case QueryTypeClassic:
 		q := &ClassicQuery{}
@@ -142,33 +160,11 @@ func (h *ExpressionQueryReader) ReadQuery(
 			if len(q.Conditions) != 1 {
 				return eq, fmt.Errorf("threshold expression requires exactly one condition")
 			}
-			firstCondition := q.Conditions[0]
-
-			threshold, err := NewThresholdCommand(common.RefID, referenceVar, firstCondition.Evaluator.Type, firstCondition.Evaluator.Params)
+			eq.Command, err = newThresholdOrHysteresisCommand(common.RefID, referenceVar, q.Conditions[0], h.features)
 			if err != nil {
-				return eq, fmt.Errorf("invalid condition: %w", err)
+				return eq, err
 			}
-			eq.Command = threshold
 			eq.Properties = q
-
-			if firstCondition.UnloadEvaluator != nil && h.features.IsEnabledGlobally(featuremgmt.FlagRecoveryThreshold) {
-				unloading, err := NewThresholdCommand(common.RefID, referenceVar, firstCondition.UnloadEvaluator.Type, firstCondition.UnloadEvaluator.Params)
-				unloading.Invert = true
-				if err != nil {
-					return eq, fmt.Errorf("invalid unloadCondition: %w", err)
-				}
-				var d Fingerprints
-				if firstCondition.LoadedDimensions != nil {
-					d, err = FingerprintsFromFrame(firstCondition.LoadedDimensions)
-					if err != nil {
-						return eq, fmt.Errorf("failed to parse loaded dimensions: %w", err)
-					}
-				}
-				eq.Command, err = NewHysteresisCommand(common.RefID, referenceVar, *threshold, *unloading, d)
-				if err != nil {
-					return eq, err
-				}
-			}
 		}
 
 	default:
@@ -0,0 +1,71 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/scottlepp/go-duck/duck"
+)
+
+// Limits restricts the resources a single SQL expression can use. Zero values mean unlimited.
+type Limits struct {
+	// MaxInputRows is the maximum number of rows of all tables referenced by the query.
+	MaxInputRows int64
+	// MaxOutputRows is the maximum number of rows the query can return.
+	MaxOutputRows int64
+	// MaxMemoryMB is the memory limit of the engine.
+	MaxMemoryMB int64
+}
+
+// CheckInput returns an error if the tables exceed the input limits.
+func (l Limits) CheckInput(refID string, tables []*data.Frame) error {
+	if l.MaxInputRows <= 0 {
+		return nil
+	}
+	total := int64(0)
+	counts := make([]string, 0, len(tables))
+	for _, t := range tables {
+		total += int64(t.Rows())
+		counts = append(counts, fmt.Sprintf("%s: %d", t.RefID, t.Rows()))
+	}
+	if total > l.MaxInputRows {
+		return fmt.Errorf("[%s] SQL expression input has %d rows (%s), which exceeds the limit of %d rows", refID, total, strings.Join(counts, ", "), l.MaxInputRows)
+	}
+	return nil
+}
+
+// CheckOutput returns an error if the result of the query exceeds the output limits.
+func (l Limits) CheckOutput(refID string, frame *data.Frame) error {
+	if l.MaxOutputRows <= 0 || frame == nil {
+		return nil
+	}
+	if rows := int64(frame.Rows()); rows > l.MaxOutputRows {
+		return fmt.Errorf("[%s] SQL expression returned %d rows, which exceeds the limit of %d rows", refID, rows, l.MaxOutputRows)
+	}
+	return nil
+}
+
+// Query runs the query of the SQL expression refID against the tables. Every table is available under its RefID.
+func Query(refID string, query string, tables []*data.Frame, limits Limits) (*data.Frame, error) {
+	if err := limits.CheckInput(refID, tables); err != nil {
+		return nil, err
+	}
+	if limits.MaxMemoryMB > 0 {
+		query = fmt.Sprintf("SET memory_limit='%dMB';\n%s", limits.MaxMemoryMB, query)
+	}
+
+	db := duck.NewInMemoryDB()
+	frame := &data.Frame{}
+	logger.Debug("Executing query", "refID", refID, "query", query, "tables", len(tables))
+	if err := db.QueryFramesInto(refID, query, tables, frame); err != nil {
+		return nil, fmt.Errorf("[%s] failed to execute SQL expression: %w", refID, err)
+	}
+	logger.Debug("Done executing query", "refID", refID, "rows", frame.Rows())
+
+	if err := limits.CheckOutput(refID, frame); err != nil {
+		return nil, err
+	}
+	frame.RefID = refID
+	return frame, nil
+}
@@ -0,0 +1,268 @@
+package sql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+)
+
+const (
+	// TimeColumn is the name of the column that holds the timestamps of time series in the long format tables.
+	TimeColumn = "time"
+	// ValueColumn is the name of the column that holds the values of time series and numbers in the long format tables.
+	ValueColumn = "value"
+)
+
+// ToLongFrame converts the results of the query or expression refID into a single table in long format,
+// so they can be referenced by a SQL expression as the table refID.
+// Time series produce the columns TimeColumn, one column per label and ValueColumn, with one row per point.
+// Numbers produce the same columns except TimeColumn. Labels missing in some of the series are NULL.
+// Tables are used as they are. If there is no data, it returns an empty table with the columns TimeColumn and
+// ValueColumn, so that the SQL expression still runs and can handle the missing data.
+func ToLongFrame(refID string, values mathexp.Values) (*data.Frame, error) {
+	var series []mathexp.Series
+	var numbers []mathexp.Number
+	for _, v := range values {
+		switch v := v.(type) {
+		case mathexp.Series:
+			series = append(series, v)
+		case mathexp.Number:
+			numbers = append(numbers, v)
+		case mathexp.TableData:
+			if len(values) > 1 {
+				return nil, fmt.Errorf("[%s] cannot use a table together with other results", refID)
+			}
+			if v.Frame == nil {
+				return emptyLongFrame(refID), nil
+			}
+			frame := *v.Frame
+			frame.RefID = refID
+			return &frame, nil
+		case mathexp.NoData:
+			continue
+		default:
+			return nil, fmt.Errorf("[%s] cannot convert results of type %s to a table", refID, v.Type())
+		}
+	}
+	if len(series) > 0 && len(numbers) > 0 {
+		return nil, fmt.Errorf("[%s] cannot convert a mix of time series and numbers to a table", refID)
+	}
+	if len(series) == 0 && len(numbers) == 0 {
+		return emptyLongFrame(refID), nil
+	}
+
+	labeled := make([]mathexp.Value, 0, len(series)+len(numbers))
+	for _, s := range series {
+		labeled = append(labeled, s)
+	}
+	for _, n := range numbers {
+		labeled = append(labeled, n)
+	}
+	labelNames, err := labelColumns(refID, labeled)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := 0
+	for _, s := range series {
+		rows += s.Len()
+	}
+	rows += len(numbers)
+
+	var timeField *data.Field
+	if len(series) > 0 {
+		timeField = data.NewField(TimeColumn, nil, make([]time.Time, 0, rows))
+	}
+	labelFields := make([]*data.Field, 0, len(labelNames))
+	for _, name := range labelNames {
+		labelFields = append(labelFields, data.NewField(name, nil, make([]*string, 0, rows)))
+	}
+	valueField := data.NewField(ValueColumn, nil, make([]*float64, 0, rows))
+
+	appendLabels := func(lbls data.Labels) {
+		for i, name := range labelNames {
+			var value *string
+			if v, ok := lbls[name]; ok {
+				value = &v
+			}
+			labelFields[i].Append(value)
+		}
+	}
+	for _, s := range series {
+		lbls := s.GetLabels()
+		for i := 0; i < s.Len(); i++ {
+			t, v := s.GetPoint(i)
+			timeField.Append(t)
+			appendLabels(lbls)
+			valueField.Append(v)
+		}
+	}
+	for _, n := range numbers {
+		appendLabels(n.GetLabels())
+		valueField.Append(n.GetFloat64Value())
+	}
+
+	frame := data.NewFrame(refID)
+	if timeField != nil {
+		frame.Fields = append(frame.Fields, timeField)
+	}
+	frame.Fields = append(frame.Fields, labelFields...)
+	frame.Fields = append(frame.Fields, valueField)
+	frame.RefID = refID
+	return frame, nil
+}
+
+// labelColumns returns the sorted union of the label names of all values.
+func labelColumns(refID string, values []mathexp.Value) ([]string, error) {
+	names := map[string]struct{}{}
+	for _, v := range values {
+		for name := range v.GetLabels() {
+			if name == TimeColumn || name == ValueColumn {
+				return nil, fmt.Errorf("[%s] label %q conflicts with the column %q of the table", refID, name, name)
+			}
+			names[name] = struct{}{}
+		}
+	}
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func emptyLongFrame(refID string) *data.Frame {
+	frame := data.NewFrame(refID,
+		data.NewField(TimeColumn, nil, []time.Time{}),
+		data.NewField(ValueColumn, nil, []*float64{}),
+	)
+	frame.RefID = refID
+	return frame
+}
+
+// FromFrame converts the result of the SQL expression refID back to expression values.
+// If the frame has a time column, every numeric column is converted to a time series per distinct combination of
+// the string and boolean columns, which become the labels of the series. Without a time column, every row
+// produces a number per numeric column. Frames without numeric columns are returned as a table.
+func FromFrame(refID string, frame *data.Frame) (mathexp.Values, error) {
+	if frame == nil {
+		return mathexp.Values{mathexp.NewNoData()}, nil
+	}
+	if frame.Rows() == 0 {
+		return mathexp.Values{mathexp.NoData{Frame: frame}}, nil
+	}
+
+	timeIdx := -1
+	var valueIdx, labelIdx []int
+	for i, field := range frame.Fields {
+		switch {
+		case field.Type().Time():
+			if timeIdx >= 0 {
+				return nil, fmt.Errorf("[%s] column %q: only one time column is supported but column %q is also a time column", refID, field.Name, frame.Fields[timeIdx].Name)
+			}
+			timeIdx = i
+		case field.Type().Numeric():
+			valueIdx = append(valueIdx, i)
+		case field.Type() == data.FieldTypeString, field.Type() == data.FieldTypeNullableString,
+			field.Type() == data.FieldTypeBool, field.Type() == data.FieldTypeNullableBool:
+			labelIdx = append(labelIdx, i)
+		default:
+			return nil, fmt.Errorf("[%s] column %q has unsupported type %s", refID, field.Name, field.Type().ItemTypeString())
+		}
+	}
+	if len(valueIdx) == 0 {
+		return mathexp.Values{mathexp.TableData{Frame: frame}}, nil
+	}
+
+	// group rows by their labels, keeping the order in which the groups first appear.
+	var groups []data.Labels
+	rowsByGroup := map[data.Fingerprint][]int{}
+	for row := 0; row < frame.Rows(); row++ {
+		lbls, err := rowLabels(frame, labelIdx, row)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] %w", refID, err)
+		}
+		fp := lbls.Fingerprint()
+		if _, ok := rowsByGroup[fp]; !ok {
+			groups = append(groups, lbls)
+		}
+		rowsByGroup[fp] = append(rowsByGroup[fp], row)
+	}
+
+	values := make(mathexp.Values, 0, len(groups)*len(valueIdx))
+	for _, idx := range valueIdx {
+		field := frame.Fields[idx]
+		for _, lbls := range groups {
+			rows := rowsByGroup[lbls.Fingerprint()]
+			if timeIdx < 0 {
+				if len(rows) > 1 {
+					return nil, fmt.Errorf("[%s] column %q: rows %d and %d have the same labels %s, use GROUP BY or add a time column", refID, field.Name, rows[0], rows[1], lbls)
+				}
+				v, err := field.NullableFloatAt(rows[0])
+				if err != nil {
+					return nil, fmt.Errorf("[%s] column %q, row %d: %w", refID, field.Name, rows[0], err)
+				}
+				n := mathexp.NewNumber(field.Name, lbls.Copy())
+				n.SetValue(v)
+				values = append(values, n)
+				continue
+			}
+			s, err := seriesFromRows(frame, timeIdx, idx, rows)
+			if err != nil {
+				return nil, fmt.Errorf("[%s] %w", refID, err)
+			}
+			s.SetLabels(lbls.Copy())
+			values = append(values, s)
+		}
+	}
+	return values, nil
+}
+
+func seriesFromRows(frame *data.Frame, timeIdx, valueIdx int, rows []int) (mathexp.Series, error) {
+	timeField, valueField := frame.Fields[timeIdx], frame.Fields[valueIdx]
+	s := mathexp.NewSeries(valueField.Name, nil, len(rows))
+	seen := make(map[int64]int, len(rows))
+	for i, row := range rows {
+		t, ok := timeField.ConcreteAt(row)
+		if !ok {
+			return s, fmt.Errorf("column %q, row %d: time must not be NULL", timeField.Name, row)
+		}
+		ts := t.(time.Time)
+		if prev, ok := seen[ts.UnixNano()]; ok {
+			return s, fmt.Errorf("column %q: rows %d and %d have the same time and labels, use GROUP BY to aggregate them", valueField.Name, prev, row)
+		}
+		seen[ts.UnixNano()] = row
+		v, err := valueField.NullableFloatAt(row)
+		if err != nil {
+			return s, fmt.Errorf("column %q, row %d: %w", valueField.Name, row, err)
+		}
+		s.SetPoint(i, ts, v)
+	}
+	s.SortByTime(false)
+	return s, nil
+}
+
+func rowLabels(frame *data.Frame, labelIdx []int, row int) (data.Labels, error) {
+	lbls := make(data.Labels, len(labelIdx))
+	for _, idx := range labelIdx {
+		field := frame.Fields[idx]
+		v, ok := field.ConcreteAt(row)
+		if !ok {
+			continue
+		}
+		switch v := v.(type) {
+		case string:
+			lbls[field.Name] = v
+		case bool:
+			lbls[field.Name] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("column %q, row %d: unexpected label value of type %T", field.Name, row, v)
+		}
+	}
+	return lbls, nil
+}
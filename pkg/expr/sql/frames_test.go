@@ -0,0 +1,207 @@
+package sql
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func TestToLongFrame(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	series := func(lbls data.Labels, values ...float64) mathexp.Series {
+		s := mathexp.NewSeries("A", lbls, len(values))
+		for i, v := range values {
+			s.SetPoint(i, start.Add(time.Duration(i)*time.Minute), util.Pointer(v))
+		}
+		return s
+	}
+	number := func(lbls data.Labels, v float64) mathexp.Number {
+		n := mathexp.NewNumber("A", lbls)
+		n.SetValue(util.Pointer(v))
+		return n
+	}
+
+	t.Run("should convert time series to long format", func(t *testing.T) {
+		frame, err := ToLongFrame("A", mathexp.Values{
+			series(data.Labels{"host": "a"}, 1, 2),
+			series(data.Labels{"host": "b", "dc": "eu"}, 3),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "A", frame.RefID)
+		require.Equal(t, data.NewFrame("A",
+			data.NewField(TimeColumn, nil, []time.Time{start, start.Add(time.Minute), start}),
+			data.NewField("dc", nil, []*string{nil, nil, util.Pointer("eu")}),
+			data.NewField("host", nil, []*string{util.Pointer("a"), util.Pointer("a"), util.Pointer("b")}),
+			data.NewField(ValueColumn, nil, []*float64{util.Pointer(1.0), util.Pointer(2.0), util.Pointer(3.0)}),
+		).Fields, frame.Fields)
+	})
+
+	t.Run("should convert numbers to long format without time", func(t *testing.T) {
+		frame, err := ToLongFrame("A", mathexp.Values{number(data.Labels{"host": "a"}, 1), number(nil, 2)})
+		require.NoError(t, err)
+		require.Equal(t, data.NewFrame("A",
+			data.NewField("host", nil, []*string{util.Pointer("a"), nil}),
+			data.NewField(ValueColumn, nil, []*float64{util.Pointer(1.0), util.Pointer(2.0)}),
+		).Fields, frame.Fields)
+	})
+
+	t.Run("should use tables as they are", func(t *testing.T) {
+		table := data.NewFrame("", data.NewField("name", nil, []string{"a"}))
+		frame, err := ToLongFrame("A", mathexp.Values{mathexp.TableData{Frame: table}})
+		require.NoError(t, err)
+		require.Equal(t, "A", frame.RefID)
+		require.Equal(t, table.Fields, frame.Fields)
+		require.Empty(t, table.RefID)
+	})
+
+	t.Run("should return an empty table for no data", func(t *testing.T) {
+		for _, values := range []mathexp.Values{{mathexp.NewNoData()}, {mathexp.TableData{}}, {}} {
+			frame, err := ToLongFrame("A", values)
+			require.NoError(t, err)
+			require.Equal(t, "A", frame.RefID)
+			require.Equal(t, 0, frame.Rows())
+			require.Len(t, frame.Fields, 2)
+			require.Equal(t, TimeColumn, frame.Fields[0].Name)
+			require.Equal(t, ValueColumn, frame.Fields[1].Name)
+		}
+	})
+
+	t.Run("should fail if labels conflict with columns", func(t *testing.T) {
+		_, err := ToLongFrame("A", mathexp.Values{series(data.Labels{"value": "a"}, 1)})
+		require.ErrorContains(t, err, `[A] label "value" conflicts`)
+	})
+
+	t.Run("should fail for a mix of series and numbers", func(t *testing.T) {
+		_, err := ToLongFrame("A", mathexp.Values{series(nil, 1), number(nil, 1)})
+		require.ErrorContains(t, err, "[A] cannot convert a mix")
+	})
+}
+
+func TestFromFrame(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should convert rows with time to series", func(t *testing.T) {
+		frame := data.NewFrame("",
+			data.NewField("time", nil, []time.Time{start.Add(time.Minute), start, start}),
+			data.NewField("host", nil, []*string{util.Pointer("a"), util.Pointer("a"), util.Pointer("b")}),
+			data.NewField("avg", nil, []float64{2, 1, 3}),
+		)
+		values, err := FromFrame("B", frame)
+		require.NoError(t, err)
+		require.Len(t, values, 2)
+
+		a, ok := values[0].(mathexp.Series)
+		require.True(t, ok)
+		require.Equal(t, data.Labels{"host": "a"}, a.GetLabels())
+		require.Equal(t, "avg", a.GetName())
+		require.Equal(t, 2, a.Len())
+		require.Equal(t, start, a.GetTime(0))
+		require.Equal(t, util.Pointer(1.0), a.GetValue(0))
+		require.Equal(t, util.Pointer(2.0), a.GetValue(1))
+
+		b, ok := values[1].(mathexp.Series)
+		require.True(t, ok)
+		require.Equal(t, data.Labels{"host": "b"}, b.GetLabels())
+		require.Equal(t, util.Pointer(3.0), b.GetValue(0))
+	})
+
+	t.Run("should convert rows without time to numbers", func(t *testing.T) {
+		frame := data.NewFrame("",
+			data.NewField("host", nil, []string{"a", "b"}),
+			data.NewField("up", nil, []bool{true, false}),
+			data.NewField("cnt", nil, []*int64{util.Pointer(int64(5)), nil}),
+		)
+		values, err := FromFrame("B", frame)
+		require.NoError(t, err)
+		require.Len(t, values, 2)
+		n, ok := values[0].(mathexp.Number)
+		require.True(t, ok)
+		require.Equal(t, data.Labels{"host": "a", "up": "true"}, n.GetLabels())
+		require.Equal(t, util.Pointer(5.0), n.GetFloat64Value())
+		n, ok = values[1].(mathexp.Number)
+		require.True(t, ok)
+		require.Equal(t, data.Labels{"host": "b", "up": "false"}, n.GetLabels())
+		require.Nil(t, n.GetFloat64Value())
+	})
+
+	t.Run("should return tables without numeric columns as they are", func(t *testing.T) {
+		frame := data.NewFrame("", data.NewField("host", nil, []string{"a"}))
+		values, err := FromFrame("B", frame)
+		require.NoError(t, err)
+		require.Equal(t, mathexp.Values{mathexp.TableData{Frame: frame}}, values)
+	})
+
+	t.Run("should return no data for empty results", func(t *testing.T) {
+		values, err := FromFrame("B", data.NewFrame("", data.NewField("value", nil, []float64{})))
+		require.NoError(t, err)
+		require.Equal(t, mathexp.Values{mathexp.NoData{Frame: data.NewFrame("", data.NewField("value", nil, []float64{}))}}, values)
+	})
+
+	t.Run("should fail for duplicate labels without time", func(t *testing.T) {
+		frame := data.NewFrame("",
+			data.NewField("host", nil, []string{"a", "a"}),
+			data.NewField("value", nil, []float64{1, 2}),
+		)
+		_, err := FromFrame("B", frame)
+		require.ErrorContains(t, err, `[B] column "value": rows 0 and 1 have the same labels`)
+	})
+
+	t.Run("should fail for duplicate time and labels", func(t *testing.T) {
+		frame := data.NewFrame("",
+			data.NewField("time", nil, []time.Time{start, start}),
+			data.NewField("value", nil, []float64{1, 2}),
+		)
+		_, err := FromFrame("B", frame)
+		require.ErrorContains(t, err, `[B] column "value": rows 0 and 1 have the same time`)
+	})
+
+	t.Run("should fail for multiple time columns", func(t *testing.T) {
+		frame := data.NewFrame("",
+			data.NewField("time", nil, []time.Time{start}),
+			data.NewField("other", nil, []time.Time{start}),
+			data.NewField("value", nil, []float64{1}),
+		)
+		_, err := FromFrame("B", frame)
+		require.ErrorContains(t, err, `[B] column "other": only one time column`)
+	})
+
+	t.Run("should fail for unsupported column types", func(t *testing.T) {
+		frame := data.NewFrame("",
+			data.NewField("raw", nil, []json.RawMessage{json.RawMessage(`{}`)}),
+			data.NewField("value", nil, []float64{1}),
+		)
+		_, err := FromFrame("B", frame)
+		require.ErrorContains(t, err, `[B] column "raw" has unsupported type`)
+	})
+}
+
+func TestLimits(t *testing.T) {
+	table := func(refID string, rows int) *data.Frame {
+		f := data.NewFrame("", data.NewField("value", nil, make([]float64, rows)))
+		f.RefID = refID
+		return f
+	}
+
+	t.Run("should fail if input has too many rows", func(t *testing.T) {
+		err := Limits{MaxInputRows: 10}.CheckInput("C", []*data.Frame{table("A", 6), table("B", 5)})
+		require.EqualError(t, err, "[C] SQL expression input has 11 rows (A: 6, B: 5), which exceeds the limit of 10 rows")
+		require.NoError(t, Limits{MaxInputRows: 11}.CheckInput("C", []*data.Frame{table("A", 6), table("B", 5)}))
+	})
+
+	t.Run("should fail if output has too many rows", func(t *testing.T) {
+		err := Limits{MaxOutputRows: 1}.CheckOutput("C", table("C", 2))
+		require.EqualError(t, err, "[C] SQL expression returned 2 rows, which exceeds the limit of 1 rows")
+	})
+
+	t.Run("zero limits are unlimited", func(t *testing.T) {
+		require.NoError(t, Limits{}.CheckInput("C", []*data.Frame{table("A", 1000)}))
+		require.NoError(t, Limits{}.CheckOutput("C", table("C", 1000)))
+	})
+}
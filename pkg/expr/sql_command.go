@@ -7,19 +7,24 @@ import (
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
-	"github.com/scottlepp/go-duck/duck"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/grafana/grafana/pkg/apimachinery/errutil"
 	"github.com/grafana/grafana/pkg/expr/mathexp"
 	"github.com/grafana/grafana/pkg/expr/sql"
 	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
-// SQLCommand is an expression to run SQL over results
+// SQLCommand is an expression to run SQL over results.
+// The results of every referenced query or expression are available as a table named after its refID.
+// Time series and numbers are converted to tables in long format (see sql.ToLongFrame), and the result
+// of the query is converted back to time series or numbers when it has numeric columns (see sql.FromFrame).
 type SQLCommand struct {
 	query       string
 	varsToQuery []string
 	refID       string
+	limits      sql.Limits
 }
 
 // NewSQLCommand creates a new SQLCommand.
@@ -48,8 +53,20 @@ func NewSQLCommand(refID, rawSQL string) (*SQLCommand, error) {
 	}, nil
 }
 
+// sqlLimits returns the limits of SQL expressions configured in the [expressions] section.
+func sqlLimits(cfg *setting.Cfg) sql.Limits {
+	if cfg == nil {
+		return sql.Limits{}
+	}
+	return sql.Limits{
+		MaxInputRows:  cfg.SQLExpressionMaxInputRows,
+		MaxOutputRows: cfg.SQLExpressionMaxOutputRows,
+		MaxMemoryMB:   cfg.SQLExpressionMaxMemoryMB,
+	}
+}
+
 // UnmarshalSQLCommand creates a SQLCommand from Grafana's frontend query.
-func UnmarshalSQLCommand(rn *rawNode) (*SQLCommand, error) {
+func UnmarshalSQLCommand(rn *rawNode, limits sql.Limits) (*SQLCommand, error) {
 	if rn.TimeRange == nil {
 		logger.Error("time range must be specified for refID", "refID", rn.RefID)
 		return nil, fmt.Errorf("time range must be specified for refID %s", rn.RefID)
@@ -66,7 +83,12 @@ func UnmarshalSQLCommand(rn *rawNode) (*SQLCommand, error) {
 		return nil, fmt.Errorf("expected sql expression to be type string, but got type %T", expressionRaw)
 	}
 
-	return NewSQLCommand(rn.RefID, expression)
+	cmd, err := NewSQLCommand(rn.RefID, expression)
+	if err != nil {
+		return nil, err
+	}
+	cmd.limits = limits
+	return cmd, nil
 }
 
 // NeedsVars returns the variable names (refIds) that are dependencies
@@ -81,43 +103,36 @@ func (gr *SQLCommand) Execute(ctx context.Context, now time.Time, vars mathexp.V
 	_, span := tracer.Start(ctx, "SSE.ExecuteSQL")
 	defer span.End()
 
-	allFrames := []*data.Frame{}
+	rsp := mathexp.Results{}
+
+	tables := make([]*data.Frame, 0, len(gr.varsToQuery))
 	for _, ref := range gr.varsToQuery {
 		results, ok := vars[ref]
 		if !ok {
-			logger.Warn("no results found for", "ref", ref)
+			// not a refID, e.g. a common table expression
+			logger.Debug("no results found for table", "ref", ref)
 			continue
 		}
-		frames := results.Values.AsDataFrames(ref)
-		allFrames = append(allFrames, frames...)
+		table, err := sql.ToLongFrame(ref, results.Values)
+		if err != nil {
+			rsp.Error = err
+			return rsp, nil
+		}
+		tables = append(tables, table)
 	}
 
-	rsp := mathexp.Results{}
-
-	duckDB := duck.NewInMemoryDB()
-	var frame = &data.Frame{}
-
-	logger.Debug("Executing query", "query", gr.query, "frames", len(allFrames))
-	err := duckDB.QueryFramesInto(gr.refID, gr.query, allFrames, frame)
+	frame, err := sql.Query(gr.refID, gr.query, tables, gr.limits)
 	if err != nil {
 		logger.Error("Failed to query frames", "error", err.Error())
 		rsp.Error = err
 		return rsp, nil
 	}
-	logger.Debug("Done Executing query", "query", gr.query, "rows", frame.Rows())
-
-	frame.RefID = gr.refID
+	span.SetAttributes(attribute.Int("rows", frame.Rows()))
 
-	if frame.Rows() == 0 {
-		rsp.Values = mathexp.Values{
-			mathexp.NoData{Frame: frame},
-		}
-	}
-
-	rsp.Values = mathexp.Values{
-		mathexp.TableData{Frame: frame},
+	rsp.Values, err = sql.FromFrame(gr.refID, frame)
+	if err != nil {
+		rsp.Error = err
 	}
-
 	return rsp, nil
 }
 
@@ -1,8 +1,18 @@
 package expr
 
 import (
+	"context"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/expr/sql"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 func TestNewCommand(t *testing.T) {
@@ -25,3 +35,48 @@ func TestNewCommand(t *testing.T) {
 		return
 	}
 }
+
+func TestSQLCommandExecute(t *testing.T) {
+	tracer := tracing.InitializeTracerForTest()
+	cmd := &SQLCommand{query: "SELECT * FROM A JOIN B USING (host)", varsToQuery: []string{"A", "B"}, refID: "C"}
+
+	number := func(host string, v float64) mathexp.Number {
+		n := mathexp.NewNumber("A", data.Labels{"host": host})
+		n.SetValue(&v)
+		return n
+	}
+
+	t.Run("should pass an empty table if a referenced table has no data", func(t *testing.T) {
+		// the input limit fails the command before the query runs, and its error lists the input tables.
+		limited := *cmd
+		limited.limits = sql.Limits{MaxInputRows: 1}
+		vars := mathexp.Vars{
+			"A": mathexp.Results{Values: mathexp.Values{number("a", 1), number("b", 2)}},
+			"B": mathexp.Results{Values: mathexp.Values{mathexp.NewNoData()}},
+		}
+		res, err := limited.Execute(context.Background(), time.Now(), vars, tracer)
+		require.NoError(t, err)
+		require.ErrorContains(t, res.Error, "[C] SQL expression input has 2 rows (A: 2, B: 0)")
+	})
+
+	t.Run("should fail if inputs exceed the limits", func(t *testing.T) {
+		limited := *cmd
+		limited.limits = sql.Limits{MaxInputRows: 1}
+		vars := mathexp.Vars{
+			"A": mathexp.Results{Values: mathexp.Values{number("a", 1)}},
+			"B": mathexp.Results{Values: mathexp.Values{number("a", 2)}},
+		}
+		res, err := limited.Execute(context.Background(), time.Now(), vars, tracer)
+		require.NoError(t, err)
+		require.ErrorContains(t, res.Error, "[C] SQL expression input has 2 rows (A: 1, B: 1)")
+	})
+}
+
+func TestSQLLimits(t *testing.T) {
+	require.Equal(t, sql.Limits{}, sqlLimits(nil))
+	require.Equal(t, sql.Limits{MaxInputRows: 1, MaxOutputRows: 2, MaxMemoryMB: 3}, sqlLimits(&setting.Cfg{
+		SQLExpressionMaxInputRows:  1,
+		SQLExpressionMaxOutputRows: 2,
+		SQLExpressionMaxMemoryMB:   3,
+	}))
+}
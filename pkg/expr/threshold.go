@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 
 	"github.com/grafana/grafana/pkg/expr/mathexp"
@@ -26,6 +27,8 @@ type ThresholdCommand struct {
 	ThresholdFunc ThresholdType
 	Invert        bool
 	predicate     predicate
+	// baseline is set for threshold functions that compare the value of a series to its own past values.
+	baseline *baselineDeviation
 }
 
 // +enum
@@ -36,6 +39,14 @@ const (
 	ThresholdIsBelow        ThresholdType = "lt"
 	ThresholdIsWithinRange  ThresholdType = "within_range"
 	ThresholdIsOutsideRange ThresholdType = "outside_range"
+	// ThresholdSeasonalIsAbove and ThresholdSeasonalIsBelow compare the difference between the last value of a series
+	// and the baseline, i.e. the value of the same series one or more periods ago.
+	ThresholdSeasonalIsAbove ThresholdType = "seasonal_gt"
+	ThresholdSeasonalIsBelow ThresholdType = "seasonal_lt"
+	// ThresholdPercentChangeIsAbove and ThresholdPercentChangeIsBelow compare the change of the last value of a series
+	// relative to the baseline, in percent.
+	ThresholdPercentChangeIsAbove ThresholdType = "pct_change_gt"
+	ThresholdPercentChangeIsBelow ThresholdType = "pct_change_lt"
 )
 
 var (
@@ -44,11 +55,33 @@ var (
 		string(ThresholdIsBelow),
 		string(ThresholdIsWithinRange),
 		string(ThresholdIsOutsideRange),
+		string(ThresholdSeasonalIsAbove),
+		string(ThresholdSeasonalIsBelow),
+		string(ThresholdPercentChangeIsAbove),
+		string(ThresholdPercentChangeIsBelow),
 	}
 )
 
+// Baseline configures how the baseline of seasonal and percentage change threshold functions is computed.
+type Baseline struct {
+	// Period is the length of the season, for example, one week for week-over-week comparison.
+	// It is required by seasonal threshold functions. If it is zero, percentage change is computed relative to the first value of the series.
+	Period time.Duration
+	// Periods is the number of past periods whose values are averaged into the baseline. Defaults to 1.
+	Periods int
+	// Tolerance is how far before the offset time a point can be to be used as the baseline.
+	// Defaults to the interval between the last two points of the series.
+	Tolerance time.Duration
+}
+
 func NewThresholdCommand(refID, referenceVar string, thresholdFunc ThresholdType, conditions []float64) (*ThresholdCommand, error) {
+	return NewThresholdCommandWithBaseline(refID, referenceVar, thresholdFunc, conditions, Baseline{})
+}
+
+// NewThresholdCommandWithBaseline creates a ThresholdCommand. The baseline is used only by seasonal and percentage change threshold functions.
+func NewThresholdCommandWithBaseline(refID, referenceVar string, thresholdFunc ThresholdType, conditions []float64, baseline Baseline) (*ThresholdCommand, error) {
 	var predicate predicate
+	var deviation *baselineDeviation
 	switch thresholdFunc {
 	case ThresholdIsOutsideRange:
 		if len(conditions) < 2 {
@@ -70,6 +103,20 @@ func NewThresholdCommand(refID, referenceVar string, thresholdFunc ThresholdType
 			return nil, fmt.Errorf("incorrect number of arguments for threshold function '%s': got %d but need 1", thresholdFunc, len(conditions))
 		}
 		predicate = lessThanPredicate{value: conditions[0]}
+	case ThresholdSeasonalIsAbove, ThresholdSeasonalIsBelow, ThresholdPercentChangeIsAbove, ThresholdPercentChangeIsBelow:
+		if len(conditions) < 1 {
+			return nil, fmt.Errorf("incorrect number of arguments for threshold function '%s': got %d but need 1", thresholdFunc, len(conditions))
+		}
+		var err error
+		deviation, err = newBaselineDeviation(thresholdFunc, baseline)
+		if err != nil {
+			return nil, err
+		}
+		if thresholdFunc == ThresholdSeasonalIsAbove || thresholdFunc == ThresholdPercentChangeIsAbove {
+			predicate = greaterThanPredicate{value: conditions[0]}
+		} else {
+			predicate = lessThanPredicate{value: conditions[0]}
+		}
 	default:
 		return nil, fmt.Errorf("expected threshold function to be one of [%s], got %s", strings.Join(supportedThresholdFuncs, ", "), thresholdFunc)
 	}
@@ -79,12 +126,74 @@ func NewThresholdCommand(refID, referenceVar string, thresholdFunc ThresholdType
 		ReferenceVar:  referenceVar,
 		ThresholdFunc: thresholdFunc,
 		predicate:     predicate,
+		baseline:      deviation,
 	}, nil
 }
 
 type ConditionEvalJSON struct {
 	Params []float64     `json:"params"`
 	Type   ThresholdType `json:"type"` // e.g. "gt"
+	// Baseline is used by seasonal and percentage change threshold functions.
+	Baseline *ThresholdBaselineJSON `json:"baseline,omitempty"`
+}
+
+type ThresholdBaselineJSON struct {
+	// Period is the length of the season, e.g. "1w"
+	Period string `json:"period,omitempty"`
+	// Periods is the number of past periods that are averaged into the baseline. Defaults to 1.
+	Periods int `json:"periods,omitempty"`
+	// Tolerance is how far before the offset time a point can be to be used as the baseline, e.g. "5m"
+	Tolerance string `json:"tolerance,omitempty"`
+}
+
+// newThresholdCommandFromJSON creates a ThresholdCommand from the evaluator of a threshold condition.
+func newThresholdCommandFromJSON(refID, referenceVar string, evaluator ConditionEvalJSON) (*ThresholdCommand, error) {
+	var baseline Baseline
+	if evaluator.Baseline != nil {
+		var err error
+		if evaluator.Baseline.Period != "" {
+			baseline.Period, err = gtime.ParseDuration(evaluator.Baseline.Period)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse baseline period: %w", err)
+			}
+		}
+		if evaluator.Baseline.Tolerance != "" {
+			baseline.Tolerance, err = gtime.ParseDuration(evaluator.Baseline.Tolerance)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse baseline tolerance: %w", err)
+			}
+		}
+		baseline.Periods = evaluator.Baseline.Periods
+	}
+	return NewThresholdCommandWithBaseline(refID, referenceVar, evaluator.Type, evaluator.Params, baseline)
+}
+
+// newThresholdOrHysteresisCommand creates a ThresholdCommand from the first condition, or a HysteresisCommand if the condition has an unload evaluator.
+func newThresholdOrHysteresisCommand(refID, referenceVar string, condition ThresholdConditionJSON, features featuremgmt.FeatureToggles) (Command, error) {
+	threshold, err := newThresholdCommandFromJSON(refID, referenceVar, condition.Evaluator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition: %w", err)
+	}
+	if condition.UnloadEvaluator == nil || !features.IsEnabledGlobally(featuremgmt.FlagRecoveryThreshold) {
+		return threshold, nil
+	}
+	unloading, err := newThresholdCommandFromJSON(refID, referenceVar, *condition.UnloadEvaluator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid unloadCondition: %w", err)
+	}
+	// Both thresholds must produce the same kind of result, otherwise results of loaded and unloaded dimensions cannot be combined.
+	if (threshold.baseline == nil) != (unloading.baseline == nil) {
+		return nil, fmt.Errorf("invalid unloadCondition: threshold function '%s' cannot be used together with '%s'", unloading.ThresholdFunc, threshold.ThresholdFunc)
+	}
+	unloading.Invert = true
+	var d Fingerprints
+	if condition.LoadedDimensions != nil {
+		d, err = FingerprintsFromFrame(condition.LoadedDimensions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse loaded dimensions: %w", err)
+		}
+	}
+	return NewHysteresisCommand(refID, referenceVar, *threshold, *unloading, d)
 }
 
 // UnmarshalResampleCommand creates a ResampleCMD from Grafana's frontend query.
@@ -102,28 +211,7 @@ func UnmarshalThresholdCommand(rn *rawNode, features featuremgmt.FeatureToggles)
 	if len(cmdConfig.Conditions) != 1 {
 		return nil, fmt.Errorf("threshold expression requires exactly one condition")
 	}
-	firstCondition := cmdConfig.Conditions[0]
-
-	threshold, err := NewThresholdCommand(rn.RefID, referenceVar, firstCondition.Evaluator.Type, firstCondition.Evaluator.Params)
-	if err != nil {
-		return nil, fmt.Errorf("invalid condition: %w", err)
-	}
-	if firstCondition.UnloadEvaluator != nil && features.IsEnabledGlobally(featuremgmt.FlagRecoveryThreshold) {
-		unloading, err := NewThresholdCommand(rn.RefID, referenceVar, firstCondition.UnloadEvaluator.Type, firstCondition.UnloadEvaluator.Params)
-		if err != nil {
-			return nil, fmt.Errorf("invalid unloadCondition: %w", err)
-		}
-		unloading.Invert = true
-		var d Fingerprints
-		if firstCondition.LoadedDimensions != nil {
-			d, err = FingerprintsFromFrame(firstCondition.LoadedDimensions)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse loaded dimensions: %w", err)
-			}
-		}
-		return NewHysteresisCommand(rn.RefID, referenceVar, *threshold, *unloading, d)
-	}
-	return threshold, nil
+	return newThresholdOrHysteresisCommand(rn.RefID, referenceVar, cmdConfig.Conditions[0], features)
 }
 
 // NeedsVars returns the variable names (refIds) that are dependencies
@@ -150,6 +238,19 @@ func (tc *ThresholdCommand) Execute(_ context.Context, _ time.Time, vars mathexp
 	refVarResult := vars[tc.ReferenceVar]
 	newRes := mathexp.Results{Values: make(mathexp.Values, 0, len(refVarResult.Values))}
 	for _, val := range refVarResult.Values {
+		if tc.baseline != nil {
+			switch v := val.(type) {
+			case mathexp.Series:
+				n := mathexp.NewNumber(tc.RefID, v.GetLabels())
+				n.SetValue(eval(tc.baseline.compute(v)))
+				newRes.Values = append(newRes.Values, n)
+			case mathexp.NoData:
+				newRes.Values = append(newRes.Values, mathexp.NewNoData())
+			default:
+				return newRes, fmt.Errorf("threshold function '%s' requires time series as input, got type %v", tc.ThresholdFunc, val.Type())
+			}
+			continue
+		}
 		switch v := val.(type) {
 		case mathexp.Series:
 			s := mathexp.NewSeries(tc.RefID, v.GetLabels(), v.Len())
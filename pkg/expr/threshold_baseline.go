@@ -0,0 +1,111 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+)
+
+// baselineDeviation computes how much the last value of a series deviates from its baseline,
+// either as an absolute difference (seasonal threshold functions) or in percent (percentage change threshold functions).
+type baselineDeviation struct {
+	Baseline
+	percent bool
+}
+
+func newBaselineDeviation(thresholdFunc ThresholdType, b Baseline) (*baselineDeviation, error) {
+	if b.Period < 0 {
+		return nil, fmt.Errorf("baseline period must not be negative")
+	}
+	if b.Tolerance < 0 {
+		return nil, fmt.Errorf("baseline tolerance must not be negative")
+	}
+	if b.Periods < 0 {
+		return nil, fmt.Errorf("number of baseline periods must not be negative")
+	}
+	if b.Periods == 0 {
+		b.Periods = 1
+	}
+	percent := thresholdFunc == ThresholdPercentChangeIsAbove || thresholdFunc == ThresholdPercentChangeIsBelow
+	if !percent && b.Period == 0 {
+		return nil, fmt.Errorf("threshold function '%s' requires baseline period", thresholdFunc)
+	}
+	return &baselineDeviation{Baseline: b, percent: percent}, nil
+}
+
+type point struct {
+	t time.Time
+	v float64
+}
+
+// compute returns the deviation of the last value of the series from the baseline,
+// or nil if the series has no values or there are no values to compute the baseline from.
+func (d *baselineDeviation) compute(s mathexp.Series) *float64 {
+	points := make([]point, 0, s.Len())
+	for i := 0; i < s.Len(); i++ {
+		t, v := s.GetPoint(i)
+		if v == nil || math.IsNaN(*v) {
+			continue
+		}
+		points = append(points, point{t: t, v: *v})
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].t.Before(points[j].t) })
+
+	last := points[len(points)-1]
+	baseline, ok := d.baselineValue(points)
+	if !ok {
+		return nil
+	}
+
+	result := last.v - baseline
+	if d.percent {
+		switch {
+		case baseline != 0:
+			result = result / math.Abs(baseline) * 100
+		case result > 0:
+			result = math.Inf(1)
+		case result < 0:
+			result = math.Inf(-1)
+		}
+	}
+	return &result
+}
+
+// baselineValue returns the average of the values found one or more periods before the last point.
+// If the period is not set, the first point of the series is the baseline.
+func (d *baselineDeviation) baselineValue(points []point) (float64, bool) {
+	last := points[len(points)-1]
+	if d.Period == 0 {
+		if len(points) < 2 {
+			return 0, false
+		}
+		return points[0].v, true
+	}
+
+	tolerance := d.Tolerance
+	if tolerance == 0 && len(points) > 1 {
+		tolerance = last.t.Sub(points[len(points)-2].t)
+	}
+
+	sum, count := 0.0, 0
+	for k := 1; k <= d.Periods; k++ {
+		target := last.t.Add(-time.Duration(k) * d.Period)
+		// Find the last point at or before the target.
+		idx := sort.Search(len(points), func(i int) bool { return points[i].t.After(target) }) - 1
+		if idx < 0 || target.Sub(points[idx].t) > tolerance {
+			continue
+		}
+		sum += points[idx].v
+		count++
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return sum / float64(count), true
+}
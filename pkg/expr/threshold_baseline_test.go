@@ -0,0 +1,211 @@
+package expr
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func TestBaselineDeviation(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	// series creates a series with a point every hour, ending at now.
+	series := func(values ...*float64) mathexp.Series {
+		s := mathexp.NewSeries("A", nil, len(values))
+		for i, v := range values {
+			s.SetPoint(i, now.Add(-time.Duration(len(values)-1-i)*time.Hour), v)
+		}
+		return s
+	}
+	// daily creates a series with the provided value at the same time of every day, ending at now.
+	daily := func(values ...float64) mathexp.Series {
+		s := mathexp.NewSeries("A", nil, len(values))
+		for i, v := range values {
+			s.SetPoint(i, now.Add(-time.Duration(len(values)-1-i)*day), util.Pointer(v))
+		}
+		return s
+	}
+
+	testCases := []struct {
+		name      string
+		fn        ThresholdType
+		baseline  Baseline
+		input     mathexp.Series
+		expected  *float64
+		expectErr string
+	}{
+		{
+			name:     "seasonal difference to the previous period",
+			fn:       ThresholdSeasonalIsAbove,
+			baseline: Baseline{Period: day},
+			input:    daily(10, 20, 50),
+			expected: util.Pointer(30.0),
+		},
+		{
+			name:     "seasonal difference to the average of several periods",
+			fn:       ThresholdSeasonalIsAbove,
+			baseline: Baseline{Period: day, Periods: 2},
+			input:    daily(10, 20, 50),
+			expected: util.Pointer(35.0),
+		},
+		{
+			name:     "seasonal difference skips missing periods",
+			fn:       ThresholdSeasonalIsAbove,
+			baseline: Baseline{Period: day, Periods: 3},
+			input:    daily(10, 20, 50),
+			expected: util.Pointer(35.0),
+		},
+		{
+			name:     "no value if series does not cover the period",
+			fn:       ThresholdSeasonalIsAbove,
+			baseline: Baseline{Period: 2 * day},
+			input:    series(util.Pointer(1.0), util.Pointer(2.0)),
+			expected: nil,
+		},
+		{
+			name:     "no value if the closest point is out of tolerance",
+			fn:       ThresholdSeasonalIsAbove,
+			baseline: Baseline{Period: 3 * time.Hour, Tolerance: 30 * time.Minute},
+			input:    series(util.Pointer(1.0), nil, nil, nil, util.Pointer(5.0), util.Pointer(8.0)),
+			expected: nil,
+		},
+		{
+			name:     "percentage change relative to the first value",
+			fn:       ThresholdPercentChangeIsAbove,
+			input:    series(nil, util.Pointer(-50.0), util.Pointer(10.0), util.Pointer(25.0)),
+			expected: util.Pointer(150.0),
+		},
+		{
+			name:     "percentage change relative to the previous period",
+			fn:       ThresholdPercentChangeIsBelow,
+			baseline: Baseline{Period: day},
+			input:    daily(100, 200, 50),
+			expected: util.Pointer(-75.0),
+		},
+		{
+			name:     "percentage change from zero is infinite",
+			fn:       ThresholdPercentChangeIsAbove,
+			input:    series(util.Pointer(0.0), util.Pointer(1.0)),
+			expected: util.Pointer(math.Inf(1)),
+		},
+		{
+			name:     "no value for a single point",
+			fn:       ThresholdPercentChangeIsAbove,
+			input:    series(util.Pointer(1.0)),
+			expected: nil,
+		},
+		{
+			name:     "no value for empty series",
+			fn:       ThresholdPercentChangeIsAbove,
+			input:    series(nil, util.Pointer(math.NaN())),
+			expected: nil,
+		},
+		{
+			name:      "seasonal threshold requires period",
+			fn:        ThresholdSeasonalIsBelow,
+			expectErr: "requires baseline period",
+		},
+		{
+			name:      "negative period is not allowed",
+			fn:        ThresholdPercentChangeIsBelow,
+			baseline:  Baseline{Period: -day},
+			expectErr: "must not be negative",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := newBaselineDeviation(tc.fn, tc.baseline)
+			if tc.expectErr != "" {
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, d.compute(tc.input))
+		})
+	}
+}
+
+func TestThresholdWithBaselineExecute(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tracer := tracing.InitializeTracerForTest()
+
+	series := func(label string, values ...float64) mathexp.Series {
+		s := mathexp.NewSeries("A", data.Labels{"label": label}, len(values))
+		for i, v := range values {
+			s.SetPoint(i, now.Add(-time.Duration(len(values)-1-i)*time.Hour), util.Pointer(v))
+		}
+		return s
+	}
+	number := func(label string, value *float64) mathexp.Number {
+		n := mathexp.NewNumber("B", data.Labels{"label": label})
+		n.SetValue(value)
+		return n
+	}
+
+	t.Run("should produce a number for every series", func(t *testing.T) {
+		cmd, err := NewThresholdCommandWithBaseline("B", "A", ThresholdSeasonalIsAbove, []float64{5}, Baseline{Period: 2 * time.Hour})
+		require.NoError(t, err)
+
+		vars := mathexp.Vars{"A": mathexp.Results{Values: mathexp.Values{
+			series("up", 1, 2, 10),
+			series("flat", 1, 2, 3),
+			series("short", 1),
+		}}}
+		result, err := cmd.Execute(context.Background(), now, vars, tracer)
+		require.NoError(t, err)
+		require.Equal(t, mathexp.Values{
+			number("up", util.Pointer(1.0)),
+			number("flat", util.Pointer(0.0)),
+			number("short", nil),
+		}, result.Values)
+	})
+
+	t.Run("should return NoData for NoData", func(t *testing.T) {
+		cmd, err := NewThresholdCommand("B", "A", ThresholdPercentChangeIsAbove, []float64{5})
+		require.NoError(t, err)
+		result, err := cmd.Execute(context.Background(), now, mathexp.Vars{"A": mathexp.Results{Values: mathexp.Values{mathexp.NewNoData()}}}, tracer)
+		require.NoError(t, err)
+		require.Equal(t, mathexp.Values{mathexp.NewNoData()}, result.Values)
+	})
+
+	t.Run("should fail if input is not a time series", func(t *testing.T) {
+		cmd, err := NewThresholdCommand("B", "A", ThresholdPercentChangeIsAbove, []float64{5})
+		require.NoError(t, err)
+		_, err = cmd.Execute(context.Background(), now, mathexp.Vars{"A": mathexp.Results{Values: mathexp.Values{number("n", util.Pointer(1.0))}}}, tracer)
+		require.ErrorContains(t, err, "requires time series")
+	})
+
+	t.Run("should apply unloading threshold to loaded dimensions", func(t *testing.T) {
+		// Fire when the value grows by more than 50%, recover when the growth is below 20%.
+		loading, err := NewThresholdCommand("B", "A", ThresholdPercentChangeIsAbove, []float64{50})
+		require.NoError(t, err)
+		unloading, err := NewThresholdCommand("B", "A", ThresholdPercentChangeIsBelow, []float64{20})
+		require.NoError(t, err)
+		unloading.Invert = true
+
+		loaded := Fingerprints{data.Labels{"label": "loaded"}.Fingerprint(): {}}
+		cmd, err := NewHysteresisCommand("B", "A", *loading, *unloading, loaded)
+		require.NoError(t, err)
+
+		vars := mathexp.Vars{"A": mathexp.Results{Values: mathexp.Values{
+			series("loaded", 100, 130),
+			series("unloaded", 100, 130),
+		}}}
+		result, err := cmd.Execute(context.Background(), now, vars, tracer)
+		require.NoError(t, err)
+		require.ElementsMatch(t, mathexp.Values{
+			number("loaded", util.Pointer(1.0)),
+			number("unloaded", util.Pointer(0.0)),
+		}, result.Values)
+	})
+}
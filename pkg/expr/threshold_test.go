@@ -197,6 +197,88 @@ func TestUnmarshalThresholdCommand(t *testing.T) {
 				require.EqualValues(t, []uint64{2, 3, 4, 5, 18446744073709551615}, actual)
 			},
 		},
+		{
+			description: "unmarshal seasonal threshold with baseline",
+			query: `{
+				"expression" : "A",
+				"type": "threshold",
+				"conditions": [{
+					"evaluator": {
+						"type": "seasonal_gt",
+						"params": [50],
+						"baseline": {"period": "1w", "periods": 2, "tolerance": "5m"}
+					}
+				}]
+			}`,
+			assert: func(t *testing.T, command Command) {
+				require.IsType(t, &ThresholdCommand{}, command)
+				cmd := command.(*ThresholdCommand)
+				require.Equal(t, ThresholdSeasonalIsAbove, cmd.ThresholdFunc)
+				require.Equal(t, greaterThanPredicate{50.0}, cmd.predicate)
+				require.Equal(t, &baselineDeviation{Baseline: Baseline{Period: 7 * 24 * time.Hour, Periods: 2, Tolerance: 5 * time.Minute}}, cmd.baseline)
+			},
+		},
+		{
+			description: "unmarshal seasonal threshold without period should error",
+			query: `{
+				"expression" : "A",
+				"type": "threshold",
+				"conditions": [{
+					"evaluator": {
+						"type": "seasonal_lt",
+						"params": [-50]
+					}
+				}]
+			}`,
+			shouldError:   true,
+			expectedError: "requires baseline period",
+		},
+		{
+			description: "unmarshal as hysteresis command with percentage change thresholds",
+			query: `{
+				"expression" : "A",
+				"type": "threshold",
+				"conditions": [{
+					"evaluator": {
+						"type": "pct_change_gt",
+						"params": [20],
+						"baseline": {"period": "1d"}
+					},
+					"unloadEvaluator": {
+						"type": "pct_change_lt",
+						"params": [10],
+						"baseline": {"period": "1d"}
+					}
+				}]
+			}`,
+			assert: func(t *testing.T, c Command) {
+				require.IsType(t, &HysteresisCommand{}, c)
+				cmd := c.(*HysteresisCommand)
+				require.Equal(t, ThresholdPercentChangeIsAbove, cmd.LoadingThresholdFunc.ThresholdFunc)
+				require.Equal(t, ThresholdPercentChangeIsBelow, cmd.UnloadingThresholdFunc.ThresholdFunc)
+				require.True(t, cmd.UnloadingThresholdFunc.Invert)
+				require.NotNil(t, cmd.UnloadingThresholdFunc.baseline)
+			},
+		},
+		{
+			description: "unmarshal hysteresis with static and baseline thresholds should error",
+			query: `{
+				"expression" : "A",
+				"type": "threshold",
+				"conditions": [{
+					"evaluator": {
+						"type": "pct_change_gt",
+						"params": [20]
+					},
+					"unloadEvaluator": {
+						"type": "lt",
+						"params": [10]
+					}
+				}]
+			}`,
+			shouldError:   true,
+			expectedError: "cannot be used together",
+		},
 	}
 
 	for _, tc := range cases {
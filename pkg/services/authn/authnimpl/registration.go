@@ -5,6 +5,7 @@ import (
 	"github.com/grafana/grafana/pkg/infra/remotecache"
 	"github.com/grafana/grafana/pkg/infra/tracing"
 	"github.com/grafana/grafana/pkg/login/social"
+	"github.com/grafana/grafana/pkg/login/social/connectors"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/apikey"
 	"github.com/grafana/grafana/pkg/services/auth"
@@ -37,7 +38,7 @@ func ProvideRegistration(
 	features *featuremgmt.FeatureManager, oauthTokenService oauthtoken.OAuthTokenService,
 	socialService social.Service, cache *remotecache.RemoteCache,
 	ldapService service.LDAP, settingsProviderService setting.Provider,
-	tracer tracing.Tracer,
+	tracer tracing.Tracer, orgRoleMapper *connectors.OrgRoleMapper,
 ) Registration {
 	logger := log.New("authn.registration")
 
@@ -90,6 +91,15 @@ func ProvideRegistration(
 		authnSvc.RegisterClient(clients.ProvideJWT(jwtService, cfg))
 	}
 
+	if cfg.AuthClientCert.Enabled {
+		clientCert, err := clients.ProvideClientCert(cfg, userService, orgRoleMapper)
+		if err != nil {
+			logger.Error("Failed to configure client certificate authentication", "err", err)
+		} else {
+			authnSvc.RegisterClient(clientCert)
+		}
+	}
+
 	if cfg.ExtJWTAuth.Enabled && features.IsEnabledGlobally(featuremgmt.FlagAuthAPIAccessTokenAuth) {
 		authnSvc.RegisterClient(clients.ProvideExtendedJWT(cfg))
 	}
@@ -0,0 +1,361 @@
+package clients
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/login/social/connectors"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	pemCertificateHeader = "-----BEGIN CERTIFICATE-----"
+	pemCertificateFooter = "-----END CERTIFICATE-----"
+)
+
+var (
+	errClientCertInvalid = errutil.Unauthorized(
+		"client-cert.invalid", errutil.WithPublicMessage("Invalid client certificate"))
+	errClientCertNotAcceptedIP = errutil.Unauthorized(
+		"client-cert.invalid-ip", errutil.WithPublicMessage("Invalid client certificate"))
+	errClientCertMissingIdentity = errutil.Unauthorized(
+		"client-cert.missing-identity", errutil.WithPublicMessage("Client certificate does not identify a user"))
+	errClientCertServiceAccountNotFound = errutil.Unauthorized(
+		"client-cert.service-account-not-found", errutil.WithPublicMessage("Client certificate does not identify a service account"))
+	errClientCertOrgMismatch = errutil.Unauthorized(
+		"client-cert.organization-mismatch", errutil.WithPublicMessage("Service account does not belong to the requested organization"))
+)
+
+var (
+	// oidEmailAddress is the legacy emailAddress attribute of certificate subjects.
+	oidEmailAddress = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}
+	// xfccCertRegexp matches the URL encoded certificates of an Envoy x-forwarded-client-cert element.
+	xfccCertRegexp = regexp.MustCompile(`(?:^|;)\s*(Chain|Cert)="?([^";]+)"?`)
+)
+
+var _ authn.ContextAwareClient = new(ClientCert)
+
+func ProvideClientCert(cfg *setting.Cfg, userService user.Service, orgRoleMapper *connectors.OrgRoleMapper) (*ClientCert, error) {
+	if cfg.AuthClientCert.CAFile == "" {
+		return nil, errors.New("ca_file is required to verify client certificates")
+	}
+
+	roots, err := LoadCertPool(cfg.AuthClientCert.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var acceptedIPs []*net.IPNet
+	if cfg.AuthClientCert.HeaderName != "" {
+		// any client could send the header, so it is only accepted from known proxies
+		if acceptedIPs, err = parseAcceptList(cfg.AuthClientCert.HeaderAcceptList); err != nil {
+			return nil, err
+		}
+		if len(acceptedIPs) == 0 {
+			return nil, errors.New("header_accept_list is required when client certificates are forwarded in a header")
+		}
+	}
+
+	return &ClientCert{
+		cfg:           cfg,
+		log:           log.New(authn.ClientCert),
+		userService:   userService,
+		orgRoleMapper: orgRoleMapper,
+		orgMappingCfg: orgRoleMapper.ParseOrgMappingSettings(context.Background(), cfg.AuthClientCert.OrgMapping, false),
+		roots:         roots,
+		acceptedIPs:   acceptedIPs,
+	}, nil
+}
+
+// ClientCert authenticates requests with an X.509 client certificate, presented during the
+// TLS handshake or forwarded in a header by a TLS terminating proxy.
+type ClientCert struct {
+	cfg           *setting.Cfg
+	log           log.Logger
+	userService   user.Service
+	orgRoleMapper *connectors.OrgRoleMapper
+	orgMappingCfg *connectors.MappingConfiguration
+	roots         *x509.CertPool
+	acceptedIPs   []*net.IPNet
+}
+
+func (c *ClientCert) Name() string {
+	return authn.ClientCert
+}
+
+func (c *ClientCert) Authenticate(ctx context.Context, r *authn.Request) (*authn.Identity, error) {
+	certs, err := c.certificates(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := c.verify(certs)
+	if err != nil {
+		c.log.FromContext(ctx).Debug("Failed to verify client certificate", "subject", certs[0].Subject.String(), "error", err)
+		return nil, errClientCertInvalid.Errorf("failed to verify client certificate: %w", err)
+	}
+
+	if saLogin, ok := c.serviceAccountLogin(cert); ok {
+		return c.serviceAccountIdentity(ctx, r, saLogin)
+	}
+
+	return c.userIdentity(cert)
+}
+
+func (c *ClientCert) IsEnabled() bool {
+	return c.cfg.AuthClientCert.Enabled
+}
+
+func (c *ClientCert) Test(ctx context.Context, r *authn.Request) bool {
+	if r.HTTPRequest == nil {
+		return false
+	}
+
+	if r.HTTPRequest.TLS != nil && len(r.HTTPRequest.TLS.PeerCertificates) > 0 {
+		return true
+	}
+
+	return c.cfg.AuthClientCert.HeaderName != "" && r.HTTPRequest.Header.Get(c.cfg.AuthClientCert.HeaderName) != ""
+}
+
+func (c *ClientCert) Priority() uint {
+	return 45
+}
+
+// certificates returns the certificate chain presented by the client, leaf certificate first.
+func (c *ClientCert) certificates(r *authn.Request) ([]*x509.Certificate, error) {
+	if r.HTTPRequest.TLS != nil && len(r.HTTPRequest.TLS.PeerCertificates) > 0 {
+		return r.HTTPRequest.TLS.PeerCertificates, nil
+	}
+
+	if !isAcceptedIP(r, c.acceptedIPs) {
+		return nil, errClientCertNotAcceptedIP.Errorf("request ip is not in the configured accept list")
+	}
+
+	certs, err := parseForwardedCertificates(r.HTTPRequest.Header.Get(c.cfg.AuthClientCert.HeaderName))
+	if err != nil {
+		return nil, errClientCertInvalid.Errorf("failed to parse forwarded client certificate: %w", err)
+	}
+	return certs, nil
+}
+
+func (c *ClientCert) verify(certs []*x509.Certificate) (*x509.Certificate, error) {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         c.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+// serviceAccountLogin returns the login of the service account identified by a URI SAN
+// starting with the configured prefix.
+func (c *ClientCert) serviceAccountLogin(cert *x509.Certificate) (string, bool) {
+	prefix := c.cfg.AuthClientCert.ServiceAccountURIPrefix
+	if prefix == "" {
+		return "", false
+	}
+
+	for _, uri := range cert.URIs {
+		if saLogin, ok := strings.CutPrefix(uri.String(), prefix); ok && saLogin != "" {
+			return saLogin, true
+		}
+	}
+	return "", false
+}
+
+func (c *ClientCert) serviceAccountIdentity(ctx context.Context, r *authn.Request, saLogin string) (*authn.Identity, error) {
+	sa, err := c.userService.GetByLogin(ctx, &user.GetUserByLoginQuery{LoginOrEmail: saLogin})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, errClientCertServiceAccountNotFound.Errorf("service account %s not found", saLogin)
+		}
+		return nil, err
+	}
+
+	if !sa.IsServiceAccount || sa.Login != saLogin {
+		return nil, errClientCertServiceAccountNotFound.Errorf("%s is not a service account", saLogin)
+	}
+
+	if sa.IsDisabled {
+		return nil, errClientCertServiceAccountNotFound.Errorf("service account %s is disabled", saLogin)
+	}
+
+	if r.OrgID == 0 {
+		r.OrgID = sa.OrgID
+	} else if r.OrgID != sa.OrgID {
+		return nil, errClientCertOrgMismatch.Errorf("service account does not belong to the requested organization")
+	}
+
+	return &authn.Identity{
+		ID:              identity.NewTypedID(identity.TypeServiceAccount, sa.ID),
+		OrgID:           sa.OrgID,
+		AuthenticatedBy: login.ClientCertModule,
+		ClientParams:    authn.ClientParams{FetchSyncedUser: true, SyncPermissions: true},
+	}, nil
+}
+
+func (c *ClientCert) userIdentity(cert *x509.Certificate) (*authn.Identity, error) {
+	id := &authn.Identity{
+		Login:           certificateAttribute(cert, c.cfg.AuthClientCert.LoginAttribute),
+		Email:           certificateAttribute(cert, c.cfg.AuthClientCert.EmailAttribute),
+		Name:            cert.Subject.CommonName,
+		AuthenticatedBy: login.ClientCertModule,
+		Groups:          cert.Subject.OrganizationalUnit,
+		ClientParams: authn.ClientParams{
+			SyncUser:        true,
+			FetchSyncedUser: true,
+			SyncPermissions: true,
+			SyncOrgRoles:    !c.cfg.AuthClientCert.SkipOrgRoleSync,
+			AllowSignUp:     c.cfg.AuthClientCert.AutoSignUp,
+		},
+	}
+
+	if id.Login == "" && id.Email == "" {
+		return nil, errClientCertMissingIdentity.Errorf("missing login and email in client certificate %s", cert.Subject.String())
+	}
+
+	id.AuthID = id.Login
+	if id.AuthID == "" {
+		id.AuthID = id.Email
+	}
+	if id.Login != "" {
+		id.ClientParams.LookUpParams.Login = &id.Login
+	}
+	if id.Email != "" {
+		id.ClientParams.LookUpParams.Email = &id.Email
+	}
+
+	if !c.cfg.AuthClientCert.SkipOrgRoleSync {
+		// the organizational units of the subject are mapped to organizations and roles
+		id.OrgRoles = c.orgRoleMapper.MapOrgRoles(c.orgMappingCfg, cert.Subject.OrganizationalUnit, "")
+	}
+
+	return id, nil
+}
+
+func certificateAttribute(cert *x509.Certificate, attribute string) string {
+	switch attribute {
+	case setting.ClientCertAttributeCommonName:
+		return cert.Subject.CommonName
+	case setting.ClientCertAttributeEmail:
+		if len(cert.EmailAddresses) > 0 {
+			return cert.EmailAddresses[0]
+		}
+		for _, name := range cert.Subject.Names {
+			if email, ok := name.Value.(string); ok && name.Type.Equal(oidEmailAddress) {
+				return email
+			}
+		}
+	case setting.ClientCertAttributeDNSSAN:
+		if len(cert.DNSNames) > 0 {
+			return cert.DNSNames[0]
+		}
+	case setting.ClientCertAttributeURISAN:
+		if len(cert.URIs) > 0 {
+			return cert.URIs[0].String()
+		}
+	}
+	return ""
+}
+
+// parseForwardedCertificates parses the certificates forwarded by a proxy. The header can hold
+// URL encoded PEM certificates, base64 encoded DER certificates or an Envoy x-forwarded-client-cert element.
+func parseForwardedCertificates(value string) ([]*x509.Certificate, error) {
+	// only the first element holds the certificate of the client
+	if matches := xfccCertRegexp.FindAllStringSubmatch(strings.SplitN(value, ",", 2)[0], -1); len(matches) > 0 {
+		encoded := matches[0][2]
+		// the chain includes the leaf certificate
+		for _, match := range matches {
+			if match[1] == "Chain" {
+				encoded = match[2]
+			}
+		}
+
+		unescaped, err := url.QueryUnescape(encoded)
+		if err != nil {
+			return nil, err
+		}
+		value = unescaped
+	}
+
+	if strings.Contains(value, "%") {
+		unescaped, err := url.PathUnescape(value)
+		if err != nil {
+			return nil, err
+		}
+		value = unescaped
+	}
+
+	var ders []string
+	if strings.Contains(value, pemCertificateHeader) {
+		for _, block := range strings.Split(value, pemCertificateHeader)[1:] {
+			body, _, found := strings.Cut(block, pemCertificateFooter)
+			if !found {
+				return nil, errors.New("unterminated PEM certificate")
+			}
+			// proxies replace the line breaks of PEM certificates with spaces or tabs
+			ders = append(ders, strings.Join(strings.Fields(body), ""))
+		}
+	} else {
+		ders = strings.Split(value, ",")
+	}
+
+	certs := make([]*x509.Certificate, 0, len(ders))
+	for _, encoded := range ders {
+		der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found")
+	}
+	return certs, nil
+}
+
+// LoadCertPool reads the PEM encoded certificates of a CA file.
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	// We can ignore the gosec G304 warning on this one because `file` comes
+	// from the Grafana configuration file
+	//nolint:gosec
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate found in CA file %s", file)
+	}
+	return pool, nil
+}
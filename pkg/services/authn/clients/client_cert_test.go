@@ -0,0 +1,321 @@
+package clients
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/login/social/connectors"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgtest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue signs a client certificate with the subject and SANs of the template.
+func (ca *testCA) issue(t *testing.T, template *x509.Certificate) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if template.ExtKeyUsage == nil {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func (ca *testCA) writePEM(t *testing.T) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600))
+	return file
+}
+
+func encodePEM(cert *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+func tlsRequest(certs ...*x509.Certificate) *authn.Request {
+	return &authn.Request{HTTPRequest: &http.Request{
+		Header:     http.Header{},
+		RemoteAddr: "10.0.0.1:4444",
+		TLS:        &tls.ConnectionState{PeerCertificates: certs},
+	}}
+}
+
+func forwardedRequest(remoteAddr, value string) *authn.Request {
+	return &authn.Request{HTTPRequest: &http.Request{
+		Header:     http.Header{"X-Client-Cert": {value}},
+		RemoteAddr: remoteAddr,
+	}}
+}
+
+func TestClientCert_Authenticate(t *testing.T) {
+	ca := newTestCA(t)
+	kioskURI, err := url.Parse("spiffe://example.org/grafana/sa-kiosk")
+	require.NoError(t, err)
+
+	userCert := ca.issue(t, &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "jane", OrganizationalUnit: []string{"tooling"}},
+		EmailAddresses: []string{"jane@example.org"},
+	})
+	saCert := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "kiosk"}, URIs: []*url.URL{kioskURI}})
+
+	type testCase struct {
+		desc             string
+		req              *authn.Request
+		expectedIdentity *authn.Identity
+		expectedOrgID    int64
+		expectedErr      error
+	}
+
+	tests := []testCase{
+		{
+			desc: "should authenticate user with TLS client certificate",
+			req:  tlsRequest(userCert),
+			expectedIdentity: &authn.Identity{
+				Login:           "jane",
+				Email:           "jane@example.org",
+				Name:            "jane",
+				AuthID:          "jane",
+				AuthenticatedBy: login.ClientCertModule,
+				Groups:          []string{"tooling"},
+				OrgRoles:        map[int64]org.RoleType{2: org.RoleEditor},
+				ClientParams: authn.ClientParams{
+					SyncUser:        true,
+					FetchSyncedUser: true,
+					SyncPermissions: true,
+					SyncOrgRoles:    true,
+					LookUpParams: login.UserLookupParams{
+						Login: strPtr("jane"),
+						Email: strPtr("jane@example.org"),
+					},
+				},
+			},
+		},
+		{
+			desc: "should authenticate user with forwarded client certificate",
+			req:  forwardedRequest("192.168.1.10:4444", url.PathEscape(encodePEM(userCert))),
+			expectedIdentity: &authn.Identity{
+				Login:           "jane",
+				Email:           "jane@example.org",
+				Name:            "jane",
+				AuthID:          "jane",
+				AuthenticatedBy: login.ClientCertModule,
+				Groups:          []string{"tooling"},
+				OrgRoles:        map[int64]org.RoleType{2: org.RoleEditor},
+				ClientParams: authn.ClientParams{
+					SyncUser:        true,
+					FetchSyncedUser: true,
+					SyncPermissions: true,
+					SyncOrgRoles:    true,
+					LookUpParams: login.UserLookupParams{
+						Login: strPtr("jane"),
+						Email: strPtr("jane@example.org"),
+					},
+				},
+			},
+		},
+		{
+			desc: "should authenticate service account",
+			req:  tlsRequest(saCert),
+			expectedIdentity: &authn.Identity{
+				ID:              identity.NewTypedID(identity.TypeServiceAccount, 10),
+				OrgID:           3,
+				AuthenticatedBy: login.ClientCertModule,
+				ClientParams:    authn.ClientParams{FetchSyncedUser: true, SyncPermissions: true},
+			},
+			expectedOrgID: 3,
+		},
+		{
+			desc:        "should fail for service account of another organization",
+			req:         &authn.Request{OrgID: 1, HTTPRequest: tlsRequest(saCert).HTTPRequest},
+			expectedErr: errClientCertOrgMismatch,
+		},
+		{
+			desc:        "should fail for certificate of another CA",
+			req:         tlsRequest(newTestCA(t).issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "jane"}})),
+			expectedErr: errClientCertInvalid,
+		},
+		{
+			desc: "should fail for certificate without client authentication usage",
+			req: tlsRequest(ca.issue(t, &x509.Certificate{
+				Subject:     pkix.Name{CommonName: "jane"},
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			})),
+			expectedErr: errClientCertInvalid,
+		},
+		{
+			desc:        "should fail for forwarded certificate from a not accepted ip",
+			req:         forwardedRequest("10.0.0.1:4444", url.PathEscape(encodePEM(userCert))),
+			expectedErr: errClientCertNotAcceptedIP,
+		},
+		{
+			desc:        "should fail for invalid forwarded certificate",
+			req:         forwardedRequest("192.168.1.10:4444", "invalid"),
+			expectedErr: errClientCertInvalid,
+		},
+		{
+			desc:        "should fail for certificate without login and email",
+			req:         tlsRequest(ca.issue(t, &x509.Certificate{Subject: pkix.Name{Organization: []string{"Grafana"}}})),
+			expectedErr: errClientCertMissingIdentity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfg := setting.NewCfg()
+			cfg.AuthClientCert = setting.AuthClientCertSettings{
+				Enabled:                 true,
+				CAFile:                  ca.writePEM(t),
+				HeaderName:              "X-Client-Cert",
+				HeaderAcceptList:        "192.168.1.0/24",
+				LoginAttribute:          setting.ClientCertAttributeCommonName,
+				EmailAttribute:          setting.ClientCertAttributeEmail,
+				ServiceAccountURIPrefix: "spiffe://example.org/grafana/",
+				OrgMapping:              []string{"tooling:2:Editor"},
+			}
+			userService := &usertest.FakeUserService{
+				ExpectedUser: &user.User{ID: 10, Login: "sa-kiosk", OrgID: 3, IsServiceAccount: true},
+			}
+
+			c, err := ProvideClientCert(cfg, userService, connectors.ProvideOrgRoleMapper(cfg, &orgtest.FakeOrgService{}))
+			require.NoError(t, err)
+
+			id, err := c.Authenticate(context.Background(), tt.req)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, id)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.EqualValues(t, tt.expectedIdentity, id)
+			if tt.expectedOrgID != 0 {
+				assert.Equal(t, tt.expectedOrgID, tt.req.OrgID)
+			}
+		})
+	}
+}
+
+func TestClientCert_Test(t *testing.T) {
+	ca := newTestCA(t)
+	cfg := setting.NewCfg()
+	cfg.AuthClientCert = setting.AuthClientCertSettings{
+		Enabled:          true,
+		CAFile:           ca.writePEM(t),
+		HeaderName:       "X-Client-Cert",
+		HeaderAcceptList: "192.168.1.10",
+	}
+	c, err := ProvideClientCert(cfg, usertest.NewUserServiceFake(), connectors.ProvideOrgRoleMapper(cfg, &orgtest.FakeOrgService{}))
+	require.NoError(t, err)
+
+	cert := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "jane"}})
+	assert.True(t, c.Test(context.Background(), tlsRequest(cert)))
+	assert.True(t, c.Test(context.Background(), forwardedRequest("192.168.1.10:4444", "cert")))
+	assert.False(t, c.Test(context.Background(), tlsRequest()))
+	assert.False(t, c.Test(context.Background(), forwardedRequest("192.168.1.10:4444", "")))
+	assert.False(t, c.Test(context.Background(), &authn.Request{}))
+}
+
+func TestProvideClientCert(t *testing.T) {
+	ca := newTestCA(t)
+	orgRoleMapper := connectors.ProvideOrgRoleMapper(setting.NewCfg(), &orgtest.FakeOrgService{})
+
+	for desc, settings := range map[string]setting.AuthClientCertSettings{
+		"missing CA file":             {Enabled: true},
+		"unknown CA file":             {Enabled: true, CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		"header without accept list":  {Enabled: true, CAFile: ca.writePEM(t), HeaderName: "X-Client-Cert"},
+		"header with invalid network": {Enabled: true, CAFile: ca.writePEM(t), HeaderName: "X-Client-Cert", HeaderAcceptList: "invalid"},
+	} {
+		t.Run("should fail for "+desc, func(t *testing.T) {
+			cfg := setting.NewCfg()
+			cfg.AuthClientCert = settings
+			_, err := ProvideClientCert(cfg, usertest.NewUserServiceFake(), orgRoleMapper)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseForwardedCertificates(t *testing.T) {
+	ca := newTestCA(t)
+	leaf := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "jane"}})
+	chainPEM := encodePEM(leaf) + encodePEM(ca.cert)
+
+	tests := map[string]string{
+		"URL encoded PEM":          url.PathEscape(chainPEM),
+		"PEM with spaces":          strings.ReplaceAll(strings.TrimSpace(chainPEM), "\n", " "),
+		"base64 encoded DER":       base64.StdEncoding.EncodeToString(leaf.Raw) + "," + base64.StdEncoding.EncodeToString(ca.cert.Raw),
+		"Envoy XFCC chain":         `By=spiffe://grafana;Hash=abc;Cert="` + url.QueryEscape(encodePEM(leaf)) + `";Chain="` + url.QueryEscape(chainPEM) + `";Subject="CN=jane"`,
+		"Envoy XFCC first element": `Hash=abc;Chain="` + url.QueryEscape(chainPEM) + `",By=spiffe://proxy;Cert="invalid"`,
+	}
+
+	for desc, value := range tests {
+		t.Run(desc, func(t *testing.T) {
+			certs, err := parseForwardedCertificates(value)
+			require.NoError(t, err)
+			require.Len(t, certs, 2)
+			assert.Equal(t, leaf.Raw, certs[0].Raw)
+			assert.Equal(t, ca.cert.Raw, certs[1].Raw)
+		})
+	}
+
+	t.Run("should fail for invalid certificates", func(t *testing.T) {
+		for _, value := range []string{"", "invalid", pemCertificateHeader + "invalid", base64.StdEncoding.EncodeToString([]byte("invalid"))} {
+			_, err := parseForwardedCertificates(value)
+			assert.Error(t, err, value)
+		}
+	})
+}
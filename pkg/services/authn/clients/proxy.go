@@ -188,7 +188,13 @@ func (c *Proxy) Hook(ctx context.Context, id *authn.Identity, r *authn.Request)
 }
 
 func (c *Proxy) isAllowedIP(r *authn.Request) bool {
-	if len(c.acceptedIPs) == 0 {
+	return isAcceptedIP(r, c.acceptedIPs)
+}
+
+// isAcceptedIP returns true if the request comes from one of the accepted networks
+// or if no network is configured.
+func isAcceptedIP(r *authn.Request, acceptedIPs []*net.IPNet) bool {
+	if len(acceptedIPs) == 0 {
 		return true
 	}
 
@@ -198,7 +204,7 @@ func (c *Proxy) isAllowedIP(r *authn.Request) bool {
 	}
 
 	ip := net.ParseIP(host)
-	for _, v := range c.acceptedIPs {
+	for _, v := range acceptedIPs {
 		if v.Contains(ip) {
 			return true
 		}
@@ -5,12 +5,18 @@ import (
 	"fmt"
 
 	"github.com/grafana/grafana/pkg/services/live/telemetry"
+	"github.com/grafana/grafana/pkg/services/live/telemetry/otlp"
+	"github.com/grafana/grafana/pkg/services/live/telemetry/prometheus"
 	"github.com/grafana/grafana/pkg/services/live/telemetry/telegraf"
 )
 
 type Converter struct {
-	telegrafConverterWide         *telegraf.Converter
-	telegrafConverterLabelsColumn *telegraf.Converter
+	telegrafConverterWide           *telegraf.Converter
+	telegrafConverterLabelsColumn   *telegraf.Converter
+	prometheusConverterWide         *prometheus.Converter
+	prometheusConverterLabelsColumn *prometheus.Converter
+	otlpConverterWide               *otlp.Converter
+	otlpConverterLabelsColumn       *otlp.Converter
 }
 
 func NewConverter() *Converter {
@@ -22,18 +28,41 @@ func NewConverter() *Converter {
 			telegraf.WithUseLabelsColumn(true),
 			telegraf.WithFloat64Numbers(true),
 		),
+		prometheusConverterWide: prometheus.NewConverter(),
+		prometheusConverterLabelsColumn: prometheus.NewConverter(
+			prometheus.WithUseLabelsColumn(true),
+		),
+		otlpConverterWide: otlp.NewConverter(),
+		otlpConverterLabelsColumn: otlp.NewConverter(
+			otlp.WithUseLabelsColumn(true),
+		),
 	}
 }
 
 var ErrUnsupportedFrameFormat = errors.New("unsupported frame format")
 
+// Convert converts Influx line protocol.
 func (c *Converter) Convert(data []byte, frameFormat string) ([]telemetry.FrameWrapper, error) {
+	return convert(c.telegrafConverterWide, c.telegrafConverterLabelsColumn, data, frameFormat)
+}
+
+// ConvertPrometheus converts Prometheus text exposition format.
+func (c *Converter) ConvertPrometheus(data []byte, frameFormat string) ([]telemetry.FrameWrapper, error) {
+	return convert(c.prometheusConverterWide, c.prometheusConverterLabelsColumn, data, frameFormat)
+}
+
+// ConvertOTLP converts OTLP metrics export requests encoded as protobuf or JSON.
+func (c *Converter) ConvertOTLP(data []byte, frameFormat string) ([]telemetry.FrameWrapper, error) {
+	return convert(c.otlpConverterWide, c.otlpConverterLabelsColumn, data, frameFormat)
+}
+
+func convert(wide, labelsColumn telemetry.Converter, data []byte, frameFormat string) ([]telemetry.FrameWrapper, error) {
 	var converter telemetry.Converter
 	switch frameFormat {
 	case "wide":
-		converter = c.telegrafConverterWide
+		converter = wide
 	case "labels_column":
-		converter = c.telegrafConverterLabelsColumn
+		converter = labelsColumn
 	default:
 		return nil, ErrUnsupportedFrameFormat
 	}
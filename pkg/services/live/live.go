@@ -164,6 +164,12 @@ func ProvideService(plugCtxProvider *plugincontext.Provider, cfg *setting.Cfg, r
 
 	g.ManagedStreamRunner = managedStreamRunner
 
+	if g.Cfg.LivePipelineStorage != "" {
+		if err := g.setupPipeline(); err != nil {
+			return nil, err
+		}
+	}
+
 	g.contextGetter = liveplugin.NewContextGetter(g.PluginContextProvider, g.DataSourceCache)
 	pipelinedChannelLocalPublisher := liveplugin.NewChannelLocalPublisher(node, g.Pipeline)
 	numLocalSubscribersGetter := liveplugin.NewNumLocalSubscribersGetter(node)
@@ -403,6 +409,7 @@ type GrafanaLive struct {
 	ManagedStreamRunner *managedstream.Runner
 	Pipeline            *pipeline.Pipeline
 	pipelineStorage     pipeline.Storage
+	pipelineRules       *pipeline.CacheSegmentedTree
 
 	contextGetter    *liveplugin.ContextGetter
 	runStreamManager *runstream.Manager
@@ -1063,6 +1070,27 @@ func (g *GrafanaLive) HandleChannelRulesListHTTP(c *contextmodel.ReqContext) res
 	})
 }
 
+// HandleChannelRuleVersionsListHTTP returns the version history of a channel rule.
+func (g *GrafanaLive) HandleChannelRuleVersionsListHTTP(c *contextmodel.ReqContext) response.Response {
+	pattern := c.Query("pattern")
+	if pattern == "" {
+		return response.Error(http.StatusBadRequest, "Rule pattern required", nil)
+	}
+	storage, ok := g.pipelineStorage.(pipeline.VersionedStorage)
+	if !ok {
+		return response.Error(http.StatusNotImplemented, "Channel rule storage does not keep versions", nil)
+	}
+	versions, err := storage.ListChannelRuleVersions(c.Req.Context(), c.SignedInUser.GetOrgID(), pipeline.ChannelRuleVersionsGetCmd{
+		Pattern: pattern,
+	})
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get channel rule versions", err)
+	}
+	return response.JSON(http.StatusOK, util.DynMap{
+		"versions": versions,
+	})
+}
+
 type ConvertDryRunRequest struct {
 	ChannelRules []pipeline.ChannelRule `json:"channelRules"`
 	Channel      string                 `json:"channel"`
@@ -1171,8 +1199,9 @@ func (g *GrafanaLive) HandleChannelRulesPostHTTP(c *contextmodel.ReqContext) res
 	}
 	rule, err := g.pipelineStorage.CreateChannelRule(c.Req.Context(), c.SignedInUser.GetOrgID(), cmd)
 	if err != nil {
-		return response.Error(http.StatusInternalServerError, "Failed to create channel rule", err)
+		return pipelineStorageErrorResponse("Failed to create channel rule", err)
 	}
+	g.invalidatePipelineRules(c.SignedInUser.GetOrgID())
 	return response.JSON(http.StatusOK, util.DynMap{
 		"rule": rule,
 	})
@@ -1194,8 +1223,9 @@ func (g *GrafanaLive) HandleChannelRulesPutHTTP(c *contextmodel.ReqContext) resp
 	}
 	rule, err := g.pipelineStorage.UpdateChannelRule(c.Req.Context(), c.SignedInUser.GetOrgID(), cmd)
 	if err != nil {
-		return response.Error(http.StatusInternalServerError, "Failed to update channel rule", err)
+		return pipelineStorageErrorResponse("Failed to update channel rule", err)
 	}
+	g.invalidatePipelineRules(c.SignedInUser.GetOrgID())
 	return response.JSON(http.StatusOK, util.DynMap{
 		"rule": rule,
 	})
@@ -1217,8 +1247,9 @@ func (g *GrafanaLive) HandleChannelRulesDeleteHTTP(c *contextmodel.ReqContext) r
 	}
 	err = g.pipelineStorage.DeleteChannelRule(c.Req.Context(), c.SignedInUser.GetOrgID(), cmd)
 	if err != nil {
-		return response.Error(http.StatusInternalServerError, "Failed to delete channel rule", err)
+		return pipelineStorageErrorResponse("Failed to delete channel rule", err)
 	}
+	g.invalidatePipelineRules(c.SignedInUser.GetOrgID())
 	return response.JSON(http.StatusOK, util.DynMap{})
 }
 
@@ -1261,8 +1292,9 @@ func (g *GrafanaLive) HandleWriteConfigsPostHTTP(c *contextmodel.ReqContext) res
 	}
 	result, err := g.pipelineStorage.CreateWriteConfig(c.Req.Context(), c.SignedInUser.GetOrgID(), cmd)
 	if err != nil {
-		return response.Error(http.StatusInternalServerError, "Failed to create write config", err)
+		return pipelineStorageErrorResponse("Failed to create write config", err)
 	}
+	g.invalidatePipelineRules(c.SignedInUser.GetOrgID())
 	return response.JSON(http.StatusOK, util.DynMap{
 		"writeConfig": pipeline.WriteConfigToDto(result),
 	})
@@ -1305,8 +1337,9 @@ func (g *GrafanaLive) HandleWriteConfigsPutHTTP(c *contextmodel.ReqContext) resp
 	}
 	result, err := g.pipelineStorage.UpdateWriteConfig(c.Req.Context(), c.SignedInUser.GetOrgID(), cmd)
 	if err != nil {
-		return response.Error(http.StatusInternalServerError, "Failed to update write config", err)
+		return pipelineStorageErrorResponse("Failed to update write config", err)
 	}
+	g.invalidatePipelineRules(c.SignedInUser.GetOrgID())
 	return response.JSON(http.StatusOK, util.DynMap{
 		"writeConfig": pipeline.WriteConfigToDto(result),
 	})
@@ -1328,8 +1361,9 @@ func (g *GrafanaLive) HandleWriteConfigsDeleteHTTP(c *contextmodel.ReqContext) r
 	}
 	err = g.pipelineStorage.DeleteWriteConfig(c.Req.Context(), c.SignedInUser.GetOrgID(), cmd)
 	if err != nil {
-		return response.Error(http.StatusInternalServerError, "Failed to delete write config", err)
+		return pipelineStorageErrorResponse("Failed to delete write config", err)
 	}
+	g.invalidatePipelineRules(c.SignedInUser.GetOrgID())
 	return response.JSON(http.StatusOK, util.DynMap{})
 }
 
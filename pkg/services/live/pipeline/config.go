@@ -32,14 +32,19 @@ type ChannelRule struct {
 	OrgId    int64               `json:"-"`
 	Pattern  string              `json:"pattern"`
 	Settings ChannelRuleSettings `json:"settings"`
+	// Version is incremented on every change of the rule by storages that keep
+	// the history of rules.
+	Version int64 `json:"version,omitempty"`
 }
 
 type ConverterConfig struct {
-	Type                      string                     `json:"type" ts_type:"Omit<keyof ConverterConfig, 'type'>"`
-	AutoJsonConverterConfig   *AutoJsonConverterConfig   `json:"jsonAuto,omitempty"`
-	ExactJsonConverterConfig  *ExactJsonConverterConfig  `json:"jsonExact,omitempty"`
-	AutoInfluxConverterConfig *AutoInfluxConverterConfig `json:"influxAuto,omitempty"`
-	JsonFrameConverterConfig  *JsonFrameConverterConfig  `json:"jsonFrame,omitempty"`
+	Type                          string                         `json:"type" ts_type:"Omit<keyof ConverterConfig, 'type'>"`
+	AutoJsonConverterConfig       *AutoJsonConverterConfig       `json:"jsonAuto,omitempty"`
+	ExactJsonConverterConfig      *ExactJsonConverterConfig      `json:"jsonExact,omitempty"`
+	AutoInfluxConverterConfig     *AutoInfluxConverterConfig     `json:"influxAuto,omitempty"`
+	AutoPrometheusConverterConfig *AutoPrometheusConverterConfig `json:"prometheusAuto,omitempty"`
+	AutoOTLPConverterConfig       *AutoOTLPConverterConfig       `json:"otlpAuto,omitempty"`
+	JsonFrameConverterConfig      *JsonFrameConverterConfig      `json:"jsonFrame,omitempty"`
 }
 
 type DropFieldsFrameProcessorConfig struct {
@@ -50,11 +55,59 @@ type KeepFieldsFrameProcessorConfig struct {
 	FieldNames []string `json:"fieldNames"`
 }
 
+type RenameFieldsFrameProcessorConfig struct {
+	// Names maps current field names to new names.
+	Names map[string]string `json:"names"`
+}
+
+type UnitConversion struct {
+	FieldName string `json:"fieldName"`
+	// Scale multiplies values, 1 if not set.
+	Scale *float64 `json:"scale,omitempty"`
+	// Offset is added to values after scaling.
+	Offset float64 `json:"offset,omitempty"`
+	// Unit is set to the field config if not empty.
+	Unit string `json:"unit,omitempty"`
+}
+
+type ConvertUnitsFrameProcessorConfig struct {
+	Conversions []UnitConversion `json:"conversions"`
+}
+
+type AggregateFrameProcessorConfig struct {
+	// IntervalMilliseconds is the length of the tumbling window.
+	IntervalMilliseconds int64 `json:"intervalMilliseconds"`
+	// Reducer is one of avg, min, max or last.
+	Reducer string `json:"reducer"`
+	// FieldNames to aggregate, all number fields are aggregated if empty.
+	FieldNames []string `json:"fieldNames,omitempty"`
+}
+
+type RateFrameProcessorConfig struct {
+	// FieldNames to calculate rate for, all number fields are used if empty.
+	FieldNames []string `json:"fieldNames,omitempty"`
+	// Counter treats decreasing values as counter resets.
+	Counter bool `json:"counter,omitempty"`
+}
+
+type DedupFrameProcessorConfig struct {
+	// FieldNames to compare, all fields except time and string fields are compared if empty.
+	FieldNames []string `json:"fieldNames,omitempty"`
+	// HeartbeatMilliseconds allows passing an unchanged row when the interval passed
+	// since the last passed row. Unchanged rows are always dropped if not set.
+	HeartbeatMilliseconds int64 `json:"heartbeatMilliseconds,omitempty"`
+}
+
 type FrameProcessorConfig struct {
-	Type                      string                          `json:"type" ts_type:"Omit<keyof FrameProcessorConfig, 'type'>"`
-	DropFieldsProcessorConfig *DropFieldsFrameProcessorConfig `json:"dropFields,omitempty"`
-	KeepFieldsProcessorConfig *KeepFieldsFrameProcessorConfig `json:"keepFields,omitempty"`
-	MultipleProcessorConfig   *MultipleFrameProcessorConfig   `json:"multiple,omitempty"`
+	Type                        string                            `json:"type" ts_type:"Omit<keyof FrameProcessorConfig, 'type'>"`
+	DropFieldsProcessorConfig   *DropFieldsFrameProcessorConfig   `json:"dropFields,omitempty"`
+	KeepFieldsProcessorConfig   *KeepFieldsFrameProcessorConfig   `json:"keepFields,omitempty"`
+	RenameFieldsProcessorConfig *RenameFieldsFrameProcessorConfig `json:"renameFields,omitempty"`
+	ConvertUnitsProcessorConfig *ConvertUnitsFrameProcessorConfig `json:"convertUnits,omitempty"`
+	AggregateProcessorConfig    *AggregateFrameProcessorConfig    `json:"aggregate,omitempty"`
+	RateProcessorConfig         *RateFrameProcessorConfig         `json:"rate,omitempty"`
+	DedupProcessorConfig        *DedupFrameProcessorConfig        `json:"dedup,omitempty"`
+	MultipleProcessorConfig     *MultipleFrameProcessorConfig     `json:"multiple,omitempty"`
 }
 
 type MultipleFrameProcessorConfig struct {
@@ -150,6 +203,16 @@ type AutoInfluxConverterConfig struct {
 	FrameFormat string `json:"frameFormat"`
 }
 
+// AutoPrometheusConverterConfig ...
+type AutoPrometheusConverterConfig struct {
+	FrameFormat string `json:"frameFormat"`
+}
+
+// AutoOTLPConverterConfig ...
+type AutoOTLPConverterConfig struct {
+	FrameFormat string `json:"frameFormat"`
+}
+
 type JsonFrameConverterConfig struct{}
 
 type ManagedStreamOutputConfig struct{}
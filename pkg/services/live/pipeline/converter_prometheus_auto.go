@@ -0,0 +1,41 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/live/convert"
+)
+
+// AutoPrometheusConverter decodes Prometheus text exposition format input and
+// transforms it to several ChannelFrame objects where Channel is constructed
+// from original channel + / + <metric_name>.
+type AutoPrometheusConverter struct {
+	config    AutoPrometheusConverterConfig
+	converter *convert.Converter
+}
+
+// NewAutoPrometheusConverter creates new AutoPrometheusConverter.
+func NewAutoPrometheusConverter(config AutoPrometheusConverterConfig) *AutoPrometheusConverter {
+	return &AutoPrometheusConverter{config: config, converter: convert.NewConverter()}
+}
+
+const ConverterTypePrometheusAuto = "prometheusAuto"
+
+func (c *AutoPrometheusConverter) Type() string {
+	return ConverterTypePrometheusAuto
+}
+
+func (c *AutoPrometheusConverter) Convert(_ context.Context, vars Vars, body []byte) ([]*ChannelFrame, error) {
+	frameWrappers, err := c.converter.ConvertPrometheus(body, c.config.FrameFormat)
+	if err != nil {
+		return nil, err
+	}
+	channelFrames := make([]*ChannelFrame, 0, len(frameWrappers))
+	for _, fw := range frameWrappers {
+		channelFrames = append(channelFrames, &ChannelFrame{
+			Channel: vars.Channel + "/" + fw.Key(),
+			Frame:   fw.Frame(),
+		})
+	}
+	return channelFrames, nil
+}
@@ -0,0 +1,270 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	AggregateReducerAvg  = "avg"
+	AggregateReducerMin  = "min"
+	AggregateReducerMax  = "max"
+	AggregateReducerLast = "last"
+)
+
+// maxAggregateNumberFields limits the number of number fields with distinct names and
+// labels aggregated in a window. Values of further fields are dropped until the window
+// is closed.
+const maxAggregateNumberFields = 1000
+
+// AggregateFrameProcessor aggregates number fields over tumbling time windows, so
+// a high-frequency stream can be reduced before it is outputted. Values are reduced
+// separately for each label set: number fields with different labels and rows with
+// different values of string fields (like the labels column) are aggregated apart.
+// A window is closed when the first row of a later window arrives, then the frame
+// with one row per label set, timestamped with the start of the window, is passed
+// further. Processing stops while the window is still open.
+type AggregateFrameProcessor struct {
+	config   AggregateFrameProcessorConfig
+	interval time.Duration
+	states   *FrameProcessorStateStorage
+	key      string
+	now      func() time.Time
+}
+
+func NewAggregateFrameProcessor(states *FrameProcessorStateStorage, config AggregateFrameProcessorConfig) (*AggregateFrameProcessor, error) {
+	if config.IntervalMilliseconds <= 0 {
+		return nil, fmt.Errorf("aggregation interval must be positive")
+	}
+	switch config.Reducer {
+	case AggregateReducerAvg, AggregateReducerMin, AggregateReducerMax, AggregateReducerLast:
+	default:
+		return nil, fmt.Errorf("unknown aggregation reducer: %s", config.Reducer)
+	}
+	if states == nil {
+		states = NewFrameProcessorStateStorage()
+	}
+	return &AggregateFrameProcessor{
+		config:   config,
+		interval: time.Duration(config.IntervalMilliseconds) * time.Millisecond,
+		states:   states,
+		key:      frameProcessorStateKey(FrameProcessorTypeAggregate, config),
+		now:      time.Now,
+	}, nil
+}
+
+const FrameProcessorTypeAggregate = "aggregate"
+
+func (p *AggregateFrameProcessor) Type() string {
+	return FrameProcessorTypeAggregate
+}
+
+func (p *AggregateFrameProcessor) stateKey() string {
+	return p.key
+}
+
+func (p *AggregateFrameProcessor) ProcessFrame(_ context.Context, vars Vars, frame *data.Frame) (*data.Frame, error) {
+	state := p.states.GetOrCreate(p.key, vars.OrgID, vars.Channel, func() any {
+		return newAggregateState()
+	}).(*aggregateState)
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	now := p.now()
+	timeIndex := frameTimeField(frame)
+	var closed []aggregateWindow
+	for row := 0; row < frame.Rows(); row++ {
+		start := rowTime(frame, timeIndex, row, now).Truncate(p.interval)
+		if state.window.groups == nil {
+			state.window = newAggregateWindow(start)
+		} else if start.After(state.window.start) {
+			closed = append(closed, state.window)
+			state.window = newAggregateWindow(start)
+		}
+		// Late rows are added to the current window.
+		state.add(frame, row, p.config.FieldNames)
+	}
+	if len(closed) == 0 {
+		return nil, nil
+	}
+	out := state.frame(frame.Name, closed, p.config.Reducer)
+	state.compact()
+	return out, nil
+}
+
+type aggregateState struct {
+	mu           sync.Mutex
+	stringFields []string
+	numberFields []*data.Field
+	numberIndex  map[string]int
+	window       aggregateWindow
+}
+
+func newAggregateState() *aggregateState {
+	return &aggregateState{numberIndex: map[string]int{}}
+}
+
+type aggregateWindow struct {
+	start  time.Time
+	groups map[string]*aggregateGroup
+	order  []string
+}
+
+func newAggregateWindow(start time.Time) aggregateWindow {
+	return aggregateWindow{start: start, groups: map[string]*aggregateGroup{}}
+}
+
+type aggregateGroup struct {
+	strings []*string
+	values  map[int]*aggregateValue
+}
+
+type aggregateValue struct {
+	sum, min, max, last float64
+	count               int
+}
+
+func (s *aggregateState) add(frame *data.Frame, row int, fieldNames []string) {
+	key, strs := rowGroupKey(frame, row)
+	if s.stringFields == nil {
+		s.stringFields = []string{}
+		for _, f := range frame.Fields {
+			if f.Type() == data.FieldTypeString || f.Type() == data.FieldTypeNullableString {
+				s.stringFields = append(s.stringFields, f.Name)
+			}
+		}
+	}
+	group, ok := s.window.groups[key]
+	if !ok {
+		group = &aggregateGroup{strings: strs, values: map[int]*aggregateValue{}}
+		s.window.groups[key] = group
+		s.window.order = append(s.window.order, key)
+	}
+	for _, f := range frame.Fields {
+		if !isProcessedNumberField(f, fieldNames) {
+			continue
+		}
+		v, err := f.NullableFloatAt(row)
+		if err != nil || v == nil || math.IsNaN(*v) {
+			continue
+		}
+		idx, ok := s.numberIndex[fieldKey(f)]
+		if !ok {
+			if len(s.numberFields) >= maxAggregateNumberFields {
+				continue
+			}
+			field := data.NewFieldFromFieldType(data.FieldTypeNullableFloat64, 0)
+			field.Name = f.Name
+			field.Labels = f.Labels.Copy()
+			field.Config = f.Config
+			idx = len(s.numberFields)
+			s.numberFields = append(s.numberFields, field)
+			s.numberIndex[fieldKey(f)] = idx
+		}
+		value, ok := group.values[idx]
+		if !ok {
+			group.values[idx] = &aggregateValue{sum: *v, min: *v, max: *v, last: *v, count: 1}
+			continue
+		}
+		value.sum += *v
+		value.min = math.Min(value.min, *v)
+		value.max = math.Max(value.max, *v)
+		value.last = *v
+		value.count++
+	}
+}
+
+// compact forgets the number fields without values in the open window, so label
+// sets that no longer appear in the channel do not accumulate in the state.
+func (s *aggregateState) compact() {
+	used := make([]bool, len(s.numberFields))
+	for _, group := range s.window.groups {
+		for idx := range group.values {
+			used[idx] = true
+		}
+	}
+	remap := make(map[int]int, len(s.numberFields))
+	numberFields := make([]*data.Field, 0, len(s.numberFields))
+	numberIndex := make(map[string]int, len(s.numberFields))
+	for idx, f := range s.numberFields {
+		if !used[idx] {
+			continue
+		}
+		remap[idx] = len(numberFields)
+		numberIndex[fieldKey(f)] = len(numberFields)
+		numberFields = append(numberFields, f)
+	}
+	for _, group := range s.window.groups {
+		values := make(map[int]*aggregateValue, len(group.values))
+		for idx, v := range group.values {
+			values[remap[idx]] = v
+		}
+		group.values = values
+	}
+	s.numberFields = numberFields
+	s.numberIndex = numberIndex
+}
+
+func (s *aggregateState) frame(name string, windows []aggregateWindow, reducer string) *data.Frame {
+	timeField := data.NewFieldFromFieldType(data.FieldTypeTime, 0)
+	timeField.Name = "time"
+	fields := []*data.Field{timeField}
+	stringFields := make([]*data.Field, len(s.stringFields))
+	for i, n := range s.stringFields {
+		stringFields[i] = data.NewFieldFromFieldType(data.FieldTypeNullableString, 0)
+		stringFields[i].Name = n
+		fields = append(fields, stringFields[i])
+	}
+	numberFields := make([]*data.Field, len(s.numberFields))
+	for i, f := range s.numberFields {
+		numberFields[i] = data.NewFieldFromFieldType(data.FieldTypeNullableFloat64, 0)
+		numberFields[i].Name = f.Name
+		numberFields[i].Labels = f.Labels
+		numberFields[i].Config = f.Config
+		fields = append(fields, numberFields[i])
+	}
+
+	for _, w := range windows {
+		for _, key := range w.order {
+			group := w.groups[key]
+			timeField.Append(w.start)
+			for i, f := range stringFields {
+				var v *string
+				if i < len(group.strings) {
+					v = group.strings[i]
+				}
+				f.Append(v)
+			}
+			for i, f := range numberFields {
+				value, ok := group.values[i]
+				if !ok {
+					f.Append(nil)
+					continue
+				}
+				f.Append(value.reduce(reducer))
+			}
+		}
+	}
+	return data.NewFrame(name, fields...)
+}
+
+func (v *aggregateValue) reduce(reducer string) *float64 {
+	var r float64
+	switch reducer {
+	case AggregateReducerMin:
+		r = v.min
+	case AggregateReducerMax:
+		r = v.max
+	case AggregateReducerLast:
+		r = v.last
+	default:
+		r = v.sum / float64(v.count)
+	}
+	return &r
+}
@@ -0,0 +1,125 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateFrameProcessor(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	vars := Vars{OrgID: 1, Channel: "stream/test/aggregate"}
+
+	newFrame := func(offsets []time.Duration, hosts []string, values []float64) *data.Frame {
+		times := make([]time.Time, len(offsets))
+		for i, o := range offsets {
+			times[i] = start.Add(o)
+		}
+		return data.NewFrame("test",
+			data.NewField("time", nil, times),
+			data.NewField("host", nil, hosts),
+			data.NewField("value", nil, values),
+		)
+	}
+
+	testCases := []struct {
+		reducer  string
+		expected []float64
+	}{
+		{reducer: AggregateReducerAvg, expected: []float64{2, 10}},
+		{reducer: AggregateReducerMin, expected: []float64{1, 10}},
+		{reducer: AggregateReducerMax, expected: []float64{3, 10}},
+		{reducer: AggregateReducerLast, expected: []float64{3, 10}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.reducer, func(t *testing.T) {
+			states := NewFrameProcessorStateStorage()
+			proc, err := NewAggregateFrameProcessor(states, AggregateFrameProcessorConfig{IntervalMilliseconds: 1000, Reducer: tc.reducer})
+			require.NoError(t, err)
+
+			frame, err := proc.ProcessFrame(context.Background(), vars, newFrame(
+				[]time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond},
+				[]string{"a", "b", "a"},
+				[]float64{1, 10, 3},
+			))
+			require.NoError(t, err)
+			require.Nil(t, frame, "window is not closed yet")
+
+			// The state survives rebuilding the processor with the same config.
+			proc, err = NewAggregateFrameProcessor(states, AggregateFrameProcessorConfig{IntervalMilliseconds: 1000, Reducer: tc.reducer})
+			require.NoError(t, err)
+
+			frame, err = proc.ProcessFrame(context.Background(), vars, newFrame(
+				[]time.Duration{1100 * time.Millisecond},
+				[]string{"a"},
+				[]float64{100},
+			))
+			require.NoError(t, err)
+			require.NotNil(t, frame)
+			require.Equal(t, 2, frame.Rows())
+			require.Equal(t, start, frame.Fields[0].At(0))
+			require.Equal(t, "a", *frame.Fields[1].At(0).(*string))
+			require.Equal(t, "b", *frame.Fields[1].At(1).(*string))
+			require.Equal(t, tc.expected[0], *frame.Fields[2].At(0).(*float64))
+			require.Equal(t, tc.expected[1], *frame.Fields[2].At(1).(*float64))
+		})
+	}
+
+	t.Run("number fields of previous windows are forgotten", func(t *testing.T) {
+		states := NewFrameProcessorStateStorage()
+		proc, err := NewAggregateFrameProcessor(states, AggregateFrameProcessorConfig{IntervalMilliseconds: 1000, Reducer: AggregateReducerLast})
+		require.NoError(t, err)
+
+		var frame *data.Frame
+		for i := 0; i < 2*maxAggregateNumberFields; i++ {
+			value := data.NewField("value", data.Labels{"pod": fmt.Sprint(i)}, []float64{float64(i)})
+			frame, err = proc.ProcessFrame(context.Background(), vars, data.NewFrame("test",
+				data.NewField("time", nil, []time.Time{start.Add(time.Duration(i) * time.Second)}),
+				value,
+			))
+			require.NoError(t, err)
+		}
+		require.NotNil(t, frame)
+		// Only the fields of the closed window and of the open one are left.
+		require.Len(t, frame.Fields, 3)
+		require.Equal(t, data.Labels{"pod": fmt.Sprint(2*maxAggregateNumberFields - 2)}, frame.Fields[1].Labels)
+		require.Equal(t, float64(2*maxAggregateNumberFields-2), *frame.Fields[1].At(0).(*float64))
+		require.Equal(t, data.Labels{"pod": fmt.Sprint(2*maxAggregateNumberFields - 1)}, frame.Fields[2].Labels)
+		require.Nil(t, frame.Fields[2].At(0))
+
+		state := states.GetOrCreate(proc.key, vars.OrgID, vars.Channel, nil).(*aggregateState)
+		require.Len(t, state.numberFields, 1)
+		require.Len(t, state.numberIndex, 1)
+	})
+
+	t.Run("number fields of a window are limited", func(t *testing.T) {
+		states := NewFrameProcessorStateStorage()
+		proc, err := NewAggregateFrameProcessor(states, AggregateFrameProcessorConfig{IntervalMilliseconds: 1000, Reducer: AggregateReducerLast})
+		require.NoError(t, err)
+
+		fields := []*data.Field{data.NewField("time", nil, []time.Time{start})}
+		for i := 0; i < maxAggregateNumberFields+10; i++ {
+			fields = append(fields, data.NewField("value", data.Labels{"pod": fmt.Sprint(i)}, []float64{float64(i)}))
+		}
+		_, err = proc.ProcessFrame(context.Background(), vars, data.NewFrame("test", fields...))
+		require.NoError(t, err)
+
+		frame, err := proc.ProcessFrame(context.Background(), vars, data.NewFrame("test",
+			data.NewField("time", nil, []time.Time{start.Add(time.Second)}),
+		))
+		require.NoError(t, err)
+		require.NotNil(t, frame)
+		require.Len(t, frame.Fields, maxAggregateNumberFields+1)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := NewAggregateFrameProcessor(nil, AggregateFrameProcessorConfig{Reducer: AggregateReducerAvg})
+		require.Error(t, err)
+		_, err = NewAggregateFrameProcessor(nil, AggregateFrameProcessorConfig{IntervalMilliseconds: 1000, Reducer: "median"})
+		require.Error(t, err)
+	})
+}
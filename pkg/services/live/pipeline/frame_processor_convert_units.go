@@ -0,0 +1,61 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// ConvertUnitsFrameProcessor can convert values of number fields to another unit
+// with a linear conversion: value*scale + offset.
+type ConvertUnitsFrameProcessor struct {
+	config ConvertUnitsFrameProcessorConfig
+}
+
+func NewConvertUnitsFrameProcessor(config ConvertUnitsFrameProcessorConfig) *ConvertUnitsFrameProcessor {
+	return &ConvertUnitsFrameProcessor{config: config}
+}
+
+const FrameProcessorTypeConvertUnits = "convertUnits"
+
+func (p *ConvertUnitsFrameProcessor) Type() string {
+	return FrameProcessorTypeConvertUnits
+}
+
+func (p *ConvertUnitsFrameProcessor) ProcessFrame(_ context.Context, _ Vars, frame *data.Frame) (*data.Frame, error) {
+	for _, conversion := range p.config.Conversions {
+		scale := 1.0
+		if conversion.Scale != nil {
+			scale = *conversion.Scale
+		}
+		for i, f := range frame.Fields {
+			if f.Name != conversion.FieldName || !f.Type().Numeric() {
+				continue
+			}
+			converted := data.NewFieldFromFieldType(data.FieldTypeNullableFloat64, f.Len())
+			converted.Name = f.Name
+			converted.Labels = f.Labels
+			if f.Config != nil {
+				config := *f.Config
+				converted.Config = &config
+			}
+			if conversion.Unit != "" {
+				if converted.Config == nil {
+					converted.Config = &data.FieldConfig{}
+				}
+				converted.Config.Unit = conversion.Unit
+			}
+			for row := 0; row < f.Len(); row++ {
+				v, err := f.NullableFloatAt(row)
+				if err != nil {
+					return nil, err
+				}
+				if v != nil {
+					converted.SetConcrete(row, *v*scale+conversion.Offset)
+				}
+			}
+			frame.Fields[i] = converted
+		}
+	}
+	return frame, nil
+}
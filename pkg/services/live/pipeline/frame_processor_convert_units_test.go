@@ -0,0 +1,29 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertUnitsFrameProcessor(t *testing.T) {
+	scale := 1.8
+	proc := NewConvertUnitsFrameProcessor(ConvertUnitsFrameProcessorConfig{
+		Conversions: []UnitConversion{{FieldName: "temperature", Scale: &scale, Offset: 32, Unit: "fahrenheit"}},
+	})
+	frame, err := proc.ProcessFrame(context.Background(), Vars{}, data.NewFrame("test",
+		data.NewField("temperature", nil, []int64{0, 100}),
+	))
+	require.NoError(t, err)
+	require.Equal(t, 32.0, *frame.Fields[0].At(0).(*float64))
+	require.Equal(t, 212.0, *frame.Fields[0].At(1).(*float64))
+	require.Equal(t, "fahrenheit", frame.Fields[0].Config.Unit)
+
+	renamed, err := NewRenameFieldsFrameProcessor(RenameFieldsFrameProcessorConfig{
+		Names: map[string]string{"temperature": "temperature_f"},
+	}).ProcessFrame(context.Background(), Vars{}, frame)
+	require.NoError(t, err)
+	require.Equal(t, "temperature_f", renamed.Fields[0].Name)
+}
@@ -0,0 +1,103 @@
+package pipeline
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// DedupFrameProcessor drops rows with values unchanged since the last passed row of
+// the same series, a series is identified by values of string fields in a row. Only
+// the configured fields are compared, all fields except time and string fields are
+// compared when no field names are set. An unchanged row is still passed when the
+// heartbeat interval passed since the last passed row of the series. Processing stops
+// when all rows of a frame are dropped.
+type DedupFrameProcessor struct {
+	config    DedupFrameProcessorConfig
+	heartbeat time.Duration
+	states    *FrameProcessorStateStorage
+	key       string
+	now       func() time.Time
+}
+
+func NewDedupFrameProcessor(states *FrameProcessorStateStorage, config DedupFrameProcessorConfig) *DedupFrameProcessor {
+	if states == nil {
+		states = NewFrameProcessorStateStorage()
+	}
+	return &DedupFrameProcessor{
+		config:    config,
+		heartbeat: time.Duration(config.HeartbeatMilliseconds) * time.Millisecond,
+		states:    states,
+		key:       frameProcessorStateKey(FrameProcessorTypeDedup, config),
+		now:       time.Now,
+	}
+}
+
+const FrameProcessorTypeDedup = "dedup"
+
+func (p *DedupFrameProcessor) Type() string {
+	return FrameProcessorTypeDedup
+}
+
+func (p *DedupFrameProcessor) stateKey() string {
+	return p.key
+}
+
+type dedupState struct {
+	mu   sync.Mutex
+	last map[string]dedupRow
+}
+
+type dedupRow struct {
+	time   time.Time
+	values []any
+}
+
+func (p *DedupFrameProcessor) ProcessFrame(_ context.Context, vars Vars, frame *data.Frame) (*data.Frame, error) {
+	state := p.states.GetOrCreate(p.key, vars.OrgID, vars.Channel, func() any {
+		return &dedupState{last: map[string]dedupRow{}}
+	}).(*dedupState)
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	var compared []*data.Field
+	for _, f := range frame.Fields {
+		if len(p.config.FieldNames) > 0 {
+			if stringInSlice(f.Name, p.config.FieldNames) {
+				compared = append(compared, f)
+			}
+			continue
+		}
+		if f.Type().Time() || f.Type() == data.FieldTypeString || f.Type() == data.FieldTypeNullableString {
+			continue
+		}
+		compared = append(compared, f)
+	}
+
+	now := p.now()
+	timeIndex := frameTimeField(frame)
+	result := frame.EmptyCopy()
+	for row := 0; row < frame.Rows(); row++ {
+		key, _ := rowGroupKey(frame, row)
+		current := dedupRow{time: rowTime(frame, timeIndex, row, now), values: make([]any, len(compared))}
+		for i, f := range compared {
+			current.values[i], _ = f.ConcreteAt(row)
+		}
+		last, ok := state.last[key]
+		if ok && reflect.DeepEqual(last.values, current.values) {
+			if p.heartbeat <= 0 || current.time.Sub(last.time) < p.heartbeat {
+				continue
+			}
+		}
+		state.last[key] = current
+		result.AppendRow(frame.RowCopy(row)...)
+	}
+	if result.Rows() == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
@@ -0,0 +1,43 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupFrameProcessor(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	vars := Vars{OrgID: 1, Channel: "stream/test/dedup"}
+	proc := NewDedupFrameProcessor(nil, DedupFrameProcessorConfig{HeartbeatMilliseconds: 10000})
+
+	frame, err := proc.ProcessFrame(context.Background(), vars, data.NewFrame("test",
+		data.NewField("time", nil, []time.Time{start, start.Add(time.Second), start.Add(2 * time.Second), start.Add(3 * time.Second)}),
+		data.NewField("host", nil, []string{"a", "a", "b", "a"}),
+		data.NewField("value", nil, []float64{1, 1, 1, 2}),
+	))
+	require.NoError(t, err)
+	require.Equal(t, 3, frame.Rows())
+	require.Equal(t, []any{start, "a", 1.0}, frame.RowCopy(0))
+	require.Equal(t, []any{start.Add(2 * time.Second), "b", 1.0}, frame.RowCopy(1))
+	require.Equal(t, []any{start.Add(3 * time.Second), "a", 2.0}, frame.RowCopy(2))
+
+	frame, err = proc.ProcessFrame(context.Background(), vars, data.NewFrame("test",
+		data.NewField("time", nil, []time.Time{start.Add(4 * time.Second)}),
+		data.NewField("host", nil, []string{"a"}),
+		data.NewField("value", nil, []float64{2}),
+	))
+	require.NoError(t, err)
+	require.Nil(t, frame)
+
+	frame, err = proc.ProcessFrame(context.Background(), vars, data.NewFrame("test",
+		data.NewField("time", nil, []time.Time{start.Add(13 * time.Second)}),
+		data.NewField("host", nil, []string{"a"}),
+		data.NewField("value", nil, []float64{2}),
+	))
+	require.NoError(t, err)
+	require.Equal(t, 1, frame.Rows(), "heartbeat passes unchanged row")
+}
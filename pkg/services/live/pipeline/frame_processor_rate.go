@@ -0,0 +1,103 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// RateFrameProcessor replaces values of number fields with their per-second rate
+// of change since the previous value of the same series. A series is identified by
+// field name, field labels and values of string fields in a row. The first value
+// of a series has no rate and becomes null.
+type RateFrameProcessor struct {
+	config RateFrameProcessorConfig
+	states *FrameProcessorStateStorage
+	key    string
+	now    func() time.Time
+}
+
+func NewRateFrameProcessor(states *FrameProcessorStateStorage, config RateFrameProcessorConfig) *RateFrameProcessor {
+	if states == nil {
+		states = NewFrameProcessorStateStorage()
+	}
+	return &RateFrameProcessor{
+		config: config,
+		states: states,
+		key:    frameProcessorStateKey(FrameProcessorTypeRate, config),
+		now:    time.Now,
+	}
+}
+
+const FrameProcessorTypeRate = "rate"
+
+func (p *RateFrameProcessor) Type() string {
+	return FrameProcessorTypeRate
+}
+
+func (p *RateFrameProcessor) stateKey() string {
+	return p.key
+}
+
+type rateState struct {
+	mu       sync.Mutex
+	previous map[string]ratePoint
+}
+
+type ratePoint struct {
+	time  time.Time
+	value float64
+}
+
+func (p *RateFrameProcessor) ProcessFrame(_ context.Context, vars Vars, frame *data.Frame) (*data.Frame, error) {
+	state := p.states.GetOrCreate(p.key, vars.OrgID, vars.Channel, func() any {
+		return &rateState{previous: map[string]ratePoint{}}
+	}).(*rateState)
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	now := p.now()
+	timeIndex := frameTimeField(frame)
+	for i, f := range frame.Fields {
+		if !isProcessedNumberField(f, p.config.FieldNames) {
+			continue
+		}
+		rates := data.NewFieldFromFieldType(data.FieldTypeNullableFloat64, f.Len())
+		rates.Name = f.Name
+		rates.Labels = f.Labels
+		rates.Config = f.Config
+		for row := 0; row < f.Len(); row++ {
+			v, err := f.NullableFloatAt(row)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				continue
+			}
+			groupKey, _ := rowGroupKey(frame, row)
+			key := fieldKey(f) + "|" + groupKey
+			point := ratePoint{time: rowTime(frame, timeIndex, row, now), value: *v}
+			previous, ok := state.previous[key]
+			if ok && !point.time.After(previous.time) {
+				// Keep the previous point for out of order and duplicate rows.
+				continue
+			}
+			state.previous[key] = point
+			if !ok {
+				continue
+			}
+			delta := point.value - previous.value
+			if p.config.Counter && delta < 0 {
+				// Counter was reset, it started from zero.
+				delta = point.value
+			}
+			rate := delta / point.time.Sub(previous.time).Seconds()
+			rates.SetConcrete(row, rate)
+		}
+		frame.Fields[i] = rates
+	}
+	return frame, nil
+}
@@ -0,0 +1,32 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateFrameProcessor(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	vars := Vars{OrgID: 1, Channel: "stream/test/rate"}
+	proc := NewRateFrameProcessor(nil, RateFrameProcessorConfig{Counter: true})
+
+	frame, err := proc.ProcessFrame(context.Background(), vars, data.NewFrame("test",
+		data.NewField("time", nil, []time.Time{start, start.Add(2 * time.Second)}),
+		data.NewField("requests", nil, []float64{10, 20}),
+	))
+	require.NoError(t, err)
+	require.Nil(t, frame.Fields[1].At(0))
+	require.Equal(t, 5.0, *frame.Fields[1].At(1).(*float64))
+
+	// Counter reset.
+	frame, err = proc.ProcessFrame(context.Background(), vars, data.NewFrame("test",
+		data.NewField("time", nil, []time.Time{start.Add(4 * time.Second)}),
+		data.NewField("requests", nil, []float64{4}),
+	))
+	require.NoError(t, err)
+	require.Equal(t, 2.0, *frame.Fields[1].At(0).(*float64))
+}
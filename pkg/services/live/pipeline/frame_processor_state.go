@@ -0,0 +1,154 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/services/live/orgchannel"
+)
+
+// frameProcessorStateIdleTimeout is how long the state of a channel is kept after
+// a frame of the channel was last processed.
+const frameProcessorStateIdleTimeout = time.Hour
+
+// FrameProcessorStateStorage keeps the state of stateful frame processors in memory.
+// Channel rules are rebuilt periodically, keeping state outside of processors allows
+// aggregation windows and previous values to survive a rebuild. Not usable in HA setup.
+type FrameProcessorStateStorage struct {
+	mu     sync.Mutex
+	states map[string]*frameProcessorState
+	now    func() time.Time
+}
+
+type frameProcessorState struct {
+	processorKey string
+	orgID        int64
+	state        any
+	lastUsed     time.Time
+}
+
+func NewFrameProcessorStateStorage() *FrameProcessorStateStorage {
+	return &FrameProcessorStateStorage{
+		states: map[string]*frameProcessorState{},
+		now:    time.Now,
+	}
+}
+
+// GetOrCreate returns the state stored for the processor key and the channel,
+// creating it with newState if there is no state yet.
+func (s *FrameProcessorStateStorage) GetOrCreate(processorKey string, orgID int64, channel string, newState func() any) any {
+	key := processorKey + "|" + orgchannel.PrependOrgID(orgID, channel)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[key]
+	if !ok {
+		state = &frameProcessorState{processorKey: processorKey, orgID: orgID, state: newState()}
+		s.states[key] = state
+	}
+	state.lastUsed = s.now()
+	return state.state
+}
+
+// Retain is called when the channel rules of the org are rebuilt with the keys of
+// their processors. It removes the states of the org that belong to other processors,
+// which were removed or whose configuration changed, and the states of all channels
+// that were not used for frameProcessorStateIdleTimeout.
+func (s *FrameProcessorStateStorage) Retain(orgID int64, processorKeys map[string]struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for key, state := range s.states {
+		_, used := processorKeys[state.processorKey]
+		if (state.orgID == orgID && !used) || now.Sub(state.lastUsed) > frameProcessorStateIdleTimeout {
+			delete(s.states, key)
+		}
+	}
+}
+
+// statefulFrameProcessor is a frame processor that keeps its state in FrameProcessorStateStorage.
+type statefulFrameProcessor interface {
+	stateKey() string
+}
+
+// addFrameProcessorStateKeys adds the state keys of the processor and of the processors
+// it combines to keys.
+func addFrameProcessorStateKeys(keys map[string]struct{}, proc FrameProcessor) {
+	switch p := proc.(type) {
+	case statefulFrameProcessor:
+		keys[p.stateKey()] = struct{}{}
+	case *MultipleFrameProcessor:
+		for _, child := range p.Processors {
+			addFrameProcessorStateKeys(keys, child)
+		}
+	}
+}
+
+// frameProcessorStateKey returns the key of the processor state. It depends on the
+// processor configuration, so the state is reset when the configuration changes.
+func frameProcessorStateKey(processorType string, config any) string {
+	b, _ := json.Marshal(config)
+	return processorType + "|" + string(b)
+}
+
+// frameTimeField returns the index of the first time field in a frame or -1.
+func frameTimeField(frame *data.Frame) int {
+	for i, f := range frame.Fields {
+		if f.Type().Time() {
+			return i
+		}
+	}
+	return -1
+}
+
+// rowTime returns the time of a row, or now if the frame has no time value for it.
+func rowTime(frame *data.Frame, timeIndex int, row int, now time.Time) time.Time {
+	if timeIndex < 0 {
+		return now
+	}
+	if t, ok := frame.Fields[timeIndex].ConcreteAt(row); ok {
+		return t.(time.Time)
+	}
+	return now
+}
+
+// rowGroupKey returns the values of string fields of a row joined to a key. In
+// frames with labels column or with tags as string fields those values identify
+// the series a row belongs to.
+func rowGroupKey(frame *data.Frame, row int) (string, []*string) {
+	var values []*string
+	var sb strings.Builder
+	for _, f := range frame.Fields {
+		if f.Type() != data.FieldTypeString && f.Type() != data.FieldTypeNullableString {
+			continue
+		}
+		v, ok := f.ConcreteAt(row)
+		if !ok {
+			values = append(values, nil)
+			sb.WriteString("\x00|")
+			continue
+		}
+		s := v.(string)
+		values = append(values, &s)
+		sb.WriteString(s)
+		sb.WriteString("|")
+	}
+	return sb.String(), values
+}
+
+// fieldKey identifies a field by its name and labels.
+func fieldKey(f *data.Field) string {
+	return f.Name + f.Labels.String()
+}
+
+// isProcessedNumberField reports whether the field is a number field selected
+// by the field names. All number fields are selected when fieldNames is empty.
+func isProcessedNumberField(f *data.Field, fieldNames []string) bool {
+	if !f.Type().Numeric() {
+		return false
+	}
+	return len(fieldNames) == 0 || stringInSlice(f.Name, fieldNames)
+}
@@ -0,0 +1,50 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameProcessorStateStorage(t *testing.T) {
+	newState := func() any { return new(int) }
+
+	t.Run("states of processors that are no longer used are removed", func(t *testing.T) {
+		states := NewFrameProcessorStateStorage()
+		kept := states.GetOrCreate("kept", 1, "stream/test/a", newState)
+		removed := states.GetOrCreate("removed", 1, "stream/test/a", newState)
+		otherOrg := states.GetOrCreate("removed", 2, "stream/test/a", newState)
+
+		states.Retain(1, map[string]struct{}{"kept": {}})
+
+		require.Len(t, states.states, 2)
+		require.Same(t, kept, states.GetOrCreate("kept", 1, "stream/test/a", newState))
+		require.Same(t, otherOrg, states.GetOrCreate("removed", 2, "stream/test/a", newState))
+		require.NotSame(t, removed, states.GetOrCreate("removed", 1, "stream/test/a", newState))
+	})
+
+	t.Run("states of idle channels are removed", func(t *testing.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		states := NewFrameProcessorStateStorage()
+		states.now = func() time.Time { return now }
+		idle := states.GetOrCreate("proc", 2, "stream/test/idle", newState)
+		now = now.Add(frameProcessorStateIdleTimeout / 2)
+		active := states.GetOrCreate("proc", 2, "stream/test/active", newState)
+		now = now.Add(frameProcessorStateIdleTimeout/2 + time.Second)
+
+		states.Retain(1, map[string]struct{}{})
+
+		require.Len(t, states.states, 1)
+		require.Same(t, active, states.GetOrCreate("proc", 2, "stream/test/active", newState))
+		require.NotSame(t, idle, states.GetOrCreate("proc", 2, "stream/test/idle", newState))
+	})
+
+	t.Run("keys of combined processors are collected", func(t *testing.T) {
+		aggregate, err := NewAggregateFrameProcessor(nil, AggregateFrameProcessorConfig{IntervalMilliseconds: 1000, Reducer: AggregateReducerAvg})
+		require.NoError(t, err)
+		keys := map[string]struct{}{}
+		addFrameProcessorStateKeys(keys, NewMultipleFrameProcessor(aggregate, NewKeepFieldsFrameProcessor(KeepFieldsFrameProcessorConfig{})))
+		require.Equal(t, map[string]struct{}{aggregate.key: {}}, keys)
+	})
+}
@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/grafana/grafana/pkg/services/live/pipeline/pattern"
 	"github.com/grafana/grafana/pkg/services/live/pipeline/tree"
@@ -154,8 +155,23 @@ type ChannelRuleCreateCmd struct {
 type ChannelRuleUpdateCmd struct {
 	Pattern  string              `json:"pattern"`
 	Settings ChannelRuleSettings `json:"settings"`
+	// Version is the version of the rule the update is based on. The update
+	// fails if the rule was changed since. Not checked if zero.
+	Version int64 `json:"version,omitempty"`
 }
 
 type ChannelRuleDeleteCmd struct {
 	Pattern string `json:"pattern"`
 }
+
+type ChannelRuleVersionsGetCmd struct {
+	Pattern string `json:"pattern"`
+}
+
+// ChannelRuleVersion is a version of a channel rule in the history of its changes.
+type ChannelRuleVersion struct {
+	Pattern  string              `json:"pattern"`
+	Version  int64               `json:"version"`
+	Settings ChannelRuleSettings `json:"settings"`
+	Created  time.Time           `json:"created"`
+}
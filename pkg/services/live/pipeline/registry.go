@@ -62,6 +62,20 @@ var ConvertersRegistry = []EntityInfo{
 			FrameFormat: "labels_column",
 		},
 	},
+	{
+		Type:        ConverterTypePrometheusAuto,
+		Description: "accept Prometheus text exposition format",
+		Example: AutoPrometheusConverterConfig{
+			FrameFormat: "labels_column",
+		},
+	},
+	{
+		Type:        ConverterTypeOTLPAuto,
+		Description: "accept OTLP metrics encoded as protobuf or JSON",
+		Example: AutoOTLPConverterConfig{
+			FrameFormat: "labels_column",
+		},
+	},
 	{
 		Type:        ConverterTypeJsonFrame,
 		Description: "JSON-encoded Grafana data frame",
@@ -79,6 +93,31 @@ var FrameProcessorsRegistry = []EntityInfo{
 		Description: "list the fields that should be removed",
 		Example:     DropFieldsFrameProcessorConfig{},
 	},
+	{
+		Type:        FrameProcessorTypeRenameFields,
+		Description: "rename fields",
+		Example:     RenameFieldsFrameProcessorConfig{},
+	},
+	{
+		Type:        FrameProcessorTypeConvertUnits,
+		Description: "convert values of number fields to another unit",
+		Example:     ConvertUnitsFrameProcessorConfig{},
+	},
+	{
+		Type:        FrameProcessorTypeAggregate,
+		Description: "aggregate number fields over time windows per label set",
+		Example:     AggregateFrameProcessorConfig{},
+	},
+	{
+		Type:        FrameProcessorTypeRate,
+		Description: "replace values of number fields with per-second rate",
+		Example:     RateFrameProcessorConfig{},
+	},
+	{
+		Type:        FrameProcessorTypeDedup,
+		Description: "drop rows with unchanged values",
+		Example:     DedupFrameProcessorConfig{},
+	},
 }
 
 var DataOutputsRegistry = []EntityInfo{
@@ -14,6 +14,7 @@ type StorageRuleBuilder struct {
 	Node                 *centrifuge.Node
 	ManagedStream        *managedstream.Runner
 	FrameStorage         *FrameStorage
+	ProcessorStates      *FrameProcessorStateStorage
 	Storage              Storage
 	ChannelHandlerGetter ChannelHandlerGetter
 	SecretsService       secrets.Service
@@ -69,6 +70,16 @@ func (f *StorageRuleBuilder) extractConverter(config *ConverterConfig) (Converte
 			return nil, missingConfiguration
 		}
 		return NewAutoInfluxConverter(*config.AutoInfluxConverterConfig), nil
+	case ConverterTypePrometheusAuto:
+		if config.AutoPrometheusConverterConfig == nil {
+			return nil, missingConfiguration
+		}
+		return NewAutoPrometheusConverter(*config.AutoPrometheusConverterConfig), nil
+	case ConverterTypeOTLPAuto:
+		if config.AutoOTLPConverterConfig == nil {
+			return nil, missingConfiguration
+		}
+		return NewAutoOTLPConverter(*config.AutoOTLPConverterConfig), nil
 	default:
 		return nil, fmt.Errorf("unknown converter type: %s", config.Type)
 	}
@@ -90,6 +101,35 @@ func (f *StorageRuleBuilder) extractFrameProcessor(config *FrameProcessorConfig)
 			return nil, missingConfiguration
 		}
 		return NewKeepFieldsFrameProcessor(*config.KeepFieldsProcessorConfig), nil
+	case FrameProcessorTypeRenameFields:
+		if config.RenameFieldsProcessorConfig == nil {
+			return nil, missingConfiguration
+		}
+		return NewRenameFieldsFrameProcessor(*config.RenameFieldsProcessorConfig), nil
+	case FrameProcessorTypeConvertUnits:
+		if config.ConvertUnitsProcessorConfig == nil {
+			return nil, missingConfiguration
+		}
+		return NewConvertUnitsFrameProcessor(*config.ConvertUnitsProcessorConfig), nil
+	case FrameProcessorTypeAggregate:
+		if config.AggregateProcessorConfig == nil {
+			return nil, missingConfiguration
+		}
+		proc, err := NewAggregateFrameProcessor(f.ProcessorStates, *config.AggregateProcessorConfig)
+		if err != nil {
+			return nil, err
+		}
+		return proc, nil
+	case FrameProcessorTypeRate:
+		if config.RateProcessorConfig == nil {
+			return nil, missingConfiguration
+		}
+		return NewRateFrameProcessor(f.ProcessorStates, *config.RateProcessorConfig), nil
+	case FrameProcessorTypeDedup:
+		if config.DedupProcessorConfig == nil {
+			return nil, missingConfiguration
+		}
+		return NewDedupFrameProcessor(f.ProcessorStates, *config.DedupProcessorConfig), nil
 	case FrameProcessorTypeMultiple:
 		if config.MultipleProcessorConfig == nil {
 			return nil, missingConfiguration
@@ -311,6 +351,7 @@ func (f *StorageRuleBuilder) BuildRules(ctx context.Context, orgID int64) ([]*Li
 	}
 
 	rules := make([]*LiveChannelRule, 0, len(channelRules))
+	stateKeys := map[string]struct{}{}
 
 	for _, ruleConfig := range channelRules {
 		rule := &LiveChannelRule{
@@ -340,6 +381,7 @@ func (f *StorageRuleBuilder) BuildRules(ctx context.Context, orgID int64) ([]*Li
 				return nil, fmt.Errorf("error building processor for %s: %w", rule.Pattern, err)
 			}
 			processors = append(processors, proc)
+			addFrameProcessorStateKeys(stateKeys, proc)
 		}
 		rule.FrameProcessors = processors
 
@@ -376,5 +418,8 @@ func (f *StorageRuleBuilder) BuildRules(ctx context.Context, orgID int64) ([]*Li
 		rules = append(rules, rule)
 	}
 
+	if f.ProcessorStates != nil {
+		f.ProcessorStates.Retain(orgID, stateKeys)
+	}
 	return rules, nil
 }
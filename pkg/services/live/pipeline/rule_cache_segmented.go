@@ -59,6 +59,14 @@ func (s *CacheSegmentedTree) fillOrg(orgID int64) error {
 	return nil
 }
 
+// Invalidate drops the cached rules of the org, so they are built again from
+// the storage on the next access.
+func (s *CacheSegmentedTree) Invalidate(orgID int64) {
+	s.radixMu.Lock()
+	defer s.radixMu.Unlock()
+	delete(s.radix, orgID)
+}
+
 func (s *CacheSegmentedTree) Get(orgID int64, channel string) (*LiveChannelRule, bool, error) {
 	s.radixMu.RLock()
 	_, ok := s.radix[orgID]
@@ -1,6 +1,17 @@
 package pipeline
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrChannelRuleNotFound        = errors.New("rule not found")
+	ErrChannelRuleExists          = errors.New("rule already exists")
+	ErrChannelRuleVersionConflict = errors.New("rule was changed since the version the update is based on")
+	ErrWriteConfigNotFound        = errors.New("write config not found")
+	ErrWriteConfigExists          = errors.New("write config already exists")
+)
 
 // Storage describes all methods to manage Live pipeline persistent data.
 type Storage interface {
@@ -14,3 +25,10 @@ type Storage interface {
 	UpdateChannelRule(_ context.Context, orgID int64, cmd ChannelRuleUpdateCmd) (ChannelRule, error)
 	DeleteChannelRule(_ context.Context, orgID int64, cmd ChannelRuleDeleteCmd) error
 }
+
+// VersionedStorage is a Storage that keeps the history of channel rule changes.
+type VersionedStorage interface {
+	Storage
+	// ListChannelRuleVersions returns the versions of a channel rule, newest first.
+	ListChannelRuleVersions(_ context.Context, orgID int64, cmd ChannelRuleVersionsGetCmd) ([]ChannelRuleVersion, error)
+}
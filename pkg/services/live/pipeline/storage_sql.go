@@ -0,0 +1,404 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+var _ VersionedStorage = (*SQLStorage)(nil)
+
+// SQLStorage keeps channel rules and write configs in the database, so they
+// are shared by all Grafana instances. Every change of a channel rule is kept
+// in the rule version history.
+type SQLStorage struct {
+	store          db.DB
+	secretsService secrets.Service
+}
+
+// NewSQLStorage creates new SQLStorage.
+func NewSQLStorage(store db.DB, secretsService secrets.Service) *SQLStorage {
+	return &SQLStorage{store: store, secretsService: secretsService}
+}
+
+type channelRuleRecord struct {
+	ID       int64     `xorm:"pk autoincr 'id'"`
+	OrgID    int64     `xorm:"org_id"`
+	Pattern  string    `xorm:"pattern"`
+	Version  int64     `xorm:"'version'"`
+	Settings string    `xorm:"settings"`
+	Created  time.Time `xorm:"'created'"`
+	Updated  time.Time `xorm:"'updated'"`
+}
+
+func (channelRuleRecord) TableName() string {
+	return "live_channel_rule"
+}
+
+type channelRuleVersionRecord struct {
+	ID       int64     `xorm:"pk autoincr 'id'"`
+	OrgID    int64     `xorm:"org_id"`
+	Pattern  string    `xorm:"pattern"`
+	Version  int64     `xorm:"'version'"`
+	Settings string    `xorm:"settings"`
+	Created  time.Time `xorm:"'created'"`
+}
+
+func (channelRuleVersionRecord) TableName() string {
+	return "live_channel_rule_version"
+}
+
+type writeConfigRecord struct {
+	ID             int64     `xorm:"pk autoincr 'id'"`
+	OrgID          int64     `xorm:"org_id"`
+	UID            string    `xorm:"uid"`
+	Settings       string    `xorm:"settings"`
+	SecureSettings string    `xorm:"secure_settings"`
+	Created        time.Time `xorm:"'created'"`
+	Updated        time.Time `xorm:"'updated'"`
+}
+
+func (writeConfigRecord) TableName() string {
+	return "live_write_config"
+}
+
+func (r channelRuleRecord) toChannelRule() (ChannelRule, error) {
+	rule := ChannelRule{
+		OrgId:   r.OrgID,
+		Pattern: r.Pattern,
+		Version: r.Version,
+	}
+	if err := json.Unmarshal([]byte(r.Settings), &rule.Settings); err != nil {
+		return ChannelRule{}, fmt.Errorf("can't unmarshal settings of channel rule %s: %w", r.Pattern, err)
+	}
+	return rule, nil
+}
+
+func (r writeConfigRecord) toWriteConfig() (WriteConfig, error) {
+	config := WriteConfig{
+		OrgId: r.OrgID,
+		UID:   r.UID,
+	}
+	if err := json.Unmarshal([]byte(r.Settings), &config.Settings); err != nil {
+		return WriteConfig{}, fmt.Errorf("can't unmarshal settings of write config %s: %w", r.UID, err)
+	}
+	if r.SecureSettings != "" {
+		if err := json.Unmarshal([]byte(r.SecureSettings), &config.SecureSettings); err != nil {
+			return WriteConfig{}, fmt.Errorf("can't unmarshal secure settings of write config %s: %w", r.UID, err)
+		}
+	}
+	return config, nil
+}
+
+func (s *SQLStorage) ListWriteConfigs(ctx context.Context, orgID int64) ([]WriteConfig, error) {
+	var records []writeConfigRecord
+	err := s.store.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("org_id = ?", orgID).Asc("uid").Find(&records)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't read write configs: %w", err)
+	}
+	configs := make([]WriteConfig, 0, len(records))
+	for _, r := range records {
+		config, err := r.toWriteConfig()
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+func (s *SQLStorage) GetWriteConfig(ctx context.Context, orgID int64, cmd WriteConfigGetCmd) (WriteConfig, bool, error) {
+	var record writeConfigRecord
+	var found bool
+	err := s.store.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		found, err = sess.Where("org_id = ? AND uid = ?", orgID, cmd.UID).Get(&record)
+		return err
+	})
+	if err != nil {
+		return WriteConfig{}, false, fmt.Errorf("can't read write config: %w", err)
+	}
+	if !found {
+		return WriteConfig{}, false, nil
+	}
+	config, err := record.toWriteConfig()
+	if err != nil {
+		return WriteConfig{}, false, err
+	}
+	return config, true, nil
+}
+
+// newWriteConfigRecord encrypts the secure settings and validates the write config.
+func (s *SQLStorage) newWriteConfigRecord(ctx context.Context, orgID int64, uid string, settings WriteSettings, secureSettings map[string]string) (writeConfigRecord, WriteConfig, error) {
+	encrypted, err := s.secretsService.EncryptJsonData(ctx, secureSettings, secrets.WithoutScope())
+	if err != nil {
+		return writeConfigRecord{}, WriteConfig{}, fmt.Errorf("error encrypting data: %w", err)
+	}
+	config := WriteConfig{
+		OrgId:          orgID,
+		UID:            uid,
+		Settings:       settings,
+		SecureSettings: encrypted,
+	}
+	ok, reason := config.Valid()
+	if !ok {
+		return writeConfigRecord{}, WriteConfig{}, fmt.Errorf("invalid write config: %s", reason)
+	}
+	settingsJSON, err := json.Marshal(config.Settings)
+	if err != nil {
+		return writeConfigRecord{}, WriteConfig{}, err
+	}
+	secureSettingsJSON, err := json.Marshal(config.SecureSettings)
+	if err != nil {
+		return writeConfigRecord{}, WriteConfig{}, err
+	}
+	return writeConfigRecord{
+		OrgID:          orgID,
+		UID:            uid,
+		Settings:       string(settingsJSON),
+		SecureSettings: string(secureSettingsJSON),
+	}, config, nil
+}
+
+func (s *SQLStorage) CreateWriteConfig(ctx context.Context, orgID int64, cmd WriteConfigCreateCmd) (WriteConfig, error) {
+	if cmd.UID == "" {
+		cmd.UID = util.GenerateShortUID()
+	}
+	record, config, err := s.newWriteConfigRecord(ctx, orgID, cmd.UID, cmd.Settings, cmd.SecureSettings)
+	if err != nil {
+		return WriteConfig{}, err
+	}
+	err = s.store.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		exists, err := sess.Where("org_id = ? AND uid = ?", orgID, cmd.UID).Exist(&writeConfigRecord{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%w in org: %s", ErrWriteConfigExists, cmd.UID)
+		}
+		record.Created = time.Now()
+		record.Updated = record.Created
+		_, err = sess.Insert(&record)
+		return err
+	})
+	if err != nil {
+		return WriteConfig{}, err
+	}
+	return config, nil
+}
+
+func (s *SQLStorage) UpdateWriteConfig(ctx context.Context, orgID int64, cmd WriteConfigUpdateCmd) (WriteConfig, error) {
+	record, config, err := s.newWriteConfigRecord(ctx, orgID, cmd.UID, cmd.Settings, cmd.SecureSettings)
+	if err != nil {
+		return WriteConfig{}, err
+	}
+	var updated int64
+	err = s.store.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		record.Updated = time.Now()
+		var err error
+		updated, err = sess.Where("org_id = ? AND uid = ?", orgID, cmd.UID).Cols("settings", "secure_settings", "updated").Update(&record)
+		return err
+	})
+	if err != nil {
+		return WriteConfig{}, err
+	}
+	if updated == 0 {
+		return s.CreateWriteConfig(ctx, orgID, WriteConfigCreateCmd(cmd))
+	}
+	return config, nil
+}
+
+func (s *SQLStorage) DeleteWriteConfig(ctx context.Context, orgID int64, cmd WriteConfigDeleteCmd) error {
+	return s.store.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		deleted, err := sess.Where("org_id = ? AND uid = ?", orgID, cmd.UID).Delete(&writeConfigRecord{})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return ErrWriteConfigNotFound
+		}
+		return nil
+	})
+}
+
+func (s *SQLStorage) ListChannelRules(ctx context.Context, orgID int64) ([]ChannelRule, error) {
+	var rules []ChannelRule
+	err := s.store.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		rules, err = listChannelRules(sess, orgID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't read channel rules: %w", err)
+	}
+	return rules, nil
+}
+
+func listChannelRules(sess *db.Session, orgID int64) ([]ChannelRule, error) {
+	var records []channelRuleRecord
+	if err := sess.Where("org_id = ?", orgID).Asc("pattern").Find(&records); err != nil {
+		return nil, err
+	}
+	rules := make([]ChannelRule, 0, len(records))
+	for _, r := range records {
+		rule, err := r.toChannelRule()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// saveChannelRule validates the rule against the other rules of the org, then
+// inserts or updates it with the next version, and adds the version to the
+// history. If expectedVersion is not zero, the stored rule must have it.
+func saveChannelRule(sess *db.Session, rule ChannelRule, expectedVersion int64) (ChannelRule, error) {
+	ok, reason := rule.Valid()
+	if !ok {
+		return ChannelRule{}, fmt.Errorf("invalid channel rule: %s", reason)
+	}
+
+	existing, err := listChannelRules(sess, rule.OrgId)
+	if err != nil {
+		return ChannelRule{}, err
+	}
+	var current *ChannelRule
+	rules := make([]ChannelRule, 0, len(existing)+1)
+	for i, r := range existing {
+		if r.Pattern == rule.Pattern {
+			current = &existing[i]
+			continue
+		}
+		rules = append(rules, r)
+	}
+	if expectedVersion != 0 && (current == nil || current.Version != expectedVersion) {
+		return ChannelRule{}, ErrChannelRuleVersionConflict
+	}
+	ok, reason = checkRulesValid(rule.OrgId, append(rules, rule))
+	if !ok {
+		return ChannelRule{}, errors.New(reason)
+	}
+
+	// versions continue after the ones of a deleted rule with the same pattern.
+	var latest channelRuleVersionRecord
+	if _, err := sess.Where("org_id = ? AND pattern = ?", rule.OrgId, rule.Pattern).Desc("version").Get(&latest); err != nil {
+		return ChannelRule{}, err
+	}
+	rule.Version = latest.Version + 1
+
+	settings, err := json.Marshal(rule.Settings)
+	if err != nil {
+		return ChannelRule{}, err
+	}
+	now := time.Now()
+	record := channelRuleRecord{
+		OrgID:    rule.OrgId,
+		Pattern:  rule.Pattern,
+		Version:  rule.Version,
+		Settings: string(settings),
+		Created:  now,
+		Updated:  now,
+	}
+	if current == nil {
+		if _, err := sess.Insert(&record); err != nil {
+			return ChannelRule{}, err
+		}
+	} else {
+		// the version condition prevents lost updates by concurrent changes.
+		updated, err := sess.Where("org_id = ? AND pattern = ? AND version = ?", rule.OrgId, rule.Pattern, current.Version).
+			Cols("version", "settings", "updated").Update(&record)
+		if err != nil {
+			return ChannelRule{}, err
+		}
+		if updated == 0 {
+			return ChannelRule{}, ErrChannelRuleVersionConflict
+		}
+	}
+	if _, err := sess.Insert(&channelRuleVersionRecord{
+		OrgID:    rule.OrgId,
+		Pattern:  rule.Pattern,
+		Version:  rule.Version,
+		Settings: string(settings),
+		Created:  now,
+	}); err != nil {
+		return ChannelRule{}, err
+	}
+	return rule, nil
+}
+
+func (s *SQLStorage) CreateChannelRule(ctx context.Context, orgID int64, cmd ChannelRuleCreateCmd) (ChannelRule, error) {
+	var result ChannelRule
+	err := s.store.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		exists, err := sess.Where("org_id = ? AND pattern = ?", orgID, cmd.Pattern).Exist(&channelRuleRecord{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%w in org: %s", ErrChannelRuleExists, cmd.Pattern)
+		}
+		result, err = saveChannelRule(sess, ChannelRule{OrgId: orgID, Pattern: cmd.Pattern, Settings: cmd.Settings}, 0)
+		return err
+	})
+	if err != nil {
+		return ChannelRule{}, err
+	}
+	return result, nil
+}
+
+func (s *SQLStorage) UpdateChannelRule(ctx context.Context, orgID int64, cmd ChannelRuleUpdateCmd) (ChannelRule, error) {
+	var result ChannelRule
+	err := s.store.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		result, err = saveChannelRule(sess, ChannelRule{OrgId: orgID, Pattern: cmd.Pattern, Settings: cmd.Settings}, cmd.Version)
+		return err
+	})
+	if err != nil {
+		return ChannelRule{}, err
+	}
+	return result, nil
+}
+
+func (s *SQLStorage) DeleteChannelRule(ctx context.Context, orgID int64, cmd ChannelRuleDeleteCmd) error {
+	return s.store.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		deleted, err := sess.Where("org_id = ? AND pattern = ?", orgID, cmd.Pattern).Delete(&channelRuleRecord{})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return ErrChannelRuleNotFound
+		}
+		return nil
+	})
+}
+
+func (s *SQLStorage) ListChannelRuleVersions(ctx context.Context, orgID int64, cmd ChannelRuleVersionsGetCmd) ([]ChannelRuleVersion, error) {
+	var records []channelRuleVersionRecord
+	err := s.store.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("org_id = ? AND pattern = ?", orgID, cmd.Pattern).Desc("version").Find(&records)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't read channel rule versions: %w", err)
+	}
+	versions := make([]ChannelRuleVersion, 0, len(records))
+	for _, r := range records {
+		version := ChannelRuleVersion{
+			Pattern: r.Pattern,
+			Version: r.Version,
+			Created: r.Created,
+		}
+		if err := json.Unmarshal([]byte(r.Settings), &version.Settings); err != nil {
+			return nil, fmt.Errorf("can't unmarshal settings of channel rule %s version %d: %w", r.Pattern, r.Version, err)
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
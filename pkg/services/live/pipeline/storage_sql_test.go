@@ -0,0 +1,120 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func TestIntegrationSQLStorage_ChannelRules(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	storage := NewSQLStorage(db.InitTestDB(t), fakes.NewFakeSecretsService())
+
+	settings := ChannelRuleSettings{Converter: &ConverterConfig{Type: ConverterTypeJsonAuto}}
+	rule, err := storage.CreateChannelRule(ctx, 1, ChannelRuleCreateCmd{Pattern: "stream/test/json", Settings: settings})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rule.Version)
+
+	_, err = storage.CreateChannelRule(ctx, 1, ChannelRuleCreateCmd{Pattern: "stream/test/json", Settings: settings})
+	require.ErrorIs(t, err, ErrChannelRuleExists)
+
+	_, err = storage.CreateChannelRule(ctx, 1, ChannelRuleCreateCmd{Pattern: "stream/test/json", Settings: ChannelRuleSettings{Converter: &ConverterConfig{Type: "unknown"}}})
+	require.Error(t, err)
+
+	t.Run("rules are isolated per org", func(t *testing.T) {
+		_, err := storage.CreateChannelRule(ctx, 2, ChannelRuleCreateCmd{Pattern: "stream/test/json", Settings: settings})
+		require.NoError(t, err)
+		rules, err := storage.ListChannelRules(ctx, 2)
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		require.NoError(t, storage.DeleteChannelRule(ctx, 2, ChannelRuleDeleteCmd{Pattern: "stream/test/json"}))
+		rules, err = storage.ListChannelRules(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+	})
+
+	t.Run("update increments version and keeps history", func(t *testing.T) {
+		influx := ChannelRuleSettings{Converter: &ConverterConfig{
+			Type:                      ConverterTypeInfluxAuto,
+			AutoInfluxConverterConfig: &AutoInfluxConverterConfig{FrameFormat: "wide"},
+		}}
+		updated, err := storage.UpdateChannelRule(ctx, 1, ChannelRuleUpdateCmd{Pattern: "stream/test/json", Settings: influx, Version: 1})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), updated.Version)
+
+		_, err = storage.UpdateChannelRule(ctx, 1, ChannelRuleUpdateCmd{Pattern: "stream/test/json", Settings: settings, Version: 1})
+		require.ErrorIs(t, err, ErrChannelRuleVersionConflict)
+
+		rules, err := storage.ListChannelRules(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, []ChannelRule{{OrgId: 1, Pattern: "stream/test/json", Settings: influx, Version: 2}}, rules)
+
+		versions, err := storage.ListChannelRuleVersions(ctx, 1, ChannelRuleVersionsGetCmd{Pattern: "stream/test/json"})
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		require.Equal(t, int64(2), versions[0].Version)
+		require.Equal(t, influx, versions[0].Settings)
+		require.Equal(t, int64(1), versions[1].Version)
+		require.Equal(t, settings, versions[1].Settings)
+	})
+
+	t.Run("versions continue after delete", func(t *testing.T) {
+		require.NoError(t, storage.DeleteChannelRule(ctx, 1, ChannelRuleDeleteCmd{Pattern: "stream/test/json"}))
+		require.ErrorIs(t, storage.DeleteChannelRule(ctx, 1, ChannelRuleDeleteCmd{Pattern: "stream/test/json"}), ErrChannelRuleNotFound)
+
+		rule, err := storage.UpdateChannelRule(ctx, 1, ChannelRuleUpdateCmd{Pattern: "stream/test/json", Settings: settings})
+		require.NoError(t, err)
+		require.Equal(t, int64(3), rule.Version)
+	})
+}
+
+func TestIntegrationSQLStorage_WriteConfigs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	storage := NewSQLStorage(db.InitTestDB(t), fakes.NewFakeSecretsService())
+
+	config, err := storage.CreateWriteConfig(ctx, 1, WriteConfigCreateCmd{
+		Settings:       WriteSettings{Endpoint: "http://localhost:9090/api/v1/write"},
+		SecureSettings: map[string]string{"basicAuthPassword": "secret"},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, config.UID)
+
+	_, err = storage.CreateWriteConfig(ctx, 1, WriteConfigCreateCmd{UID: config.UID, Settings: config.Settings})
+	require.ErrorIs(t, err, ErrWriteConfigExists)
+
+	stored, ok, err := storage.GetWriteConfig(ctx, 1, WriteConfigGetCmd{UID: config.UID})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, config, stored)
+
+	_, ok, err = storage.GetWriteConfig(ctx, 2, WriteConfigGetCmd{UID: config.UID})
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	updated, err := storage.UpdateWriteConfig(ctx, 1, WriteConfigUpdateCmd{
+		UID:      config.UID,
+		Settings: WriteSettings{Endpoint: "http://localhost:9091/api/v1/write"},
+	})
+	require.NoError(t, err)
+	configs, err := storage.ListWriteConfigs(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []WriteConfig{updated}, configs)
+
+	require.NoError(t, storage.DeleteWriteConfig(ctx, 1, WriteConfigDeleteCmd{UID: config.UID}))
+	require.ErrorIs(t, storage.DeleteWriteConfig(ctx, 1, WriteConfigDeleteCmd{UID: config.UID}), ErrWriteConfigNotFound)
+}
@@ -0,0 +1,94 @@
+package live
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/centrifugal/centrifuge"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/services/live/pipeline"
+)
+
+// pipelineInvalidateOp is the operation of the node notification sent after
+// a change of the pipeline storage, so all nodes rebuild the rules of the org.
+const pipelineInvalidateOp = "pipeline_invalidate"
+
+type pipelineInvalidateNotification struct {
+	OrgID int64 `json:"orgId"`
+}
+
+// setupPipeline creates the pipeline with the storage set in configuration.
+// It must be called before the node runs.
+func (g *GrafanaLive) setupPipeline() error {
+	switch g.Cfg.LivePipelineStorage {
+	case "database":
+		g.pipelineStorage = pipeline.NewSQLStorage(g.SQLStore, g.SecretsService)
+	default:
+		g.pipelineStorage = &pipeline.FileStorage{
+			DataPath:       g.Cfg.DataPath,
+			SecretsService: g.SecretsService,
+		}
+	}
+	builder := &pipeline.StorageRuleBuilder{
+		Node:                 g.node,
+		ManagedStream:        g.ManagedStreamRunner,
+		FrameStorage:         pipeline.NewFrameStorage(),
+		ProcessorStates:      pipeline.NewFrameProcessorStateStorage(),
+		Storage:              g.pipelineStorage,
+		ChannelHandlerGetter: g,
+		SecretsService:       g.SecretsService,
+	}
+	g.pipelineRules = pipeline.NewCacheSegmentedTree(builder)
+	p, err := pipeline.New(g.pipelineRules)
+	if err != nil {
+		return err
+	}
+	g.Pipeline = p
+
+	// Notifications are delivered to all nodes over the HA engine when it is
+	// configured, and to the current node otherwise.
+	g.node.OnNotification(g.handleNodeNotification)
+	return nil
+}
+
+func (g *GrafanaLive) handleNodeNotification(e centrifuge.NotificationEvent) {
+	switch e.Op {
+	case pipelineInvalidateOp:
+		var n pipelineInvalidateNotification
+		if err := json.Unmarshal(e.Data, &n); err != nil {
+			logger.Error("Error decoding pipeline invalidate notification", "error", err, "fromNode", e.FromNodeID)
+			return
+		}
+		g.pipelineRules.Invalidate(n.OrgID)
+	default:
+		logger.Warn("Unknown node notification", "op", e.Op, "fromNode", e.FromNodeID)
+	}
+}
+
+// invalidatePipelineRules notifies all nodes that the pipeline storage of the
+// org changed.
+func (g *GrafanaLive) invalidatePipelineRules(orgID int64) {
+	data, err := json.Marshal(pipelineInvalidateNotification{OrgID: orgID})
+	if err != nil {
+		logger.Error("Error encoding pipeline invalidate notification", "error", err)
+		return
+	}
+	if err := g.node.Notify(pipelineInvalidateOp, data, ""); err != nil {
+		// Other nodes still get the change on the periodic rule update.
+		logger.Error("Error sending pipeline invalidate notification", "error", err, "orgId", orgID)
+	}
+}
+
+func pipelineStorageErrorResponse(message string, err error) response.Response {
+	switch {
+	case errors.Is(err, pipeline.ErrChannelRuleNotFound), errors.Is(err, pipeline.ErrWriteConfigNotFound):
+		return response.Error(http.StatusNotFound, message, err)
+	case errors.Is(err, pipeline.ErrChannelRuleExists), errors.Is(err, pipeline.ErrWriteConfigExists),
+		errors.Is(err, pipeline.ErrChannelRuleVersionConflict):
+		return response.Error(http.StatusConflict, message, err)
+	default:
+		return response.Error(http.StatusInternalServerError, message, err)
+	}
+}
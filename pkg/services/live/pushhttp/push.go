@@ -1,24 +1,31 @@
 package pushhttp
 
 import (
+	"compress/gzip"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 
 	liveDto "github.com/grafana/grafana-plugin-sdk-go/live"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/services/live"
 	"github.com/grafana/grafana/pkg/services/live/convert"
 	"github.com/grafana/grafana/pkg/services/live/pushurl"
+	"github.com/grafana/grafana/pkg/services/live/telemetry"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/web"
 )
 
 var (
 	logger = log.New("live.push_http")
+
+	errBodyTooLarge = errors.New("request body is too large")
+	errInvalidBody  = errors.New("invalid request body")
 )
 
 func ProvideService(cfg *setting.Cfg, live *live.GrafanaLive) *Gateway {
@@ -45,42 +52,85 @@ func (g *Gateway) Run(ctx context.Context) error {
 	return ctx.Err()
 }
 
+// Handle pushes metrics in Influx line protocol to a stream.
 func (g *Gateway) Handle(ctx *contextmodel.ReqContext) {
+	ctx.Resp.WriteHeader(g.push(ctx, "influx", g.converter.Convert))
+}
+
+// HandlePrometheus pushes metrics in Prometheus text exposition format to a stream.
+func (g *Gateway) HandlePrometheus(ctx *contextmodel.ReqContext) {
+	ctx.Resp.WriteHeader(g.push(ctx, "prometheus", g.converter.ConvertPrometheus))
+}
+
+// HandleOTLP pushes OTLP metrics to a stream. It implements the metrics endpoint
+// of OTLP/HTTP, so both protobuf and JSON encoded requests are accepted.
+func (g *Gateway) HandleOTLP(ctx *contextmodel.ReqContext) {
+	status := g.push(ctx, "otlp", g.converter.ConvertOTLP)
+	if status != http.StatusOK {
+		ctx.Resp.WriteHeader(status)
+		return
+	}
+
+	// OTLP/HTTP clients expect a response encoded like the request.
+	resp := pmetricotlp.NewExportResponse()
+	contentType := ctx.Req.Header.Get("Content-Type")
+	var body []byte
+	var err error
+	if contentType == "application/json" {
+		body, err = resp.MarshalJSON()
+	} else {
+		contentType = "application/x-protobuf"
+		body, err = resp.MarshalProto()
+	}
+	if err != nil {
+		logger.Error("Error encoding OTLP response", "error", err)
+		ctx.Resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	ctx.Resp.Header().Set("Content-Type", contentType)
+	ctx.Resp.WriteHeader(http.StatusOK)
+	if _, err := ctx.Resp.Write(body); err != nil {
+		logger.Error("Error writing OTLP response", "error", err)
+	}
+}
+
+type convertFunc func(data []byte, frameFormat string) ([]telemetry.FrameWrapper, error)
+
+// push converts the request body and pushes the resulting frames to the stream
+// of the request. It returns the status code of the response.
+func (g *Gateway) push(ctx *contextmodel.ReqContext, format string, convertBody convertFunc) int {
 	streamID := web.Params(ctx.Req)[":streamId"]
 
 	stream, err := g.GrafanaLive.ManagedStreamRunner.GetOrCreateStream(ctx.SignedInUser.OrgID, liveDto.ScopeStream, streamID)
 	if err != nil {
 		logger.Error("Error getting stream", "error", err)
-		ctx.Resp.WriteHeader(http.StatusInternalServerError)
-		return
+		return http.StatusInternalServerError
 	}
 
 	// TODO Grafana 8: decide which formats to use or keep all.
 	urlValues := ctx.Req.URL.Query()
 	frameFormat := pushurl.FrameFormatFromValues(urlValues)
 
-	body, err := io.ReadAll(ctx.Req.Body)
+	body, err := readBody(ctx.Req, g.Cfg.LivePushMaxBodySize)
 	if err != nil {
 		logger.Error("Error reading body", "error", err)
-		ctx.Resp.WriteHeader(http.StatusInternalServerError)
-		return
+		return readBodyStatus(err)
 	}
 	logger.Debug("Live Push request",
 		"protocol", "http",
+		"format", format,
 		"streamId", streamID,
 		"bodyLength", len(body),
 		"frameFormat", frameFormat,
 	)
 
-	metricFrames, err := g.converter.Convert(body, frameFormat)
+	metricFrames, err := convertBody(body, frameFormat)
 	if err != nil {
-		logger.Error("Error converting metrics", "error", err, "frameFormat", frameFormat)
-		if errors.Is(err, convert.ErrUnsupportedFrameFormat) {
-			ctx.Resp.WriteHeader(http.StatusBadRequest)
-		} else {
-			ctx.Resp.WriteHeader(http.StatusInternalServerError)
+		logger.Error("Error converting metrics", "error", err, "format", format, "frameFormat", frameFormat)
+		if errors.Is(err, convert.ErrUnsupportedFrameFormat) || errors.Is(err, telemetry.ErrInvalidMetrics) {
+			return http.StatusBadRequest
 		}
-		return
+		return http.StatusInternalServerError
 	}
 
 	// TODO -- make sure all packets are combined together!
@@ -90,21 +140,62 @@ func (g *Gateway) Handle(ctx *contextmodel.ReqContext) {
 		err := stream.Push(ctx.Req.Context(), mf.Key(), mf.Frame())
 		if err != nil {
 			logger.Error("Error pushing frame", "error", err, "data", string(body))
-			ctx.Resp.WriteHeader(http.StatusInternalServerError)
-			return
+			return http.StatusInternalServerError
 		}
 	}
 
-	ctx.Resp.WriteHeader(http.StatusOK)
+	return http.StatusOK
+}
+
+// readBody reads the body of the request, which may be compressed with gzip. Unless maxSize is 0,
+// errBodyTooLarge is returned if the body is larger than maxSize bytes after decompression.
+func readBody(req *http.Request, maxSize int64) ([]byte, error) {
+	var reader io.Reader = req.Body
+	compressed := req.Header.Get("Content-Encoding") == "gzip"
+	if compressed {
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidBody, err)
+		}
+		defer func() { _ = gz.Close() }()
+		reader = gz
+	}
+	if maxSize > 0 {
+		// reading one more byte than allowed tells whether the body is too large.
+		reader = io.LimitReader(reader, maxSize+1)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		if compressed {
+			return nil, fmt.Errorf("%w: %w", errInvalidBody, err)
+		}
+		return nil, err
+	}
+	if maxSize > 0 && int64(len(body)) > maxSize {
+		return nil, errBodyTooLarge
+	}
+	return body, nil
+}
+
+// readBodyStatus returns the status of the response to a request whose body cannot be read.
+func readBodyStatus(err error) int {
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errInvalidBody):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
 func (g *Gateway) HandlePipelinePush(ctx *contextmodel.ReqContext) {
 	channelID := web.Params(ctx.Req)["*"]
 
-	body, err := io.ReadAll(ctx.Req.Body)
+	body, err := readBody(ctx.Req, g.Cfg.LivePushMaxBodySize)
 	if err != nil {
 		logger.Error("Error reading body", "error", err)
-		ctx.Resp.WriteHeader(http.StatusInternalServerError)
+		ctx.Resp.WriteHeader(readBodyStatus(err))
 		return
 	}
 	logger.Debug("Live channel push request",
@@ -0,0 +1,71 @@
+package pushhttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBody(t *testing.T) {
+	gzipped := func(t *testing.T, body string) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, err := gz.Write([]byte(body))
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+		return buf.Bytes()
+	}
+	request := func(body []byte, encoding string) *http.Request {
+		req, _ := http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+		return req
+	}
+
+	t.Run("body within the limit is read", func(t *testing.T) {
+		body, err := readBody(request([]byte("metric 1"), ""), 8)
+		require.NoError(t, err)
+		require.Equal(t, "metric 1", string(body))
+	})
+
+	t.Run("gzip body is decompressed", func(t *testing.T) {
+		body, err := readBody(request(gzipped(t, "metric 1"), "gzip"), 8)
+		require.NoError(t, err)
+		require.Equal(t, "metric 1", string(body))
+	})
+
+	t.Run("body larger than the limit returns 413", func(t *testing.T) {
+		_, err := readBody(request([]byte("metric 10"), ""), 8)
+		require.ErrorIs(t, err, errBodyTooLarge)
+		require.Equal(t, http.StatusRequestEntityTooLarge, readBodyStatus(err))
+	})
+
+	t.Run("decompressed body larger than the limit returns 413", func(t *testing.T) {
+		compressed := gzipped(t, strings.Repeat("0", 1<<20))
+		require.Less(t, len(compressed), 1<<12)
+		_, err := readBody(request(compressed, "gzip"), 1<<12)
+		require.ErrorIs(t, err, errBodyTooLarge)
+	})
+
+	t.Run("body is not limited if the limit is 0", func(t *testing.T) {
+		body, err := readBody(request(gzipped(t, strings.Repeat("0", 1<<20)), "gzip"), 0)
+		require.NoError(t, err)
+		require.Len(t, body, 1<<20)
+	})
+
+	t.Run("malformed gzip body returns 400", func(t *testing.T) {
+		_, err := readBody(request([]byte("metric 1"), "gzip"), 0)
+		require.ErrorIs(t, err, errInvalidBody)
+		require.Equal(t, http.StatusBadRequest, readBodyStatus(err))
+
+		truncated := gzipped(t, "metric 1")
+		_, err = readBody(request(truncated[:len(truncated)-4], "gzip"), 0)
+		require.ErrorIs(t, err, errInvalidBody)
+	})
+}
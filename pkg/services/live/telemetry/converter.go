@@ -1,6 +1,13 @@
 package telemetry
 
-import "github.com/grafana/grafana-plugin-sdk-go/data"
+import (
+	"errors"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// ErrInvalidMetrics is returned by converters when the input is not valid.
+var ErrInvalidMetrics = errors.New("invalid metrics")
 
 // Converter can convert input to Grafana Data Frames.
 type Converter interface {
@@ -0,0 +1,202 @@
+package otlp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"time"
+
+	influx "github.com/influxdata/line-protocol"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/grafana/grafana/pkg/services/live/telemetry"
+	"github.com/grafana/grafana/pkg/services/live/telemetry/prometheus"
+	"github.com/grafana/grafana/pkg/services/live/telemetry/telegraf"
+)
+
+var _ telemetry.Converter = (*Converter)(nil)
+
+// Converter converts OTLP metrics export requests to Grafana frames.
+type Converter struct {
+	useLabelsColumn bool
+	now             func() time.Time
+	frames          *telegraf.Converter
+}
+
+// ConverterOption ...
+type ConverterOption func(*Converter)
+
+// WithUseLabelsColumn ...
+func WithUseLabelsColumn(enabled bool) ConverterOption {
+	return func(c *Converter) {
+		c.useLabelsColumn = enabled
+	}
+}
+
+// WithNow sets the function that returns the time of data points without a timestamp.
+func WithNow(now func() time.Time) ConverterOption {
+	return func(c *Converter) {
+		c.now = now
+	}
+}
+
+// NewConverter creates new Converter from OTLP metrics to Grafana Data Frames. Both the protobuf
+// and the JSON encoding of OTLP/HTTP are accepted. Every data point becomes a metric named after
+// the OTLP metric, with the resource and data point attributes as tags. Values are stored in fields
+// named like for the Prometheus text exposition format: counter for monotonic sums, gauge for gauges
+// and other sums, sum, count and one field per quantile or bucket upper bound for summaries and
+// histograms. Histogram buckets are cumulative, like Prometheus buckets.
+func NewConverter(opts ...ConverterOption) *Converter {
+	c := &Converter{
+		now: time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.frames = telegraf.NewConverter(
+		telegraf.WithUseLabelsColumn(c.useLabelsColumn),
+		telegraf.WithFloat64Numbers(true),
+	)
+	return c
+}
+
+// Convert metrics.
+func (c *Converter) Convert(body []byte) ([]telemetry.FrameWrapper, error) {
+	req := pmetricotlp.NewExportRequest()
+	var err error
+	if isJSON(body) {
+		err = req.UnmarshalJSON(body)
+	} else {
+		err = req.UnmarshalProto(body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: error parsing metrics: %w", telemetry.ErrInvalidMetrics, err)
+	}
+
+	now := c.now()
+	var metrics []influx.Metric
+	resourceMetrics := req.Metrics().ResourceMetrics()
+	for i := 0; i < resourceMetrics.Len(); i++ {
+		rm := resourceMetrics.At(i)
+		scopeMetrics := rm.ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			ms := scopeMetrics.At(j).Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				converted, err := toMetrics(ms.At(k), rm.Resource().Attributes(), now)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %w", telemetry.ErrInvalidMetrics, err)
+				}
+				metrics = append(metrics, converted...)
+			}
+		}
+	}
+	return c.frames.ConvertMetrics(metrics)
+}
+
+// isJSON reports whether the body is JSON encoded. A protobuf encoded export
+// request can't start with a curly brace, as it is not a valid field tag.
+func isJSON(body []byte) bool {
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+type dataPoint struct {
+	attributes pcommon.Map
+	timestamp  pcommon.Timestamp
+	fields     map[string]any
+}
+
+func toMetrics(m pmetric.Metric, resource pcommon.Map, now time.Time) ([]influx.Metric, error) {
+	var points []dataPoint
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		points = numberDataPoints(m.Gauge().DataPoints(), "gauge")
+	case pmetric.MetricTypeSum:
+		field := "gauge"
+		if m.Sum().IsMonotonic() {
+			field = "counter"
+		}
+		points = numberDataPoints(m.Sum().DataPoints(), field)
+	case pmetric.MetricTypeHistogram:
+		dps := m.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			fields := map[string]any{"count": float64(dp.Count())}
+			if dp.HasSum() {
+				fields["sum"] = dp.Sum()
+			}
+			var cumulative uint64
+			bounds := dp.ExplicitBounds()
+			for b := 0; b < bounds.Len() && b < dp.BucketCounts().Len(); b++ {
+				cumulative += dp.BucketCounts().At(b)
+				fields[prometheus.FormatBound(bounds.At(b))] = float64(cumulative)
+			}
+			fields[prometheus.FormatBound(math.Inf(1))] = float64(dp.Count())
+			points = append(points, dataPoint{attributes: dp.Attributes(), timestamp: dp.Timestamp(), fields: fields})
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := m.ExponentialHistogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			fields := map[string]any{"count": float64(dp.Count())}
+			if dp.HasSum() {
+				fields["sum"] = dp.Sum()
+			}
+			points = append(points, dataPoint{attributes: dp.Attributes(), timestamp: dp.Timestamp(), fields: fields})
+		}
+	case pmetric.MetricTypeSummary:
+		dps := m.Summary().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			fields := map[string]any{"count": float64(dp.Count()), "sum": dp.Sum()}
+			quantiles := dp.QuantileValues()
+			for q := 0; q < quantiles.Len(); q++ {
+				fields[prometheus.FormatBound(quantiles.At(q).Quantile())] = quantiles.At(q).Value()
+			}
+			points = append(points, dataPoint{attributes: dp.Attributes(), timestamp: dp.Timestamp(), fields: fields})
+		}
+	default:
+		return nil, nil
+	}
+
+	metrics := make([]influx.Metric, 0, len(points))
+	for _, p := range points {
+		tags := make(map[string]string, resource.Len()+p.attributes.Len())
+		for _, attrs := range []pcommon.Map{resource, p.attributes} {
+			attrs.Range(func(k string, v pcommon.Value) bool {
+				tags[k] = v.AsString()
+				return true
+			})
+		}
+		tm := now
+		if p.timestamp != 0 {
+			tm = p.timestamp.AsTime()
+		}
+		metric, err := influx.New(m.Name(), tags, p.fields, tm)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+func numberDataPoints(dps pmetric.NumberDataPointSlice, field string) []dataPoint {
+	points := make([]dataPoint, 0, dps.Len())
+	for i := 0; i < dps.Len(); i++ {
+		dp := dps.At(i)
+		var value float64
+		switch dp.ValueType() {
+		case pmetric.NumberDataPointValueTypeDouble:
+			value = dp.DoubleValue()
+		case pmetric.NumberDataPointValueTypeInt:
+			value = float64(dp.IntValue())
+		default:
+			continue
+		}
+		points = append(points, dataPoint{attributes: dp.Attributes(), timestamp: dp.Timestamp(), fields: map[string]any{field: value}})
+	}
+	return points
+}
@@ -0,0 +1,91 @@
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/grafana/grafana/pkg/services/live/telemetry"
+)
+
+func exportRequest(ts time.Time) pmetricotlp.ExportRequest {
+	metrics := pmetric.NewMetrics()
+	rm := metrics.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("service.name", "edge")
+	ms := rm.ScopeMetrics().AppendEmpty().Metrics()
+
+	requests := ms.AppendEmpty()
+	requests.SetName("http.requests")
+	sum := requests.SetEmptySum()
+	sum.SetIsMonotonic(true)
+	dp := sum.DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+	dp.SetIntValue(10)
+	dp.Attributes().PutStr("method", "get")
+
+	temperature := ms.AppendEmpty()
+	temperature.SetName("temperature")
+	gauge := temperature.SetEmptyGauge().DataPoints().AppendEmpty()
+	gauge.SetDoubleValue(21.5)
+
+	duration := ms.AppendEmpty()
+	duration.SetName("duration")
+	hdp := duration.SetEmptyHistogram().DataPoints().AppendEmpty()
+	hdp.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+	hdp.SetCount(6)
+	hdp.SetSum(3.2)
+	hdp.ExplicitBounds().FromRaw([]float64{0.1, 1})
+	hdp.BucketCounts().FromRaw([]uint64{2, 3, 1})
+
+	return pmetricotlp.NewExportRequestFromMetrics(metrics)
+}
+
+func TestConverter_Convert(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := ts.Add(time.Minute)
+	req := exportRequest(ts)
+	protoBody, err := req.MarshalProto()
+	require.NoError(t, err)
+	jsonBody, err := req.MarshalJSON()
+	require.NoError(t, err)
+
+	for name, body := range map[string][]byte{"protobuf": protoBody, "json": jsonBody} {
+		t.Run(name, func(t *testing.T) {
+			converter := NewConverter(WithUseLabelsColumn(true), WithNow(func() time.Time { return now }))
+			frameWrappers, err := converter.Convert(body)
+			require.NoError(t, err)
+			require.Len(t, frameWrappers, 3)
+
+			frames := map[string]*data.Frame{}
+			for _, fw := range frameWrappers {
+				frames[fw.Key()] = fw.Frame()
+			}
+
+			requests := frames["http.requests"]
+			require.Equal(t, "method=get, service.name=edge", requests.Fields[0].At(0))
+			require.Equal(t, ts, requests.Fields[1].At(0).(time.Time).UTC())
+			require.Equal(t, "counter", requests.Fields[2].Name)
+			require.Equal(t, float64(10), *requests.Fields[2].At(0).(*float64))
+
+			temperature := frames["temperature"]
+			require.Equal(t, now, temperature.Fields[1].At(0))
+			require.Equal(t, "gauge", temperature.Fields[2].Name)
+
+			values := map[string]float64{}
+			for _, f := range frames["duration"].Fields[2:] {
+				values[f.Name] = *f.At(0).(*float64)
+			}
+			require.Equal(t, map[string]float64{"0.1": 2, "1": 5, "+Inf": 6, "sum": 3.2, "count": 6}, values)
+		})
+	}
+
+	t.Run("invalid input", func(t *testing.T) {
+		_, err := NewConverter().Convert([]byte("{"))
+		require.ErrorIs(t, err, telemetry.ErrInvalidMetrics)
+	})
+}
@@ -0,0 +1,137 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	influx "github.com/influxdata/line-protocol"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/grafana/grafana/pkg/services/live/telemetry"
+	"github.com/grafana/grafana/pkg/services/live/telemetry/telegraf"
+)
+
+var _ telemetry.Converter = (*Converter)(nil)
+
+// Converter converts metrics in Prometheus text exposition format to Grafana frames.
+type Converter struct {
+	useLabelsColumn bool
+	now             func() time.Time
+	frames          *telegraf.Converter
+}
+
+// ConverterOption ...
+type ConverterOption func(*Converter)
+
+// WithUseLabelsColumn ...
+func WithUseLabelsColumn(enabled bool) ConverterOption {
+	return func(c *Converter) {
+		c.useLabelsColumn = enabled
+	}
+}
+
+// WithNow sets the function that returns the time of samples without a timestamp.
+func WithNow(now func() time.Time) ConverterOption {
+	return func(c *Converter) {
+		c.now = now
+	}
+}
+
+// NewConverter creates new Converter from Prometheus text exposition format to Grafana Data Frames.
+// Every metric family becomes a metric named after the family, with the labels of the samples as tags.
+// Values are stored in fields named like in the Telegraf Prometheus input: counter, gauge and value for
+// counters, gauges and untyped metrics, sum, count and one field per quantile or bucket upper bound for
+// summaries and histograms.
+func NewConverter(opts ...ConverterOption) *Converter {
+	c := &Converter{
+		now: time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.frames = telegraf.NewConverter(
+		telegraf.WithUseLabelsColumn(c.useLabelsColumn),
+		telegraf.WithFloat64Numbers(true),
+	)
+	return c
+}
+
+// Convert metrics.
+func (c *Converter) Convert(body []byte) ([]telemetry.FrameWrapper, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: error parsing metrics: %w", telemetry.ErrInvalidMetrics, err)
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := c.now()
+	var metrics []influx.Metric
+	for _, name := range names {
+		family := families[name]
+		for _, m := range family.GetMetric() {
+			metric, err := c.toMetric(name, family.GetType(), m, now)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", telemetry.ErrInvalidMetrics, err)
+			}
+			metrics = append(metrics, metric)
+		}
+	}
+	return c.frames.ConvertMetrics(metrics)
+}
+
+func (c *Converter) toMetric(name string, metricType dto.MetricType, m *dto.Metric, now time.Time) (influx.Metric, error) {
+	tags := make(map[string]string, len(m.GetLabel()))
+	for _, l := range m.GetLabel() {
+		tags[l.GetName()] = l.GetValue()
+	}
+
+	fields := map[string]any{}
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		fields["counter"] = m.GetCounter().GetValue()
+	case dto.MetricType_GAUGE:
+		fields["gauge"] = m.GetGauge().GetValue()
+	case dto.MetricType_SUMMARY:
+		fields["sum"] = m.GetSummary().GetSampleSum()
+		fields["count"] = float64(m.GetSummary().GetSampleCount())
+		for _, q := range m.GetSummary().GetQuantile() {
+			fields[FormatBound(q.GetQuantile())] = q.GetValue()
+		}
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		fields["sum"] = m.GetHistogram().GetSampleSum()
+		fields["count"] = float64(m.GetHistogram().GetSampleCount())
+		for _, b := range m.GetHistogram().GetBucket() {
+			fields[FormatBound(b.GetUpperBound())] = float64(b.GetCumulativeCount())
+		}
+		// the parser drops the +Inf bucket, which is always equal to count.
+		fields[FormatBound(math.Inf(1))] = float64(m.GetHistogram().GetSampleCount())
+	default:
+		fields["value"] = m.GetUntyped().GetValue()
+	}
+
+	tm := now
+	if m.TimestampMs != nil {
+		tm = time.UnixMilli(m.GetTimestampMs())
+	}
+	return influx.New(name, tags, fields, tm)
+}
+
+// FormatBound formats the upper bound of a bucket or a quantile the same way
+// as in the le and quantile labels of the exposition format.
+func FormatBound(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,85 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/live/telemetry"
+)
+
+const exposition = `# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027 1395066363000
+http_requests_total{method="post",code="400"} 3 1395066363000
+# TYPE temperature gauge
+temperature{room="kitchen"} 21.5
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.1"} 2
+request_duration_seconds_bucket{le="1"} 5
+request_duration_seconds_bucket{le="+Inf"} 6
+request_duration_seconds_sum 3.2
+request_duration_seconds_count 6
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.5"} 0.05
+rpc_duration_seconds_sum 17
+rpc_duration_seconds_count 100
+uptime_seconds 42
+`
+
+func TestConverter_Convert(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("labels column", func(t *testing.T) {
+		converter := NewConverter(WithUseLabelsColumn(true), WithNow(func() time.Time { return now }))
+		frameWrappers, err := converter.Convert([]byte(exposition))
+		require.NoError(t, err)
+
+		frames := map[string]*data.Frame{}
+		for _, fw := range frameWrappers {
+			frames[fw.Key()] = fw.Frame()
+		}
+		require.Len(t, frames, 5)
+
+		requests := frames["http_requests_total"]
+		require.Equal(t, 2, requests.Rows())
+		require.Equal(t, "code=200, method=post", requests.Fields[0].At(0))
+		require.Equal(t, time.UnixMilli(1395066363000), requests.Fields[1].At(0))
+		require.Equal(t, "counter", requests.Fields[2].Name)
+		require.Equal(t, float64(3), *requests.Fields[2].At(1).(*float64))
+
+		temperature := frames["temperature"]
+		require.Equal(t, now, temperature.Fields[1].At(0))
+		require.Equal(t, "gauge", temperature.Fields[2].Name)
+
+		values := func(frame *data.Frame) map[string]float64 {
+			result := map[string]float64{}
+			for _, f := range frame.Fields[2:] {
+				result[f.Name] = *f.At(0).(*float64)
+			}
+			return result
+		}
+		require.Equal(t, map[string]float64{"0.1": 2, "1": 5, "+Inf": 6, "sum": 3.2, "count": 6}, values(frames["request_duration_seconds"]))
+		require.Equal(t, map[string]float64{"0.5": 0.05, "sum": 17, "count": 100}, values(frames["rpc_duration_seconds"]))
+		require.Equal(t, map[string]float64{"value": 42}, values(frames["uptime_seconds"]))
+	})
+
+	t.Run("wide", func(t *testing.T) {
+		converter := NewConverter(WithNow(func() time.Time { return now }))
+		frameWrappers, err := converter.Convert([]byte(exposition))
+		require.NoError(t, err)
+		require.Equal(t, "http_requests_total", frameWrappers[0].Key())
+
+		frame := frameWrappers[0].Frame()
+		require.Len(t, frame.Fields, 3)
+		require.Equal(t, data.Labels{"method": "post", "code": "200"}, frame.Fields[1].Labels)
+		require.Equal(t, data.Labels{"method": "post", "code": "400"}, frame.Fields[2].Labels)
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		_, err := NewConverter().Convert([]byte("metric{"))
+		require.ErrorIs(t, err, telemetry.ErrInvalidMetrics)
+	})
+}
@@ -64,8 +64,14 @@ func getFrameKey(m influx.Metric) string {
 func (c *Converter) Convert(body []byte) ([]telemetry.FrameWrapper, error) {
 	metrics, err := c.parser.Parse(body)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing metrics: %w", err)
+		return nil, fmt.Errorf("%w: error parsing metrics: %w", telemetry.ErrInvalidMetrics, err)
 	}
+	return c.ConvertMetrics(metrics)
+}
+
+// ConvertMetrics converts metrics that were decoded from another input format
+// the same way as Influx line protocol metrics.
+func (c *Converter) ConvertMetrics(metrics []influx.Metric) ([]telemetry.FrameWrapper, error) {
 	if !c.useLabelsColumn {
 		return c.convertWideFields(metrics)
 	}
@@ -25,14 +25,16 @@ type Store interface {
 
 const (
 	// modules
-	PasswordAuthModule  = "password"
-	APIKeyAuthModule    = "apikey"
-	SAMLAuthModule      = "auth.saml"
-	LDAPAuthModule      = "ldap"
-	AuthProxyAuthModule = "authproxy"
-	JWTModule           = "jwt"
-	ExtendedJWTModule   = "extendedjwt"
-	RenderModule        = "render"
+	PasswordAuthModule     = "password"
+	APIKeyAuthModule       = "apikey"
+	SAMLAuthModule         = "auth.saml"
+	LDAPAuthModule         = "ldap"
+	AuthProxyAuthModule    = "authproxy"
+	JWTModule              = "jwt"
+	ExtendedJWTModule      = "extendedjwt"
+	RenderModule           = "render"
+	ClientCertModule       = "clientcert"
+	WorkloadIdentityModule = "workloadidentity"
 	// OAuth provider modules
 	AzureADAuthModule    = "oauth_azuread"
 	GoogleAuthModule     = "oauth_google"
@@ -47,6 +49,8 @@ const (
 	SAMLLabel = "SAML"
 	LDAPLabel = "LDAP"
 	JWTLabel  = "JWT"
+	// Client certificate label
+	ClientCertLabel = "Client certificate"
 	// OAuth provider labels
 	AuthProxyLabel    = "Auth Proxy"
 	AzureADLabel      = "AzureAD"
@@ -77,6 +81,8 @@ func IsExternallySynced(cfg *setting.Cfg, authModule string, oauthInfo *social.O
 		return !cfg.LDAPSkipOrgRoleSync
 	case JWTModule:
 		return !cfg.JWTAuth.SkipOrgRoleSync
+	case ClientCertModule:
+		return !cfg.AuthClientCert.SkipOrgRoleSync
 	}
 	switch authModule {
 	case GoogleAuthModule, OktaAuthModule, AzureADAuthModule, GitLabAuthModule, GithubAuthModule, GrafanaComAuthModule, GenericOAuthModule:
@@ -116,6 +122,8 @@ func IsProviderEnabled(cfg *setting.Cfg, authModule string, oauthInfo *social.OA
 		return cfg.LDAPAuthEnabled
 	case JWTModule:
 		return cfg.JWTAuth.Enabled
+	case ClientCertModule:
+		return cfg.AuthClientCert.Enabled
 	case GoogleAuthModule, OktaAuthModule, AzureADAuthModule, GitLabAuthModule, GithubAuthModule, GrafanaComAuthModule, GenericOAuthModule:
 		if oauthInfo == nil {
 			return false
@@ -146,6 +154,8 @@ func GetAuthProviderLabel(authModule string) string {
 		return LDAPLabel
 	case JWTModule:
 		return JWTLabel
+	case ClientCertModule:
+		return ClientCertLabel
 	case AuthProxyAuthModule:
 		return AuthProxyLabel
 	case GenericOAuthModule:
@@ -0,0 +1,89 @@
+package mfa
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/services/org"
+)
+
+type FactorType string
+
+const (
+	FactorTypeTOTP     FactorType = "totp"
+	FactorTypeWebAuthn FactorType = "webauthn"
+)
+
+// MethodRecoveryCode is the method recorded for sessions verified with a recovery code.
+const MethodRecoveryCode = "recovery_code"
+
+var (
+	ErrFactorNotFound     = errutil.NotFound("mfa.factor-not-found", errutil.WithPublicMessage("Factor not found"))
+	ErrPolicyNotFound     = errutil.NotFound("mfa.policy-not-found", errutil.WithPublicMessage("Policy not found"))
+	ErrPolicyExists       = errutil.Conflict("mfa.policy-exists", errutil.WithPublicMessage("Policy already exists"))
+	ErrInvalidRole        = errutil.BadRequest("mfa.invalid-role", errutil.WithPublicMessage("Invalid role"))
+	ErrInvalidCode        = errutil.Unauthorized("mfa.invalid-code", errutil.WithPublicMessage("Invalid verification code"))
+	ErrInvalidChallenge   = errutil.Unauthorized("mfa.invalid-challenge", errutil.WithPublicMessage("Invalid or expired challenge, log in again"))
+	ErrInvalidCredential  = errutil.BadRequest("mfa.invalid-credential", errutil.WithPublicMessage("Invalid security key credential"))
+	ErrBasicAuthForbidden = errutil.Unauthorized("mfa.basic-auth", errutil.WithPublicMessage("Basic authentication is not available for users with multi-factor authentication"))
+	ErrTooManyAttempts    = errutil.TooManyRequests("mfa.too-many-attempts", errutil.WithPublicMessage("Too many failed attempts, try again later"))
+)
+
+// Service manages second authentication factors of users.
+type Service interface {
+	// GetStatus returns the factors of a user and whether the user must use a second factor.
+	GetStatus(ctx context.Context, userID int64) (*Status, error)
+	// IsRequired reports whether a user must complete a second factor to log in with a password.
+	IsRequired(ctx context.Context, userID int64) (bool, error)
+	// IsSessionVerified reports whether a second factor was completed for a session.
+	IsSessionVerified(ctx context.Context, tokenID int64) (bool, error)
+	// ResetFactors removes all factors and recovery codes of a user.
+	ResetFactors(ctx context.Context, userID int64) error
+}
+
+// Factor is a second authentication factor enrolled by a user.
+type Factor struct {
+	ID     int64      `xorm:"pk autoincr 'id'" json:"id"`
+	UserID int64      `xorm:"user_id" json:"-"`
+	Type   FactorType `xorm:"'type'" json:"type"`
+	Name   string     `xorm:"name" json:"name"`
+	// Secret is the encrypted TOTP secret.
+	Secret string `xorm:"secret" json:"-"`
+	// CredentialID is the base64url encoded ID of a WebAuthn credential.
+	CredentialID string `xorm:"credential_id" json:"-"`
+	// PublicKey is the base64url encoded SubjectPublicKeyInfo of a WebAuthn credential.
+	PublicKey string `xorm:"public_key" json:"-"`
+	SignCount int64  `xorm:"sign_count" json:"-"`
+	// LastStep is the time step of the last accepted TOTP code, codes can't be reused.
+	LastStep  int64      `xorm:"last_step" json:"-"`
+	Confirmed bool       `xorm:"confirmed" json:"confirmed"`
+	Created   time.Time  `xorm:"'created'" json:"created"`
+	LastUsed  *time.Time `xorm:"last_used" json:"lastUsed,omitempty"`
+}
+
+func (f Factor) TableName() string {
+	return "user_mfa_factor"
+}
+
+// Status of multi-factor authentication of a user.
+type Status struct {
+	Factors []*Factor `json:"factors"`
+	// Required is true when the user has a confirmed factor or an enforcement
+	// policy applies to the user in one of their organizations.
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recoveryCodesRemaining"`
+}
+
+// Policy enforces multi-factor authentication for members of an organization.
+// A policy without role applies to all members.
+type Policy struct {
+	ID      int64        `xorm:"pk autoincr 'id'" json:"id"`
+	OrgID   int64        `xorm:"org_id" json:"orgId"`
+	Role    org.RoleType `xorm:"'role'" json:"role"`
+	Created time.Time    `xorm:"'created'" json:"created"`
+}
+
+func (p Policy) TableName() string {
+	return "mfa_policy"
+}
@@ -0,0 +1,450 @@
+package mfaimpl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+var (
+	errSessionNotVerified = errutil.Forbidden("mfa.session-not-verified",
+		errutil.WithPublicMessage("Log in again with your second factor to change your factors"))
+	errNotUser = errutil.BadRequest("mfa.not-user", errutil.WithPublicMessage("Only users can use multi-factor authentication"))
+)
+
+type api struct {
+	service       *Service
+	accessControl accesscontrol.AccessControl
+	routeRegister routing.RouteRegister
+}
+
+func newAPI(service *Service, accessControl accesscontrol.AccessControl, routeRegister routing.RouteRegister) *api {
+	return &api{
+		service:       service,
+		accessControl: accessControl,
+		routeRegister: routeRegister,
+	}
+}
+
+func (a *api) registerAPIEndpoints() {
+	authorize := accesscontrol.Middleware(a.accessControl)
+	reqSignedIn := middleware.ReqSignedInNoAnonymous
+
+	a.routeRegister.Post("/api/login/mfa/totp", routing.Wrap(a.enrollTOTPOnLogin))
+
+	a.routeRegister.Group("/api/user/mfa", func(r routing.RouteRegister) {
+		r.Get("/", routing.Wrap(a.getStatus))
+		r.Post("/totp", routing.Wrap(a.enrollTOTP))
+		r.Post("/totp/:factorId/confirm", routing.Wrap(a.confirmTOTP))
+		r.Post("/webauthn/options", routing.Wrap(a.webAuthnCreationOptions))
+		r.Post("/webauthn", routing.Wrap(a.registerWebAuthn))
+		r.Delete("/factors/:factorId", routing.Wrap(a.deleteFactor))
+		r.Post("/recovery-codes", routing.Wrap(a.regenerateRecoveryCodes))
+	}, reqSignedIn)
+
+	a.routeRegister.Group("/api/org/mfa/policies", func(r routing.RouteRegister) {
+		r.Get("/", authorize(accesscontrol.EvalPermission(accesscontrol.ActionOrgsRead)), routing.Wrap(a.listPolicies))
+		r.Post("/", authorize(accesscontrol.EvalPermission(accesscontrol.ActionOrgsWrite)), routing.Wrap(a.createPolicy))
+		r.Delete("/:policyId", authorize(accesscontrol.EvalPermission(accesscontrol.ActionOrgsWrite)), routing.Wrap(a.deletePolicy))
+	}, reqSignedIn)
+
+	userIDScope := accesscontrol.Scope("global.users", "id", accesscontrol.Parameter(":id"))
+	a.routeRegister.Group("/api/admin/users/:id/mfa", func(r routing.RouteRegister) {
+		r.Get("/", authorize(accesscontrol.EvalPermission(accesscontrol.ActionUsersRead, userIDScope)), routing.Wrap(a.getUserStatus))
+		r.Delete("/", authorize(accesscontrol.EvalPermission(accesscontrol.ActionUsersWrite, userIDScope)), routing.Wrap(a.resetUserFactors))
+	}, reqSignedIn)
+}
+
+type statusResponse struct {
+	*mfa.Status
+	SessionVerified bool `json:"sessionVerified"`
+}
+
+func (a *api) getStatus(c *contextmodel.ReqContext) response.Response {
+	userID, err := signedInUserID(c)
+	if err != nil {
+		return response.Err(err)
+	}
+	status, err := a.service.GetStatus(c.Req.Context(), userID)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get multi-factor authentication status", err)
+	}
+	verified, err := a.isSessionVerified(c)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get multi-factor authentication status", err)
+	}
+	return response.JSON(http.StatusOK, statusResponse{Status: status, SessionVerified: verified})
+}
+
+type enrollTOTPRequest struct {
+	Name string `json:"name"`
+}
+
+type enrollTOTPResponse struct {
+	ID     int64  `json:"id"`
+	Secret string `json:"secret"`
+	// URL is the otpauth URL to show as QR code.
+	URL string `json:"url"`
+}
+
+func (a *api) enrollTOTP(c *contextmodel.ReqContext) response.Response {
+	userID, err := a.checkCanChangeFactors(c)
+	if err != nil {
+		return response.Err(err)
+	}
+	var req enrollTOTPRequest
+	if err := web.Bind(c.Req, &req); err != nil {
+		return response.Error(http.StatusBadRequest, "Bad request data", err)
+	}
+	return a.enrollTOTPForUser(c.Req.Context(), userID, req.Name, nil)
+}
+
+type enrollTOTPOnLoginRequest struct {
+	ChallengeToken string `json:"challengeToken"`
+}
+
+// enrollTOTPOnLogin enrolls a TOTP factor for users that must use a second
+// factor by policy and have none yet. The factor is confirmed by completing
+// the login with a code.
+func (a *api) enrollTOTPOnLogin(c *contextmodel.ReqContext) response.Response {
+	var req enrollTOTPOnLoginRequest
+	if err := web.Bind(c.Req, &req); err != nil {
+		return response.Error(http.StatusBadRequest, "Bad request data", err)
+	}
+	ctx := c.Req.Context()
+	state, err := a.service.getChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		return response.Err(err)
+	}
+	if !state.EnrollmentRequired {
+		return response.Err(mfa.ErrInvalidChallenge.Errorf("user already has a second factor"))
+	}
+	return a.enrollTOTPForUser(ctx, state.UserID, "", func(factorID int64) error {
+		if state.EnrollFactorID != 0 {
+			if err := a.service.store.deleteFactor(ctx, state.UserID, state.EnrollFactorID); err != nil && !errors.Is(err, mfa.ErrFactorNotFound) {
+				return err
+			}
+		}
+		state.EnrollFactorID = factorID
+		return a.service.saveChallenge(ctx, req.ChallengeToken, state)
+	})
+}
+
+func (a *api) enrollTOTPForUser(ctx context.Context, userID int64, name string, enrolled func(factorID int64) error) response.Response {
+	usr, err := a.service.userService.GetByID(ctx, &user.GetUserByIDQuery{ID: userID})
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get user", err)
+	}
+	factor, secret, err := a.service.enrollTOTP(ctx, usr, name)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to enroll authenticator app", err)
+	}
+	if enrolled != nil {
+		if err := enrolled(factor.ID); err != nil {
+			return response.ErrOrFallback(http.StatusInternalServerError, "Failed to enroll authenticator app", err)
+		}
+	}
+	return response.JSON(http.StatusOK, enrollTOTPResponse{
+		ID:     factor.ID,
+		Secret: secret,
+		URL:    totpURL(a.service.cfg.AuthMFA.Issuer, usr.Login, secret),
+	})
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code"`
+}
+
+type confirmResponse struct {
+	Factor *mfa.Factor `json:"factor"`
+	// RecoveryCodes are generated when the first factor of a user is confirmed.
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+func (a *api) confirmTOTP(c *contextmodel.ReqContext) response.Response {
+	userID, err := signedInUserID(c)
+	if err != nil {
+		return response.Err(err)
+	}
+	factorID, err := strconv.ParseInt(web.Params(c.Req)[":factorId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "factorId is invalid", err)
+	}
+	var req confirmTOTPRequest
+	if err := web.Bind(c.Req, &req); err != nil {
+		return response.Error(http.StatusBadRequest, "Bad request data", err)
+	}
+
+	ctx := c.Req.Context()
+	factor, err := a.service.store.getFactor(ctx, userID, factorID)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get factor", err)
+	}
+	if factor.Type != mfa.FactorTypeTOTP || factor.Confirmed {
+		return response.Err(mfa.ErrFactorNotFound.Errorf("no unconfirmed TOTP factor %d", factorID))
+	}
+	ok, err := a.service.verifyTOTP(ctx, factor, req.Code)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to confirm factor", err)
+	}
+	if !ok {
+		return response.Err(mfa.ErrInvalidCode.Errorf("invalid TOTP code"))
+	}
+	return a.factorConfirmed(c, userID, factor, string(mfa.FactorTypeTOTP))
+}
+
+// factorConfirmed records the current session as verified, the user just
+// completed the factor, and generates recovery codes for the first factor.
+func (a *api) factorConfirmed(c *contextmodel.ReqContext, userID int64, factor *mfa.Factor, method string) response.Response {
+	ctx := c.Req.Context()
+	if c.UserToken != nil {
+		a.service.recordSession(ctx, userID, c.UserToken.Id, method)
+	}
+	resp := confirmResponse{Factor: factor}
+	remaining, err := a.service.store.countRecoveryCodes(ctx, userID)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get recovery codes", err)
+	}
+	if remaining == 0 {
+		resp.RecoveryCodes, err = a.service.generateRecoveryCodes(ctx, userID)
+		if err != nil {
+			return response.ErrOrFallback(http.StatusInternalServerError, "Failed to generate recovery codes", err)
+		}
+	}
+	return response.JSON(http.StatusOK, resp)
+}
+
+func (a *api) webAuthnCreationOptions(c *contextmodel.ReqContext) response.Response {
+	userID, err := a.checkCanChangeFactors(c)
+	if err != nil {
+		return response.Err(err)
+	}
+	ctx := c.Req.Context()
+	factors, err := a.service.store.listFactors(ctx, userID)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to list factors", err)
+	}
+	var exclude []string
+	for _, f := range factors {
+		if f.Type == mfa.FactorTypeWebAuthn {
+			exclude = append(exclude, f.CredentialID)
+		}
+	}
+	challenge, err := newWebAuthnChallenge()
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to create challenge", err)
+	}
+	if err := a.service.cache.Set(ctx, webAuthnCacheKey(userID), []byte(challenge), a.service.cfg.AuthMFA.ChallengeTTL); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to create challenge", err)
+	}
+	options := a.service.webAuthn.creationOptions(challenge, userID, c.SignedInUser.Login, c.SignedInUser.Name, exclude)
+	return response.JSON(http.StatusOK, map[string]any{"publicKey": options})
+}
+
+type registerWebAuthnRequest struct {
+	Name       string              `json:"name"`
+	Credential *CredentialCreation `json:"credential"`
+}
+
+func (a *api) registerWebAuthn(c *contextmodel.ReqContext) response.Response {
+	userID, err := a.checkCanChangeFactors(c)
+	if err != nil {
+		return response.Err(err)
+	}
+	var req registerWebAuthnRequest
+	if err := web.Bind(c.Req, &req); err != nil {
+		return response.Error(http.StatusBadRequest, "Bad request data", err)
+	}
+
+	ctx := c.Req.Context()
+	challenge, err := a.service.cache.Get(ctx, webAuthnCacheKey(userID))
+	if err != nil {
+		if errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return response.Err(mfa.ErrInvalidChallenge.Errorf("no registration in progress"))
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get challenge", err)
+	}
+	if err := a.service.cache.Delete(ctx, webAuthnCacheKey(userID)); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to delete challenge", err)
+	}
+
+	credentialID, publicKey, signCount, err := a.service.webAuthn.verifyRegistration(string(challenge), req.Credential)
+	if err != nil {
+		return response.Err(mfa.ErrInvalidCredential.Errorf("invalid registration: %w", err))
+	}
+	if req.Name == "" {
+		req.Name = "Security key"
+	}
+	factor := &mfa.Factor{
+		UserID:       userID,
+		Type:         mfa.FactorTypeWebAuthn,
+		Name:         req.Name,
+		CredentialID: credentialID,
+		PublicKey:    publicKey,
+		SignCount:    int64(signCount),
+		Confirmed:    true,
+		Created:      a.service.now(),
+	}
+	if err := a.service.store.insertFactor(ctx, factor); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to register security key", err)
+	}
+	return a.factorConfirmed(c, userID, factor, string(mfa.FactorTypeWebAuthn))
+}
+
+func (a *api) deleteFactor(c *contextmodel.ReqContext) response.Response {
+	userID, err := a.checkCanChangeFactors(c)
+	if err != nil {
+		return response.Err(err)
+	}
+	factorID, err := strconv.ParseInt(web.Params(c.Req)[":factorId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "factorId is invalid", err)
+	}
+	if err := a.service.store.deleteFactor(c.Req.Context(), userID, factorID); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to delete factor", err)
+	}
+	return response.Success("Factor deleted")
+}
+
+func (a *api) regenerateRecoveryCodes(c *contextmodel.ReqContext) response.Response {
+	userID, err := a.checkCanChangeFactors(c)
+	if err != nil {
+		return response.Err(err)
+	}
+	ctx := c.Req.Context()
+	factors, err := a.service.store.listFactors(ctx, userID)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to list factors", err)
+	}
+	if len(confirmedFactors(factors)) == 0 {
+		return response.Error(http.StatusBadRequest, "Recovery codes require a second factor", nil)
+	}
+	codes, err := a.service.generateRecoveryCodes(ctx, userID)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to generate recovery codes", err)
+	}
+	return response.JSON(http.StatusOK, map[string]any{"recoveryCodes": codes})
+}
+
+func (a *api) listPolicies(c *contextmodel.ReqContext) response.Response {
+	policies, err := a.service.store.listPolicies(c.Req.Context(), c.SignedInUser.GetOrgID())
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to list policies", err)
+	}
+	return response.JSON(http.StatusOK, policies)
+}
+
+type createPolicyRequest struct {
+	// Role the policy applies to, all members of the organization if empty.
+	Role org.RoleType `json:"role"`
+}
+
+func (a *api) createPolicy(c *contextmodel.ReqContext) response.Response {
+	var req createPolicyRequest
+	if err := web.Bind(c.Req, &req); err != nil {
+		return response.Error(http.StatusBadRequest, "Bad request data", err)
+	}
+	if req.Role != "" && !req.Role.IsValid() {
+		return response.Err(mfa.ErrInvalidRole.Errorf("invalid role %q", req.Role))
+	}
+	policy := &mfa.Policy{
+		OrgID:   c.SignedInUser.GetOrgID(),
+		Role:    req.Role,
+		Created: a.service.now(),
+	}
+	if err := a.service.store.insertPolicy(c.Req.Context(), policy); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to create policy", err)
+	}
+	return response.JSON(http.StatusOK, policy)
+}
+
+func (a *api) deletePolicy(c *contextmodel.ReqContext) response.Response {
+	policyID, err := strconv.ParseInt(web.Params(c.Req)[":policyId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "policyId is invalid", err)
+	}
+	if err := a.service.store.deletePolicy(c.Req.Context(), c.SignedInUser.GetOrgID(), policyID); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to delete policy", err)
+	}
+	return response.Success("Policy deleted")
+}
+
+func (a *api) getUserStatus(c *contextmodel.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+	status, err := a.service.GetStatus(c.Req.Context(), userID)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get multi-factor authentication status", err)
+	}
+	return response.JSON(http.StatusOK, status)
+}
+
+func (a *api) resetUserFactors(c *contextmodel.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+	if err := a.service.ResetFactors(c.Req.Context(), userID); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to reset factors", err)
+	}
+	return response.Success("Factors reset")
+}
+
+// checkCanChangeFactors returns the ID of the signed in user. Users with a
+// confirmed factor can only change their factors in a session verified with a
+// second factor, so a stolen password alone isn't enough.
+func (a *api) checkCanChangeFactors(c *contextmodel.ReqContext) (int64, error) {
+	userID, err := signedInUserID(c)
+	if err != nil {
+		return 0, err
+	}
+	factors, err := a.service.store.listFactors(c.Req.Context(), userID)
+	if err != nil {
+		return 0, err
+	}
+	if len(confirmedFactors(factors)) == 0 {
+		return userID, nil
+	}
+	verified, err := a.isSessionVerified(c)
+	if err != nil {
+		return 0, err
+	}
+	if !verified {
+		return 0, errSessionNotVerified.Errorf("session of user %d not verified", userID)
+	}
+	return userID, nil
+}
+
+func (a *api) isSessionVerified(c *contextmodel.ReqContext) (bool, error) {
+	if c.UserToken == nil {
+		return false, nil
+	}
+	return a.service.IsSessionVerified(c.Req.Context(), c.UserToken.Id)
+}
+
+func signedInUserID(c *contextmodel.ReqContext) (int64, error) {
+	if !c.SignedInUser.GetID().IsType(identity.TypeUser) {
+		return 0, errNotUser.Errorf("identity %s is not a user", c.SignedInUser.GetID())
+	}
+	return c.SignedInUser.GetID().ParseInt()
+}
+
+func webAuthnCacheKey(userID int64) string {
+	return webAuthnCachePrefix + strconv.FormatInt(userID, 10)
+}
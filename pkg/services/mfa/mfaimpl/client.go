@@ -0,0 +1,51 @@
+package mfaimpl
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// metaKeyMethod is set on requests completing a login challenge, so the
+// session created for the login is recorded as verified.
+const metaKeyMethod = "mfaMethod"
+
+var errBadForm = errutil.BadRequest("mfa.invalid-form", errutil.WithPublicMessage("bad login data"))
+
+var _ authn.Client = new(Client)
+
+// Client completes logins challenged for a second factor after the password.
+type Client struct {
+	service *Service
+}
+
+func (c *Client) Name() string {
+	return authn.ClientMFA
+}
+
+func (c *Client) IsEnabled() bool {
+	return c.service.cfg.AuthMFA.Enabled
+}
+
+func (c *Client) Authenticate(ctx context.Context, r *authn.Request) (*authn.Identity, error) {
+	form := LoginForm{}
+	if err := web.Bind(r.HTTPRequest, &form); err != nil {
+		return nil, errBadForm.Errorf("failed to parse request: %w", err)
+	}
+
+	state, method, err := c.service.verifyChallenge(ctx, form)
+	if err != nil {
+		return nil, err
+	}
+
+	r.SetMeta(metaKeyMethod, method)
+	return &authn.Identity{
+		ID:              identity.NewTypedID(identity.TypeUser, state.UserID),
+		OrgID:           r.OrgID,
+		ClientParams:    authn.ClientParams{FetchSyncedUser: true, SyncPermissions: true},
+		AuthenticatedBy: state.AuthModule,
+	}, nil
+}
@@ -0,0 +1,596 @@
+package mfaimpl
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const (
+	challengeCachePrefix      = "mfa-challenge-"
+	webAuthnCachePrefix       = "mfa-webauthn-registration-"
+	failedAttemptsCachePrefix = "mfa-failed-attempts-"
+	maxChallengeAttempts      = 5
+	recoveryCodeCount         = 10
+	// maxFailedAttempts is the number of failed attempts of a user, across all
+	// challenges, after which no challenge is issued or completed until no
+	// attempt failed for failedAttemptsLockout.
+	maxFailedAttempts     = 10
+	failedAttemptsLockout = 15 * time.Minute
+)
+
+var errMFARequired = errutil.Unauthorized("mfa.required").MustTemplate(
+	"second authentication factor required for user {{ .Private.userID }}",
+	errutil.WithPublic("Second authentication factor required"),
+)
+
+var _ mfa.Service = (*Service)(nil)
+
+type Service struct {
+	cfg         *setting.Cfg
+	log         log.Logger
+	store       *store
+	secrets     secrets.Service
+	cache       remotecache.CacheStorage
+	userService user.Service
+	webAuthn    *webAuthn
+	now         func() time.Time
+}
+
+func ProvideService(
+	cfg *setting.Cfg, sqlStore db.DB, secretsService secrets.Service, cache remotecache.CacheStorage,
+	authnService authn.Service, userService user.Service,
+	accessControl accesscontrol.AccessControl, routeRegister routing.RouteRegister,
+) *Service {
+	s := &Service{
+		cfg:         cfg,
+		log:         log.New("mfa"),
+		store:       &store{db: sqlStore},
+		secrets:     secretsService,
+		cache:       cache,
+		userService: userService,
+		webAuthn: &webAuthn{
+			rpID:   cfg.AuthMFA.WebAuthnRPID,
+			rpName: cfg.AuthMFA.Issuer,
+			origin: cfg.AuthMFA.WebAuthnOrigin,
+		},
+		now: time.Now,
+	}
+
+	if cfg.AuthMFA.Enabled {
+		authnService.RegisterClient(&Client{service: s})
+		// The challenge runs once the user is synced and the identity is complete.
+		authnService.RegisterPostAuthHook(s.challengeHook, 105)
+		authnService.RegisterPostLoginHook(s.recordSessionHook, 150)
+
+		api := newAPI(s, accessControl, routeRegister)
+		api.registerAPIEndpoints()
+	}
+
+	return s
+}
+
+func (s *Service) GetStatus(ctx context.Context, userID int64) (*mfa.Status, error) {
+	factors, err := s.store.listFactors(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := s.isRequired(ctx, userID, factors)
+	if err != nil {
+		return nil, err
+	}
+	remaining, err := s.store.countRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &mfa.Status{Factors: factors, Required: required, RecoveryCodesRemaining: remaining}, nil
+}
+
+func (s *Service) IsRequired(ctx context.Context, userID int64) (bool, error) {
+	factors, err := s.store.listFactors(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return s.isRequired(ctx, userID, factors)
+}
+
+func (s *Service) isRequired(ctx context.Context, userID int64, factors []*mfa.Factor) (bool, error) {
+	if len(confirmedFactors(factors)) > 0 {
+		return true, nil
+	}
+	return s.store.isRequiredByPolicy(ctx, userID)
+}
+
+func (s *Service) IsSessionVerified(ctx context.Context, tokenID int64) (bool, error) {
+	return s.store.isSessionVerified(ctx, tokenID)
+}
+
+func (s *Service) ResetFactors(ctx context.Context, userID int64) error {
+	if err := s.store.deleteUserData(ctx, userID); err != nil {
+		return err
+	}
+	return s.cache.Delete(ctx, failedAttemptsKey(userID))
+}
+
+// challengeHook stops password authentications of users that must complete a
+// second factor. Logins get a challenge to complete with the MFA client, other
+// requests, like basic auth, are rejected.
+func (s *Service) challengeHook(ctx context.Context, id *authn.Identity, r *authn.Request) error {
+	if r.GetMeta(authn.MetaKeyUsername) == "" || !id.ID.IsType(identity.TypeUser) {
+		return nil
+	}
+	userID, err := id.ID.ParseInt()
+	if err != nil {
+		return err
+	}
+	factors, err := s.store.listFactors(ctx, userID)
+	if err != nil {
+		return err
+	}
+	required, err := s.isRequired(ctx, userID, factors)
+	if err != nil || !required {
+		return err
+	}
+	if r.GetMeta(authn.MetaKeyIsLogin) != "true" {
+		return mfa.ErrBasicAuthForbidden.Errorf("user %d must complete a second factor", userID)
+	}
+	if err := s.checkFailedAttempts(ctx, userID); err != nil {
+		return err
+	}
+	return s.newChallenge(ctx, userID, id.AuthenticatedBy, confirmedFactors(factors))
+}
+
+// challenge is the state of a login waiting for a second factor.
+type challenge struct {
+	UserID     int64  `json:"userId"`
+	AuthModule string `json:"authModule"`
+	// EnrollmentRequired is set when a policy requires a second factor and the
+	// user has none, a TOTP factor can then be enrolled with the challenge.
+	EnrollmentRequired bool      `json:"enrollmentRequired"`
+	EnrollFactorID     int64     `json:"enrollFactorId,omitempty"`
+	WebAuthnChallenge  string    `json:"webAuthnChallenge,omitempty"`
+	Attempts           int       `json:"attempts"`
+	Expires            time.Time `json:"expires"`
+}
+
+func (s *Service) newChallenge(ctx context.Context, userID int64, authModule string, factors []*mfa.Factor) error {
+	token, err := util.GetRandomString(32)
+	if err != nil {
+		return err
+	}
+	state := challenge{
+		UserID:             userID,
+		AuthModule:         authModule,
+		EnrollmentRequired: len(factors) == 0,
+		Expires:            s.now().Add(s.cfg.AuthMFA.ChallengeTTL),
+	}
+
+	methods := []string{}
+	var credentials []string
+	for _, f := range factors {
+		switch f.Type {
+		case mfa.FactorTypeTOTP:
+			methods = appendMethod(methods, string(mfa.FactorTypeTOTP))
+		case mfa.FactorTypeWebAuthn:
+			methods = appendMethod(methods, string(mfa.FactorTypeWebAuthn))
+			credentials = append(credentials, f.CredentialID)
+		}
+	}
+	if state.EnrollmentRequired {
+		methods = append(methods, string(mfa.FactorTypeTOTP))
+	} else {
+		methods = append(methods, mfa.MethodRecoveryCode)
+	}
+
+	public := map[string]any{
+		"challengeToken":     token,
+		"methods":            methods,
+		"enrollmentRequired": state.EnrollmentRequired,
+	}
+	if len(credentials) > 0 {
+		state.WebAuthnChallenge, err = newWebAuthnChallenge()
+		if err != nil {
+			return err
+		}
+		public["webauthn"] = s.webAuthn.requestOptions(state.WebAuthnChallenge, credentials)
+	}
+
+	if err := s.saveChallenge(ctx, token, &state); err != nil {
+		return err
+	}
+	return errMFARequired.Build(errutil.TemplateData{
+		Private: map[string]any{"userID": userID},
+		Public:  public,
+	})
+}
+
+func appendMethod(methods []string, method string) []string {
+	for _, m := range methods {
+		if m == method {
+			return methods
+		}
+	}
+	return append(methods, method)
+}
+
+func challengeKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return challengeCachePrefix + hex.EncodeToString(sum[:])
+}
+
+func (s *Service) saveChallenge(ctx context.Context, token string, state *challenge) error {
+	ttl := state.Expires.Sub(s.now())
+	if ttl <= 0 {
+		return mfa.ErrInvalidChallenge.Errorf("challenge expired")
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.cache.Set(ctx, challengeKey(token), data, ttl)
+}
+
+func (s *Service) getChallenge(ctx context.Context, token string) (*challenge, error) {
+	if token == "" {
+		return nil, mfa.ErrInvalidChallenge.Errorf("missing challenge token")
+	}
+	data, err := s.cache.Get(ctx, challengeKey(token))
+	if err != nil {
+		if errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return nil, mfa.ErrInvalidChallenge.Errorf("challenge not found")
+		}
+		return nil, err
+	}
+	var state challenge
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	if !s.now().Before(state.Expires) {
+		return nil, mfa.ErrInvalidChallenge.Errorf("challenge expired")
+	}
+	return &state, nil
+}
+
+// LoginForm is the body of a request completing a login challenge with one of
+// a TOTP code, a recovery code or a WebAuthn assertion.
+type LoginForm struct {
+	ChallengeToken string               `json:"challengeToken"`
+	Code           string               `json:"code"`
+	RecoveryCode   string               `json:"recoveryCode"`
+	WebAuthn       *CredentialAssertion `json:"webauthn"`
+}
+
+// verifyChallenge completes a login challenge and returns its state and the
+// method used. The challenge is dropped after too many failed attempts, and no
+// challenge of the user is completed after too many failed attempts overall.
+func (s *Service) verifyChallenge(ctx context.Context, form LoginForm) (*challenge, string, error) {
+	state, err := s.getChallenge(ctx, form.ChallengeToken)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.checkFailedAttempts(ctx, state.UserID); err != nil {
+		return nil, "", err
+	}
+
+	method, err := s.verifyFactor(ctx, state, form)
+	if err != nil {
+		if errors.Is(err, mfa.ErrInvalidCode) || errors.Is(err, mfa.ErrInvalidCredential) {
+			if err := s.recordFailedAttempt(ctx, state.UserID); err != nil {
+				s.log.FromContext(ctx).Warn("Failed to record failed attempt", "userId", state.UserID, "error", err)
+			}
+		}
+		state.Attempts++
+		if state.Attempts >= maxChallengeAttempts {
+			if err := s.cache.Delete(ctx, challengeKey(form.ChallengeToken)); err != nil {
+				s.log.FromContext(ctx).Warn("Failed to delete challenge", "error", err)
+			}
+		} else if err := s.saveChallenge(ctx, form.ChallengeToken, state); err != nil {
+			s.log.FromContext(ctx).Warn("Failed to update challenge", "error", err)
+		}
+		return nil, "", err
+	}
+
+	if err := s.cache.Delete(ctx, challengeKey(form.ChallengeToken)); err != nil {
+		return nil, "", err
+	}
+	if err := s.cache.Delete(ctx, failedAttemptsKey(state.UserID)); err != nil {
+		s.log.FromContext(ctx).Warn("Failed to reset failed attempts", "userId", state.UserID, "error", err)
+	}
+	return state, method, nil
+}
+
+// failedAttempts counts the failed attempts of a user to complete a challenge.
+// Each failure extends the lockout, so the count is kept until no attempt
+// failed for failedAttemptsLockout.
+type failedAttempts struct {
+	Count int       `json:"count"`
+	Until time.Time `json:"until"`
+}
+
+func failedAttemptsKey(userID int64) string {
+	return failedAttemptsCachePrefix + strconv.FormatInt(userID, 10)
+}
+
+func (s *Service) getFailedAttempts(ctx context.Context, userID int64) (*failedAttempts, error) {
+	data, err := s.cache.Get(ctx, failedAttemptsKey(userID))
+	if err != nil {
+		if errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return &failedAttempts{}, nil
+		}
+		return nil, err
+	}
+	var attempts failedAttempts
+	if err := json.Unmarshal(data, &attempts); err != nil {
+		return nil, err
+	}
+	if !s.now().Before(attempts.Until) {
+		return &failedAttempts{}, nil
+	}
+	return &attempts, nil
+}
+
+// checkFailedAttempts rejects users with too many failed attempts. A password
+// login creates a new challenge, so the attempts of a single challenge don't
+// limit guessing codes.
+func (s *Service) checkFailedAttempts(ctx context.Context, userID int64) error {
+	attempts, err := s.getFailedAttempts(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if attempts.Count >= maxFailedAttempts {
+		return mfa.ErrTooManyAttempts.Errorf("user %d failed to complete a second factor %d times", userID, attempts.Count)
+	}
+	return nil
+}
+
+func (s *Service) recordFailedAttempt(ctx context.Context, userID int64) error {
+	attempts, err := s.getFailedAttempts(ctx, userID)
+	if err != nil {
+		return err
+	}
+	attempts.Count++
+	attempts.Until = s.now().Add(failedAttemptsLockout)
+	data, err := json.Marshal(attempts)
+	if err != nil {
+		return err
+	}
+	return s.cache.Set(ctx, failedAttemptsKey(userID), data, failedAttemptsLockout)
+}
+
+func (s *Service) verifyFactor(ctx context.Context, state *challenge, form LoginForm) (string, error) {
+	switch {
+	case form.Code != "":
+		factors, err := s.store.listFactors(ctx, state.UserID)
+		if err != nil {
+			return "", err
+		}
+		for _, f := range factors {
+			if f.Type != mfa.FactorTypeTOTP || (!f.Confirmed && f.ID != state.EnrollFactorID) {
+				continue
+			}
+			ok, err := s.verifyTOTP(ctx, f, form.Code)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				return string(mfa.FactorTypeTOTP), nil
+			}
+		}
+		return "", mfa.ErrInvalidCode.Errorf("invalid TOTP code")
+	case form.RecoveryCode != "":
+		if state.EnrollmentRequired {
+			return "", mfa.ErrInvalidCode.Errorf("no recovery codes before enrollment")
+		}
+		if err := s.useRecoveryCode(ctx, state.UserID, form.RecoveryCode); err != nil {
+			return "", err
+		}
+		return mfa.MethodRecoveryCode, nil
+	case form.WebAuthn != nil:
+		if state.WebAuthnChallenge == "" {
+			return "", mfa.ErrInvalidCredential.Errorf("no security key registered")
+		}
+		if err := s.verifyWebAuthn(ctx, state.UserID, state.WebAuthnChallenge, form.WebAuthn); err != nil {
+			return "", err
+		}
+		return string(mfa.FactorTypeWebAuthn), nil
+	default:
+		return "", mfa.ErrInvalidCode.Errorf("missing second factor")
+	}
+}
+
+func (s *Service) verifyTOTP(ctx context.Context, factor *mfa.Factor, code string) (bool, error) {
+	secret, err := s.decryptSecret(ctx, factor.Secret)
+	if err != nil {
+		return false, err
+	}
+	step, ok := validateTOTP(secret, code, s.now(), factor.LastStep)
+	if !ok {
+		return false, nil
+	}
+	previous := *factor
+	now := s.now()
+	factor.LastStep = step
+	factor.Confirmed = true
+	factor.LastUsed = &now
+	return s.store.updateFactorUse(ctx, factor, previous)
+}
+
+func (s *Service) verifyWebAuthn(ctx context.Context, userID int64, webAuthnChallenge string, assertion *CredentialAssertion) error {
+	rawID, err := decodeBase64URL(assertion.RawID)
+	if err != nil {
+		return mfa.ErrInvalidCredential.Errorf("invalid credential ID: %w", err)
+	}
+	credentialID := base64.RawURLEncoding.EncodeToString(rawID)
+
+	factors, err := s.store.listFactors(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, f := range factors {
+		if f.Type != mfa.FactorTypeWebAuthn || f.CredentialID != credentialID {
+			continue
+		}
+		signCount, err := s.webAuthn.verifyAssertion(webAuthnChallenge, f.PublicKey, f.SignCount, assertion)
+		if err != nil {
+			return mfa.ErrInvalidCredential.Errorf("invalid assertion: %w", err)
+		}
+		previous := *f
+		now := s.now()
+		f.SignCount = int64(signCount)
+		f.LastUsed = &now
+		ok, err := s.store.updateFactorUse(ctx, f, previous)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return mfa.ErrInvalidCredential.Errorf("credential used concurrently")
+		}
+		return nil
+	}
+	return mfa.ErrInvalidCredential.Errorf("unknown credential")
+}
+
+// enrollTOTP creates an unconfirmed TOTP factor. It is confirmed by the first
+// valid code.
+func (s *Service) enrollTOTP(ctx context.Context, usr *user.User, name string) (*mfa.Factor, string, error) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	encrypted, err := s.secrets.Encrypt(ctx, []byte(secret), secrets.WithoutScope())
+	if err != nil {
+		return nil, "", err
+	}
+	if name == "" {
+		name = "Authenticator app"
+	}
+	factor := &mfa.Factor{
+		UserID:  usr.ID,
+		Type:    mfa.FactorTypeTOTP,
+		Name:    name,
+		Secret:  base64.StdEncoding.EncodeToString(encrypted),
+		Created: s.now(),
+	}
+	if err := s.store.insertFactor(ctx, factor); err != nil {
+		return nil, "", err
+	}
+	return factor, secret, nil
+}
+
+func (s *Service) decryptSecret(ctx context.Context, encoded string) (string, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	secret, err := s.secrets.Decrypt(ctx, encrypted)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// generateRecoveryCodes replaces the recovery codes of a user. Only hashes of
+// the codes are stored, they are shown to the user once.
+func (s *Service) generateRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	stored := make([]*recoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := util.GetRandomString(10)
+		if err != nil {
+			return nil, err
+		}
+		code = strings.ToLower(code)
+		salt, err := util.GetRandomString(10)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := util.EncodePassword(code, salt)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+		stored = append(stored, &recoveryCode{UserID: userID, CodeHash: hash, Salt: salt, Created: s.now()})
+	}
+	if err := s.store.replaceRecoveryCodes(ctx, userID, stored); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *Service) useRecoveryCode(ctx context.Context, userID int64, code string) error {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	codes, err := s.store.listRecoveryCodes(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, c := range codes {
+		hash, err := util.EncodePassword(code, c.Salt)
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(c.CodeHash)) != 1 {
+			continue
+		}
+		ok, err := s.store.deleteRecoveryCode(ctx, c.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		return nil
+	}
+	return mfa.ErrInvalidCode.Errorf("invalid recovery code")
+}
+
+// recordSessionHook records the sessions created after completing a second factor.
+func (s *Service) recordSessionHook(ctx context.Context, id *authn.Identity, r *authn.Request, err error) {
+	method := r.GetMeta(metaKeyMethod)
+	if err != nil || method == "" || id == nil || id.SessionToken == nil {
+		return
+	}
+	s.recordSession(ctx, id.SessionToken.UserId, id.SessionToken.Id, method)
+}
+
+func (s *Service) recordSession(ctx context.Context, userID, tokenID int64, method string) {
+	err := s.store.insertSession(ctx, &session{
+		UserID:   userID,
+		TokenID:  tokenID,
+		Method:   method,
+		Verified: s.now(),
+	}, s.cfg.LoginMaxLifetime)
+	if err != nil {
+		s.log.FromContext(ctx).Error("Failed to record verified session", "userId", userID, "error", err)
+	}
+}
+
+func confirmedFactors(factors []*mfa.Factor) []*mfa.Factor {
+	confirmed := make([]*mfa.Factor, 0, len(factors))
+	for _, f := range factors {
+		if f.Confirmed {
+			confirmed = append(confirmed, f)
+		}
+	}
+	return confirmed
+}
@@ -0,0 +1,325 @@
+package mfaimpl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/auth"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func setupTestService(t *testing.T) (*Service, db.DB) {
+	t.Helper()
+	sqlStore := db.InitTestDB(t)
+	cfg := setting.NewCfg()
+	cfg.AuthMFA = setting.AuthMFASettings{
+		Issuer:         "Grafana",
+		ChallengeTTL:   5 * time.Minute,
+		WebAuthnRPID:   testWebAuthn.rpID,
+		WebAuthnOrigin: testWebAuthn.origin,
+	}
+	cfg.LoginMaxLifetime = 30 * 24 * time.Hour
+	// MFA is disabled so no client or API gets registered, the hooks are called directly.
+	s := ProvideService(cfg, sqlStore, fakes.NewFakeSecretsService(), remotecache.NewFakeCacheStorage(), nil, nil, nil, nil)
+	return s, sqlStore
+}
+
+func passwordRequest(login bool) *authn.Request {
+	r := &authn.Request{}
+	r.SetMeta(authn.MetaKeyUsername, "test")
+	if login {
+		r.SetMeta(authn.MetaKeyIsLogin, "true")
+	}
+	return r
+}
+
+func userIdentity(userID int64) *authn.Identity {
+	return &authn.Identity{ID: identity.NewTypedID(identity.TypeUser, userID), AuthenticatedBy: "password"}
+}
+
+// challengeToken runs the challenge hook and returns the token of the issued challenge.
+func challengeToken(t *testing.T, s *Service, userID int64) (string, map[string]any) {
+	t.Helper()
+	err := s.challengeHook(context.Background(), userIdentity(userID), passwordRequest(true))
+	require.ErrorIs(t, err, errMFARequired.Base)
+
+	var grafanaErr errutil.Error
+	require.ErrorAs(t, err, &grafanaErr)
+	public := grafanaErr.Public().Extra
+	token, ok := public["challengeToken"].(string)
+	require.True(t, ok)
+	return token, public
+}
+
+func enrollConfirmedTOTP(t *testing.T, s *Service, userID int64) string {
+	t.Helper()
+	factor, secret, err := s.enrollTOTP(context.Background(), &user.User{ID: userID, Login: "test"}, "")
+	require.NoError(t, err)
+	ok, err := s.verifyTOTP(context.Background(), factor, currentTOTPCode(t, s, secret))
+	require.NoError(t, err)
+	require.True(t, ok)
+	return secret
+}
+
+func currentTOTPCode(t *testing.T, s *Service, secret string) string {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	return totpCode(key, s.now().Unix()/totpPeriod)
+}
+
+func TestIntegrationMFA_Challenge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+
+	t.Run("users without factors or policies are not challenged", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		require.NoError(t, s.challengeHook(ctx, userIdentity(1), passwordRequest(true)))
+	})
+
+	t.Run("requests without password are not challenged", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		enrollConfirmedTOTP(t, s, 1)
+		require.NoError(t, s.challengeHook(ctx, userIdentity(1), &authn.Request{}))
+	})
+
+	t.Run("basic auth is rejected for users with a factor", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		enrollConfirmedTOTP(t, s, 1)
+		err := s.challengeHook(ctx, userIdentity(1), passwordRequest(false))
+		assert.ErrorIs(t, err, mfa.ErrBasicAuthForbidden)
+	})
+
+	t.Run("login completes with a TOTP code once", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		secret := enrollConfirmedTOTP(t, s, 1)
+		// The code used to confirm the factor can't be reused.
+		s.now = func() time.Time { return time.Now().Add(totpPeriod * time.Second) }
+
+		token, public := challengeToken(t, s, 1)
+		assert.Equal(t, []string{"totp", mfa.MethodRecoveryCode}, public["methods"])
+		assert.Equal(t, false, public["enrollmentRequired"])
+
+		state, method, err := s.verifyChallenge(ctx, LoginForm{ChallengeToken: token, Code: currentTOTPCode(t, s, secret)})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), state.UserID)
+		assert.Equal(t, "password", state.AuthModule)
+		assert.Equal(t, "totp", method)
+
+		_, _, err = s.verifyChallenge(ctx, LoginForm{ChallengeToken: token, Code: currentTOTPCode(t, s, secret)})
+		assert.ErrorIs(t, err, mfa.ErrInvalidChallenge)
+	})
+
+	t.Run("challenge is dropped after too many attempts", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		enrollConfirmedTOTP(t, s, 1)
+		token, _ := challengeToken(t, s, 1)
+
+		for i := 0; i < maxChallengeAttempts; i++ {
+			_, _, err := s.verifyChallenge(ctx, LoginForm{ChallengeToken: token, Code: "000000"})
+			require.ErrorIs(t, err, mfa.ErrInvalidCode)
+		}
+		_, _, err := s.verifyChallenge(ctx, LoginForm{ChallengeToken: token, Code: "000000"})
+		assert.ErrorIs(t, err, mfa.ErrInvalidChallenge)
+	})
+
+	t.Run("attempts are limited across challenges", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		secret := enrollConfirmedTOTP(t, s, 1)
+		s.now = func() time.Time { return time.Now().Add(totpPeriod * time.Second) }
+
+		for failed := 0; failed < maxFailedAttempts; {
+			token, _ := challengeToken(t, s, 1)
+			for i := 0; i < maxChallengeAttempts-1 && failed < maxFailedAttempts; i++ {
+				_, _, err := s.verifyChallenge(ctx, LoginForm{ChallengeToken: token, Code: "000000"})
+				require.ErrorIs(t, err, mfa.ErrInvalidCode)
+				failed++
+			}
+		}
+
+		// No challenge is issued, and pending challenges can't be completed with a valid code.
+		err := s.challengeHook(ctx, userIdentity(1), passwordRequest(true))
+		require.ErrorIs(t, err, mfa.ErrTooManyAttempts)
+
+		s.now = func() time.Time { return time.Now().Add(failedAttemptsLockout + totpPeriod*time.Second) }
+		token, _ := challengeToken(t, s, 1)
+		s.now = func() time.Time { return time.Now().Add(totpPeriod * time.Second) }
+		_, _, err = s.verifyChallenge(ctx, LoginForm{ChallengeToken: token, Code: currentTOTPCode(t, s, secret)})
+		require.ErrorIs(t, err, mfa.ErrTooManyAttempts)
+
+		// The lockout ends when no attempt failed for a while, and a completed challenge resets the count.
+		s.now = func() time.Time { return time.Now().Add(failedAttemptsLockout + totpPeriod*time.Second) }
+		_, _, err = s.verifyChallenge(ctx, LoginForm{ChallengeToken: token, Code: currentTOTPCode(t, s, secret)})
+		require.NoError(t, err)
+		attempts, err := s.getFailedAttempts(ctx, 1)
+		require.NoError(t, err)
+		assert.Zero(t, attempts.Count)
+	})
+
+	t.Run("challenge expires", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		enrollConfirmedTOTP(t, s, 1)
+		token, _ := challengeToken(t, s, 1)
+
+		s.now = func() time.Time { return time.Now().Add(s.cfg.AuthMFA.ChallengeTTL) }
+		_, _, err := s.verifyChallenge(ctx, LoginForm{ChallengeToken: token, Code: "000000"})
+		assert.ErrorIs(t, err, mfa.ErrInvalidChallenge)
+	})
+
+	t.Run("login completes with a recovery code once", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		enrollConfirmedTOTP(t, s, 1)
+		codes, err := s.generateRecoveryCodes(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, codes, recoveryCodeCount)
+
+		token, _ := challengeToken(t, s, 1)
+		_, method, err := s.verifyChallenge(ctx, LoginForm{ChallengeToken: token, RecoveryCode: codes[0]})
+		require.NoError(t, err)
+		assert.Equal(t, mfa.MethodRecoveryCode, method)
+
+		token, _ = challengeToken(t, s, 1)
+		_, _, err = s.verifyChallenge(ctx, LoginForm{ChallengeToken: token, RecoveryCode: codes[0]})
+		assert.ErrorIs(t, err, mfa.ErrInvalidCode)
+
+		status, err := s.GetStatus(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(recoveryCodeCount-1), status.RecoveryCodesRemaining)
+	})
+
+	t.Run("login completes with a security key", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		authenticator := newFakeAuthenticator(t)
+		registration, err := newWebAuthnChallenge()
+		require.NoError(t, err)
+		credentialID, publicKey, signCount, err := s.webAuthn.verifyRegistration(registration, authenticator.create(registration))
+		require.NoError(t, err)
+		require.NoError(t, s.store.insertFactor(ctx, &mfa.Factor{
+			UserID: 1, Type: mfa.FactorTypeWebAuthn, Name: "key", CredentialID: credentialID,
+			PublicKey: publicKey, SignCount: int64(signCount), Confirmed: true, Created: time.Now(),
+		}))
+
+		token, public := challengeToken(t, s, 1)
+		options, ok := public["webauthn"].(CredentialRequestOptions)
+		require.True(t, ok)
+		require.Len(t, options.AllowCredentials, 1)
+
+		_, method, err := s.verifyChallenge(ctx, LoginForm{ChallengeToken: token, WebAuthn: authenticator.get(options.Challenge)})
+		require.NoError(t, err)
+		assert.Equal(t, "webauthn", method)
+	})
+}
+
+func TestIntegrationMFA_Policy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	s, sqlStore := setupTestService(t)
+
+	err := sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Insert(
+			&org.OrgUser{OrgID: 1, UserID: 1, Role: org.RoleAdmin, Created: time.Now(), Updated: time.Now()},
+			&org.OrgUser{OrgID: 1, UserID: 2, Role: org.RoleViewer, Created: time.Now(), Updated: time.Now()},
+			&org.OrgUser{OrgID: 2, UserID: 2, Role: org.RoleViewer, Created: time.Now(), Updated: time.Now()},
+		)
+		return err
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, s.store.insertPolicy(ctx, &mfa.Policy{OrgID: 1, Role: org.RoleAdmin, Created: time.Now()}))
+	assert.ErrorIs(t, s.store.insertPolicy(ctx, &mfa.Policy{OrgID: 1, Role: org.RoleAdmin, Created: time.Now()}), mfa.ErrPolicyExists)
+
+	required, err := s.IsRequired(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, required)
+	required, err = s.IsRequired(ctx, 2)
+	require.NoError(t, err)
+	assert.False(t, required)
+
+	// A policy for all members of another organization of the user applies too.
+	policy := &mfa.Policy{OrgID: 2, Created: time.Now()}
+	require.NoError(t, s.store.insertPolicy(ctx, policy))
+	required, err = s.IsRequired(ctx, 2)
+	require.NoError(t, err)
+	assert.True(t, required)
+
+	t.Run("users without factors must enroll TOTP on login", func(t *testing.T) {
+		token, public := challengeToken(t, s, 2)
+		assert.Equal(t, true, public["enrollmentRequired"])
+		assert.Equal(t, []string{"totp"}, public["methods"])
+
+		state, err := s.getChallenge(ctx, token)
+		require.NoError(t, err)
+		factor, secret, err := s.enrollTOTP(ctx, &user.User{ID: 2, Login: "viewer"}, "")
+		require.NoError(t, err)
+		state.EnrollFactorID = factor.ID
+		require.NoError(t, s.saveChallenge(ctx, token, state))
+
+		_, method, err := s.verifyChallenge(ctx, LoginForm{ChallengeToken: token, Code: currentTOTPCode(t, s, secret)})
+		require.NoError(t, err)
+		assert.Equal(t, "totp", method)
+
+		status, err := s.GetStatus(ctx, 2)
+		require.NoError(t, err)
+		require.Len(t, status.Factors, 1)
+		assert.True(t, status.Factors[0].Confirmed)
+	})
+
+	require.NoError(t, s.store.deletePolicy(ctx, 2, policy.ID))
+	assert.ErrorIs(t, s.store.deletePolicy(ctx, 2, policy.ID), mfa.ErrPolicyNotFound)
+}
+
+func TestIntegrationMFA_Sessions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	s, _ := setupTestService(t)
+	enrollConfirmedTOTP(t, s, 1)
+	_, err := s.generateRecoveryCodes(ctx, 1)
+	require.NoError(t, err)
+
+	r := &authn.Request{}
+	r.SetMeta(metaKeyMethod, "totp")
+	s.recordSessionHook(ctx, &authn.Identity{SessionToken: &auth.UserToken{Id: 10, UserId: 1}}, r, nil)
+	// Sessions of logins without a second factor are not recorded.
+	s.recordSessionHook(ctx, &authn.Identity{SessionToken: &auth.UserToken{Id: 11, UserId: 1}}, &authn.Request{}, nil)
+
+	verified, err := s.IsSessionVerified(ctx, 10)
+	require.NoError(t, err)
+	assert.True(t, verified)
+	verified, err = s.IsSessionVerified(ctx, 11)
+	require.NoError(t, err)
+	assert.False(t, verified)
+
+	require.NoError(t, s.ResetFactors(ctx, 1))
+	status, err := s.GetStatus(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, status.Factors)
+	assert.False(t, status.Required)
+	assert.Zero(t, status.RecoveryCodesRemaining)
+	verified, err = s.IsSessionVerified(ctx, 10)
+	require.NoError(t, err)
+	assert.False(t, verified)
+}
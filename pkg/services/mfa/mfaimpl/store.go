@@ -0,0 +1,226 @@
+package mfaimpl
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/mfa"
+)
+
+type recoveryCode struct {
+	ID       int64     `xorm:"pk autoincr 'id'"`
+	UserID   int64     `xorm:"user_id"`
+	CodeHash string    `xorm:"code_hash"`
+	Salt     string    `xorm:"salt"`
+	Created  time.Time `xorm:"'created'"`
+}
+
+func (c recoveryCode) TableName() string {
+	return "user_mfa_recovery_code"
+}
+
+type session struct {
+	ID       int64     `xorm:"pk autoincr 'id'"`
+	UserID   int64     `xorm:"user_id"`
+	TokenID  int64     `xorm:"token_id"`
+	Method   string    `xorm:"method"`
+	Verified time.Time `xorm:"verified"`
+}
+
+func (s session) TableName() string {
+	return "user_mfa_session"
+}
+
+type store struct {
+	db db.DB
+}
+
+func (s *store) listFactors(ctx context.Context, userID int64) ([]*mfa.Factor, error) {
+	factors := make([]*mfa.Factor, 0)
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("user_id = ?", userID).Asc("id").Find(&factors)
+	})
+	return factors, err
+}
+
+func (s *store) getFactor(ctx context.Context, userID, id int64) (*mfa.Factor, error) {
+	var factor mfa.Factor
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		ok, err := sess.Where("user_id = ? AND id = ?", userID, id).Get(&factor)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return mfa.ErrFactorNotFound.Errorf("factor %d not found", id)
+		}
+		return nil
+	})
+	return &factor, err
+}
+
+func (s *store) insertFactor(ctx context.Context, factor *mfa.Factor) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Insert(factor)
+		return err
+	})
+}
+
+// updateFactorUse updates a factor after it was used to verify a user. The
+// update only succeeds when the counters weren't changed concurrently, so the
+// same code or assertion can't be used twice.
+func (s *store) updateFactorUse(ctx context.Context, factor *mfa.Factor, previous mfa.Factor) (bool, error) {
+	var ok bool
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		n, err := sess.Where("id = ? AND last_step = ? AND sign_count = ?", factor.ID, previous.LastStep, previous.SignCount).
+			Cols("last_step", "sign_count", "confirmed", "last_used").Update(factor)
+		ok = n == 1
+		return err
+	})
+	return ok, err
+}
+
+func (s *store) deleteFactor(ctx context.Context, userID, id int64) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		n, err := sess.Where("user_id = ? AND id = ?", userID, id).Delete(&mfa.Factor{})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return mfa.ErrFactorNotFound.Errorf("factor %d not found", id)
+		}
+		return nil
+	})
+}
+
+func (s *store) listRecoveryCodes(ctx context.Context, userID int64) ([]*recoveryCode, error) {
+	codes := make([]*recoveryCode, 0)
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("user_id = ?", userID).Find(&codes)
+	})
+	return codes, err
+}
+
+func (s *store) countRecoveryCodes(ctx context.Context, userID int64) (int64, error) {
+	var count int64
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		count, err = sess.Where("user_id = ?", userID).Count(&recoveryCode{})
+		return err
+	})
+	return count, err
+}
+
+func (s *store) replaceRecoveryCodes(ctx context.Context, userID int64, codes []*recoveryCode) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		if _, err := sess.Where("user_id = ?", userID).Delete(&recoveryCode{}); err != nil {
+			return err
+		}
+		for _, c := range codes {
+			if _, err := sess.Insert(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// deleteRecoveryCode deletes a used recovery code and reports whether it
+// existed, so a code can't be used twice concurrently.
+func (s *store) deleteRecoveryCode(ctx context.Context, id int64) (bool, error) {
+	var ok bool
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		n, err := sess.ID(id).Delete(&recoveryCode{})
+		ok = n == 1
+		return err
+	})
+	return ok, err
+}
+
+func (s *store) deleteUserData(ctx context.Context, userID int64) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		for _, bean := range []any{&mfa.Factor{}, &recoveryCode{}, &session{}} {
+			if _, err := sess.Where("user_id = ?", userID).Delete(bean); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *store) insertSession(ctx context.Context, verified *session, maxAge time.Duration) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		// Sessions older than the max lifetime of logins are expired, there is no
+		// need to keep track of them.
+		if maxAge > 0 {
+			if _, err := sess.Where("user_id = ? AND verified < ?", verified.UserID, verified.Verified.Add(-maxAge)).Delete(&session{}); err != nil {
+				return err
+			}
+		}
+		if _, err := sess.Where("token_id = ?", verified.TokenID).Delete(&session{}); err != nil {
+			return err
+		}
+		_, err := sess.Insert(verified)
+		return err
+	})
+}
+
+func (s *store) isSessionVerified(ctx context.Context, tokenID int64) (bool, error) {
+	var ok bool
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		ok, err = sess.Where("token_id = ?", tokenID).Exist(&session{})
+		return err
+	})
+	return ok, err
+}
+
+func (s *store) listPolicies(ctx context.Context, orgID int64) ([]*mfa.Policy, error) {
+	policies := make([]*mfa.Policy, 0)
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("org_id = ?", orgID).Asc("id").Find(&policies)
+	})
+	return policies, err
+}
+
+func (s *store) insertPolicy(ctx context.Context, policy *mfa.Policy) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		exists, err := sess.Where("org_id = ? AND role = ?", policy.OrgID, policy.Role).Exist(&mfa.Policy{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			return mfa.ErrPolicyExists.Errorf("policy for role %q already exists", policy.Role)
+		}
+		_, err = sess.Insert(policy)
+		return err
+	})
+}
+
+func (s *store) deletePolicy(ctx context.Context, orgID, id int64) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		n, err := sess.Where("org_id = ? AND id = ?", orgID, id).Delete(&mfa.Policy{})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return mfa.ErrPolicyNotFound.Errorf("policy %d not found", id)
+		}
+		return nil
+	})
+}
+
+// isRequiredByPolicy reports whether a policy applies to the user in any of
+// their organizations, so switching the organization doesn't skip a policy.
+func (s *store) isRequiredByPolicy(ctx context.Context, userID int64) (bool, error) {
+	var count int64
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		count, err = sess.Table("mfa_policy").
+			Join("INNER", "org_user", "org_user.org_id = mfa_policy.org_id").
+			Where("org_user.user_id = ? AND (mfa_policy.role = '' OR mfa_policy.role = org_user.role)", userID).
+			Count()
+		return err
+	})
+	return count > 0, err
+}
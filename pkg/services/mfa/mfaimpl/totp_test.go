@@ -0,0 +1,91 @@
+package mfaimpl
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA1 secret of the RFC 6238 test vectors, "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfcSecret)
+	require.NoError(t, err)
+
+	// RFC 6238 appendix B, truncated to 6 digits.
+	tests := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, expected := range tests {
+		assert.Equal(t, expected, totpCode(key, unix/totpPeriod), "time %d", unix)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+
+	t.Run("accepts the current code", func(t *testing.T) {
+		step, ok := validateTOTP(rfcSecret, "005924", now, 0)
+		require.True(t, ok)
+		assert.Equal(t, current, step)
+	})
+
+	t.Run("accepts codes with spaces", func(t *testing.T) {
+		_, ok := validateTOTP(rfcSecret, "005 924", now, 0)
+		assert.True(t, ok)
+	})
+
+	t.Run("accepts codes of adjacent steps", func(t *testing.T) {
+		step, ok := validateTOTP(rfcSecret, "005924", now.Add(totpPeriod*time.Second), 0)
+		require.True(t, ok)
+		assert.Equal(t, current, step)
+	})
+
+	t.Run("rejects codes of older steps", func(t *testing.T) {
+		_, ok := validateTOTP(rfcSecret, "005924", now.Add(2*totpPeriod*time.Second), 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("rejects reused codes", func(t *testing.T) {
+		_, ok := validateTOTP(rfcSecret, "005924", now, current)
+		assert.False(t, ok)
+	})
+
+	t.Run("rejects invalid codes", func(t *testing.T) {
+		for _, code := range []string{"", "123456", "00592", "0059245"} {
+			_, ok := validateTOTP(rfcSecret, code, now, 0)
+			assert.False(t, ok, code)
+		}
+	})
+
+	t.Run("accepts generated secrets", func(t *testing.T) {
+		secret, err := generateTOTPSecret()
+		require.NoError(t, err)
+		key, err := totpEncoding.DecodeString(secret)
+		require.NoError(t, err)
+
+		_, ok := validateTOTP(secret, totpCode(key, current), now, 0)
+		assert.True(t, ok)
+	})
+}
+
+func TestTOTPURL(t *testing.T) {
+	u, err := url.Parse(totpURL("Grafana", "admin", rfcSecret))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/Grafana:admin", u.Path)
+	assert.Equal(t, rfcSecret, u.Query().Get("secret"))
+	assert.Equal(t, "Grafana", u.Query().Get("issuer"))
+}
@@ -0,0 +1,336 @@
+package mfaimpl
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The WebAuthn ceremonies use the JSON serialization of credentials, where all
+// binary values are base64url encoded. Registration responses must include the
+// publicKey of the credential, as returned by AuthenticatorAttestationResponse.getPublicKey(),
+// so attestation objects don't have to be decoded. Only the "none" attestation
+// conveyance is requested.
+
+const (
+	webAuthnTimeoutMilliseconds = 60000
+
+	coseAlgES256 = -7
+	coseAlgEdDSA = -8
+	coseAlgRS256 = -257
+
+	flagUserPresent            = 0x01
+	flagAttestedCredentialData = 0x40
+)
+
+type relyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type webAuthnUser struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type credentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type credentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type authenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CredentialCreationOptions are the options of navigator.credentials.create().
+type CredentialCreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     relyingParty           `json:"rp"`
+	User                   webAuthnUser           `json:"user"`
+	PubKeyCredParams       []credentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	Attestation            string                 `json:"attestation"`
+	ExcludeCredentials     []credentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection authenticatorSelection `json:"authenticatorSelection"`
+}
+
+// CredentialRequestOptions are the options of navigator.credentials.get().
+type CredentialRequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []credentialDescriptor `json:"allowCredentials"`
+	Timeout          int64                  `json:"timeout"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// CredentialCreation is a PublicKeyCredential returned by navigator.credentials.create().
+type CredentialCreation struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON     string `json:"clientDataJSON"`
+		AuthenticatorData  string `json:"authenticatorData"`
+		PublicKey          string `json:"publicKey"`
+		PublicKeyAlgorithm int    `json:"publicKeyAlgorithm"`
+	} `json:"response"`
+}
+
+// CredentialAssertion is a PublicKeyCredential returned by navigator.credentials.get().
+type CredentialAssertion struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+type collectedClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type webAuthn struct {
+	rpID   string
+	rpName string
+	origin string
+}
+
+func newWebAuthnChallenge() (string, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(challenge), nil
+}
+
+func (w *webAuthn) creationOptions(challenge string, userID int64, login, name string, exclude []string) CredentialCreationOptions {
+	options := CredentialCreationOptions{
+		Challenge: challenge,
+		RP:        relyingParty{ID: w.rpID, Name: w.rpName},
+		User: webAuthnUser{
+			ID:          base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprint(userID))),
+			Name:        login,
+			DisplayName: name,
+		},
+		PubKeyCredParams: []credentialParameter{
+			{Type: "public-key", Alg: coseAlgES256},
+			{Type: "public-key", Alg: coseAlgEdDSA},
+			{Type: "public-key", Alg: coseAlgRS256},
+		},
+		Timeout:                webAuthnTimeoutMilliseconds,
+		Attestation:            "none",
+		ExcludeCredentials:     make([]credentialDescriptor, 0, len(exclude)),
+		AuthenticatorSelection: authenticatorSelection{ResidentKey: "preferred", UserVerification: "preferred"},
+	}
+	for _, id := range exclude {
+		options.ExcludeCredentials = append(options.ExcludeCredentials, credentialDescriptor{Type: "public-key", ID: id})
+	}
+	return options
+}
+
+func (w *webAuthn) requestOptions(challenge string, allow []string) CredentialRequestOptions {
+	options := CredentialRequestOptions{
+		Challenge:        challenge,
+		RPID:             w.rpID,
+		AllowCredentials: make([]credentialDescriptor, 0, len(allow)),
+		Timeout:          webAuthnTimeoutMilliseconds,
+		UserVerification: "preferred",
+	}
+	for _, id := range allow {
+		options.AllowCredentials = append(options.AllowCredentials, credentialDescriptor{Type: "public-key", ID: id})
+	}
+	return options
+}
+
+// verifyRegistration verifies a new credential and returns its ID, public key
+// and signature counter.
+func (w *webAuthn) verifyRegistration(challenge string, cred *CredentialCreation) (string, string, uint32, error) {
+	if cred == nil || cred.Type != "public-key" {
+		return "", "", 0, errors.New("unsupported credential type")
+	}
+	if _, err := w.verifyClientData(cred.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return "", "", 0, err
+	}
+	authData, err := decodeBase64URL(cred.Response.AuthenticatorData)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("invalid authenticator data: %w", err)
+	}
+	signCount, err := w.verifyAuthenticatorData(authData)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	// Attested credential data: AAGUID (16), credential ID length (2), credential ID.
+	if authData[32]&flagAttestedCredentialData == 0 || len(authData) < 55 {
+		return "", "", 0, errors.New("missing attested credential data")
+	}
+	idLen := int(binary.BigEndian.Uint16(authData[53:55]))
+	if len(authData) < 55+idLen {
+		return "", "", 0, errors.New("invalid attested credential data")
+	}
+	credentialID := authData[55 : 55+idLen]
+	rawID, err := decodeBase64URL(cred.RawID)
+	if err != nil || !bytes.Equal(rawID, credentialID) {
+		return "", "", 0, errors.New("credential ID doesn't match authenticator data")
+	}
+
+	publicKey, err := decodeBase64URL(cred.Response.PublicKey)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("invalid public key: %w", err)
+	}
+	if _, err := parsePublicKey(publicKey); err != nil {
+		return "", "", 0, err
+	}
+	return base64.RawURLEncoding.EncodeToString(credentialID), base64.RawURLEncoding.EncodeToString(publicKey), signCount, nil
+}
+
+// verifyAssertion verifies an assertion signed by a registered credential and
+// returns the new signature counter.
+func (w *webAuthn) verifyAssertion(challenge string, publicKey string, storedSignCount int64, cred *CredentialAssertion) (uint32, error) {
+	if cred == nil || cred.Type != "public-key" {
+		return 0, errors.New("unsupported credential type")
+	}
+	clientData, err := w.verifyClientData(cred.Response.ClientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return 0, err
+	}
+	authData, err := decodeBase64URL(cred.Response.AuthenticatorData)
+	if err != nil {
+		return 0, fmt.Errorf("invalid authenticator data: %w", err)
+	}
+	signCount, err := w.verifyAuthenticatorData(authData)
+	if err != nil {
+		return 0, err
+	}
+	signature, err := decodeBase64URL(cred.Response.Signature)
+	if err != nil {
+		return 0, fmt.Errorf("invalid signature: %w", err)
+	}
+	keyData, err := decodeBase64URL(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	key, err := parsePublicKey(keyData)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(clientData)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+	if !verifySignature(key, signed, signature) {
+		return 0, errors.New("invalid signature")
+	}
+
+	// Authenticators that don't implement counters always return zero,
+	// otherwise the counter must increase or the credential may be cloned.
+	if (signCount != 0 || storedSignCount != 0) && int64(signCount) <= storedSignCount {
+		return 0, errors.New("signature counter didn't increase")
+	}
+	return signCount, nil
+}
+
+func (w *webAuthn) verifyClientData(encoded, ceremony, challenge string) ([]byte, error) {
+	raw, err := decodeBase64URL(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client data: %w", err)
+	}
+	var clientData collectedClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return nil, fmt.Errorf("invalid client data: %w", err)
+	}
+	if clientData.Type != ceremony {
+		return nil, fmt.Errorf("unexpected ceremony type %q", clientData.Type)
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimRight(clientData.Challenge, "=")), []byte(challenge)) != 1 {
+		return nil, errors.New("challenge doesn't match")
+	}
+	if clientData.Origin != w.origin {
+		return nil, fmt.Errorf("unexpected origin %q", clientData.Origin)
+	}
+	return raw, nil
+}
+
+// verifyAuthenticatorData checks the relying party and the user presence and
+// returns the signature counter.
+func (w *webAuthn) verifyAuthenticatorData(authData []byte) (uint32, error) {
+	if len(authData) < 37 {
+		return 0, errors.New("authenticator data too short")
+	}
+	rpIDHash := sha256.Sum256([]byte(w.rpID))
+	if subtle.ConstantTimeCompare(authData[:32], rpIDHash[:]) != 1 {
+		return 0, errors.New("relying party ID doesn't match")
+	}
+	if authData[32]&flagUserPresent == 0 {
+		return 0, errors.New("user not present")
+	}
+	return binary.BigEndian.Uint32(authData[33:37]), nil
+}
+
+func parsePublicKey(der []byte) (crypto.PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+func verifySignature(key crypto.PublicKey, signed, signature []byte) bool {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		var digest []byte
+		switch k.Curve.Params().BitSize {
+		case 384:
+			sum := sha512.Sum384(signed)
+			digest = sum[:]
+		case 521:
+			sum := sha512.Sum512(signed)
+			digest = sum[:]
+		default:
+			sum := sha256.Sum256(signed)
+			digest = sum[:]
+		}
+		return ecdsa.VerifyASN1(k, digest, signature)
+	case *rsa.PublicKey:
+		sum := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, signed, signature)
+	default:
+		return false
+	}
+}
+
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,167 @@
+package mfaimpl
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testWebAuthn = &webAuthn{rpID: "grafana.example.com", rpName: "Grafana", origin: "https://grafana.example.com"}
+
+// fakeAuthenticator creates credentials and assertions like a security key.
+type fakeAuthenticator struct {
+	t            *testing.T
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+}
+
+func newFakeAuthenticator(t *testing.T) *fakeAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return &fakeAuthenticator{t: t, key: key, credentialID: []byte("credential-1")}
+}
+
+func (a *fakeAuthenticator) authData(rpID string, flags byte, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+	}
+	return data
+}
+
+func (a *fakeAuthenticator) clientData(ceremony, challenge, origin string) []byte {
+	data, err := json.Marshal(collectedClientData{Type: ceremony, Challenge: challenge, Origin: origin})
+	require.NoError(a.t, err)
+	return data
+}
+
+func (a *fakeAuthenticator) create(challenge string) *CredentialCreation {
+	publicKey, err := x509.MarshalPKIXPublicKey(&a.key.PublicKey)
+	require.NoError(a.t, err)
+
+	cred := &CredentialCreation{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: base64.RawURLEncoding.EncodeToString(a.credentialID),
+		Type:  "public-key",
+	}
+	cred.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(a.clientData("webauthn.create", challenge, testWebAuthn.origin))
+	cred.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(a.authData(testWebAuthn.rpID, flagUserPresent|flagAttestedCredentialData, true))
+	cred.Response.PublicKey = base64.RawURLEncoding.EncodeToString(publicKey)
+	cred.Response.PublicKeyAlgorithm = coseAlgES256
+	return cred
+}
+
+func (a *fakeAuthenticator) get(challenge string) *CredentialAssertion {
+	a.signCount++
+	authData := a.authData(testWebAuthn.rpID, flagUserPresent, false)
+	clientData := a.clientData("webauthn.get", challenge, testWebAuthn.origin)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(a.t, err)
+
+	cred := &CredentialAssertion{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: base64.RawURLEncoding.EncodeToString(a.credentialID),
+		Type:  "public-key",
+	}
+	cred.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientData)
+	cred.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+	cred.Response.Signature = base64.RawURLEncoding.EncodeToString(signature)
+	return cred
+}
+
+func TestWebAuthn_Registration(t *testing.T) {
+	authenticator := newFakeAuthenticator(t)
+	challenge, err := newWebAuthnChallenge()
+	require.NoError(t, err)
+
+	t.Run("verifies a new credential", func(t *testing.T) {
+		credentialID, publicKey, signCount, err := testWebAuthn.verifyRegistration(challenge, authenticator.create(challenge))
+		require.NoError(t, err)
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(authenticator.credentialID), credentialID)
+		assert.NotEmpty(t, publicKey)
+		assert.Zero(t, signCount)
+	})
+
+	t.Run("rejects another challenge", func(t *testing.T) {
+		other, err := newWebAuthnChallenge()
+		require.NoError(t, err)
+		_, _, _, err = testWebAuthn.verifyRegistration(challenge, authenticator.create(other))
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects another origin", func(t *testing.T) {
+		w := &webAuthn{rpID: testWebAuthn.rpID, origin: "https://evil.example.com"}
+		_, _, _, err := w.verifyRegistration(challenge, authenticator.create(challenge))
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects another relying party", func(t *testing.T) {
+		w := &webAuthn{rpID: "evil.example.com", origin: testWebAuthn.origin}
+		_, _, _, err := w.verifyRegistration(challenge, authenticator.create(challenge))
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects mismatching credential IDs", func(t *testing.T) {
+		cred := authenticator.create(challenge)
+		cred.RawID = base64.RawURLEncoding.EncodeToString([]byte("credential-2"))
+		_, _, _, err := testWebAuthn.verifyRegistration(challenge, cred)
+		assert.Error(t, err)
+	})
+}
+
+func TestWebAuthn_Assertion(t *testing.T) {
+	authenticator := newFakeAuthenticator(t)
+	challenge, err := newWebAuthnChallenge()
+	require.NoError(t, err)
+	_, publicKey, _, err := testWebAuthn.verifyRegistration(challenge, authenticator.create(challenge))
+	require.NoError(t, err)
+
+	t.Run("verifies a signed assertion", func(t *testing.T) {
+		signCount, err := testWebAuthn.verifyAssertion(challenge, publicKey, 0, authenticator.get(challenge))
+		require.NoError(t, err)
+		assert.Equal(t, authenticator.signCount, signCount)
+	})
+
+	t.Run("rejects a counter that didn't increase", func(t *testing.T) {
+		assertion := authenticator.get(challenge)
+		_, err := testWebAuthn.verifyAssertion(challenge, publicKey, int64(authenticator.signCount), assertion)
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects a tampered signature", func(t *testing.T) {
+		assertion := authenticator.get(challenge)
+		assertion.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authenticator.authData(testWebAuthn.rpID, flagUserPresent|0x04, false))
+		_, err := testWebAuthn.verifyAssertion(challenge, publicKey, 0, assertion)
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects another key", func(t *testing.T) {
+		other := newFakeAuthenticator(t)
+		_, err := testWebAuthn.verifyAssertion(challenge, publicKey, 0, other.get(challenge))
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects a registration ceremony", func(t *testing.T) {
+		assertion := authenticator.get(challenge)
+		assertion.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(authenticator.clientData("webauthn.create", challenge, testWebAuthn.origin))
+		_, err := testWebAuthn.verifyAssertion(challenge, publicKey, 0, assertion)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,32 @@
+package mfatest
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/mfa"
+)
+
+var _ mfa.Service = new(FakeService)
+
+type FakeService struct {
+	ExpectedStatus   *mfa.Status
+	ExpectedRequired bool
+	ExpectedVerified bool
+	ExpectedErr      error
+}
+
+func (f *FakeService) GetStatus(ctx context.Context, userID int64) (*mfa.Status, error) {
+	return f.ExpectedStatus, f.ExpectedErr
+}
+
+func (f *FakeService) IsRequired(ctx context.Context, userID int64) (bool, error) {
+	return f.ExpectedRequired, f.ExpectedErr
+}
+
+func (f *FakeService) IsSessionVerified(ctx context.Context, tokenID int64) (bool, error) {
+	return f.ExpectedVerified, f.ExpectedErr
+}
+
+func (f *FakeService) ResetFactors(ctx context.Context, userID int64) error {
+	return f.ExpectedErr
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/datasourceproxy"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/folder"
 	"github.com/grafana/grafana/pkg/services/ngalert/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/ngalert/backtesting"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
@@ -60,6 +61,7 @@ type API struct {
 	RuleStore            RuleStore
 	AlertingStore        store.AlertingStore
 	AdminConfigStore     store.AdminConfigurationStore
+	SilenceScheduleStore notifier.SilenceScheduleStore
 	DataProxy            *datasourceproxy.DataSourceProxyService
 	MultiOrgAlertmanager *notifier.MultiOrgAlertmanager
 	StateManager         *state.Manager
@@ -70,10 +72,14 @@ type API struct {
 	Templates            *provisioning.TemplateService
 	MuteTimings          *provisioning.MuteTimingService
 	AlertRules           *provisioning.AlertRuleService
+	FolderService        folder.Service
 	AlertsRouter         *sender.AlertsRouter
 	EvaluatorFactory     eval.EvaluatorFactory
 	FeatureManager       featuremgmt.FeatureToggles
 	Historian            Historian
+	RuleLinter           RuleLinter
+	RuleLintStore        RuleLintStore
+	ReceiverTests        ReceiverTestRecorder
 	Tracer               tracing.Tracer
 	AppUrl               *url.URL
 
@@ -89,24 +95,32 @@ func (api *API) RegisterAPIEndpoints(m *metrics.API) {
 		ac:        api.AccessControl,
 	}
 	ruleAuthzService := accesscontrol.NewRuleService(api.AccessControl)
+	silenceSvc := notifier.NewSilenceService(
+		accesscontrol.NewSilenceService(api.AccessControl, api.RuleStore),
+		api.TransactionManager,
+		logger,
+		api.MultiOrgAlertmanager,
+		api.RuleStore,
+		ruleAuthzService,
+	)
 
 	// Register endpoints for proxying to Alertmanager-compatible backends.
 	api.RegisterAlertmanagerApiEndpoints(NewForkingAM(
 		api.DatasourceCache,
 		NewLotexAM(proxy, logger),
 		&AlertmanagerSrv{
-			crypto: api.MultiOrgAlertmanager.Crypto,
-			log:    logger,
-			ac:     api.AccessControl,
-			mam:    api.MultiOrgAlertmanager,
-			silenceSvc: notifier.NewSilenceService(
+			crypto:     api.MultiOrgAlertmanager.Crypto,
+			log:        logger,
+			ac:         api.AccessControl,
+			mam:        api.MultiOrgAlertmanager,
+			silenceSvc: silenceSvc,
+			silenceScheduleSvc: notifier.NewSilenceScheduleService(
 				accesscontrol.NewSilenceService(api.AccessControl, api.RuleStore),
-				api.TransactionManager,
-				logger,
+				api.SilenceScheduleStore,
 				api.MultiOrgAlertmanager,
-				api.RuleStore,
-				ruleAuthzService,
+				logger,
 			),
+			receiverTests: api.ReceiverTests,
 		},
 	), m)
 	// Register endpoints for proxying to Prometheus-compatible backends.
@@ -131,6 +145,8 @@ func (api *API) RegisterAPIEndpoints(m *metrics.API) {
 			amConfigStore:      api.AlertingStore,
 			amRefresher:        api.MultiOrgAlertmanager,
 			featureManager:     api.FeatureManager,
+			linter:             api.RuleLinter,
+			lintStore:          api.RuleLintStore,
 		},
 	), m)
 	api.RegisterTestingApiEndpoints(NewTestingApi(
@@ -146,6 +162,9 @@ func (api *API) RegisterAPIEndpoints(m *metrics.API) {
 			appUrl:          api.AppUrl,
 			tracer:          api.Tracer,
 			folderService:   api.RuleStore,
+			routing:         api.MultiOrgAlertmanager,
+			silences:        silenceSvc,
+			ruleStore:       api.RuleStore,
 		}), m)
 	api.RegisterConfigurationApiEndpoints(NewConfiguration(
 		&ConfigSrv{
@@ -164,6 +183,8 @@ func (api *API) RegisterAPIEndpoints(m *metrics.API) {
 		templates:           api.Templates,
 		muteTimings:         api.MuteTimings,
 		alertRules:          api.AlertRules,
+		folderSvc:           api.FolderService,
+		maxImportSize:       api.Cfg.UnifiedAlerting.ImportMaxSizeBytes,
 		// XXX: Used to flag recording rules, remove when FT is removed
 		featureManager: api.FeatureManager,
 	}), m)
@@ -33,6 +33,14 @@ type AlertmanagerSrv struct {
 	mam        *notifier.MultiOrgAlertmanager
 	crypto     notifier.Crypto
 	silenceSvc SilenceService
+
+	silenceScheduleSvc SilenceScheduleService
+	receiverTests      ReceiverTestRecorder
+}
+
+// ReceiverTestRecorder records the contact points that were tested successfully.
+type ReceiverTestRecorder interface {
+	RecordReceiverTests(ctx context.Context, orgID int64, receivers []string, at time.Time) error
 }
 
 type UnknownReceiverError struct {
@@ -262,9 +270,34 @@ func (srv AlertmanagerSrv) RoutePostTestReceivers(c *contextmodel.ReqContext, bo
 		return response.Error(http.StatusInternalServerError, "", err)
 	}
 
+	srv.recordReceiverTests(c.Req.Context(), c.SignedInUser.GetOrgID(), result)
+
 	return response.JSON(statusForTestReceivers(result.Receivers), newTestReceiversResult(result))
 }
 
+// recordReceiverTests records the contact points whose integrations were all tested successfully.
+func (srv AlertmanagerSrv) recordReceiverTests(ctx context.Context, orgID int64, result *notifier.TestReceiversResult) {
+	if srv.receiverTests == nil {
+		return
+	}
+	var tested []string
+	for _, r := range result.Receivers {
+		ok := len(r.Configs) > 0
+		for _, cfg := range r.Configs {
+			if cfg.Error != nil {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			tested = append(tested, r.Name)
+		}
+	}
+	if err := srv.receiverTests.RecordReceiverTests(ctx, orgID, tested, result.NotifedAt); err != nil {
+		srv.log.Error("Failed to record tested contact points", "error", err)
+	}
+}
+
 func (srv AlertmanagerSrv) RoutePostTestTemplates(c *contextmodel.ReqContext, body apimodels.TestTemplatesConfigBodyParams) response.Response {
 	am, errResp := srv.AlertmanagerFor(c.SignedInUser.GetOrgID())
 	if errResp != nil {
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// SilenceScheduleService is the service for managing and authenticating access to silence schedules in Grafana AM.
+type SilenceScheduleService interface {
+	GetSilenceSchedule(ctx context.Context, user identity.Requester, uid string) (*models.SilenceSchedule, error)
+	ListSilenceSchedules(ctx context.Context, user identity.Requester) ([]*models.SilenceSchedule, error)
+	CreateSilenceSchedule(ctx context.Context, user identity.Requester, schedule models.SilenceSchedule) (*models.SilenceSchedule, error)
+	UpdateSilenceSchedule(ctx context.Context, user identity.Requester, schedule models.SilenceSchedule) (*models.SilenceSchedule, error)
+	DeleteSilenceSchedule(ctx context.Context, user identity.Requester, uid string) error
+	WithAccessControlMetadata(ctx context.Context, user identity.Requester, schedules ...*models.SilenceScheduleWithMetadata) error
+}
+
+// RouteGetSilenceSchedule is the single silence schedule GET endpoint for Grafana AM.
+func (srv AlertmanagerSrv) RouteGetSilenceSchedule(c *contextmodel.ReqContext, uid string) response.Response {
+	schedule, err := srv.silenceScheduleSvc.GetSilenceSchedule(c.Req.Context(), c.SignedInUser, uid)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to get silence schedule", err)
+	}
+
+	withMetadata := &models.SilenceScheduleWithMetadata{SilenceSchedule: schedule}
+	if c.QueryBool("accesscontrol") {
+		if err := srv.silenceScheduleSvc.WithAccessControlMetadata(c.Req.Context(), c.SignedInUser, withMetadata); err != nil {
+			srv.log.Error("failed to get silence schedule access control metadata", "uid", uid, "error", err)
+		}
+	}
+	return response.JSON(http.StatusOK, SilenceScheduleToGettableSilenceSchedule(withMetadata))
+}
+
+// RouteGetSilenceSchedules is the silence schedule list GET endpoint for Grafana AM.
+func (srv AlertmanagerSrv) RouteGetSilenceSchedules(c *contextmodel.ReqContext) response.Response {
+	schedules, err := srv.silenceScheduleSvc.ListSilenceSchedules(c.Req.Context(), c.SignedInUser)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to list silence schedules", err)
+	}
+
+	withMetadata := make([]*models.SilenceScheduleWithMetadata, 0, len(schedules))
+	for _, schedule := range schedules {
+		withMetadata = append(withMetadata, &models.SilenceScheduleWithMetadata{SilenceSchedule: schedule})
+	}
+	if c.QueryBool("accesscontrol") {
+		if err := srv.silenceScheduleSvc.WithAccessControlMetadata(c.Req.Context(), c.SignedInUser, withMetadata...); err != nil {
+			srv.log.Error("failed to get silence schedule access control metadata", "error", err)
+		}
+	}
+	return response.JSON(http.StatusOK, SilenceSchedulesToGettableSilenceSchedules(withMetadata))
+}
+
+// RouteCreateSilenceSchedule is the silence schedule POST endpoint for Grafana AM.
+func (srv AlertmanagerSrv) RouteCreateSilenceSchedule(c *contextmodel.ReqContext, body apimodels.PostableSilenceSchedule) response.Response {
+	schedule, err := srv.silenceScheduleSvc.CreateSilenceSchedule(c.Req.Context(), c.SignedInUser, PostableSilenceScheduleToSilenceSchedule(body))
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to create silence schedule", err)
+	}
+	return response.JSON(http.StatusCreated, SilenceScheduleToGettableSilenceSchedule(&models.SilenceScheduleWithMetadata{SilenceSchedule: schedule}))
+}
+
+// RouteUpdateSilenceSchedule is the silence schedule PUT endpoint for Grafana AM.
+func (srv AlertmanagerSrv) RouteUpdateSilenceSchedule(c *contextmodel.ReqContext, body apimodels.PostableSilenceSchedule, uid string) response.Response {
+	update := PostableSilenceScheduleToSilenceSchedule(body)
+	update.UID = uid
+	schedule, err := srv.silenceScheduleSvc.UpdateSilenceSchedule(c.Req.Context(), c.SignedInUser, update)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to update silence schedule", err)
+	}
+	return response.JSON(http.StatusOK, SilenceScheduleToGettableSilenceSchedule(&models.SilenceScheduleWithMetadata{SilenceSchedule: schedule}))
+}
+
+// RouteDeleteSilenceSchedule is the silence schedule DELETE endpoint for Grafana AM.
+func (srv AlertmanagerSrv) RouteDeleteSilenceSchedule(c *contextmodel.ReqContext, uid string) response.Response {
+	if err := srv.silenceScheduleSvc.DeleteSilenceSchedule(c.Req.Context(), c.SignedInUser, uid); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to delete silence schedule", err)
+	}
+	return response.JSON(http.StatusOK, util.DynMap{"message": "silence schedule deleted"})
+}
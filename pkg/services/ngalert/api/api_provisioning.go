@@ -32,6 +32,8 @@ type ProvisioningSrv struct {
 	muteTimings         MuteTimingService
 	alertRules          AlertRuleService
 	folderSvc           folder.Service
+	// maxImportSize is the maximum size of an imported document in bytes. Zero means no limit.
+	maxImportSize int64
 
 	// XXX: Used to flag recording rules, remove when FT is removed
 	featureManager featuremgmt.FeatureToggles
@@ -72,6 +74,7 @@ type AlertRuleService interface {
 	DeleteAlertRule(ctx context.Context, user identity.Requester, ruleUID string, provenance alerting_models.Provenance) error
 	GetRuleGroup(ctx context.Context, user identity.Requester, folder, group string) (alerting_models.AlertRuleGroup, error)
 	ReplaceRuleGroup(ctx context.Context, user identity.Requester, group alerting_models.AlertRuleGroup, provenance alerting_models.Provenance) error
+	ImportRuleGroup(ctx context.Context, user identity.Requester, group alerting_models.AlertRuleGroup, provenance alerting_models.Provenance, opts provisioning.ImportRuleGroupOptions) (*store.GroupDelta, error)
 	DeleteRuleGroup(ctx context.Context, user identity.Requester, folder, group string, provenance alerting_models.Provenance) error
 	GetAlertRuleWithFolderFullpath(ctx context.Context, u identity.Requester, ruleUID string) (provisioning.AlertRuleWithFolderFullpath, error)
 	GetAlertRuleGroupWithFolderFullpath(ctx context.Context, u identity.Requester, folder, group string) (alerting_models.AlertRuleGroupWithFolderFullpath, error)
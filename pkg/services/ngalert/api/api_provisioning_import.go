@@ -0,0 +1,709 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/folder"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/hcl"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	alerting_models "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/provisioning"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+const (
+	importKindAlertRule          = "alertRule"
+	importKindContactPoint       = "contactPoint"
+	importKindNotificationPolicy = "notificationPolicy"
+	importKindMuteTiming         = "muteTiming"
+
+	importActionCreate = "create"
+	importActionUpdate = "update"
+	importActionDelete = "delete"
+)
+
+var errInvalidImport = errors.New("invalid import")
+
+// RoutePostImport imports the resources of a document in provisioning file format. The resources are applied in
+// the order they depend on each other: mute timings, contact points, notification policies and rule groups.
+// All resources are validated before any of them is applied. Each resource is then applied separately,
+// so an error while applying, such as a conflicting change, leaves the resources that were applied before it changed.
+func (srv *ProvisioningSrv) RoutePostImport(c *contextmodel.ReqContext) response.Response {
+	body, errResp := srv.readImportBody(c)
+	if errResp != nil {
+		return errResp
+	}
+	doc, err := decodeImportDocument(extractImportFormat(c), body, c.SignedInUser.GetOrgID())
+	if err != nil {
+		return ErrResp(http.StatusBadRequest, err, "failed to decode document")
+	}
+
+	imp := &importer{
+		srv:        srv,
+		user:       c.SignedInUser,
+		orgID:      c.SignedInUser.GetOrgID(),
+		provenance: alerting_models.Provenance(determineProvenance(c)),
+		dryRun:     c.QueryBoolWithDefault("dryRun", false),
+		folders:    make(map[string]string),
+	}
+	result, err := imp.importDocument(c.Req.Context(), doc)
+	if err != nil {
+		return importErrorResponse(err)
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
+// readImportBody reads the imported document from the request body, up to the configured maximum size.
+func (srv *ProvisioningSrv) readImportBody(c *contextmodel.ReqContext) ([]byte, response.Response) {
+	reader := c.Req.Body
+	if srv.maxImportSize > 0 {
+		reader = http.MaxBytesReader(c.Resp, c.Req.Body, srv.maxImportSize)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, ErrResp(http.StatusRequestEntityTooLarge, err, "document is larger than %d bytes", maxBytesErr.Limit)
+		}
+		return nil, ErrResp(http.StatusBadRequest, err, "failed to read request body")
+	}
+	return body, nil
+}
+
+func importErrorResponse(err error) response.Response {
+	if errors.Is(err, errInvalidImport) ||
+		errors.Is(err, provisioning.ErrValidation) ||
+		errors.Is(err, alerting_models.ErrAlertRuleFailedValidation) ||
+		errors.Is(err, alerting_models.ErrAlertRuleUniqueConstraintViolation) {
+		return ErrResp(http.StatusBadRequest, err, "")
+	}
+	if errors.Is(err, store.ErrOptimisticLock) {
+		return ErrResp(http.StatusConflict, err, "")
+	}
+	return response.ErrOrFallback(http.StatusInternalServerError, "failed to import", err)
+}
+
+// extractImportFormat returns the format of the imported document from the format query parameter or the Content-Type header.
+func extractImportFormat(c *contextmodel.ReqContext) string {
+	format := "yaml"
+	contentType := c.Req.Header.Get("Content-Type")
+	switch {
+	case strings.Contains(contentType, "hcl"):
+		format = "hcl"
+	case strings.Contains(contentType, "json"):
+		format = "json"
+	}
+	queryFormat := c.Query("format")
+	if queryFormat == "yaml" || queryFormat == "json" || queryFormat == "hcl" {
+		format = queryFormat
+	}
+	return format
+}
+
+// decodeImportDocument decodes a document in provisioning file format. Resources always belong to the given organization.
+func decodeImportDocument(format string, body []byte, orgID int64) (definitions.AlertingFileExport, error) {
+	var doc definitions.AlertingFileExport
+	switch format {
+	case "json":
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return doc, err
+		}
+	case "yaml":
+		if err := yaml.Unmarshal(body, &doc); err != nil {
+			return doc, err
+		}
+	case "hcl":
+		d, err := decodeHclDocument(body, orgID)
+		if err != nil {
+			return doc, err
+		}
+		doc = d
+	default:
+		return doc, fmt.Errorf("unsupported format '%s'", format)
+	}
+	if doc.APIVersion > 1 {
+		return doc, fmt.Errorf("unsupported apiVersion %d", doc.APIVersion)
+	}
+	for i := range doc.Groups {
+		doc.Groups[i].OrgID = orgID
+	}
+	for i := range doc.ContactPoints {
+		doc.ContactPoints[i].OrgID = orgID
+	}
+	for i := range doc.Policies {
+		doc.Policies[i].OrgID = orgID
+	}
+	for i := range doc.MuteTimings {
+		doc.MuteTimings[i].OrgID = orgID
+	}
+	return doc, nil
+}
+
+// decodeHclDocument decodes the resources that exportHcl writes.
+func decodeHclDocument(body []byte, orgID int64) (definitions.AlertingFileExport, error) {
+	doc := definitions.AlertingFileExport{APIVersion: 1}
+	resources, err := hcl.Decode(body, "import.tf", func(resourceType string) (any, error) {
+		switch resourceType {
+		case "grafana_rule_group":
+			return &definitions.AlertRuleGroupExport{}, nil
+		case "grafana_contact_point":
+			return &definitions.ContactPoint{}, nil
+		case "grafana_notification_policy":
+			return &definitions.RouteExport{}, nil
+		case "grafana_mute_timing":
+			return &definitions.MuteTimeIntervalExportHcl{}, nil
+		}
+		return nil, fmt.Errorf("unsupported resource type '%s'", resourceType)
+	})
+	if err != nil {
+		return doc, err
+	}
+	for _, r := range resources {
+		switch body := r.Body.(type) {
+		case *definitions.AlertRuleGroupExport:
+			doc.Groups = append(doc.Groups, *body)
+		case *definitions.ContactPoint:
+			cp, err := ContactPointExportFromContactPoint(orgID, *body)
+			if err != nil {
+				return doc, fmt.Errorf("invalid contact point '%s': %w", body.Name, err)
+			}
+			doc.ContactPoints = append(doc.ContactPoints, cp)
+		case *definitions.RouteExport:
+			doc.Policies = append(doc.Policies, definitions.NotificationPolicyExport{OrgID: orgID, RouteExport: body})
+		case *definitions.MuteTimeIntervalExportHcl:
+			mt, err := MuteTimeIntervalExportFromMuteTimeIntervalExportHcl(orgID, *body)
+			if err != nil {
+				return doc, fmt.Errorf("invalid mute timing '%s': %w", body.Name, err)
+			}
+			doc.MuteTimings = append(doc.MuteTimings, mt)
+		}
+	}
+	return doc, nil
+}
+
+// importer applies the resources of a document, or only validates them and calculates the changes if dryRun is true.
+type importer struct {
+	srv        *ProvisioningSrv
+	user       identity.Requester
+	orgID      int64
+	provenance alerting_models.Provenance
+	dryRun     bool
+	// folders caches the UIDs of folders by their full path.
+	folders map[string]string
+	// receivers and timeIntervals are the names of the contact points and mute timings of the document,
+	// which the notification settings of the rules can refer to.
+	receivers     []string
+	timeIntervals []string
+	changes       []definitions.ImportChange
+}
+
+func (imp *importer) importDocument(ctx context.Context, doc definitions.AlertingFileExport) (definitions.ImportResult, error) {
+	if len(doc.Policies) > 1 {
+		return definitions.ImportResult{}, fmt.Errorf("%w: document contains %d notification policy trees but there can be only one", errInvalidImport, len(doc.Policies))
+	}
+	for _, cp := range doc.ContactPoints {
+		imp.receivers = append(imp.receivers, cp.Name)
+	}
+	for _, mt := range doc.MuteTimings {
+		imp.timeIntervals = append(imp.timeIntervals, mt.Name)
+	}
+	if !imp.dryRun {
+		// Validate the whole document first, so that an invalid resource does not leave it partially applied.
+		validation := *imp
+		validation.dryRun = true
+		validation.changes = nil
+		if err := validation.apply(ctx, doc); err != nil {
+			return definitions.ImportResult{}, err
+		}
+	}
+	if err := imp.apply(ctx, doc); err != nil {
+		return definitions.ImportResult{}, err
+	}
+	return imp.result(), nil
+}
+
+// apply applies the resources of the document in the order they depend on each other, and records the changes.
+func (imp *importer) apply(ctx context.Context, doc definitions.AlertingFileExport) error {
+	if err := imp.importMuteTimings(ctx, doc.MuteTimings); err != nil {
+		return err
+	}
+	if err := imp.importContactPoints(ctx, doc.ContactPoints); err != nil {
+		return err
+	}
+	if len(doc.Policies) == 1 {
+		if err := imp.importPolicies(ctx, doc); err != nil {
+			return err
+		}
+	}
+	for _, group := range doc.Groups {
+		if err := imp.importRuleGroup(ctx, group); err != nil {
+			return fmt.Errorf("rule group '%s': %w", group.Name, err)
+		}
+	}
+	return nil
+}
+
+func (imp *importer) result() definitions.ImportResult {
+	changes := imp.changes
+	if changes == nil {
+		changes = []definitions.ImportChange{}
+	}
+	return definitions.ImportResult{DryRun: imp.dryRun, Changes: changes}
+}
+
+func (imp *importer) importMuteTimings(ctx context.Context, muteTimings []definitions.MuteTimeIntervalExport) error {
+	for _, export := range muteTimings {
+		mt := definitions.MuteTimeInterval{
+			MuteTimeInterval: export.MuteTimeInterval,
+			Provenance:       definitions.Provenance(imp.provenance),
+		}
+		if err := mt.Validate(); err != nil {
+			return fmt.Errorf("%w: mute timing '%s': %s", errInvalidImport, mt.Name, err)
+		}
+		existing, err := imp.srv.muteTimings.GetMuteTiming(ctx, mt.Name, imp.orgID)
+		if errors.Is(err, provisioning.ErrTimeIntervalNotFound) {
+			imp.changes = append(imp.changes, definitions.ImportChange{Kind: importKindMuteTiming, Action: importActionCreate, Name: mt.Name})
+			if imp.dryRun {
+				continue
+			}
+			if _, err := imp.srv.muteTimings.CreateMuteTiming(ctx, mt, imp.orgID); err != nil {
+				return fmt.Errorf("mute timing '%s': %w", mt.Name, err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("mute timing '%s': %w", mt.Name, err)
+		}
+		diff, err := diffPaths(existing.MuteTimeInterval, mt.MuteTimeInterval)
+		if err != nil {
+			return err
+		}
+		if len(diff) == 0 {
+			continue
+		}
+		imp.changes = append(imp.changes, definitions.ImportChange{Kind: importKindMuteTiming, Action: importActionUpdate, Name: mt.Name, Diff: diff})
+		if imp.dryRun {
+			continue
+		}
+		mt.Version = existing.Version
+		if _, err := imp.srv.muteTimings.UpdateMuteTiming(ctx, mt, imp.orgID); err != nil {
+			return fmt.Errorf("mute timing '%s': %w", mt.Name, err)
+		}
+	}
+	return nil
+}
+
+// importContactPoints replaces the integrations of the imported contact points. Integrations are matched by UID,
+// or by type and position if the document does not have UIDs, which is the case for HCL documents. Existing
+// integrations that are not matched are deleted.
+func (imp *importer) importContactPoints(ctx context.Context, contactPoints []definitions.ContactPointExport) error {
+	if len(contactPoints) == 0 {
+		return nil
+	}
+	// Contact points are exported by the name of integrations, which is the same as the name of the receiver.
+	all, err := imp.srv.contactPointService.GetContactPoints(ctx, provisioning.ContactPointQuery{OrgID: imp.orgID}, imp.user)
+	if err != nil {
+		return fmt.Errorf("contact points: %w", err)
+	}
+	for _, cp := range contactPoints {
+		if cp.Name == "" {
+			return fmt.Errorf("%w: contact point name is empty", errInvalidImport)
+		}
+		imported, err := EmbeddedContactPointsFromContactPointExport(cp)
+		if err != nil {
+			return fmt.Errorf("%w: contact point '%s': %s", errInvalidImport, cp.Name, err)
+		}
+		var existing []definitions.EmbeddedContactPoint
+		for _, integration := range all {
+			if integration.Name == cp.Name {
+				existing = append(existing, integration)
+			}
+		}
+
+		matched := make([]*definitions.EmbeddedContactPoint, len(imported))
+		used := make([]bool, len(existing))
+		for i, integration := range imported {
+			if integration.UID == "" {
+				continue
+			}
+			for j := range existing {
+				if !used[j] && existing[j].UID == integration.UID {
+					matched[i], used[j] = &existing[j], true
+					break
+				}
+			}
+		}
+		for i, integration := range imported {
+			if matched[i] != nil || integration.UID != "" {
+				continue
+			}
+			for j := range existing {
+				if !used[j] && existing[j].Type == integration.Type {
+					matched[i], used[j] = &existing[j], true
+					break
+				}
+			}
+		}
+
+		for i, integration := range imported {
+			integration.Provenance = string(imp.provenance)
+			if err := provisioning.ValidateContactPoint(ctx, integration, passthroughDecrypt); err != nil {
+				return fmt.Errorf("%w: contact point '%s': %s", errInvalidImport, cp.Name, err)
+			}
+			if matched[i] == nil {
+				if hasRedactedSettings(integration) {
+					return fmt.Errorf("%w: contact point '%s': %s integration is new but its secure settings are redacted", errInvalidImport, cp.Name, integration.Type)
+				}
+				imp.changes = append(imp.changes, definitions.ImportChange{Kind: importKindContactPoint, Action: importActionCreate, Name: cp.Name, UID: integration.UID})
+				if imp.dryRun {
+					continue
+				}
+				if _, err := imp.srv.contactPointService.CreateContactPoint(ctx, imp.orgID, integration, imp.provenance); err != nil {
+					return fmt.Errorf("contact point '%s': %w", cp.Name, err)
+				}
+				continue
+			}
+			integration.UID = matched[i].UID
+			diff, err := diffContactPoint(*matched[i], integration)
+			if err != nil {
+				return err
+			}
+			if len(diff) == 0 {
+				continue
+			}
+			imp.changes = append(imp.changes, definitions.ImportChange{Kind: importKindContactPoint, Action: importActionUpdate, Name: cp.Name, UID: integration.UID, Diff: diff})
+			if imp.dryRun {
+				continue
+			}
+			if err := imp.srv.contactPointService.UpdateContactPoint(ctx, imp.orgID, integration, imp.provenance); err != nil {
+				return fmt.Errorf("contact point '%s': %w", cp.Name, err)
+			}
+		}
+
+		for j, e := range existing {
+			if used[j] {
+				continue
+			}
+			imp.changes = append(imp.changes, definitions.ImportChange{Kind: importKindContactPoint, Action: importActionDelete, Name: cp.Name, UID: e.UID})
+			if imp.dryRun {
+				continue
+			}
+			if err := imp.srv.contactPointService.DeleteContactPoint(ctx, imp.orgID, e.UID); err != nil {
+				return fmt.Errorf("contact point '%s': %w", cp.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// passthroughDecrypt returns secure settings as they are. Imported settings are not encrypted.
+func passthroughDecrypt(_ context.Context, _ map[string][]byte, _ string, fallback string) string {
+	return fallback
+}
+
+func hasRedactedSettings(cp definitions.EmbeddedContactPoint) bool {
+	if cp.Settings == nil {
+		return false
+	}
+	for _, v := range cp.Settings.MustMap() {
+		if s, ok := v.(string); ok && s == definitions.RedactedValue {
+			return true
+		}
+	}
+	return false
+}
+
+// diffContactPoint returns the paths of the changed fields of the integration. Existing secure settings are redacted,
+// so redacted imported settings are considered unchanged and other imported secure settings are always changed.
+func diffContactPoint(existing, imported definitions.EmbeddedContactPoint) ([]string, error) {
+	existingSettings := existing.Settings.MustMap()
+	settings := imported.Settings.MustMap()
+	normalized := make(map[string]any, len(settings))
+	for k, v := range settings {
+		if s, ok := v.(string); ok && s == definitions.RedactedValue {
+			v = existingSettings[k]
+		}
+		normalized[k] = v
+	}
+	type integration struct {
+		Type                  string         `json:"type"`
+		DisableResolveMessage bool           `json:"disableResolveMessage"`
+		Settings              map[string]any `json:"settings"`
+	}
+	return diffPaths(
+		integration{Type: existing.Type, DisableResolveMessage: existing.DisableResolveMessage, Settings: existingSettings},
+		integration{Type: imported.Type, DisableResolveMessage: imported.DisableResolveMessage, Settings: normalized},
+	)
+}
+
+// importPolicies replaces the notification policy tree. The receivers and mute timings that the tree refers to
+// must exist or be created by the same document.
+func (imp *importer) importPolicies(ctx context.Context, doc definitions.AlertingFileExport) error {
+	policy := doc.Policies[0]
+	if policy.RouteExport == nil {
+		return fmt.Errorf("%w: notification policy tree is empty", errInvalidImport)
+	}
+	tree, err := RouteFromRouteExport(policy.RouteExport)
+	if err != nil {
+		return fmt.Errorf("%w: notification policy tree: %s", errInvalidImport, err)
+	}
+	if err := tree.Validate(); err != nil {
+		return fmt.Errorf("%w: notification policy tree: %s", errInvalidImport, err)
+	}
+
+	receivers := map[string]struct{}{"": {}} // Allow empty receiver (inheriting from parent)
+	cps, err := imp.srv.contactPointService.GetContactPoints(ctx, provisioning.ContactPointQuery{OrgID: imp.orgID}, imp.user)
+	if err != nil {
+		return err
+	}
+	for _, cp := range cps {
+		receivers[cp.Name] = struct{}{}
+	}
+	for _, cp := range doc.ContactPoints {
+		receivers[cp.Name] = struct{}{}
+	}
+	if err := tree.ValidateReceivers(receivers); err != nil {
+		return fmt.Errorf("%w: notification policy tree: %s", errInvalidImport, err)
+	}
+	muteTimes := map[string]struct{}{}
+	mts, err := imp.srv.muteTimings.GetMuteTimings(ctx, imp.orgID)
+	if err != nil {
+		return err
+	}
+	for _, mt := range mts {
+		muteTimes[mt.Name] = struct{}{}
+	}
+	for _, mt := range doc.MuteTimings {
+		muteTimes[mt.Name] = struct{}{}
+	}
+	if err := tree.ValidateMuteTimes(muteTimes); err != nil {
+		return fmt.Errorf("%w: notification policy tree: %s", errInvalidImport, err)
+	}
+
+	existing, err := imp.srv.policies.GetPolicyTree(ctx, imp.orgID)
+	if err != nil && !errors.Is(err, store.ErrNoAlertmanagerConfiguration) {
+		return err
+	}
+	diff, err := diffPaths(RouteExportFromRoute(&existing), RouteExportFromRoute(tree))
+	if err != nil {
+		return err
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+	imp.changes = append(imp.changes, definitions.ImportChange{Kind: importKindNotificationPolicy, Action: importActionUpdate, Name: tree.Receiver, Diff: diff})
+	if imp.dryRun {
+		return nil
+	}
+	if err := imp.srv.policies.UpdatePolicyTree(ctx, imp.orgID, *tree, imp.provenance); err != nil {
+		return fmt.Errorf("notification policy tree: %w", err)
+	}
+	return nil
+}
+
+func (imp *importer) importRuleGroup(ctx context.Context, export definitions.AlertRuleGroupExport) error {
+	folderUID, err := imp.resolveFolder(ctx, export)
+	if err != nil {
+		return err
+	}
+	group, err := AlertRuleGroupFromAlertRuleGroupExport(imp.orgID, folderUID, export)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errInvalidImport, err)
+	}
+	return imp.applyRuleGroup(ctx, group)
+}
+
+// applyRuleGroup replaces the rule group in the folder of the group and records the changes.
+func (imp *importer) applyRuleGroup(ctx context.Context, group alerting_models.AlertRuleGroup) error {
+	if !imp.srv.featureManager.IsEnabledGlobally(featuremgmt.FlagGrafanaManagedRecordingRules) {
+		for _, rule := range group.Rules {
+			if rule.Type() == alerting_models.RuleTypeRecording {
+				return fmt.Errorf("%w: recording rules cannot be created on this instance", alerting_models.ErrAlertRuleFailedValidation)
+			}
+		}
+	}
+	delta, err := imp.srv.alertRules.ImportRuleGroup(ctx, imp.user, group, imp.provenance, provisioning.ImportRuleGroupOptions{
+		DryRun:               imp.dryRun,
+		PendingReceivers:     imp.receivers,
+		PendingTimeIntervals: imp.timeIntervals,
+	})
+	if err != nil {
+		return err
+	}
+	newChange := func(action string, rule *alerting_models.AlertRule) definitions.ImportChange {
+		return definitions.ImportChange{
+			Kind:      importKindAlertRule,
+			Action:    action,
+			Name:      rule.Title,
+			UID:       rule.UID,
+			FolderUID: rule.NamespaceUID,
+			RuleGroup: rule.RuleGroup,
+		}
+	}
+	for _, rule := range delta.New {
+		imp.changes = append(imp.changes, newChange(importActionCreate, rule))
+	}
+	for _, upd := range delta.Update {
+		if len(upd.Diff) == 0 {
+			continue
+		}
+		change := newChange(importActionUpdate, upd.New)
+		change.UID = upd.Existing.UID
+		change.Diff = upd.Diff.Paths()
+		imp.changes = append(imp.changes, change)
+	}
+	for _, rule := range delta.Delete {
+		imp.changes = append(imp.changes, newChange(importActionDelete, rule))
+	}
+	return nil
+}
+
+// resolveFolder returns the UID of the folder of the rule group. HCL documents refer to the folder by UID,
+// other documents by its full path.
+func (imp *importer) resolveFolder(ctx context.Context, group definitions.AlertRuleGroupExport) (string, error) {
+	if group.FolderUID != "" {
+		return imp.folderByUID(ctx, group.FolderUID)
+	}
+	if group.Folder == "" {
+		return "", fmt.Errorf("%w: folder is not set", errInvalidImport)
+	}
+	if uid, ok := imp.folders[group.Folder]; ok {
+		return uid, nil
+	}
+	var parentUID *string
+	for _, title := range splitFolderPath(group.Folder) {
+		f, err := imp.srv.folderSvc.Get(ctx, &folder.GetFolderQuery{Title: &title, ParentUID: parentUID, OrgID: imp.orgID, SignedInUser: imp.user})
+		if err != nil {
+			if isFolderNotFound(err) {
+				return "", fmt.Errorf("%w: folder '%s' does not exist", errInvalidImport, group.Folder)
+			}
+			return "", err
+		}
+		parentUID = &f.UID
+	}
+	imp.folders[group.Folder] = *parentUID
+	return *parentUID, nil
+}
+
+// folderByUID returns the UID of the folder if it exists.
+func (imp *importer) folderByUID(ctx context.Context, uid string) (string, error) {
+	f, err := imp.srv.folderSvc.Get(ctx, &folder.GetFolderQuery{UID: &uid, OrgID: imp.orgID, SignedInUser: imp.user})
+	if err != nil {
+		if isFolderNotFound(err) {
+			return "", fmt.Errorf("%w: folder with UID '%s' does not exist", errInvalidImport, uid)
+		}
+		return "", err
+	}
+	return f.UID, nil
+}
+
+func isFolderNotFound(err error) bool {
+	return errors.Is(err, dashboards.ErrFolderNotFound) || errors.Is(err, folder.ErrFolderNotFound)
+}
+
+// splitFolderPath splits the full path of a folder into the titles of the folders. Slashes in titles are escaped with a backslash.
+func splitFolderPath(path string) []string {
+	var (
+		titles  []string
+		current strings.Builder
+	)
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '/':
+			current.WriteByte('/')
+			i++
+		case path[i] == '/':
+			titles = append(titles, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(path[i])
+		}
+	}
+	return append(titles, current.String())
+}
+
+// diffPaths returns the paths of the fields that differ between the JSON representations of the existing and imported resources.
+func diffPaths(existing, imported any) ([]string, error) {
+	toGeneric := func(v any) (any, error) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var result any
+		err = json.Unmarshal(b, &result)
+		return result, err
+	}
+	l, err := toGeneric(existing)
+	if err != nil {
+		return nil, err
+	}
+	r, err := toGeneric(imported)
+	if err != nil {
+		return nil, err
+	}
+	return diffValues("", l, r, nil), nil
+}
+
+func diffValues(path string, l, r any, paths []string) []string {
+	switch lv := l.(type) {
+	case map[string]any:
+		rv, ok := r.(map[string]any)
+		if !ok {
+			return append(paths, path)
+		}
+		keys := make([]string, 0, len(lv)+len(rv))
+		for k := range lv {
+			keys = append(keys, k)
+		}
+		for k := range rv {
+			if _, ok := lv[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			paths = diffValues(p, lv[k], rv[k], paths)
+		}
+		return paths
+	case []any:
+		rv, ok := r.([]any)
+		if !ok {
+			return append(paths, path)
+		}
+		for i := 0; i < max(len(lv), len(rv)); i++ {
+			var le, re any
+			if i < len(lv) {
+				le = lv[i]
+			}
+			if i < len(rv) {
+				re = rv[i]
+			}
+			paths = diffValues(fmt.Sprintf("%s[%d]", path, i), le, re, paths)
+		}
+		return paths
+	default:
+		if !reflect.DeepEqual(l, r) {
+			return append(paths, path)
+		}
+		return paths
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	alerting_models "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/prom"
+)
+
+// RoutePostImportPrometheusRules converts the rule groups of a Prometheus or Mimir rule file to Grafana-managed
+// rule groups in a folder and imports them. Rules are matched to the existing rules of the group by title, which is
+// the name of the alert or of the recorded metric, so importing the same file again does not change anything.
+// All groups are validated before any of them is applied.
+func (srv *ProvisioningSrv) RoutePostImportPrometheusRules(c *contextmodel.ReqContext) response.Response {
+	folderUID := c.Query("folderUid")
+	if folderUID == "" {
+		return ErrResp(http.StatusBadRequest, fmt.Errorf("%w: folderUid is required", errInvalidImport), "")
+	}
+	body, errResp := srv.readImportBody(c)
+	if errResp != nil {
+		return errResp
+	}
+	groups, err := prom.ParseRuleGroups(body)
+	if err != nil {
+		return ErrResp(http.StatusBadRequest, err, "failed to parse Prometheus rules")
+	}
+	converter, err := prom.NewConverter(prom.Config{
+		DatasourceUID: c.Query("datasourceUid"),
+		RecordTarget:  alerting_models.RecordTarget(c.Query("recordTarget")),
+	})
+	if err != nil {
+		return ErrResp(http.StatusBadRequest, err, "")
+	}
+
+	imp := &importer{
+		srv:        srv,
+		user:       c.SignedInUser,
+		orgID:      c.SignedInUser.GetOrgID(),
+		provenance: alerting_models.Provenance(determineProvenance(c)),
+		dryRun:     c.QueryBoolWithDefault("dryRun", false),
+	}
+	ctx := c.Req.Context()
+	folderUID, err = imp.folderByUID(ctx, folderUID)
+	if err != nil {
+		return importErrorResponse(err)
+	}
+	converted, err := converter.Convert(imp.orgID, folderUID, groups)
+	if err != nil {
+		return ErrResp(http.StatusBadRequest, err, "")
+	}
+	if !imp.dryRun {
+		// Validate all groups first, so that an invalid group does not leave the file partially applied.
+		validation := *imp
+		validation.dryRun = true
+		if err := validation.applyRuleGroups(ctx, converted); err != nil {
+			return importErrorResponse(err)
+		}
+	}
+	if err := imp.applyRuleGroups(ctx, converted); err != nil {
+		return importErrorResponse(err)
+	}
+	return response.JSON(http.StatusOK, imp.result())
+}
+
+func (imp *importer) applyRuleGroups(ctx context.Context, groups []alerting_models.AlertRuleGroup) error {
+	for _, group := range groups {
+		if err := imp.applyRuleGroup(ctx, group); err != nil {
+			return fmt.Errorf("rule group '%s': %w", group.Title, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+const testPrometheusRules = `
+groups:
+  - name: prometheus-group
+    rules:
+      - alert: HighErrorRate
+        expr: sum(rate(errors_total[5m])) by (job) > 10
+        for: 5m
+        labels:
+          severity: critical
+      - record: job:errors:rate5m
+        expr: sum(rate(errors_total[5m])) by (job)
+`
+
+func TestProvisioningApiImportPrometheusRules(t *testing.T) {
+	createProvisioningSrvSut := func(t *testing.T, features featuremgmt.FeatureToggles) ProvisioningSrv {
+		env := createTestEnv(t, testConfig)
+		env.features = features
+		env.ac = &recordingAccessControlFake{
+			Callback: func(user *user.SignedInUser, evaluator accesscontrol.Evaluator) (bool, error) {
+				return true, nil
+			},
+		}
+		return createProvisioningSrvSutFromEnv(t, &env)
+	}
+	importRules := func(t *testing.T, sut ProvisioningSrv, folderUID, body string, dryRun bool) (int, definitions.ImportResult, string) {
+		t.Helper()
+		rc := createTestRequestCtx()
+		rc.Context.Req.Form.Set("folderUid", folderUID)
+		rc.Context.Req.Form.Set("datasourceUid", "prometheus")
+		if dryRun {
+			rc.Context.Req.Form.Set("dryRun", "true")
+		}
+		rc.Context.Req.Body = io.NopCloser(strings.NewReader(body))
+		response := sut.RoutePostImportPrometheusRules(&rc)
+		var result definitions.ImportResult
+		if response.Status() == 200 {
+			require.NoError(t, json.Unmarshal(response.Body(), &result))
+		}
+		return response.Status(), result, string(response.Body())
+	}
+
+	t.Run("rules are imported once", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t, featuremgmt.WithFeatures(featuremgmt.FlagGrafanaManagedRecordingRules))
+
+		status, result, body := importRules(t, sut, "folder-uid", testPrometheusRules, true)
+		require.Equal(t, 200, status, body)
+		require.True(t, result.DryRun)
+		require.Len(t, result.Changes, 2)
+
+		rc := createTestRequestCtx()
+		require.Equal(t, 404, sut.RouteGetAlertRuleGroup(&rc, "folder-uid", "prometheus-group").Status())
+
+		status, result, body = importRules(t, sut, "folder-uid", testPrometheusRules, false)
+		require.Equal(t, 200, status, body)
+		require.False(t, result.DryRun)
+		require.Len(t, result.Changes, 2)
+		for i, title := range []string{"HighErrorRate", "job:errors:rate5m"} {
+			require.Equal(t, importKindAlertRule, result.Changes[i].Kind)
+			require.Equal(t, importActionCreate, result.Changes[i].Action)
+			require.Equal(t, title, result.Changes[i].Name)
+			require.Equal(t, "folder-uid", result.Changes[i].FolderUID)
+			require.Equal(t, "prometheus-group", result.Changes[i].RuleGroup)
+		}
+
+		response := sut.RouteGetAlertRuleGroup(&rc, "folder-uid", "prometheus-group")
+		require.Equal(t, 200, response.Status())
+		group := definitions.AlertRuleGroup{}
+		require.NoError(t, json.Unmarshal(response.Body(), &group))
+		require.Len(t, group.Rules, 2)
+		require.Equal(t, "B", group.Rules[0].Condition)
+		require.Equal(t, "critical", group.Rules[0].Labels["severity"])
+		require.NotNil(t, group.Rules[1].Record)
+		require.Equal(t, "job:errors:rate5m", group.Rules[1].Record.Metric)
+
+		status, result, body = importRules(t, sut, "folder-uid", testPrometheusRules, false)
+		require.Equal(t, 200, status, body)
+		require.Empty(t, result.Changes)
+	})
+
+	t.Run("changed rules are updated by title", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t, featuremgmt.WithFeatures(featuremgmt.FlagGrafanaManagedRecordingRules))
+		status, _, body := importRules(t, sut, "folder-uid", testPrometheusRules, false)
+		require.Equal(t, 200, status, body)
+
+		changed := strings.Replace(testPrometheusRules, "> 10", "> 20", 1)
+		status, result, body := importRules(t, sut, "folder-uid", changed, false)
+		require.Equal(t, 200, status, body)
+		require.Len(t, result.Changes, 1)
+		require.Equal(t, importActionUpdate, result.Changes[0].Action)
+		require.Equal(t, "HighErrorRate", result.Changes[0].Name)
+		require.NotEmpty(t, result.Changes[0].UID)
+	})
+
+	t.Run("reordered groups are imported without changes", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t, featuremgmt.WithFeatures(featuremgmt.FlagGrafanaManagedRecordingRules))
+		first := `
+  - name: first
+    rules:
+      - alert: InstanceDown
+        expr: up == 0
+`
+		second := `
+  - name: second
+    rules:
+      - alert: InstanceDown
+        expr: absent(up)
+`
+		status, result, body := importRules(t, sut, "folder-uid", "groups:"+first+second, false)
+		require.Equal(t, 200, status, body)
+		require.Len(t, result.Changes, 2)
+		require.Equal(t, "InstanceDown (first)", result.Changes[0].Name)
+		require.Equal(t, "InstanceDown (second)", result.Changes[1].Name)
+
+		status, result, body = importRules(t, sut, "folder-uid", "groups:"+second+first, false)
+		require.Equal(t, 200, status, body)
+		require.Empty(t, result.Changes)
+	})
+
+	t.Run("recording rules return 400 if they are disabled", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t, featuremgmt.WithFeatures())
+		status, _, body := importRules(t, sut, "folder-uid", testPrometheusRules, true)
+		require.Equal(t, 400, status)
+		require.Contains(t, body, "recording rules cannot be created")
+	})
+
+	t.Run("folder that does not exist returns 400", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t, featuremgmt.WithFeatures(featuremgmt.FlagGrafanaManagedRecordingRules))
+		status, _, body := importRules(t, sut, "missing", testPrometheusRules, true)
+		require.Equal(t, 400, status)
+		require.Contains(t, body, "folder with UID 'missing' does not exist")
+	})
+
+	t.Run("invalid rule file returns 400", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t, featuremgmt.WithFeatures())
+		status, _, _ := importRules(t, sut, "folder-uid", `groups: [{name: group, rules: [{alert: alert, expr: "up >"}]}]`, true)
+		require.Equal(t, 400, status)
+
+		status, _, body := importRules(t, sut, "folder-uid", `groups: [{name: group, rules: [{alert: alert, expr: up, keep_firing_for: 5m}]}]`, true)
+		require.Equal(t, 400, status)
+		require.Contains(t, body, "keep_firing_for")
+	})
+
+	t.Run("invalid group prevents applying the groups before it", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t, featuremgmt.WithFeatures())
+		rules := `
+groups:
+  - name: alerting-group
+    rules:
+      - alert: HighErrorRate
+        expr: sum(rate(errors_total[5m])) by (job) > 10
+  - name: recording-group
+    rules:
+      - record: job:errors:rate5m
+        expr: sum(rate(errors_total[5m])) by (job)
+`
+		status, _, body := importRules(t, sut, "folder-uid", rules, false)
+		require.Equal(t, 400, status)
+		require.Contains(t, body, "recording-group")
+
+		rc := createTestRequestCtx()
+		require.Equal(t, 404, sut.RouteGetAlertRuleGroup(&rc, "folder-uid", "alerting-group").Status())
+	})
+
+	t.Run("file larger than the limit returns 413", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t, featuremgmt.WithFeatures(featuremgmt.FlagGrafanaManagedRecordingRules))
+		sut.maxImportSize = int64(len(testPrometheusRules) - 1)
+		status, _, _ := importRules(t, sut, "folder-uid", testPrometheusRules, true)
+		require.Equal(t, 413, status)
+	})
+}
@@ -0,0 +1,368 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestProvisioningApiImport(t *testing.T) {
+	createProvisioningSrvSut := func(t *testing.T) ProvisioningSrv {
+		env := createTestEnv(t, testConfig)
+		env.ac = &recordingAccessControlFake{
+			Callback: func(user *user.SignedInUser, evaluator accesscontrol.Evaluator) (bool, error) {
+				return true, nil
+			},
+		}
+		return createProvisioningSrvSutFromEnv(t, &env)
+	}
+	importDoc := func(t *testing.T, sut ProvisioningSrv, format, body string, dryRun bool) (int, definitions.ImportResult, string) {
+		t.Helper()
+		rc := createTestRequestCtx()
+		rc.Context.Req.Form.Set("format", format)
+		if dryRun {
+			rc.Context.Req.Form.Set("dryRun", "true")
+		}
+		rc.Context.Req.Body = io.NopCloser(strings.NewReader(body))
+		response := sut.RoutePostImport(&rc)
+		var result definitions.ImportResult
+		if response.Status() == 200 {
+			require.NoError(t, json.Unmarshal(response.Body(), &result))
+		}
+		return response.Status(), result, string(response.Body())
+	}
+
+	for _, format := range []string{"yaml", "json", "hcl"} {
+		t.Run("export of "+format+" imports without changes", func(t *testing.T) {
+			sut := createProvisioningSrvSut(t)
+			// Durations of the test rules are in nanoseconds, which cannot be exported.
+			rule1 := createTestAlertRule("rule1", 1)
+			rule1.NotificationSettings = nil
+			rule1.Labels = map[string]string{"test": "label"}
+			rule1.For = model.Duration(5 * time.Minute)
+			rule1.Data[0].RelativeTimeRange.From = definitions.Duration(10 * time.Minute)
+			insertRule(t, sut, rule1)
+			rule2 := createTestAlertRule("rule2", 1)
+			rule2.NotificationSettings = nil
+			rule2.For = 0
+			rule2.Data[0].RelativeTimeRange.From = definitions.Duration(10 * time.Minute)
+			insertRule(t, sut, rule2)
+
+			exports := []string{}
+			rc := createTestRequestCtx()
+			rc.Context.Req.Form.Set("format", format)
+			exports = append(exports, string(sut.RouteGetAlertRuleGroupExport(&rc, "folder-uid", "my-cool-group").Body()))
+			exports = append(exports, string(sut.RouteGetMuteTimingsExport(&rc).Body()))
+			exports = append(exports, string(sut.RouteGetContactPointsExport(&rc).Body()))
+
+			for _, export := range exports {
+				status, result, body := importDoc(t, sut, format, export, true)
+				require.Equalf(t, 200, status, body)
+				require.True(t, result.DryRun)
+				require.Emptyf(t, result.Changes, "export:\n%s", export)
+			}
+		})
+	}
+
+	t.Run("dry run reports changes of rules without applying them", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t)
+		rule := createTestAlertRule("rule1", 1)
+		rule.NotificationSettings = nil
+		insertRule(t, sut, rule)
+
+		doc := `apiVersion: 1
+groups:
+  - orgId: 1
+    name: my-cool-group
+    folder: Folder Title
+    interval: 1m
+    rules:
+      - title: rule1
+        condition: A
+        data:
+          - refId: A
+            relativeTimeRange:
+              from: 60
+              to: 0
+            datasourceUid: prometheus
+            model:
+              expr: up
+        noDataState: OK
+        execErrState: OK
+        for: 5m
+      - uid: new-rule-uid
+        title: rule2
+        condition: A
+        data:
+          - refId: A
+            relativeTimeRange:
+              from: 60
+              to: 0
+            datasourceUid: prometheus
+            model:
+              expr: up
+        noDataState: OK
+        execErrState: OK
+`
+		status, result, body := importDoc(t, sut, "yaml", doc, true)
+		require.Equal(t, 200, status, body)
+		require.Len(t, result.Changes, 2)
+		require.Equal(t, definitions.ImportChange{
+			Kind:      importKindAlertRule,
+			Action:    importActionCreate,
+			Name:      "rule2",
+			UID:       "new-rule-uid",
+			FolderUID: "folder-uid",
+			RuleGroup: "my-cool-group",
+		}, result.Changes[0])
+		require.Equal(t, importActionUpdate, result.Changes[1].Action)
+		require.Equal(t, "rule1", result.Changes[1].UID)
+		require.Contains(t, result.Changes[1].Diff, "For")
+		require.Contains(t, result.Changes[1].Diff, "Data[0].Model")
+
+		rc := createTestRequestCtx()
+		require.Equal(t, 404, sut.RouteRouteGetAlertRule(&rc, "new-rule-uid").Status())
+
+		status, result, body = importDoc(t, sut, "yaml", doc, false)
+		require.Equal(t, 200, status, body)
+		require.False(t, result.DryRun)
+		require.Len(t, result.Changes, 2)
+		require.Equal(t, 200, sut.RouteRouteGetAlertRule(&rc, "new-rule-uid").Status())
+
+		status, result, body = importDoc(t, sut, "yaml", doc, true)
+		require.Equal(t, 200, status, body)
+		require.Empty(t, result.Changes)
+	})
+
+	t.Run("rule group in folder that does not exist returns 400", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t)
+		doc := `{"apiVersion": 1, "groups": [{"name": "group", "folder": "Folder Title/missing", "interval": "1m", "rules": []}]}`
+		status, _, body := importDoc(t, sut, "json", doc, true)
+		require.Equal(t, 400, status)
+		require.Contains(t, body, "folder 'Folder Title/missing' does not exist")
+	})
+
+	t.Run("rule group in folder is resolved by title", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t)
+		doc := `{"apiVersion": 1, "groups": [{"name": "group", "folder": "Folder Title2", "interval": "1m", "rules": [{
+			"title": "rule", "condition": "A", "noDataState": "OK", "execErrState": "OK",
+			"data": [{"refId": "A", "datasourceUid": "prometheus", "relativeTimeRange": {"from": 60, "to": 0}, "model": {"expr": "up"}}]
+		}]}]}`
+		status, result, body := importDoc(t, sut, "json", doc, true)
+		require.Equal(t, 200, status, body)
+		require.Len(t, result.Changes, 1)
+		require.Equal(t, "folder-uid2", result.Changes[0].FolderUID)
+	})
+
+	t.Run("new contact point with redacted settings returns 400", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t)
+		doc := `apiVersion: 1
+contactPoints:
+  - name: new-contact-point
+    receivers:
+      - type: slack
+        settings:
+          url: '[REDACTED]'
+          recipient: '#alerts'
+`
+		status, _, body := importDoc(t, sut, "yaml", doc, true)
+		require.Equal(t, 400, status)
+		require.Contains(t, body, "secure settings are redacted")
+	})
+
+	t.Run("dry run reports changes of notification resources", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t)
+		doc := `apiVersion: 1
+contactPoints:
+  - name: email receiver
+    receivers:
+      - uid: email-uid
+        type: email
+        settings:
+          addresses: <other@email.com>
+      - uid: webhook-uid
+        type: webhook
+        settings:
+          url: http://localhost
+muteTimes:
+  - name: interval
+    time_intervals:
+      - weekdays: [saturday, sunday]
+  - name: new-interval
+    time_intervals: []
+policies:
+  - receiver: email receiver
+    routes:
+      - receiver: email receiver
+        mute_time_intervals: [new-interval]
+`
+		status, result, body := importDoc(t, sut, "yaml", doc, true)
+		require.Equal(t, 200, status, body)
+		require.Equal(t, []definitions.ImportChange{
+			{Kind: importKindMuteTiming, Action: importActionUpdate, Name: "interval", Diff: []string{"time_intervals[0]"}},
+			{Kind: importKindMuteTiming, Action: importActionCreate, Name: "new-interval"},
+			{Kind: importKindContactPoint, Action: importActionUpdate, Name: "email receiver", UID: "email-uid", Diff: []string{"settings.addresses"}},
+			{Kind: importKindContactPoint, Action: importActionCreate, Name: "email receiver", UID: "webhook-uid"},
+			{Kind: importKindNotificationPolicy, Action: importActionUpdate, Name: "email receiver", Diff: []string{"receiver", "routes"}},
+		}, result.Changes)
+	})
+
+	t.Run("policy with unknown receiver returns 400", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t)
+		doc := `{"apiVersion": 1, "policies": [{"receiver": "unknown"}]}`
+		status, _, _ := importDoc(t, sut, "json", doc, true)
+		require.Equal(t, 400, status)
+	})
+
+	t.Run("invalid resource prevents applying the resources before it", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t)
+		doc := `apiVersion: 1
+muteTimes:
+  - name: imported-interval
+    time_intervals:
+      - weekdays: [saturday, sunday]
+groups:
+  - name: group
+    folder: Folder Title/missing
+    interval: 1m
+    rules: []
+`
+		status, _, body := importDoc(t, sut, "yaml", doc, false)
+		require.Equal(t, 400, status)
+		require.Contains(t, body, "folder 'Folder Title/missing' does not exist")
+
+		rc := createTestRequestCtx()
+		require.Equal(t, 404, sut.RouteGetMuteTiming(&rc, "imported-interval").Status())
+	})
+
+	t.Run("rules can refer to contact points and mute timings of the document", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t)
+		doc := `apiVersion: 1
+contactPoints:
+  - name: imported-receiver
+    receivers:
+      - uid: imported-webhook-uid
+        type: webhook
+        settings:
+          url: http://localhost
+muteTimes:
+  - name: imported-interval
+    time_intervals: []
+groups:
+  - name: group
+    folder: Folder Title
+    interval: 1m
+    rules:
+      - title: rule
+        condition: A
+        noDataState: OK
+        execErrState: OK
+        data:
+          - refId: A
+            relativeTimeRange:
+              from: 60
+              to: 0
+            datasourceUid: prometheus
+            model:
+              expr: up
+        notification_settings:
+          receiver: imported-receiver
+          mute_time_intervals: [imported-interval]
+`
+		status, result, body := importDoc(t, sut, "yaml", doc, true)
+		require.Equal(t, 200, status, body)
+		require.Len(t, result.Changes, 3)
+	})
+
+	t.Run("unsupported HCL resource returns 400", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t)
+		status, _, body := importDoc(t, sut, "hcl", `resource "grafana_folder" "folder" {}`, true)
+		require.Equal(t, 400, status)
+		require.Contains(t, body, "unsupported resource type")
+	})
+
+	t.Run("document larger than the limit returns 413", func(t *testing.T) {
+		sut := createProvisioningSrvSut(t)
+		sut.maxImportSize = 16
+		status, _, _ := importDoc(t, sut, "yaml", "apiVersion: 1\ngroups: []\n", true)
+		require.Equal(t, 413, status)
+
+		sut.maxImportSize = 1024
+		status, _, body := importDoc(t, sut, "yaml", "apiVersion: 1\ngroups: []\n", true)
+		require.Equal(t, 200, status, body)
+	})
+}
+
+func TestDecodeImportDocument(t *testing.T) {
+	decode := func(t *testing.T, file, format string) definitions.AlertingFileExport {
+		t.Helper()
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		doc, err := decodeImportDocument(format, data, 1)
+		require.NoError(t, err)
+		return doc
+	}
+
+	t.Run("rule groups decode to the same rules in all formats", func(t *testing.T) {
+		yamlDoc := decode(t, "test-data/post-rulegroup-101-export.yaml", "yaml")
+		jsonDoc := decode(t, "test-data/post-rulegroup-101-export.json", "json")
+		hclDoc := decode(t, "test-data/post-rulegroup-101-export.hcl", "hcl")
+
+		expected, err := AlertRuleGroupFromAlertRuleGroupExport(1, "folder-uid", yamlDoc.Groups[0])
+		require.NoError(t, err)
+		require.NotEmpty(t, expected.Rules)
+		fromJSON, err := AlertRuleGroupFromAlertRuleGroupExport(1, "folder-uid", jsonDoc.Groups[0])
+		require.NoError(t, err)
+		require.Equal(t, expected, fromJSON)
+
+		fromHCL, err := AlertRuleGroupFromAlertRuleGroupExport(1, "folder-uid", hclDoc.Groups[0])
+		require.NoError(t, err)
+		// HCL documents do not have UIDs of rules, and store models of queries as JSON strings.
+		for i := range expected.Rules {
+			expected.Rules[i].UID = ""
+			for j := range expected.Rules[i].Data {
+				require.JSONEq(t, string(expected.Rules[i].Data[j].Model), string(fromHCL.Rules[i].Data[j].Model))
+				fromHCL.Rules[i].Data[j].Model = expected.Rules[i].Data[j].Model
+			}
+		}
+		require.Equal(t, expected, fromHCL)
+	})
+
+	t.Run("mute timings decode to the same intervals in all formats", func(t *testing.T) {
+		yamlDoc := decode(t, "test-data/alertmanager_default_mutetimings-export.yaml", "yaml")
+		jsonDoc := decode(t, "test-data/alertmanager_default_mutetimings-export.json", "json")
+		hclDoc := decode(t, "test-data/alertmanager_default_mutetimings-export.hcl", "hcl")
+
+		require.NotEmpty(t, yamlDoc.MuteTimings)
+		require.Equal(t, yamlDoc.MuteTimings, jsonDoc.MuteTimings)
+		// HCL documents omit empty intervals.
+		for i := range hclDoc.MuteTimings {
+			if hclDoc.MuteTimings[i].TimeIntervals == nil {
+				hclDoc.MuteTimings[i].TimeIntervals = []timeinterval.TimeInterval{}
+			}
+		}
+		require.Equal(t, yamlDoc.MuteTimings, hclDoc.MuteTimings)
+	})
+
+	t.Run("unsupported apiVersion", func(t *testing.T) {
+		_, err := decodeImportDocument("json", []byte(`{"apiVersion": 2}`), 1)
+		require.ErrorContains(t, err, "unsupported apiVersion 2")
+	})
+}
+
+func TestSplitFolderPath(t *testing.T) {
+	require.Equal(t, []string{"a"}, splitFolderPath("a"))
+	require.Equal(t, []string{"a", "b", "c"}, splitFolderPath("a/b/c"))
+	require.Equal(t, []string{"a/b", "c"}, splitFolderPath(`a\/b/c`))
+}
@@ -57,6 +57,9 @@ type RulerSrv struct {
 	amConfigStore  AMConfigStore
 	amRefresher    AMRefresher
 	featureManager featuremgmt.FeatureToggles
+
+	linter    RuleLinter
+	lintStore RuleLintStore
 }
 
 var (
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// RouteGetRuleDependencies returns the dependencies between the rules the user has access to.
+// It uses the same dependency graph as the scheduler, which evaluates the rules that read the metric of
+// a recording rule after the recording rule.
+func (srv RulerSrv) RouteGetRuleDependencies(c *contextmodel.ReqContext) response.Response {
+	namespaceMap, err := srv.store.GetUserVisibleNamespaces(c.Req.Context(), c.SignedInUser.GetOrgID(), c.SignedInUser)
+	if err != nil {
+		return ErrResp(http.StatusInternalServerError, err, "failed to get namespaces visible to the user")
+	}
+	result := apimodels.RuleDependencyGraph{
+		Nodes:  []apimodels.RuleDependencyNode{},
+		Edges:  []apimodels.RuleDependencyEdge{},
+		Cycles: [][]string{},
+	}
+	if len(namespaceMap) == 0 {
+		return response.JSON(http.StatusOK, result)
+	}
+
+	namespaceUIDs := make([]string, 0, len(namespaceMap))
+	for k := range namespaceMap {
+		namespaceUIDs = append(namespaceUIDs, k)
+	}
+	configs, _, err := srv.searchAuthorizedAlertRules(c.Req.Context(), authorizedRuleGroupQuery{
+		User:          c.SignedInUser,
+		NamespaceUIDs: namespaceUIDs,
+	})
+	if err != nil {
+		return errorToResponse(err)
+	}
+	var rules []*ngmodels.AlertRule
+	for _, group := range configs {
+		rules = append(rules, group...)
+	}
+	// sort result so the response is always stable
+	slices.SortFunc(rules, func(a, b *ngmodels.AlertRule) int {
+		return cmp.Or(
+			cmp.Compare(a.NamespaceUID, b.NamespaceUID),
+			cmp.Compare(a.RuleGroup, b.RuleGroup),
+			cmp.Compare(a.RuleGroupIndex, b.RuleGroupIndex),
+			cmp.Compare(a.UID, b.UID),
+		)
+	})
+
+	return response.JSON(http.StatusOK, toRuleDependencyGraph(rules, ngmodels.NewRuleDependencyGraph(rules, ngmodels.RecordTargetDatasourcesFromSettings(srv.cfg.RecordingRules))))
+}
+
+func toRuleDependencyGraph(rules []*ngmodels.AlertRule, graph *ngmodels.RuleDependencyGraph) apimodels.RuleDependencyGraph {
+	result := apimodels.RuleDependencyGraph{
+		Nodes:  make([]apimodels.RuleDependencyNode, 0, len(rules)),
+		Edges:  []apimodels.RuleDependencyEdge{},
+		Cycles: make([][]string, 0, len(graph.Cycles())),
+	}
+	for _, rule := range rules {
+		node := apimodels.RuleDependencyNode{
+			UID:       rule.UID,
+			Title:     rule.Title,
+			FolderUID: rule.NamespaceUID,
+			RuleGroup: rule.RuleGroup,
+			Type:      string(rule.Type()),
+		}
+		if rule.Record != nil {
+			node.Metric = rule.Record.Metric
+		}
+		result.Nodes = append(result.Nodes, node)
+	}
+	for _, edge := range graph.Edges() {
+		result.Edges = append(result.Edges, apimodels.RuleDependencyEdge{
+			Source: edge.Recording.UID,
+			Target: edge.Dependent.UID,
+			Cyclic: edge.Cyclic,
+		})
+	}
+	for _, cycle := range graph.Cycles() {
+		uids := make([]string, 0, len(cycle))
+		for _, key := range cycle {
+			uids = append(uids, key.UID)
+		}
+		result.Cycles = append(result.Cycles, uids)
+	}
+	return result
+}
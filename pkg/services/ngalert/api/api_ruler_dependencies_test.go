@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
+)
+
+func TestRouteGetRuleDependencies(t *testing.T) {
+	orgID := rand.Int63()
+	folder := randFolder()
+	ruleStore := fakes.NewRuleStore(t)
+	ruleStore.Folders[orgID] = append(ruleStore.Folders[orgID], folder)
+	groupKey := models.GenerateGroupKey(orgID)
+	groupKey.NamespaceUID = folder.UID
+
+	query := func(expr string) models.AlertQuery {
+		return models.AlertQuery{RefID: "A", DatasourceUID: "prometheus", Model: json.RawMessage(`{"expr":"` + expr + `"}`)}
+	}
+	gen := models.RuleGen.With(models.RuleGen.WithGroupKey(groupKey))
+	recording := gen.With(
+		gen.WithGroupIndex(1),
+		gen.WithAllRecordingRules(),
+		gen.WithMetric("job:up:sum"),
+		gen.WithQuery(query("sum(up) by (job)")),
+	).GenerateRef()
+	alert := gen.With(gen.WithGroupIndex(2), gen.WithQuery(query("job:up:sum < 1"))).GenerateRef()
+	alert.Record = nil
+	ruleStore.PutRule(context.Background(), recording, alert)
+
+	req := createRequestContextWithPerms(orgID, createPermissionsForRules([]*models.AlertRule{recording, alert}, orgID), nil)
+	response := createService(ruleStore).RouteGetRuleDependencies(req)
+	require.Equal(t, http.StatusOK, response.Status())
+
+	result := apimodels.RuleDependencyGraph{}
+	require.NoError(t, json.Unmarshal(response.Body(), &result))
+	require.Equal(t, []apimodels.RuleDependencyNode{
+		{UID: recording.UID, Title: recording.Title, FolderUID: folder.UID, RuleGroup: groupKey.RuleGroup, Type: "recording", Metric: "job:up:sum"},
+		{UID: alert.UID, Title: alert.Title, FolderUID: folder.UID, RuleGroup: groupKey.RuleGroup, Type: "alerting"},
+	}, result.Nodes)
+	require.Equal(t, []apimodels.RuleDependencyEdge{{Source: recording.UID, Target: alert.UID}}, result.Edges)
+	require.Empty(t, result.Cycles)
+}
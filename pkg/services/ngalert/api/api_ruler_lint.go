@@ -0,0 +1,170 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// RuleLinter checks alert rules for problems that make them noisy or unreliable.
+// Lint returns one result per rule, in the order of the rules.
+type RuleLinter interface {
+	Lint(ctx context.Context, orgID int64, rules []*ngmodels.AlertRule) []ngmodels.RuleLintResult
+}
+
+// RuleLintStore returns the stored results of the rule linter.
+type RuleLintStore interface {
+	ListRuleLintResults(ctx context.Context, query *ngmodels.ListRuleLintResultsQuery) ([]ngmodels.RuleLintResult, error)
+}
+
+// RouteGetRuleLintReport returns the stored results of the rule linter for the rules the user has access to,
+// and the health score of their folders. The folders can be filtered with the query parameter folderUid.
+func (srv RulerSrv) RouteGetRuleLintReport(c *contextmodel.ReqContext) response.Response {
+	namespaceMap, err := srv.store.GetUserVisibleNamespaces(c.Req.Context(), c.SignedInUser.GetOrgID(), c.SignedInUser)
+	if err != nil {
+		return ErrResp(http.StatusInternalServerError, err, "failed to get namespaces visible to the user")
+	}
+
+	namespaceUIDs := make([]string, 0, len(namespaceMap))
+	if folderUIDs := c.QueryStrings("folderUid"); len(folderUIDs) > 0 {
+		for _, uid := range folderUIDs {
+			if _, ok := namespaceMap[uid]; ok {
+				namespaceUIDs = append(namespaceUIDs, uid)
+			}
+		}
+	} else {
+		for uid := range namespaceMap {
+			namespaceUIDs = append(namespaceUIDs, uid)
+		}
+	}
+	if len(namespaceUIDs) == 0 {
+		return response.JSON(http.StatusOK, toRuleLintReport(nil, nil))
+	}
+
+	configs, _, err := srv.searchAuthorizedAlertRules(c.Req.Context(), authorizedRuleGroupQuery{
+		User:          c.SignedInUser,
+		NamespaceUIDs: namespaceUIDs,
+	})
+	if err != nil {
+		return errorToResponse(err)
+	}
+	rules := make(map[string]*ngmodels.AlertRule)
+	for _, group := range configs {
+		for _, rule := range group {
+			rules[rule.UID] = rule
+		}
+	}
+	if len(rules) == 0 {
+		return response.JSON(http.StatusOK, toRuleLintReport(nil, nil))
+	}
+
+	results, err := srv.lintStore.ListRuleLintResults(c.Req.Context(), &ngmodels.ListRuleLintResultsQuery{
+		OrgID: c.SignedInUser.GetOrgID(),
+	})
+	if err != nil {
+		return ErrResp(http.StatusInternalServerError, err, "failed to get rule lint results")
+	}
+	// results of rules that were deleted or that the user cannot access are dropped.
+	results = slices.DeleteFunc(results, func(r ngmodels.RuleLintResult) bool {
+		_, ok := rules[r.RuleUID]
+		return !ok
+	})
+	titles := make([]string, 0, len(results))
+	for _, r := range results {
+		titles = append(titles, rules[r.RuleUID].Title)
+	}
+
+	return response.JSON(http.StatusOK, toRuleLintReport(results, titles))
+}
+
+// RoutePostRulesGroupForLint lints the submitted rule group without saving it.
+// Can return 403 StatusForbidden if user is not authorized to read folder `namespaceUID`
+func (srv RulerSrv) RoutePostRulesGroupForLint(c *contextmodel.ReqContext, ruleGroupConfig apimodels.PostableRuleGroupConfig, namespaceUID string) response.Response {
+	namespace, err := srv.store.GetNamespaceByUID(c.Req.Context(), namespaceUID, c.SignedInUser.GetOrgID(), c.SignedInUser)
+	if err != nil {
+		return toNamespaceErrorResponse(err)
+	}
+
+	rulesWithOptionals, err := ValidateRuleGroup(&ruleGroupConfig, c.SignedInUser.GetOrgID(), namespace.UID, RuleLimitsFromConfig(srv.cfg, srv.featureManager))
+	if err != nil {
+		return ErrResp(http.StatusBadRequest, err, "")
+	}
+
+	// new rules have no UID yet, so the results are matched to the rules by their position.
+	rules := make([]*ngmodels.AlertRule, 0, len(rulesWithOptionals))
+	titles := make([]string, 0, len(rulesWithOptionals))
+	for i := range rulesWithOptionals {
+		rule := &rulesWithOptionals[i].AlertRule
+		rules = append(rules, rule)
+		titles = append(titles, rule.Title)
+	}
+
+	return response.JSON(http.StatusOK, toRuleLintReport(srv.linter.Lint(c.Req.Context(), c.SignedInUser.GetOrgID(), rules), titles))
+}
+
+// toRuleLintReport builds the report of the results, titles are the titles of the rules of the results.
+func toRuleLintReport(results []ngmodels.RuleLintResult, titles []string) apimodels.RuleLintReport {
+	report := apimodels.RuleLintReport{
+		Folders: []apimodels.FolderLintHealth{},
+		Rules:   make([]apimodels.RuleLintResult, 0, len(results)),
+	}
+	folders := make(map[string]*apimodels.FolderLintHealth)
+	for i, r := range results {
+		result := apimodels.RuleLintResult{
+			UID:         r.RuleUID,
+			Title:       titles[i],
+			FolderUID:   r.NamespaceUID,
+			RuleGroup:   r.RuleGroup,
+			Score:       r.Score,
+			MaxSeverity: string(r.MaxSeverity()),
+			Issues:      make([]apimodels.RuleLintIssue, 0, len(r.Issues)),
+			LintedAt:    r.Linted,
+		}
+		folder, ok := folders[r.NamespaceUID]
+		if !ok {
+			folder = &apimodels.FolderLintHealth{FolderUID: r.NamespaceUID}
+			folders[r.NamespaceUID] = folder
+		}
+		folder.Rules++
+		// the sum of the scores, it is divided by the number of rules below.
+		folder.Score += r.Score
+		for _, issue := range r.Issues {
+			switch issue.Severity {
+			case ngmodels.RuleLintSeverityError:
+				folder.Errors++
+			case ngmodels.RuleLintSeverityWarning:
+				folder.Warnings++
+			}
+			result.Issues = append(result.Issues, apimodels.RuleLintIssue{
+				Check:    issue.Check,
+				Severity: string(issue.Severity),
+				Message:  issue.Message,
+				RefID:    issue.RefID,
+			})
+		}
+		report.Rules = append(report.Rules, result)
+	}
+	for _, folder := range folders {
+		folder.Score /= folder.Rules
+		report.Folders = append(report.Folders, *folder)
+	}
+	// sort result so the response is always stable
+	slices.SortFunc(report.Folders, func(a, b apimodels.FolderLintHealth) int {
+		return cmp.Compare(a.FolderUID, b.FolderUID)
+	})
+	slices.SortFunc(report.Rules, func(a, b apimodels.RuleLintResult) int {
+		return cmp.Or(
+			cmp.Compare(a.FolderUID, b.FolderUID),
+			cmp.Compare(a.RuleGroup, b.RuleGroup),
+			cmp.Compare(a.UID, b.UID),
+			cmp.Compare(a.Title, b.Title),
+		)
+	})
+	return report
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
+)
+
+type fakeRuleLintStore struct {
+	results []models.RuleLintResult
+}
+
+func (f *fakeRuleLintStore) ListRuleLintResults(_ context.Context, query *models.ListRuleLintResultsQuery) ([]models.RuleLintResult, error) {
+	var result []models.RuleLintResult
+	for _, r := range f.results {
+		if r.OrgID == query.OrgID {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+type fakeRuleLinter struct{}
+
+func (f fakeRuleLinter) Lint(_ context.Context, _ int64, rules []*models.AlertRule) []models.RuleLintResult {
+	results := make([]models.RuleLintResult, 0, len(rules))
+	for i, rule := range rules {
+		var issues []models.RuleLintIssue
+		if i == 0 {
+			issues = []models.RuleLintIssue{{Check: models.RuleLintCheckMissingDataSource, Severity: models.RuleLintSeverityError, Message: "missing"}}
+		}
+		results = append(results, models.NewRuleLintResult(rule, issues, time.Now()))
+	}
+	return results
+}
+
+func TestRouteGetRuleLintReport(t *testing.T) {
+	orgID := rand.Int63()
+	folder := randFolder()
+	ruleStore := fakes.NewRuleStore(t)
+	ruleStore.Folders[orgID] = append(ruleStore.Folders[orgID], folder)
+	groupKey := models.GenerateGroupKey(orgID)
+	groupKey.NamespaceUID = folder.UID
+
+	gen := models.RuleGen.With(models.RuleGen.WithGroupKey(groupKey))
+	healthy := gen.GenerateRef()
+	noisy := gen.GenerateRef()
+	ruleStore.PutRule(context.Background(), healthy, noisy)
+
+	linted := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	issues := []models.RuleLintIssue{
+		{Check: models.RuleLintCheckMissingDataSource, Severity: models.RuleLintSeverityError, Message: "missing", RefID: "A"},
+		{Check: models.RuleLintCheckHighCardinalityLabel, Severity: models.RuleLintSeverityWarning, Message: "label"},
+	}
+	lintStore := &fakeRuleLintStore{results: []models.RuleLintResult{
+		models.NewRuleLintResult(healthy, nil, linted),
+		models.NewRuleLintResult(noisy, issues, linted),
+		// the result of a rule that was deleted is not reported.
+		{OrgID: orgID, RuleUID: "deleted", NamespaceUID: folder.UID, RuleGroup: groupKey.RuleGroup, Score: 0, Linted: linted},
+	}}
+
+	srv := createService(ruleStore)
+	srv.lintStore = lintStore
+
+	t.Run("should return results of rules the user has access to", func(t *testing.T) {
+		req := createRequestContextWithPerms(orgID, createPermissionsForRules([]*models.AlertRule{healthy, noisy}, orgID), nil)
+		response := srv.RouteGetRuleLintReport(req)
+		require.Equal(t, http.StatusOK, response.Status())
+
+		result := apimodels.RuleLintReport{}
+		require.NoError(t, json.Unmarshal(response.Body(), &result))
+		require.Equal(t, []apimodels.FolderLintHealth{
+			{FolderUID: folder.UID, Score: (100 + 45) / 2, Rules: 2, Errors: 1, Warnings: 1},
+		}, result.Folders)
+		require.Len(t, result.Rules, 2)
+		byUID := map[string]apimodels.RuleLintResult{}
+		for _, r := range result.Rules {
+			byUID[r.UID] = r
+		}
+		require.Equal(t, 100, byUID[healthy.UID].Score)
+		require.Empty(t, byUID[healthy.UID].MaxSeverity)
+		require.Empty(t, byUID[healthy.UID].Issues)
+		require.Equal(t, noisy.Title, byUID[noisy.UID].Title)
+		require.Equal(t, 45, byUID[noisy.UID].Score)
+		require.Equal(t, "error", byUID[noisy.UID].MaxSeverity)
+		require.Equal(t, []apimodels.RuleLintIssue{
+			{Check: models.RuleLintCheckMissingDataSource, Severity: "error", Message: "missing", RefID: "A"},
+			{Check: models.RuleLintCheckHighCardinalityLabel, Severity: "warning", Message: "label"},
+		}, byUID[noisy.UID].Issues)
+	})
+
+	t.Run("should return empty report if folder is filtered out", func(t *testing.T) {
+		req := createRequestContextWithPerms(orgID, createPermissionsForRules([]*models.AlertRule{healthy, noisy}, orgID), nil)
+		req.Req.Form.Set("folderUid", "other")
+		response := srv.RouteGetRuleLintReport(req)
+		require.Equal(t, http.StatusOK, response.Status())
+
+		result := apimodels.RuleLintReport{}
+		require.NoError(t, json.Unmarshal(response.Body(), &result))
+		require.Empty(t, result.Folders)
+		require.Empty(t, result.Rules)
+	})
+}
+
+func TestRoutePostRulesGroupForLint(t *testing.T) {
+	orgID := rand.Int63()
+	folder := randFolder()
+	ruleStore := fakes.NewRuleStore(t)
+	ruleStore.Folders[orgID] = append(ruleStore.Folders[orgID], folder)
+
+	srv := createService(ruleStore)
+	srv.linter = fakeRuleLinter{}
+
+	t.Run("should report titles of new rules", func(t *testing.T) {
+		first, second := validRule(), validRule()
+		first.GrafanaManagedAlert.UID = ""
+		second.GrafanaManagedAlert.UID = ""
+		group := validGroup(srv.cfg, first, second)
+
+		req := createRequestContextWithPerms(orgID, nil, nil)
+		response := srv.RoutePostRulesGroupForLint(req, group, folder.UID)
+		require.Equal(t, http.StatusOK, response.Status())
+
+		result := apimodels.RuleLintReport{}
+		require.NoError(t, json.Unmarshal(response.Body(), &result))
+		require.Len(t, result.Rules, 2)
+		byTitle := map[string]apimodels.RuleLintResult{}
+		for _, r := range result.Rules {
+			require.Empty(t, r.UID)
+			byTitle[r.Title] = r
+		}
+		require.Len(t, byTitle[first.GrafanaManagedAlert.Title].Issues, 1)
+		require.Empty(t, byTitle[second.GrafanaManagedAlert.Title].Issues)
+	})
+}
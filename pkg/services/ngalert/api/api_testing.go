@@ -10,6 +10,7 @@ import (
 
 	"github.com/benbjohnson/clock"
 	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	prometheusModel "github.com/prometheus/common/model"
 
 	"github.com/grafana/alerting/models"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -29,6 +30,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/ngalert/backtesting"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
 	"github.com/grafana/grafana/pkg/services/ngalert/state"
 	"github.com/grafana/grafana/pkg/services/ngalert/store"
 	"github.com/grafana/grafana/pkg/setting"
@@ -51,15 +53,122 @@ type TestingApiSrv struct {
 	appUrl          *url.URL
 	tracer          tracing.Tracer
 	folderService   folderService
+	routing         RoutingPreviewer
+	silences        silenceLister
+	ruleStore       ruleVersionStore
+}
+
+// silenceLister lists the silences of the organization of the user that the user can read.
+type silenceLister interface {
+	ListSilences(ctx context.Context, user identity.Requester, filter []string) ([]*ngmodels.Silence, error)
+}
+
+// RoutingPreviewer routes alerts through the notification policy tree of an organization without sending them.
+type RoutingPreviewer interface {
+	PreviewRouting(ctx context.Context, orgID int64, settings []ngmodels.NotificationSettings, alerts []prometheusModel.LabelSet, silences []*ngmodels.Silence, now time.Time) ([]notifier.AlertRouting, error)
+	// GetRoutingConfiguration returns the notification policies alerts with the given notification settings are routed by.
+	GetRoutingConfiguration(ctx context.Context, orgID int64, settings []ngmodels.NotificationSettings) (*apimodels.PostableApiAlertingConfig, error)
+}
+
+// ruleVersionStore provides the versions of the stored rules to compare.
+type ruleVersionStore interface {
+	GetAlertRuleByUID(ctx context.Context, query *ngmodels.GetAlertRuleByUIDQuery) (*ngmodels.AlertRule, error)
+	GetAlertRuleVersion(ctx context.Context, orgID int64, ruleUID string, version int64) (*ngmodels.AlertRule, error)
 }
 
 // RouteTestGrafanaRuleConfig returns a list of potential alerts for a given rule configuration. This is intended to be
 // as true as possible to what would be generated by the ruler except that the resulting alerts are not filtered to
 // only Resolved / Firing and ready to send.
 func (srv TestingApiSrv) RouteTestGrafanaRuleConfig(c *contextmodel.ReqContext, body apimodels.PostableExtendedRuleNodeExtended) response.Response {
+	_, transitions, _, errResp := srv.evaluateGrafanaRule(c, body)
+	if errResp != nil {
+		return errResp
+	}
+
+	alerts := make([]*amv2.PostableAlert, 0, len(transitions))
+	for _, alertState := range transitions {
+		alerts = append(alerts, state.StateToPostableAlert(alertState, srv.appUrl))
+	}
+
+	return response.JSON(http.StatusOK, alerts)
+}
+
+// RouteDryRunGrafanaRuleConfig evaluates the rule like RouteTestGrafanaRuleConfig, and routes every resulting alert
+// through the notification policy tree of the organization. It returns the policies that match each alert, and the
+// active mute timings and silences that would prevent it from being sent. Notifications are not sent.
+func (srv TestingApiSrv) RouteDryRunGrafanaRuleConfig(c *contextmodel.ReqContext, body apimodels.PostableExtendedRuleNodeExtended) response.Response {
+	rule, transitions, now, errResp := srv.evaluateGrafanaRule(c, body)
+	if errResp != nil {
+		return errResp
+	}
+
+	alerts := make([]prometheusModel.LabelSet, 0, len(transitions))
+	for _, t := range transitions {
+		lset := make(prometheusModel.LabelSet, len(t.Labels))
+		for k, v := range t.Labels {
+			lset[prometheusModel.LabelName(k)] = prometheusModel.LabelValue(v)
+		}
+		alerts = append(alerts, lset)
+	}
+	// Only the silences the user can read are reported.
+	silences, err := srv.silences.ListSilences(c.Req.Context(), c.SignedInUser, nil)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to list silences", err)
+	}
+	routing, err := srv.routing.PreviewRouting(c.Req.Context(), c.SignedInUser.GetOrgID(), rule.NotificationSettings, alerts, silences, now)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to route alerts through the notification policies", err)
+	}
+
+	result := apimodels.RuleDryRunResult{
+		Instances: make([]apimodels.RuleDryRunInstance, 0, len(transitions)),
+	}
+	for i, t := range transitions {
+		result.Instances = append(result.Instances, toRuleDryRunInstance(t, routing[i]))
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
+func toRuleDryRunInstance(t state.StateTransition, routing notifier.AlertRouting) apimodels.RuleDryRunInstance {
+	instance := apimodels.RuleDryRunInstance{
+		State:       t.State.State.String(),
+		StateReason: t.StateReason,
+		Labels:      t.Labels,
+		Annotations: t.Annotations,
+		Routes:      make([]apimodels.RuleDryRunRoute, 0, len(routing.Routes)),
+		SilencedBy:  routing.SilencedBy,
+	}
+	if instance.SilencedBy == nil {
+		instance.SilencedBy = []string{}
+	}
+	for _, r := range routing.Routes {
+		groupLabels := make(map[string]string, len(r.GroupLabels))
+		for k, v := range r.GroupLabels {
+			groupLabels[string(k)] = string(v)
+		}
+		instance.Routes = append(instance.Routes, apimodels.RuleDryRunRoute{
+			RouteID:        r.RouteID,
+			Matchers:       r.Matchers,
+			Receiver:       r.Receiver,
+			GroupKey:       r.GroupKey,
+			GroupLabels:    groupLabels,
+			GroupWait:      prometheusModel.Duration(r.GroupWait),
+			GroupInterval:  prometheusModel.Duration(r.GroupInterval),
+			RepeatInterval: prometheusModel.Duration(r.RepeatInterval),
+			MutedBy:        r.MutedBy,
+			Autogenerated:  r.Autogenerated,
+		})
+	}
+	return instance
+}
+
+// evaluateGrafanaRule validates and evaluates the rule, and processes the results in a new state manager, which
+// expands the templates of the labels and annotations. It returns the rule, the resulting state transitions and
+// the time of the evaluation, or an error response.
+func (srv TestingApiSrv) evaluateGrafanaRule(c *contextmodel.ReqContext, body apimodels.PostableExtendedRuleNodeExtended) (*ngmodels.AlertRule, state.StateTransitions, time.Time, response.Response) {
 	folder, err := srv.folderService.GetNamespaceByUID(c.Req.Context(), body.NamespaceUID, c.OrgID, c.SignedInUser)
 	if err != nil {
-		return toNamespaceErrorResponse(dashboards.ErrFolderAccessDenied)
+		return nil, nil, time.Time{}, toNamespaceErrorResponse(dashboards.ErrFolderAccessDenied)
 	}
 	rule, err := validateRuleNode(
 		&body.Rule,
@@ -70,28 +179,28 @@ func (srv TestingApiSrv) RouteTestGrafanaRuleConfig(c *contextmodel.ReqContext,
 		RuleLimitsFromConfig(srv.cfg, srv.featureManager),
 	)
 	if err != nil {
-		return ErrResp(http.StatusBadRequest, err, "")
+		return nil, nil, time.Time{}, ErrResp(http.StatusBadRequest, err, "")
 	}
 
 	if err := srv.authz.AuthorizeDatasourceAccessForRule(c.Req.Context(), c.SignedInUser, rule); err != nil {
-		return response.ErrOrFallback(http.StatusInternalServerError, "failed to authorize access to rule group", err)
+		return nil, nil, time.Time{}, response.ErrOrFallback(http.StatusInternalServerError, "failed to authorize access to rule group", err)
 	}
 
 	if srv.featureManager.IsEnabled(c.Req.Context(), featuremgmt.FlagAlertingQueryOptimization) {
 		if _, err := store.OptimizeAlertQueries(rule.Data); err != nil {
-			return ErrResp(http.StatusInternalServerError, err, "Failed to optimize query")
+			return nil, nil, time.Time{}, ErrResp(http.StatusInternalServerError, err, "Failed to optimize query")
 		}
 	}
 
 	evaluator, err := srv.evaluator.Create(eval.NewContext(c.Req.Context(), c.SignedInUser), rule.GetEvalCondition().WithSource("preview"))
 	if err != nil {
-		return ErrResp(http.StatusBadRequest, err, "Failed to build evaluator for queries and expressions")
+		return nil, nil, time.Time{}, ErrResp(http.StatusBadRequest, err, "Failed to build evaluator for queries and expressions")
 	}
 
 	now := time.Now()
 	results, err := evaluator.Evaluate(c.Req.Context(), now)
 	if err != nil {
-		return ErrResp(http.StatusInternalServerError, err, "Failed to evaluate queries")
+		return nil, nil, time.Time{}, ErrResp(http.StatusInternalServerError, err, "Failed to evaluate queries")
 	}
 
 	cfg := state.ManagerCfg{
@@ -114,13 +223,7 @@ func (srv TestingApiSrv) RouteTestGrafanaRuleConfig(c *contextmodel.ReqContext,
 		state.GetRuleExtraLabels(log.New("testing"), rule, folder.Fullpath, includeFolder),
 		nil,
 	)
-
-	alerts := make([]*amv2.PostableAlert, 0, len(transitions))
-	for _, alertState := range transitions {
-		alerts = append(alerts, state.StateToPostableAlert(alertState, srv.appUrl))
-	}
-
-	return response.JSON(http.StatusOK, alerts)
+	return rule, transitions, now, nil
 }
 
 func (srv TestingApiSrv) RouteTestRuleConfig(c *contextmodel.ReqContext, body apimodels.TestRulePayload, datasourceUID string) response.Response {
@@ -225,31 +328,175 @@ func (srv TestingApiSrv) BacktestAlertRule(c *contextmodel.ReqContext, cmd apimo
 		return ErrResp(http.StatusNotFound, nil, "Backgtesting API is not enabled")
 	}
 
+	rule, errResp := srv.backtestRuleFromConfig(c, cmd)
+	if errResp != nil {
+		return errResp
+	}
+
+	result, err := srv.backtesting.Test(c.Req.Context(), c.SignedInUser, rule, cmd.From, cmd.To)
+	if err != nil {
+		if errors.Is(err, backtesting.ErrInvalidInputData) {
+			return ErrResp(400, err, "Failed to evaluate")
+		}
+		return ErrResp(500, err, "Failed to evaluate")
+	}
+
+	body, err := data.FrameToJSON(result, data.IncludeAll)
+	if err != nil {
+		return ErrResp(500, err, "Failed to convert frame to JSON")
+	}
+	return response.JSON(http.StatusOK, body)
+}
+
+// BacktestAlertRuleReport replays the rule over the requested interval and returns a report of state transitions,
+// and optionally of the notifications the notification policies of the organization would have sent.
+func (srv TestingApiSrv) BacktestAlertRuleReport(c *contextmodel.ReqContext, cmd apimodels.BacktestReportConfig) response.Response {
+	if !srv.featureManager.IsEnabled(c.Req.Context(), featuremgmt.FlagAlertingBacktesting) {
+		return ErrResp(http.StatusNotFound, nil, "Backgtesting API is not enabled")
+	}
+
+	rule, errResp := srv.backtestRuleFromConfig(c, cmd.BacktestConfig)
+	if errResp != nil {
+		return errResp
+	}
+
+	folderTitle := ""
+	if cmd.NamespaceUID != "" {
+		folder, err := srv.folderService.GetNamespaceByUID(c.Req.Context(), cmd.NamespaceUID, c.SignedInUser.GetOrgID(), c.SignedInUser)
+		if err != nil {
+			return toNamespaceErrorResponse(dashboards.ErrFolderAccessDenied)
+		}
+		rule.NamespaceUID = folder.UID
+		folderTitle = folder.Fullpath
+	}
+
+	opts, errResp := srv.replayOptions(c, rule, folderTitle, cmd.SimulateNotifications)
+	if errResp != nil {
+		return errResp
+	}
+
+	report, err := srv.backtesting.Replay(c.Req.Context(), c.SignedInUser, rule, cmd.From, cmd.To, opts)
+	if err != nil {
+		if errors.Is(err, backtesting.ErrInvalidInputData) {
+			return ErrResp(400, err, "Failed to evaluate")
+		}
+		return ErrResp(500, err, "Failed to evaluate")
+	}
+	return response.JSON(http.StatusOK, report)
+}
+
+// BacktestCompareRuleVersions replays two versions of a stored rule over the same interval and returns the difference
+// between their reports.
+func (srv TestingApiSrv) BacktestCompareRuleVersions(c *contextmodel.ReqContext, cmd apimodels.BacktestCompareConfig) response.Response {
+	ctx := c.Req.Context()
+	if !srv.featureManager.IsEnabled(ctx, featuremgmt.FlagAlertingBacktesting) {
+		return ErrResp(http.StatusNotFound, nil, "Backgtesting API is not enabled")
+	}
+
+	if cmd.RuleUID == "" {
+		return ErrResp(400, nil, "rule_uid is required")
+	}
 	if cmd.From.After(cmd.To) {
 		return ErrResp(400, nil, "From cannot be greater than To")
 	}
 
+	orgID := c.SignedInUser.GetOrgID()
+	current, err := srv.ruleStore.GetAlertRuleByUID(ctx, &ngmodels.GetAlertRuleByUIDQuery{OrgID: orgID, UID: cmd.RuleUID})
+	if err != nil {
+		if errors.Is(err, ngmodels.ErrAlertRuleNotFound) {
+			return ErrResp(http.StatusNotFound, err, "")
+		}
+		return errorToResponse(err)
+	}
+	if err := srv.authz.AuthorizeAccessInFolder(ctx, c.SignedInUser, current); err != nil {
+		return errorToResponse(err)
+	}
+
+	newVersion := cmd.NewVersion
+	if newVersion == 0 {
+		newVersion = current.Version
+	}
+	oldVersion := cmd.OldVersion
+	if oldVersion == 0 {
+		oldVersion = newVersion - 1
+	}
+	if oldVersion < 1 || newVersion > current.Version || oldVersion >= newVersion {
+		return ErrResp(400, nil, "Invalid versions to compare: %d and %d, the current version of the rule is %d", oldVersion, newVersion, current.Version)
+	}
+
+	oldRule, err := srv.ruleStore.GetAlertRuleVersion(ctx, orgID, cmd.RuleUID, oldVersion)
+	if err != nil {
+		if errors.Is(err, ngmodels.ErrAlertRuleNotFound) {
+			return ErrResp(http.StatusNotFound, err, "")
+		}
+		return errorToResponse(err)
+	}
+	newRule, err := srv.ruleStore.GetAlertRuleVersion(ctx, orgID, cmd.RuleUID, newVersion)
+	if err != nil {
+		if errors.Is(err, ngmodels.ErrAlertRuleNotFound) {
+			return ErrResp(http.StatusNotFound, err, "")
+		}
+		return errorToResponse(err)
+	}
+	// the rule could have been moved between folders, so the user must have access to the folder of every version.
+	for _, rule := range []*ngmodels.AlertRule{oldRule, newRule} {
+		if rule.Type() != ngmodels.RuleTypeAlerting {
+			return ErrResp(400, nil, "Version %d of the rule is not an alerting rule", rule.Version)
+		}
+		if err := srv.authz.AuthorizeAccessInFolder(ctx, c.SignedInUser, rule); err != nil {
+			return errorToResponse(err)
+		}
+		if err := srv.authz.AuthorizeDatasourceAccessForRule(ctx, c.SignedInUser, rule); err != nil {
+			return errorToResponse(err)
+		}
+	}
+
+	folder, err := srv.folderService.GetNamespaceByUID(ctx, newRule.NamespaceUID, orgID, c.SignedInUser)
+	if err != nil {
+		return toNamespaceErrorResponse(dashboards.ErrFolderAccessDenied)
+	}
+	opts, errResp := srv.replayOptions(c, newRule, folder.Fullpath, cmd.SimulateNotifications)
+	if errResp != nil {
+		return errResp
+	}
+
+	diff, err := srv.backtesting.Compare(ctx, c.SignedInUser, oldRule, newRule, cmd.From, cmd.To, opts)
+	if err != nil {
+		if errors.Is(err, backtesting.ErrInvalidInputData) {
+			return ErrResp(400, err, "Failed to evaluate")
+		}
+		return ErrResp(500, err, "Failed to evaluate")
+	}
+	return response.JSON(http.StatusOK, diff)
+}
+
+// backtestRuleFromConfig validates the backtesting configuration and builds the ad-hoc rule to test.
+func (srv TestingApiSrv) backtestRuleFromConfig(c *contextmodel.ReqContext, cmd apimodels.BacktestConfig) (*ngmodels.AlertRule, response.Response) {
+	if cmd.From.After(cmd.To) {
+		return nil, ErrResp(400, nil, "From cannot be greater than To")
+	}
+
 	noDataState, err := ngmodels.NoDataStateFromString(string(cmd.NoDataState))
 
 	if err != nil {
-		return ErrResp(400, err, "")
+		return nil, ErrResp(400, err, "")
 	}
 	forInterval := time.Duration(cmd.For)
 	if forInterval < 0 {
-		return ErrResp(400, nil, "Bad For interval")
+		return nil, ErrResp(400, nil, "Bad For interval")
 	}
 
 	intervalSeconds, err := validateInterval(time.Duration(cmd.Interval), srv.cfg.BaseInterval)
 	if err != nil {
-		return ErrResp(400, err, "")
+		return nil, ErrResp(400, err, "")
 	}
 
 	queries := AlertQueriesFromApiAlertQueries(cmd.Data)
 	if err := srv.authz.AuthorizeDatasourceAccessForRule(c.Req.Context(), c.SignedInUser, &ngmodels.AlertRule{Data: queries}); err != nil {
-		return errorToResponse(err)
+		return nil, errorToResponse(err)
 	}
 
-	rule := &ngmodels.AlertRule{
+	return &ngmodels.AlertRule{
 		// ID:             0,
 		// Updated:        time.Time{},
 		// Version:        0,
@@ -270,19 +517,24 @@ func (srv TestingApiSrv) BacktestAlertRule(c *contextmodel.ReqContext, cmd apimo
 		For:             forInterval,
 		Annotations:     cmd.Annotations,
 		Labels:          cmd.Labels,
-	}
+	}, nil
+}
 
-	result, err := srv.backtesting.Test(c.Req.Context(), c.SignedInUser, rule, cmd.From, cmd.To)
-	if err != nil {
-		if errors.Is(err, backtesting.ErrInvalidInputData) {
-			return ErrResp(400, err, "Failed to evaluate")
-		}
-		return ErrResp(500, err, "Failed to evaluate")
+// replayOptions adds the labels the scheduler adds to the alerts of the rule, so that they are routed the same way
+// as real alerts, and loads the notification policy when notifications should be simulated.
+func (srv TestingApiSrv) replayOptions(c *contextmodel.ReqContext, rule *ngmodels.AlertRule, folderTitle string, simulateNotifications bool) (backtesting.ReplayOptions, response.Response) {
+	includeFolder := folderTitle != "" && !srv.cfg.ReservedLabels.IsReservedLabelDisabled(models.FolderTitleLabel)
+	opts := backtesting.ReplayOptions{
+		ExtraLabels: state.GetRuleExtraLabels(log.New("testing"), rule, folderTitle, includeFolder),
+	}
+	if !simulateNotifications {
+		return opts, nil
 	}
 
-	body, err := data.FrameToJSON(result, data.IncludeAll)
+	cfg, err := srv.routing.GetRoutingConfiguration(c.Req.Context(), c.SignedInUser.GetOrgID(), rule.NotificationSettings)
 	if err != nil {
-		return ErrResp(500, err, "Failed to convert frame to JSON")
+		return opts, ErrResp(http.StatusInternalServerError, err, "Failed to load the notification policies")
 	}
-	return response.JSON(http.StatusOK, body)
+	opts.Policy = backtesting.NotificationPolicyFromConfig(cfg)
+	return opts, nil
 }
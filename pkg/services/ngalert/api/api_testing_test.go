@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"testing"
@@ -9,9 +10,11 @@ import (
 	"github.com/google/uuid"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	prometheusModel "github.com/prometheus/common/model"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
 	"github.com/grafana/grafana/pkg/infra/tracing"
 	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
 	acMock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
@@ -23,9 +26,11 @@ import (
 	"github.com/grafana/grafana/pkg/services/folder"
 	"github.com/grafana/grafana/pkg/services/ngalert/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/backtesting"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval/eval_mocks"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
 	fakes2 "github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/web"
@@ -243,6 +248,340 @@ func TestRouteTestGrafanaRuleConfig(t *testing.T) {
 	})
 }
 
+type fakeRoutingPreviewer struct {
+	settings []models.NotificationSettings
+	alerts   []prometheusModel.LabelSet
+	silences []*models.Silence
+	routing  []notifier.AlertRouting
+}
+
+func (f *fakeRoutingPreviewer) GetRoutingConfiguration(_ context.Context, _ int64, settings []models.NotificationSettings) (*definitions.PostableApiAlertingConfig, error) {
+	f.settings = settings
+	return &definitions.PostableApiAlertingConfig{Config: definitions.Config{Route: &definitions.Route{Receiver: "default"}}}, nil
+}
+
+func (f *fakeRoutingPreviewer) PreviewRouting(_ context.Context, _ int64, settings []models.NotificationSettings, alerts []prometheusModel.LabelSet, silences []*models.Silence, _ time.Time) ([]notifier.AlertRouting, error) {
+	f.settings = settings
+	f.alerts = alerts
+	f.silences = silences
+	return f.routing, nil
+}
+
+// fakeSilenceLister returns the silences the user can read.
+type fakeSilenceLister struct {
+	user     identity.Requester
+	silences []*models.Silence
+}
+
+func (f *fakeSilenceLister) ListSilences(_ context.Context, user identity.Requester, _ []string) ([]*models.Silence, error) {
+	f.user = user
+	return f.silences, nil
+}
+
+func TestRouteDryRunGrafanaRuleConfig(t *testing.T) {
+	rc := &contextmodel.ReqContext{
+		Context: &web.Context{
+			Req: &http.Request{},
+		},
+		SignedInUser: &user.SignedInUser{
+			OrgID: 1,
+		},
+	}
+
+	query := models.RuleGen.GenerateQuery()
+	permissions := acMock.New().WithPermissions([]ac.Permission{
+		{Action: datasources.ActionQuery, Scope: datasources.ScopeProvider.GetResourceScopeUID(query.DatasourceUID)},
+	})
+	ds := &fakes.FakeCacheService{DataSources: []*datasources.DataSource{{UID: query.DatasourceUID}}}
+
+	evaluator := &eval_mocks.ConditionEvaluatorMock{}
+	evaluator.EXPECT().Evaluate(mock.Anything, mock.Anything).Return(eval.Results{
+		{Instance: data.Labels{"instance": "db-1"}, State: eval.Alerting, EvaluatedAt: time.Now()},
+	}, nil)
+
+	f := randFolder()
+	ruleStore := fakes2.NewRuleStore(t)
+	ruleStore.Folders[rc.OrgID] = []*folder.Folder{f}
+
+	srv := createTestingApiSrv(t, ds, permissions, eval_mocks.NewEvaluatorFactory(evaluator), featuremgmt.WithFeatures(), ruleStore)
+	previewer := &fakeRoutingPreviewer{routing: []notifier.AlertRouting{{
+		Routes: []notifier.RouteMatch{{
+			RouteID:     "{}/{team=\"db\"}/0",
+			Matchers:    []string{`team="db"`},
+			Receiver:    "db",
+			GroupKey:    `{}/{team="db"}:{instance="db-1"}`,
+			GroupLabels: prometheusModel.LabelSet{"instance": "db-1"},
+			GroupWait:   30 * time.Second,
+			MutedBy:     []string{"weekends"},
+		}},
+		SilencedBy: []string{"silence-1"},
+	}}}
+	srv.routing = previewer
+	readable := models.SilenceGen()()
+	silences := &fakeSilenceLister{silences: []*models.Silence{&readable}}
+	srv.silences = silences
+
+	rule := validRule()
+	rule.GrafanaManagedAlert.Data = ApiAlertQueriesFromAlertQueries([]models.AlertQuery{query})
+	rule.GrafanaManagedAlert.Condition = query.RefID
+	rule.GrafanaManagedAlert.NotificationSettings = nil
+	forDuration := prometheusModel.Duration(0)
+	rule.ApiRuleNode.For = &forDuration
+	rule.ApiRuleNode.Labels = map[string]string{"team": "db"}
+	rule.ApiRuleNode.Annotations = map[string]string{"summary": "{{ $labels.instance }} is down"}
+	response := srv.RouteDryRunGrafanaRuleConfig(rc, definitions.PostableExtendedRuleNodeExtended{
+		Rule:           rule,
+		NamespaceUID:   f.UID,
+		NamespaceTitle: f.Title,
+	})
+	require.Equal(t, http.StatusOK, response.Status())
+
+	result := definitions.RuleDryRunResult{}
+	require.NoError(t, json.Unmarshal(response.Body(), &result))
+	require.Len(t, result.Instances, 1)
+	instance := result.Instances[0]
+	require.Equal(t, eval.Alerting.String(), instance.State)
+	require.Equal(t, "db", instance.Labels["team"])
+	require.Equal(t, "db-1", instance.Labels["instance"])
+	require.Equal(t, "db-1 is down", instance.Annotations["summary"])
+	require.Equal(t, []definitions.RuleDryRunRoute{{
+		RouteID:     "{}/{team=\"db\"}/0",
+		Matchers:    []string{`team="db"`},
+		Receiver:    "db",
+		GroupKey:    `{}/{team="db"}:{instance="db-1"}`,
+		GroupLabels: map[string]string{"instance": "db-1"},
+		GroupWait:   prometheusModel.Duration(30 * time.Second),
+		MutedBy:     []string{"weekends"},
+	}}, instance.Routes)
+	require.Equal(t, []string{"silence-1"}, instance.SilencedBy)
+
+	require.Len(t, previewer.alerts, 1)
+	require.Equal(t, prometheusModel.LabelValue("db-1"), previewer.alerts[0]["instance"])
+	require.Equal(t, prometheusModel.LabelValue("db"), previewer.alerts[0]["team"])
+	require.Equal(t, rc.SignedInUser, silences.user)
+	require.Equal(t, []*models.Silence{&readable}, previewer.silences, "only the silences the user can read should be matched")
+}
+
+func TestBacktestAlertRuleReport(t *testing.T) {
+	rc := &contextmodel.ReqContext{
+		Context: &web.Context{
+			Req: &http.Request{},
+		},
+		SignedInUser: &user.SignedInUser{
+			OrgID: 1,
+		},
+	}
+
+	query := models.RuleGen.GenerateQuery()
+	permissions := acMock.New().WithPermissions([]ac.Permission{
+		{Action: datasources.ActionQuery, Scope: datasources.ScopeProvider.GetResourceScopeUID(query.DatasourceUID)},
+	})
+	ds := &fakes.FakeCacheService{DataSources: []*datasources.DataSource{{UID: query.DatasourceUID}}}
+
+	evaluator := &eval_mocks.ConditionEvaluatorMock{}
+	evaluator.EXPECT().Evaluate(mock.Anything, mock.Anything).Return(eval.Results{
+		{Instance: data.Labels{"instance": "db-1"}, State: eval.Alerting, EvaluatedAt: time.Now()},
+	}, nil)
+	factory := eval_mocks.NewEvaluatorFactory(evaluator)
+
+	from := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cmd := definitions.BacktestReportConfig{
+		BacktestConfig: definitions.BacktestConfig{
+			From:        from,
+			To:          from.Add(30 * time.Minute),
+			Interval:    prometheusModel.Duration(time.Minute),
+			Condition:   query.RefID,
+			Data:        ApiAlertQueriesFromAlertQueries([]models.AlertQuery{query}),
+			Title:       "test",
+			NoDataState: definitions.NoData,
+		},
+	}
+
+	t.Run("should return 404 if backtesting is disabled", func(t *testing.T) {
+		srv := createTestingApiSrv(t, ds, permissions, factory, featuremgmt.WithFeatures(), fakes2.NewRuleStore(t))
+
+		response := srv.BacktestAlertRuleReport(rc, cmd)
+		require.Equal(t, http.StatusNotFound, response.Status())
+	})
+
+	t.Run("should return 403 if user cannot query the data source", func(t *testing.T) {
+		srv := createTestingApiSrv(t, ds, acMock.New(), factory, featuremgmt.WithFeatures(featuremgmt.FlagAlertingBacktesting), fakes2.NewRuleStore(t))
+		srv.backtesting = backtesting.NewEngine(nil, factory, tracing.InitializeTracerForTest())
+		srv.cfg.BaseInterval = time.Minute
+
+		response := srv.BacktestAlertRuleReport(rc, cmd)
+		require.Equal(t, http.StatusForbidden, response.Status())
+	})
+
+	t.Run("should report transitions and simulated notifications", func(t *testing.T) {
+		srv := createTestingApiSrv(t, ds, permissions, factory, featuremgmt.WithFeatures(featuremgmt.FlagAlertingBacktesting), fakes2.NewRuleStore(t))
+		srv.backtesting = backtesting.NewEngine(nil, factory, tracing.InitializeTracerForTest())
+		srv.cfg.BaseInterval = time.Minute
+		srv.routing = &fakeRoutingPreviewer{}
+
+		cmd := cmd
+		cmd.SimulateNotifications = true
+		response := srv.BacktestAlertRuleReport(rc, cmd)
+		require.Equal(t, http.StatusOK, response.Status())
+
+		report := definitions.BacktestReport{}
+		require.NoError(t, json.Unmarshal(response.Body(), &report))
+		require.Equal(t, 30, report.Evaluations)
+		require.Len(t, report.Transitions, 1)
+		require.Equal(t, "Alerting", report.Transitions[0].To)
+		require.Equal(t, "test", report.Transitions[0].Labels[prometheusModel.AlertNameLabel])
+		require.Equal(t, "db-1", report.Transitions[0].Labels["instance"])
+		require.Equal(t, []definitions.BacktestReceiverSummary{{Receiver: "default", Notifications: 1, Groups: 1}}, report.Receivers)
+	})
+
+	t.Run("should add the folder label if folder is specified", func(t *testing.T) {
+		f := randFolder()
+		ruleStore := fakes2.NewRuleStore(t)
+		ruleStore.Folders[rc.OrgID] = []*folder.Folder{f}
+		srv := createTestingApiSrv(t, ds, permissions, factory, featuremgmt.WithFeatures(featuremgmt.FlagAlertingBacktesting), ruleStore)
+		srv.backtesting = backtesting.NewEngine(nil, factory, tracing.InitializeTracerForTest())
+		srv.cfg.BaseInterval = time.Minute
+
+		cmd := cmd
+		cmd.NamespaceUID = f.UID
+		response := srv.BacktestAlertRuleReport(rc, cmd)
+		require.Equal(t, http.StatusOK, response.Status())
+
+		report := definitions.BacktestReport{}
+		require.NoError(t, json.Unmarshal(response.Body(), &report))
+		require.Len(t, report.Transitions, 1)
+		require.Equal(t, f.Fullpath, report.Transitions[0].Labels[models.FolderTitleLabel])
+
+		cmd.NamespaceUID = "unknown"
+		response = srv.BacktestAlertRuleReport(rc, cmd)
+		require.Equal(t, http.StatusForbidden, response.Status())
+	})
+}
+
+func TestBacktestCompareRuleVersions(t *testing.T) {
+	rc := &contextmodel.ReqContext{
+		Context: &web.Context{
+			Req: &http.Request{},
+		},
+		SignedInUser: &user.SignedInUser{
+			OrgID: 1,
+		},
+	}
+
+	f := randFolder()
+	query := models.RuleGen.GenerateQuery()
+	permissions := acMock.New().WithPermissions([]ac.Permission{
+		{Action: datasources.ActionQuery, Scope: datasources.ScopeProvider.GetResourceScopeUID(query.DatasourceUID)},
+		{Action: ac.ActionAlertingRuleRead, Scope: dashboards.ScopeFoldersProvider.GetResourceScopeUID(f.UID)},
+		{Action: dashboards.ActionFoldersRead, Scope: dashboards.ScopeFoldersProvider.GetResourceScopeUID(f.UID)},
+	})
+	ds := &fakes.FakeCacheService{DataSources: []*datasources.DataSource{{UID: query.DatasourceUID}}}
+
+	evaluator := &eval_mocks.ConditionEvaluatorMock{}
+	evaluator.EXPECT().Evaluate(mock.Anything, mock.Anything).Return(eval.Results{
+		{Instance: data.Labels{"instance": "db-1"}, State: eval.Alerting, EvaluatedAt: time.Now()},
+	}, nil)
+	factory := eval_mocks.NewEvaluatorFactory(evaluator)
+
+	// the previous version fires immediately, the current one keeps the alert pending for the whole interval.
+	gen := models.RuleGen.With(
+		models.RuleGen.WithOrgID(rc.OrgID),
+		models.RuleGen.WithNamespaceUID(f.UID),
+		models.RuleGen.WithQuery(query),
+		models.RuleGen.WithIntervalSeconds(60),
+		models.RuleGen.WithNoNotificationSettings(),
+	)
+	current := gen.With(models.RuleGen.WithFor(time.Hour)).GenerateRef()
+	current.Version = 2
+	previous := models.CopyRule(current)
+	previous.Version = 1
+	previous.For = 0
+
+	ruleStore := fakes2.NewRuleStore(t)
+	ruleStore.Folders[rc.OrgID] = []*folder.Folder{f}
+	ruleStore.PutRule(context.Background(), current)
+	ruleStore.RuleVersions = map[int64][]*models.AlertRule{rc.OrgID: {previous}}
+
+	from := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cmd := definitions.BacktestCompareConfig{
+		RuleUID:               current.UID,
+		From:                  from,
+		To:                    from.Add(30 * time.Minute),
+		SimulateNotifications: true,
+	}
+
+	createSrv := func(t *testing.T, permissions *acMock.Mock) *TestingApiSrv {
+		srv := createTestingApiSrv(t, ds, permissions, factory, featuremgmt.WithFeatures(featuremgmt.FlagAlertingBacktesting), ruleStore)
+		srv.backtesting = backtesting.NewEngine(nil, factory, tracing.InitializeTracerForTest())
+		srv.routing = &fakeRoutingPreviewer{}
+		srv.ruleStore = ruleStore
+		return srv
+	}
+
+	t.Run("should compare the current version with the previous one", func(t *testing.T) {
+		response := createSrv(t, permissions).BacktestCompareRuleVersions(rc, cmd)
+		require.Equal(t, http.StatusOK, response.Status())
+
+		diff := definitions.BacktestReportDiff{}
+		require.NoError(t, json.Unmarshal(response.Body(), &diff))
+		require.Len(t, diff.Old.Transitions, 1)
+		require.Equal(t, "Alerting", diff.Old.Transitions[0].To)
+		require.Len(t, diff.New.Transitions, 1)
+		require.Equal(t, "Pending", diff.New.Transitions[0].To)
+		require.Equal(t, f.Fullpath, diff.New.Transitions[0].Labels[models.FolderTitleLabel])
+		require.Equal(t, -1, diff.NotificationsDelta)
+		require.Equal(t, []definitions.BacktestReceiverDiff{{
+			Receiver:           "default",
+			Old:                definitions.BacktestReceiverSummary{Receiver: "default", Notifications: 1, Groups: 1},
+			New:                definitions.BacktestReceiverSummary{Receiver: "default"},
+			NotificationsDelta: -1,
+		}}, diff.Receivers)
+	})
+
+	t.Run("should return 404 if the rule does not exist", func(t *testing.T) {
+		cmd := cmd
+		cmd.RuleUID = "unknown"
+		response := createSrv(t, permissions).BacktestCompareRuleVersions(rc, cmd)
+		require.Equal(t, http.StatusNotFound, response.Status())
+	})
+
+	t.Run("should return 404 if the version does not exist", func(t *testing.T) {
+		ruleStore.RuleVersions = nil
+		t.Cleanup(func() {
+			ruleStore.RuleVersions = map[int64][]*models.AlertRule{rc.OrgID: {previous}}
+		})
+		response := createSrv(t, permissions).BacktestCompareRuleVersions(rc, cmd)
+		require.Equal(t, http.StatusNotFound, response.Status())
+	})
+
+	t.Run("should return 400 if versions are invalid", func(t *testing.T) {
+		cmd := cmd
+		cmd.OldVersion = 2
+		cmd.NewVersion = 1
+		response := createSrv(t, permissions).BacktestCompareRuleVersions(rc, cmd)
+		require.Equal(t, http.StatusBadRequest, response.Status())
+	})
+
+	t.Run("should return 403 if user cannot read rules in the folder", func(t *testing.T) {
+		permissions := acMock.New().WithPermissions([]ac.Permission{
+			{Action: datasources.ActionQuery, Scope: datasources.ScopeProvider.GetResourceScopeUID(query.DatasourceUID)},
+		})
+		response := createSrv(t, permissions).BacktestCompareRuleVersions(rc, cmd)
+		require.Equal(t, http.StatusForbidden, response.Status())
+	})
+
+	t.Run("should return 403 if user cannot read rules in the folder of a previous version", func(t *testing.T) {
+		moved := models.CopyRule(previous)
+		moved.NamespaceUID = randFolder().UID
+		ruleStore.RuleVersions = map[int64][]*models.AlertRule{rc.OrgID: {moved}}
+		t.Cleanup(func() {
+			ruleStore.RuleVersions = map[int64][]*models.AlertRule{rc.OrgID: {previous}}
+		})
+		response := createSrv(t, permissions).BacktestCompareRuleVersions(rc, cmd)
+		require.Equal(t, http.StatusForbidden, response.Status())
+	})
+}
+
 func TestRouteEvalQueries(t *testing.T) {
 	t.Run("when fine-grained access is enabled", func(t *testing.T) {
 		rc := &contextmodel.ReqContext{
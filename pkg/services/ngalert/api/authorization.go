@@ -38,6 +38,8 @@ func (api *API) authorize(method, path string) web.Handler {
 			ac.EvalPermission(dashboards.ActionFoldersRead, dashboards.ScopeFoldersProvider.GetResourceScopeUID(ac.Parameter(":Namespace"))),
 		)
 	case http.MethodGet + "/api/ruler/grafana/api/v1/rules",
+		http.MethodGet + "/api/ruler/grafana/api/v1/dependencies",
+		http.MethodGet + "/api/ruler/grafana/api/v1/lint",
 		http.MethodGet + "/api/ruler/grafana/api/v1/export/rules":
 		eval = ac.EvalPermission(ac.ActionAlertingRuleRead)
 	case http.MethodGet + "/api/ruler/grafana/api/v1/rule/{RuleUID}":
@@ -45,7 +47,8 @@ func (api *API) authorize(method, path string) web.Handler {
 			ac.EvalPermission(ac.ActionAlertingRuleRead),
 			ac.EvalPermission(dashboards.ActionFoldersRead),
 		)
-	case http.MethodPost + "/api/ruler/grafana/api/v1/rules/{Namespace}/export":
+	case http.MethodPost + "/api/ruler/grafana/api/v1/rules/{Namespace}/export",
+		http.MethodPost + "/api/ruler/grafana/api/v1/rules/{Namespace}/lint":
 		scope := dashboards.ScopeFoldersProvider.GetResourceScopeUID(ac.Parameter(":Namespace"))
 		// more granular permissions are enforced by the handler via "authorizeRuleChanges"
 		eval = ac.EvalAll(ac.EvalPermission(ac.ActionAlertingRuleRead, scope),
@@ -92,8 +95,16 @@ func (api *API) authorize(method, path string) web.Handler {
 	case http.MethodPost + "/api/v1/rule/test/grafana":
 		// additional authorization is done in the request handler
 		eval = ac.EvalPermission(ac.ActionAlertingRuleRead)
+	case http.MethodPost + "/api/v1/rule/dry-run/grafana":
+		// additional authorization is done in the request handler
+		eval = ac.EvalAll(
+			ac.EvalPermission(ac.ActionAlertingRuleRead),
+			ac.EvalPermission(ac.ActionAlertingNotificationsRead),
+		)
 	// Grafana Rules Testing Paths
-	case http.MethodPost + "/api/v1/rule/backtest":
+	case http.MethodPost + "/api/v1/rule/backtest",
+		http.MethodPost + "/api/v1/rule/backtest/report",
+		http.MethodPost + "/api/v1/rule/backtest/compare":
 		// additional authorization is done in the request handler
 		eval = ac.EvalPermission(ac.ActionAlertingRuleRead)
 	case http.MethodPost + "/api/v1/eval":
@@ -161,6 +172,37 @@ func (api *API) authorize(method, path string) web.Handler {
 			),
 		)
 
+	// Silence schedules for Grafana paths. They are authorized in the same way as the silences they create.
+	case http.MethodGet + "/api/alertmanager/grafana/api/v2/silence-schedule/{UID}",
+		http.MethodGet + "/api/alertmanager/grafana/api/v2/silence-schedules":
+		eval = ac.EvalAny(
+			ac.EvalPermission(ac.ActionAlertingInstanceRead),
+			ac.EvalPermission(ac.ActionAlertingSilencesRead),
+		)
+	case http.MethodPost + "/api/alertmanager/grafana/api/v2/silence-schedules":
+		eval = ac.EvalAll(
+			ac.EvalAny(
+				ac.EvalPermission(ac.ActionAlertingInstanceRead),
+				ac.EvalPermission(ac.ActionAlertingSilencesRead),
+			),
+			ac.EvalAny(
+				ac.EvalPermission(ac.ActionAlertingInstanceCreate),
+				ac.EvalPermission(ac.ActionAlertingSilencesCreate),
+			),
+		)
+	case http.MethodPut + "/api/alertmanager/grafana/api/v2/silence-schedule/{UID}",
+		http.MethodDelete + "/api/alertmanager/grafana/api/v2/silence-schedule/{UID}":
+		eval = ac.EvalAll(
+			ac.EvalAny(
+				ac.EvalPermission(ac.ActionAlertingInstanceRead),
+				ac.EvalPermission(ac.ActionAlertingSilencesRead),
+			),
+			ac.EvalAny(
+				ac.EvalPermission(ac.ActionAlertingInstanceUpdate),
+				ac.EvalPermission(ac.ActionAlertingSilencesWrite),
+			),
+		)
+
 	// Alert Instances. Grafana Paths
 	case http.MethodGet + "/api/alertmanager/grafana/api/v2/alerts/groups":
 		eval = ac.EvalPermission(ac.ActionAlertingInstanceRead)
@@ -361,6 +403,22 @@ func (api *API) authorize(method, path string) web.Handler {
 			),
 		)
 
+	case http.MethodPost + "/api/v1/provisioning/import":
+		// The import changes both alert rules and notification resources. Access to the folders of
+		// the rules is checked when the changes of each rule group are determined.
+		eval = ac.EvalAny(
+			ac.EvalPermission(ac.ActionAlertingProvisioningWrite),
+			ac.EvalAll(
+				ac.EvalPermission(ac.ActionAlertingRulesProvisioningWrite),
+				ac.EvalPermission(ac.ActionAlertingNotificationsProvisioningWrite),
+			),
+		)
+	case http.MethodPost + "/api/v1/provisioning/import/prometheus":
+		eval = ac.EvalAny(
+			ac.EvalPermission(ac.ActionAlertingProvisioningWrite),
+			ac.EvalPermission(ac.ActionAlertingRulesProvisioningWrite),
+		)
+
 	case http.MethodPut + "/api/v1/provisioning/policies",
 		http.MethodDelete + "/api/v1/provisioning/policies",
 		http.MethodPost + "/api/v1/provisioning/contact-points",
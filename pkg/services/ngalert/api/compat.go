@@ -460,10 +460,14 @@ func AlertRuleRecordExportFromRecord(r *models.Record) *definitions.AlertRuleRec
 	if r == nil {
 		return nil
 	}
-	return &definitions.AlertRuleRecordExport{
+	result := &definitions.AlertRuleRecordExport{
 		Metric: r.Metric,
 		From:   r.From,
 	}
+	if r.Target != "" {
+		result.Target = util.Pointer(string(r.Target))
+	}
+	return result
 }
 
 func ModelRecordFromApiRecord(r *definitions.Record) *models.Record {
@@ -473,6 +477,7 @@ func ModelRecordFromApiRecord(r *definitions.Record) *models.Record {
 	return &models.Record{
 		Metric: r.Metric,
 		From:   r.From,
+		Target: models.RecordTarget(r.Target),
 	}
 }
 
@@ -483,5 +488,6 @@ func ApiRecordFromModelRecord(r *models.Record) *definitions.Record {
 	return &definitions.Record{
 		Metric: r.Metric,
 		From:   r.From,
+		Target: string(r.Target),
 	}
 }
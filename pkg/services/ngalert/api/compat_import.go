@@ -0,0 +1,301 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/alerting/definition"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// AlertRuleGroupFromAlertRuleGroupExport converts definitions.AlertRuleGroupExport to models.AlertRuleGroup
+// that belongs to the folder with the given UID. It is the reverse of AlertRuleGroupExportFromAlertRuleGroupWithFolderFullpath.
+func AlertRuleGroupFromAlertRuleGroupExport(orgID int64, folderUID string, g definitions.AlertRuleGroupExport) (models.AlertRuleGroup, error) {
+	interval := int64(time.Duration(g.Interval).Seconds())
+	if interval == 0 {
+		interval = g.IntervalSeconds
+	}
+	result := models.AlertRuleGroup{
+		Title:     g.Name,
+		FolderUID: folderUID,
+		Interval:  interval,
+		Rules:     make([]models.AlertRule, 0, len(g.Rules)),
+	}
+	for _, r := range g.Rules {
+		rule, err := AlertRuleFromAlertRuleExport(r)
+		if err != nil {
+			return models.AlertRuleGroup{}, fmt.Errorf("invalid rule '%s': %w", r.Title, err)
+		}
+		rule.OrgID = orgID
+		rule.NamespaceUID = folderUID
+		rule.RuleGroup = g.Name
+		rule.IntervalSeconds = interval
+		result.Rules = append(result.Rules, rule)
+	}
+	return result, nil
+}
+
+// AlertRuleFromAlertRuleExport converts definitions.AlertRuleExport to models.AlertRule. It is the reverse of AlertRuleExportFromAlertRule.
+func AlertRuleFromAlertRuleExport(rule definitions.AlertRuleExport) (models.AlertRule, error) {
+	data := make([]models.AlertQuery, 0, len(rule.Data))
+	for _, d := range rule.Data {
+		query, err := AlertQueryFromAlertQueryExport(d)
+		if err != nil {
+			return models.AlertRule{}, fmt.Errorf("invalid query '%s': %w", d.RefID, err)
+		}
+		data = append(data, query)
+	}
+	forDuration := time.Duration(rule.For)
+	if forDuration == 0 && rule.ForString != nil {
+		d, err := model.ParseDuration(*rule.ForString)
+		if err != nil {
+			return models.AlertRule{}, fmt.Errorf("invalid for: %w", err)
+		}
+		forDuration = time.Duration(d)
+	}
+	ns, err := NotificationSettingsFromAlertRuleNotificationSettingsExport(rule.NotificationSettings)
+	if err != nil {
+		return models.AlertRule{}, err
+	}
+	result := models.AlertRule{
+		UID:                  rule.UID,
+		Title:                rule.Title,
+		Condition:            rule.Condition,
+		Data:                 data,
+		DashboardUID:         rule.DashboardUID,
+		PanelID:              rule.PanelID,
+		NoDataState:          models.NoDataState(rule.NoDataState),
+		ExecErrState:         models.ExecutionErrorState(rule.ExecErrState),
+		For:                  forDuration,
+		IsPaused:             rule.IsPaused,
+		NotificationSettings: ns,
+		Record:               ModelRecordFromAlertRuleRecordExport(rule.Record),
+	}
+	if rule.Annotations != nil {
+		result.Annotations = *rule.Annotations
+	}
+	if rule.Labels != nil {
+		result.Labels = *rule.Labels
+	}
+	return result, nil
+}
+
+// AlertQueryFromAlertQueryExport converts definitions.AlertQueryExport to models.AlertQuery. The model is taken from
+// ModelString if Model is empty, which is the case for HCL documents.
+func AlertQueryFromAlertQueryExport(query definitions.AlertQueryExport) (models.AlertQuery, error) {
+	var mdl json.RawMessage
+	if query.Model != nil {
+		m, err := json.Marshal(query.Model)
+		if err != nil {
+			return models.AlertQuery{}, err
+		}
+		mdl = m
+	} else if query.ModelString != "" {
+		if !json.Valid([]byte(query.ModelString)) {
+			return models.AlertQuery{}, errors.New("model is not a valid JSON")
+		}
+		mdl = json.RawMessage(query.ModelString)
+	}
+	result := models.AlertQuery{
+		RefID: query.RefID,
+		RelativeTimeRange: models.RelativeTimeRange{
+			From: models.Duration(time.Duration(query.RelativeTimeRange.FromSeconds) * time.Second),
+			To:   models.Duration(time.Duration(query.RelativeTimeRange.ToSeconds) * time.Second),
+		},
+		DatasourceUID: query.DatasourceUID,
+		Model:         mdl,
+	}
+	if query.QueryType != nil {
+		result.QueryType = *query.QueryType
+	}
+	return result, nil
+}
+
+// NotificationSettingsFromAlertRuleNotificationSettingsExport converts definitions.AlertRuleNotificationSettingsExport to []models.NotificationSettings
+func NotificationSettingsFromAlertRuleNotificationSettingsExport(ns *definitions.AlertRuleNotificationSettingsExport) ([]models.NotificationSettings, error) {
+	if ns == nil {
+		return nil, nil
+	}
+	var errs []error
+	parse := func(field string, s *string) *model.Duration {
+		if s == nil {
+			return nil
+		}
+		d, err := model.ParseDuration(*s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s of notification settings: %w", field, err))
+			return nil
+		}
+		return &d
+	}
+	result := models.NotificationSettings{
+		Receiver:          ns.Receiver,
+		GroupBy:           ns.GroupBy,
+		GroupWait:         parse("group_wait", ns.GroupWait),
+		GroupInterval:     parse("group_interval", ns.GroupInterval),
+		RepeatInterval:    parse("repeat_interval", ns.RepeatInterval),
+		MuteTimeIntervals: ns.MuteTimeIntervals,
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return []models.NotificationSettings{result}, nil
+}
+
+// ModelRecordFromAlertRuleRecordExport converts definitions.AlertRuleRecordExport to models.Record
+func ModelRecordFromAlertRuleRecordExport(r *definitions.AlertRuleRecordExport) *models.Record {
+	if r == nil {
+		return nil
+	}
+	result := &models.Record{
+		Metric: r.Metric,
+		From:   r.From,
+	}
+	if r.Target != nil {
+		result.Target = models.RecordTarget(*r.Target)
+	}
+	return result
+}
+
+// EmbeddedContactPointsFromContactPointExport converts definitions.ContactPointExport to the integrations of the contact point.
+// It is the reverse of AlertingFileExportFromEmbeddedContactPoints.
+func EmbeddedContactPointsFromContactPointExport(cp definitions.ContactPointExport) ([]definitions.EmbeddedContactPoint, error) {
+	result := make([]definitions.EmbeddedContactPoint, 0, len(cp.Receivers))
+	for _, r := range cp.Receivers {
+		settings, err := simplejson.NewJson(r.Settings)
+		if err != nil {
+			return nil, fmt.Errorf("invalid settings of %s integration (uid:%s): %w", r.Type, r.UID, err)
+		}
+		result = append(result, definitions.EmbeddedContactPoint{
+			UID:                   r.UID,
+			Name:                  cp.Name,
+			Type:                  r.Type,
+			Settings:              settings,
+			DisableResolveMessage: r.DisableResolveMessage,
+		})
+	}
+	return result, nil
+}
+
+// ContactPointExportFromContactPoint converts the strongly typed definitions.ContactPoint of HCL documents
+// to definitions.ContactPointExport. It is the reverse of ContactPointFromContactPointExport.
+func ContactPointExportFromContactPoint(orgID int64, cp definitions.ContactPoint) (definitions.ContactPointExport, error) {
+	receiver, err := ContactPointToContactPointExport(cp)
+	if err != nil {
+		return definitions.ContactPointExport{}, err
+	}
+	result := definitions.ContactPointExport{
+		OrgID:     orgID,
+		Name:      cp.Name,
+		Receivers: make([]definitions.ReceiverExport, 0, len(receiver.Integrations)),
+	}
+	for _, integration := range receiver.Integrations {
+		result.Receivers = append(result.Receivers, definitions.ReceiverExport{
+			UID:                   integration.UID,
+			Type:                  integration.Type,
+			Settings:              definitions.RawMessage(integration.Settings),
+			DisableResolveMessage: integration.DisableResolveMessage,
+		})
+	}
+	return result, nil
+}
+
+// RouteFromRouteExport converts definitions.RouteExport to definitions.Route. It is the reverse of RouteExportFromRoute.
+// Matchers of HCL documents are converted to object matchers.
+func RouteFromRouteExport(export *definitions.RouteExport) (*definitions.Route, error) {
+	parse := func(s *string) (*model.Duration, error) {
+		if s == nil {
+			return nil, nil
+		}
+		d, err := model.ParseDuration(*s)
+		if err != nil {
+			return nil, err
+		}
+		return &d, nil
+	}
+
+	route := definitions.Route{
+		Receiver:       export.Receiver,
+		Match:          export.Match,
+		MatchRE:        export.MatchRE,
+		Matchers:       export.Matchers,
+		ObjectMatchers: export.ObjectMatchers,
+	}
+	if export.GroupByStr != nil {
+		route.GroupByStr = *export.GroupByStr
+	}
+	if export.MuteTimeIntervals != nil {
+		route.MuteTimeIntervals = *export.MuteTimeIntervals
+	}
+	if export.Continue != nil {
+		route.Continue = *export.Continue
+	}
+	var err error
+	if route.GroupWait, err = parse(export.GroupWait); err != nil {
+		return nil, fmt.Errorf("invalid group_wait: %w", err)
+	}
+	if route.GroupInterval, err = parse(export.GroupInterval); err != nil {
+		return nil, fmt.Errorf("invalid group_interval: %w", err)
+	}
+	if route.RepeatInterval, err = parse(export.RepeatInterval); err != nil {
+		return nil, fmt.Errorf("invalid repeat_interval: %w", err)
+	}
+
+	if len(route.ObjectMatchers) == 0 && len(export.ObjectMatchersSlice) > 0 {
+		route.ObjectMatchers = make(definition.ObjectMatchers, 0, len(export.ObjectMatchersSlice))
+		for _, m := range export.ObjectMatchersSlice {
+			matcher, err := matcherFromMatcherExport(m)
+			if err != nil {
+				return nil, err
+			}
+			route.ObjectMatchers = append(route.ObjectMatchers, matcher)
+		}
+	}
+
+	for _, r := range export.Routes {
+		child, err := RouteFromRouteExport(r)
+		if err != nil {
+			return nil, err
+		}
+		route.Routes = append(route.Routes, child)
+	}
+	return &route, nil
+}
+
+func matcherFromMatcherExport(m *definitions.MatcherExport) (*labels.Matcher, error) {
+	var matchType labels.MatchType
+	switch m.Match {
+	case labels.MatchEqual.String():
+		matchType = labels.MatchEqual
+	case labels.MatchNotEqual.String():
+		matchType = labels.MatchNotEqual
+	case labels.MatchRegexp.String():
+		matchType = labels.MatchRegexp
+	case labels.MatchNotRegexp.String():
+		matchType = labels.MatchNotRegexp
+	default:
+		return nil, fmt.Errorf("invalid match type '%s' of matcher for label '%s'", m.Match, m.Label)
+	}
+	return labels.NewMatcher(matchType, m.Label, m.Value)
+}
+
+// MuteTimeIntervalExportFromMuteTimeIntervalExportHcl converts definitions.MuteTimeIntervalExportHcl to definitions.MuteTimeIntervalExport using JSON marshalling.
+// It is the reverse of MuteTimingIntervalToMuteTimeIntervalHclExport.
+func MuteTimeIntervalExportFromMuteTimeIntervalExportHcl(orgID int64, m definitions.MuteTimeIntervalExportHcl) (definitions.MuteTimeIntervalExport, error) {
+	result := definitions.MuteTimeIntervalExport{OrgID: orgID}
+	j := jsoniter.ConfigCompatibleWithStandardLibrary
+	mdata, err := j.Marshal(m)
+	if err != nil {
+		return result, err
+	}
+	err = j.Unmarshal(mdata, &result)
+	return result, err
+}
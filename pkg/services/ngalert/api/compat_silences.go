@@ -2,6 +2,9 @@ package api
 
 import (
 	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
 
 	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
@@ -15,15 +18,7 @@ func SilenceToGettableGrafanaSilence(s *models.SilenceWithMetadata) definitions.
 	}
 
 	if s.Metadata.Permissions != nil {
-		gettable.Permissions = make(map[definitions.SilencePermission]bool, len(*s.Metadata.Permissions))
-		for _, permission := range models.SilencePermissions() {
-			p, err := SilencePermissionToAPI(permission)
-			if err != nil {
-				// Skip unknown permissions in response.
-				continue
-			}
-			gettable.Permissions[p] = s.Metadata.Permissions.Has(permission)
-		}
+		gettable.Permissions = silencePermissionSetToAPI(*s.Metadata.Permissions)
 	}
 
 	if s.Metadata.RuleMetadata != nil {
@@ -55,6 +50,74 @@ func PostableSilenceToSilence(s definitions.PostableSilence) models.Silence {
 	}
 }
 
+func silencePermissionSetToAPI(permissions models.SilencePermissionSet) map[definitions.SilencePermission]bool {
+	res := make(map[definitions.SilencePermission]bool, len(permissions))
+	for _, permission := range models.SilencePermissions() {
+		p, err := SilencePermissionToAPI(permission)
+		if err != nil {
+			// Skip unknown permissions in response.
+			continue
+		}
+		res[p] = permissions.Has(permission)
+	}
+	return res
+}
+
+func SilenceScheduleToGettableSilenceSchedule(s *models.SilenceScheduleWithMetadata) definitions.GettableSilenceSchedule {
+	gettable := definitions.GettableSilenceSchedule{
+		UID: s.UID,
+		PostableSilenceSchedule: definitions.PostableSilenceSchedule{
+			Title:         s.Title,
+			Comment:       s.Comment,
+			Matchers:      s.Matchers,
+			Cron:          s.Cron,
+			Duration:      model.Duration(s.Duration),
+			Location:      s.Location,
+			TimeIntervals: s.TimeIntervals,
+			IsPaused:      s.IsPaused,
+			Version:       s.Version,
+		},
+		Silences:  make([]definitions.ScheduledSilence, 0, len(s.Silences)),
+		CreatedBy: s.CreatedBy,
+		Created:   s.Created,
+		UpdatedBy: s.UpdatedBy,
+		Updated:   s.Updated,
+	}
+	for _, silence := range s.Silences {
+		gettable.Silences = append(gettable.Silences, definitions.ScheduledSilence{
+			ID:       silence.ID,
+			StartsAt: silence.StartsAt,
+			EndsAt:   silence.EndsAt,
+		})
+	}
+	if s.Permissions != nil {
+		gettable.Permissions = silencePermissionSetToAPI(*s.Permissions)
+	}
+	return gettable
+}
+
+func SilenceSchedulesToGettableSilenceSchedules(schedules []*models.SilenceScheduleWithMetadata) definitions.GettableSilenceSchedules {
+	res := make(definitions.GettableSilenceSchedules, 0, len(schedules))
+	for _, schedule := range schedules {
+		res = append(res, SilenceScheduleToGettableSilenceSchedule(schedule))
+	}
+	return res
+}
+
+func PostableSilenceScheduleToSilenceSchedule(s definitions.PostableSilenceSchedule) models.SilenceSchedule {
+	return models.SilenceSchedule{
+		Title:         s.Title,
+		Comment:       s.Comment,
+		Matchers:      s.Matchers,
+		Cron:          s.Cron,
+		Duration:      time.Duration(s.Duration),
+		Location:      s.Location,
+		TimeIntervals: s.TimeIntervals,
+		IsPaused:      s.IsPaused,
+		Version:       s.Version,
+	}
+}
+
 func SilencePermissionToAPI(p models.SilencePermission) (definitions.SilencePermission, error) {
 	switch p {
 	case models.SilencePermissionRead:
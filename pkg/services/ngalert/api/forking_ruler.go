@@ -113,6 +113,22 @@ func (f *RulerApiHandler) handleRoutePostRulesGroupForExport(ctx *contextmodel.R
 	return f.GrafanaRuler.ExportFromPayload(ctx, conf, namespace)
 }
 
+func (f *RulerApiHandler) handleRouteGetRuleDependencies(ctx *contextmodel.ReqContext) response.Response {
+	return f.GrafanaRuler.RouteGetRuleDependencies(ctx)
+}
+
+func (f *RulerApiHandler) handleRouteGetRuleLintReport(ctx *contextmodel.ReqContext) response.Response {
+	return f.GrafanaRuler.RouteGetRuleLintReport(ctx)
+}
+
+func (f *RulerApiHandler) handleRoutePostRulesGroupForLint(ctx *contextmodel.ReqContext, conf apimodels.PostableRuleGroupConfig, namespace string) response.Response {
+	payloadType := conf.Type()
+	if payloadType != apimodels.GrafanaBackend {
+		return errorToResponse(backendTypeDoesNotMatchPayloadTypeError(apimodels.GrafanaBackend, conf.Type().String()))
+	}
+	return f.GrafanaRuler.RoutePostRulesGroupForLint(ctx, conf, namespace)
+}
+
 func (f *RulerApiHandler) handleRouteGetRulesForExport(ctx *contextmodel.ReqContext) response.Response {
 	return f.GrafanaRuler.ExportRules(ctx)
 }
@@ -20,12 +20,23 @@ import (
 )
 
 type TestingApi interface {
+	BacktestCompareConfig(*contextmodel.ReqContext) response.Response
 	BacktestConfig(*contextmodel.ReqContext) response.Response
+	BacktestReportConfig(*contextmodel.ReqContext) response.Response
+	RouteDryRunRuleGrafanaConfig(*contextmodel.ReqContext) response.Response
 	RouteEvalQueries(*contextmodel.ReqContext) response.Response
 	RouteTestRuleConfig(*contextmodel.ReqContext) response.Response
 	RouteTestRuleGrafanaConfig(*contextmodel.ReqContext) response.Response
 }
 
+func (f *TestingApiHandler) BacktestCompareConfig(ctx *contextmodel.ReqContext) response.Response {
+	// Parse Request Body
+	conf := apimodels.BacktestCompareConfig{}
+	if err := web.Bind(ctx.Req, &conf); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	return f.handleBacktestCompareConfig(ctx, conf)
+}
 func (f *TestingApiHandler) BacktestConfig(ctx *contextmodel.ReqContext) response.Response {
 	// Parse Request Body
 	conf := apimodels.BacktestConfig{}
@@ -34,6 +45,22 @@ func (f *TestingApiHandler) BacktestConfig(ctx *contextmodel.ReqContext) respons
 	}
 	return f.handleBacktestConfig(ctx, conf)
 }
+func (f *TestingApiHandler) BacktestReportConfig(ctx *contextmodel.ReqContext) response.Response {
+	// Parse Request Body
+	conf := apimodels.BacktestReportConfig{}
+	if err := web.Bind(ctx.Req, &conf); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	return f.handleBacktestReportConfig(ctx, conf)
+}
+func (f *TestingApiHandler) RouteDryRunRuleGrafanaConfig(ctx *contextmodel.ReqContext) response.Response {
+	// Parse Request Body
+	conf := apimodels.PostableExtendedRuleNodeExtended{}
+	if err := web.Bind(ctx.Req, &conf); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	return f.handleRouteDryRunRuleGrafanaConfig(ctx, conf)
+}
 func (f *TestingApiHandler) RouteEvalQueries(ctx *contextmodel.ReqContext) response.Response {
 	// Parse Request Body
 	conf := apimodels.EvalQueriesPayload{}
@@ -75,6 +102,42 @@ func (api *API) RegisterTestingApiEndpoints(srv TestingApi, m *metrics.API) {
 				m,
 			),
 		)
+		group.Post(
+			toMacaronPath("/api/v1/rule/backtest/compare"),
+			requestmeta.SetOwner(requestmeta.TeamAlerting),
+			requestmeta.SetSLOGroup(requestmeta.SLOGroupHighSlow),
+			api.authorize(http.MethodPost, "/api/v1/rule/backtest/compare"),
+			metrics.Instrument(
+				http.MethodPost,
+				"/api/v1/rule/backtest/compare",
+				api.Hooks.Wrap(srv.BacktestCompareConfig),
+				m,
+			),
+		)
+		group.Post(
+			toMacaronPath("/api/v1/rule/backtest/report"),
+			requestmeta.SetOwner(requestmeta.TeamAlerting),
+			requestmeta.SetSLOGroup(requestmeta.SLOGroupHighSlow),
+			api.authorize(http.MethodPost, "/api/v1/rule/backtest/report"),
+			metrics.Instrument(
+				http.MethodPost,
+				"/api/v1/rule/backtest/report",
+				api.Hooks.Wrap(srv.BacktestReportConfig),
+				m,
+			),
+		)
+		group.Post(
+			toMacaronPath("/api/v1/rule/dry-run/grafana"),
+			requestmeta.SetOwner(requestmeta.TeamAlerting),
+			requestmeta.SetSLOGroup(requestmeta.SLOGroupHighSlow),
+			api.authorize(http.MethodPost, "/api/v1/rule/dry-run/grafana"),
+			metrics.Instrument(
+				http.MethodPost,
+				"/api/v1/rule/dry-run/grafana",
+				api.Hooks.Wrap(srv.RouteDryRunRuleGrafanaConfig),
+				m,
+			),
+		)
 		group.Post(
 			toMacaronPath("/api/v1/eval"),
 			requestmeta.SetOwner(requestmeta.TeamAlerting),
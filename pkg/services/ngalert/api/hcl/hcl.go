@@ -1,10 +1,20 @@
 package hcl
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
 
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/mitchellh/mapstructure"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
 )
 
 type Resource struct {
@@ -28,3 +38,138 @@ func Encode(resources ...Resource) (data []byte, err error) {
 	}
 	return f.Bytes(), nil
 }
+
+// evalContext is the context of the expressions of decoded documents.
+// It provides the functions that Terraform configurations use to build attributes such as the model of queries.
+var evalContext = &hcl.EvalContext{
+	Functions: map[string]function.Function{
+		"jsonencode": stdlib.JSONEncodeFunc,
+		"jsondecode": stdlib.JSONDecodeFunc,
+	},
+}
+
+// Decode parses the resources of an HCL document, the reverse of Encode. The body of each resource is decoded
+// into the value that newBody returns for the type of the resource, which must be a pointer to a struct with hcl tags.
+// Unlike gohcl, attributes that are missing in the document are left empty, because Encode omits empty attributes.
+func Decode(data []byte, filename string, newBody func(resourceType string) (any, error)) ([]Resource, error) {
+	file, diags := hclsyntax.ParseConfig(data, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, errors.New("unexpected body of HCL document")
+	}
+	for _, attr := range body.Attributes {
+		return nil, fmt.Errorf("%s: unexpected attribute %q, only resource blocks are supported", attr.SrcRange, attr.Name)
+	}
+
+	resources := make([]Resource, 0, len(body.Blocks))
+	for _, blk := range body.Blocks {
+		if blk.Type != "resource" || len(blk.Labels) != 2 {
+			return nil, fmt.Errorf("%s: unexpected block %q, only resource blocks with type and name are supported", blk.DefRange(), blk.Type)
+		}
+		resource := Resource{Type: blk.Labels[0], Name: blk.Labels[1]}
+		target, err := newBody(resource.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", blk.DefRange(), err)
+		}
+		values, err := decodeBody(blk.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err := decodeValues(values, target); err != nil {
+			return nil, fmt.Errorf("%s: failed to decode resource %s.%s: %w", blk.DefRange(), resource.Type, resource.Name, err)
+		}
+		resource.Body = target
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// decodeBody returns the values of the attributes and nested blocks of the body. Nested blocks are lists of values
+// keyed by the type of the block.
+func decodeBody(body *hclsyntax.Body) (map[string]any, error) {
+	result := make(map[string]any, len(body.Attributes)+len(body.Blocks))
+	for name, attr := range body.Attributes {
+		v, diags := attr.Expr.Value(evalContext)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		value, err := ctyToGo(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid value of attribute %q: %w", attr.SrcRange, name, err)
+		}
+		result[name] = value
+	}
+	for _, blk := range body.Blocks {
+		if len(blk.Labels) > 0 {
+			return nil, fmt.Errorf("%s: unexpected labels of block %q", blk.DefRange(), blk.Type)
+		}
+		value, err := decodeBody(blk.Body)
+		if err != nil {
+			return nil, err
+		}
+		list, _ := result[blk.Type].([]any)
+		result[blk.Type] = append(list, value)
+	}
+	return result, nil
+}
+
+func ctyToGo(v cty.Value) (any, error) {
+	if v.IsNull() {
+		return nil, nil
+	}
+	if !v.IsWhollyKnown() {
+		return nil, errors.New("value is not known")
+	}
+	b, err := ctyjson.Marshal(v, v.Type())
+	if err != nil {
+		return nil, err
+	}
+	var result any
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func decodeValues(values map[string]any, target any) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       singleBlockHook,
+		ErrorUnused:      true,
+		WeaklyTypedInput: true,
+		Result:           target,
+		TagName:          "hcl",
+		// Names are matched exactly, so that attributes are not decoded into fields without hcl tag
+		// that have a similar name, such as the model of queries.
+		MatchName: func(mapKey, fieldName string) bool {
+			return mapKey == fieldName
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(values)
+}
+
+// singleBlockHook decodes a block into a field that is a struct, or a pointer to a struct, rather than a list.
+func singleBlockHook(from reflect.Type, to reflect.Type, data any) (any, error) {
+	if from.Kind() != reflect.Slice {
+		return data, nil
+	}
+	if to.Kind() == reflect.Pointer {
+		to = to.Elem()
+	}
+	if to.Kind() != reflect.Struct {
+		return data, nil
+	}
+	list, ok := data.([]any)
+	if !ok {
+		return data, nil
+	}
+	if len(list) != 1 {
+		return nil, fmt.Errorf("expected a single block but got %d", len(list))
+	}
+	return list[0], nil
+}
@@ -1,6 +1,7 @@
 package hcl
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -73,3 +74,61 @@ func TestEncode(t *testing.T) {
 }
 `, string(encoded))
 }
+
+func TestDecode(t *testing.T) {
+	type data struct {
+		Name    string  `hcl:"name"`
+		Number  float64 `hcl:"number"`
+		Model   string  `hcl:"model"`
+		Ignored string
+		Blocks  []data `hcl:"blocks,block"`
+		SubData *data  `hcl:"sub,block"`
+	}
+	newBody := func(resourceType string) (any, error) {
+		if resourceType != "grafana_test" {
+			return nil, errors.New("unsupported resource type")
+		}
+		return &data{}, nil
+	}
+
+	t.Run("decodes encoded resources", func(t *testing.T) {
+		expected := &data{
+			Name:   "test",
+			Number: 123,
+			Blocks: []data{{Name: "el-0"}, {Name: "el-1", Number: 2}},
+			SubData: &data{
+				Name: "sub-data",
+			},
+		}
+		encoded, err := Encode(Resource{Type: "grafana_test", Name: "test-01", Body: expected})
+		require.NoError(t, err)
+
+		resources, err := Decode(encoded, "test.tf", newBody)
+		require.NoError(t, err)
+		require.Equal(t, []Resource{{Type: "grafana_test", Name: "test-01", Body: expected}}, resources)
+	})
+
+	t.Run("leaves missing attributes empty and evaluates functions", func(t *testing.T) {
+		resources, err := Decode([]byte(`resource "grafana_test" "test-01" {
+  name  = "test"
+  model = jsonencode({ expr = "up" })
+}`), "test.tf", newBody)
+		require.NoError(t, err)
+		require.Equal(t, &data{Name: "test", Model: `{"expr":"up"}`}, resources[0].Body)
+	})
+
+	t.Run("fails on unknown attributes", func(t *testing.T) {
+		_, err := Decode([]byte(`resource "grafana_test" "test-01" {
+  ignored = "test"
+}`), "test.tf", newBody)
+		require.ErrorContains(t, err, "ignored")
+	})
+
+	t.Run("fails on unsupported blocks and resources", func(t *testing.T) {
+		_, err := Decode([]byte(`data "grafana_test" "test-01" {}`), "test.tf", newBody)
+		require.ErrorContains(t, err, "only resource blocks")
+
+		_, err = Decode([]byte(`resource "grafana_folder" "test-01" {}`), "test.tf", newBody)
+		require.ErrorContains(t, err, "unsupported resource type")
+	})
+}
@@ -116,6 +116,14 @@ func (f *ProvisioningApiHandler) handleRouteResetPolicyTree(ctx *contextmodel.Re
 	return f.svc.RouteResetPolicyTree(ctx)
 }
 
+func (f *ProvisioningApiHandler) handleRoutePostImport(ctx *contextmodel.ReqContext) response.Response {
+	return f.svc.RoutePostImport(ctx)
+}
+
+func (f *ProvisioningApiHandler) handleRoutePostImportPrometheusRules(ctx *contextmodel.ReqContext) response.Response {
+	return f.svc.RoutePostImportPrometheusRules(ctx)
+}
+
 func (f *ProvisioningApiHandler) handleRouteGetAlertRuleGroup(ctx *contextmodel.ReqContext, folder, group string) response.Response {
 	return f.svc.RouteGetAlertRuleGroup(ctx, folder, group)
 }
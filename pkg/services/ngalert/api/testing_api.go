@@ -25,6 +25,10 @@ func (f *TestingApiHandler) handleRouteTestRuleGrafanaConfig(c *contextmodel.Req
 	return f.svc.RouteTestGrafanaRuleConfig(c, body)
 }
 
+func (f *TestingApiHandler) handleRouteDryRunRuleGrafanaConfig(c *contextmodel.ReqContext, body apimodels.PostableExtendedRuleNodeExtended) response.Response {
+	return f.svc.RouteDryRunGrafanaRuleConfig(c, body)
+}
+
 func (f *TestingApiHandler) handleRouteEvalQueries(c *contextmodel.ReqContext, body apimodels.EvalQueriesPayload) response.Response {
 	return f.svc.RouteEvalQueries(c, body)
 }
@@ -32,3 +36,11 @@ func (f *TestingApiHandler) handleRouteEvalQueries(c *contextmodel.ReqContext, b
 func (f *TestingApiHandler) handleBacktestConfig(ctx *contextmodel.ReqContext, conf apimodels.BacktestConfig) response.Response {
 	return f.svc.BacktestAlertRule(ctx, conf)
 }
+
+func (f *TestingApiHandler) handleBacktestReportConfig(ctx *contextmodel.ReqContext, conf apimodels.BacktestReportConfig) response.Response {
+	return f.svc.BacktestAlertRuleReport(ctx, conf)
+}
+
+func (f *TestingApiHandler) handleBacktestCompareConfig(ctx *contextmodel.ReqContext, conf apimodels.BacktestCompareConfig) response.Response {
+	return f.svc.BacktestCompareRuleVersions(ctx, conf)
+}
@@ -32,6 +32,28 @@ import (
 //       403: ForbiddenError
 //
 
+// swagger:route Get /ruler/grafana/api/v1/dependencies ruler RouteGetRuleDependencies
+//
+// Get the dependencies between rules. A rule depends on a Grafana-managed recording rule if it reads the metric that the recording rule writes.
+//
+//     Produces:
+//     - application/json
+//
+//     Responses:
+//       200: RuleDependencyGraph
+//       403: ForbiddenError
+
+// swagger:route Get /ruler/grafana/api/v1/lint ruler RouteGetRuleLintReport
+//
+// Get the problems the rule linter found in the rules, and the health score of their folders. Rules are linted periodically, rules that were not linted yet are not in the report.
+//
+//     Produces:
+//     - application/json
+//
+//     Responses:
+//       200: RuleLintReport
+//       403: ForbiddenError
+
 // swagger:route Get /ruler/grafana/api/v1/export/rules ruler RouteGetRulesForExport
 //
 // List rules in provisioning format
@@ -185,7 +207,23 @@ import (
 //       403: ForbiddenError
 //       404: NotFound
 
-// swagger:parameters RoutePostNameRulesConfig RoutePostNameGrafanaRulesConfig RoutePostRulesGroupForExport
+// swagger:route POST /ruler/grafana/api/v1/rules/{Namespace}/lint ruler RoutePostRulesGroupForLint
+//
+// Lints submitted rule group without saving it
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Responses:
+//       200: RuleLintReport
+//       400: ValidationError
+//       403: ForbiddenError
+//       404: NotFound
+
+// swagger:parameters RoutePostNameRulesConfig RoutePostNameGrafanaRulesConfig RoutePostRulesGroupForExport RoutePostRulesGroupForLint
 type NamespaceConfig struct {
 	// The UID of the rule folder
 	// in:path
@@ -218,6 +256,14 @@ type PathGetRulesParams struct {
 	PanelID int64
 }
 
+// swagger:parameters RouteGetRuleLintReport
+type RuleLintReportParams struct {
+	// The UIDs of the folders to report. All folders the user has access to are reported if empty.
+	// in: query
+	// required: false
+	FolderUID []string `json:"folderUid"`
+}
+
 // swagger:parameters RouteGetRuleByUID
 type PathGetRuleByUIDParams struct {
 	// in: path
@@ -487,6 +533,10 @@ type Record struct {
 	// required: true
 	// example: A
 	From string `json:"from" yaml:"from"`
+	// Where the recorded metric is written to. If empty, the default target of the instance is used.
+	// enum: prometheus,influxdb,otlp,sql
+	// example: prometheus
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
 }
 
 // swagger:model
@@ -597,3 +647,78 @@ type UpdateRuleGroupResponse struct {
 	Updated []string `json:"updated,omitempty"`
 	Deleted []string `json:"deleted,omitempty"`
 }
+
+// RuleDependencyGraph contains the rules visible to the user and the dependencies between them.
+// Rules that read the metric of a recording rule are evaluated after the recording rule in the same tick.
+// swagger:model
+type RuleDependencyGraph struct {
+	Nodes []RuleDependencyNode `json:"nodes"`
+	Edges []RuleDependencyEdge `json:"edges"`
+	// Cycles contains the UIDs of rules that depend on each other. These rules are evaluated independently.
+	Cycles [][]string `json:"cycles"`
+}
+
+// swagger:model
+type RuleDependencyNode struct {
+	UID       string `json:"uid"`
+	Title     string `json:"title"`
+	FolderUID string `json:"folderUid"`
+	RuleGroup string `json:"ruleGroup"`
+	// enum: alerting,recording
+	Type string `json:"type"`
+	// Metric is the metric written by a recording rule.
+	Metric string `json:"metric,omitempty"`
+}
+
+// swagger:model
+type RuleDependencyEdge struct {
+	// Source is the UID of the recording rule.
+	Source string `json:"source"`
+	// Target is the UID of the rule that reads the metric of the recording rule.
+	Target string `json:"target"`
+	// Cyclic is true if the dependency is part of a cycle.
+	Cyclic bool `json:"cyclic,omitempty"`
+}
+
+// RuleLintReport contains the problems the rule linter found in the rules, and the health score of their folders.
+// swagger:model
+type RuleLintReport struct {
+	Folders []FolderLintHealth `json:"folders"`
+	Rules   []RuleLintResult   `json:"rules"`
+}
+
+// FolderLintHealth summarizes the results of the rule linter for the rules of a folder.
+// swagger:model
+type FolderLintHealth struct {
+	FolderUID string `json:"folderUid"`
+	// Score is the average score of the rules of the folder, between 0 and 100.
+	Score int `json:"score"`
+	// Rules is the number of linted rules in the folder.
+	Rules    int `json:"rules"`
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+}
+
+// swagger:model
+type RuleLintResult struct {
+	UID       string `json:"uid"`
+	Title     string `json:"title"`
+	FolderUID string `json:"folderUid"`
+	RuleGroup string `json:"ruleGroup"`
+	// Score is between 0 and 100, and lower the more and the more severe the problems of the rule are.
+	Score int `json:"score"`
+	// enum: info,warning,error
+	MaxSeverity string          `json:"maxSeverity,omitempty"`
+	Issues      []RuleLintIssue `json:"issues"`
+	LintedAt    time.Time       `json:"lintedAt"`
+}
+
+// swagger:model
+type RuleLintIssue struct {
+	Check string `json:"check"`
+	// enum: info,warning,error
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// RefID is the query the problem was found in, if any.
+	RefID string `json:"refId,omitempty"`
+}
@@ -34,3 +34,110 @@ type DecryptQueryParams struct {
 	// default: false
 	Decrypt bool `json:"decrypt"`
 }
+
+// swagger:route POST /v1/provisioning/import provisioning stable RoutePostImport
+//
+// Import alert rule groups, contact points, notification policies and mute timings from a document in provisioning file format.
+//
+// The resources of the document replace the existing resources with the same identity, other resources are not changed.
+//
+//     Consumes:
+//     - application/json
+//     - application/yaml
+//     - text/yaml
+//     - text/hcl
+//
+//     Responses:
+//       200: ImportResult
+//       400: ValidationError
+
+// swagger:parameters RoutePostImport
+type ImportQueryParams struct {
+	// Format of the document. Supported yaml, json or hcl. Content-Type header can also be used, but the query parameter will take precedence.
+	// in: query
+	// required: false
+	// default: yaml
+	// enum: yaml,json,hcl
+	Format string `json:"format"`
+
+	// Whether to only validate the document and return the changes without applying them.
+	// in: query
+	// required: false
+	// default: false
+	DryRun bool `json:"dryRun"`
+}
+
+// swagger:parameters RoutePostImport
+type ImportHeaders struct {
+	// in:header
+	XDisableProvenance string `json:"X-Disable-Provenance"`
+}
+
+// swagger:route POST /v1/provisioning/import/prometheus provisioning stable RoutePostImportPrometheusRules
+//
+// Import the rule groups of a Prometheus or Mimir rule file as Grafana-managed rule groups in a folder.
+//
+// Queries read from the given Prometheus data source. Rules are matched to the existing rules of the group by title.
+//
+//     Consumes:
+//     - application/yaml
+//     - text/yaml
+//
+//     Responses:
+//       200: ImportResult
+//       400: ValidationError
+
+// swagger:parameters RoutePostImportPrometheusRules
+type ImportPrometheusRulesQueryParams struct {
+	// UID of the folder of the rule groups.
+	// in: query
+	// required: true
+	FolderUID string `json:"folderUid"`
+
+	// UID of the Prometheus data source that the queries of the rules read from.
+	// in: query
+	// required: true
+	DatasourceUID string `json:"datasourceUid"`
+
+	// Target that recording rules write to. The default target of the instance is used if empty.
+	// in: query
+	// required: false
+	// enum: prometheus,influxdb,otlp,sql
+	RecordTarget string `json:"recordTarget"`
+
+	// Whether to only validate the rules and return the changes without applying them.
+	// in: query
+	// required: false
+	// default: false
+	DryRun bool `json:"dryRun"`
+}
+
+// swagger:parameters RoutePostImportPrometheusRules
+type ImportPrometheusRulesHeaders struct {
+	// in:header
+	XDisableProvenance string `json:"X-Disable-Provenance"`
+}
+
+// ImportResult lists the changes of an import.
+// swagger:model
+type ImportResult struct {
+	// DryRun is true if the changes were not applied.
+	DryRun  bool           `json:"dryRun"`
+	Changes []ImportChange `json:"changes"`
+}
+
+// ImportChange is a change of a single resource.
+type ImportChange struct {
+	// Kind of the resource: alertRule, contactPoint, notificationPolicy or muteTiming.
+	Kind string `json:"kind"`
+	// Action is one of create, update or delete.
+	Action string `json:"action"`
+	// Name is the title of the rule, the name of the contact point or mute timing, or the receiver of the root policy.
+	Name string `json:"name"`
+	// UID of the alert rule or contact point integration.
+	UID       string `json:"uid,omitempty"`
+	FolderUID string `json:"folderUid,omitempty"`
+	RuleGroup string `json:"ruleGroup,omitempty"`
+	// Diff contains the paths of the fields that are changed by an update.
+	Diff []string `json:"diff,omitempty"`
+}
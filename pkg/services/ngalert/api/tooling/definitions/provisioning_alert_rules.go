@@ -307,6 +307,7 @@ type AlertRuleNotificationSettingsExport struct {
 
 // Record is the provisioned export of models.Record.
 type AlertRuleRecordExport struct {
-	Metric string `json:"metric" yaml:"metric" hcl:"metric"`
-	From   string `json:"from" yaml:"from" hcl:"from"`
+	Metric string  `json:"metric" yaml:"metric" hcl:"metric"`
+	From   string  `json:"from" yaml:"from" hcl:"from"`
+	Target *string `json:"target,omitempty" yaml:"target,omitempty" hcl:"target,optional"`
 }
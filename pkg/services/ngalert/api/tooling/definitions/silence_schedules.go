@@ -0,0 +1,124 @@
+package definitions
+
+import (
+	"time"
+
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/common/model"
+)
+
+// swagger:route GET /alertmanager/grafana/api/v2/silence-schedules alertmanager RouteGetGrafanaSilenceSchedules
+//
+// get silence schedules
+//
+//     Responses:
+//       200: GettableSilenceSchedules
+//       403: ForbiddenError
+
+// swagger:route POST /alertmanager/grafana/api/v2/silence-schedules alertmanager RouteCreateGrafanaSilenceSchedule
+//
+// create silence schedule
+//
+//     Responses:
+//       201: GettableSilenceSchedule
+//       400: ValidationError
+//       403: ForbiddenError
+//       409: GenericPublicError
+
+// swagger:route GET /alertmanager/grafana/api/v2/silence-schedule/{UID} alertmanager RouteGetGrafanaSilenceSchedule
+//
+// get silence schedule
+//
+//     Responses:
+//       200: GettableSilenceSchedule
+//       403: ForbiddenError
+//       404: NotFound
+
+// swagger:route PUT /alertmanager/grafana/api/v2/silence-schedule/{UID} alertmanager RouteUpdateGrafanaSilenceSchedule
+//
+// update silence schedule
+//
+//     Responses:
+//       200: GettableSilenceSchedule
+//       400: ValidationError
+//       403: ForbiddenError
+//       404: NotFound
+//       409: GenericPublicError
+
+// swagger:route DELETE /alertmanager/grafana/api/v2/silence-schedule/{UID} alertmanager RouteDeleteGrafanaSilenceSchedule
+//
+// delete silence schedule
+//
+//     Responses:
+//       200: Ack
+//       403: ForbiddenError
+//       404: NotFound
+
+// swagger:parameters RouteGetGrafanaSilenceSchedule RouteUpdateGrafanaSilenceSchedule RouteDeleteGrafanaSilenceSchedule
+type SilenceScheduleUIDParams struct {
+	// in:path
+	UID string
+}
+
+// swagger:parameters RouteGetGrafanaSilenceSchedules RouteGetGrafanaSilenceSchedule
+type GetSilenceSchedulesParams struct {
+	// Return access control metadata with silence schedules.
+	// in:query
+	// required:false
+	AccessControl bool `json:"accesscontrol"`
+}
+
+// swagger:parameters RouteCreateGrafanaSilenceSchedule RouteUpdateGrafanaSilenceSchedule
+type PostableSilenceScheduleParams struct {
+	// in:body
+	Body PostableSilenceSchedule
+}
+
+// PostableSilenceSchedule is a silence that recurs either on a cron schedule or in time intervals.
+// swagger:model
+type PostableSilenceSchedule struct {
+	// required: true
+	Title   string `json:"title"`
+	Comment string `json:"comment,omitempty"`
+	// required: true
+	Matchers amv2.Matchers `json:"matchers"`
+	// Cron expression of the start of the silences, for example "0 22 * * 6" for every Saturday at 22:00.
+	// example: 0 22 * * 6
+	Cron string `json:"cron,omitempty"`
+	// Duration of the silences started by the cron expression.
+	// example: 4h
+	Duration model.Duration `json:"duration,omitempty"`
+	// Time zone of the cron expression. Defaults to UTC.
+	// example: Europe/Berlin
+	Location string `json:"location,omitempty"`
+	// Time intervals of the silences, in the same format as the time intervals of mute timings.
+	TimeIntervals []timeinterval.TimeInterval `json:"time_intervals,omitempty"`
+	IsPaused      bool                        `json:"isPaused"`
+	// Version of the schedule that is updated. The update fails if the schedule was changed in the meantime.
+	Version int64 `json:"version,omitempty"`
+}
+
+// GettableSilenceSchedule is a silence schedule and the silences that were created for it and did not end yet.
+// swagger:model
+type GettableSilenceSchedule struct {
+	UID string `json:"uid"`
+	PostableSilenceSchedule
+	Silences  []ScheduledSilence `json:"silences"`
+	CreatedBy string             `json:"createdBy"`
+	Created   time.Time          `json:"created"`
+	UpdatedBy string             `json:"updatedBy"`
+	Updated   time.Time          `json:"updated"`
+	// example: {"read": true, "write": false, "create": false}
+	Permissions map[SilencePermission]bool `json:"accessControl,omitempty"`
+}
+
+// ScheduledSilence is a silence that was created for a period of a silence schedule.
+type ScheduledSilence struct {
+	ID       string    `json:"id"`
+	StartsAt time.Time `json:"startsAt"`
+	EndsAt   time.Time `json:"endsAt"`
+}
+
+// swagger:model
+type GettableSilenceSchedules []GettableSilenceSchedule
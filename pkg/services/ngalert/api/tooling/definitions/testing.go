@@ -28,6 +28,21 @@ import (
 //       400: ValidationError
 //       404: NotFound
 
+// swagger:route Post /v1/rule/dry-run/grafana testing RouteDryRunRuleGrafanaConfig
+//
+// Evaluate a rule against Grafana ruler, and route the resulting alerts through the notification policies without sending them
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Responses:
+//       200: RuleDryRunResult
+//       400: ValidationError
+//       404: NotFound
+
 // swagger:route Post /v1/rule/test/{DatasourceUID} testing RouteTestRuleConfig
 //
 // Test a rule against external data source ruler
@@ -68,6 +83,35 @@ import (
 //     Responses:
 //       200: BacktestResult
 
+// swagger:route Post /v1/rule/backtest/report testing BacktestReportConfig
+//
+// Replay a rule over a time range and report its state transitions, and the notifications the notification policies would send
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Responses:
+//       200: BacktestReport
+//       400: ValidationError
+
+// swagger:route Post /v1/rule/backtest/compare testing BacktestCompareConfig
+//
+// Replay two versions of a stored rule over the same time range and report the difference
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Responses:
+//       200: BacktestReportDiff
+//       400: ValidationError
+//       404: NotFound
+
 // swagger:parameters RouteTestReceiverConfig
 type TestReceiverRequest struct {
 	// in:body
@@ -100,6 +144,51 @@ type TestGrafanaRuleRequest struct {
 	Body PostableExtendedRuleNodeExtended
 }
 
+// swagger:parameters RouteDryRunRuleGrafanaConfig
+type DryRunGrafanaRuleRequest struct {
+	// in:body
+	Body PostableExtendedRuleNodeExtended
+}
+
+// RuleDryRunResult contains the alerts of the evaluation of a rule, and how the Alertmanager would handle them.
+// swagger:model
+type RuleDryRunResult struct {
+	Instances []RuleDryRunInstance `json:"instances"`
+}
+
+// swagger:model
+type RuleDryRunInstance struct {
+	// enum: Normal,Alerting,Pending,NoData,Error
+	State       string `json:"state"`
+	StateReason string `json:"stateReason,omitempty"`
+	// Labels are the labels of the alert, with the templates expanded and the labels added by Grafana.
+	Labels map[string]string `json:"labels"`
+	// Annotations are the annotations of the alert, with the templates expanded.
+	Annotations map[string]string `json:"annotations"`
+	// Routes are the notification policies that match the alert.
+	Routes []RuleDryRunRoute `json:"routes"`
+	// SilencedBy contains the IDs of the active silences that match the alert.
+	SilencedBy []string `json:"silencedBy"`
+}
+
+// swagger:model
+type RuleDryRunRoute struct {
+	// RouteID identifies the policy by its position in the policy tree.
+	RouteID string `json:"routeId"`
+	// Matchers are the matchers of the policy and of all its parents.
+	Matchers       []string          `json:"matchers"`
+	Receiver       string            `json:"receiver"`
+	GroupKey       string            `json:"groupKey"`
+	GroupLabels    map[string]string `json:"groupLabels"`
+	GroupWait      model.Duration    `json:"groupWait"`
+	GroupInterval  model.Duration    `json:"groupInterval"`
+	RepeatInterval model.Duration    `json:"repeatInterval"`
+	// MutedBy contains the names of the mute timings of the policy that are active, or of its active time intervals if none of them is active.
+	MutedBy []string `json:"mutedBy,omitempty"`
+	// Autogenerated is true if the policy is generated from the notification settings of rules.
+	Autogenerated bool `json:"autogenerated,omitempty"`
+}
+
 // swagger:model
 type PostableExtendedRuleNodeExtended struct {
 	// required: true
@@ -232,5 +321,107 @@ type BacktestConfig struct {
 	NoDataState NoDataState `json:"no_data_state"`
 }
 
+// swagger:parameters BacktestReportConfig
+type BacktestReportConfigRequest struct {
+	// in:body
+	Body BacktestReportConfig
+}
+
+// swagger:model
+type BacktestReportConfig struct {
+	BacktestConfig
+	// NamespaceUID is the UID of the folder the rule would be created in. It adds the grafana_folder label to the replayed alerts.
+	NamespaceUID string `json:"folderUid,omitempty"`
+	// SimulateNotifications routes the replayed alerts through the notification policies of the organization.
+	SimulateNotifications bool `json:"simulate_notifications,omitempty"`
+}
+
+// swagger:parameters BacktestCompareConfig
+type BacktestCompareConfigRequest struct {
+	// in:body
+	Body BacktestCompareConfig
+}
+
+// swagger:model
+type BacktestCompareConfig struct {
+	RuleUID string `json:"rule_uid"`
+	// OldVersion is the version to compare against. Defaults to the version before NewVersion.
+	OldVersion int64 `json:"old_version,omitempty"`
+	// NewVersion is the version to compare. Defaults to the current version of the rule.
+	NewVersion int64     `json:"new_version,omitempty"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	// SimulateNotifications routes the replayed alerts through the notification policies of the organization.
+	SimulateNotifications bool `json:"simulate_notifications,omitempty"`
+}
+
+// swagger:model
+type BacktestReport struct {
+	RuleUID     string    `json:"ruleUID"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Evaluations int       `json:"evaluations"`
+	// Transitions contains only evaluations that changed the state or the state reason of an alert instance.
+	Transitions []BacktestTransition `json:"transitions"`
+	// Notifications contains every notification the Alertmanager would have sent or muted, ordered by time.
+	Notifications []BacktestNotification `json:"notifications,omitempty"`
+	// Receivers is a per-receiver summary of Notifications, ordered by receiver name.
+	Receivers []BacktestReceiverSummary `json:"receivers,omitempty"`
+}
+
+// BacktestTransition is a change of the state of a single alert instance.
+type BacktestTransition struct {
+	Time   time.Time   `json:"time"`
+	Labels data.Labels `json:"labels"`
+	From   string      `json:"from"`
+	To     string      `json:"to"`
+}
+
+// BacktestNotification is a simulated flush of an aggregation group in the Alertmanager.
+type BacktestNotification struct {
+	Time        time.Time   `json:"time"`
+	Receiver    string      `json:"receiver"`
+	GroupKey    string      `json:"groupKey"`
+	GroupLabels data.Labels `json:"groupLabels"`
+	Firing      int         `json:"firing"`
+	Resolved    int         `json:"resolved"`
+	// MutedBy contains the names of the time intervals that muted the notification. Empty if the notification was sent.
+	MutedBy []string `json:"mutedBy,omitempty"`
+}
+
+// Muted returns true if the notification was not sent because of an active mute timing.
+func (n BacktestNotification) Muted() bool {
+	return len(n.MutedBy) > 0
+}
+
+// BacktestReceiverSummary aggregates notifications of a single receiver.
+type BacktestReceiverSummary struct {
+	Receiver string `json:"receiver"`
+	// Notifications is the number of notifications that would have been sent.
+	Notifications int `json:"notifications"`
+	// Muted is the number of notifications that would have been suppressed by mute timings.
+	Muted int `json:"muted"`
+	// Groups is the number of distinct aggregation groups the receiver was notified about.
+	Groups int `json:"groups"`
+}
+
+// swagger:model
+type BacktestReportDiff struct {
+	Old                *BacktestReport        `json:"old"`
+	New                *BacktestReport        `json:"new"`
+	TransitionsDelta   int                    `json:"transitionsDelta"`
+	NotificationsDelta int                    `json:"notificationsDelta"`
+	Receivers          []BacktestReceiverDiff `json:"receivers"`
+}
+
+// BacktestReceiverDiff is the difference between summaries of the same receiver in two reports.
+type BacktestReceiverDiff struct {
+	Receiver           string                  `json:"receiver"`
+	Old                BacktestReceiverSummary `json:"old"`
+	New                BacktestReceiverSummary `json:"new"`
+	NotificationsDelta int                     `json:"notificationsDelta"`
+	MutedDelta         int                     `json:"mutedDelta"`
+}
+
 // swagger:model
 type BacktestResult data.Frame
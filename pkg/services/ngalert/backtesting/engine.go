@@ -41,12 +41,14 @@ type stateManager interface {
 
 type Engine struct {
 	evalFactory        eval.EvaluatorFactory
+	appURL             *url.URL
 	createStateManager func() stateManager
 }
 
 func NewEngine(appUrl *url.URL, evalFactory eval.EvaluatorFactory, tracer tracing.Tracer) *Engine {
 	return &Engine{
 		evalFactory: evalFactory,
+		appURL:      appUrl,
 		createStateManager: func() stateManager {
 			cfg := state.ManagerCfg{
 				Metrics:       nil,
@@ -64,40 +66,15 @@ func NewEngine(appUrl *url.URL, evalFactory eval.EvaluatorFactory, tracer tracin
 }
 
 func (e *Engine) Test(ctx context.Context, user identity.Requester, rule *models.AlertRule, from, to time.Time) (*data.Frame, error) {
-	ruleCtx := models.WithRuleKey(ctx, rule.GetKey())
-	logger := logger.FromContext(ctx)
-
-	if !from.Before(to) {
-		return nil, fmt.Errorf("%w: invalid interval of the backtesting [%d,%d]", ErrInvalidInputData, from.Unix(), to.Unix())
-	}
-	if to.Sub(from).Seconds() < float64(rule.IntervalSeconds) {
-		return nil, fmt.Errorf("%w: interval of the backtesting [%d,%d] is less than evaluation interval [%ds]", ErrInvalidInputData, from.Unix(), to.Unix(), rule.IntervalSeconds)
-	}
-	length := int(to.Sub(from).Seconds()) / int(rule.IntervalSeconds)
-
-	stateManager := e.createStateManager()
-
-	evaluator, err := backtestingEvaluatorFactory(ruleCtx, e.evalFactory, user, rule.GetEvalCondition().WithSource("backtesting"), &schedule.AlertingResultsFromRuleState{
-		Manager: stateManager,
-		Rule:    rule,
-	})
+	length, err := evaluationsCount(rule, from, to)
 	if err != nil {
-		return nil, errors.Join(ErrInvalidInputData, err)
+		return nil, err
 	}
 
-	logger.Info("Start testing alert rule", "from", from, "to", to, "interval", rule.IntervalSeconds, "evaluations", length)
-
-	start := time.Now()
-
 	tsField := data.NewField("Time", nil, make([]time.Time, length))
 	valueFields := make(map[data.Fingerprint]*data.Field)
 
-	err = evaluator.Eval(ruleCtx, from, time.Duration(rule.IntervalSeconds)*time.Second, length, func(idx int, currentTime time.Time, results eval.Results) error {
-		if idx >= length {
-			logger.Info("Unexpected evaluation. Skipping", "from", from, "to", to, "interval", rule.IntervalSeconds, "evaluationTime", currentTime, "evaluationIndex", idx, "expectedEvaluations", length)
-			return nil
-		}
-		states := stateManager.ProcessEvalResults(ruleCtx, currentTime, rule, results, nil, nil)
+	err = e.replay(ctx, user, rule, from, length, nil, func(idx int, currentTime time.Time, states state.StateTransitions) {
 		tsField.Set(idx, currentTime)
 		for _, s := range states {
 			field, ok := valueFields[s.CacheID]
@@ -114,20 +91,128 @@ func (e *Engine) Test(ctx context.Context, user identity.Requester, rule *models
 				continue
 			}
 		}
-		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
 	fields := make([]*data.Field, 0, len(valueFields)+1)
 	fields = append(fields, tsField)
 	for _, f := range valueFields {
 		fields = append(fields, f)
 	}
-	result := data.NewFrame("Testing results", fields...)
+	return data.NewFrame("Testing results", fields...), nil
+}
 
+// Replay evaluates the rule over the interval [from, to) the same way Test does, but instead of a data frame it returns
+// a report of all state transitions. If opts contains a notification policy, the transitions are also routed through it
+// to simulate the notifications that would have been sent by the Alertmanager.
+func (e *Engine) Replay(ctx context.Context, user identity.Requester, rule *models.AlertRule, from, to time.Time, opts ReplayOptions) (*Report, error) {
+	length, err := evaluationsCount(rule, from, to)
 	if err != nil {
 		return nil, err
 	}
+
+	var simulator *notificationSimulator
+	if opts.Policy != nil {
+		simulator, err = newNotificationSimulator(opts.Policy, e.appURL)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidInputData, err)
+		}
+	}
+
+	report := &Report{
+		RuleUID:     rule.UID,
+		From:        from,
+		To:          to,
+		Evaluations: length,
+	}
+	err = e.replay(ctx, user, rule, from, length, opts.ExtraLabels, func(_ int, currentTime time.Time, states state.StateTransitions) {
+		for _, s := range states {
+			if s.State.State == s.PreviousState && s.StateReason == s.PreviousStateReason {
+				continue
+			}
+			report.Transitions = append(report.Transitions, Transition{
+				Time:   currentTime,
+				Labels: s.Labels,
+				From:   s.PreviousFormatted(),
+				To:     s.Formatted(),
+			})
+		}
+		if simulator != nil {
+			simulator.process(currentTime, states)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if simulator != nil {
+		simulator.flushUntil(to)
+		report.Notifications = simulator.notifications
+		report.Receivers = summarizeReceivers(simulator.notifications)
+	}
+	return report, nil
+}
+
+// Compare replays two versions of the same rule over the same interval and with the same options, and returns
+// the difference between the resulting reports.
+func (e *Engine) Compare(ctx context.Context, user identity.Requester, oldRule, newRule *models.AlertRule, from, to time.Time, opts ReplayOptions) (*ReportDiff, error) {
+	oldReport, err := e.Replay(ctx, user, oldRule, from, to, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay the old version of the rule: %w", err)
+	}
+	newReport, err := e.Replay(ctx, user, newRule, from, to, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay the new version of the rule: %w", err)
+	}
+	return DiffReports(oldReport, newReport), nil
+}
+
+func evaluationsCount(rule *models.AlertRule, from, to time.Time) (int, error) {
+	if !from.Before(to) {
+		return 0, fmt.Errorf("%w: invalid interval of the backtesting [%d,%d]", ErrInvalidInputData, from.Unix(), to.Unix())
+	}
+	if to.Sub(from).Seconds() < float64(rule.IntervalSeconds) {
+		return 0, fmt.Errorf("%w: interval of the backtesting [%d,%d] is less than evaluation interval [%ds]", ErrInvalidInputData, from.Unix(), to.Unix(), rule.IntervalSeconds)
+	}
+	return int(to.Sub(from).Seconds()) / int(rule.IntervalSeconds), nil
+}
+
+// replay evaluates the rule the given number of times starting at from, processes the results by a fresh state manager
+// and calls the callback with the state transitions of every evaluation.
+func (e *Engine) replay(ctx context.Context, user identity.Requester, rule *models.AlertRule, from time.Time, length int, extraLabels data.Labels, callback func(idx int, now time.Time, states state.StateTransitions)) error {
+	ruleCtx := models.WithRuleKey(ctx, rule.GetKey())
+	logger := logger.FromContext(ctx)
+
+	stateManager := e.createStateManager()
+
+	evaluator, err := backtestingEvaluatorFactory(ruleCtx, e.evalFactory, user, rule.GetEvalCondition().WithSource("backtesting"), &schedule.AlertingResultsFromRuleState{
+		Manager: stateManager,
+		Rule:    rule,
+	})
+	if err != nil {
+		return errors.Join(ErrInvalidInputData, err)
+	}
+
+	logger.Info("Start testing alert rule", "from", from, "interval", rule.IntervalSeconds, "evaluations", length)
+
+	start := time.Now()
+
+	err = evaluator.Eval(ruleCtx, from, time.Duration(rule.IntervalSeconds)*time.Second, length, func(idx int, currentTime time.Time, results eval.Results) error {
+		if idx >= length {
+			logger.Info("Unexpected evaluation. Skipping", "from", from, "interval", rule.IntervalSeconds, "evaluationTime", currentTime, "evaluationIndex", idx, "expectedEvaluations", length)
+			return nil
+		}
+		states := stateManager.ProcessEvalResults(ruleCtx, currentTime, rule, results, extraLabels, nil)
+		callback(idx, currentTime, states)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 	logger.Info("Rule testing finished successfully", "duration", time.Since(start))
-	return result, nil
+	return nil
 }
 
 func newBacktestingEvaluator(ctx context.Context, evalFactory eval.EvaluatorFactory, user identity.Requester, condition models.Condition, reader eval.AlertingResultsReader) (backtestingEvaluator, error) {
@@ -14,6 +14,7 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 
 	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval/eval_mocks"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
@@ -382,6 +383,89 @@ func TestEvaluatorTest(t *testing.T) {
 	})
 }
 
+func TestEngineReplay(t *testing.T) {
+	evaluator := &fakeBacktestingEvaluator{
+		evalCallback: func(now time.Time) (eval.Results, error) {
+			return eval.GenerateResults(1, eval.ResultGen()), nil
+		},
+	}
+	backtestingEvaluatorFactory = func(ctx context.Context, evalFactory eval.EvaluatorFactory, user identity.Requester, condition models.Condition, r eval.AlertingResultsReader) (backtestingEvaluator, error) {
+		return evaluator, nil
+	}
+	t.Cleanup(func() {
+		backtestingEvaluatorFactory = newBacktestingEvaluator
+	})
+
+	gen := models.RuleGen
+	rule := gen.With(gen.WithInterval(time.Minute)).GenerateRef()
+	from := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	to := from.Add(30 * time.Minute)
+	lbls := data.Labels{"alertname": "test"}
+
+	// flapping fires the alert every other evaluation, stable fires it once and keeps it firing.
+	flapping := &fakeStateManager{stateCallback: func(now time.Time) []state.StateTransition {
+		idx := int(now.Sub(from) / time.Minute)
+		current, previous := eval.Normal, eval.Alerting
+		if idx%2 == 1 {
+			current, previous = previous, current
+		}
+		if idx == 0 {
+			previous = eval.Normal
+		}
+		return []state.StateTransition{transition(lbls, current, previous)}
+	}}
+	stable := &fakeStateManager{stateCallback: func(now time.Time) []state.StateTransition {
+		if now.Equal(from) {
+			return []state.StateTransition{transition(lbls, eval.Alerting, eval.Normal)}
+		}
+		return []state.StateTransition{transition(lbls, eval.Alerting, eval.Alerting)}
+	}}
+
+	policy := &NotificationPolicy{
+		Route: &apimodels.Route{Receiver: "default"},
+	}
+
+	t.Run("should report only transitions without notification policy", func(t *testing.T) {
+		engine := &Engine{createStateManager: func() stateManager { return flapping }}
+		report, err := engine.Replay(context.Background(), nil, rule, from, to, ReplayOptions{})
+		require.NoError(t, err)
+		require.Equal(t, 30, report.Evaluations)
+		require.Len(t, report.Transitions, 29)
+		require.Empty(t, report.Notifications)
+		require.Empty(t, report.Receivers)
+	})
+
+	t.Run("should simulate notifications", func(t *testing.T) {
+		engine := &Engine{createStateManager: func() stateManager { return stable }}
+		report, err := engine.Replay(context.Background(), nil, rule, from, to, ReplayOptions{Policy: policy})
+		require.NoError(t, err)
+		require.Len(t, report.Transitions, 1)
+		require.Len(t, report.Notifications, 1)
+		require.Equal(t, []ReceiverSummary{{Receiver: "default", Notifications: 1, Groups: 1}}, report.Receivers)
+	})
+
+	t.Run("should compare two versions of the rule", func(t *testing.T) {
+		managers := []stateManager{flapping, stable}
+		engine := &Engine{createStateManager: func() stateManager {
+			m := managers[0]
+			managers = managers[1:]
+			return m
+		}}
+		diff, err := engine.Compare(context.Background(), nil, rule, rule, from, to, ReplayOptions{Policy: policy})
+		require.NoError(t, err)
+		require.Equal(t, -28, diff.TransitionsDelta)
+		require.Less(t, diff.NotificationsDelta, 0)
+		require.Len(t, diff.Receivers, 1)
+		require.Equal(t, diff.NotificationsDelta, diff.Receivers[0].NotificationsDelta)
+	})
+
+	t.Run("should fail if notification policy is invalid", func(t *testing.T) {
+		engine := &Engine{createStateManager: func() stateManager { return stable }}
+		_, err := engine.Replay(context.Background(), nil, rule, from, to, ReplayOptions{Policy: &NotificationPolicy{}})
+		require.ErrorIs(t, err, ErrInvalidInputData)
+	})
+}
+
 type fakeStateManager struct {
 	stateCallback func(now time.Time) []state.StateTransition
 }
@@ -0,0 +1,274 @@
+package backtesting
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/common/model"
+
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
+
+// NotificationPolicy is the part of the Alertmanager configuration that is needed to simulate notifications.
+type NotificationPolicy struct {
+	Route             *apimodels.Route
+	MuteTimeIntervals []config.MuteTimeInterval
+	TimeIntervals     []config.TimeInterval
+}
+
+// NotificationPolicyFromConfig extracts the notification policy from the Alertmanager configuration.
+func NotificationPolicyFromConfig(cfg *apimodels.PostableApiAlertingConfig) *NotificationPolicy {
+	return &NotificationPolicy{
+		Route:             cfg.Route,
+		MuteTimeIntervals: cfg.MuteTimeIntervals,
+		TimeIntervals:     cfg.TimeIntervals,
+	}
+}
+
+// notificationSimulator approximates the Alertmanager dispatcher: alerts are routed through the policy tree,
+// collected into aggregation groups, and every group is flushed after group_wait, then every group_interval.
+// A flush produces a notification if the group changed since the last notification or if repeat_interval elapsed.
+// Inhibition rules and silences are not taken into account.
+type notificationSimulator struct {
+	route     *dispatch.Route
+	intervals map[string][]timeinterval.TimeInterval
+	appURL    *url.URL
+
+	groups map[string]*aggregationGroup
+	// active contains labels of the alerts that are currently firing, by state cache ID.
+	// Labels of the alert can change when the state is resolved (e.g. NoData), therefore the original ones are used to resolve it.
+	active map[data.Fingerprint]model.LabelSet
+
+	notifications []Notification
+}
+
+type aggregationGroup struct {
+	key    string
+	route  *dispatch.Route
+	labels model.LabelSet
+	// alerts contains the alerts of the group, the value is true if the alert is resolved but not notified yet.
+	alerts       map[model.Fingerprint]bool
+	nextFlush    time.Time
+	lastNotified *time.Time
+	changed      bool
+}
+
+func newNotificationSimulator(policy *NotificationPolicy, appURL *url.URL) (*notificationSimulator, error) {
+	if policy.Route == nil {
+		return nil, errors.New("notification policy tree must not be empty")
+	}
+	amRoute := policy.Route.AsAMRoute()
+	if err := setGroupBy(amRoute); err != nil {
+		return nil, err
+	}
+
+	intervals := make(map[string][]timeinterval.TimeInterval, len(policy.MuteTimeIntervals)+len(policy.TimeIntervals))
+	for _, ti := range policy.MuteTimeIntervals {
+		intervals[ti.Name] = ti.TimeIntervals
+	}
+	for _, ti := range policy.TimeIntervals {
+		intervals[ti.Name] = ti.TimeIntervals
+	}
+
+	return &notificationSimulator{
+		route:     dispatch.NewRoute(amRoute, nil),
+		intervals: intervals,
+		appURL:    appURL,
+		groups:    make(map[string]*aggregationGroup),
+		active:    make(map[data.Fingerprint]model.LabelSet),
+	}, nil
+}
+
+// setGroupBy populates the parsed group_by fields of the route tree, which are normally set during validation of the configuration.
+func setGroupBy(r *config.Route) error {
+	if len(r.GroupByStr) > 0 && len(r.GroupBy) == 0 && !r.GroupByAll {
+		for _, l := range r.GroupByStr {
+			if l == "..." {
+				r.GroupByAll = true
+				continue
+			}
+			ln := model.LabelName(l)
+			if !ln.IsValid() {
+				return fmt.Errorf("invalid label name %q in group_by list", l)
+			}
+			r.GroupBy = append(r.GroupBy, ln)
+		}
+		if r.GroupByAll && len(r.GroupBy) > 0 {
+			return fmt.Errorf("cannot have wildcard group_by (`...`) and other labels at the same time")
+		}
+	}
+	for _, child := range r.Routes {
+		if err := setGroupBy(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// process flushes all groups that are due before now and then applies the state transitions of the evaluation at now.
+func (s *notificationSimulator) process(now time.Time, transitions state.StateTransitions) {
+	s.flushUntil(now)
+	for _, t := range transitions {
+		if isFiring(t.State.State) {
+			lbls := s.alertLabels(t)
+			s.active[t.CacheID] = lbls
+			s.fire(now, lbls)
+			continue
+		}
+		if lbls, ok := s.active[t.CacheID]; ok {
+			delete(s.active, t.CacheID)
+			s.resolve(lbls)
+		}
+	}
+}
+
+func (s *notificationSimulator) alertLabels(t state.StateTransition) model.LabelSet {
+	alert := state.StateToPostableAlert(t, s.appURL)
+	lbls := make(model.LabelSet, len(alert.Labels))
+	for k, v := range alert.Labels {
+		lbls[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return lbls
+}
+
+func (s *notificationSimulator) fire(now time.Time, lbls model.LabelSet) {
+	fp := lbls.Fingerprint()
+	for _, r := range s.route.Match(lbls) {
+		groupLabels := getGroupLabels(lbls, r)
+		key := fmt.Sprintf("%s:%s", r.Key(), groupLabels)
+		g, ok := s.groups[key]
+		if !ok {
+			g = &aggregationGroup{
+				key:       key,
+				route:     r,
+				labels:    groupLabels,
+				alerts:    make(map[model.Fingerprint]bool),
+				nextFlush: now.Add(r.RouteOpts.GroupWait),
+			}
+			s.groups[key] = g
+		}
+		if resolved, ok := g.alerts[fp]; !ok || resolved {
+			g.alerts[fp] = false
+			g.changed = true
+		}
+	}
+}
+
+func (s *notificationSimulator) resolve(lbls model.LabelSet) {
+	fp := lbls.Fingerprint()
+	for _, g := range s.groups {
+		if _, ok := g.alerts[fp]; ok {
+			g.alerts[fp] = true
+			g.changed = true
+		}
+	}
+}
+
+// flushUntil flushes groups in the order of their flush time until there are no groups that are due at or before t.
+func (s *notificationSimulator) flushUntil(t time.Time) {
+	for {
+		var next *aggregationGroup
+		for _, g := range s.groups {
+			if g.nextFlush.After(t) {
+				continue
+			}
+			if next == nil || g.nextFlush.Before(next.nextFlush) || (g.nextFlush.Equal(next.nextFlush) && g.key < next.key) {
+				next = g
+			}
+		}
+		if next == nil {
+			return
+		}
+		s.flush(next)
+	}
+}
+
+func (s *notificationSimulator) flush(g *aggregationGroup) {
+	now := g.nextFlush
+	firing, resolved := 0, 0
+	for _, r := range g.alerts {
+		if r {
+			resolved++
+		} else {
+			firing++
+		}
+	}
+
+	// Resolved alerts are not notified about if the group has never been notified about the firing ones.
+	changed := g.changed && (firing > 0 || g.lastNotified != nil)
+	repeat := firing > 0 && g.lastNotified != nil && !g.lastNotified.Add(g.route.RouteOpts.RepeatInterval).After(now)
+	if changed || repeat {
+		n := Notification{
+			Time:        now,
+			Receiver:    g.route.RouteOpts.Receiver,
+			GroupKey:    g.key,
+			GroupLabels: toDataLabels(g.labels),
+			Firing:      firing,
+			Resolved:    resolved,
+			MutedBy:     s.activeMuteTimings(g.route, now),
+		}
+		s.notifications = append(s.notifications, n)
+		// A muted notification is not recorded in the notification log, so the group is still considered changed
+		// and the firing alerts are notified about as soon as the mute timing ends.
+		if !n.Muted() {
+			g.lastNotified = &now
+			g.changed = false
+		} else if firing == 0 {
+			g.changed = false
+		}
+	}
+
+	for fp, r := range g.alerts {
+		if r {
+			delete(g.alerts, fp)
+		}
+	}
+	if len(g.alerts) == 0 {
+		delete(s.groups, g.key)
+		return
+	}
+	g.nextFlush = now.Add(g.route.RouteOpts.GroupInterval)
+}
+
+func (s *notificationSimulator) activeMuteTimings(r *dispatch.Route, now time.Time) []string {
+	var result []string
+	for _, name := range r.RouteOpts.MuteTimeIntervals {
+		for _, ti := range s.intervals[name] {
+			if ti.ContainsTime(now.UTC()) {
+				result = append(result, name)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func getGroupLabels(lbls model.LabelSet, r *dispatch.Route) model.LabelSet {
+	groupLabels := model.LabelSet{}
+	for ln, lv := range lbls {
+		if _, ok := r.RouteOpts.GroupBy[ln]; ok || r.RouteOpts.GroupByAll {
+			groupLabels[ln] = lv
+		}
+	}
+	return groupLabels
+}
+
+func toDataLabels(lbls model.LabelSet) data.Labels {
+	result := make(data.Labels, len(lbls))
+	for k, v := range lbls {
+		result[string(k)] = string(v)
+	}
+	return result
+}
+
+func isFiring(s eval.State) bool {
+	return s == eval.Alerting || s == eval.NoData || s == eval.Error
+}
@@ -0,0 +1,191 @@
+package backtesting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
+
+func TestNotificationSimulator(t *testing.T) {
+	duration := func(d time.Duration) *model.Duration {
+		md := model.Duration(d)
+		return &md
+	}
+	policy := &NotificationPolicy{
+		Route: &apimodels.Route{
+			Receiver:       "default",
+			GroupByStr:     []string{"alertname"},
+			GroupWait:      duration(30 * time.Second),
+			GroupInterval:  duration(5 * time.Minute),
+			RepeatInterval: duration(4 * time.Hour),
+			Routes: []*apimodels.Route{
+				{
+					Receiver:          "ops",
+					Matchers:          config.Matchers{mustMatcher(t, "team", "ops")},
+					MuteTimeIntervals: []string{"night"},
+				},
+			},
+		},
+		MuteTimeIntervals: []config.MuteTimeInterval{
+			{
+				Name: "night",
+				TimeIntervals: []timeinterval.TimeInterval{
+					{Times: []timeinterval.TimeRange{{StartMinute: 0, EndMinute: 60}}},
+				},
+			},
+		},
+	}
+	from := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	interval := time.Minute
+
+	// replay runs the simulator over evaluations produced by the callback and flushes it until the end of the interval.
+	replay := func(t *testing.T, start time.Time, evaluations int, callback func(idx int) state.StateTransitions) []Notification {
+		t.Helper()
+		s, err := newNotificationSimulator(policy, nil)
+		require.NoError(t, err)
+		for i := 0; i < evaluations; i++ {
+			s.process(start.Add(time.Duration(i)*interval), callback(i))
+		}
+		s.flushUntil(start.Add(time.Duration(evaluations) * interval))
+		return s.notifications
+	}
+
+	t.Run("should group alerts and notify after group_wait", func(t *testing.T) {
+		notifications := replay(t, from, 10, func(idx int) state.StateTransitions {
+			return state.StateTransitions{
+				transition(data.Labels{"alertname": "test", "instance": "a"}, eval.Alerting, eval.Alerting),
+				transition(data.Labels{"alertname": "test", "instance": "b"}, eval.Alerting, eval.Alerting),
+			}
+		})
+		require.Len(t, notifications, 1)
+		require.Equal(t, from.Add(30*time.Second), notifications[0].Time)
+		require.Equal(t, "default", notifications[0].Receiver)
+		require.Equal(t, data.Labels{"alertname": "test"}, notifications[0].GroupLabels)
+		require.Equal(t, 2, notifications[0].Firing)
+		require.False(t, notifications[0].Muted())
+	})
+
+	t.Run("should notify about resolved alerts at the next group_interval", func(t *testing.T) {
+		notifications := replay(t, from, 10, func(idx int) state.StateTransitions {
+			if idx >= 2 {
+				return state.StateTransitions{transition(data.Labels{"alertname": "test"}, eval.Normal, eval.Alerting)}
+			}
+			return state.StateTransitions{transition(data.Labels{"alertname": "test"}, eval.Alerting, eval.Alerting)}
+		})
+		require.Len(t, notifications, 2)
+		require.Equal(t, 1, notifications[0].Firing)
+		require.Equal(t, from.Add(30*time.Second+5*time.Minute), notifications[1].Time)
+		require.Equal(t, 0, notifications[1].Firing)
+		require.Equal(t, 1, notifications[1].Resolved)
+	})
+
+	t.Run("should not notify about alerts resolved before group_wait", func(t *testing.T) {
+		s, err := newNotificationSimulator(policy, nil)
+		require.NoError(t, err)
+		s.process(from, state.StateTransitions{transition(data.Labels{"alertname": "test"}, eval.Alerting, eval.Normal)})
+		s.process(from.Add(10*time.Second), state.StateTransitions{transition(data.Labels{"alertname": "test"}, eval.Normal, eval.Alerting)})
+		s.flushUntil(from.Add(time.Hour))
+		require.Empty(t, s.notifications)
+	})
+
+	t.Run("should repeat notifications after repeat_interval", func(t *testing.T) {
+		notifications := replay(t, from, 5*60, func(idx int) state.StateTransitions {
+			return state.StateTransitions{transition(data.Labels{"alertname": "test"}, eval.Alerting, eval.Alerting)}
+		})
+		require.Len(t, notifications, 2)
+		require.Equal(t, from.Add(30*time.Second+4*time.Hour), notifications[1].Time)
+	})
+
+	t.Run("should mute notifications by mute timings", func(t *testing.T) {
+		midnight := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+		notifications := replay(t, midnight, 90, func(idx int) state.StateTransitions {
+			return state.StateTransitions{transition(data.Labels{"alertname": "test", "team": "ops"}, eval.Alerting, eval.Alerting)}
+		})
+		require.NotEmpty(t, notifications)
+		last := notifications[len(notifications)-1]
+		for _, n := range notifications[:len(notifications)-1] {
+			require.Equal(t, "ops", n.Receiver)
+			require.Equal(t, []string{"night"}, n.MutedBy)
+		}
+		require.False(t, last.Muted())
+		require.False(t, last.Time.Before(midnight.Add(time.Hour)))
+		require.Equal(t, 1, last.Firing)
+	})
+
+	t.Run("should fail if the policy tree is empty", func(t *testing.T) {
+		_, err := newNotificationSimulator(&NotificationPolicy{}, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestDiffReports(t *testing.T) {
+	old := &Report{
+		Transitions: make([]Transition, 5),
+		Notifications: []Notification{
+			{Receiver: "a", GroupKey: "1"},
+			{Receiver: "a", GroupKey: "1"},
+			{Receiver: "b", GroupKey: "2", MutedBy: []string{"night"}},
+		},
+	}
+	old.Receivers = summarizeReceivers(old.Notifications)
+	new := &Report{
+		Transitions: make([]Transition, 2),
+		Notifications: []Notification{
+			{Receiver: "a", GroupKey: "1"},
+			{Receiver: "c", GroupKey: "3"},
+		},
+	}
+	new.Receivers = summarizeReceivers(new.Notifications)
+
+	diff := DiffReports(old, new)
+	require.Equal(t, -3, diff.TransitionsDelta)
+	require.Equal(t, 0, diff.NotificationsDelta)
+	require.Equal(t, []ReceiverDiff{
+		{
+			Receiver:           "a",
+			Old:                ReceiverSummary{Receiver: "a", Notifications: 2, Groups: 1},
+			New:                ReceiverSummary{Receiver: "a", Notifications: 1, Groups: 1},
+			NotificationsDelta: -1,
+		},
+		{
+			Receiver:   "b",
+			Old:        ReceiverSummary{Receiver: "b", Muted: 1},
+			New:        ReceiverSummary{Receiver: "b"},
+			MutedDelta: -1,
+		},
+		{
+			Receiver:           "c",
+			Old:                ReceiverSummary{Receiver: "c"},
+			New:                ReceiverSummary{Receiver: "c", Notifications: 1, Groups: 1},
+			NotificationsDelta: 1,
+		},
+	}, diff.Receivers)
+}
+
+func transition(lbls data.Labels, current, previous eval.State) state.StateTransition {
+	return state.StateTransition{
+		State: &state.State{
+			CacheID: lbls.Fingerprint(),
+			Labels:  lbls,
+			State:   current,
+		},
+		PreviousState: previous,
+	}
+}
+
+func mustMatcher(t *testing.T, name, value string) *labels.Matcher {
+	t.Helper()
+	m, err := labels.NewMatcher(labels.MatchEqual, name, value)
+	require.NoError(t, err)
+	return m
+}
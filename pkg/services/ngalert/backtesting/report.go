@@ -0,0 +1,115 @@
+package backtesting
+
+import (
+	"sort"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+)
+
+// ReplayOptions configures Engine.Replay.
+type ReplayOptions struct {
+	// ExtraLabels are added to every alert instance, like the scheduler does with the rule's reserved labels
+	// (alertname, folder title, rule UID). Routing often depends on them.
+	ExtraLabels data.Labels
+	// Policy is the notification policy tree to route the replayed alerts through.
+	// If it is nil, notifications are not simulated.
+	Policy *NotificationPolicy
+}
+
+// The report types are part of the API, see the definitions package.
+type (
+	// Report is the result of a replay of an alert rule.
+	Report = apimodels.BacktestReport
+	// Transition is a change of the state of a single alert instance.
+	Transition = apimodels.BacktestTransition
+	// Notification is a simulated flush of an aggregation group in the Alertmanager.
+	Notification = apimodels.BacktestNotification
+	// ReceiverSummary aggregates notifications of a single receiver.
+	ReceiverSummary = apimodels.BacktestReceiverSummary
+	// ReportDiff describes how the replay of a new version of a rule differs from the old one.
+	ReportDiff = apimodels.BacktestReportDiff
+	// ReceiverDiff is the difference between summaries of the same receiver in two reports.
+	ReceiverDiff = apimodels.BacktestReceiverDiff
+)
+
+// DiffReports compares two reports. Receivers that are present in only one of the reports are compared against an empty summary.
+func DiffReports(old, new *Report) *ReportDiff {
+	diff := &ReportDiff{
+		Old:                old,
+		New:                new,
+		TransitionsDelta:   len(new.Transitions) - len(old.Transitions),
+		NotificationsDelta: sentCount(new.Notifications) - sentCount(old.Notifications),
+	}
+
+	byReceiver := make(map[string]*ReceiverDiff)
+	get := func(receiver string) *ReceiverDiff {
+		d, ok := byReceiver[receiver]
+		if !ok {
+			d = &ReceiverDiff{
+				Receiver: receiver,
+				Old:      ReceiverSummary{Receiver: receiver},
+				New:      ReceiverSummary{Receiver: receiver},
+			}
+			byReceiver[receiver] = d
+		}
+		return d
+	}
+	for _, s := range old.Receivers {
+		get(s.Receiver).Old = s
+	}
+	for _, s := range new.Receivers {
+		get(s.Receiver).New = s
+	}
+
+	diff.Receivers = make([]ReceiverDiff, 0, len(byReceiver))
+	for _, d := range byReceiver {
+		d.NotificationsDelta = d.New.Notifications - d.Old.Notifications
+		d.MutedDelta = d.New.Muted - d.Old.Muted
+		diff.Receivers = append(diff.Receivers, *d)
+	}
+	sort.Slice(diff.Receivers, func(i, j int) bool {
+		return diff.Receivers[i].Receiver < diff.Receivers[j].Receiver
+	})
+	return diff
+}
+
+func summarizeReceivers(notifications []Notification) []ReceiverSummary {
+	byReceiver := make(map[string]*ReceiverSummary)
+	groups := make(map[string]map[string]struct{})
+	for _, n := range notifications {
+		s, ok := byReceiver[n.Receiver]
+		if !ok {
+			s = &ReceiverSummary{Receiver: n.Receiver}
+			byReceiver[n.Receiver] = s
+			groups[n.Receiver] = make(map[string]struct{})
+		}
+		if n.Muted() {
+			s.Muted++
+			continue
+		}
+		s.Notifications++
+		groups[n.Receiver][n.GroupKey] = struct{}{}
+	}
+
+	result := make([]ReceiverSummary, 0, len(byReceiver))
+	for receiver, s := range byReceiver {
+		s.Groups = len(groups[receiver])
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Receiver < result[j].Receiver
+	})
+	return result
+}
+
+func sentCount(notifications []Notification) int {
+	count := 0
+	for _, n := range notifications {
+		if !n.Muted() {
+			count++
+		}
+	}
+	return count
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 
 	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
 	"github.com/grafana/grafana/pkg/expr"
 	"github.com/grafana/grafana/pkg/expr/classic"
 	"github.com/grafana/grafana/pkg/infra/log"
@@ -59,6 +60,8 @@ type conditionEvaluator struct {
 	condition         models.Condition
 	evalTimeout       time.Duration
 	evalResultLimit   int
+	// user is the identity the queries are executed with.
+	user identity.Requester
 }
 
 func (r *conditionEvaluator) EvaluateRaw(ctx context.Context, now time.Time) (resp *backend.QueryDataResponse, err error) {
@@ -80,6 +83,11 @@ func (r *conditionEvaluator) EvaluateRaw(ctx context.Context, now time.Time) (re
 		defer cancel()
 		execCtx = timeoutCtx
 	}
+	// Data sources that authorize queries by the requester of the context, such as the Grafana data source,
+	// need it also when the rule is evaluated by the scheduler.
+	if _, err := identity.GetRequester(execCtx); err != nil && r.user != nil {
+		execCtx = identity.WithRequester(execCtx, r.user)
+	}
 	logger.FromContext(ctx).Debug("Executing pipeline", "commands", strings.Join(r.pipeline.GetCommandTypes(), ","), "datasources", strings.Join(r.pipeline.GetDatasourceTypes(), ","))
 	result, err := r.expressionService.ExecutePipeline(execCtx, now, r.pipeline)
 
@@ -881,6 +889,7 @@ func (e *evaluatorImpl) create(condition models.Condition, req *expr.Request) (C
 				condition:         condition,
 				evalTimeout:       e.evaluationTimeout,
 				evalResultLimit:   e.evaluationResultLimit,
+				user:              req.User,
 			}, nil
 		}
 		conditions = append(conditions, node.RefID())
@@ -0,0 +1,134 @@
+package lint
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	history_model "github.com/grafana/grafana/pkg/services/ngalert/state/historian/model"
+)
+
+const (
+	// defaultFlapWindow is how long the state transitions of a rule are remembered.
+	defaultFlapWindow = 24 * time.Hour
+	// defaultFlapThreshold is how many times an alert must start firing within the window to flap.
+	defaultFlapThreshold = 3
+)
+
+// FlapTracker observes the state transitions of the rules evaluated by this instance of Grafana, and remembers
+// when their alerts started firing.
+type FlapTracker struct {
+	clock     clock.Clock
+	window    time.Duration
+	threshold int
+
+	mtx   sync.Mutex
+	rules map[models.AlertRuleKey]*ruleFlaps
+}
+
+type ruleFlaps struct {
+	evaluated time.Time
+	// fired is when the alerts of the rule started firing within the window, by alert. At most threshold times
+	// are kept.
+	fired map[data.Fingerprint][]time.Time
+}
+
+func NewFlapTracker(clk clock.Clock) *FlapTracker {
+	return &FlapTracker{
+		clock:     clk,
+		window:    defaultFlapWindow,
+		threshold: defaultFlapThreshold,
+		rules:     make(map[models.AlertRuleKey]*ruleFlaps),
+	}
+}
+
+// Observe records the state transitions of an evaluation of a rule.
+func (t *FlapTracker) Observe(key models.AlertRuleKey, transitions state.StateTransitions) {
+	now := t.clock.Now()
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	rf, ok := t.rules[key]
+	if !ok {
+		rf = &ruleFlaps{fired: make(map[data.Fingerprint][]time.Time)}
+		t.rules[key] = rf
+	}
+	rf.evaluated = now
+	for _, tr := range transitions {
+		if tr.State.State != eval.Alerting || tr.PreviousState == eval.Alerting {
+			continue
+		}
+		fired := append(rf.fired[tr.CacheID], now)
+		if len(fired) > t.threshold {
+			fired = fired[len(fired)-t.threshold:]
+		}
+		rf.fired[tr.CacheID] = fired
+	}
+	for fp, fired := range rf.fired {
+		if now.Sub(fired[len(fired)-1]) > t.window {
+			delete(rf.fired, fp)
+		}
+	}
+}
+
+// Flapping returns true if an alert of the rule started firing at least threshold times within the window.
+func (t *FlapTracker) Flapping(key models.AlertRuleKey) bool {
+	now := t.clock.Now()
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	rf, ok := t.rules[key]
+	if !ok {
+		return false
+	}
+	for _, fired := range rf.fired {
+		if len(fired) >= t.threshold && now.Sub(fired[0]) <= t.window {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluated returns true if the rule was evaluated by this instance of Grafana within the window.
+func (t *FlapTracker) Evaluated(key models.AlertRuleKey) bool {
+	now := t.clock.Now()
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	rf, ok := t.rules[key]
+	return ok && now.Sub(rf.evaluated) <= t.window
+}
+
+// Prune forgets the rules that were not evaluated within the window.
+func (t *FlapTracker) Prune() {
+	now := t.clock.Now()
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for key, rf := range t.rules {
+		if now.Sub(rf.evaluated) > t.window {
+			delete(t.rules, key)
+		}
+	}
+}
+
+// Historian returns a historian that records the state transitions in the tracker before it passes them to h.
+func (t *FlapTracker) Historian(h state.Historian) state.Historian {
+	return flapTrackingHistorian{Historian: h, tracker: t}
+}
+
+type flapTrackingHistorian struct {
+	state.Historian
+	tracker *FlapTracker
+}
+
+func (h flapTrackingHistorian) Record(ctx context.Context, rule history_model.RuleMeta, states []state.StateTransition) <-chan error {
+	h.tracker.Observe(models.AlertRuleKey{OrgID: rule.OrgID, UID: rule.UID}, states)
+	return h.Historian.Record(ctx, rule, states)
+}
@@ -0,0 +1,46 @@
+package lint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
+
+func TestFlapTracker(t *testing.T) {
+	clk := clock.NewMock()
+	tracker := NewFlapTracker(clk)
+	key := models.AlertRuleKey{OrgID: 1, UID: "rule"}
+
+	fire := state.StateTransitions{{State: &state.State{CacheID: 1, State: eval.Alerting}, PreviousState: eval.Normal}}
+	resolve := state.StateTransitions{{State: &state.State{CacheID: 1, State: eval.Normal}, PreviousState: eval.Alerting}}
+	keepFiring := state.StateTransitions{{State: &state.State{CacheID: 1, State: eval.Alerting}, PreviousState: eval.Alerting}}
+
+	require.False(t, tracker.Evaluated(key))
+
+	for i := 0; i < defaultFlapThreshold-1; i++ {
+		tracker.Observe(key, fire)
+		tracker.Observe(key, keepFiring)
+		tracker.Observe(key, resolve)
+		clk.Add(time.Hour)
+	}
+	require.True(t, tracker.Evaluated(key))
+	require.False(t, tracker.Flapping(key), "alert should not flap until it starts firing threshold times")
+
+	tracker.Observe(key, fire)
+	require.True(t, tracker.Flapping(key))
+	require.False(t, tracker.Flapping(models.AlertRuleKey{OrgID: 1, UID: "other"}))
+
+	clk.Add(defaultFlapWindow)
+	require.False(t, tracker.Flapping(key), "alert should not flap if it started firing outside of the window")
+
+	clk.Add(time.Minute)
+	require.False(t, tracker.Evaluated(key))
+	tracker.Prune()
+	require.Empty(t, tracker.rules)
+}
@@ -0,0 +1,206 @@
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// grafanaDataSourceUID is the UID of the built-in Grafana data source, which is not stored with the other data
+// sources. See grafanads.DatasourceUID.
+const grafanaDataSourceUID = "grafana"
+
+// valueTemplate matches templates that read the value of the query, such as {{ $value }} or {{ $values.B }}.
+var valueTemplate = regexp.MustCompile(`{{-?[^}]*(\$values?\b|\.Values?\b)[^}]*}}`)
+
+// DataSourceGetter returns data sources. It is implemented by datasources.DataSourceService.
+type DataSourceGetter interface {
+	GetDataSource(ctx context.Context, query *datasources.GetDataSourceQuery) (*datasources.DataSource, error)
+}
+
+// ReceiverTests returns when the contact points of an organization were last tested successfully.
+type ReceiverTests interface {
+	LastReceiverTests(ctx context.Context, orgID int64) (map[string]time.Time, error)
+}
+
+// FlapCounter tells whether the alerts of a rule flap.
+type FlapCounter interface {
+	Flapping(key models.AlertRuleKey) bool
+}
+
+// Linter checks alert rules for problems that make them noisy or unreliable, but that are not invalid.
+type Linter struct {
+	dataSources   DataSourceGetter
+	receiverTests ReceiverTests
+	flaps         FlapCounter
+	clock         clock.Clock
+	log           log.Logger
+}
+
+// NewLinter returns a linter. The flap counter can be nil, in which case flapping rules are not reported.
+func NewLinter(dataSources DataSourceGetter, receiverTests ReceiverTests, flaps FlapCounter, clk clock.Clock, log log.Logger) *Linter {
+	return &Linter{
+		dataSources:   dataSources,
+		receiverTests: receiverTests,
+		flaps:         flaps,
+		clock:         clk,
+		log:           log,
+	}
+}
+
+// Lint checks the rules, which must belong to the same organization, and returns the results in the same order.
+func (l *Linter) Lint(ctx context.Context, orgID int64, rules []*models.AlertRule) []models.RuleLintResult {
+	tested, err := l.receiverTests.LastReceiverTests(ctx, orgID)
+	if err != nil {
+		l.log.Error("Failed to get the tests of contact points, they are not checked", "org", orgID, "error", err)
+		tested = nil
+	}
+	dataSources := map[string]bool{}
+	now := l.clock.Now()
+	results := make([]models.RuleLintResult, 0, len(rules))
+	for _, rule := range rules {
+		var issues []models.RuleLintIssue
+		issues = append(issues, checkQueryRanges(rule)...)
+		issues = append(issues, l.checkFlapping(rule)...)
+		issues = append(issues, checkLabelTemplates(rule)...)
+		issues = append(issues, l.checkDataSources(ctx, rule, dataSources)...)
+		if tested != nil {
+			issues = append(issues, checkContactPoints(rule, tested)...)
+		}
+		results = append(results, models.NewRuleLintResult(rule, issues, now))
+	}
+	return results
+}
+
+// checkQueryRanges reports data source queries whose time range is shorter than the evaluation interval.
+// Instant queries are not checked, because the range they read is part of the query.
+func checkQueryRanges(rule *models.AlertRule) []models.RuleLintIssue {
+	interval := time.Duration(rule.IntervalSeconds) * time.Second
+	var issues []models.RuleLintIssue
+	for _, q := range rule.Data {
+		if isExpr, err := q.IsExpression(); err != nil || isExpr {
+			continue
+		}
+		if isInstantQuery(q) {
+			continue
+		}
+		r := time.Duration(q.RelativeTimeRange.From - q.RelativeTimeRange.To)
+		if r <= 0 || r >= interval {
+			continue
+		}
+		issues = append(issues, models.RuleLintIssue{
+			Check:    models.RuleLintCheckQueryRangeShorterThanInterval,
+			Severity: models.RuleLintSeverityWarning,
+			Message:  fmt.Sprintf("query %s reads the last %s of data, but the rule is evaluated every %s, so data between evaluations is ignored", q.RefID, r, interval),
+			RefID:    q.RefID,
+		})
+	}
+	return issues
+}
+
+func isInstantQuery(q models.AlertQuery) bool {
+	var model struct {
+		Instant bool `json:"instant"`
+		Range   bool `json:"range"`
+	}
+	if err := json.Unmarshal(q.Model, &model); err != nil {
+		return false
+	}
+	return model.Instant && !model.Range
+}
+
+// checkFlapping reports alerting rules without a pending period whose alerts flap.
+func (l *Linter) checkFlapping(rule *models.AlertRule) []models.RuleLintIssue {
+	if l.flaps == nil || rule.Type() != models.RuleTypeAlerting || rule.For > 0 {
+		return nil
+	}
+	if !l.flaps.Flapping(rule.GetKey()) {
+		return nil
+	}
+	return []models.RuleLintIssue{{
+		Check:    models.RuleLintCheckFlappingWithoutPendingPeriod,
+		Severity: models.RuleLintSeverityWarning,
+		Message:  "alerts of the rule repeatedly start firing and resolve, set a pending period so that they fire only if the condition holds for some time",
+	}}
+}
+
+// checkLabelTemplates reports labels whose template reads the value of the query. Every distinct value of the label
+// is a different alert instance.
+func checkLabelTemplates(rule *models.AlertRule) []models.RuleLintIssue {
+	names := make([]string, 0, len(rule.Labels))
+	for name, value := range rule.Labels {
+		if valueTemplate.MatchString(value) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	issues := make([]models.RuleLintIssue, 0, len(names))
+	for _, name := range names {
+		issues = append(issues, models.RuleLintIssue{
+			Check:    models.RuleLintCheckHighCardinalityLabel,
+			Severity: models.RuleLintSeverityWarning,
+			Message:  fmt.Sprintf("label %s uses the value of the query, which creates a new alert every time the value changes, use an annotation instead", name),
+		})
+	}
+	return issues
+}
+
+// checkDataSources reports queries of data sources that do not exist. Whether a data source exists is cached in
+// the given map.
+func (l *Linter) checkDataSources(ctx context.Context, rule *models.AlertRule, exists map[string]bool) []models.RuleLintIssue {
+	var issues []models.RuleLintIssue
+	for _, q := range rule.Data {
+		if isExpr, err := q.IsExpression(); err != nil || isExpr {
+			continue
+		}
+		if q.DatasourceUID == grafanaDataSourceUID {
+			continue
+		}
+		found, ok := exists[q.DatasourceUID]
+		if !ok {
+			_, err := l.dataSources.GetDataSource(ctx, &datasources.GetDataSourceQuery{UID: q.DatasourceUID, OrgID: rule.OrgID})
+			if err != nil && !errors.Is(err, datasources.ErrDataSourceNotFound) {
+				l.log.Error("Failed to get data source of rule", "rule_uid", rule.UID, "datasource_uid", q.DatasourceUID, "error", err)
+				continue
+			}
+			found = err == nil
+			exists[q.DatasourceUID] = found
+		}
+		if found {
+			continue
+		}
+		issues = append(issues, models.RuleLintIssue{
+			Check:    models.RuleLintCheckMissingDataSource,
+			Severity: models.RuleLintSeverityError,
+			Message:  fmt.Sprintf("query %s uses data source %s, which does not exist", q.RefID, q.DatasourceUID),
+			RefID:    q.RefID,
+		})
+	}
+	return issues
+}
+
+// checkContactPoints reports contact points of simplified routing that were never tested successfully.
+func checkContactPoints(rule *models.AlertRule, tested map[string]time.Time) []models.RuleLintIssue {
+	var issues []models.RuleLintIssue
+	for _, ns := range rule.NotificationSettings {
+		if _, ok := tested[ns.Receiver]; ok || ns.Receiver == "" {
+			continue
+		}
+		issues = append(issues, models.RuleLintIssue{
+			Check:    models.RuleLintCheckUntestedContactPoint,
+			Severity: models.RuleLintSeverityInfo,
+			Message:  fmt.Sprintf("contact point %s was never tested successfully", ns.Receiver),
+		})
+	}
+	return issues
+}
@@ -0,0 +1,135 @@
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	fakes "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+type fakeReceiverTests map[string]time.Time
+
+func (f fakeReceiverTests) LastReceiverTests(context.Context, int64) (map[string]time.Time, error) {
+	return f, nil
+}
+
+type fakeFlapCounter map[models.AlertRuleKey]bool
+
+func (f fakeFlapCounter) Flapping(key models.AlertRuleKey) bool {
+	return f[key]
+}
+
+func query(refID, dsUID string, from time.Duration, model string) models.AlertQuery {
+	return models.AlertQuery{
+		RefID:             refID,
+		DatasourceUID:     dsUID,
+		RelativeTimeRange: models.RelativeTimeRange{From: models.Duration(from)},
+		Model:             json.RawMessage(model),
+	}
+}
+
+func TestLint(t *testing.T) {
+	ds := &fakes.FakeDataSourceService{DataSources: []*datasources.DataSource{{UID: "prometheus", OrgID: 1}}}
+	healthy := func() *models.AlertRule {
+		rule := models.RuleGen.With(models.RuleGen.WithOrgID(1)).GenerateRef()
+		rule.IntervalSeconds = 60
+		rule.For = 5 * time.Minute
+		rule.Labels = map[string]string{"team": "alerting"}
+		rule.NotificationSettings = nil
+		rule.Data = []models.AlertQuery{
+			query("A", "prometheus", 5*time.Minute, `{"expr":"up"}`),
+			query("B", "__expr__", 0, `{"type":"reduce","expression":"A"}`),
+		}
+		rule.Condition = "B"
+		return rule
+	}
+
+	testCases := []struct {
+		name     string
+		mutate   func(rule *models.AlertRule)
+		flapping bool
+		expected []string
+	}{
+		{
+			name:     "healthy rule",
+			mutate:   func(rule *models.AlertRule) {},
+			expected: nil,
+		},
+		{
+			name: "query range shorter than interval",
+			mutate: func(rule *models.AlertRule) {
+				rule.Data[0] = query("A", "prometheus", 30*time.Second, `{"expr":"up"}`)
+			},
+			expected: []string{models.RuleLintCheckQueryRangeShorterThanInterval},
+		},
+		{
+			name: "instant query with short range",
+			mutate: func(rule *models.AlertRule) {
+				rule.Data[0] = query("A", "prometheus", 30*time.Second, `{"expr":"rate(up[5m])","instant":true}`)
+			},
+			expected: nil,
+		},
+		{
+			name: "flapping rule without pending period",
+			mutate: func(rule *models.AlertRule) {
+				rule.For = 0
+			},
+			flapping: true,
+			expected: []string{models.RuleLintCheckFlappingWithoutPendingPeriod},
+		},
+		{
+			name:     "flapping rule with pending period",
+			mutate:   func(rule *models.AlertRule) {},
+			flapping: true,
+			expected: nil,
+		},
+		{
+			name: "label with value of query",
+			mutate: func(rule *models.AlertRule) {
+				rule.Labels["value"] = "{{ $values.B.Value }}"
+				rule.Labels["instance"] = "{{ $labels.instance }}"
+			},
+			expected: []string{models.RuleLintCheckHighCardinalityLabel},
+		},
+		{
+			name: "missing data source",
+			mutate: func(rule *models.AlertRule) {
+				rule.Data[0].DatasourceUID = "deleted"
+			},
+			expected: []string{models.RuleLintCheckMissingDataSource},
+		},
+		{
+			name: "untested contact point",
+			mutate: func(rule *models.AlertRule) {
+				rule.NotificationSettings = []models.NotificationSettings{{Receiver: "untested"}, {Receiver: "tested"}}
+			},
+			expected: []string{models.RuleLintCheckUntestedContactPoint},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := healthy()
+			tc.mutate(rule)
+			linter := NewLinter(ds, fakeReceiverTests{"tested": time.Now()}, fakeFlapCounter{rule.GetKey(): tc.flapping}, clock.NewMock(), log.NewNopLogger())
+
+			results := linter.Lint(context.Background(), 1, []*models.AlertRule{rule})
+			require.Len(t, results, 1)
+			var checks []string
+			for _, issue := range results[0].Issues {
+				checks = append(checks, issue.Check)
+			}
+			require.Equal(t, tc.expected, checks)
+			require.Equal(t, rule.UID, results[0].RuleUID)
+			require.Equal(t, models.RuleLintScore(results[0].Issues), results[0].Score)
+		})
+	}
+}
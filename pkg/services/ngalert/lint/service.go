@@ -0,0 +1,111 @@
+package lint
+
+import (
+	"context"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// defaultLintInterval is how often the rules are linted.
+const defaultLintInterval = time.Hour
+
+// Store reads the rules and stores the results of the linter.
+type Store interface {
+	ListAlertRules(ctx context.Context, query *models.ListAlertRulesQuery) (models.RulesGroup, error)
+	ListRuleLintResults(ctx context.Context, query *models.ListRuleLintResultsQuery) ([]models.RuleLintResult, error)
+	SaveRuleLintResults(ctx context.Context, results []models.RuleLintResult) error
+	DeleteRuleLintResults(ctx context.Context, orgID int64, ruleUIDs ...string) error
+}
+
+// Service lints the rules periodically and stores the results.
+// Whether the alerts of a rule flap is only known by the instance of Grafana that evaluates the rule, so every
+// instance lints the rules it evaluated recently, and the paused rules.
+type Service struct {
+	linter  *Linter
+	tracker *FlapTracker
+	store   Store
+	clock   clock.Clock
+	log     log.Logger
+
+	interval time.Duration
+}
+
+func NewService(linter *Linter, tracker *FlapTracker, store Store, clk clock.Clock, log log.Logger) *Service {
+	return &Service{
+		linter:   linter,
+		tracker:  tracker,
+		store:    store,
+		clock:    clk,
+		log:      log,
+		interval: defaultLintInterval,
+	}
+}
+
+// Run lints the rules periodically until the context is cancelled.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := s.clock.Ticker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.LintRules(ctx)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// LintRules lints the rules of all organizations that this instance of Grafana evaluated recently and the paused
+// rules, stores the results, and deletes the results of rules that no longer exist.
+func (s *Service) LintRules(ctx context.Context) {
+	start := s.clock.Now()
+	rules, err := s.store.ListAlertRules(ctx, &models.ListAlertRulesQuery{OrgID: -1})
+	if err != nil {
+		s.log.Error("Failed to list rules to lint", "error", err)
+		return
+	}
+	byOrg := make(map[int64][]*models.AlertRule)
+	exists := make(map[models.AlertRuleKey]struct{}, len(rules))
+	for _, rule := range rules {
+		exists[rule.GetKey()] = struct{}{}
+		if rule.IsPaused || s.tracker.Evaluated(rule.GetKey()) {
+			byOrg[rule.OrgID] = append(byOrg[rule.OrgID], rule)
+		}
+	}
+
+	linted := 0
+	for orgID, toLint := range byOrg {
+		if err := s.store.SaveRuleLintResults(ctx, s.linter.Lint(ctx, orgID, toLint)); err != nil {
+			s.log.Error("Failed to save rule lint results", "org", orgID, "error", err)
+			continue
+		}
+		linted += len(toLint)
+	}
+	s.deleteStale(ctx, exists)
+	s.tracker.Prune()
+	s.log.Debug("Rules linted", "rules", linted, "duration", s.clock.Since(start))
+}
+
+// deleteStale deletes the results of the rules that no longer exist, also in organizations that have no rules left.
+func (s *Service) deleteStale(ctx context.Context, exists map[models.AlertRuleKey]struct{}) {
+	stored, err := s.store.ListRuleLintResults(ctx, &models.ListRuleLintResultsQuery{OrgID: -1})
+	if err != nil {
+		s.log.Error("Failed to list rule lint results", "error", err)
+		return
+	}
+	stale := make(map[int64][]string)
+	for _, r := range stored {
+		if _, ok := exists[models.AlertRuleKey{OrgID: r.OrgID, UID: r.RuleUID}]; !ok {
+			stale[r.OrgID] = append(stale[r.OrgID], r.RuleUID)
+		}
+	}
+	for orgID, ruleUIDs := range stale {
+		if err := s.store.DeleteRuleLintResults(ctx, orgID, ruleUIDs...); err != nil {
+			s.log.Error("Failed to delete rule lint results of deleted rules", "org", orgID, "error", err)
+		}
+	}
+}
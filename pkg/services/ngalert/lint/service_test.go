@@ -0,0 +1,72 @@
+package lint
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	fakes "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+type fakeStore struct {
+	rules   models.RulesGroup
+	results map[models.AlertRuleKey]models.RuleLintResult
+}
+
+func (f *fakeStore) ListAlertRules(context.Context, *models.ListAlertRulesQuery) (models.RulesGroup, error) {
+	return f.rules, nil
+}
+
+func (f *fakeStore) ListRuleLintResults(_ context.Context, query *models.ListRuleLintResultsQuery) ([]models.RuleLintResult, error) {
+	var results []models.RuleLintResult
+	for _, r := range f.results {
+		if query.OrgID < 0 || r.OrgID == query.OrgID {
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+func (f *fakeStore) SaveRuleLintResults(_ context.Context, results []models.RuleLintResult) error {
+	for _, r := range results {
+		f.results[models.AlertRuleKey{OrgID: r.OrgID, UID: r.RuleUID}] = r
+	}
+	return nil
+}
+
+func (f *fakeStore) DeleteRuleLintResults(_ context.Context, orgID int64, ruleUIDs ...string) error {
+	for _, uid := range ruleUIDs {
+		delete(f.results, models.AlertRuleKey{OrgID: orgID, UID: uid})
+	}
+	return nil
+}
+
+func TestLintRules(t *testing.T) {
+	clk := clock.NewMock()
+	paused := models.RuleGen.With(models.RuleGen.WithOrgID(1), models.RuleGen.WithIsPaused(true)).GenerateRef()
+	deleted := models.RuleGen.With(models.RuleGen.WithOrgID(1)).GenerateRef()
+	deletedOtherOrg := models.RuleGen.With(models.RuleGen.WithOrgID(2)).GenerateRef()
+
+	store := &fakeStore{
+		rules: models.RulesGroup{paused},
+		results: map[models.AlertRuleKey]models.RuleLintResult{
+			deleted.GetKey():         models.NewRuleLintResult(deleted, nil, clk.Now()),
+			deletedOtherOrg.GetKey(): models.NewRuleLintResult(deletedOtherOrg, nil, clk.Now()),
+		},
+	}
+	linter := NewLinter(&fakes.FakeDataSourceService{}, fakeReceiverTests{}, fakeFlapCounter{}, clk, log.NewNopLogger())
+	s := NewService(linter, NewFlapTracker(clk), store, clk, log.NewNopLogger())
+
+	clk.Add(time.Minute)
+	s.LintRules(context.Background())
+
+	// the results of deleted rules are deleted, also in organizations without rules.
+	require.Len(t, store.results, 1)
+	require.Contains(t, store.results, paused.GetKey())
+	require.Equal(t, clk.Now(), store.results[paused.GetKey()].Linted)
+}
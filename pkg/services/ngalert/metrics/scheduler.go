@@ -31,6 +31,11 @@ type Scheduler struct {
 	UpdateSchedulableAlertRulesDuration prometheus.Histogram
 	Ticker                              *ticker.Metrics
 	EvaluationMissed                    *prometheus.CounterVec
+	RuleDependencyCycles                prometheus.Gauge
+	RecordingWriteDuration              *prometheus.HistogramVec
+	RecordingWriteFailures              *prometheus.CounterVec
+	ShardingMembers                     prometheus.Gauge
+	ShardingOwnedRules                  prometheus.Gauge
 }
 
 func NewSchedulerMetrics(r prometheus.Registerer) *Scheduler {
@@ -183,5 +188,48 @@ func NewSchedulerMetrics(r prometheus.Registerer) *Scheduler {
 			},
 			[]string{"org", "name"},
 		),
+		RuleDependencyCycles: promauto.With(r).NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "schedule_rule_dependency_cycles",
+				Help:      "The number of cycles in the dependencies between rules. Rules in a cycle are evaluated independently.",
+			},
+		),
+		RecordingWriteDuration: promauto.With(r).NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "recording_rule_write_duration_seconds",
+				Help:      "The time to write the result of a recording rule to its target.",
+				Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30},
+			},
+			[]string{"org", "target"},
+		),
+		RecordingWriteFailures: promauto.With(r).NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "recording_rule_write_failures_total",
+				Help:      "The total number of failed writes of recording rule results.",
+			},
+			[]string{"org", "target"},
+		),
+		ShardingMembers: promauto.With(r).NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "schedule_sharding_members",
+				Help:      "The number of instances the evaluation of alert rules is sharded across.",
+			},
+		),
+		ShardingOwnedRules: promauto.With(r).NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "schedule_sharding_owned_rules",
+				Help:      "The number of alert rules evaluated by this instance when the evaluation is sharded.",
+			},
+		),
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"hash/fnv"
 	"maps"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -545,7 +546,7 @@ func (alertRule *AlertRule) ValidateAlertRule(cfg setting.UnifiedAlertingSetting
 
 	var err error
 	if alertRule.Type() == RuleTypeRecording {
-		err = validateRecordingRuleFields(alertRule)
+		err = validateRecordingRuleFields(alertRule, cfg.RecordingRules)
 	} else {
 		err = validateAlertRuleFields(alertRule)
 	}
@@ -588,7 +589,7 @@ func validateAlertRuleFields(rule *AlertRule) error {
 	return nil
 }
 
-func validateRecordingRuleFields(rule *AlertRule) error {
+func validateRecordingRuleFields(rule *AlertRule, cfg setting.RecordingRuleSettings) error {
 	metricName := prommodels.LabelValue(rule.Record.Metric)
 	if !metricName.IsValid() {
 		return fmt.Errorf("%w: %s", ErrAlertRuleFailedValidation, "metric name for recording rule must be a valid utf8 string")
@@ -596,6 +597,13 @@ func validateRecordingRuleFields(rule *AlertRule) error {
 	if !prommodels.IsValidMetricName(metricName) {
 		return fmt.Errorf("%w: %s", ErrAlertRuleFailedValidation, "metric name for recording rule must be a valid Prometheus metric name")
 	}
+	target, err := ParseRecordTarget(string(rule.Record.Target))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrAlertRuleFailedValidation, err.Error())
+	}
+	if target != "" && !slices.Contains(ConfiguredRecordTargets(cfg), target) {
+		return fmt.Errorf("%w: recording rule target %q is not configured", ErrAlertRuleFailedValidation, target)
+	}
 	return nil
 }
 
@@ -857,6 +865,62 @@ type Record struct {
 	Metric string
 	// From contains a query RefID, indicating which expression node is the output of the recording rule.
 	From string
+	// Target is the writer that stores the results. Empty means the default writer configured for the instance.
+	Target RecordTarget `json:",omitempty"`
+}
+
+// RecordTarget is the type of storage the results of a recording rule are written to.
+type RecordTarget string
+
+const (
+	// RecordTargetPrometheus writes results to a Prometheus remote-write endpoint.
+	RecordTargetPrometheus RecordTarget = "prometheus"
+	// RecordTargetInfluxDB writes results to an InfluxDB line protocol endpoint.
+	RecordTargetInfluxDB RecordTarget = "influxdb"
+	// RecordTargetOTLP writes results to an OTLP/HTTP metrics endpoint.
+	RecordTargetOTLP RecordTarget = "otlp"
+	// RecordTargetSQL writes results to a table of the Grafana database that can be queried with the Grafana data source.
+	RecordTargetSQL RecordTarget = "sql"
+)
+
+// RecordTargets are all supported targets of recording rules.
+var RecordTargets = []RecordTarget{RecordTargetPrometheus, RecordTargetInfluxDB, RecordTargetOTLP, RecordTargetSQL}
+
+// ParseRecordTarget returns the RecordTarget with the given name. An empty name is the default target.
+func ParseRecordTarget(s string) (RecordTarget, error) {
+	if s == "" || slices.Contains(RecordTargets, RecordTarget(s)) {
+		return RecordTarget(s), nil
+	}
+	return "", fmt.Errorf("unknown recording rule target %q, must be one of %v", s, RecordTargets)
+}
+
+// ConfiguredRecordTargets returns the targets that a writer is configured for.
+// The Prometheus target is always configured because it is the default target.
+func ConfiguredRecordTargets(cfg setting.RecordingRuleSettings) []RecordTarget {
+	targets := []RecordTarget{RecordTargetPrometheus}
+	if cfg.InfluxDB.URL != "" {
+		targets = append(targets, RecordTargetInfluxDB)
+	}
+	if cfg.OTLP.URL != "" {
+		targets = append(targets, RecordTargetOTLP)
+	}
+	if cfg.SQL.Enabled {
+		targets = append(targets, RecordTargetSQL)
+	}
+	return targets
+}
+
+type recordTargetContextKey struct{}
+
+// WithRecordTarget returns a context with the target of the recording rule that is evaluated.
+func WithRecordTarget(ctx context.Context, target RecordTarget) context.Context {
+	return context.WithValue(ctx, recordTargetContextKey{}, target)
+}
+
+// RecordTargetFromContext returns the target of the recording rule that is evaluated.
+func RecordTargetFromContext(ctx context.Context) (RecordTarget, bool) {
+	target, ok := ctx.Value(recordTargetContextKey{}).(RecordTarget)
+	return target, ok
 }
 
 func (r *Record) Fingerprint() data.Fingerprint {
@@ -871,5 +935,9 @@ func (r *Record) Fingerprint() data.Fingerprint {
 
 	writeString(r.Metric)
 	writeString(r.From)
+	// the target is only added if it is set, so that the fingerprints of existing rules do not change.
+	if r.Target != "" {
+		writeString(string(r.Target))
+	}
 	return data.Fingerprint(h.Sum64())
 }
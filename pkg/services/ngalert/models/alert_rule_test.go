@@ -17,6 +17,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 
+	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
 	"github.com/grafana/grafana/pkg/util/cmputil"
 )
@@ -99,6 +100,57 @@ func TestNoDataStateFromString(t *testing.T) {
 	})
 }
 
+func TestParseRecordTarget(t *testing.T) {
+	t.Run("should parse known values", func(t *testing.T) {
+		for _, target := range RecordTargets {
+			actual, err := ParseRecordTarget(string(target))
+			require.NoError(t, err)
+			require.Equal(t, target, actual)
+		}
+	})
+
+	t.Run("should accept empty value as the default target", func(t *testing.T) {
+		actual, err := ParseRecordTarget("")
+		require.NoError(t, err)
+		require.Equal(t, RecordTarget(""), actual)
+	})
+
+	t.Run("should fail to parse unknown values", func(t *testing.T) {
+		input := util.GenerateShortUID()
+		actual, err := ParseRecordTarget(input)
+		require.Errorf(t, err, "expected error for input value [%s]", input)
+		require.Equal(t, RecordTarget(""), actual)
+	})
+}
+
+func TestValidateAlertRuleRecordTarget(t *testing.T) {
+	cfg := setting.UnifiedAlertingSettings{
+		BaseInterval: 10 * time.Second,
+		RecordingRules: setting.RecordingRuleSettings{
+			InfluxDB: setting.RecordingRuleInfluxDBSettings{URL: "http://influxdb:8086"},
+		},
+	}
+	gen := RuleGen.With(RuleGen.WithAllRecordingRules(), RuleGen.WithIntervalSeconds(10))
+
+	t.Run("should accept the default and configured targets", func(t *testing.T) {
+		for _, target := range []RecordTarget{"", RecordTargetPrometheus, RecordTargetInfluxDB} {
+			rule := gen.GenerateRef()
+			rule.Record.Target = target
+			require.NoError(t, rule.ValidateAlertRule(cfg), "target %q", target)
+		}
+	})
+
+	t.Run("should reject targets that are not configured", func(t *testing.T) {
+		for _, target := range []RecordTarget{RecordTargetOTLP, RecordTargetSQL} {
+			rule := gen.GenerateRef()
+			rule.Record.Target = target
+			err := rule.ValidateAlertRule(cfg)
+			require.ErrorIs(t, err, ErrAlertRuleFailedValidation)
+			require.ErrorContains(t, err, "is not configured")
+		}
+	})
+}
+
 func TestErrStateFromString(t *testing.T) {
 	allKnownErrStates := [...]ExecutionErrorState{
 		AlertingErrState,
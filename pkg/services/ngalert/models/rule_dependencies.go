@@ -0,0 +1,355 @@
+package models
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// grafanaDatasourceUID is the UID of the -- Grafana -- data source, which queries the metrics written by the sql target.
+const grafanaDatasourceUID = "grafana"
+
+// RecordTargetDatasources maps the targets of recording rules to the UIDs of the data sources the written metrics
+// are queried with. The empty target is the default writer of the instance.
+type RecordTargetDatasources map[RecordTarget]string
+
+// RecordTargetDatasourcesFromSettings returns the data sources configured for the writers of recording rules.
+func RecordTargetDatasourcesFromSettings(cfg setting.RecordingRuleSettings) RecordTargetDatasources {
+	result := RecordTargetDatasources{
+		RecordTargetPrometheus: cfg.DatasourceUID,
+		RecordTargetInfluxDB:   cfg.InfluxDB.DatasourceUID,
+		RecordTargetOTLP:       cfg.OTLP.DatasourceUID,
+		RecordTargetSQL:        grafanaDatasourceUID,
+	}
+	result[""] = result[RecordTarget(cfg.DefaultTarget)]
+	return result
+}
+
+// RuleDependencyGraph describes which rules read the results of Grafana-managed recording rules.
+//
+// A rule depends on a recording rule of the same organization if one of its queries against the data source
+// of the target of the recording rule references the metric written by the recording rule. Rules of the same group declare their order
+// by their index in the group: a rule only depends on recording rules that come before it in the group,
+// a recording rule that comes after it is read with the result of the previous evaluation, like in Prometheus.
+//
+// Dependencies that form a cycle are reported by Cycles and are not returned by Dependencies and Dependents,
+// so that the remaining graph is acyclic.
+type RuleDependencyGraph struct {
+	rules        map[AlertRuleKey]*AlertRule
+	dependencies map[AlertRuleKey][]AlertRuleKey
+	dependents   map[AlertRuleKey][]AlertRuleKey
+	// cyclic contains the dependencies between the rules of the same cycle.
+	cyclic  map[AlertRuleKey][]AlertRuleKey
+	cycles  [][]AlertRuleKey
+	inCycle map[AlertRuleKey]struct{}
+}
+
+// RuleDependency is an edge of the RuleDependencyGraph.
+type RuleDependency struct {
+	// Recording is the recording rule that writes the metric.
+	Recording AlertRuleKey
+	// Dependent is the rule that reads the metric.
+	Dependent AlertRuleKey
+	// Cyclic is true if the dependency is part of a cycle and is ignored for scheduling.
+	Cyclic bool
+}
+
+// NewRuleDependencyGraph computes the dependencies between the given rules. If the data source of the target of
+// a recording rule is unknown, queries against any data source can read its metric.
+func NewRuleDependencyGraph(rules []*AlertRule, datasources RecordTargetDatasources) *RuleDependencyGraph {
+	g := &RuleDependencyGraph{
+		rules:        make(map[AlertRuleKey]*AlertRule, len(rules)),
+		dependencies: map[AlertRuleKey][]AlertRuleKey{},
+		dependents:   map[AlertRuleKey][]AlertRuleKey{},
+		cyclic:       map[AlertRuleKey][]AlertRuleKey{},
+		inCycle:      map[AlertRuleKey]struct{}{},
+	}
+	recordingByOrg := map[int64][]*AlertRule{}
+	for _, rule := range rules {
+		g.rules[rule.GetKey()] = rule
+		if rule.Type() == RuleTypeRecording && rule.Record.Metric != "" {
+			recordingByOrg[rule.OrgID] = append(recordingByOrg[rule.OrgID], rule)
+		}
+	}
+	if len(recordingByOrg) == 0 {
+		return g
+	}
+
+	all := map[AlertRuleKey][]AlertRuleKey{}
+	for _, rule := range rules {
+		recordings := recordingByOrg[rule.OrgID]
+		if len(recordings) == 0 {
+			continue
+		}
+		queries := metricQueries(rule)
+		for _, recording := range recordings {
+			if recording.UID == rule.UID || !readsMetric(queries, recording.Record.Metric, datasources[recording.Record.Target]) {
+				continue
+			}
+			if recording.GetGroupKey() == rule.GetGroupKey() && recording.RuleGroupIndex >= rule.RuleGroupIndex {
+				continue
+			}
+			all[rule.GetKey()] = append(all[rule.GetKey()], recording.GetKey())
+		}
+	}
+
+	g.cycles = findCycles(all)
+	component := map[AlertRuleKey]int{}
+	for i, cycle := range g.cycles {
+		for _, key := range cycle {
+			component[key] = i
+			g.inCycle[key] = struct{}{}
+		}
+	}
+	for dependent, recordings := range all {
+		for _, recording := range recordings {
+			c1, ok1 := component[dependent]
+			c2, ok2 := component[recording]
+			if ok1 && ok2 && c1 == c2 {
+				g.cyclic[dependent] = append(g.cyclic[dependent], recording)
+				continue
+			}
+			g.dependencies[dependent] = append(g.dependencies[dependent], recording)
+			g.dependents[recording] = append(g.dependents[recording], dependent)
+		}
+	}
+	for _, keys := range g.dependencies {
+		slices.SortFunc(keys, compareRuleKeys)
+	}
+	for _, keys := range g.dependents {
+		slices.SortFunc(keys, compareRuleKeys)
+	}
+	return g
+}
+
+// Rule returns the rule with the given key.
+func (g *RuleDependencyGraph) Rule(key AlertRuleKey) (*AlertRule, bool) {
+	rule, ok := g.rules[key]
+	return rule, ok
+}
+
+// Dependencies returns the recording rules the rule reads, sorted by UID.
+func (g *RuleDependencyGraph) Dependencies(key AlertRuleKey) []AlertRuleKey {
+	return g.dependencies[key]
+}
+
+// Dependents returns the rules that read the result of the recording rule, sorted by UID.
+func (g *RuleDependencyGraph) Dependents(key AlertRuleKey) []AlertRuleKey {
+	return g.dependents[key]
+}
+
+// Cycles returns the groups of rules that depend on each other. Each cycle is sorted by UID.
+func (g *RuleDependencyGraph) Cycles() [][]AlertRuleKey {
+	return g.cycles
+}
+
+// InCycle returns true if the rule is part of a dependency cycle.
+func (g *RuleDependencyGraph) InCycle(key AlertRuleKey) bool {
+	_, ok := g.inCycle[key]
+	return ok
+}
+
+// Edges returns all dependencies including the cyclic ones, sorted by the recording rule and then by the dependent rule.
+func (g *RuleDependencyGraph) Edges() []RuleDependency {
+	var edges []RuleDependency
+	for dependent, recordings := range g.dependencies {
+		for _, recording := range recordings {
+			edges = append(edges, RuleDependency{Recording: recording, Dependent: dependent})
+		}
+	}
+	for dependent, recordings := range g.cyclic {
+		for _, recording := range recordings {
+			edges = append(edges, RuleDependency{Recording: recording, Dependent: dependent, Cyclic: true})
+		}
+	}
+	slices.SortFunc(edges, func(a, b RuleDependency) int {
+		if c := compareRuleKeys(a.Recording, b.Recording); c != 0 {
+			return c
+		}
+		return compareRuleKeys(a.Dependent, b.Dependent)
+	})
+	return edges
+}
+
+// metricQuery is the part of a data source query that can reference metrics.
+type metricQuery struct {
+	datasourceUID string
+	// metric is the metric selected by name, like queries of the -- Grafana -- data source do.
+	metric string
+	// expr is the PromQL or LogQL expression without quoted strings, which are label values.
+	expr string
+	// query is the query of other data sources, for example InfluxQL or Flux. It can quote measurement names.
+	query string
+}
+
+// metricQueries returns the data source queries of the rule.
+func metricQueries(rule *AlertRule) []metricQuery {
+	result := make([]metricQuery, 0, len(rule.Data))
+	for i := range rule.Data {
+		q := &rule.Data[i]
+		if isExpr, err := q.IsExpression(); err != nil || isExpr {
+			continue
+		}
+		m := struct {
+			Metric string `json:"metric"`
+			Expr   string `json:"expr"`
+			Query  string `json:"query"`
+		}{}
+		if err := json.Unmarshal(q.Model, &m); err != nil {
+			continue
+		}
+		result = append(result, metricQuery{
+			datasourceUID: q.DatasourceUID,
+			metric:        m.Metric,
+			expr:          withoutQuotedStrings(m.Expr, "__name__"),
+			query:         m.Query,
+		})
+	}
+	return result
+}
+
+// readsMetric returns true if any of the queries against the data source references the metric as a whole token.
+// If datasourceUID is empty, queries against any data source are considered.
+func readsMetric(queries []metricQuery, metric, datasourceUID string) bool {
+	for _, q := range queries {
+		if datasourceUID != "" && q.datasourceUID != datasourceUID {
+			continue
+		}
+		if q.metric == metric || containsMetricName(q.expr, metric) || containsMetricName(q.query, metric) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutQuotedStrings replaces the quoted strings of a PromQL or LogQL expression with spaces, except the values
+// of the label keep, e.g. the metric name in {__name__="metric"}.
+func withoutQuotedStrings(s string, keep string) string {
+	result := []byte(s)
+	var quote byte
+	erase := true
+	for i := 0; i < len(result); i++ {
+		c := result[i]
+		switch {
+		case quote == 0 && (c == '"' || c == '\'' || c == '`'):
+			quote = c
+			erase = !isValueOf(s[:i], keep)
+		case quote == 0:
+			continue
+		case c == '\\' && quote != '`' && i+1 < len(result):
+			if erase {
+				result[i], result[i+1] = ' ', ' '
+			}
+			i++
+			continue
+		case c == quote:
+			quote = 0
+		}
+		if erase {
+			result[i] = ' '
+		}
+	}
+	return string(result)
+}
+
+// isValueOf returns true if the expression ends with an equality matcher of the label.
+func isValueOf(expr, label string) bool {
+	expr = strings.TrimRight(expr, " ")
+	if !strings.HasSuffix(expr, "=") || strings.HasSuffix(expr, "!=") {
+		return false
+	}
+	expr = strings.TrimRight(strings.TrimSuffix(expr, "="), " ")
+	return strings.HasSuffix(expr, label) && (len(expr) == len(label) || !isMetricNameChar(expr[len(expr)-len(label)-1]))
+}
+
+// containsMetricName returns true if s contains the metric name as a whole token,
+// i.e. not as a part of a longer metric or label name.
+func containsMetricName(s, metric string) bool {
+	for offset := 0; offset < len(s); {
+		idx := strings.Index(s[offset:], metric)
+		if idx < 0 {
+			return false
+		}
+		start := offset + idx
+		end := start + len(metric)
+		if (start == 0 || !isMetricNameChar(s[start-1])) && (end == len(s) || !isMetricNameChar(s[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+	return false
+}
+
+func isMetricNameChar(c byte) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// findCycles returns the strongly connected components of the graph with more than one rule, using Tarjan's algorithm.
+func findCycles(edges map[AlertRuleKey][]AlertRuleKey) [][]AlertRuleKey {
+	nodes := make([]AlertRuleKey, 0, len(edges))
+	for key := range edges {
+		nodes = append(nodes, key)
+	}
+	slices.SortFunc(nodes, compareRuleKeys)
+
+	var (
+		index   = map[AlertRuleKey]int{}
+		lowLink = map[AlertRuleKey]int{}
+		onStack = map[AlertRuleKey]bool{}
+		stack   []AlertRuleKey
+		cycles  [][]AlertRuleKey
+		visit   func(key AlertRuleKey)
+	)
+	visit = func(key AlertRuleKey) {
+		index[key] = len(index)
+		lowLink[key] = index[key]
+		stack = append(stack, key)
+		onStack[key] = true
+		for _, next := range edges[key] {
+			if _, visited := index[next]; !visited {
+				visit(next)
+				lowLink[key] = min(lowLink[key], lowLink[next])
+			} else if onStack[next] {
+				lowLink[key] = min(lowLink[key], index[next])
+			}
+		}
+		if lowLink[key] != index[key] {
+			return
+		}
+		var component []AlertRuleKey
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == key {
+				break
+			}
+		}
+		if len(component) > 1 {
+			slices.SortFunc(component, compareRuleKeys)
+			cycles = append(cycles, component)
+		}
+	}
+	for _, key := range nodes {
+		if _, visited := index[key]; !visited {
+			visit(key)
+		}
+	}
+	slices.SortFunc(cycles, func(a, b []AlertRuleKey) int {
+		return compareRuleKeys(a[0], b[0])
+	})
+	return cycles
+}
+
+func compareRuleKeys(a, b AlertRuleKey) int {
+	if a.OrgID != b.OrgID {
+		if a.OrgID < b.OrgID {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a.UID, b.UID)
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestRuleDependencyGraph(t *testing.T) {
+	promQuery := func(expr string) AlertQuery {
+		return AlertQuery{
+			RefID:         "A",
+			DatasourceUID: "prometheus",
+			Model:         json.RawMessage(fmt.Sprintf(`{"expr": %q}`, expr)),
+		}
+	}
+	recording := func(uid, metric, expr string) *AlertRule {
+		return &AlertRule{OrgID: 1, UID: uid, NamespaceUID: "folder", RuleGroup: uid, Record: &Record{Metric: metric, From: "A"}, Data: []AlertQuery{promQuery(expr)}}
+	}
+	alerting := func(uid, expr string) *AlertRule {
+		return &AlertRule{OrgID: 1, UID: uid, NamespaceUID: "folder", RuleGroup: uid, Data: []AlertQuery{promQuery(expr)}}
+	}
+	key := func(uid string) AlertRuleKey {
+		return AlertRuleKey{OrgID: 1, UID: uid}
+	}
+
+	t.Run("should find rules that read the metric of a recording rule", func(t *testing.T) {
+		rules := []*AlertRule{
+			recording("rec", "job:requests:rate5m", "sum(rate(requests_total[5m])) by (job)"),
+			alerting("alert", "job:requests:rate5m > 10"),
+			alerting("other", "job:requests:rate5m_total > 10"),
+		}
+		g := NewRuleDependencyGraph(rules, nil)
+		assert.Equal(t, []AlertRuleKey{key("rec")}, g.Dependencies(key("alert")))
+		assert.Empty(t, g.Dependencies(key("other")))
+		assert.Equal(t, []AlertRuleKey{key("alert")}, g.Dependents(key("rec")))
+		assert.Empty(t, g.Cycles())
+	})
+
+	t.Run("should not depend on rules of other organizations", func(t *testing.T) {
+		rec := recording("rec", "job:requests:rate5m", "requests_total")
+		rec.OrgID = 2
+		g := NewRuleDependencyGraph([]*AlertRule{rec, alerting("alert", "job:requests:rate5m > 10")}, nil)
+		assert.Empty(t, g.Dependencies(key("alert")))
+	})
+
+	t.Run("should respect the order of rules in the same group", func(t *testing.T) {
+		rec := recording("rec", "job:requests:rate5m", "requests_total")
+		before := alerting("before", "job:requests:rate5m > 10")
+		after := alerting("after", "job:requests:rate5m > 10")
+		for i, r := range []*AlertRule{before, rec, after} {
+			r.RuleGroup = "group"
+			r.RuleGroupIndex = i + 1
+		}
+		g := NewRuleDependencyGraph([]*AlertRule{before, rec, after}, nil)
+		assert.Empty(t, g.Dependencies(key("before")))
+		assert.Equal(t, []AlertRuleKey{key("rec")}, g.Dependencies(key("after")))
+	})
+
+	t.Run("should detect cycles", func(t *testing.T) {
+		rules := []*AlertRule{
+			recording("a", "metric_a", "metric_c + 1"),
+			recording("b", "metric_b", "metric_a + 1"),
+			recording("c", "metric_c", "metric_b + 1"),
+			alerting("alert", "metric_a > 1"),
+		}
+		g := NewRuleDependencyGraph(rules, nil)
+		require.Equal(t, [][]AlertRuleKey{{key("a"), key("b"), key("c")}}, g.Cycles())
+		assert.True(t, g.InCycle(key("b")))
+		assert.False(t, g.InCycle(key("alert")))
+		assert.Empty(t, g.Dependencies(key("a")))
+		assert.Equal(t, []AlertRuleKey{key("a")}, g.Dependencies(key("alert")))
+		assert.Equal(t, []RuleDependency{
+			{Recording: key("a"), Dependent: key("alert")},
+			{Recording: key("a"), Dependent: key("b"), Cyclic: true},
+			{Recording: key("b"), Dependent: key("c"), Cyclic: true},
+			{Recording: key("c"), Dependent: key("a"), Cyclic: true},
+		}, g.Edges())
+	})
+
+	t.Run("should only consider queries against the data source of the target", func(t *testing.T) {
+		rec := recording("rec", "job:requests:rate5m", "requests_total")
+		rec.Record.Target = RecordTargetInfluxDB
+		influx := alerting("influx", "")
+		influx.Data = []AlertQuery{{RefID: "A", DatasourceUID: "influx", Model: json.RawMessage(`{"query": "SELECT last(\"value\") FROM \"job:requests:rate5m\""}`)}}
+		prom := alerting("prom", "job:requests:rate5m > 10")
+		datasources := RecordTargetDatasources{RecordTargetInfluxDB: "influx", "": "prometheus"}
+
+		g := NewRuleDependencyGraph([]*AlertRule{rec, influx, prom}, datasources)
+		assert.Equal(t, []AlertRuleKey{key("rec")}, g.Dependencies(key("influx")))
+		assert.Empty(t, g.Dependencies(key("prom")))
+
+		// without a configured data source, queries against any data source are considered.
+		g = NewRuleDependencyGraph([]*AlertRule{rec, influx, prom}, nil)
+		assert.Equal(t, []AlertRuleKey{key("rec")}, g.Dependencies(key("influx")))
+		assert.Equal(t, []AlertRuleKey{key("rec")}, g.Dependencies(key("prom")))
+	})
+
+	t.Run("should match the metric name as a whole token of the query", func(t *testing.T) {
+		rec := recording("rec", "up", "vector(1)")
+		rules := []*AlertRule{
+			rec,
+			alerting("reads", "up == 0"),
+			alerting("selector", `{__name__="up", job="api"} == 0`),
+			alerting("label-value", `node_load1{job="up"} > 1`),
+			alerting("longer-name", "up_time > 1 or node:up"),
+			alerting("regexp", `{__name__=~"up"} == 0`),
+		}
+		// the name of a JSON field is not a part of the query.
+		legend := alerting("legend", "node_load1 > 1")
+		legend.Data[0].Model = json.RawMessage(`{"expr": "node_load1 > 1", "legendFormat": "up"}`)
+		rules = append(rules, legend)
+
+		g := NewRuleDependencyGraph(rules, nil)
+		assert.Equal(t, []AlertRuleKey{key("rec")}, g.Dependencies(key("reads")))
+		assert.Equal(t, []AlertRuleKey{key("rec")}, g.Dependencies(key("selector")))
+		assert.Empty(t, g.Dependencies(key("label-value")))
+		assert.Empty(t, g.Dependencies(key("longer-name")))
+		assert.Empty(t, g.Dependencies(key("regexp")))
+		assert.Empty(t, g.Dependencies(key("legend")))
+	})
+
+	t.Run("should find rules that read recorded metrics of the Grafana data source", func(t *testing.T) {
+		rec := recording("rec", "job:requests:rate5m", "requests_total")
+		rec.Record.Target = RecordTargetSQL
+		alert := alerting("alert", "")
+		alert.Data = []AlertQuery{{RefID: "A", DatasourceUID: grafanaDatasourceUID, Model: json.RawMessage(`{"queryType": "recordedMetric", "metric": "job:requests:rate5m"}`)}}
+
+		g := NewRuleDependencyGraph([]*AlertRule{rec, alert}, RecordTargetDatasourcesFromSettings(setting.RecordingRuleSettings{DefaultTarget: "prometheus"}))
+		assert.Equal(t, []AlertRuleKey{key("rec")}, g.Dependencies(key("alert")))
+	})
+}
+
+func TestRecordTargetDatasourcesFromSettings(t *testing.T) {
+	datasources := RecordTargetDatasourcesFromSettings(setting.RecordingRuleSettings{
+		DefaultTarget: "influxdb",
+		DatasourceUID: "prometheus",
+		InfluxDB:      setting.RecordingRuleInfluxDBSettings{DatasourceUID: "influx"},
+	})
+	assert.Equal(t, RecordTargetDatasources{
+		"":                     "influx",
+		RecordTargetPrometheus: "prometheus",
+		RecordTargetInfluxDB:   "influx",
+		RecordTargetOTLP:       "",
+		RecordTargetSQL:        grafanaDatasourceUID,
+	}, datasources)
+}
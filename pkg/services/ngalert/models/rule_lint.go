@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+)
+
+// RuleLintSeverity is the severity of a problem found by the rule linter.
+type RuleLintSeverity string
+
+const (
+	RuleLintSeverityInfo    RuleLintSeverity = "info"
+	RuleLintSeverityWarning RuleLintSeverity = "warning"
+	RuleLintSeverityError   RuleLintSeverity = "error"
+)
+
+// Checks of the rule linter.
+const (
+	// RuleLintCheckQueryRangeShorterThanInterval reports queries that read less data than is produced between two
+	// evaluations of the rule, so that some data is never evaluated.
+	RuleLintCheckQueryRangeShorterThanInterval = "query-range-shorter-than-interval"
+	// RuleLintCheckFlappingWithoutPendingPeriod reports rules without a pending period whose alerts repeatedly start
+	// firing and resolve.
+	RuleLintCheckFlappingWithoutPendingPeriod = "flapping-without-pending-period"
+	// RuleLintCheckHighCardinalityLabel reports labels whose value changes with the query result, which creates
+	// a new alert instance every time the value changes.
+	RuleLintCheckHighCardinalityLabel = "high-cardinality-label"
+	// RuleLintCheckMissingDataSource reports queries of data sources that do not exist.
+	RuleLintCheckMissingDataSource = "missing-data-source"
+	// RuleLintCheckUntestedContactPoint reports contact points of simplified routing that were never tested.
+	RuleLintCheckUntestedContactPoint = "untested-contact-point"
+)
+
+// ruleLintPenalty is how much each problem of a severity lowers the score of a rule.
+var ruleLintPenalty = map[RuleLintSeverity]int{
+	RuleLintSeverityInfo:    5,
+	RuleLintSeverityWarning: 15,
+	RuleLintSeverityError:   40,
+}
+
+// RuleLintMaxScore is the score of a rule without problems.
+const RuleLintMaxScore = 100
+
+// RuleLintIssue is a problem found by the rule linter.
+type RuleLintIssue struct {
+	Check    string           `json:"check"`
+	Severity RuleLintSeverity `json:"severity"`
+	Message  string           `json:"message"`
+	// RefID is the query the problem was found in, if any.
+	RefID string `json:"refId,omitempty"`
+}
+
+// RuleLintResult is the result of linting a rule.
+type RuleLintResult struct {
+	OrgID        int64
+	RuleUID      string
+	NamespaceUID string
+	RuleGroup    string
+	Issues       []RuleLintIssue
+	// Score is between 0 and RuleLintMaxScore, and lower the more and the more severe the problems of the rule are.
+	Score  int
+	Linted time.Time
+}
+
+// NewRuleLintResult returns the result of linting a rule with the given problems.
+func NewRuleLintResult(rule *AlertRule, issues []RuleLintIssue, linted time.Time) RuleLintResult {
+	if issues == nil {
+		issues = []RuleLintIssue{}
+	}
+	return RuleLintResult{
+		OrgID:        rule.OrgID,
+		RuleUID:      rule.UID,
+		NamespaceUID: rule.NamespaceUID,
+		RuleGroup:    rule.RuleGroup,
+		Issues:       issues,
+		Score:        RuleLintScore(issues),
+		Linted:       linted,
+	}
+}
+
+// RuleLintScore returns the score of a rule with the given problems.
+func RuleLintScore(issues []RuleLintIssue) int {
+	score := RuleLintMaxScore
+	for _, issue := range issues {
+		score -= ruleLintPenalty[issue.Severity]
+	}
+	return max(score, 0)
+}
+
+// MaxSeverity returns the highest severity of the problems of the rule, or an empty string if there are none.
+func (r RuleLintResult) MaxSeverity() RuleLintSeverity {
+	var result RuleLintSeverity
+	for _, issue := range r.Issues {
+		if ruleLintPenalty[issue.Severity] > ruleLintPenalty[result] {
+			result = issue.Severity
+		}
+	}
+	return result
+}
+
+// ListRuleLintResultsQuery is the query for the stored results of the rule linter.
+type ListRuleLintResultsQuery struct {
+	// OrgID is the organization of the results, the results of all organizations are listed if it is negative.
+	OrgID int64
+	// RuleUIDs limits the results to the given rules, if not empty.
+	RuleUIDs []string
+}
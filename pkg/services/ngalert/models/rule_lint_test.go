@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleLintScore(t *testing.T) {
+	require.Equal(t, RuleLintMaxScore, RuleLintScore(nil))
+	require.Equal(t, 40, RuleLintScore([]RuleLintIssue{
+		{Severity: RuleLintSeverityInfo},
+		{Severity: RuleLintSeverityWarning},
+		{Severity: RuleLintSeverityError},
+	}))
+	require.Equal(t, 0, RuleLintScore([]RuleLintIssue{
+		{Severity: RuleLintSeverityError},
+		{Severity: RuleLintSeverityError},
+		{Severity: RuleLintSeverityError},
+	}), "score should not be negative")
+}
+
+func TestRuleLintResultMaxSeverity(t *testing.T) {
+	require.Empty(t, RuleLintResult{}.MaxSeverity())
+	require.Equal(t, RuleLintSeverityWarning, RuleLintResult{Issues: []RuleLintIssue{
+		{Severity: RuleLintSeverityInfo},
+		{Severity: RuleLintSeverityWarning},
+		{Severity: RuleLintSeverityInfo},
+	}}.MaxSeverity())
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/common/model"
 	"golang.org/x/exp/maps"
 
 	alertingModels "github.com/grafana/alerting/models"
@@ -18,6 +20,25 @@ func (s Silence) GetRuleUID() *string {
 	return getRuleUIDLabelValue(s.Silence)
 }
 
+// IsActive returns true if the silence is active.
+func (s Silence) IsActive() bool {
+	return s.Status != nil && s.Status.State != nil && *s.Status.State == amv2.SilenceStatusStateActive
+}
+
+// Matches returns true if the matchers of the silence match the labels.
+func (s Silence) Matches(lset model.LabelSet) bool {
+	for _, m := range s.Matchers {
+		if m == nil || m.Name == nil || m.Value == nil || m.IsRegex == nil {
+			return false
+		}
+		matcher, err := labels.NewMatcher(matcherType(*m), *m.Name, *m.Value)
+		if err != nil || !matcher.Matches(string(lset[model.LabelName(*m.Name)])) {
+			return false
+		}
+	}
+	return len(s.Matchers) > 0
+}
+
 // getRuleUIDLabelValue returns the value of the RuleUIDLabel matcher in the given silence, if it exists.
 func getRuleUIDLabelValue(silence notify.Silence) *string {
 	for _, m := range silence.Matchers {
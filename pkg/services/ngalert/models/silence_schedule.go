@@ -0,0 +1,297 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/robfig/cron/v3"
+
+	"github.com/grafana/alerting/notify"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+)
+
+var (
+	ErrSilenceScheduleNotFound   = errutil.NotFound("alerting.silence-schedule.notFound", errutil.WithPublicMessage("Silence schedule not found"))
+	ErrSilenceScheduleValidation = errutil.ValidationFailed("alerting.silence-schedule.invalidFormat")
+	ErrSilenceScheduleExists     = errutil.Conflict("alerting.silence-schedule.exists", errutil.WithPublicMessage("Silence schedule with the same title already exists"))
+	ErrSilenceScheduleConflict   = errutil.Conflict("alerting.silence-schedule.conflict", errutil.WithPublicMessage("Silence schedule was changed by someone else, please try again"))
+)
+
+const (
+	// maxSilenceScheduleTitleLength is the maximum length of the title of a silence schedule.
+	maxSilenceScheduleTitleLength = 190
+	// MaxSilenceSchedulePeriods is the maximum number of periods that are returned by SilenceSchedule.Periods,
+	// to limit the number of silences that are created at once for schedules that recur very often.
+	MaxSilenceSchedulePeriods = 100
+)
+
+// SilenceSchedule is a saved silence definition that recurs. The silences of the periods of the recurrence are
+// created in the Alertmanager ahead of time, and the schedule keeps track of them so that they can be expired
+// when the schedule changes. The recurrence is either a cron expression and a duration, or time intervals in the
+// same format as the time intervals of mute timings.
+type SilenceSchedule struct {
+	UID     string
+	OrgID   int64
+	Title   string
+	Comment string
+	// Matchers are the matchers of the silences.
+	Matchers amv2.Matchers
+	// Cron is the cron expression of the start times of the silences. Each silence lasts Duration.
+	Cron     string
+	Duration time.Duration
+	// Location is the time zone of the cron expression. Defaults to UTC.
+	Location string
+	// TimeIntervals are the time intervals of the silences. There is a silence for every range of times in
+	// every day that matches an interval, or for the whole day if the interval has no times.
+	TimeIntervals []timeinterval.TimeInterval
+	// IsPaused is true if silences must not be created for the schedule.
+	IsPaused bool
+	// Silences are the silences that were created for the schedule and did not end yet.
+	Silences []ScheduledSilence
+
+	// CreatedBy and UpdatedBy are the UIDs of the identities that created and last updated the schedule.
+	CreatedBy string
+	Created   time.Time
+	UpdatedBy string
+	Updated   time.Time
+	// Version is incremented every time the schedule or its silences are changed.
+	Version int64
+}
+
+// ScheduledSilence is a silence of a period of a silence schedule.
+type ScheduledSilence struct {
+	ID string `json:"id"`
+	// StartsAt is the start of the period, which is different from the start of the silence if the
+	// silence was created when the period already started.
+	StartsAt time.Time `json:"startsAt"`
+	EndsAt   time.Time `json:"endsAt"`
+}
+
+// SilencePeriod is a period of time silenced by a silence schedule.
+type SilencePeriod struct {
+	StartsAt time.Time
+	EndsAt   time.Time
+}
+
+// ListSilenceSchedulesQuery is the query of silence schedules. OrgID 0 means all organizations.
+type ListSilenceSchedulesQuery struct {
+	OrgID         int64
+	ExcludePaused bool
+}
+
+// SilenceScheduleWithMetadata is a silence schedule with the permissions of the user on its silences.
+type SilenceScheduleWithMetadata struct {
+	*SilenceSchedule
+	Permissions *SilencePermissionSet
+}
+
+// Validate returns an error if the schedule is not valid.
+func (s *SilenceSchedule) Validate() error {
+	if s.Title == "" {
+		return ErrSilenceScheduleValidation.Errorf("title is required")
+	}
+	if len(s.Title) > maxSilenceScheduleTitleLength {
+		return ErrSilenceScheduleValidation.Errorf("title is longer than %d characters", maxSilenceScheduleTitleLength)
+	}
+	if err := validateSilenceMatchers(s.Matchers); err != nil {
+		return ErrSilenceScheduleValidation.Errorf("invalid matchers: %w", err)
+	}
+	switch {
+	case s.Cron != "" && len(s.TimeIntervals) > 0:
+		return ErrSilenceScheduleValidation.Errorf("only one of cron and time intervals can be set")
+	case s.Cron != "":
+		if s.Duration <= 0 {
+			return ErrSilenceScheduleValidation.Errorf("duration must be positive")
+		}
+		if _, err := s.cronSchedule(); err != nil {
+			return ErrSilenceScheduleValidation.Errorf("invalid cron expression: %w", err)
+		}
+	case len(s.TimeIntervals) > 0:
+		if s.Duration != 0 {
+			return ErrSilenceScheduleValidation.Errorf("duration can only be set with a cron expression")
+		}
+		if s.Location != "" {
+			return ErrSilenceScheduleValidation.Errorf("location can only be set with a cron expression, set the location of the time intervals instead")
+		}
+	default:
+		return ErrSilenceScheduleValidation.Errorf("one of cron and time intervals is required")
+	}
+	return nil
+}
+
+// validateSilenceMatchers validates matchers the same way the Alertmanager validates the matchers of silences.
+func validateSilenceMatchers(matchers amv2.Matchers) error {
+	if len(matchers) == 0 {
+		return errors.New("at least one matcher is required")
+	}
+	matchesNonEmpty := false
+	for _, m := range matchers {
+		if m == nil || m.Name == nil || m.Value == nil || m.IsRegex == nil {
+			return errors.New("name, value and isRegex of matchers are required")
+		}
+		matcher, err := labels.NewMatcher(matcherType(*m), *m.Name, *m.Value)
+		if err != nil {
+			return err
+		}
+		if !matcher.Matches("") {
+			matchesNonEmpty = true
+		}
+	}
+	if !matchesNonEmpty {
+		return errors.New("at least one matcher must not match the empty string")
+	}
+	return nil
+}
+
+func matcherType(m amv2.Matcher) labels.MatchType {
+	isEqual := m.IsEqual == nil || *m.IsEqual
+	switch {
+	case *m.IsRegex && isEqual:
+		return labels.MatchRegexp
+	case *m.IsRegex:
+		return labels.MatchNotRegexp
+	case isEqual:
+		return labels.MatchEqual
+	default:
+		return labels.MatchNotEqual
+	}
+}
+
+func (s *SilenceSchedule) location() (*time.Location, error) {
+	if s.Location == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(s.Location)
+}
+
+func (s *SilenceSchedule) cronSchedule() (cron.Schedule, error) {
+	loc, err := s.location()
+	if err != nil {
+		return nil, err
+	}
+	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	schedule, err := parser.Parse(s.Cron)
+	if err != nil {
+		return nil, err
+	}
+	// Schedules like @every 1h depend on the time they start from, so the same periods would not be found every time.
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok {
+		return nil, errors.New("only schedules of fixed times are supported")
+	}
+	if spec.Location == time.Local {
+		spec.Location = loc
+	}
+	return spec, nil
+}
+
+// Periods returns the periods of the schedule that overlap with the range [from, to), ordered by their start.
+// Periods that started before from are returned with their actual start. At most MaxSilenceSchedulePeriods
+// periods are returned.
+func (s *SilenceSchedule) Periods(from, to time.Time) ([]SilencePeriod, error) {
+	var periods []SilencePeriod
+	if s.Cron != "" {
+		schedule, err := s.cronSchedule()
+		if err != nil {
+			return nil, err
+		}
+		// Periods that end after from started after from minus the duration.
+		for t := schedule.Next(from.Add(-s.Duration)); !t.IsZero() && t.Before(to); t = schedule.Next(t) {
+			periods = append(periods, SilencePeriod{StartsAt: t, EndsAt: t.Add(s.Duration)})
+			if len(periods) == MaxSilenceSchedulePeriods {
+				break
+			}
+		}
+		return periods, nil
+	}
+
+	seen := make(map[SilencePeriod]struct{})
+	for _, ti := range s.TimeIntervals {
+		loc := time.UTC
+		if ti.Location != nil {
+			loc = ti.Location.Location
+		}
+		// The intervals of a day are checked without the times, which are the ranges of the periods of the day.
+		days := ti
+		days.Times = nil
+		ranges := ti.Times
+		if len(ranges) == 0 {
+			ranges = []timeinterval.TimeRange{{StartMinute: 0, EndMinute: 24 * 60}}
+		}
+		start := from.In(loc)
+		// Start a day earlier, in case a period of the previous day is still in progress.
+		for day := time.Date(start.Year(), start.Month(), start.Day()-1, 0, 0, 0, 0, loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+			if !days.ContainsTime(day) {
+				continue
+			}
+			for _, r := range ranges {
+				p := SilencePeriod{
+					StartsAt: day.Add(time.Duration(r.StartMinute) * time.Minute),
+					EndsAt:   day.Add(time.Duration(r.EndMinute) * time.Minute),
+				}
+				if !p.EndsAt.After(from) || !p.StartsAt.Before(to) {
+					continue
+				}
+				if _, ok := seen[p]; ok {
+					continue
+				}
+				seen[p] = struct{}{}
+				periods = append(periods, p)
+			}
+		}
+	}
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].StartsAt.Before(periods[j].StartsAt)
+	})
+	if len(periods) > MaxSilenceSchedulePeriods {
+		periods = periods[:MaxSilenceSchedulePeriods]
+	}
+	return periods, nil
+}
+
+// HasSilence returns true if a silence was created for the period.
+func (s *SilenceSchedule) HasSilence(p SilencePeriod) bool {
+	for _, silence := range s.Silences {
+		if silence.StartsAt.Equal(p.StartsAt) && silence.EndsAt.Equal(p.EndsAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// Silence returns the silence of a period of the schedule. If the period already started, the silence starts at now.
+func (s *SilenceSchedule) Silence(p SilencePeriod, now time.Time) *Silence {
+	silence := s.AsSilence()
+	startsAt := p.StartsAt
+	if startsAt.Before(now) {
+		startsAt = now
+	}
+	starts, ends := strfmt.DateTime(startsAt), strfmt.DateTime(p.EndsAt)
+	silence.StartsAt = &starts
+	silence.EndsAt = &ends
+	return silence
+}
+
+// AsSilence returns a silence with the matchers and the comment of the schedule but without a period. It is used to
+// authorize access to the schedule in the same way as access to the silences it creates.
+func (s *SilenceSchedule) AsSilence() *Silence {
+	comment := s.Comment
+	if comment == "" {
+		comment = s.Title
+	}
+	createdBy := fmt.Sprintf("silence schedule %s", s.UID)
+	return &Silence{
+		Silence: notify.Silence{
+			Comment:   &comment,
+			CreatedBy: &createdBy,
+			Matchers:  s.Matchers,
+		},
+	}
+}
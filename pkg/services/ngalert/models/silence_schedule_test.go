@@ -0,0 +1,120 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func TestSilenceScheduleValidate(t *testing.T) {
+	valid := func() SilenceSchedule {
+		return SilenceSchedule{
+			Title:    "Weekly maintenance",
+			Matchers: amv2.Matchers{{Name: util.Pointer("team"), Value: util.Pointer("db"), IsRegex: util.Pointer(false)}},
+			Cron:     "0 22 * * 6",
+			Duration: 4 * time.Hour,
+		}
+	}
+	weekends := []timeinterval.TimeInterval{{Weekdays: []timeinterval.WeekdayRange{{InclusiveRange: timeinterval.InclusiveRange{Begin: 6, End: 6}}}}}
+
+	testCases := []struct {
+		name   string
+		mutate func(s *SilenceSchedule)
+		err    string
+	}{
+		{name: "valid cron schedule", mutate: func(s *SilenceSchedule) {}},
+		{name: "valid time intervals schedule", mutate: func(s *SilenceSchedule) {
+			s.Cron, s.Duration, s.TimeIntervals = "", 0, weekends
+		}},
+		{name: "missing title", mutate: func(s *SilenceSchedule) { s.Title = "" }, err: "title is required"},
+		{name: "missing matchers", mutate: func(s *SilenceSchedule) { s.Matchers = nil }, err: "at least one matcher is required"},
+		{name: "matchers match everything", mutate: func(s *SilenceSchedule) {
+			s.Matchers = amv2.Matchers{{Name: util.Pointer("team"), Value: util.Pointer(".*"), IsRegex: util.Pointer(true)}}
+		}, err: "must not match the empty string"},
+		{name: "invalid regex", mutate: func(s *SilenceSchedule) {
+			s.Matchers = amv2.Matchers{{Name: util.Pointer("team"), Value: util.Pointer("("), IsRegex: util.Pointer(true)}}
+		}, err: "invalid matchers"},
+		{name: "no recurrence", mutate: func(s *SilenceSchedule) { s.Cron = "" }, err: "one of cron and time intervals is required"},
+		{name: "both cron and time intervals", mutate: func(s *SilenceSchedule) { s.TimeIntervals = weekends }, err: "only one of cron and time intervals"},
+		{name: "cron without duration", mutate: func(s *SilenceSchedule) { s.Duration = 0 }, err: "duration must be positive"},
+		{name: "invalid cron", mutate: func(s *SilenceSchedule) { s.Cron = "0 25 * * *" }, err: "invalid cron expression"},
+		{name: "cron with @every", mutate: func(s *SilenceSchedule) { s.Cron = "@every 1h" }, err: "only schedules of fixed times"},
+		{name: "unknown location", mutate: func(s *SilenceSchedule) { s.Location = "Mars/Olympus_Mons" }, err: "invalid cron expression"},
+		{name: "time intervals with duration", mutate: func(s *SilenceSchedule) {
+			s.Cron, s.TimeIntervals = "", weekends
+		}, err: "duration can only be set with a cron expression"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := valid()
+			tc.mutate(&s)
+			err := s.Validate()
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrSilenceScheduleValidation)
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestSilenceSchedulePeriods(t *testing.T) {
+	t.Run("cron periods include the period in progress", func(t *testing.T) {
+		s := SilenceSchedule{Cron: "0 22 * * *", Duration: 4 * time.Hour, Location: "Europe/Berlin"}
+		berlin, err := time.LoadLocation("Europe/Berlin")
+		require.NoError(t, err)
+		from := time.Date(2024, 6, 1, 23, 0, 0, 0, berlin)
+
+		periods, err := s.Periods(from, from.Add(48*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, periods, 3)
+		for i, p := range periods {
+			start := time.Date(2024, 6, 1+i, 22, 0, 0, 0, berlin)
+			require.True(t, start.Equal(p.StartsAt), "period %d starts at %s", i, p.StartsAt)
+			require.True(t, start.Add(4*time.Hour).Equal(p.EndsAt))
+		}
+	})
+
+	t.Run("cron periods are capped", func(t *testing.T) {
+		s := SilenceSchedule{Cron: "* * * * *", Duration: time.Minute}
+		from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+		periods, err := s.Periods(from, from.Add(24*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, periods, MaxSilenceSchedulePeriods)
+	})
+
+	t.Run("time intervals without times silence whole days", func(t *testing.T) {
+		s := SilenceSchedule{TimeIntervals: []timeinterval.TimeInterval{{
+			Weekdays: []timeinterval.WeekdayRange{{InclusiveRange: timeinterval.InclusiveRange{Begin: 6, End: 6}}},
+		}}}
+		// Friday.
+		from := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+		periods, err := s.Periods(from, from.Add(7*24*time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, []SilencePeriod{{
+			StartsAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			EndsAt:   time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC),
+		}}, periods)
+	})
+
+	t.Run("time intervals with times silence the ranges of the days", func(t *testing.T) {
+		s := SilenceSchedule{TimeIntervals: []timeinterval.TimeInterval{
+			{Times: []timeinterval.TimeRange{{StartMinute: 22 * 60, EndMinute: 24 * 60}}},
+			// Overlapping intervals result in the same periods only once.
+			{Times: []timeinterval.TimeRange{{StartMinute: 22 * 60, EndMinute: 24 * 60}}},
+		}}
+		from := time.Date(2024, 6, 1, 23, 0, 0, 0, time.UTC)
+		periods, err := s.Periods(from, from.Add(24*time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, []SilencePeriod{
+			{StartsAt: time.Date(2024, 6, 1, 22, 0, 0, 0, time.UTC), EndsAt: time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)},
+			{StartsAt: time.Date(2024, 6, 2, 22, 0, 0, 0, time.UTC), EndsAt: time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)},
+		}, periods)
+	})
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/grafana/grafana/pkg/infra/httpclient"
 	"github.com/grafana/grafana/pkg/infra/kvstore"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
 	"github.com/grafana/grafana/pkg/infra/tracing"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/annotations"
@@ -32,6 +33,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	"github.com/grafana/grafana/pkg/services/ngalert/image"
+	"github.com/grafana/grafana/pkg/services/ngalert/lint"
 	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
@@ -76,6 +78,7 @@ func ProvideService(
 	tracer tracing.Tracer,
 	ruleStore *store.DBstore,
 	httpClientProvider httpclient.Provider,
+	remoteCache remotecache.CacheStorage,
 ) (*AlertNG, error) {
 	ng := &AlertNG{
 		Cfg:                  cfg,
@@ -103,6 +106,7 @@ func ProvideService(
 		tracer:               tracer,
 		store:                ruleStore,
 		httpClientProvider:   httpClientProvider,
+		remoteCache:          remoteCache,
 	}
 
 	if ng.IsDisabled() {
@@ -137,10 +141,14 @@ type AlertNG struct {
 	RecordingWriter     schedule.RecordingWriter
 	schedule            schedule.ScheduleService
 	stateManager        *state.Manager
+	silenceScheduler    *notifier.SilenceScheduler
+	historian           Historian
+	lintService         *lint.Service
 	folderService       folder.Service
 	dashboardService    dashboards.DashboardService
 	Api                 *api.API
 	httpClientProvider  httpclient.Provider
+	remoteCache         remotecache.CacheStorage
 
 	// Alerting notification services
 	MultiOrgAlertmanager *notifier.MultiOrgAlertmanager
@@ -344,45 +352,52 @@ func (ng *AlertNG) init() error {
 
 	evalFactory := eval.NewEvaluatorFactory(ng.Cfg.UnifiedAlerting, ng.DataSourceCache, ng.ExpressionService, ng.pluginsStore)
 
-	recordingWriter, err := createRecordingWriter(ng.FeatureToggles, ng.Cfg.UnifiedAlerting.RecordingRules, ng.httpClientProvider, clk, ng.tracer, ng.Metrics.GetRemoteWriterMetrics())
+	recordingWriter, err := createRecordingWriter(ng.FeatureToggles, ng.Cfg.UnifiedAlerting.RecordingRules, ng.httpClientProvider, ng.SQLStore, clk, ng.tracer, ng.Metrics.GetRemoteWriterMetrics())
 	if err != nil {
 		return fmt.Errorf("failed to initialize recording writer: %w", err)
 	}
 	ng.RecordingWriter = recordingWriter
 
 	schedCfg := schedule.SchedulerCfg{
-		MaxAttempts:          ng.Cfg.UnifiedAlerting.MaxAttempts,
-		C:                    clk,
-		BaseInterval:         ng.Cfg.UnifiedAlerting.BaseInterval,
-		MinRuleInterval:      ng.Cfg.UnifiedAlerting.MinInterval,
-		DisableGrafanaFolder: ng.Cfg.UnifiedAlerting.ReservedLabels.IsReservedLabelDisabled(models.FolderTitleLabel),
-		JitterEvaluations:    schedule.JitterStrategyFrom(ng.Cfg.UnifiedAlerting, ng.FeatureToggles),
-		AppURL:               appUrl,
-		EvaluatorFactory:     evalFactory,
-		RuleStore:            ng.store,
-		FeatureToggles:       ng.FeatureToggles,
-		Metrics:              ng.Metrics.GetSchedulerMetrics(),
-		AlertSender:          alertsRouter,
-		Tracer:               ng.tracer,
-		Log:                  log.New("ngalert.scheduler"),
-		RecordingWriter:      ng.RecordingWriter,
+		MaxAttempts:             ng.Cfg.UnifiedAlerting.MaxAttempts,
+		C:                       clk,
+		BaseInterval:            ng.Cfg.UnifiedAlerting.BaseInterval,
+		MinRuleInterval:         ng.Cfg.UnifiedAlerting.MinInterval,
+		DisableGrafanaFolder:    ng.Cfg.UnifiedAlerting.ReservedLabels.IsReservedLabelDisabled(models.FolderTitleLabel),
+		JitterEvaluations:       schedule.JitterStrategyFrom(ng.Cfg.UnifiedAlerting, ng.FeatureToggles),
+		AppURL:                  appUrl,
+		EvaluatorFactory:        evalFactory,
+		RuleStore:               ng.store,
+		FeatureToggles:          ng.FeatureToggles,
+		Metrics:                 ng.Metrics.GetSchedulerMetrics(),
+		AlertSender:             alertsRouter,
+		Tracer:                  ng.tracer,
+		Log:                     log.New("ngalert.scheduler"),
+		RecordingWriter:         ng.RecordingWriter,
+		TemplateQueryTimeout:    ng.Cfg.UnifiedAlerting.TemplateQueryTimeout,
+		TemplateMaxQueries:      ng.Cfg.UnifiedAlerting.TemplateMaxQueries,
+		RecordTargetDatasources: models.RecordTargetDatasourcesFromSettings(ng.Cfg.UnifiedAlerting.RecordingRules),
 	}
 
 	// There are a set of feature toggles available that act as short-circuits for common configurations.
 	// If any are set, override the config accordingly.
 	ApplyStateHistoryFeatureToggles(&ng.Cfg.UnifiedAlerting.StateHistory, ng.FeatureToggles, ng.Log)
-	history, err := configureHistorianBackend(initCtx, ng.Cfg.UnifiedAlerting.StateHistory, ng.annotationsRepo, ng.dashboardService, ng.store, ng.Metrics.GetHistorianMetrics(), ng.Log, ng.tracer, ac.NewRuleService(ng.accesscontrol))
+	history, err := configureHistorianBackend(initCtx, ng.Cfg.UnifiedAlerting.StateHistory, ng.annotationsRepo, ng.dashboardService, ng.store, ng.Metrics.GetHistorianMetrics(), ng.Log, ng.tracer, ac.NewRuleService(ng.accesscontrol), ng.SQLStore, ng.httpClientProvider, ng.Metrics.GetRemoteWriterMetrics())
 	if err != nil {
 		return err
 	}
+	ng.historian = history
+	// The flap tracker observes the state transitions of the rules evaluated by this instance for the rule linter.
+	flapTracker := lint.NewFlapTracker(clk)
+	stateStorage := configureStateStorage(initCtx, ng.Cfg, ng.store, ng.remoteCache, ng.KVStore, ng.FeatureToggles, ng.Log)
 	cfg := state.ManagerCfg{
 		Metrics:                        ng.Metrics.GetStateMetrics(),
 		ExternalURL:                    appUrl,
 		DisableExecution:               !ng.Cfg.UnifiedAlerting.ExecuteAlerts,
-		InstanceStore:                  ng.store,
+		InstanceStore:                  stateStorage.store,
 		Images:                         ng.ImageService,
 		Clock:                          clk,
-		Historian:                      history,
+		Historian:                      flapTracker.Historian(history),
 		DoNotSaveNormalState:           ng.FeatureToggles.IsEnabledGlobally(featuremgmt.FlagAlertingNoNormalState),
 		ApplyNoDataAndErrorToAllStates: ng.FeatureToggles.IsEnabledGlobally(featuremgmt.FlagAlertingNoDataErrorExecution),
 		MaxStateSaveConcurrency:        ng.Cfg.UnifiedAlerting.MaxStateSaveConcurrency,
@@ -390,6 +405,9 @@ func (ng *AlertNG) init() error {
 		Tracer:                         ng.tracer,
 		Log:                            log.New("ngalert.state.manager"),
 		ResolvedRetention:              ng.Cfg.UnifiedAlerting.ResolvedAlertRetention,
+		// The periodic save replaces all alert instances with the ones in the cache, so the state of all rules
+		// must be loaded on startup.
+		WarmRulesLazily: stateStorage.name == setting.StateStorageRedis && !ng.FeatureToggles.IsEnabledGlobally(featuremgmt.FlagAlertingSaveStatePeriodic),
 	}
 	logger := log.New("ngalert.state.manager.persist")
 	statePersister := state.NewSyncStatePersisiter(logger, cfg)
@@ -397,6 +415,18 @@ func (ng *AlertNG) init() error {
 		ticker := clock.New().Ticker(ng.Cfg.UnifiedAlerting.StatePeriodicSaveInterval)
 		statePersister = state.NewAsyncStatePersister(logger, ticker, cfg)
 	}
+	if ng.Cfg.UnifiedAlerting.HAEvaluationSharding {
+		// The periodic save replaces all alert instances in the database with the ones of this instance,
+		// which would delete the state of the rules evaluated by other instances.
+		if ng.FeatureToggles.IsEnabledGlobally(featuremgmt.FlagAlertingSaveStatePeriodic) {
+			return fmt.Errorf("ha_evaluation_sharding cannot be used together with the %s feature toggle", featuremgmt.FlagAlertingSaveStatePeriodic)
+		}
+		if membership, ok := ng.MultiOrgAlertmanager.ClusterMembership(); ok {
+			schedCfg.ClusterMembership = membership
+		} else {
+			ng.Log.Warn("Evaluation sharding is enabled but high availability is not configured, all alert rules are evaluated by this instance")
+		}
+	}
 	stateManager := state.NewManager(cfg, statePersister)
 	scheduler := schedule.NewScheduler(schedCfg, stateManager)
 
@@ -407,6 +437,7 @@ func (ng *AlertNG) init() error {
 
 	ng.stateManager = stateManager
 	ng.schedule = scheduler
+	ng.silenceScheduler = notifier.NewSilenceScheduler(ng.store, ng.MultiOrgAlertmanager, clk, log.New("ngalert.silence-scheduler"))
 
 	receiverService := notifier.NewReceiverService(ng.accesscontrol, ng.store, ng.store, ng.SecretsService, ng.store, ng.Log)
 
@@ -418,9 +449,13 @@ func (ng *AlertNG) init() error {
 	alertRuleService := provisioning.NewAlertRuleService(ng.store, ng.store, ng.folderService, ng.QuotaService, ng.store,
 		int64(ng.Cfg.UnifiedAlerting.DefaultRuleEvaluationInterval.Seconds()),
 		int64(ng.Cfg.UnifiedAlerting.BaseInterval.Seconds()),
-		ng.Cfg.UnifiedAlerting.RulesPerRuleGroupLimit, ng.Log, notifier.NewNotificationSettingsValidationService(ng.store),
+		ng.Cfg.UnifiedAlerting.RulesPerRuleGroupLimit, ng.Cfg.UnifiedAlerting.RecordingRules, ng.Log, notifier.NewNotificationSettingsValidationService(ng.store),
 		ac.NewRuleService(ng.accesscontrol))
 
+	receiverTests := notifier.NewReceiverTestStore(ng.KVStore)
+	linter := lint.NewLinter(ng.DataSourceService, receiverTests, flapTracker, clk, log.New("ngalert.lint"))
+	ng.lintService = lint.NewService(linter, flapTracker, ng.store, clk, log.New("ngalert.lint"))
+
 	ng.Api = &api.API{
 		Cfg:                  ng.Cfg,
 		DatasourceCache:      ng.DataSourceCache,
@@ -432,6 +467,7 @@ func (ng *AlertNG) init() error {
 		RuleStore:            ng.store,
 		AlertingStore:        ng.store,
 		AdminConfigStore:     ng.store,
+		SilenceScheduleStore: ng.store,
 		ProvenanceStore:      ng.store,
 		MultiOrgAlertmanager: ng.MultiOrgAlertmanager,
 		StateManager:         ng.stateManager,
@@ -442,11 +478,15 @@ func (ng *AlertNG) init() error {
 		Templates:            templateService,
 		MuteTimings:          muteTimingService,
 		AlertRules:           alertRuleService,
+		FolderService:        ng.folderService,
 		AlertsRouter:         alertsRouter,
 		EvaluatorFactory:     evalFactory,
 		FeatureManager:       ng.FeatureToggles,
 		AppUrl:               appUrl,
 		Historian:            history,
+		RuleLinter:           linter,
+		RuleLintStore:        ng.store,
+		ReceiverTests:        receiverTests,
 		Hooks:                api.NewHooks(ng.Log),
 		Tracer:               ng.tracer,
 	}
@@ -493,6 +533,9 @@ func (ng *AlertNG) Run(ctx context.Context) error {
 	children.Go(func() error {
 		return ng.AlertsRouter.Run(subCtx)
 	})
+	children.Go(func() error {
+		return ng.silenceScheduler.Run(subCtx)
+	})
 
 	if ng.Cfg.UnifiedAlerting.ExecuteAlerts {
 		// Only Warm() the state manager if we are actually executing alerts.
@@ -512,6 +555,21 @@ func (ng *AlertNG) Run(ctx context.Context) error {
 		children.Go(func() error {
 			return ng.stateManager.Run(subCtx)
 		})
+		children.Go(func() error {
+			return ng.lintService.Run(subCtx)
+		})
+	}
+	// Some state history backends run background maintenance, such as compaction of old entries.
+	if r, ok := ng.historian.(historian.Runner); ok {
+		children.Go(func() error {
+			return r.Run(subCtx)
+		})
+	}
+	// The same applies to recording rule writers, such as the one that writes to the database.
+	if r, ok := ng.RecordingWriter.(interface{ Run(context.Context) error }); ok {
+		children.Go(func() error {
+			return r.Run(subCtx)
+		})
 	}
 	return children.Wait()
 }
@@ -536,7 +594,7 @@ type Historian interface {
 	state.Historian
 }
 
-func configureHistorianBackend(ctx context.Context, cfg setting.UnifiedAlertingStateHistorySettings, ar annotations.Repository, ds dashboards.DashboardService, rs historian.RuleStore, met *metrics.Historian, l log.Logger, tracer tracing.Tracer, ac historian.AccessControl) (Historian, error) {
+func configureHistorianBackend(ctx context.Context, cfg setting.UnifiedAlertingStateHistorySettings, ar annotations.Repository, ds dashboards.DashboardService, rs historian.RuleStore, met *metrics.Historian, l log.Logger, tracer tracing.Tracer, ac historian.AccessControl, sqlStore db.DB, httpClientProvider httpclient.Provider, rwm *metrics.RemoteWriter) (Historian, error) {
 	if !cfg.Enabled {
 		met.Info.WithLabelValues("noop").Set(0)
 		return historian.NewNopHistorian(), nil
@@ -551,7 +609,7 @@ func configureHistorianBackend(ctx context.Context, cfg setting.UnifiedAlertingS
 	if backend == historian.BackendTypeMultiple {
 		primaryCfg := cfg
 		primaryCfg.Backend = cfg.MultiPrimary
-		primary, err := configureHistorianBackend(ctx, primaryCfg, ar, ds, rs, met, l, tracer, ac)
+		primary, err := configureHistorianBackend(ctx, primaryCfg, ar, ds, rs, met, l, tracer, ac, sqlStore, httpClientProvider, rwm)
 		if err != nil {
 			return nil, fmt.Errorf("multi-backend target \"%s\" was misconfigured: %w", cfg.MultiPrimary, err)
 		}
@@ -560,7 +618,7 @@ func configureHistorianBackend(ctx context.Context, cfg setting.UnifiedAlertingS
 		for _, b := range cfg.MultiSecondaries {
 			secCfg := cfg
 			secCfg.Backend = b
-			sec, err := configureHistorianBackend(ctx, secCfg, ar, ds, rs, met, l, tracer, ac)
+			sec, err := configureHistorianBackend(ctx, secCfg, ar, ds, rs, met, l, tracer, ac, sqlStore, httpClientProvider, rwm)
 			if err != nil {
 				return nil, fmt.Errorf("multi-backend target \"%s\" was miconfigured: %w", b, err)
 			}
@@ -591,6 +649,33 @@ func configureHistorianBackend(ctx context.Context, cfg setting.UnifiedAlertingS
 		}
 		return backend, nil
 	}
+	if backend == historian.BackendTypeSQL {
+		if sqlStore == nil {
+			return nil, fmt.Errorf("sql state history backend requires a database")
+		}
+		sqlBackendLogger := log.New("ngalert.state.historian", "backend", "sql")
+		return historian.NewSQLBackend(sqlBackendLogger, historian.NewSQLConfig(cfg), sqlStore, rs, met, ac), nil
+	}
+	if backend == historian.BackendTypePrometheus {
+		pcfg, err := historian.NewPrometheusConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prometheus state history configuration: %w", err)
+		}
+		if httpClientProvider == nil {
+			return nil, fmt.Errorf("prometheus state history backend requires an HTTP client provider")
+		}
+		promBackendLogger := log.New("ngalert.state.historian", "backend", "prometheus")
+		w, err := writer.NewPrometheusWriter(setting.RecordingRuleSettings{
+			URL:               cfg.PrometheusWriteURL,
+			BasicAuthUsername: cfg.PrometheusBasicAuthUsername,
+			BasicAuthPassword: cfg.PrometheusBasicAuthPassword,
+			Timeout:           cfg.PrometheusTimeout,
+		}, httpClientProvider, clock.New(), tracer, promBackendLogger, rwm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create prometheus remote writer: %w", err)
+		}
+		return historian.NewPrometheusBackend(promBackendLogger, pcfg, w, historian.NewRequester(), rs, met, ac), nil
+	}
 
 	return nil, fmt.Errorf("unrecognized state history backend: %s", backend)
 }
@@ -644,12 +729,41 @@ func createRemoteAlertmanager(cfg remote.AlertmanagerConfig, kvstore kvstore.KVS
 	return remote.NewAlertmanager(cfg, notifier.NewFileStore(cfg.OrgID, kvstore), decryptFn, autogenFn, m, tracer)
 }
 
-func createRecordingWriter(featureToggles featuremgmt.FeatureToggles, settings setting.RecordingRuleSettings, httpClientProvider httpclient.Provider, clock clock.Clock, tracer tracing.Tracer, m *metrics.RemoteWriter) (schedule.RecordingWriter, error) {
+func createRecordingWriter(featureToggles featuremgmt.FeatureToggles, settings setting.RecordingRuleSettings, httpClientProvider httpclient.Provider, store db.DB, clock clock.Clock, tracer tracing.Tracer, m *metrics.RemoteWriter) (schedule.RecordingWriter, error) {
 	logger := log.New("ngalert.writer")
 
-	if featureToggles.IsEnabledGlobally(featuremgmt.FlagGrafanaManagedRecordingRules) {
-		return writer.NewPrometheusWriter(settings, httpClientProvider, clock, tracer, logger, m)
+	if !featureToggles.IsEnabledGlobally(featuremgmt.FlagGrafanaManagedRecordingRules) {
+		return writer.NoopWriter{}, nil
 	}
 
-	return writer.NoopWriter{}, nil
+	prom, err := writer.NewPrometheusWriter(settings, httpClientProvider, clock, tracer, logger, m)
+	if err != nil {
+		return nil, err
+	}
+	writers := map[models.RecordTarget]writer.Writer{
+		models.RecordTargetPrometheus: prom,
+	}
+	if settings.InfluxDB.URL != "" {
+		w, err := writer.NewInfluxDBWriter(settings.InfluxDB, httpClientProvider, clock, tracer, logger, m)
+		if err != nil {
+			return nil, fmt.Errorf("invalid InfluxDB recording rule target: %w", err)
+		}
+		writers[models.RecordTargetInfluxDB] = w
+	}
+	if settings.OTLP.URL != "" {
+		w, err := writer.NewOTLPWriter(settings.OTLP, httpClientProvider, clock, tracer, logger, m)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP recording rule target: %w", err)
+		}
+		writers[models.RecordTargetOTLP] = w
+	}
+	if settings.SQL.Enabled {
+		writers[models.RecordTargetSQL] = writer.NewSQLWriter(settings.SQL, store, clock, logger)
+	}
+
+	defaultTarget := models.RecordTarget(settings.DefaultTarget)
+	if defaultTarget == "" {
+		defaultTarget = models.RecordTargetPrometheus
+	}
+	return writer.NewTargetRouter(defaultTarget, writers)
 }
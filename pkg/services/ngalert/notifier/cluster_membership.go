@@ -0,0 +1,44 @@
+package notifier
+
+import (
+	alertingCluster "github.com/grafana/alerting/cluster"
+)
+
+// ClusterMembership provides the members of the high availability cluster of Grafana instances.
+type ClusterMembership interface {
+	// Members returns the names of the healthy members of the cluster, including this instance.
+	Members() []string
+	// Self returns the name of this instance.
+	Self() string
+}
+
+// ClusterMembership returns the members of the cluster the Alertmanagers of this instance are part of.
+// It returns false if high availability is not configured.
+func (moa *MultiOrgAlertmanager) ClusterMembership() (ClusterMembership, bool) {
+	switch p := moa.peer.(type) {
+	case *redisPeer:
+		return p, true
+	case *alertingCluster.Peer:
+		return &gossipMembership{peer: p}, true
+	default:
+		return nil, false
+	}
+}
+
+// gossipMembership provides the members of a cluster that uses the gossip protocol.
+type gossipMembership struct {
+	peer *alertingCluster.Peer
+}
+
+func (m *gossipMembership) Members() []string {
+	peers := m.peer.Peers()
+	members := make([]string, 0, len(peers))
+	for _, p := range peers {
+		members = append(members, p.Name())
+	}
+	return members
+}
+
+func (m *gossipMembership) Self() string {
+	return m.peer.Name()
+}
@@ -0,0 +1,57 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+)
+
+const receiverTestsKey = "receiver_tests"
+
+// ReceiverTestStore records when the contact points of an organization were last tested successfully.
+type ReceiverTestStore struct {
+	kv kvstore.KVStore
+}
+
+func NewReceiverTestStore(kv kvstore.KVStore) *ReceiverTestStore {
+	return &ReceiverTestStore{kv: kv}
+}
+
+// RecordReceiverTests records that the given contact points were tested successfully at the given time.
+func (s *ReceiverTestStore) RecordReceiverTests(ctx context.Context, orgID int64, receivers []string, at time.Time) error {
+	if len(receivers) == 0 {
+		return nil
+	}
+	tested, err := s.LastReceiverTests(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	for _, name := range receivers {
+		tested[name] = at
+	}
+	value, err := json.Marshal(tested)
+	if err != nil {
+		return err
+	}
+	return kvstore.WithNamespace(s.kv, orgID, KVNamespace).Set(ctx, receiverTestsKey, string(value))
+}
+
+// LastReceiverTests returns when the contact points of the organization were last tested successfully, by name.
+// Contact points that were never tested are not in the result.
+func (s *ReceiverTestStore) LastReceiverTests(ctx context.Context, orgID int64) (map[string]time.Time, error) {
+	value, ok, err := kvstore.WithNamespace(s.kv, orgID, KVNamespace).Get(ctx, receiverTestsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the tests of contact points: %w", err)
+	}
+	tested := map[string]time.Time{}
+	if !ok {
+		return tested, nil
+	}
+	if err := json.Unmarshal([]byte(value), &tested); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the tests of contact points: %w", err)
+	}
+	return tested, nil
+}
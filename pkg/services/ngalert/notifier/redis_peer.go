@@ -407,6 +407,11 @@ func (p *redisPeer) Members() []string {
 	return p.members
 }
 
+// Self returns the name of this instance, as it appears in the list of members.
+func (p *redisPeer) Self() string {
+	return p.withPrefix(p.name)
+}
+
 func (p *redisPeer) WaitReady(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
@@ -0,0 +1,184 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// AlertRouting is the outcome of routing an alert through the notification policy tree of an organization.
+type AlertRouting struct {
+	Routes []RouteMatch
+	// SilencedBy contains the IDs of the active silences that match the alert.
+	SilencedBy []string
+}
+
+// RouteMatch is a notification policy that matches an alert.
+type RouteMatch struct {
+	// RouteID identifies the policy by its position in the policy tree.
+	RouteID string
+	// Matchers are the matchers of the policy and of all its parents.
+	Matchers       []string
+	Receiver       string
+	GroupKey       string
+	GroupLabels    model.LabelSet
+	GroupWait      time.Duration
+	GroupInterval  time.Duration
+	RepeatInterval time.Duration
+	// MutedBy contains the names of the mute timings of the policy that are active, or of its active time intervals
+	// if none of them is active.
+	MutedBy []string
+	// Autogenerated is true if the policy is generated from the notification settings of rules.
+	Autogenerated bool
+}
+
+// previewRuleStore adds notification settings to the ones of the stored rules, so that the autogenerated policies
+// include the settings of a rule that is not saved.
+type previewRuleStore struct {
+	autogenRuleStore
+	settings []models.NotificationSettings
+}
+
+func (s previewRuleStore) ListNotificationSettings(ctx context.Context, q models.ListNotificationSettingsQuery) (map[models.AlertRuleKey][]models.NotificationSettings, error) {
+	result, err := s.autogenRuleStore.ListNotificationSettings(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = make(map[models.AlertRuleKey][]models.NotificationSettings, 1)
+	}
+	if len(s.settings) > 0 {
+		result[models.AlertRuleKey{OrgID: q.OrgID, UID: "preview"}] = s.settings
+	}
+	return result, nil
+}
+
+// PreviewRouting routes alerts with the given labels through the notification policy tree of the organization as
+// the Alertmanager would at the given time, and finds the active silences among the given ones that match them.
+// Inhibition rules are not taken into account. The notification settings are added to the autogenerated policies
+// of simplified routing, so that alerts of a rule that is not saved are routed as if it was.
+func (moa *MultiOrgAlertmanager) PreviewRouting(ctx context.Context, orgID int64, settings []models.NotificationSettings, alerts []model.LabelSet, silences []*models.Silence, now time.Time) ([]AlertRouting, error) {
+	cfg, err := moa.GetRoutingConfiguration(ctx, orgID, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	intervals := make(map[string][]timeinterval.TimeInterval)
+	for _, ti := range cfg.MuteTimeIntervals {
+		intervals[ti.Name] = ti.TimeIntervals
+	}
+	for _, ti := range cfg.TimeIntervals {
+		intervals[ti.Name] = ti.TimeIntervals
+	}
+	root := dispatch.NewRoute(cfg.Route.AsAMRoute(), nil)
+	parents := map[*dispatch.Route]*dispatch.Route{}
+	root.Walk(func(r *dispatch.Route) {
+		for _, child := range r.Routes {
+			parents[child] = r
+		}
+	})
+
+	result := make([]AlertRouting, 0, len(alerts))
+	for _, lset := range alerts {
+		routing := AlertRouting{}
+		for _, r := range root.Match(lset) {
+			routing.Routes = append(routing.Routes, newRouteMatch(r, parents, lset, intervals, now))
+		}
+		for _, s := range silences {
+			if s.IsActive() && s.Matches(lset) && s.ID != nil {
+				routing.SilencedBy = append(routing.SilencedBy, *s.ID)
+			}
+		}
+		sort.Strings(routing.SilencedBy)
+		result = append(result, routing)
+	}
+	return result, nil
+}
+
+// GetRoutingConfiguration returns the latest Alertmanager configuration of the organization, with the autogenerated
+// policies of simplified routing. The notification settings are added to the autogenerated policies.
+func (moa *MultiOrgAlertmanager) GetRoutingConfiguration(ctx context.Context, orgID int64, settings []models.NotificationSettings) (*definitions.PostableApiAlertingConfig, error) {
+	amConfig, err := moa.configStore.GetLatestAlertmanagerConfiguration(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest configuration: %w", err)
+	}
+	cfg, err := Load([]byte(amConfig.AlertmanagerConfiguration))
+	if err != nil {
+		return nil, err
+	}
+	if moa.featureManager.IsEnabled(ctx, featuremgmt.FlagAlertingSimplifiedRouting) {
+		store := previewRuleStore{autogenRuleStore: moa.configStore, settings: settings}
+		if err := AddAutogenConfig(ctx, moa.logger, store, orgID, &cfg.AlertmanagerConfig, true); err != nil {
+			return nil, err
+		}
+	}
+	return &cfg.AlertmanagerConfig, nil
+}
+
+func newRouteMatch(r *dispatch.Route, parents map[*dispatch.Route]*dispatch.Route, lset model.LabelSet, intervals map[string][]timeinterval.TimeInterval, now time.Time) RouteMatch {
+	groupLabels := model.LabelSet{}
+	for ln, lv := range lset {
+		if _, ok := r.RouteOpts.GroupBy[ln]; ok || r.RouteOpts.GroupByAll {
+			groupLabels[ln] = lv
+		}
+	}
+	match := RouteMatch{
+		RouteID:        r.ID(),
+		Receiver:       r.RouteOpts.Receiver,
+		GroupKey:       fmt.Sprintf("%s:%s", r.Key(), groupLabels),
+		GroupLabels:    groupLabels,
+		GroupWait:      r.RouteOpts.GroupWait,
+		GroupInterval:  r.RouteOpts.GroupInterval,
+		RepeatInterval: r.RouteOpts.RepeatInterval,
+	}
+	for p := r; p != nil; p = parents[p] {
+		for _, m := range p.Matchers {
+			if m.Name == models.AutogeneratedRouteLabel {
+				match.Autogenerated = true
+			}
+		}
+		// the matchers of the parents come first.
+		matchers := make([]string, 0, len(p.Matchers)+len(match.Matchers))
+		for _, m := range p.Matchers {
+			matchers = append(matchers, m.String())
+		}
+		match.Matchers = append(matchers, match.Matchers...)
+	}
+	for _, name := range r.RouteOpts.MuteTimeIntervals {
+		if inTimeInterval(intervals[name], now) {
+			match.MutedBy = append(match.MutedBy, name)
+		}
+	}
+	// Like the Alertmanager, a policy with active time intervals is muted when none of them is active.
+	if len(r.RouteOpts.ActiveTimeIntervals) > 0 {
+		active := false
+		for _, name := range r.RouteOpts.ActiveTimeIntervals {
+			if inTimeInterval(intervals[name], now) {
+				active = true
+				break
+			}
+		}
+		if !active {
+			match.MutedBy = append(match.MutedBy, r.RouteOpts.ActiveTimeIntervals...)
+		}
+	}
+	return match
+}
+
+func inTimeInterval(intervals []timeinterval.TimeInterval, now time.Time) bool {
+	for _, ti := range intervals {
+		if ti.ContainsTime(now.UTC()) {
+			return true
+		}
+	}
+	return false
+}
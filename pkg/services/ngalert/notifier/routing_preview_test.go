@@ -0,0 +1,127 @@
+package notifier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+const previewRoutingConfig = `
+{
+	"alertmanager_config": {
+		"route": {
+			"receiver": "grafana-default-email",
+			"group_by": ["alertname"],
+			"routes": [{
+				"receiver": "db",
+				"object_matchers": [["team", "=", "db"]],
+				"group_by": ["alertname", "instance"],
+				"mute_time_intervals": ["always"]
+			}]
+		},
+		"mute_time_intervals": [{"name": "always", "time_intervals": [{}]}],
+		"receivers": [{
+			"name": "grafana-default-email",
+			"grafana_managed_receiver_configs": [{"uid": "", "name": "email", "type": "email", "settings": {"addresses": "<example@email.com>"}}]
+		}, {
+			"name": "db",
+			"grafana_managed_receiver_configs": [{"uid": "", "name": "db", "type": "email", "settings": {"addresses": "<db@email.com>"}}]
+		}]
+	}
+}`
+
+func TestMultiOrgAlertmanager_PreviewRouting(t *testing.T) {
+	mam := setupMam(t, nil)
+	mam.featureManager = featuremgmt.WithFeatures(featuremgmt.FlagAlertingSimplifiedRouting)
+	ctx := context.Background()
+	require.NoError(t, mam.LoadAndSyncAlertmanagersForOrgs(ctx))
+
+	cfg, err := Load([]byte(previewRoutingConfig))
+	require.NoError(t, err)
+	require.NoError(t, mam.SaveAndApplyAlertmanagerConfiguration(ctx, 1, *cfg))
+
+	silence := models.SilenceGen(func(s *models.Silence) { s.Matchers = nil }, models.SilenceMuts.WithEmptyId(), models.SilenceMuts.WithMatcher("instance", "db-1", labels.MatchEqual))()
+	silenceID, err := mam.CreateSilence(ctx, 1, silence)
+	require.NoError(t, err)
+
+	settings := models.NotificationSettings{Receiver: "db", GroupBy: []string{"alertname", models.FolderTitleLabel, "instance"}}
+	autogen := model.LabelSet{"alertname": "rule", "team": "web"}
+	for k, v := range settings.ToLabels() {
+		autogen[model.LabelName(k)] = model.LabelValue(v)
+	}
+	silences, err := mam.ListSilences(ctx, 1, nil)
+	require.NoError(t, err)
+	now := time.Now()
+	routing, err := mam.PreviewRouting(ctx, 1, []models.NotificationSettings{settings}, []model.LabelSet{
+		{"alertname": "rule", "team": "db", "instance": "db-1"},
+		{"alertname": "rule", "team": "web", "instance": "web-1"},
+		autogen,
+	}, silences, now)
+	require.NoError(t, err)
+	require.Len(t, routing, 3)
+
+	t.Run("alert matched by policy with active mute timing and silence", func(t *testing.T) {
+		require.Len(t, routing[0].Routes, 1)
+		route := routing[0].Routes[0]
+		require.Equal(t, "db", route.Receiver)
+		require.Equal(t, []string{`team="db"`}, route.Matchers)
+		require.Equal(t, model.LabelSet{"alertname": "rule", "instance": "db-1"}, route.GroupLabels)
+		require.Equal(t, `{}/{team="db"}:{alertname="rule", instance="db-1"}`, route.GroupKey)
+		require.Equal(t, []string{"always"}, route.MutedBy)
+		require.False(t, route.Autogenerated)
+		require.Equal(t, []string{silenceID}, routing[0].SilencedBy)
+	})
+
+	t.Run("alert matched by default policy", func(t *testing.T) {
+		require.Len(t, routing[1].Routes, 1)
+		route := routing[1].Routes[0]
+		require.Equal(t, "grafana-default-email", route.Receiver)
+		require.Empty(t, route.Matchers)
+		require.Equal(t, model.LabelSet{"alertname": "rule"}, route.GroupLabels)
+		require.Empty(t, route.MutedBy)
+		require.Empty(t, routing[1].SilencedBy)
+	})
+
+	t.Run("alert of rule with notification settings matched by autogenerated policy", func(t *testing.T) {
+		require.Len(t, routing[2].Routes, 1)
+		route := routing[2].Routes[0]
+		require.Equal(t, "db", route.Receiver)
+		require.True(t, route.Autogenerated)
+		require.Empty(t, route.MutedBy)
+	})
+
+	t.Run("only the given silences are matched", func(t *testing.T) {
+		routing, err := mam.PreviewRouting(ctx, 1, nil, []model.LabelSet{
+			{"alertname": "rule", "team": "db", "instance": "db-1"},
+		}, nil, now)
+		require.NoError(t, err)
+		require.Empty(t, routing[0].SilencedBy)
+	})
+}
+
+func TestNewRouteMatch_ActiveTimeIntervals(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	intervals := map[string][]timeinterval.TimeInterval{
+		"never": nil,
+		"past":  {{Years: []timeinterval.YearRange{{InclusiveRange: timeinterval.InclusiveRange{Begin: 1999, End: 1999}}}}},
+		"now":   {{Years: []timeinterval.YearRange{{InclusiveRange: timeinterval.InclusiveRange{Begin: 2024, End: 2024}}}}},
+	}
+	match := func(active ...string) RouteMatch {
+		r := dispatch.NewRoute(&config.Route{Receiver: "receiver", ActiveTimeIntervals: active}, nil)
+		return newRouteMatch(r, nil, model.LabelSet{}, intervals, now)
+	}
+
+	require.Empty(t, match().MutedBy, "policy without active time intervals should not be muted")
+	require.Empty(t, match("past", "now").MutedBy, "policy in one of its active time intervals should not be muted")
+	require.Equal(t, []string{"never", "past"}, match("never", "past").MutedBy, "policy outside its active time intervals should be muted")
+}
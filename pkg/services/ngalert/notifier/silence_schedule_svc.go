@@ -0,0 +1,188 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// SilenceScheduleStore is the interface for storing and retrieving silence schedules.
+type SilenceScheduleStore interface {
+	GetSilenceSchedule(ctx context.Context, orgID int64, uid string) (*models.SilenceSchedule, error)
+	ListSilenceSchedules(ctx context.Context, query *models.ListSilenceSchedulesQuery) ([]*models.SilenceSchedule, error)
+	InsertSilenceSchedule(ctx context.Context, s *models.SilenceSchedule) error
+	UpdateSilenceSchedule(ctx context.Context, s *models.SilenceSchedule) error
+	DeleteSilenceSchedule(ctx context.Context, orgID int64, uid string) error
+}
+
+// SilenceScheduleService is the authenticated service for managing silence schedules. Access to a schedule is
+// authorized in the same way as access to the silences it creates, so a user that can only create silences for
+// the rules of some folders can only create schedules for the rules of these folders.
+type SilenceScheduleService struct {
+	authz    SilenceAccessControlService
+	store    SilenceScheduleStore
+	silences SilenceStore
+	log      log.Logger
+	now      func() time.Time
+}
+
+func NewSilenceScheduleService(authz SilenceAccessControlService, store SilenceScheduleStore, silences SilenceStore, log log.Logger) *SilenceScheduleService {
+	return &SilenceScheduleService{
+		authz:    authz,
+		store:    store,
+		silences: silences,
+		log:      log,
+		now:      time.Now,
+	}
+}
+
+// GetSilenceSchedule retrieves a silence schedule by its UID.
+func (s *SilenceScheduleService) GetSilenceSchedule(ctx context.Context, user identity.Requester, uid string) (*models.SilenceSchedule, error) {
+	schedule, err := s.store.GetSilenceSchedule(ctx, user.GetOrgID(), uid)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeReadSilence(ctx, user, schedule.AsSilence()); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// ListSilenceSchedules retrieves all silence schedules of the organization that the user can read.
+func (s *SilenceScheduleService) ListSilenceSchedules(ctx context.Context, user identity.Requester) ([]*models.SilenceSchedule, error) {
+	schedules, err := s.store.ListSilenceSchedules(ctx, &models.ListSilenceSchedulesQuery{OrgID: user.GetOrgID()})
+	if err != nil {
+		return nil, err
+	}
+	silences := make([]*models.Silence, 0, len(schedules))
+	bySilence := make(map[*models.Silence]*models.SilenceSchedule, len(schedules))
+	for _, schedule := range schedules {
+		silence := schedule.AsSilence()
+		silences = append(silences, silence)
+		bySilence[silence] = schedule
+	}
+	allowed, err := s.authz.FilterByAccess(ctx, user, silences...)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*models.SilenceSchedule, 0, len(allowed))
+	for _, silence := range allowed {
+		result = append(result, bySilence[silence])
+	}
+	return result, nil
+}
+
+// CreateSilenceSchedule creates a new silence schedule. Its silences are created by the SilenceScheduler.
+func (s *SilenceScheduleService) CreateSilenceSchedule(ctx context.Context, user identity.Requester, schedule models.SilenceSchedule) (*models.SilenceSchedule, error) {
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeCreateSilence(ctx, user, schedule.AsSilence()); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	schedule.OrgID = user.GetOrgID()
+	schedule.Silences = nil
+	schedule.CreatedBy = user.GetUID().String()
+	schedule.Created = now
+	schedule.UpdatedBy = schedule.CreatedBy
+	schedule.Updated = now
+	if err := s.store.InsertSilenceSchedule(ctx, &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// UpdateSilenceSchedule updates an existing silence schedule. The silences that were created for the schedule are
+// expired, and the SilenceScheduler creates the silences of the updated schedule.
+func (s *SilenceScheduleService) UpdateSilenceSchedule(ctx context.Context, user identity.Requester, schedule models.SilenceSchedule) (*models.SilenceSchedule, error) {
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	existing, err := s.store.GetSilenceSchedule(ctx, user.GetOrgID(), schedule.UID)
+	if err != nil {
+		return nil, err
+	}
+	// Version 0 means that the caller does not care about concurrent changes.
+	if schedule.Version != 0 && schedule.Version != existing.Version {
+		return nil, models.ErrSilenceScheduleConflict.Errorf("silence schedule %s has version %d, not %d", existing.UID, existing.Version, schedule.Version)
+	}
+	if err := s.authz.AuthorizeUpdateSilence(ctx, user, existing.AsSilence()); err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeUpdateSilence(ctx, user, schedule.AsSilence()); err != nil {
+		return nil, err
+	}
+	if err := validateSilenceUpdate(existing.AsSilence(), *schedule.AsSilence()); err != nil {
+		return nil, err
+	}
+
+	schedule.OrgID = existing.OrgID
+	schedule.Silences = nil
+	schedule.CreatedBy = existing.CreatedBy
+	schedule.Created = existing.Created
+	schedule.UpdatedBy = user.GetUID().String()
+	schedule.Updated = s.now()
+	schedule.Version = existing.Version
+	if err := s.store.UpdateSilenceSchedule(ctx, &schedule); err != nil {
+		return nil, err
+	}
+	s.expireSilences(ctx, existing)
+	return &schedule, nil
+}
+
+// DeleteSilenceSchedule deletes a silence schedule and expires the silences that were created for it.
+func (s *SilenceScheduleService) DeleteSilenceSchedule(ctx context.Context, user identity.Requester, uid string) error {
+	existing, err := s.store.GetSilenceSchedule(ctx, user.GetOrgID(), uid)
+	if err != nil {
+		return err
+	}
+	if err := s.authz.AuthorizeUpdateSilence(ctx, user, existing.AsSilence()); err != nil {
+		return err
+	}
+	if err := s.store.DeleteSilenceSchedule(ctx, existing.OrgID, existing.UID); err != nil {
+		return err
+	}
+	s.expireSilences(ctx, existing)
+	return nil
+}
+
+// WithAccessControlMetadata adds the permissions of the user on the silences of the schedules.
+func (s *SilenceScheduleService) WithAccessControlMetadata(ctx context.Context, user identity.Requester, schedules ...*models.SilenceScheduleWithMetadata) error {
+	silences := make([]*models.Silence, 0, len(schedules))
+	bySilence := make(map[*models.Silence]*models.SilenceScheduleWithMetadata, len(schedules))
+	for _, schedule := range schedules {
+		silence := schedule.AsSilence()
+		silences = append(silences, silence)
+		bySilence[silence] = schedule
+	}
+	permissions, err := s.authz.SilenceAccess(ctx, user, silences)
+	if err != nil {
+		return err
+	}
+	if len(permissions) != len(silences) {
+		s.log.Warn("Failed to get metadata for all silence schedules")
+	}
+	for silence, perms := range permissions {
+		if schedule, ok := bySilence[silence]; ok {
+			perms := perms
+			schedule.Permissions = &perms
+		}
+	}
+	return nil
+}
+
+// expireSilences expires the silences that were created for the schedule. Errors are logged, because the schedule
+// was already changed and the silences end eventually.
+func (s *SilenceScheduleService) expireSilences(ctx context.Context, schedule *models.SilenceSchedule) {
+	for _, silence := range schedule.Silences {
+		if err := s.silences.DeleteSilence(ctx, schedule.OrgID, silence.ID); err != nil && !errors.Is(err, ErrSilenceNotFound) {
+			s.log.FromContext(ctx).Warn("Failed to expire silence of silence schedule", "schedule", schedule.UID, "silenceID", silence.ID, "error", err)
+		}
+	}
+}
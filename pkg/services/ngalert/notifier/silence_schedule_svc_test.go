@@ -0,0 +1,116 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/log"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/ngalert/accesscontrol/fakes"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	ngfakes "github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func TestSilenceScheduleService(t *testing.T) {
+	user := ac.BackgroundUser("test", 1, org.RoleEditor, nil)
+	newSchedule := func() models.SilenceSchedule {
+		return models.SilenceSchedule{
+			Title:    "Weekly maintenance",
+			Matchers: amv2.Matchers{{Name: util.Pointer("team"), Value: util.Pointer("db"), IsRegex: util.Pointer(false)}},
+			Cron:     "0 22 * * 6",
+			Duration: 4 * time.Hour,
+		}
+	}
+	setup := func() (*SilenceScheduleService, *fakes.FakeSilenceService, *fakeSilenceScheduleStore, *ngfakes.FakeSilenceStore) {
+		authz := &fakes.FakeSilenceService{}
+		store := &fakeSilenceScheduleStore{schedules: map[string]models.SilenceSchedule{}}
+		silences := &ngfakes.FakeSilenceStore{Silences: map[string]*models.Silence{}}
+		return NewSilenceScheduleService(authz, store, silences, log.NewNopLogger()), authz, store, silences
+	}
+
+	t.Run("create records the owner and is authorized like a silence", func(t *testing.T) {
+		svc, authz, store, _ := setup()
+		created, err := svc.CreateSilenceSchedule(context.Background(), user, newSchedule())
+		require.NoError(t, err)
+		require.NotEmpty(t, created.UID)
+		require.Equal(t, user.GetOrgID(), created.OrgID)
+		require.Equal(t, user.GetUID().String(), created.CreatedBy)
+		require.Equal(t, created.CreatedBy, created.UpdatedBy)
+		require.Contains(t, store.schedules, created.UID)
+		require.Len(t, authz.Calls, 1)
+		require.Equal(t, "AuthorizeCreateSilence", authz.Calls[0].MethodName)
+
+		authz.AuthorizeCreateSilenceFunc = func(context.Context, identity.Requester, *models.Silence) error {
+			return errors.New("forbidden")
+		}
+		_, err = svc.CreateSilenceSchedule(context.Background(), user, newSchedule())
+		require.ErrorContains(t, err, "forbidden")
+		require.Len(t, store.schedules, 1)
+	})
+
+	t.Run("create rejects invalid schedules", func(t *testing.T) {
+		svc, _, _, _ := setup()
+		invalid := newSchedule()
+		invalid.Duration = 0
+		_, err := svc.CreateSilenceSchedule(context.Background(), user, invalid)
+		require.ErrorIs(t, err, models.ErrSilenceScheduleValidation)
+	})
+
+	t.Run("update expires the silences of the schedule", func(t *testing.T) {
+		svc, _, store, silences := setup()
+		created, err := svc.CreateSilenceSchedule(context.Background(), user, newSchedule())
+		require.NoError(t, err)
+		silenceID, err := silences.CreateSilence(context.Background(), 1, *created.Silence(models.SilencePeriod{StartsAt: time.Now(), EndsAt: time.Now().Add(time.Hour)}, time.Now()))
+		require.NoError(t, err)
+		stored := store.schedules[created.UID]
+		stored.Silences = []models.ScheduledSilence{{ID: silenceID}}
+		stored.Version++
+		store.schedules[created.UID] = stored
+
+		update := newSchedule()
+		update.UID = created.UID
+		update.Cron = "0 23 * * 6"
+		updated, err := svc.UpdateSilenceSchedule(context.Background(), user, update)
+		require.NoError(t, err)
+		require.Equal(t, "0 23 * * 6", updated.Cron)
+		require.Equal(t, created.CreatedBy, updated.CreatedBy)
+		require.Empty(t, updated.Silences)
+		require.Empty(t, silences.Silences)
+	})
+
+	t.Run("update of an outdated version returns conflict", func(t *testing.T) {
+		svc, _, _, _ := setup()
+		created, err := svc.CreateSilenceSchedule(context.Background(), user, newSchedule())
+		require.NoError(t, err)
+
+		update := newSchedule()
+		update.UID = created.UID
+		update.Version = created.Version + 1
+		_, err = svc.UpdateSilenceSchedule(context.Background(), user, update)
+		require.ErrorIs(t, err, models.ErrSilenceScheduleConflict)
+	})
+
+	t.Run("delete expires the silences of the schedule", func(t *testing.T) {
+		svc, authz, store, silences := setup()
+		created, err := svc.CreateSilenceSchedule(context.Background(), user, newSchedule())
+		require.NoError(t, err)
+		silenceID, err := silences.CreateSilence(context.Background(), 1, *created.Silence(models.SilencePeriod{StartsAt: time.Now(), EndsAt: time.Now().Add(time.Hour)}, time.Now()))
+		require.NoError(t, err)
+		stored := store.schedules[created.UID]
+		stored.Silences = []models.ScheduledSilence{{ID: silenceID}, {ID: "already-expired"}}
+		store.schedules[created.UID] = stored
+
+		require.NoError(t, svc.DeleteSilenceSchedule(context.Background(), user, created.UID))
+		require.Empty(t, store.schedules)
+		require.Empty(t, silences.Silences)
+		require.Equal(t, "AuthorizeUpdateSilence", authz.Calls[len(authz.Calls)-1].MethodName)
+	})
+}
@@ -0,0 +1,131 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+const (
+	// defaultSilenceSchedulerInterval is how often the silences of silence schedules are created.
+	defaultSilenceSchedulerInterval = time.Minute
+	// defaultSilenceSchedulerHorizon is how long ahead of time the silences of silence schedules are created.
+	defaultSilenceSchedulerHorizon = 24 * time.Hour
+)
+
+// SilenceScheduler creates the silences of the periods of silence schedules in the Alertmanager ahead of time.
+// In high availability mode every instance runs the scheduler. The silences of a schedule are recorded in the
+// schedule with optimistic locking, so if two instances create the silences of the same period, the instance that
+// fails to record them expires its silences.
+type SilenceScheduler struct {
+	store    SilenceScheduleStore
+	silences SilenceStore
+	clock    clock.Clock
+	log      log.Logger
+
+	interval time.Duration
+	horizon  time.Duration
+}
+
+func NewSilenceScheduler(store SilenceScheduleStore, silences SilenceStore, clk clock.Clock, log log.Logger) *SilenceScheduler {
+	return &SilenceScheduler{
+		store:    store,
+		silences: silences,
+		clock:    clk,
+		log:      log,
+		interval: defaultSilenceSchedulerInterval,
+		horizon:  defaultSilenceSchedulerHorizon,
+	}
+}
+
+// Run creates the silences of all schedules periodically until the context is cancelled.
+func (s *SilenceScheduler) Run(ctx context.Context) error {
+	ticker := s.clock.Ticker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.ScheduleSilences(ctx)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// ScheduleSilences creates the silences of the periods of all schedules that are not paused and start before
+// the horizon, if they were not created yet.
+func (s *SilenceScheduler) ScheduleSilences(ctx context.Context) {
+	schedules, err := s.store.ListSilenceSchedules(ctx, &models.ListSilenceSchedulesQuery{ExcludePaused: true})
+	if err != nil {
+		s.log.Error("Failed to list silence schedules", "error", err)
+		return
+	}
+	for _, schedule := range schedules {
+		if err := s.scheduleSilences(ctx, schedule); err != nil {
+			if errors.Is(err, models.ErrSilenceScheduleConflict) {
+				s.log.Debug("Silence schedule was changed while its silences were created", "org", schedule.OrgID, "schedule", schedule.UID)
+				continue
+			}
+			s.log.Error("Failed to create silences of silence schedule", "org", schedule.OrgID, "schedule", schedule.UID, "error", err)
+		}
+	}
+}
+
+func (s *SilenceScheduler) scheduleSilences(ctx context.Context, schedule *models.SilenceSchedule) error {
+	now := s.clock.Now()
+	periods, err := schedule.Periods(now, now.Add(s.horizon))
+	if err != nil {
+		return err
+	}
+
+	// Silences of periods that ended are forgotten.
+	silences := make([]models.ScheduledSilence, 0, len(schedule.Silences)+len(periods))
+	for _, silence := range schedule.Silences {
+		if silence.EndsAt.After(now) {
+			silences = append(silences, silence)
+		}
+	}
+	changed := len(silences) != len(schedule.Silences)
+
+	var created []string
+	for _, p := range periods {
+		if schedule.HasSilence(p) {
+			continue
+		}
+		id, err := s.silences.CreateSilence(ctx, schedule.OrgID, *schedule.Silence(p, now))
+		if err != nil {
+			s.expire(ctx, schedule.OrgID, created)
+			return err
+		}
+		created = append(created, id)
+		silences = append(silences, models.ScheduledSilence{ID: id, StartsAt: p.StartsAt, EndsAt: p.EndsAt})
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	schedule.Silences = silences
+	if err := s.store.UpdateSilenceSchedule(ctx, schedule); err != nil {
+		// Another instance created the silences of the schedule, or the schedule was changed.
+		s.expire(ctx, schedule.OrgID, created)
+		return err
+	}
+	if len(created) > 0 {
+		s.log.Info("Created silences of silence schedule", "org", schedule.OrgID, "schedule", schedule.UID, "count", len(created))
+	}
+	return nil
+}
+
+func (s *SilenceScheduler) expire(ctx context.Context, orgID int64, silenceIDs []string) {
+	for _, id := range silenceIDs {
+		if err := s.silences.DeleteSilence(ctx, orgID, id); err != nil {
+			s.log.Warn("Failed to expire silence", "org", orgID, "silenceID", id, "error", err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package notifier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	ngfakes "github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+type fakeSilenceScheduleStore struct {
+	schedules map[string]models.SilenceSchedule
+	updateErr error
+}
+
+func (f *fakeSilenceScheduleStore) GetSilenceSchedule(_ context.Context, orgID int64, uid string) (*models.SilenceSchedule, error) {
+	s, ok := f.schedules[uid]
+	if !ok || s.OrgID != orgID {
+		return nil, models.ErrSilenceScheduleNotFound.Errorf("")
+	}
+	return &s, nil
+}
+
+func (f *fakeSilenceScheduleStore) ListSilenceSchedules(_ context.Context, query *models.ListSilenceSchedulesQuery) ([]*models.SilenceSchedule, error) {
+	var result []*models.SilenceSchedule
+	for _, s := range f.schedules {
+		if (query.OrgID > 0 && s.OrgID != query.OrgID) || (query.ExcludePaused && s.IsPaused) {
+			continue
+		}
+		s := s
+		result = append(result, &s)
+	}
+	return result, nil
+}
+
+func (f *fakeSilenceScheduleStore) InsertSilenceSchedule(_ context.Context, s *models.SilenceSchedule) error {
+	if s.UID == "" {
+		s.UID = util.GenerateShortUID()
+	}
+	s.Version = 1
+	f.schedules[s.UID] = *s
+	return nil
+}
+
+func (f *fakeSilenceScheduleStore) UpdateSilenceSchedule(_ context.Context, s *models.SilenceSchedule) error {
+	if f.updateErr != nil {
+		return f.updateErr
+	}
+	existing, ok := f.schedules[s.UID]
+	if !ok || existing.Version != s.Version {
+		return models.ErrSilenceScheduleConflict.Errorf("")
+	}
+	s.Version++
+	f.schedules[s.UID] = *s
+	return nil
+}
+
+func (f *fakeSilenceScheduleStore) DeleteSilenceSchedule(_ context.Context, _ int64, uid string) error {
+	delete(f.schedules, uid)
+	return nil
+}
+
+func TestSilenceScheduler(t *testing.T) {
+	// Saturday.
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	schedule := models.SilenceSchedule{
+		UID:      "maintenance",
+		OrgID:    1,
+		Title:    "Weekly maintenance",
+		Matchers: amv2.Matchers{{Name: util.Pointer("team"), Value: util.Pointer("db"), IsRegex: util.Pointer(false)}},
+		Cron:     "0 22 * * 6",
+		Duration: 4 * time.Hour,
+		Version:  1,
+	}
+	setup := func(t *testing.T, schedules ...models.SilenceSchedule) (*SilenceScheduler, *fakeSilenceScheduleStore, *ngfakes.FakeSilenceStore, *clock.Mock) {
+		t.Helper()
+		store := &fakeSilenceScheduleStore{schedules: map[string]models.SilenceSchedule{}}
+		for _, s := range schedules {
+			store.schedules[s.UID] = s
+		}
+		silences := &ngfakes.FakeSilenceStore{Silences: map[string]*models.Silence{}}
+		clk := clock.NewMock()
+		clk.Set(start)
+		return NewSilenceScheduler(store, silences, clk, log.NewNopLogger()), store, silences, clk
+	}
+
+	t.Run("creates the silences of the periods within the horizon once", func(t *testing.T) {
+		scheduler, store, silences, clk := setup(t, schedule)
+
+		scheduler.ScheduleSilences(context.Background())
+		require.Len(t, silences.Silences, 1)
+		stored := store.schedules[schedule.UID]
+		require.Len(t, stored.Silences, 1)
+		require.Equal(t, int64(2), stored.Version)
+		silence := silences.Silences[stored.Silences[0].ID]
+		require.Equal(t, time.Date(2024, 6, 1, 22, 0, 0, 0, time.UTC), time.Time(*silence.StartsAt))
+		require.Equal(t, time.Date(2024, 6, 2, 2, 0, 0, 0, time.UTC), time.Time(*silence.EndsAt))
+		require.Equal(t, schedule.Matchers, silence.Matchers)
+		require.Equal(t, "silence schedule maintenance", *silence.CreatedBy)
+
+		clk.Add(time.Hour)
+		scheduler.ScheduleSilences(context.Background())
+		require.Len(t, silences.Silences, 1)
+		require.Equal(t, int64(2), store.schedules[schedule.UID].Version)
+
+		// After the period ended, it is forgotten.
+		clk.Set(time.Date(2024, 6, 2, 3, 0, 0, 0, time.UTC))
+		scheduler.ScheduleSilences(context.Background())
+		require.Empty(t, store.schedules[schedule.UID].Silences)
+
+		// The next period is created when it is within the horizon.
+		clk.Set(time.Date(2024, 6, 8, 0, 0, 0, 0, time.UTC))
+		scheduler.ScheduleSilences(context.Background())
+		require.Len(t, silences.Silences, 2)
+		require.Len(t, store.schedules[schedule.UID].Silences, 1)
+		require.Equal(t, time.Date(2024, 6, 8, 22, 0, 0, 0, time.UTC), store.schedules[schedule.UID].Silences[0].StartsAt)
+	})
+
+	t.Run("silence of a period in progress starts now", func(t *testing.T) {
+		scheduler, store, silences, clk := setup(t, schedule)
+		now := time.Date(2024, 6, 1, 23, 0, 0, 0, time.UTC)
+		clk.Set(now)
+
+		scheduler.ScheduleSilences(context.Background())
+		stored := store.schedules[schedule.UID]
+		require.Len(t, stored.Silences, 1)
+		require.Equal(t, time.Date(2024, 6, 1, 22, 0, 0, 0, time.UTC), stored.Silences[0].StartsAt)
+		require.Equal(t, now, time.Time(*silences.Silences[stored.Silences[0].ID].StartsAt))
+	})
+
+	t.Run("paused schedules are skipped", func(t *testing.T) {
+		paused := schedule
+		paused.IsPaused = true
+		scheduler, _, silences, _ := setup(t, paused)
+
+		scheduler.ScheduleSilences(context.Background())
+		require.Empty(t, silences.Silences)
+	})
+
+	t.Run("silences are expired if the schedule cannot be updated", func(t *testing.T) {
+		scheduler, store, silences, _ := setup(t, schedule)
+		store.updateErr = models.ErrSilenceScheduleConflict.Errorf("")
+
+		scheduler.ScheduleSilences(context.Background())
+		require.Empty(t, silences.Silences)
+		require.Empty(t, store.schedules[schedule.UID].Silences)
+	})
+}
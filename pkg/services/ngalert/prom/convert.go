@@ -0,0 +1,294 @@
+package prom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+const (
+	// queryRefID is the RefID of the query of the Prometheus expression.
+	queryRefID = "A"
+	// conditionRefID is the RefID of the expression that is the condition of alerting rules.
+	conditionRefID = "B"
+
+	// alwaysFiringExpression is the condition of alerting rules whose expression is not a comparison with a number.
+	// Prometheus fires an alert for every series that the expression returns, whatever its value is.
+	alwaysFiringExpression = "is_number($" + queryRefID + ") || is_nan($" + queryRefID + ") || is_inf($" + queryRefID + ")"
+)
+
+var (
+	ErrInvalidConfig = errors.New("invalid configuration of Prometheus rule conversion")
+	ErrInvalidRule   = errors.New("invalid Prometheus rule")
+)
+
+// Config is the configuration of the conversion of Prometheus rules to Grafana-managed rules.
+type Config struct {
+	// DatasourceUID is the UID of the Prometheus data source the queries of converted rules read from.
+	DatasourceUID string
+	// DatasourceType is the type of the data source. Defaults to prometheus.
+	DatasourceType string
+	// DefaultInterval is the evaluation interval of groups that do not set one. Defaults to 1 minute.
+	DefaultInterval time.Duration
+	// FromTimeRange is the start of the relative time range of queries. Defaults to 10 minutes.
+	FromTimeRange time.Duration
+	// RecordTarget is the target of recording rules. Empty means the default target of the instance.
+	RecordTarget models.RecordTarget
+	// NoDataState is the state of alerting rules whose query returns no data. Defaults to OK,
+	// because Prometheus does not fire alerts when the expression returns nothing.
+	NoDataState models.NoDataState
+	// ExecErrState is the state of alerting rules that fail to evaluate. Defaults to Error.
+	ExecErrState models.ExecutionErrorState
+}
+
+// Converter converts Prometheus rule groups to Grafana-managed rule groups.
+type Converter struct {
+	cfg Config
+}
+
+func NewConverter(cfg Config) (*Converter, error) {
+	if cfg.DatasourceUID == "" {
+		return nil, fmt.Errorf("%w: data source UID is required", ErrInvalidConfig)
+	}
+	if cfg.DatasourceType == "" {
+		cfg.DatasourceType = "prometheus"
+	}
+	if cfg.DefaultInterval == 0 {
+		cfg.DefaultInterval = time.Minute
+	}
+	if cfg.FromTimeRange == 0 {
+		cfg.FromTimeRange = 10 * time.Minute
+	}
+	if cfg.NoDataState == "" {
+		cfg.NoDataState = models.OK
+	}
+	if cfg.ExecErrState == "" {
+		cfg.ExecErrState = models.ErrorErrState
+	}
+	if cfg.DefaultInterval < 0 || cfg.FromTimeRange < 0 {
+		return nil, fmt.Errorf("%w: durations must be positive", ErrInvalidConfig)
+	}
+	if _, err := models.ParseRecordTarget(string(cfg.RecordTarget)); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+	}
+	if _, err := models.NoDataStateFromString(string(cfg.NoDataState)); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+	}
+	if _, err := models.ErrStateFromString(string(cfg.ExecErrState)); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+	}
+	return &Converter{cfg: cfg}, nil
+}
+
+// Convert converts the Prometheus rule groups to rule groups in the folder with the given UID. Rules do not have
+// a UID, and their title is the name of the alert or of the recorded metric. Titles are unique within a folder,
+// so the title of a rule whose name is also used in other groups gets the name of its group, such as
+// "InstanceDown (example)", and rules with the same name in a group get the suffix " (2)", " (3)" and so on
+// in the order they appear in the group. Titles do not depend on the order of the groups, so that the same groups
+// can be imported many times by matching rules by title.
+func (c *Converter) Convert(orgID int64, folderUID string, groups []PrometheusRuleGroup) ([]models.AlertRuleGroup, error) {
+	// groupsByName are the groups that have rules with each name.
+	groupsByName := make(map[string]map[string]struct{})
+	for _, g := range groups {
+		for _, r := range g.Rules {
+			name := ruleName(r)
+			if groupsByName[name] == nil {
+				groupsByName[name] = make(map[string]struct{})
+			}
+			groupsByName[name][g.Name] = struct{}{}
+		}
+	}
+	result := make([]models.AlertRuleGroup, 0, len(groups))
+	for _, g := range groups {
+		if g.Name == "" {
+			return nil, fmt.Errorf("%w: group name is empty", ErrInvalidRule)
+		}
+		interval := time.Duration(g.Interval)
+		if interval == 0 {
+			interval = c.cfg.DefaultInterval
+		}
+		group := models.AlertRuleGroup{
+			Title:     g.Name,
+			FolderUID: folderUID,
+			Interval:  int64(interval.Seconds()),
+			Rules:     make([]models.AlertRule, 0, len(g.Rules)),
+		}
+		titles := make(map[string]int)
+		for i, r := range g.Rules {
+			rule, err := c.convertRule(r)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d of group '%s': %w", i+1, g.Name, err)
+			}
+			if len(groupsByName[rule.Title]) > 1 {
+				rule.Title = fmt.Sprintf("%s (%s)", rule.Title, g.Name)
+			}
+			titles[rule.Title]++
+			if n := titles[rule.Title]; n > 1 {
+				rule.Title = fmt.Sprintf("%s (%d)", rule.Title, n)
+			}
+			rule.OrgID = orgID
+			rule.NamespaceUID = folderUID
+			rule.RuleGroup = g.Name
+			rule.RuleGroupIndex = i + 1
+			rule.IntervalSeconds = group.Interval
+			group.Rules = append(group.Rules, rule)
+		}
+		result = append(result, group)
+	}
+	return result, nil
+}
+
+// ruleName returns the name of the alert or of the recorded metric of the rule.
+func ruleName(r PrometheusRule) string {
+	if r.IsRecordingRule() {
+		return r.Record
+	}
+	return r.Alert
+}
+
+func (c *Converter) convertRule(r PrometheusRule) (models.AlertRule, error) {
+	if r.KeepFiringFor != 0 {
+		return models.AlertRule{}, fmt.Errorf("%w: keep_firing_for is not supported", ErrInvalidRule)
+	}
+	parsed, err := parser.ParseExpr(r.Expr)
+	if err != nil {
+		return models.AlertRule{}, fmt.Errorf("%w: invalid expression: %s", ErrInvalidRule, err)
+	}
+
+	if r.IsRecordingRule() {
+		if r.Alert != "" {
+			return models.AlertRule{}, fmt.Errorf("%w: only one of alert and record can be set", ErrInvalidRule)
+		}
+		query, err := c.query(r.Expr)
+		if err != nil {
+			return models.AlertRule{}, err
+		}
+		return models.AlertRule{
+			Title: r.Record,
+			Data:  []models.AlertQuery{query},
+			Record: &models.Record{
+				Metric: r.Record,
+				From:   queryRefID,
+				Target: c.cfg.RecordTarget,
+			},
+			Labels: r.Labels,
+		}, nil
+	}
+
+	if r.Alert == "" {
+		return models.AlertRule{}, fmt.Errorf("%w: one of alert and record must be set", ErrInvalidRule)
+	}
+	queryExpr, condition := splitComparison(r.Expr, parsed)
+	query, err := c.query(queryExpr)
+	if err != nil {
+		return models.AlertRule{}, err
+	}
+	return models.AlertRule{
+		Title:        r.Alert,
+		Condition:    conditionRefID,
+		Data:         []models.AlertQuery{query, condition},
+		For:          time.Duration(r.For),
+		NoDataState:  c.cfg.NoDataState,
+		ExecErrState: c.cfg.ExecErrState,
+		Labels:       r.Labels,
+		Annotations:  r.Annotations,
+	}, nil
+}
+
+func (c *Converter) query(promQL string) (models.AlertQuery, error) {
+	model, err := json.Marshal(map[string]any{
+		"refId":   queryRefID,
+		"expr":    promQL,
+		"instant": true,
+		"range":   false,
+		"datasource": map[string]string{
+			"type": c.cfg.DatasourceType,
+			"uid":  c.cfg.DatasourceUID,
+		},
+	})
+	if err != nil {
+		return models.AlertQuery{}, err
+	}
+	return models.AlertQuery{
+		RefID:         queryRefID,
+		DatasourceUID: c.cfg.DatasourceUID,
+		RelativeTimeRange: models.RelativeTimeRange{
+			From: models.Duration(c.cfg.FromTimeRange),
+			To:   0,
+		},
+		Model: model,
+	}, nil
+}
+
+// splitComparison returns the query and the condition of an alerting rule. If the expression filters a vector
+// by comparing it with a number, such as `rate(errors[5m]) > 10`, the vector is the query and the comparison is
+// a threshold expression, so that the value of the query is available to notifications even when it is below
+// the threshold. Otherwise, the whole expression is the query, and the condition is true for every series it returns.
+func splitComparison(promQL string, parsed parser.Expr) (string, models.AlertQuery) {
+	if e, ok := unwrapParens(parsed).(*parser.BinaryExpr); ok && !e.ReturnBool && (e.Op == parser.GTR || e.Op == parser.LSS) {
+		vector, number, op := e.LHS, e.RHS, e.Op
+		if _, ok := unwrapParens(vector).(*parser.NumberLiteral); ok {
+			// `10 < x` is the same as `x > 10`.
+			vector, number = number, vector
+			if op == parser.GTR {
+				op = parser.LSS
+			} else {
+				op = parser.GTR
+			}
+		}
+		if n, ok := unwrapParens(number).(*parser.NumberLiteral); ok && vector.Type() == parser.ValueTypeVector {
+			threshold := expr.ThresholdIsAbove
+			if op == parser.LSS {
+				threshold = expr.ThresholdIsBelow
+			}
+			pos := vector.PositionRange()
+			return promQL[pos.Start:pos.End], expressionQuery(map[string]any{
+				"type":       "threshold",
+				"expression": queryRefID,
+				"conditions": []any{
+					map[string]any{
+						"evaluator": map[string]any{
+							"type":   threshold,
+							"params": []float64{n.Val},
+						},
+					},
+				},
+			})
+		}
+	}
+	return promQL, expressionQuery(map[string]any{
+		"type":       "math",
+		"expression": alwaysFiringExpression,
+	})
+}
+
+func expressionQuery(model map[string]any) models.AlertQuery {
+	model["refId"] = conditionRefID
+	model["datasource"] = map[string]string{
+		"type": expr.DatasourceType,
+		"uid":  expr.DatasourceUID,
+	}
+	// The model consists of strings, numbers and maps of them, which are always marshalled.
+	raw, _ := json.Marshal(model)
+	return models.AlertQuery{
+		RefID:         conditionRefID,
+		DatasourceUID: expr.DatasourceUID,
+		Model:         raw,
+	}
+}
+
+func unwrapParens(e parser.Expr) parser.Expr {
+	for {
+		p, ok := e.(*parser.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.Expr
+	}
+}
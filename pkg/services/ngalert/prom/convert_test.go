@@ -0,0 +1,211 @@
+package prom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const testRules = `
+groups:
+  - name: example
+    interval: 30s
+    rules:
+      - alert: HighErrorRate
+        expr: sum(rate(errors_total[5m])) by (job) > 10
+        for: 5m
+        labels:
+          severity: critical
+        annotations:
+          summary: "{{ $labels.job }} has {{ $value }} errors"
+      - alert: LowDiskSpace
+        expr: 0.1 > (node_filesystem_avail_bytes / node_filesystem_size_bytes)
+      - alert: InstanceDown
+        expr: up == 0
+      - record: job:errors:rate5m
+        expr: sum(rate(errors_total[5m])) by (job)
+        labels:
+          team: backend
+  - name: other
+    rules:
+      - alert: InstanceDown
+        expr: absent(up)
+`
+
+func TestConvert(t *testing.T) {
+	groups, err := ParseRuleGroups([]byte(testRules))
+	require.NoError(t, err)
+
+	c, err := NewConverter(Config{DatasourceUID: "prom-uid", RecordTarget: models.RecordTargetSQL})
+	require.NoError(t, err)
+
+	result, err := c.Convert(1, "folder-uid", groups)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+
+	example := result[0]
+	require.Equal(t, "example", example.Title)
+	require.Equal(t, "folder-uid", example.FolderUID)
+	require.EqualValues(t, 30, example.Interval)
+	require.Len(t, example.Rules, 4)
+	require.EqualValues(t, 60, result[1].Interval)
+
+	t.Run("comparison with a number is split into query and threshold", func(t *testing.T) {
+		rule := example.Rules[0]
+		require.Equal(t, "HighErrorRate", rule.Title)
+		require.Equal(t, int64(1), rule.OrgID)
+		require.Equal(t, "folder-uid", rule.NamespaceUID)
+		require.Equal(t, "example", rule.RuleGroup)
+		require.Equal(t, 1, rule.RuleGroupIndex)
+		require.EqualValues(t, 30, rule.IntervalSeconds)
+		require.Equal(t, 5*time.Minute, rule.For)
+		require.Equal(t, map[string]string{"severity": "critical"}, rule.Labels)
+		require.Equal(t, map[string]string{"summary": "{{ $labels.job }} has {{ $value }} errors"}, rule.Annotations)
+		require.Equal(t, models.OK, rule.NoDataState)
+		require.Equal(t, models.ErrorErrState, rule.ExecErrState)
+		require.Equal(t, "B", rule.Condition)
+		require.Len(t, rule.Data, 2)
+
+		require.Equal(t, "prom-uid", rule.Data[0].DatasourceUID)
+		require.Equal(t, models.Duration(10*time.Minute), rule.Data[0].RelativeTimeRange.From)
+		require.JSONEq(t, `{
+			"refId": "A",
+			"expr": "sum(rate(errors_total[5m])) by (job)",
+			"instant": true,
+			"range": false,
+			"datasource": {"type": "prometheus", "uid": "prom-uid"}
+		}`, string(rule.Data[0].Model))
+		require.Equal(t, "__expr__", rule.Data[1].DatasourceUID)
+		require.JSONEq(t, `{
+			"refId": "B",
+			"type": "threshold",
+			"expression": "A",
+			"conditions": [{"evaluator": {"type": "gt", "params": [10]}}],
+			"datasource": {"type": "__expr__", "uid": "__expr__"}
+		}`, string(rule.Data[1].Model))
+	})
+
+	t.Run("comparison with a number on the left side is reversed", func(t *testing.T) {
+		rule := example.Rules[1]
+		require.JSONEq(t, `{
+			"refId": "A",
+			"expr": "(node_filesystem_avail_bytes / node_filesystem_size_bytes)",
+			"instant": true,
+			"range": false,
+			"datasource": {"type": "prometheus", "uid": "prom-uid"}
+		}`, string(rule.Data[0].Model))
+		require.JSONEq(t, `{
+			"refId": "B",
+			"type": "threshold",
+			"expression": "A",
+			"conditions": [{"evaluator": {"type": "lt", "params": [0.1]}}],
+			"datasource": {"type": "__expr__", "uid": "__expr__"}
+		}`, string(rule.Data[1].Model))
+	})
+
+	t.Run("other expressions fire for every returned series", func(t *testing.T) {
+		rule := example.Rules[2]
+		require.Equal(t, "InstanceDown (example)", rule.Title)
+		require.JSONEq(t, `{
+			"refId": "A",
+			"expr": "up == 0",
+			"instant": true,
+			"range": false,
+			"datasource": {"type": "prometheus", "uid": "prom-uid"}
+		}`, string(rule.Data[0].Model))
+		require.JSONEq(t, `{
+			"refId": "B",
+			"type": "math",
+			"expression": "is_number($A) || is_nan($A) || is_inf($A)",
+			"datasource": {"type": "__expr__", "uid": "__expr__"}
+		}`, string(rule.Data[1].Model))
+	})
+
+	t.Run("recording rules write the query to the target", func(t *testing.T) {
+		rule := example.Rules[3]
+		require.Equal(t, "job:errors:rate5m", rule.Title)
+		require.Equal(t, &models.Record{Metric: "job:errors:rate5m", From: "A", Target: models.RecordTargetSQL}, rule.Record)
+		require.Equal(t, map[string]string{"team": "backend"}, rule.Labels)
+		require.Len(t, rule.Data, 1)
+		require.Empty(t, rule.Condition)
+		require.EqualValues(t, models.RuleTypeRecording, rule.Type())
+	})
+
+	t.Run("rules with the same name in other groups get the name of their group", func(t *testing.T) {
+		require.Equal(t, "InstanceDown (other)", result[1].Rules[0].Title)
+	})
+
+	t.Run("rules with the same name in a group get unique titles", func(t *testing.T) {
+		groups, err := ParseRuleGroups([]byte(`
+groups:
+  - name: group
+    rules:
+      - alert: InstanceDown
+        expr: up == 0
+      - alert: InstanceDown
+        expr: absent(up)
+`))
+		require.NoError(t, err)
+		result, err := c.Convert(1, "folder-uid", groups)
+		require.NoError(t, err)
+		require.Equal(t, "InstanceDown", result[0].Rules[0].Title)
+		require.Equal(t, "InstanceDown (2)", result[0].Rules[1].Title)
+	})
+
+	t.Run("titles do not depend on the order of the groups", func(t *testing.T) {
+		reordered, err := c.Convert(1, "folder-uid", []PrometheusRuleGroup{groups[1], groups[0]})
+		require.NoError(t, err)
+		require.Equal(t, titles(result[0]), titles(reordered[1]))
+		require.Equal(t, titles(result[1]), titles(reordered[0]))
+	})
+
+	t.Run("converted rules are valid", func(t *testing.T) {
+		for _, g := range result {
+			for _, rule := range g.Rules {
+				require.NoError(t, rule.ValidateAlertRule(setting.UnifiedAlertingSettings{
+					BaseInterval:   10 * time.Second,
+					RecordingRules: setting.RecordingRuleSettings{SQL: setting.RecordingRuleSQLSettings{Enabled: true}},
+				}))
+			}
+		}
+	})
+}
+
+func titles(g models.AlertRuleGroup) []string {
+	result := make([]string, 0, len(g.Rules))
+	for _, r := range g.Rules {
+		result = append(result, r.Title)
+	}
+	return result
+}
+
+func TestConvertErrors(t *testing.T) {
+	c, err := NewConverter(Config{DatasourceUID: "prom-uid"})
+	require.NoError(t, err)
+
+	_, err = c.Convert(1, "folder-uid", []PrometheusRuleGroup{{
+		Name:  "group",
+		Rules: []PrometheusRule{{Alert: "alert", Expr: "up", KeepFiringFor: 60}},
+	}})
+	require.ErrorIs(t, err, ErrInvalidRule)
+	require.ErrorContains(t, err, "keep_firing_for")
+
+	_, err = c.Convert(1, "folder-uid", []PrometheusRuleGroup{{
+		Name:  "group",
+		Rules: []PrometheusRule{{Alert: "alert", Expr: "up >"}},
+	}})
+	require.ErrorIs(t, err, ErrInvalidRule)
+
+	_, err = NewConverter(Config{})
+	require.ErrorIs(t, err, ErrInvalidConfig)
+
+	_, err = NewConverter(Config{DatasourceUID: "prom-uid", RecordTarget: "unknown"})
+	require.ErrorIs(t, err, ErrInvalidConfig)
+
+	_, err = ParseRuleGroups([]byte(`groups: [{name: group, rules: [{alert: alert, expr: "up >"}]}]`))
+	require.Error(t, err)
+}
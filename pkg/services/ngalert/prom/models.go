@@ -0,0 +1,61 @@
+package prom
+
+import (
+	"errors"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/rulefmt"
+)
+
+// PrometheusRuleGroup is a group of rules in the format of Prometheus and Mimir rule files.
+type PrometheusRuleGroup struct {
+	Name     string           `yaml:"name"`
+	Interval model.Duration   `yaml:"interval,omitempty"`
+	Rules    []PrometheusRule `yaml:"rules"`
+}
+
+// PrometheusRule is an alerting rule if Alert is set, or a recording rule if Record is set.
+type PrometheusRule struct {
+	Alert         string            `yaml:"alert,omitempty"`
+	Record        string            `yaml:"record,omitempty"`
+	Expr          string            `yaml:"expr"`
+	For           model.Duration    `yaml:"for,omitempty"`
+	KeepFiringFor model.Duration    `yaml:"keep_firing_for,omitempty"`
+	Labels        map[string]string `yaml:"labels,omitempty"`
+	Annotations   map[string]string `yaml:"annotations,omitempty"`
+}
+
+// IsRecordingRule returns true if the rule is a recording rule.
+func (r PrometheusRule) IsRecordingRule() bool {
+	return r.Record != ""
+}
+
+// ParseRuleGroups parses and validates a rule file in the format of Prometheus, the same way Prometheus loads
+// rule files. Expressions and templates of annotations must be valid.
+func ParseRuleGroups(content []byte) ([]PrometheusRuleGroup, error) {
+	parsed, errs := rulefmt.Parse(content)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	groups := make([]PrometheusRuleGroup, 0, len(parsed.Groups))
+	for _, g := range parsed.Groups {
+		group := PrometheusRuleGroup{
+			Name:     g.Name,
+			Interval: g.Interval,
+			Rules:    make([]PrometheusRule, 0, len(g.Rules)),
+		}
+		for _, r := range g.Rules {
+			group.Rules = append(group.Rules, PrometheusRule{
+				Alert:         r.Alert.Value,
+				Record:        r.Record.Value,
+				Expr:          r.Expr.Value,
+				For:           r.For,
+				KeepFiringFor: r.KeepFiringFor,
+				Labels:        r.Labels,
+				Annotations:   r.Annotations,
+			})
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
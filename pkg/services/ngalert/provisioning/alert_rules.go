@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/grafana/grafana/pkg/apimachinery/identity"
@@ -16,6 +17,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/ngalert/provisioning/validation"
 	"github.com/grafana/grafana/pkg/services/ngalert/store"
 	"github.com/grafana/grafana/pkg/services/quota"
+	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
 )
 
@@ -37,6 +39,7 @@ type AlertRuleService struct {
 	defaultIntervalSeconds int64
 	baseIntervalSeconds    int64
 	rulesPerRuleGroupLimit int64
+	recordingRules         setting.RecordingRuleSettings
 	ruleStore              RuleStore
 	provenanceStore        ProvisioningStore
 	folderService          folder.Service
@@ -55,6 +58,7 @@ func NewAlertRuleService(ruleStore RuleStore,
 	defaultIntervalSeconds int64,
 	baseIntervalSeconds int64,
 	rulesPerRuleGroupLimit int64,
+	recordingRules setting.RecordingRuleSettings,
 	log log.Logger,
 	ns NotificationSettingsValidatorProvider,
 	authz RuleAccessControlService,
@@ -63,6 +67,7 @@ func NewAlertRuleService(ruleStore RuleStore,
 		defaultIntervalSeconds: defaultIntervalSeconds,
 		baseIntervalSeconds:    baseIntervalSeconds,
 		rulesPerRuleGroupLimit: rulesPerRuleGroupLimit,
+		recordingRules:         recordingRules,
 		ruleStore:              ruleStore,
 		provenanceStore:        provenanceStore,
 		folderService:          folderService,
@@ -377,6 +382,122 @@ func (service *AlertRuleService) ReplaceRuleGroup(ctx context.Context, user iden
 		return nil
 	}
 
+	if err := service.authorizeAndValidateDelta(ctx, user, delta); err != nil {
+		return err
+	}
+
+	return service.persistDelta(ctx, user, delta, provenance)
+}
+
+// ImportRuleGroupOptions control how ImportRuleGroup applies a rule group.
+type ImportRuleGroupOptions struct {
+	// DryRun only validates the changes and does not apply them.
+	DryRun bool
+	// PendingReceivers and PendingTimeIntervals are created by the same import but may not exist yet,
+	// for example in a dry run. The notification settings of the rules can refer to them.
+	PendingReceivers     []string
+	PendingTimeIntervals []string
+}
+
+// ImportRuleGroup replaces the rule group with the imported one, like ReplaceRuleGroup, and returns the changes.
+// Rules without UID replace the rule of the group with the same title, and rules with a UID that does not exist
+// are created with that UID, so that the same document can be imported many times and to other instances.
+func (service *AlertRuleService) ImportRuleGroup(ctx context.Context, user identity.Requester, group models.AlertRuleGroup, provenance models.Provenance, opts ImportRuleGroupOptions) (*store.GroupDelta, error) {
+	if err := models.ValidateRuleGroupInterval(group.Interval, service.baseIntervalSeconds); err != nil {
+		return nil, err
+	}
+
+	existing, err := service.ruleStore.ListAlertRules(ctx, &models.ListAlertRulesQuery{
+		OrgID:         user.GetOrgID(),
+		NamespaceUIDs: []string{group.FolderUID},
+		RuleGroups:    []string{group.Title},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alert rules: %w", err)
+	}
+	existingUIDs := make(map[string]struct{}, len(existing))
+	existingByTitle := make(map[string]string, len(existing))
+	for _, r := range existing {
+		existingUIDs[r.UID] = struct{}{}
+		existingByTitle[r.Title] = r.UID
+	}
+
+	// Titles are unique in the folder, so the rules that are created with the imported UID can be found by the title later.
+	newUIDs := make(map[string]string)
+	group.Rules = slices.Clone(group.Rules)
+	for i := range group.Rules {
+		rule := &group.Rules[i]
+		// Set the defaults of queries like the store does, so that an unchanged document does not produce updates.
+		rule.Data = slices.Clone(rule.Data)
+		for j := range rule.Data {
+			if err := rule.Data[j].PreSave(); err != nil {
+				return nil, fmt.Errorf("%w: invalid alert query %s of rule '%s': %s", models.ErrAlertRuleFailedValidation, rule.Data[j].RefID, rule.Title, err)
+			}
+		}
+		if rule.UID == "" {
+			rule.UID = existingByTitle[rule.Title]
+			continue
+		}
+		if _, ok := existingUIDs[rule.UID]; ok {
+			continue
+		}
+		// The rule can be in another group, in which case it is moved to this group.
+		rules, err := service.ruleStore.GetAlertRulesGroupByRuleUID(ctx, &models.GetAlertRulesGroupByRuleUIDQuery{OrgID: user.GetOrgID(), UID: rule.UID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query database for a group of alert rules: %w", err)
+		}
+		if len(rules) == 0 {
+			newUIDs[rule.Title] = rule.UID
+			rule.UID = ""
+		}
+	}
+
+	delta, err := service.calcDelta(ctx, user, group)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range delta.New {
+		if uid, ok := newUIDs[rule.Title]; ok {
+			rule.UID = uid
+		}
+	}
+	// The delta includes the unchanged rules of the group to keep their indexes, which does not need to be persisted.
+	if len(delta.New) == 0 && len(delta.Delete) == 0 && !slices.ContainsFunc(delta.Update, func(upd store.RuleDelta) bool { return len(upd.Diff) > 0 }) {
+		return delta, nil
+	}
+
+	cfg := setting.UnifiedAlertingSettings{
+		BaseInterval:   time.Duration(service.baseIntervalSeconds) * time.Second,
+		RecordingRules: service.recordingRules,
+	}
+	for _, rule := range delta.New {
+		if err := rule.ValidateAlertRule(cfg); err != nil {
+			return nil, fmt.Errorf("invalid rule '%s': %w", rule.Title, err)
+		}
+	}
+	for _, upd := range delta.Update {
+		if err := upd.New.ValidateAlertRule(cfg); err != nil {
+			return nil, fmt.Errorf("invalid rule '%s': %w", upd.New.Title, err)
+		}
+	}
+
+	if err := service.authorizeAndValidateDeltaWithPending(ctx, user, delta, opts.PendingReceivers, opts.PendingTimeIntervals); err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return delta, nil
+	}
+	return delta, service.persistDelta(ctx, user, delta, provenance)
+}
+
+// authorizeAndValidateDelta checks that the user can apply the changes and validates the notification settings of changed rules.
+func (service *AlertRuleService) authorizeAndValidateDelta(ctx context.Context, user identity.Requester, delta *store.GroupDelta) error {
+	return service.authorizeAndValidateDeltaWithPending(ctx, user, delta, nil, nil)
+}
+
+// authorizeAndValidateDeltaWithPending is like authorizeAndValidateDelta, but notification settings can also refer
+// to the given receivers and time intervals.
+func (service *AlertRuleService) authorizeAndValidateDeltaWithPending(ctx context.Context, user identity.Requester, delta *store.GroupDelta, receivers, timeIntervals []string) error {
 	// check if the current user has permissions to all rules and can bypass the regular authorization validation.
 	can, err := service.authz.CanWriteAllRules(ctx, user)
 	if err != nil {
@@ -395,14 +516,47 @@ func (service *AlertRuleService) ReplaceRuleGroup(ctx context.Context, user iden
 		if err != nil {
 			return err
 		}
+		if len(receivers) > 0 || len(timeIntervals) > 0 {
+			validator = pendingReferencesValidator{NotificationSettingsValidator: validator, receivers: receivers, timeIntervals: timeIntervals}
+		}
 		for _, s := range newOrUpdatedNotificationSettings {
 			if err := validator.Validate(s); err != nil {
 				return errors.Join(models.ErrAlertRuleFailedValidation, err)
 			}
 		}
 	}
+	return nil
+}
 
-	return service.persistDelta(ctx, user, delta, provenance)
+// pendingReferencesValidator accepts references to receivers and time intervals that do not exist yet.
+type pendingReferencesValidator struct {
+	notifier.NotificationSettingsValidator
+	receivers     []string
+	timeIntervals []string
+}
+
+func (v pendingReferencesValidator) Validate(s models.NotificationSettings) error {
+	err := v.NotificationSettingsValidator.Validate(s)
+	if err == nil {
+		return nil
+	}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	remaining := make([]error, 0, len(errs))
+	for _, e := range errs {
+		var receiverErr notifier.ErrorReceiverDoesNotExist
+		if errors.As(e, &receiverErr) && slices.Contains(v.receivers, receiverErr.Reference) {
+			continue
+		}
+		var intervalErr notifier.ErrorTimeIntervalDoesNotExist
+		if errors.As(e, &intervalErr) && slices.Contains(v.timeIntervals, intervalErr.Reference) {
+			continue
+		}
+		remaining = append(remaining, e)
+	}
+	return errors.Join(remaining...)
 }
 
 func (service *AlertRuleService) DeleteRuleGroup(ctx context.Context, user identity.Requester, namespaceUID, group string, provenance models.Provenance) error {
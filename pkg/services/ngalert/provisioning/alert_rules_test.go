@@ -27,6 +27,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/folder/foldertest"
 	"github.com/grafana/grafana/pkg/services/ngalert/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
 	"github.com/grafana/grafana/pkg/services/ngalert/store"
 	"github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
 	"github.com/grafana/grafana/pkg/services/ngalert/testutil"
@@ -1667,3 +1668,40 @@ func initService(t *testing.T) (*AlertRuleService, *fakes.RuleStore, *fakes.Fake
 
 	return service, ruleStore, provenanceStore, ac
 }
+
+type missingReferencesValidator struct{}
+
+func (missingReferencesValidator) Validate(s models.NotificationSettings) error {
+	errs := []error{notifier.ErrorReceiverDoesNotExist{ErrorReferenceInvalid: notifier.ErrorReferenceInvalid{Reference: s.Receiver}}}
+	for _, interval := range s.MuteTimeIntervals {
+		errs = append(errs, notifier.ErrorTimeIntervalDoesNotExist{ErrorReferenceInvalid: notifier.ErrorReferenceInvalid{Reference: interval}})
+	}
+	return errors.Join(errs...)
+}
+
+func TestPendingReferencesValidator(t *testing.T) {
+	validator := pendingReferencesValidator{
+		NotificationSettingsValidator: missingReferencesValidator{},
+		receivers:                     []string{"pending-receiver"},
+		timeIntervals:                 []string{"pending-interval"},
+	}
+
+	t.Run("accepts pending references", func(t *testing.T) {
+		require.NoError(t, validator.Validate(models.NotificationSettings{
+			Receiver:          "pending-receiver",
+			MuteTimeIntervals: []string{"pending-interval"},
+		}))
+	})
+
+	t.Run("rejects other references", func(t *testing.T) {
+		err := validator.Validate(models.NotificationSettings{
+			Receiver:          "pending-receiver",
+			MuteTimeIntervals: []string{"pending-interval", "other-interval"},
+		})
+		require.ErrorContains(t, err, "time interval other-interval does not exist")
+		require.NotContains(t, err.Error(), "pending")
+
+		err = validator.Validate(models.NotificationSettings{Receiver: "other-receiver"})
+		require.ErrorContains(t, err, "receiver other-receiver does not exist")
+	})
+}
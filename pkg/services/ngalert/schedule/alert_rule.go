@@ -15,6 +15,8 @@ import (
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/dashboards"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
@@ -22,6 +24,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
 	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	"github.com/grafana/grafana/pkg/services/ngalert/state/template"
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/util"
@@ -60,6 +63,7 @@ func newRuleFactory(
 	logger log.Logger,
 	tracer tracing.Tracer,
 	recordingWriter RecordingWriter,
+	templateQueries templateQueryLimits,
 	evalAppliedHook evalAppliedFunc,
 	stopAppliedHook stopAppliedFunc,
 ) ruleFactoryFunc {
@@ -92,6 +96,7 @@ func newRuleFactory(
 			met,
 			logger,
 			tracer,
+			templateQueries,
 			evalAppliedHook,
 			stopAppliedHook,
 		)
@@ -123,6 +128,8 @@ type alertRule struct {
 	evalFactory  eval.EvaluatorFactory
 	ruleProvider ruleProvider
 
+	templateQueries templateQueryLimits
+
 	// Event hooks that are only used in tests.
 	evalAppliedHook evalAppliedFunc
 	stopAppliedHook stopAppliedFunc
@@ -146,6 +153,7 @@ func newAlertRule(
 	met *metrics.Scheduler,
 	logger log.Logger,
 	tracer tracing.Tracer,
+	templateQueries templateQueryLimits,
 	evalAppliedHook func(ngmodels.AlertRuleKey, time.Time),
 	stopAppliedHook func(ngmodels.AlertRuleKey),
 ) *alertRule {
@@ -164,6 +172,7 @@ func newAlertRule(
 		stateManager:         stateManager,
 		evalFactory:          evalFactory,
 		ruleProvider:         ruleProvider,
+		templateQueries:      templateQueries,
 		evalAppliedHook:      evalAppliedHook,
 		stopAppliedHook:      stopAppliedHook,
 		metrics:              met,
@@ -261,6 +270,7 @@ func (a *alertRule) Run() error {
 				defer func() {
 					evalDuration.Observe(a.clock.Now().Sub(evalStart).Seconds())
 					a.evalApplied(ctx.scheduledAt)
+					ctx.done()
 				}()
 
 				for attempt := int64(1); attempt <= a.maxAttempts; attempt++ {
@@ -422,6 +432,9 @@ func (a *alertRule) evaluate(ctx context.Context, f fingerprint, attempt int64,
 		))
 	}
 	start = a.clock.Now()
+	if a.templateQueries.maxQueries > 0 {
+		ctx = template.WithQueryFunc(ctx, a.newTemplateQueryFunc(e.rule))
+	}
 	_ = a.stateManager.ProcessEvalResults(
 		ctx,
 		e.scheduledAt,
@@ -503,6 +516,13 @@ func SchedulerUserFor(orgID int64) *user.SignedInUser {
 				datasources.ActionQuery: []string{
 					datasources.ScopeAll,
 				},
+				// rules can read the metrics that recording rules of any folder write to the Grafana database.
+				accesscontrol.ActionAlertingRuleRead: []string{
+					dashboards.ScopeFoldersAll,
+				},
+				dashboards.ActionFoldersRead: []string{
+					dashboards.ScopeFoldersAll,
+				},
 			},
 		},
 	}
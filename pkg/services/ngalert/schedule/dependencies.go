@@ -0,0 +1,148 @@
+package schedule
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// updateDependencies computes the dependencies between the rules. Rules can only depend on Grafana-managed
+// recording rules, so the graph is empty if they are disabled.
+func (sch *schedule) updateDependencies(rules []*ngmodels.AlertRule) {
+	if sch.featureToggles == nil || !sch.featureToggles.IsEnabledGlobally(featuremgmt.FlagGrafanaManagedRecordingRules) {
+		sch.dependencies = ngmodels.NewRuleDependencyGraph(nil, nil)
+		sch.metrics.RuleDependencyCycles.Set(0)
+		return
+	}
+	sch.dependencies = ngmodels.NewRuleDependencyGraph(rules, sch.recordDatasources)
+	cycles := sch.dependencies.Cycles()
+	sch.metrics.RuleDependencyCycles.Set(float64(len(cycles)))
+	for _, cycle := range cycles {
+		uids := make([]string, 0, len(cycle))
+		for _, key := range cycle {
+			uids = append(uids, key.UID)
+		}
+		sch.log.Warn("Rules depend on each other and will be evaluated independently", "org_id", cycle[0].OrgID, "rules", uids)
+	}
+}
+
+// jitterSource returns the rule whose jitter is used to schedule the rule. A rule that reads the result of
+// a recording rule with the same interval uses the jitter of the recording rule, so that both are ready in
+// the same tick and the rule does not lag behind by a full interval.
+func (sch *schedule) jitterSource(rule *ngmodels.AlertRule) *ngmodels.AlertRule {
+	source := rule
+	for {
+		var next *ngmodels.AlertRule
+		for _, key := range sch.dependencies.Dependencies(source.GetKey()) {
+			dep, ok := sch.dependencies.Rule(key)
+			if ok && sch.effectiveInterval(dep) == rule.IntervalSeconds {
+				next = dep
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		source = next
+	}
+	if source == rule {
+		return rule
+	}
+	// the jitter only depends on the group and the UID of the rule, the interval must be the one of the scheduled rule.
+	jitterRule := *source
+	jitterRule.IntervalSeconds = rule.IntervalSeconds
+	return &jitterRule
+}
+
+// effectiveInterval returns the interval of the rule after the minimum interval is enforced.
+func (sch *schedule) effectiveInterval(rule *ngmodels.AlertRule) int64 {
+	return max(rule.IntervalSeconds, int64(sch.minRuleInterval.Seconds()))
+}
+
+// evaluateInOrder dispatches the evaluations of the tick. A rule that reads the result of recording rules
+// that are evaluated in the same tick is evaluated as soon as all of them are finished. All other rules
+// are spread evenly over the tick.
+func (sch *schedule) evaluateInOrder(readyToRun []readyToRunItem, tick time.Time) {
+	index := make(map[ngmodels.AlertRuleKey]int, len(readyToRun))
+	for i := range readyToRun {
+		index[readyToRun[i].rule.GetKey()] = i
+	}
+
+	callbacks := make(map[int][]func())
+	independent := make([]int, 0, len(readyToRun))
+	for i := range readyToRun {
+		var deps []int
+		for _, key := range sch.dependencies.Dependencies(readyToRun[i].rule.GetKey()) {
+			if j, ok := index[key]; ok {
+				deps = append(deps, j)
+			}
+		}
+		if len(deps) == 0 {
+			independent = append(independent, i)
+			continue
+		}
+
+		item := i
+		remaining := atomic.NewInt32(int32(len(deps)))
+		var once sync.Once
+		release := func() {
+			once.Do(func() {
+				sch.evaluate(readyToRun[item], tick)
+			})
+		}
+		// Do not wait for longer than the interval of the rule in case a dependency is never evaluated,
+		// for example because it was deleted. The next evaluation of the rule is due then anyway.
+		time.AfterFunc(time.Duration(readyToRun[i].rule.IntervalSeconds)*time.Second, release)
+		for _, j := range deps {
+			callbacks[j] = append(callbacks[j], func() {
+				if remaining.Dec() == 0 {
+					release()
+				}
+			})
+		}
+	}
+	for j, fns := range callbacks {
+		var once sync.Once
+		readyToRun[j].afterEval = func() {
+			once.Do(func() {
+				for _, fn := range fns {
+					fn()
+				}
+			})
+		}
+	}
+
+	var step int64 = 0
+	if len(independent) > 0 {
+		step = sch.baseInterval.Nanoseconds() / int64(len(independent))
+	}
+	for i, idx := range independent {
+		item := readyToRun[idx]
+		time.AfterFunc(time.Duration(int64(i)*step), func() {
+			sch.evaluate(item, tick)
+		})
+	}
+}
+
+// evaluate sends the evaluation to the rule routine.
+func (sch *schedule) evaluate(item readyToRunItem, tick time.Time) {
+	key := item.rule.GetKey()
+	success, dropped := item.ruleRoutine.Eval(&item.Evaluation)
+	// the dropped evaluation is from the previous tick, its dependents must not wait for it anymore.
+	dropped.done()
+	if !success {
+		sch.log.Debug("Scheduled evaluation was canceled because evaluation routine was stopped", append(key.LogContext(), "time", tick)...)
+		item.Evaluation.done()
+		return
+	}
+	if dropped != nil {
+		sch.log.Warn("Tick dropped because alert rule evaluation is too slow", append(key.LogContext(), "time", tick)...)
+		orgID := fmt.Sprint(key.OrgID)
+		sch.metrics.EvaluationMissed.WithLabelValues(orgID, item.rule.Title).Inc()
+	}
+}
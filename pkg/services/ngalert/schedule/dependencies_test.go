@@ -0,0 +1,102 @@
+package schedule
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+type fakeEvalRule struct {
+	evals chan *Evaluation
+}
+
+func (r *fakeEvalRule) Run() error { return nil }
+
+func (r *fakeEvalRule) Stop(error) {}
+
+func (r *fakeEvalRule) Eval(eval *Evaluation) (bool, *Evaluation) {
+	r.evals <- eval
+	return true, nil
+}
+
+func (r *fakeEvalRule) Update(RuleVersionAndPauseStatus) bool { return true }
+
+func TestEvaluateInOrder(t *testing.T) {
+	query := func(expr string) []ngmodels.AlertQuery {
+		return []ngmodels.AlertQuery{{RefID: "A", DatasourceUID: "prometheus", Model: json.RawMessage(`{"expr":"` + expr + `"}`)}}
+	}
+	gen := ngmodels.RuleGen.With(ngmodels.RuleMuts.WithOrgID(1), ngmodels.RuleMuts.WithIntervalSeconds(10))
+	recording := gen.With(ngmodels.RuleMuts.WithAllRecordingRules(), ngmodels.RuleMuts.WithMetric("job:up:sum"), ngmodels.RuleMuts.WithQuery(query("sum(up) by (job)")...)).GenerateRef()
+	alert := gen.With(ngmodels.RuleMuts.WithQuery(query("job:up:sum < 1")...)).GenerateRef()
+	alert.Record = nil
+	independent := gen.With(ngmodels.RuleMuts.WithQuery(query("up < 1")...)).GenerateRef()
+	independent.Record = nil
+
+	sch := setupScheduler(t, nil, nil, nil, nil, nil)
+	sch.updateDependencies([]*ngmodels.AlertRule{recording, alert, independent})
+	require.Equal(t, []ngmodels.AlertRuleKey{recording.GetKey()}, sch.dependencies.Dependencies(alert.GetKey()))
+
+	evals := make(chan *Evaluation, 3)
+	routine := &fakeEvalRule{evals: evals}
+	tick := time.Now()
+	items := []readyToRunItem{
+		{ruleRoutine: routine, Evaluation: Evaluation{scheduledAt: tick, rule: alert}},
+		{ruleRoutine: routine, Evaluation: Evaluation{scheduledAt: tick, rule: independent}},
+		{ruleRoutine: routine, Evaluation: Evaluation{scheduledAt: tick, rule: recording}},
+	}
+	sch.evaluateInOrder(items, tick)
+
+	received := map[string]*Evaluation{}
+	for i := 0; i < 2; i++ {
+		select {
+		case ev := <-evals:
+			received[ev.rule.UID] = ev
+		case <-time.After(5 * time.Second):
+			t.Fatal("rules without dependencies were not evaluated")
+		}
+	}
+	require.Contains(t, received, recording.UID)
+	require.Contains(t, received, independent.UID)
+
+	select {
+	case ev := <-evals:
+		t.Fatalf("rule %s was evaluated before the recording rule finished", ev.rule.UID)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	received[recording.UID].done()
+	select {
+	case ev := <-evals:
+		assert.Equal(t, alert.UID, ev.rule.UID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("dependent rule was not evaluated after the recording rule finished")
+	}
+}
+
+func TestJitterSource(t *testing.T) {
+	query := func(expr string) []ngmodels.AlertQuery {
+		return []ngmodels.AlertQuery{{RefID: "A", DatasourceUID: "prometheus", Model: json.RawMessage(`{"expr":"` + expr + `"}`)}}
+	}
+	gen := ngmodels.RuleGen.With(ngmodels.RuleMuts.WithOrgID(1), ngmodels.RuleMuts.WithGroupPrefix("group-"))
+	recording := gen.With(ngmodels.RuleMuts.WithIntervalSeconds(10), ngmodels.RuleMuts.WithAllRecordingRules(), ngmodels.RuleMuts.WithMetric("job:up:sum")).GenerateRef()
+	sameInterval := gen.With(ngmodels.RuleMuts.WithIntervalSeconds(10), ngmodels.RuleMuts.WithQuery(query("job:up:sum < 1")...)).GenerateRef()
+	sameInterval.Record = nil
+	otherInterval := gen.With(ngmodels.RuleMuts.WithIntervalSeconds(60), ngmodels.RuleMuts.WithQuery(query("job:up:sum < 1")...)).GenerateRef()
+	otherInterval.Record = nil
+
+	sch := setupScheduler(t, nil, nil, nil, nil, nil)
+	sch.updateDependencies([]*ngmodels.AlertRule{recording, sameInterval, otherInterval})
+
+	for _, strategy := range []JitterStrategy{JitterByGroup, JitterByRule} {
+		assert.Equal(t,
+			jitterOffsetInTicks(recording, sch.baseInterval, strategy),
+			jitterOffsetInTicks(sch.jitterSource(sameInterval), sch.baseInterval, strategy),
+		)
+	}
+	assert.Same(t, otherInterval, sch.jitterSource(otherInterval))
+}
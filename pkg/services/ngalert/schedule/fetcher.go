@@ -36,6 +36,7 @@ func (sch *schedule) updateSchedulableAlertRules(ctx context.Context) (diff, err
 		return diff{}, fmt.Errorf("failed to get alert rules: %w", err)
 	}
 	d := sch.schedulableAlertRules.set(q.ResultRules, q.ResultFoldersTitles)
+	sch.updateDependencies(q.ResultRules)
 	sch.log.Debug("Alert rules fetched", "rulesCount", len(q.ResultRules), "foldersCount", len(q.ResultFoldersTitles), "updatedRules", len(d.updated))
 	return d, nil
 }
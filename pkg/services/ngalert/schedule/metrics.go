@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"hash/fnv"
 	"sort"
+	"time"
 
 	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
 	models "github.com/grafana/grafana/pkg/services/ngalert/models"
@@ -73,3 +74,28 @@ func (sch *schedule) updateRulesMetrics(alertRules []*models.AlertRule) {
 	sch.metrics.SchedulableAlertRules.Set(float64(len(alertRules)))
 	sch.metrics.SchedulableAlertRulesHash.Set(float64(hashUIDs(alertRules)))
 }
+
+// recordTargetResolver is implemented by recording writers that write to different targets.
+type recordTargetResolver interface {
+	Resolve(target models.RecordTarget) models.RecordTarget
+}
+
+// recordTargetLabel returns the name of the target the writer writes to for a rule that selects the given target.
+func recordTargetLabel(w RecordingWriter, target models.RecordTarget) string {
+	if r, ok := w.(recordTargetResolver); ok {
+		target = r.Resolve(target)
+	}
+	if target == "" {
+		return "default"
+	}
+	return string(target)
+}
+
+// observeRecordingWrite records the latency and failures of writing the result of a recording rule to its target.
+func observeRecordingWrite(m *metrics.Scheduler, orgID int64, target string, duration time.Duration, err error) {
+	org := fmt.Sprint(orgID)
+	m.RecordingWriteDuration.WithLabelValues(org, target).Observe(duration.Seconds())
+	if err != nil {
+		m.RecordingWriteFailures.WithLabelValues(org, target).Inc()
+	}
+}
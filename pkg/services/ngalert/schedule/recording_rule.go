@@ -126,6 +126,7 @@ func (r *recordingRule) Run() error {
 			}
 			if !r.featureToggles.IsEnabled(ctx, featuremgmt.FlagGrafanaManagedRecordingRules) {
 				logger.Warn("Recording rule scheduled but toggle is not enabled. Skipping")
+				eval.done()
 				return nil
 			}
 			// TODO: Skipping the "evalRunning" guard that the alert rule routine does, because it seems to be dead code and impossible to hit.
@@ -158,6 +159,7 @@ func (r *recordingRule) doEvaluate(ctx context.Context, ev *Evaluation) {
 		r.evaluationDuration.Store(dur)
 
 		r.evaluationDoneTestHook(ev)
+		ev.done()
 	}()
 
 	if ev.rule.IsPaused {
@@ -256,17 +258,19 @@ func (r *recordingRule) tryEvaluation(ctx context.Context, ev *Evaluation, logge
 		return nil
 	}
 
+	target := recordTargetLabel(r.writer, ev.rule.Record.Target)
 	writeStart := r.clock.Now()
-	err = r.writer.Write(ctx, ev.rule.Record.Metric, ev.scheduledAt, frames, ev.rule.Labels)
+	err = r.writer.Write(ngmodels.WithRecordTarget(ctx, ev.rule.Record.Target), ev.rule.Record.Metric, ev.scheduledAt, frames, ev.rule.Labels)
 	writeDur := r.clock.Now().Sub(writeStart)
+	observeRecordingWrite(r.metrics, ev.rule.OrgID, target, writeDur, err)
 
 	if err != nil {
 		span.SetStatus(codes.Error, "failed to write metrics")
 		span.RecordError(err)
-		return fmt.Errorf("remote write failed: %w", err)
+		return fmt.Errorf("write to %s target failed: %w", target, err)
 	}
 
-	logger.Debug("Metrics written", "duration", writeDur)
+	logger.Debug("Metrics written", "duration", writeDur, "target", target)
 	span.AddEvent("metrics written", trace.WithAttributes(
 		attribute.Int64("frames", int64(len(frames))),
 	))
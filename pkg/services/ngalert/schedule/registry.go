@@ -85,6 +85,16 @@ type Evaluation struct {
 	scheduledAt time.Time
 	rule        *models.AlertRule
 	folderTitle string
+	// afterEval is called once the evaluation is finished or will not happen. The scheduler uses it to
+	// evaluate the rules that depend on the result of this evaluation in the same tick.
+	afterEval func()
+}
+
+// done notifies the scheduler that the evaluation is finished or will not happen.
+func (e *Evaluation) done() {
+	if e != nil && e.afterEval != nil {
+		e.afterEval()
+	}
 }
 
 func (e *Evaluation) Fingerprint() fingerprint {
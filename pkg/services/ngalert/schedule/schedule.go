@@ -2,7 +2,6 @@ package schedule
 
 import (
 	"context"
-	"fmt"
 	"net/url"
 	"slices"
 	"strings"
@@ -100,6 +99,16 @@ type schedule struct {
 	tracer tracing.Tracer
 
 	recordingWriter RecordingWriter
+	// recordDatasources are the data sources that query the metrics written by recording rules.
+	recordDatasources ngmodels.RecordTargetDatasources
+
+	templateQueries templateQueryLimits
+
+	// dependencies between the schedulable alert rules, they are updated together with schedulableAlertRules.
+	dependencies *ngmodels.RuleDependencyGraph
+
+	// sharding decides which rules this instance evaluates. It is nil if every instance evaluates all rules.
+	sharding *ruleSharding
 }
 
 // SchedulerCfg is the scheduler configuration.
@@ -119,6 +128,14 @@ type SchedulerCfg struct {
 	Tracer               tracing.Tracer
 	Log                  log.Logger
 	RecordingWriter      RecordingWriter
+	// TemplateQueryTimeout is the timeout of the queries of the query template function.
+	TemplateQueryTimeout time.Duration
+	// TemplateMaxQueries is the maximum number of distinct queries the templates of a rule run per evaluation.
+	TemplateMaxQueries int
+	// ClusterMembership, if set, shards the evaluation of rules across the members of the cluster.
+	ClusterMembership ClusterMembership
+	// RecordTargetDatasources are the data sources that query the metrics written by recording rules.
+	RecordTargetDatasources ngmodels.RecordTargetDatasources
 }
 
 // NewScheduler returns a new scheduler.
@@ -148,6 +165,12 @@ func NewScheduler(cfg SchedulerCfg, stateManager *state.Manager) *schedule {
 		alertsSender:          cfg.AlertSender,
 		tracer:                cfg.Tracer,
 		recordingWriter:       cfg.RecordingWriter,
+		templateQueries:       templateQueryLimits{timeout: cfg.TemplateQueryTimeout, maxQueries: cfg.TemplateMaxQueries},
+		dependencies:          ngmodels.NewRuleDependencyGraph(nil, nil),
+		recordDatasources:     cfg.RecordTargetDatasources,
+	}
+	if cfg.ClusterMembership != nil {
+		sch.sharding = newRuleSharding(cfg.ClusterMembership, cfg.Log, cfg.Metrics)
 	}
 
 	return &sch
@@ -265,10 +288,24 @@ func (sch *schedule) processTick(ctx context.Context, dispatcherGroup *errgroup.
 		sch.log,
 		sch.tracer,
 		sch.recordingWriter,
+		sch.templateQueries,
 		sch.evalAppliedFunc,
 		sch.stopAppliedFunc,
 	)
-	for _, item := range alertRules {
+	ownedRules := sch.shardRules(ctx, tickNum, alertRules)
+	if len(ownedRules) != len(alertRules) {
+		// rules evaluated by other instances of the cluster are not deleted
+		owned := make(map[ngmodels.AlertRuleKey]struct{}, len(ownedRules))
+		for _, item := range ownedRules {
+			owned[item.GetKey()] = struct{}{}
+		}
+		for _, item := range alertRules {
+			if _, ok := owned[item.GetKey()]; !ok {
+				delete(registeredDefinitions, item.GetKey())
+			}
+		}
+	}
+	for _, item := range ownedRules {
 		ruleRoutine, newRoutine := sch.registry.getOrCreate(ctx, item, ruleFactory)
 		key := item.GetKey()
 		logger := sch.log.FromContext(ctx).New(key.LogContext()...)
@@ -295,7 +332,7 @@ func (sch *schedule) processTick(ctx context.Context, dispatcherGroup *errgroup.
 		}
 
 		itemFrequency := item.IntervalSeconds / int64(sch.baseInterval.Seconds())
-		offset := jitterOffsetInTicks(item, sch.baseInterval, sch.jitterEvaluations)
+		offset := jitterOffsetInTicks(sch.jitterSource(item), sch.baseInterval, sch.jitterEvaluations)
 		isReadyToRun := item.IntervalSeconds != 0 && (tickNum%itemFrequency)-offset == 0
 
 		var folderTitle string
@@ -339,31 +376,10 @@ func (sch *schedule) processTick(ctx context.Context, dispatcherGroup *errgroup.
 		sch.log.Warn("Unable to obtain folder titles for some rules", "missingFolderUIDToRuleUID", missingFolder)
 	}
 
-	var step int64 = 0
-	if len(readyToRun) > 0 {
-		step = sch.baseInterval.Nanoseconds() / int64(len(readyToRun))
-	}
-
 	slices.SortFunc(readyToRun, func(a, b readyToRunItem) int {
 		return strings.Compare(a.rule.UID, b.rule.UID)
 	})
-	for i := range readyToRun {
-		item := readyToRun[i]
-
-		time.AfterFunc(time.Duration(int64(i)*step), func() {
-			key := item.rule.GetKey()
-			success, dropped := item.ruleRoutine.Eval(&item.Evaluation)
-			if !success {
-				sch.log.Debug("Scheduled evaluation was canceled because evaluation routine was stopped", append(key.LogContext(), "time", tick)...)
-				return
-			}
-			if dropped != nil {
-				sch.log.Warn("Tick dropped because alert rule evaluation is too slow", append(key.LogContext(), "time", tick)...)
-				orgID := fmt.Sprint(key.OrgID)
-				sch.metrics.EvaluationMissed.WithLabelValues(orgID, item.rule.Title).Inc()
-			}
-		})
-	}
+	sch.evaluateInOrder(readyToRun, tick)
 
 	// unregister and stop routines of the deleted alert rules
 	toDelete := make([]ngmodels.AlertRuleKey, 0, len(registeredDefinitions))
@@ -0,0 +1,181 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// errRuleReassigned is the reason a rule routine is stopped when the rule is now evaluated by another instance of the cluster.
+var errRuleReassigned = errors.New("rule is evaluated by another instance")
+
+// ringTokensPerMember is the number of points each member has on the hash ring. More points spread the rules more evenly.
+const ringTokensPerMember = 128
+
+// ClusterMembership provides the members of the cluster of Grafana instances that share the evaluation of alert rules.
+type ClusterMembership interface {
+	// Members returns the names of the healthy members of the cluster, including this instance.
+	Members() []string
+	// Self returns the name of this instance.
+	Self() string
+}
+
+// hashRing assigns keys to members using consistent hashing,
+// so that only the keys of a member that joins or leaves are assigned to a different member.
+type hashRing struct {
+	tokens []uint64
+	owners map[uint64]string
+}
+
+func newHashRing(members []string) *hashRing {
+	r := &hashRing{
+		tokens: make([]uint64, 0, len(members)*ringTokensPerMember),
+		owners: make(map[uint64]string, len(members)*ringTokensPerMember),
+	}
+	for _, m := range members {
+		for i := 0; i < ringTokensPerMember; i++ {
+			token := xxhash.Sum64String(fmt.Sprintf("%s-%d", m, i))
+			if owner, ok := r.owners[token]; ok {
+				// In the unlikely case of a collision, the member that sorts first keeps the token so all instances agree.
+				if m < owner {
+					r.owners[token] = m
+				}
+				continue
+			}
+			r.tokens = append(r.tokens, token)
+			r.owners[token] = m
+		}
+	}
+	slices.Sort(r.tokens)
+	return r
+}
+
+// owner returns the member the key is assigned to, that is the owner of the first token after the hash of the key.
+func (r *hashRing) owner(key string) string {
+	if len(r.tokens) == 0 {
+		return ""
+	}
+	h := xxhash.Sum64String(key)
+	i := sort.Search(len(r.tokens), func(i int) bool { return r.tokens[i] >= h })
+	if i == len(r.tokens) {
+		i = 0
+	}
+	return r.owners[r.tokens[i]]
+}
+
+// ruleSharding decides which rules are evaluated by this instance when the evaluation is sharded across the cluster.
+type ruleSharding struct {
+	membership ClusterMembership
+	self       string
+	members    []string
+	ring       *hashRing
+	// released contains the rules that are evaluated by other instances and whose state is not cached by this instance.
+	released map[ngmodels.AlertRuleKey]struct{}
+	log      log.Logger
+	metrics  *metrics.Scheduler
+}
+
+func newRuleSharding(membership ClusterMembership, logger log.Logger, m *metrics.Scheduler) *ruleSharding {
+	return &ruleSharding{
+		membership: membership,
+		released:   make(map[ngmodels.AlertRuleKey]struct{}),
+		log:        logger,
+		metrics:    m,
+	}
+}
+
+// update rebuilds the ring if the members of the cluster changed since the last call. It returns true if the ring changed.
+// This instance is always a member, so it keeps evaluating rules while the membership is not known yet.
+func (s *ruleSharding) update() bool {
+	self := s.membership.Self()
+	members := slices.Clone(s.membership.Members())
+	if !slices.Contains(members, self) {
+		members = append(members, self)
+	}
+	slices.Sort(members)
+	members = slices.Compact(members)
+	if s.ring != nil && self == s.self && slices.Equal(members, s.members) {
+		return false
+	}
+
+	s.log.Info("Cluster membership changed, rebalancing alert rules", "self", self, "members", members, "previousMembers", s.members)
+	s.self = self
+	s.members = members
+	s.ring = newHashRing(members)
+	s.metrics.ShardingMembers.Set(float64(len(members)))
+	return true
+}
+
+// owns returns true if the rule is evaluated by this instance.
+func (s *ruleSharding) owns(key ngmodels.AlertRuleKey) bool {
+	return s.ring.owner(key.String()) == s.self
+}
+
+// shardRules returns the rules that are evaluated by this instance. When the evaluation is not sharded, it returns all rules.
+// Rules that moved to another instance are stopped but their state is kept in the cache, and it is refreshed from
+// the database on the ticks the rules are evaluated, so the APIs of this instance keep returning it. The instance
+// that evaluates them next continues from the state in the database. Rules that moved to this instance load
+// their state from the database before they are evaluated.
+func (sch *schedule) shardRules(ctx context.Context, tickNum int64, rules []*ngmodels.AlertRule) []*ngmodels.AlertRule {
+	if sch.sharding == nil {
+		return rules
+	}
+	sch.sharding.update()
+
+	owned := make([]*ngmodels.AlertRule, 0, len(rules))
+	known := make(map[ngmodels.AlertRuleKey]struct{}, len(rules))
+	var refresh []*ngmodels.AlertRule
+	for _, rule := range rules {
+		key := rule.GetKey()
+		known[key] = struct{}{}
+		_, released := sch.sharding.released[key]
+		if sch.sharding.owns(key) {
+			if released {
+				delete(sch.sharding.released, key)
+				sch.log.FromContext(ctx).Debug("Alert rule moved to this instance, loading its state", key.LogContext()...)
+				sch.stateManager.WarmRule(ctx, rule)
+			}
+			owned = append(owned, rule)
+			continue
+		}
+		if released {
+			if sch.isDue(rule, tickNum) {
+				refresh = append(refresh, rule)
+			}
+			continue
+		}
+		sch.sharding.released[key] = struct{}{}
+		if routine, ok := sch.registry.del(key); ok {
+			sch.log.FromContext(ctx).Debug("Alert rule moved to another instance, stopping its evaluation", key.LogContext()...)
+			routine.Stop(errRuleReassigned)
+		}
+	}
+	sch.stateManager.RefreshRules(ctx, refresh)
+	// Forget the rules that were deleted while other instances evaluated them.
+	for key := range sch.sharding.released {
+		if _, ok := known[key]; !ok {
+			delete(sch.sharding.released, key)
+			sch.stateManager.ForgetRule(key)
+		}
+	}
+	sch.metrics.ShardingOwnedRules.Set(float64(len(owned)))
+	return owned
+}
+
+// isDue returns true if the rule is evaluated on the tick, not taking the jitter into account.
+func (sch *schedule) isDue(rule *ngmodels.AlertRule, tickNum int64) bool {
+	base := int64(sch.baseInterval.Seconds())
+	if base <= 0 {
+		return false
+	}
+	frequency := rule.IntervalSeconds / base
+	return frequency > 0 && tickNum%frequency == 0
+}
@@ -0,0 +1,239 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
+
+type fakeClusterMembership struct {
+	mtx     sync.Mutex
+	self    string
+	members []string
+}
+
+func (m *fakeClusterMembership) Members() []string {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.members
+}
+
+func (m *fakeClusterMembership) Self() string {
+	return m.self
+}
+
+func (m *fakeClusterMembership) setMembers(members ...string) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.members = members
+}
+
+func TestHashRing(t *testing.T) {
+	keys := make([]string, 0, 3000)
+	for i := 0; i < cap(keys); i++ {
+		keys = append(keys, fmt.Sprintf("rule-%d", i))
+	}
+
+	t.Run("should assign every key to a member", func(t *testing.T) {
+		ring := newHashRing([]string{"a", "b", "c"})
+		counts := map[string]int{}
+		for _, k := range keys {
+			counts[ring.owner(k)]++
+		}
+		require.Len(t, counts, 3)
+		for m, c := range counts {
+			assert.Greaterf(t, c, len(keys)/5, "member %s owns too few keys", m)
+		}
+	})
+
+	t.Run("should move keys only to the member that joins", func(t *testing.T) {
+		before := newHashRing([]string{"a", "b", "c"})
+		after := newHashRing([]string{"a", "b", "c", "d"})
+		moved := 0
+		for _, k := range keys {
+			if before.owner(k) != after.owner(k) {
+				require.Equal(t, "d", after.owner(k))
+				moved++
+			}
+		}
+		assert.Positive(t, moved)
+	})
+
+	t.Run("should not depend on the order of members", func(t *testing.T) {
+		r1 := newHashRing([]string{"a", "b", "c"})
+		r2 := newHashRing([]string{"c", "a", "b"})
+		for _, k := range keys {
+			require.Equal(t, r1.owner(k), r2.owner(k))
+		}
+	})
+
+	t.Run("should return empty owner if there are no members", func(t *testing.T) {
+		require.Empty(t, newHashRing(nil).owner("rule"))
+	})
+}
+
+func TestShardRules(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*schedule, *fakeClusterMembership, *state.FakeInstanceStore) {
+		is := &state.FakeInstanceStore{}
+		sch := setupScheduler(t, nil, is, nil, nil, nil)
+		membership := &fakeClusterMembership{self: "a"}
+		sch.sharding = newRuleSharding(membership, log.NewNopLogger(), sch.metrics)
+		return sch, membership, is
+	}
+
+	t.Run("should return all rules when sharding is disabled", func(t *testing.T) {
+		sch := setupScheduler(t, nil, nil, nil, nil, nil)
+		rules := models.RuleGen.GenerateManyRef(10)
+		require.Equal(t, rules, sch.shardRules(ctx, 1, rules))
+	})
+
+	t.Run("should evaluate all rules while it is the only member", func(t *testing.T) {
+		sch, _, _ := setup(t)
+		rules := models.RuleGen.GenerateManyRef(10)
+		require.Equal(t, rules, sch.shardRules(ctx, 1, rules))
+	})
+
+	t.Run("should stop rules that moved to another member and load rules that moved back", func(t *testing.T) {
+		sch, membership, is := setup(t)
+		// the rules are evaluated on even ticks.
+		rules := models.RuleGen.With(models.RuleGen.WithIntervalSeconds(2)).GenerateManyRef(50)
+		factory := ruleFactoryFromScheduler(sch)
+		routines := make(map[models.AlertRuleKey]Rule, len(rules))
+		for _, rule := range rules {
+			routine, _ := sch.registry.getOrCreate(ctx, rule, factory)
+			routines[rule.GetKey()] = routine
+			sch.stateManager.Put([]*state.State{{
+				OrgID:        rule.OrgID,
+				AlertRuleUID: rule.UID,
+				CacheID:      data.Labels{"rule": rule.UID}.Fingerprint(),
+				Labels:       data.Labels{"rule": rule.UID},
+				State:        eval.Alerting,
+			}})
+		}
+
+		membership.setMembers("a", "b")
+		owned := sch.shardRules(ctx, 1, rules)
+		require.NotEmpty(t, owned)
+		require.Less(t, len(owned), len(rules))
+
+		ownedKeys := map[models.AlertRuleKey]struct{}{}
+		for _, rule := range owned {
+			ownedKeys[rule.GetKey()] = struct{}{}
+		}
+		var moved []*models.AlertRule
+		for _, rule := range rules {
+			key := rule.GetKey()
+			if _, ok := ownedKeys[key]; ok {
+				assert.True(t, sch.registry.exists(key))
+				assert.NoError(t, routines[key].(*alertRule).ctx.Err())
+				assert.Len(t, sch.stateManager.GetStatesForRuleUID(rule.OrgID, rule.UID), 1)
+				continue
+			}
+			moved = append(moved, rule)
+			assert.False(t, sch.registry.exists(key))
+			assert.ErrorIs(t, routines[key].(*alertRule).ctx.Err(), errRuleReassigned)
+			// The state can still be read from this instance.
+			assert.Len(t, sch.stateManager.GetStatesForRuleUID(rule.OrgID, rule.UID), 1)
+		}
+		// The state is not deleted, the instance that evaluates the rule next continues from it.
+		require.Empty(t, is.RecordedOps())
+
+		// Nothing changes while the membership is the same and the rules are not evaluated.
+		require.Equal(t, owned, sch.shardRules(ctx, 1, rules))
+		require.Empty(t, is.RecordedOps())
+
+		membership.setMembers("a")
+		require.Equal(t, rules, sch.shardRules(ctx, 1, rules))
+		var queries []models.ListAlertInstancesQuery
+		for _, op := range is.RecordedOps() {
+			if q, ok := op.(models.ListAlertInstancesQuery); ok {
+				queries = append(queries, q)
+			}
+		}
+		require.Len(t, queries, len(moved))
+		for i, rule := range moved {
+			assert.Equal(t, models.ListAlertInstancesQuery{RuleOrgID: rule.OrgID, RuleUID: rule.UID}, queries[i])
+		}
+		require.Empty(t, sch.sharding.released)
+	})
+
+	t.Run("should refresh the state of released rules from the database when they are evaluated", func(t *testing.T) {
+		sch, membership, is := setup(t)
+		orgs := []int64{1, 2}
+		var rules []*models.AlertRule
+		for _, orgID := range orgs {
+			rules = append(rules, models.RuleGen.With(models.RuleGen.WithOrgID(orgID), models.RuleGen.WithIntervalSeconds(2)).GenerateManyRef(25)...)
+		}
+		for _, rule := range rules {
+			sch.stateManager.Put([]*state.State{{
+				OrgID:        rule.OrgID,
+				AlertRuleUID: rule.UID,
+				CacheID:      data.Labels{"rule": rule.UID}.Fingerprint(),
+				Labels:       data.Labels{"rule": rule.UID},
+				State:        eval.Alerting,
+			}})
+		}
+		membership.setMembers("a", "b")
+		owned := sch.shardRules(ctx, 1, rules)
+		ownedKeys := map[models.AlertRuleKey]struct{}{}
+		for _, rule := range owned {
+			ownedKeys[rule.GetKey()] = struct{}{}
+		}
+
+		sch.shardRules(ctx, 2, rules)
+		var queries []models.ListAlertInstancesQuery
+		for _, op := range is.RecordedOps() {
+			if q, ok := op.(models.ListAlertInstancesQuery); ok {
+				queries = append(queries, q)
+			}
+		}
+		// the state of the rules of an organization is read at once.
+		require.ElementsMatch(t, []models.ListAlertInstancesQuery{{RuleOrgID: 1}, {RuleOrgID: 2}}, queries)
+		for _, rule := range rules {
+			if _, ok := ownedKeys[rule.GetKey()]; ok {
+				assert.Len(t, sch.stateManager.GetStatesForRuleUID(rule.OrgID, rule.UID), 1)
+				continue
+			}
+			// the database has no state of the rule, the other instance resolved its alerts.
+			assert.Empty(t, sch.stateManager.GetStatesForRuleUID(rule.OrgID, rule.UID))
+		}
+	})
+
+	t.Run("should forget released rules that were deleted", func(t *testing.T) {
+		sch, membership, _ := setup(t)
+		membership.setMembers("a", "b")
+		rules := models.RuleGen.GenerateManyRef(50)
+		for _, rule := range rules {
+			sch.stateManager.Put([]*state.State{{
+				OrgID:        rule.OrgID,
+				AlertRuleUID: rule.UID,
+				CacheID:      data.Labels{"rule": rule.UID}.Fingerprint(),
+				Labels:       data.Labels{"rule": rule.UID},
+				State:        eval.Alerting,
+			}})
+		}
+		owned := sch.shardRules(ctx, 1, rules)
+		require.Len(t, sch.sharding.released, len(rules)-len(owned))
+
+		sch.shardRules(ctx, 1, owned)
+		require.Empty(t, sch.sharding.released)
+		for _, rule := range rules {
+			if !slices.Contains(owned, rule) {
+				assert.Empty(t, sch.stateManager.GetStatesForRuleUID(rule.OrgID, rule.UID))
+			}
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state/template"
+)
+
+const (
+	templateQueryRefID = "A"
+	// templateQueryRange is the time range of the queries of templates. Instant queries only use its end,
+	// the evaluation time, but some data sources require a range.
+	templateQueryRange = 10 * time.Minute
+)
+
+// templateQueryLimits limit the queries that templates of alert rules run during an evaluation.
+type templateQueryLimits struct {
+	timeout time.Duration
+	// maxQueries is the maximum number of distinct queries. The query function is disabled if it is 0.
+	maxQueries int
+}
+
+// templateQuery is the argument of the query template function. It is either an expression that runs against
+// the first data source the rule queries, or a JSON object with the UID of the data source and the expression,
+// the same format graphLink and tableLink accept. Templates can only query the data sources the rule queries,
+// which the author of the rule was allowed to query when the rule was saved.
+type templateQuery struct {
+	Datasource string `json:"datasource"`
+	Expr       string `json:"expr"`
+}
+
+func parseTemplateQuery(q string, rule *ngmodels.AlertRule) (templateQuery, error) {
+	var query templateQuery
+	if strings.HasPrefix(strings.TrimSpace(q), "{") {
+		if err := json.Unmarshal([]byte(q), &query); err != nil {
+			return templateQuery{}, fmt.Errorf("failed to parse query: %w", err)
+		}
+	} else {
+		query.Expr = q
+	}
+	if query.Expr == "" {
+		return templateQuery{}, errors.New("query expression is empty")
+	}
+	for _, q := range rule.Data {
+		if isExpr, _ := q.IsExpression(); isExpr {
+			continue
+		}
+		if query.Datasource == "" {
+			query.Datasource = q.DatasourceUID
+		}
+		if query.Datasource == q.DatasourceUID {
+			return query, nil
+		}
+	}
+	if query.Datasource == "" {
+		return templateQuery{}, errors.New("rule does not query a data source")
+	}
+	return templateQuery{}, fmt.Errorf("data source %q is not queried by the rule", query.Datasource)
+}
+
+// newTemplateQueryFunc returns the function that runs the queries of the query template function for a single evaluation
+// of the rule. Queries run as instant queries at the evaluation time, and each distinct query runs once.
+func (a *alertRule) newTemplateQueryFunc(rule *ngmodels.AlertRule) template.QueryFunc {
+	return template.NewCachedQueryFunc(func(ctx context.Context, q string, ts time.Time) (promql.Vector, error) {
+		query, err := parseTemplateQuery(q, rule)
+		if err != nil {
+			return nil, err
+		}
+		model, err := json.Marshal(map[string]any{
+			"refId":   templateQueryRefID,
+			"expr":    query.Expr,
+			"instant": true,
+			"range":   false,
+		})
+		if err != nil {
+			return nil, err
+		}
+		condition := ngmodels.Condition{
+			Condition: templateQueryRefID,
+			Data: []ngmodels.AlertQuery{{
+				RefID:             templateQueryRefID,
+				DatasourceUID:     query.Datasource,
+				Model:             model,
+				RelativeTimeRange: ngmodels.RelativeTimeRange{From: ngmodels.Duration(templateQueryRange)},
+			}},
+		}
+		evaluator, err := a.evalFactory.Create(eval.NewContext(ctx, SchedulerUserFor(rule.OrgID)), condition.WithSource("template"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to build query: %w", err)
+		}
+		resp, err := evaluator.EvaluateRaw(ctx, ts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run query: %w", err)
+		}
+		res, ok := resp.Responses[templateQueryRefID]
+		if !ok {
+			return nil, nil
+		}
+		if res.Error != nil {
+			return nil, fmt.Errorf("failed to run query: %w", res.Error)
+		}
+		return vectorFromFrames(res.Frames, ts), nil
+	}, a.templateQueries.timeout, a.templateQueries.maxQueries)
+}
+
+// vectorFromFrames returns a sample with the last value of each numeric field in the frames.
+func vectorFromFrames(frames data.Frames, ts time.Time) promql.Vector {
+	vector := promql.Vector{}
+	for _, frame := range frames {
+		for _, field := range frame.Fields {
+			if !field.Type().Numeric() || field.Len() == 0 {
+				continue
+			}
+			v, err := field.NullableFloatAt(field.Len() - 1)
+			if err != nil || v == nil {
+				continue
+			}
+			vector = append(vector, promql.Sample{
+				Metric: labels.FromMap(field.Labels),
+				T:      ts.UnixMilli(),
+				F:      *v,
+			})
+		}
+	}
+	sort.Slice(vector, func(i, j int) bool {
+		return labels.Compare(vector[i].Metric, vector[j].Metric) < 0
+	})
+	return vector
+}
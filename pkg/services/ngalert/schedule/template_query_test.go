@@ -0,0 +1,134 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval/eval_mocks"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestParseTemplateQuery(t *testing.T) {
+	rule := models.RuleGen.With(models.RuleGen.WithQuery(
+		models.AlertQuery{RefID: "B", DatasourceUID: expr.DatasourceUID},
+		models.AlertQuery{RefID: "A", DatasourceUID: "prometheus"},
+		models.AlertQuery{RefID: "C", DatasourceUID: "loki"},
+	)).GenerateRef()
+
+	testCases := []struct {
+		name          string
+		query         string
+		expected      templateQuery
+		expectedError string
+	}{{
+		name:     "expression runs against the first data source of the rule",
+		query:    `up{job="grafana"}`,
+		expected: templateQuery{Datasource: "prometheus", Expr: `up{job="grafana"}`},
+	}, {
+		name:     "JSON query selects the data source",
+		query:    `{"datasource": "loki", "expr": "count_over_time({job=\"grafana\"}[5m])"}`,
+		expected: templateQuery{Datasource: "loki", Expr: `count_over_time({job="grafana"}[5m])`},
+	}, {
+		name:     "JSON query without data source runs against the first data source of the rule",
+		query:    `{"expr": "up"}`,
+		expected: templateQuery{Datasource: "prometheus", Expr: "up"},
+	}, {
+		name:          "JSON query selects a data source the rule does not query",
+		query:         `{"datasource": "mysql", "expr": "SELECT 1"}`,
+		expectedError: `data source "mysql" is not queried by the rule`,
+	}, {
+		name:          "empty expression",
+		query:         `{"datasource": "loki"}`,
+		expectedError: "query expression is empty",
+	}, {
+		name:          "invalid JSON",
+		query:         `{"expr": `,
+		expectedError: "failed to parse query",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := parseTemplateQuery(tc.query, rule)
+			if tc.expectedError != "" {
+				require.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, q)
+		})
+	}
+
+	t.Run("rule without data source queries requires a data source", func(t *testing.T) {
+		rule := models.RuleGen.With(models.RuleGen.WithQuery(models.AlertQuery{RefID: "A", DatasourceUID: expr.DatasourceUID})).GenerateRef()
+		_, err := parseTemplateQuery("up", rule)
+		require.ErrorContains(t, err, "rule does not query a data source")
+	})
+}
+
+func TestVectorFromFrames(t *testing.T) {
+	ts := time.Now()
+	frames := data.Frames{
+		data.NewFrame("",
+			data.NewField("time", nil, []time.Time{ts.Add(-time.Minute), ts}),
+			data.NewField("value", data.Labels{"instance": "b"}, []float64{1, 2}),
+		),
+		data.NewFrame("",
+			data.NewField("value", data.Labels{"instance": "a"}, []*float64{nil}),
+			data.NewField("value", data.Labels{"instance": "c"}, []int64{3}),
+			data.NewField("name", nil, []string{"test"}),
+		),
+	}
+	expected := promql.Vector{
+		{Metric: labels.FromStrings("instance", "b"), T: ts.UnixMilli(), F: 2},
+		{Metric: labels.FromStrings("instance", "c"), T: ts.UnixMilli(), F: 3},
+	}
+	require.Equal(t, expected, vectorFromFrames(frames, ts))
+}
+
+func TestNewTemplateQueryFunc(t *testing.T) {
+	rule := models.RuleGen.With(models.RuleGen.WithQuery(models.AlertQuery{RefID: "A", DatasourceUID: "prometheus"})).GenerateRef()
+	ts := time.Now()
+
+	t.Run("runs each distinct query once", func(t *testing.T) {
+		evaluator := eval_mocks.NewConditionEvaluatorMock(t)
+		evaluator.EXPECT().EvaluateRaw(mock.Anything, ts).Return(&backend.QueryDataResponse{
+			Responses: backend.Responses{
+				templateQueryRefID: {Frames: data.Frames{data.NewFrame("", data.NewField("value", data.Labels{"instance": "a"}, []float64{1}))}},
+			},
+		}, nil).Once()
+		a := &alertRule{
+			evalFactory:     eval_mocks.NewEvaluatorFactory(evaluator),
+			templateQueries: templateQueryLimits{timeout: time.Second, maxQueries: 10},
+		}
+		f := a.newTemplateQueryFunc(rule)
+		for i := 0; i < 3; i++ {
+			v, err := f(context.Background(), "up", ts)
+			require.NoError(t, err)
+			require.Equal(t, promql.Vector{{Metric: labels.FromStrings("instance", "a"), T: ts.UnixMilli(), F: 1}}, v)
+		}
+	})
+
+	t.Run("returns errors of the query", func(t *testing.T) {
+		evaluator := eval_mocks.NewConditionEvaluatorMock(t)
+		evaluator.EXPECT().EvaluateRaw(mock.Anything, ts).Return(&backend.QueryDataResponse{
+			Responses: backend.Responses{
+				templateQueryRefID: {Error: errors.New("bad query")},
+			},
+		}, nil).Once()
+		a := &alertRule{
+			evalFactory:     eval_mocks.NewEvaluatorFactory(evaluator),
+			templateQueries: templateQueryLimits{timeout: time.Second, maxQueries: 10},
+		}
+		_, err := a.newTemplateQueryFunc(rule)(context.Background(), "up", ts)
+		require.ErrorContains(t, err, "bad query")
+	})
+}
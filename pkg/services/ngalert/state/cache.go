@@ -257,6 +257,17 @@ func (c *cache) setAllStates(newStates map[int64]map[string]*ruleStates) {
 	c.states = newStates
 }
 
+func (c *cache) setRuleStates(ruleKey ngModels.AlertRuleKey, rs *ruleStates) {
+	c.mtxStates.Lock()
+	defer c.mtxStates.Unlock()
+	orgStates, ok := c.states[ruleKey.OrgID]
+	if !ok {
+		orgStates = make(map[string]*ruleStates)
+		c.states[ruleKey.OrgID] = orgStates
+	}
+	orgStates[ruleKey.UID] = rs
+}
+
 func (c *cache) set(entry *State) {
 	c.mtxStates.Lock()
 	defer c.mtxStates.Unlock()
@@ -0,0 +1,481 @@
+package historian
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/prometheus/model/value"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/client"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	history_model "github.com/grafana/grafana/pkg/services/ngalert/state/historian/model"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	prometheusBackendName = "prometheus"
+
+	// AlertStateLabel, like in Prometheus' ALERTS series, contains the state of the alert instance.
+	AlertStateLabel = "alertstate"
+	// Labels that connect the series to the alert rule.
+	PrometheusRuleUIDLabel   = "grafana_rule_uid"
+	PrometheusOrgIDLabel     = "grafana_org_id"
+	PrometheusFolderUIDLabel = "grafana_folder_uid"
+	PrometheusGroupLabel     = "grafana_rule_group"
+
+	forStateSuffix = "_FOR_STATE"
+	// minQueryStep is the minimum resolution of queries to Prometheus. It should not be lower than the base interval of the scheduler.
+	minQueryStep = 10 * time.Second
+	// maxQueryPoints is the maximum number of points per series Prometheus allows to return.
+	maxQueryPoints = 11000
+)
+
+// RemoteWriter writes data frames as time series to a remote storage.
+type RemoteWriter interface {
+	Write(ctx context.Context, name string, t time.Time, frames data.Frames, extraLabels map[string]string) error
+}
+
+type PrometheusConfig struct {
+	// QueryURL is the base URL of the Prometheus HTTP API. If it is nil, the backend does not support queries.
+	QueryURL          *url.URL
+	BasicAuthUser     string
+	BasicAuthPassword string
+	MetricName        string
+	ExternalLabels    map[string]string
+}
+
+func NewPrometheusConfig(cfg setting.UnifiedAlertingStateHistorySettings) (PrometheusConfig, error) {
+	if cfg.PrometheusWriteURL == "" {
+		return PrometheusConfig{}, fmt.Errorf("remote write URL must be provided")
+	}
+	if cfg.PrometheusMetricName == "" {
+		return PrometheusConfig{}, fmt.Errorf("metric name must not be empty")
+	}
+	result := PrometheusConfig{
+		BasicAuthUser:     cfg.PrometheusBasicAuthUsername,
+		BasicAuthPassword: cfg.PrometheusBasicAuthPassword,
+		MetricName:        cfg.PrometheusMetricName,
+		ExternalLabels:    cfg.ExternalLabels,
+	}
+	if cfg.PrometheusQueryURL != "" {
+		u, err := url.Parse(cfg.PrometheusQueryURL)
+		if err != nil {
+			return PrometheusConfig{}, fmt.Errorf("failed to parse Prometheus query URL: %w", err)
+		}
+		result.QueryURL = u
+	}
+	return result, nil
+}
+
+// PrometheusBackend is an implementation of state.Historian that writes the state of alert instances as time series,
+// in the same way Prometheus exposes its own alerts in the ALERTS and ALERTS_FOR_STATE series:
+//   - <MetricName>{alertstate="pending|firing|nodata|error", <instance labels>} is 1 while the instance is in that state.
+//   - <MetricName>_FOR_STATE{<instance labels>} is the Unix time when the instance became active.
+//
+// When an instance leaves a state, a staleness marker is written so the series ends immediately.
+type PrometheusBackend struct {
+	writer  RemoteWriter
+	client  client.Requester
+	cfg     PrometheusConfig
+	rules   RuleStore
+	ac      AccessControl
+	clock   clock.Clock
+	metrics *metrics.Historian
+	log     log.Logger
+}
+
+func NewPrometheusBackend(logger log.Logger, cfg PrometheusConfig, writer RemoteWriter, req client.Requester, rules RuleStore, metrics *metrics.Historian, ac AccessControl) *PrometheusBackend {
+	return &PrometheusBackend{
+		writer:  writer,
+		client:  req,
+		cfg:     cfg,
+		rules:   rules,
+		ac:      ac,
+		clock:   clock.New(),
+		metrics: metrics,
+		log:     logger,
+	}
+}
+
+type promSample struct {
+	labels data.Labels
+	value  float64
+}
+
+// Record writes the current state of all instances of the rule.
+func (h *PrometheusBackend) Record(ctx context.Context, rule history_model.RuleMeta, states []state.StateTransition) <-chan error {
+	samples := h.buildSamples(rule, states)
+
+	errCh := make(chan error, 1)
+	if len(samples) == 0 {
+		close(errCh)
+		return errCh
+	}
+
+	writeCtx := context.Background()
+	writeCtx, cancel := context.WithTimeout(writeCtx, StateHistoryWriteTimeout)
+	writeCtx = history_model.WithRuleData(writeCtx, rule)
+	writeCtx = trace.ContextWithSpan(writeCtx, trace.SpanFromContext(ctx))
+
+	go func(ctx context.Context) {
+		defer cancel()
+		defer close(errCh)
+		logger := h.log.FromContext(ctx)
+
+		org := fmt.Sprint(rule.OrgID)
+		h.metrics.WritesTotal.WithLabelValues(org, prometheusBackendName).Inc()
+
+		var errs []error
+		for key, series := range samples {
+			frame := data.NewFrame("")
+			for _, s := range series {
+				frame.Fields = append(frame.Fields, data.NewField("", s.labels, []float64{s.value}))
+			}
+			frame.SetMeta(&data.FrameMeta{
+				Type:        data.FrameTypeNumericWide,
+				TypeVersion: data.FrameTypeVersion{0, 1},
+			})
+			if err := h.writer.Write(ctx, key.name, key.t, data.Frames{frame}, h.cfg.ExternalLabels); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			err := Join(errs...)
+			logger.Error("Failed to write alert state history series", "error", err)
+			h.metrics.WritesFailed.WithLabelValues(org, prometheusBackendName).Inc()
+			errCh <- fmt.Errorf("failed to write alert state history series: %w", err)
+		}
+	}(writeCtx)
+	return errCh
+}
+
+type seriesKey struct {
+	name string
+	t    time.Time
+}
+
+func (h *PrometheusBackend) buildSamples(rule history_model.RuleMeta, states []state.StateTransition) map[seriesKey][]promSample {
+	result := make(map[seriesKey][]promSample)
+	add := func(name string, t time.Time, lbls data.Labels, v float64) {
+		key := seriesKey{name: name, t: t}
+		result[key] = append(result[key], promSample{labels: lbls, value: v})
+	}
+	stale := math.Float64frombits(value.StaleNaN)
+	forStateName := h.cfg.MetricName + forStateSuffix
+
+	for _, s := range states {
+		lbls := removePrivateLabels(s.Labels)
+		lbls[PrometheusRuleUIDLabel] = rule.UID
+		lbls[PrometheusOrgIDLabel] = fmt.Sprint(rule.OrgID)
+		lbls[PrometheusFolderUIDLabel] = rule.NamespaceUID
+		lbls[PrometheusGroupLabel] = rule.Group
+		t := s.State.LastEvaluationTime
+
+		current, isActive := alertStateValue(s.State.State)
+		previous, wasActive := alertStateValue(s.PreviousState)
+		if wasActive && previous != current {
+			prevLbls := lbls.Copy()
+			prevLbls[AlertStateLabel] = previous
+			add(h.cfg.MetricName, t, prevLbls, stale)
+		}
+		if !isActive {
+			if wasActive {
+				add(forStateName, t, lbls, stale)
+			}
+			continue
+		}
+		add(forStateName, t, lbls.Copy(), float64(s.State.StartsAt.Unix()))
+		lbls[AlertStateLabel] = current
+		add(h.cfg.MetricName, t, lbls, 1)
+	}
+	return result
+}
+
+// alertStateValue returns the value of the alertstate label for a state, and false if the state is not active.
+func alertStateValue(s eval.State) (string, bool) {
+	switch s {
+	case eval.Pending:
+		return "pending", true
+	case eval.Alerting:
+		return "firing", true
+	case eval.NoData:
+		return "nodata", true
+	case eval.Error:
+		return "error", true
+	default:
+		return "", false
+	}
+}
+
+func stateFromAlertStateValue(v string) string {
+	switch v {
+	case "pending":
+		return eval.Pending.String()
+	case "firing":
+		return eval.Alerting.String()
+	case "nodata":
+		return eval.NoData.String()
+	case "error":
+		return eval.Error.String()
+	default:
+		return eval.Normal.String()
+	}
+}
+
+// Query reconstructs state transitions from the series written by Record, and formats them into a dataframe
+// in the same format as the Loki backend. The precision of transitions is limited by the resolution of the query.
+func (h *PrometheusBackend) Query(ctx context.Context, query models.HistoryQuery) (*data.Frame, error) {
+	if h.cfg.QueryURL == nil {
+		return nil, fmt.Errorf("querying is not supported because Prometheus query URL is not configured")
+	}
+	uids, err := getFolderUIDsForFilter(ctx, h.ac, h.rules, query)
+	if err != nil {
+		return nil, err
+	}
+	if query.DashboardUID != "" || query.PanelID != 0 {
+		h.log.FromContext(ctx).Warn("Prometheus state history backend does not support dashboard and panel queries, ignoring that filter")
+	}
+
+	now := h.clock.Now().UTC()
+	if query.To.IsZero() {
+		query.To = now
+	}
+	if query.From.IsZero() {
+		query.From = now.Add(-defaultQueryRange)
+	}
+	if query.From.After(query.To) {
+		return nil, fmt.Errorf("start time cannot be after end time")
+	}
+	step := query.To.Sub(query.From) / maxQueryPoints
+	if step < minQueryStep {
+		step = minQueryStep
+	}
+
+	matrix, err := h.rangeQuery(ctx, buildPromQuery(h.cfg.MetricName, query), query.From, query.To, step)
+	if err != nil {
+		return nil, err
+	}
+	res, err := matrixToStreams(matrix, query.From, query.To, step)
+	if err != nil {
+		return nil, err
+	}
+	limitStreams(&res, query.Limit)
+	return merge(res, uids)
+}
+
+func buildPromQuery(metric string, query models.HistoryQuery) string {
+	matchers := []string{
+		fmt.Sprintf("__name__=%s", strconv.Quote(metric)),
+		fmt.Sprintf("%s=%s", PrometheusOrgIDLabel, strconv.Quote(fmt.Sprint(query.OrgID))),
+	}
+	if query.RuleUID != "" {
+		matchers = append(matchers, fmt.Sprintf("%s=%s", PrometheusRuleUIDLabel, strconv.Quote(query.RuleUID)))
+	}
+	keys := make([]string, 0, len(query.Labels))
+	for k := range query.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		matchers = append(matchers, fmt.Sprintf("%s=%s", k, strconv.Quote(query.Labels[k])))
+	}
+	return "{" + strings.Join(matchers, ",") + "}"
+}
+
+type promMatrix []struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]any          `json:"values"`
+}
+
+type promQueryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string     `json:"resultType"`
+		Result     promMatrix `json:"result"`
+	} `json:"data"`
+}
+
+func (h *PrometheusBackend) rangeQuery(ctx context.Context, promQL string, from, to time.Time, step time.Duration) (promMatrix, error) {
+	queryURL := h.cfg.QueryURL.JoinPath("/api/v1/query_range")
+	values := url.Values{}
+	values.Set("query", promQL)
+	values.Set("start", strconv.FormatInt(from.Unix(), 10))
+	values.Set("end", strconv.FormatInt(to.Unix(), 10))
+	values.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	queryURL.RawQuery = values.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, queryURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	if h.cfg.BasicAuthUser != "" || h.cfg.BasicAuthPassword != "" {
+		req.SetBasicAuth(h.cfg.BasicAuthUser, h.cfg.BasicAuthPassword)
+	}
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error executing request: %w", err)
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading request response: %w", err)
+	}
+
+	var result promQueryResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("error parsing request response (status %d): %w", res.StatusCode, err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("received an error from Prometheus (status %d): %s: %s", res.StatusCode, result.ErrorType, result.Error)
+	}
+	if result.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("unexpected result type %q", result.Data.ResultType)
+	}
+	return result.Data.Result, nil
+}
+
+// matrixToStreams converts the series returned by Prometheus to a history of state transitions per alert instance.
+// An instance is considered to be Normal at the steps where none of its series has a sample.
+// The state at the first step is considered the initial state and is not reported as a transition.
+func matrixToStreams(matrix promMatrix, from, to time.Time, step time.Duration) (QueryRes, error) {
+	type instance struct {
+		labels data.Labels
+		rule   string
+		stream map[string]string
+		// states contains the value of the alertstate label by the timestamp of the step in seconds.
+		states map[int64]string
+	}
+	instances := make(map[string]*instance)
+	for _, series := range matrix {
+		lbls := make(data.Labels, len(series.Metric))
+		for k, v := range series.Metric {
+			switch k {
+			case "__name__", AlertStateLabel, PrometheusRuleUIDLabel, PrometheusOrgIDLabel, PrometheusFolderUIDLabel, PrometheusGroupLabel:
+				continue
+			}
+			lbls[k] = v
+		}
+		ruleUID := series.Metric[PrometheusRuleUIDLabel]
+		key := ruleUID + "\xff" + lbls.String()
+		inst, ok := instances[key]
+		if !ok {
+			inst = &instance{
+				labels: lbls,
+				rule:   ruleUID,
+				stream: map[string]string{
+					StateHistoryLabelKey: StateHistoryLabelValue,
+					OrgIDLabel:           series.Metric[PrometheusOrgIDLabel],
+					GroupLabel:           series.Metric[PrometheusGroupLabel],
+					FolderUIDLabel:       series.Metric[PrometheusFolderUIDLabel],
+				},
+				states: make(map[int64]string),
+			}
+			instances[key] = inst
+		}
+		for _, v := range series.Values {
+			ts, ok := v[0].(float64)
+			if !ok {
+				return QueryRes{}, fmt.Errorf("unexpected timestamp %v", v[0])
+			}
+			inst.states[int64(math.Round(ts))] = series.Metric[AlertStateLabel]
+		}
+	}
+
+	streams := make(map[string]*Stream)
+	for _, inst := range instances {
+		fingerprint := labelFingerprint(inst.labels)
+		previous := ""
+		for t := from.Truncate(time.Second); !t.After(to); t = t.Add(step) {
+			current := stateFromAlertStateValue(inst.states[t.Unix()])
+			if previous != "" && current != previous {
+				line, err := json.Marshal(LokiEntry{
+					SchemaVersion:  1,
+					Previous:       previous,
+					Current:        current,
+					Fingerprint:    fingerprint,
+					RuleUID:        inst.rule,
+					InstanceLabels: inst.labels,
+				})
+				if err != nil {
+					return QueryRes{}, err
+				}
+				key := inst.stream[GroupLabel] + "\xff" + inst.stream[FolderUIDLabel]
+				s, ok := streams[key]
+				if !ok {
+					s = &Stream{Stream: inst.stream}
+					streams[key] = s
+				}
+				s.Values = append(s.Values, Sample{T: t, V: string(line)})
+			}
+			previous = current
+		}
+	}
+
+	res := QueryRes{Data: QueryData{Result: make([]Stream, 0, len(streams))}}
+	for _, s := range streams {
+		sort.SliceStable(s.Values, func(i, j int) bool { return s.Values[i].T.Before(s.Values[j].T) })
+		res.Data.Result = append(res.Data.Result, *s)
+	}
+	return res, nil
+}
+
+// limitStreams keeps only the most recent samples across all streams, so that the total number of samples does not exceed the limit.
+func limitStreams(res *QueryRes, limit int) {
+	if limit < 1 {
+		limit = defaultPageSize
+	}
+	var times []time.Time
+	for _, s := range res.Data.Result {
+		for _, v := range s.Values {
+			times = append(times, v.T)
+		}
+	}
+	if len(times) <= limit {
+		return
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].After(times[j]) })
+	threshold := times[limit-1]
+	// Samples at the threshold are kept only until the limit is reached.
+	remaining := limit
+	for _, s := range res.Data.Result {
+		for _, v := range s.Values {
+			if v.T.After(threshold) {
+				remaining--
+			}
+		}
+	}
+	for i := range res.Data.Result {
+		s := &res.Data.Result[i]
+		kept := s.Values[:0]
+		for _, v := range s.Values {
+			if v.T.After(threshold) {
+				kept = append(kept, v)
+			} else if v.T.Equal(threshold) && remaining > 0 {
+				kept = append(kept, v)
+				remaining--
+			}
+		}
+		s.Values = kept
+	}
+}
@@ -0,0 +1,217 @@
+package historian
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/log"
+	acfakes "github.com/grafana/grafana/pkg/services/ngalert/accesscontrol/fakes"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
+	"github.com/grafana/grafana/pkg/services/ngalert/writer"
+)
+
+type writtenSeries struct {
+	name   string
+	labels data.Labels
+	value  float64
+}
+
+func TestPrometheusBackendRecord(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	rule := createTestRule()
+
+	record := func(t *testing.T, states []state.StateTransition) []writtenSeries {
+		t.Helper()
+		var mtx sync.Mutex
+		var written []writtenSeries
+		w := writer.FakeWriter{WriteFunc: func(ctx context.Context, name string, _ time.Time, frames data.Frames, extraLabels map[string]string) error {
+			_, ok := models.RuleKeyFromContext(ctx)
+			require.True(t, ok)
+			require.Equal(t, map[string]string{"external": "label"}, extraLabels)
+			mtx.Lock()
+			defer mtx.Unlock()
+			for _, f := range frames {
+				for _, field := range f.Fields {
+					written = append(written, writtenSeries{name: name, labels: field.Labels, value: field.At(0).(float64)})
+				}
+			}
+			return nil
+		}}
+		h := createTestPrometheusBackend(t, w, NewFakeRequester())
+		require.NoError(t, <-h.Record(context.Background(), rule, states))
+		return written
+	}
+
+	t.Run("should write active state and the time it started", func(t *testing.T) {
+		tr := sqlTransition(data.Labels{"instance": "a", "__private__": "x"}, eval.Alerting, eval.Pending, now)
+		tr.State.StartsAt = now.Add(-time.Minute)
+		written := record(t, []state.StateTransition{tr})
+
+		base := data.Labels{
+			"instance":               "a",
+			PrometheusRuleUIDLabel:   rule.UID,
+			PrometheusOrgIDLabel:     fmt.Sprint(rule.OrgID),
+			PrometheusFolderUIDLabel: rule.NamespaceUID,
+			PrometheusGroupLabel:     rule.Group,
+		}
+		withState := func(s string) data.Labels {
+			l := base.Copy()
+			l[AlertStateLabel] = s
+			return l
+		}
+		require.Len(t, written, 3)
+		require.Contains(t, written, writtenSeries{name: "GRAFANA_ALERTS", labels: withState("firing"), value: 1})
+		require.Contains(t, written, writtenSeries{name: "GRAFANA_ALERTS_FOR_STATE", labels: base, value: float64(now.Add(-time.Minute).Unix())})
+		// The series of the previous state is marked stale.
+		var stale *writtenSeries
+		for i := range written {
+			if written[i].labels[AlertStateLabel] == "pending" {
+				stale = &written[i]
+			}
+		}
+		require.NotNil(t, stale)
+		require.True(t, value.IsStaleNaN(stale.value))
+	})
+
+	t.Run("should mark all series stale when instance becomes normal", func(t *testing.T) {
+		written := record(t, []state.StateTransition{sqlTransition(data.Labels{"instance": "a"}, eval.Normal, eval.Alerting, now)})
+		require.Len(t, written, 2)
+		for _, s := range written {
+			require.True(t, value.IsStaleNaN(s.value))
+		}
+	})
+
+	t.Run("should not write anything for normal instances", func(t *testing.T) {
+		written := record(t, []state.StateTransition{sqlTransition(data.Labels{"instance": "a"}, eval.Normal, eval.Normal, now)})
+		require.Empty(t, written)
+	})
+
+	t.Run("should return error if write fails", func(t *testing.T) {
+		w := writer.FakeWriter{WriteFunc: func(context.Context, string, time.Time, data.Frames, map[string]string) error {
+			return fmt.Errorf("boom")
+		}}
+		h := createTestPrometheusBackend(t, w, NewFakeRequester())
+		err := <-h.Record(context.Background(), rule, []state.StateTransition{sqlTransition(data.Labels{"instance": "a"}, eval.Alerting, eval.Normal, now)})
+		require.ErrorContains(t, err, "boom")
+	})
+}
+
+func TestPrometheusBackendQuery(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	from := now.Add(-time.Minute)
+	ts := func(d time.Duration) float64 { return float64(from.Add(d).Unix()) }
+
+	// Instance "a" is pending for one step, then firing for two steps, then normal.
+	response := fmt.Sprintf(`{
+		"status": "success",
+		"data": {
+			"resultType": "matrix",
+			"result": [
+				{
+					"metric": {"__name__": "GRAFANA_ALERTS", "alertstate": "pending", "instance": "a", "grafana_rule_uid": "rule-uid", "grafana_org_id": "1", "grafana_folder_uid": "my-folder", "grafana_rule_group": "my-group"},
+					"values": [[%[1]v, "1"]]
+				},
+				{
+					"metric": {"__name__": "GRAFANA_ALERTS", "alertstate": "firing", "instance": "a", "grafana_rule_uid": "rule-uid", "grafana_org_id": "1", "grafana_folder_uid": "my-folder", "grafana_rule_group": "my-group"},
+					"values": [[%[2]v, "1"], [%[3]v, "1"]]
+				}
+			]
+		}
+	}`, ts(10*time.Second), ts(20*time.Second), ts(30*time.Second))
+
+	req := NewFakeRequester().WithResponse(&http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewBufferString(response)),
+		Header:     make(http.Header),
+	})
+	h := createTestPrometheusBackend(t, writer.FakeWriter{}, req)
+
+	frame, err := h.Query(context.Background(), models.HistoryQuery{
+		OrgID:        1,
+		RuleUID:      "rule-uid",
+		Labels:       map[string]string{"instance": "a"},
+		From:         from,
+		To:           now,
+		SignedInUser: &identity.StaticRequester{OrgID: 1},
+	})
+	require.NoError(t, err)
+
+	q := req.lastRequest.URL.Query()
+	require.Equal(t, "/prom/api/v1/query_range", req.lastRequest.URL.Path)
+	require.Equal(t, `{__name__="GRAFANA_ALERTS",grafana_org_id="1",grafana_rule_uid="rule-uid",instance="a"}`, q.Get("query"))
+	require.Equal(t, "10", q.Get("step"))
+	user, pass, ok := req.lastRequest.BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "user", user)
+	require.Equal(t, "pass", pass)
+
+	require.Equal(t, 3, frame.Rows())
+	expected := [][2]string{{"Normal", "Pending"}, {"Pending", "Alerting"}, {"Alerting", "Normal"}}
+	for i, e := range expected {
+		entry := requireFrameEntry(t, frame, i)
+		require.Equal(t, e[0], entry.Previous)
+		require.Equal(t, e[1], entry.Current)
+		require.Equal(t, "rule-uid", entry.RuleUID)
+		require.Equal(t, map[string]string{"instance": "a"}, entry.InstanceLabels)
+	}
+
+	t.Run("should fail if query URL is not configured", func(t *testing.T) {
+		h := createTestPrometheusBackend(t, writer.FakeWriter{}, NewFakeRequester())
+		h.cfg.QueryURL = nil
+		_, err := h.Query(context.Background(), models.HistoryQuery{OrgID: 1, SignedInUser: &identity.StaticRequester{OrgID: 1}})
+		require.Error(t, err)
+	})
+}
+
+func TestLimitStreams(t *testing.T) {
+	at := func(s int) Sample { return Sample{T: time.Unix(int64(s), 0)} }
+	res := QueryRes{Data: QueryData{Result: []Stream{
+		{Values: []Sample{at(1), at(3), at(5)}},
+		{Values: []Sample{at(2), at(4), at(5)}},
+	}}}
+	limitStreams(&res, 3)
+	require.Equal(t, []Sample{at(5)}, res.Data.Result[0].Values)
+	require.Equal(t, []Sample{at(4), at(5)}, res.Data.Result[1].Values)
+}
+
+func createTestPrometheusBackend(t *testing.T, w RemoteWriter, req *fakeRequester) *PrometheusBackend {
+	t.Helper()
+	u, err := url.Parse("http://prometheus.local/prom")
+	require.NoError(t, err)
+	cfg := PrometheusConfig{
+		QueryURL:          u,
+		BasicAuthUser:     "user",
+		BasicAuthPassword: "pass",
+		MetricName:        "GRAFANA_ALERTS",
+		ExternalLabels:    map[string]string{"external": "label"},
+	}
+	met := metrics.NewHistorianMetrics(prometheus.NewRegistry(), metrics.Subsystem)
+	ac := &acfakes.FakeRuleService{}
+	ac.CanReadAllRulesFunc = func(ctx context.Context, user identity.Requester) (bool, error) {
+		return true, nil
+	}
+	h := NewPrometheusBackend(log.NewNopLogger(), cfg, w, req, fakes.NewRuleStore(t), met, ac)
+	clk := clock.NewMock()
+	clk.Set(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	h.clock = clk
+	return h
+}
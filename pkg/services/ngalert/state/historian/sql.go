@@ -0,0 +1,375 @@
+package historian
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	history_model "github.com/grafana/grafana/pkg/services/ngalert/state/historian/model"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	sqlBackendName = "sql"
+	// sqlInsertBatchSize keeps the number of bound parameters of a single insert statement below the limits of all supported databases.
+	sqlInsertBatchSize = 50
+)
+
+type SQLConfig struct {
+	// Retention is how long entries are kept. Zero keeps them forever.
+	Retention time.Duration
+	// OrgRetention overrides Retention for specific organizations.
+	OrgRetention map[int64]time.Duration
+	// MaxEntriesPerOrg is the number of the most recent entries kept per organization. Zero means no limit.
+	MaxEntriesPerOrg   int
+	CompactionInterval time.Duration
+	// MaxQuerySize is the maximum number of rows read from the database at once. A query reads further rows until it
+	// has as many entries matching its filters as requested. Zero reads all rows at once.
+	MaxQuerySize int
+}
+
+func NewSQLConfig(cfg setting.UnifiedAlertingStateHistorySettings) SQLConfig {
+	return SQLConfig{
+		Retention:          cfg.SQLRetention,
+		OrgRetention:       cfg.SQLOrgRetention,
+		MaxEntriesPerOrg:   cfg.SQLMaxEntriesPerOrg,
+		CompactionInterval: cfg.SQLCompactionInterval,
+		MaxQuerySize:       cfg.SQLMaxQuerySize,
+	}
+}
+
+// sqlHistoryEntry is a row of the alert_state_history table.
+type sqlHistoryEntry struct {
+	ID          int64  `xorm:"pk autoincr 'id'"`
+	OrgID       int64  `xorm:"org_id"`
+	RuleUID     string `xorm:"rule_uid"`
+	FolderUID   string `xorm:"folder_uid"`
+	RuleGroup   string `xorm:"rule_group"`
+	Fingerprint string `xorm:"fingerprint"`
+	Previous    string `xorm:"previous_state"`
+	Current     string `xorm:"current_state"`
+	Line        string `xorm:"line"`
+	EvaluatedAt int64  `xorm:"evaluated_at"`
+}
+
+func (sqlHistoryEntry) TableName() string {
+	return "alert_state_history"
+}
+
+// SQLBackend is an implementation of state.Historian that stores state transitions in a dedicated table of the Grafana database.
+// Entries are stored in the same format as the Loki backend uses, so the results of queries are interchangeable.
+type SQLBackend struct {
+	db      db.DB
+	rules   RuleStore
+	ac      AccessControl
+	clock   clock.Clock
+	metrics *metrics.Historian
+	log     log.Logger
+	cfg     SQLConfig
+}
+
+func NewSQLBackend(logger log.Logger, cfg SQLConfig, store db.DB, rules RuleStore, metrics *metrics.Historian, ac AccessControl) *SQLBackend {
+	return &SQLBackend{
+		db:      store,
+		rules:   rules,
+		ac:      ac,
+		clock:   clock.New(),
+		metrics: metrics,
+		log:     logger,
+		cfg:     cfg,
+	}
+}
+
+// Record writes a number of state transitions for a given rule to the database.
+func (h *SQLBackend) Record(ctx context.Context, rule history_model.RuleMeta, states []state.StateTransition) <-chan error {
+	logger := h.log.FromContext(ctx)
+	entries := h.buildEntries(rule, states, logger)
+
+	errCh := make(chan error, 1)
+	if len(entries) == 0 {
+		close(errCh)
+		return errCh
+	}
+
+	// This is a new background job, so let's create a brand new context for it, isolated from the evaluation.
+	writeCtx := context.Background()
+	writeCtx, cancel := context.WithTimeout(writeCtx, StateHistoryWriteTimeout)
+	writeCtx = history_model.WithRuleData(writeCtx, rule)
+	writeCtx = trace.ContextWithSpan(writeCtx, trace.SpanFromContext(ctx))
+
+	go func(ctx context.Context) {
+		defer cancel()
+		defer close(errCh)
+		logger := h.log.FromContext(ctx)
+
+		org := fmt.Sprint(rule.OrgID)
+		h.metrics.WritesTotal.WithLabelValues(org, sqlBackendName).Inc()
+		h.metrics.TransitionsTotal.WithLabelValues(org).Add(float64(len(entries)))
+
+		if err := h.insert(ctx, entries); err != nil {
+			logger.Error("Failed to save alert state history batch", "error", err)
+			h.metrics.WritesFailed.WithLabelValues(org, sqlBackendName).Inc()
+			h.metrics.TransitionsFailed.WithLabelValues(org).Add(float64(len(entries)))
+			errCh <- fmt.Errorf("failed to save alert state history batch: %w", err)
+		}
+	}(writeCtx)
+	return errCh
+}
+
+func (h *SQLBackend) buildEntries(rule history_model.RuleMeta, states []state.StateTransition, logger log.Logger) []sqlHistoryEntry {
+	entries := make([]sqlHistoryEntry, 0, len(states))
+	for _, s := range states {
+		if !shouldRecord(s) {
+			continue
+		}
+		entry := newLokiEntry(rule, s)
+		line, err := json.Marshal(entry)
+		if err != nil {
+			logger.Error("Failed to construct history record for state, skipping", "error", err)
+			continue
+		}
+		entries = append(entries, sqlHistoryEntry{
+			OrgID:       rule.OrgID,
+			RuleUID:     rule.UID,
+			FolderUID:   rule.NamespaceUID,
+			RuleGroup:   rule.Group,
+			Fingerprint: entry.Fingerprint,
+			Previous:    entry.Previous,
+			Current:     entry.Current,
+			Line:        string(line),
+			EvaluatedAt: s.State.LastEvaluationTime.UnixMilli(),
+		})
+	}
+	return entries
+}
+
+func (h *SQLBackend) insert(ctx context.Context, entries []sqlHistoryEntry) error {
+	return h.db.InTransaction(ctx, func(ctx context.Context) error {
+		return h.db.WithDbSession(ctx, func(sess *db.Session) error {
+			for i := 0; i < len(entries); i += sqlInsertBatchSize {
+				batch := entries[i:min(i+sqlInsertBatchSize, len(entries))]
+				if _, err := sess.Insert(&batch); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Query reads state history from the database and formats it into a dataframe in the same format as the Loki backend.
+func (h *SQLBackend) Query(ctx context.Context, query models.HistoryQuery) (*data.Frame, error) {
+	uids, err := getFolderUIDsForFilter(ctx, h.ac, h.rules, query)
+	if err != nil {
+		return nil, err
+	}
+
+	now := h.clock.Now().UTC()
+	if query.To.IsZero() {
+		query.To = now
+	}
+	if query.From.IsZero() {
+		query.From = now.Add(-defaultQueryRange)
+	}
+	if query.From.After(query.To) {
+		return nil, fmt.Errorf("start time cannot be after end time")
+	}
+	limit := query.Limit
+	if limit < 1 {
+		limit = defaultPageSize
+	}
+
+	streams := make(map[string]*Stream)
+	count := 0
+	// Rows are read from the newest to the oldest, so the limit keeps the most recent entries, like the Loki backend does.
+	// Some filters are applied to the decoded entries, so pages of rows are read until enough entries match.
+	var last *sqlHistoryEntry
+	for count < limit {
+		rows, err := h.queryPage(ctx, query, uids, last)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query state history: %w", err)
+		}
+		for _, row := range rows {
+			if count >= limit {
+				break
+			}
+			var entry LokiEntry
+			if err := json.Unmarshal([]byte(row.Line), &entry); err != nil {
+				h.log.FromContext(ctx).Warn("Skipping state history entry in invalid format", "id", row.ID, "error", err)
+				continue
+			}
+			if !entryMatchesQuery(entry, query) {
+				continue
+			}
+			count++
+
+			key := row.RuleGroup + "\xff" + row.FolderUID
+			s, ok := streams[key]
+			if !ok {
+				s = &Stream{
+					Stream: map[string]string{
+						StateHistoryLabelKey: StateHistoryLabelValue,
+						OrgIDLabel:           fmt.Sprint(row.OrgID),
+						GroupLabel:           row.RuleGroup,
+						FolderUIDLabel:       row.FolderUID,
+					},
+				}
+				streams[key] = s
+			}
+			s.Values = append(s.Values, Sample{T: time.UnixMilli(row.EvaluatedAt), V: row.Line})
+		}
+		if h.cfg.MaxQuerySize <= 0 || len(rows) < h.cfg.MaxQuerySize {
+			break
+		}
+		last = &rows[len(rows)-1]
+	}
+
+	res := QueryRes{Data: QueryData{Result: make([]Stream, 0, len(streams))}}
+	for _, s := range streams {
+		// merge expects samples of a stream in ascending order.
+		sort.SliceStable(s.Values, func(i, j int) bool { return s.Values[i].T.Before(s.Values[j].T) })
+		res.Data.Result = append(res.Data.Result, *s)
+	}
+	return merge(res, nil)
+}
+
+// queryPage reads the rows of the query from the newest to the oldest, at most MaxQuerySize of them, starting after the
+// last row of the previous page.
+func (h *SQLBackend) queryPage(ctx context.Context, query models.HistoryQuery, folderUIDs []string, last *sqlHistoryEntry) ([]sqlHistoryEntry, error) {
+	var rows []sqlHistoryEntry
+	err := h.db.WithDbSession(ctx, func(sess *db.Session) error {
+		q := sess.Where("org_id = ? AND evaluated_at >= ? AND evaluated_at <= ?", query.OrgID, query.From.UnixMilli(), query.To.UnixMilli())
+		if query.RuleUID != "" {
+			q = q.And("rule_uid = ?", query.RuleUID)
+		}
+		if len(folderUIDs) > 0 {
+			q = q.In("folder_uid", folderUIDs)
+		}
+		if last != nil {
+			q = q.And("(evaluated_at < ? OR (evaluated_at = ? AND id < ?))", last.EvaluatedAt, last.EvaluatedAt, last.ID)
+		}
+		if h.cfg.MaxQuerySize > 0 {
+			q = q.Limit(h.cfg.MaxQuerySize)
+		}
+		return q.Desc("evaluated_at", "id").Find(&rows)
+	})
+	return rows, err
+}
+
+// entryMatchesQuery applies the filters of the query that cannot be expressed in SQL because they require decoding the entry.
+func entryMatchesQuery(entry LokiEntry, query models.HistoryQuery) bool {
+	if query.DashboardUID != "" && entry.DashboardUID != query.DashboardUID {
+		return false
+	}
+	if query.PanelID != 0 && entry.PanelID != query.PanelID {
+		return false
+	}
+	for k, v := range query.Labels {
+		if entry.InstanceLabels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Run periodically removes entries that are past retention or above the maximum number of entries, until the context is canceled.
+func (h *SQLBackend) Run(ctx context.Context) error {
+	if h.cfg.CompactionInterval <= 0 {
+		return nil
+	}
+	ticker := h.clock.Ticker(h.cfg.CompactionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			deleted, err := h.Compact(ctx)
+			if err != nil {
+				h.log.Error("Failed to compact alert state history", "error", err)
+				continue
+			}
+			h.log.Debug("Compacted alert state history", "deleted", deleted)
+		}
+	}
+}
+
+// Compact deletes entries that are past retention and, if configured, the oldest entries of organizations that have more entries than allowed.
+// It returns the number of deleted entries.
+func (h *SQLBackend) Compact(ctx context.Context) (int64, error) {
+	now := h.clock.Now()
+	var deleted int64
+	err := h.db.WithDbSession(ctx, func(sess *db.Session) error {
+		exec := func(query string, args ...any) error {
+			res, err := sess.Exec(append([]any{query}, args...)...)
+			if err != nil {
+				return err
+			}
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			deleted += affected
+			return nil
+		}
+
+		overridden := make([]any, 0, len(h.cfg.OrgRetention))
+		for orgID, retention := range h.cfg.OrgRetention {
+			overridden = append(overridden, orgID)
+			if retention <= 0 {
+				continue
+			}
+			if err := exec("DELETE FROM alert_state_history WHERE org_id = ? AND evaluated_at < ?", orgID, now.Add(-retention).UnixMilli()); err != nil {
+				return fmt.Errorf("failed to delete expired entries of organization %d: %w", orgID, err)
+			}
+		}
+
+		if h.cfg.Retention > 0 {
+			query := "DELETE FROM alert_state_history WHERE evaluated_at < ?"
+			args := []any{now.Add(-h.cfg.Retention).UnixMilli()}
+			if len(overridden) > 0 {
+				query += " AND org_id NOT IN (?" + strings.Repeat(",?", len(overridden)-1) + ")"
+				args = append(args, overridden...)
+			}
+			if err := exec(query, args...); err != nil {
+				return fmt.Errorf("failed to delete expired entries: %w", err)
+			}
+		}
+
+		if h.cfg.MaxEntriesPerOrg <= 0 {
+			return nil
+		}
+		var orgIDs []int64
+		if err := sess.Table(sqlHistoryEntry{}.TableName()).Distinct("org_id").Find(&orgIDs); err != nil {
+			return fmt.Errorf("failed to list organizations: %w", err)
+		}
+		for _, orgID := range orgIDs {
+			// Find the newest entry that is above the limit. It and all entries older than it are deleted.
+			var ids []int64
+			err := sess.Table(sqlHistoryEntry{}.TableName()).Cols("id").Where("org_id = ?", orgID).Desc("id").Limit(1, h.cfg.MaxEntriesPerOrg).Find(&ids)
+			if err != nil {
+				return fmt.Errorf("failed to find entries above the limit for organization %d: %w", orgID, err)
+			}
+			if len(ids) == 0 {
+				continue
+			}
+			if err := exec("DELETE FROM alert_state_history WHERE org_id = ? AND id <= ?", orgID, ids[0]); err != nil {
+				return fmt.Errorf("failed to delete entries above the limit for organization %d: %w", orgID, err)
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
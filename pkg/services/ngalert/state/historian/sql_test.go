@@ -0,0 +1,201 @@
+package historian
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	acfakes "github.com/grafana/grafana/pkg/services/ngalert/accesscontrol/fakes"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func TestIntegrationSQLBackend(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	rule := createTestRule()
+
+	t.Run("should record and query state transitions", func(t *testing.T) {
+		h := createTestSQLBackend(t, SQLConfig{}, now)
+
+		states := []state.StateTransition{
+			sqlTransition(data.Labels{"instance": "a"}, eval.Alerting, eval.Normal, now.Add(-2*time.Minute)),
+			sqlTransition(data.Labels{"instance": "b"}, eval.Pending, eval.Normal, now.Add(-time.Minute)),
+			// Transitions from Normal to Normal are not recorded.
+			sqlTransition(data.Labels{"instance": "c"}, eval.Normal, eval.Normal, now.Add(-time.Minute)),
+		}
+		require.NoError(t, <-h.Record(context.Background(), rule, states))
+
+		frame, err := h.Query(context.Background(), models.HistoryQuery{
+			OrgID:        rule.OrgID,
+			RuleUID:      rule.UID,
+			SignedInUser: &identity.StaticRequester{OrgID: rule.OrgID},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, frame.Rows())
+
+		first := requireFrameEntry(t, frame, 0)
+		require.Equal(t, "Normal", first.Previous)
+		require.Equal(t, "Alerting", first.Current)
+		require.Equal(t, "a", first.InstanceLabels["instance"])
+	})
+
+	t.Run("should filter by labels and limit the result to the most recent entries", func(t *testing.T) {
+		h := createTestSQLBackend(t, SQLConfig{}, now)
+
+		states := make([]state.StateTransition, 0, 10)
+		for i := 0; i < 10; i++ {
+			team := "a"
+			if i%2 == 1 {
+				team = "b"
+			}
+			states = append(states, sqlTransition(data.Labels{"team": team, "idx": string(rune('0' + i))}, eval.Alerting, eval.Normal, now.Add(-time.Duration(10-i)*time.Minute)))
+		}
+		require.NoError(t, <-h.Record(context.Background(), rule, states))
+
+		frame, err := h.Query(context.Background(), models.HistoryQuery{
+			OrgID:        rule.OrgID,
+			Labels:       map[string]string{"team": "a"},
+			Limit:        2,
+			SignedInUser: &identity.StaticRequester{OrgID: rule.OrgID},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, frame.Rows())
+		for i := 0; i < frame.Rows(); i++ {
+			entry := requireFrameEntry(t, frame, i)
+			require.Equal(t, "a", entry.InstanceLabels["team"])
+			require.Contains(t, []string{"6", "8"}, entry.InstanceLabels["idx"])
+		}
+	})
+
+	t.Run("should read further rows until enough entries match the filters", func(t *testing.T) {
+		h := createTestSQLBackend(t, SQLConfig{MaxQuerySize: 3}, now)
+
+		states := make([]state.StateTransition, 0, 10)
+		for i := 0; i < 10; i++ {
+			team := "b"
+			if i < 2 {
+				team = "a"
+			}
+			states = append(states, sqlTransition(data.Labels{"team": team, "idx": string(rune('0' + i))}, eval.Alerting, eval.Normal, now.Add(-time.Duration(10-i)*time.Minute)))
+		}
+		// Entries evaluated at the same time are read once.
+		states = append(states, sqlTransition(data.Labels{"team": "a", "idx": "x"}, eval.Alerting, eval.Normal, now.Add(-10*time.Minute)))
+		require.NoError(t, <-h.Record(context.Background(), rule, states))
+
+		frame, err := h.Query(context.Background(), models.HistoryQuery{
+			OrgID:        rule.OrgID,
+			Labels:       map[string]string{"team": "a"},
+			SignedInUser: &identity.StaticRequester{OrgID: rule.OrgID},
+		})
+		require.NoError(t, err)
+		idx := make([]string, 0, frame.Rows())
+		for i := 0; i < frame.Rows(); i++ {
+			idx = append(idx, requireFrameEntry(t, frame, i).InstanceLabels["idx"])
+		}
+		require.ElementsMatch(t, []string{"0", "1", "x"}, idx)
+
+		frame, err = h.Query(context.Background(), models.HistoryQuery{
+			OrgID:        rule.OrgID,
+			Labels:       map[string]string{"team": "a"},
+			Limit:        1,
+			SignedInUser: &identity.StaticRequester{OrgID: rule.OrgID},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, frame.Rows())
+		require.Equal(t, "1", requireFrameEntry(t, frame, 0).InstanceLabels["idx"])
+	})
+
+	t.Run("should compact entries past retention and above the limit", func(t *testing.T) {
+		h := createTestSQLBackend(t, SQLConfig{
+			Retention:        time.Hour,
+			OrgRetention:     map[int64]time.Duration{2: 24 * time.Hour},
+			MaxEntriesPerOrg: 3,
+		}, now)
+
+		old := sqlTransition(data.Labels{"instance": "old"}, eval.Alerting, eval.Normal, now.Add(-2*time.Hour))
+		require.NoError(t, <-h.Record(context.Background(), rule, []state.StateTransition{old}))
+		otherOrg := rule
+		otherOrg.OrgID = 2
+		require.NoError(t, <-h.Record(context.Background(), otherOrg, []state.StateTransition{old}))
+
+		recent := make([]state.StateTransition, 0, 5)
+		for i := 0; i < 5; i++ {
+			recent = append(recent, sqlTransition(data.Labels{"instance": string(rune('a' + i))}, eval.Alerting, eval.Normal, now.Add(-time.Duration(5-i)*time.Minute)))
+		}
+		require.NoError(t, <-h.Record(context.Background(), rule, recent))
+
+		deleted, err := h.Compact(context.Background())
+		require.NoError(t, err)
+		// One expired entry in org 1 and two entries above the limit. The entry of org 2 is kept by the org retention.
+		require.EqualValues(t, 3, deleted)
+
+		require.Equal(t, int64(3), countSQLEntries(t, h, 1))
+		require.Equal(t, int64(1), countSQLEntries(t, h, 2))
+	})
+}
+
+func createTestSQLBackend(t *testing.T, cfg SQLConfig, now time.Time) *SQLBackend {
+	t.Helper()
+	store := db.InitTestDB(t)
+	met := metrics.NewHistorianMetrics(prometheus.NewRegistry(), metrics.Subsystem)
+	ac := &acfakes.FakeRuleService{}
+	ac.CanReadAllRulesFunc = func(ctx context.Context, user identity.Requester) (bool, error) {
+		return true, nil
+	}
+	h := NewSQLBackend(log.NewNopLogger(), cfg, store, fakes.NewRuleStore(t), met, ac)
+	clk := clock.NewMock()
+	clk.Set(now)
+	h.clock = clk
+	return h
+}
+
+func countSQLEntries(t *testing.T, h *SQLBackend, orgID int64) int64 {
+	t.Helper()
+	var count int64
+	err := h.db.WithDbSession(context.Background(), func(sess *db.Session) error {
+		var err error
+		count, err = sess.Where("org_id = ?", orgID).Count(&sqlHistoryEntry{})
+		return err
+	})
+	require.NoError(t, err)
+	return count
+}
+
+func sqlTransition(lbls data.Labels, current, previous eval.State, at time.Time) state.StateTransition {
+	return state.StateTransition{
+		State: &state.State{
+			Labels:             lbls,
+			State:              current,
+			LastEvaluationTime: at,
+		},
+		PreviousState: previous,
+	}
+}
+
+func requireFrameEntry(t *testing.T, frame *data.Frame, idx int) LokiEntry {
+	t.Helper()
+	line, ok := frame.Fields[1].At(idx).(json.RawMessage)
+	require.True(t, ok)
+	return requireEntry(t, Sample{V: string(line)})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/url"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/benbjohnson/clock"
@@ -57,6 +58,16 @@ type Manager struct {
 	rulesPerRuleGroupLimit         int64
 
 	persister StatePersister
+
+	// warmRulesLazily is true if the state of a rule is loaded in the background after startup, or when the rule
+	// is first evaluated if that happens earlier, instead of on startup.
+	warmRulesLazily bool
+	// warmingMtx serializes loading the state of rules, so that the state of a rule is not loaded after it was
+	// already loaded and changed by an evaluation. A rule is marked as warmed under it once its state is loaded, so
+	// evaluations wait for a load in progress.
+	warmingMtx  sync.Mutex
+	warmedMtx   sync.Mutex
+	warmedRules map[ngModels.AlertRuleKey]struct{}
 }
 
 type ManagerCfg struct {
@@ -80,6 +91,11 @@ type ManagerCfg struct {
 	// Duration for which a resolved alert state transition will continue to be sent to the Alertmanager.
 	ResolvedRetention time.Duration
 
+	// WarmRulesLazily makes the state manager load the state of the rules from the instance store in the background
+	// after startup, or when a rule is evaluated for the first time, instead of loading the state of all rules
+	// before startup completes.
+	WarmRulesLazily bool
+
 	Tracer tracing.Tracer
 	Log    log.Logger
 }
@@ -108,6 +124,8 @@ func NewManager(cfg ManagerCfg, statePersister StatePersister) *Manager {
 		rulesPerRuleGroupLimit:         cfg.RulesPerRuleGroupLimit,
 		persister:                      statePersister,
 		tracer:                         cfg.Tracer,
+		warmRulesLazily:                cfg.WarmRulesLazily,
+		warmedRules:                    make(map[ngModels.AlertRuleKey]struct{}),
 	}
 
 	if m.applyNoDataAndErrorToAllStates {
@@ -127,6 +145,11 @@ func (st *Manager) Warm(ctx context.Context, rulesReader RuleReader) {
 		st.log.Info("Skip warming the state because instance store is not configured")
 		return
 	}
+	if st.warmRulesLazily {
+		st.log.Info("Warming the state of rules in the background")
+		go st.warmInBackground(ctx, rulesReader)
+		return
+	}
 	startTime := time.Now()
 	st.log.Info("Warming state cache for startup")
 
@@ -192,32 +215,8 @@ func (st *Manager) Warm(ctx context.Context, rulesReader RuleReader) {
 				orgStates[entry.RuleUID] = rulesStates
 			}
 
-			lbs := map[string]string(entry.Labels)
-			cacheID := entry.Labels.Fingerprint()
-			var resultFp data.Fingerprint
-			if entry.ResultFingerprint != "" {
-				fp, err := strconv.ParseUint(entry.ResultFingerprint, 16, 64)
-				if err != nil {
-					st.log.Error("Failed to parse result fingerprint of alert instance", "error", err, "ruleUID", entry.RuleUID)
-				}
-				resultFp = data.Fingerprint(fp)
-			}
-			rulesStates.states[cacheID] = &State{
-				AlertRuleUID:         entry.RuleUID,
-				OrgID:                entry.RuleOrgID,
-				CacheID:              cacheID,
-				Labels:               lbs,
-				State:                translateInstanceState(entry.CurrentState),
-				StateReason:          entry.CurrentReason,
-				LastEvaluationString: "",
-				StartsAt:             entry.CurrentStateSince,
-				EndsAt:               entry.CurrentStateEnd,
-				LastEvaluationTime:   entry.LastEvalTime,
-				Annotations:          ruleForEntry.Annotations,
-				ResultFingerprint:    resultFp,
-				ResolvedAt:           entry.ResolvedAt,
-				LastSentAt:           entry.LastSentAt,
-			}
+			s := st.stateFromInstance(entry, ruleForEntry)
+			rulesStates.states[s.CacheID] = s
 			statesCount++
 		}
 	}
@@ -226,6 +225,177 @@ func (st *Manager) Warm(ctx context.Context, rulesReader RuleReader) {
 	st.log.Info("State cache has been initialized", "states", statesCount, "duration", time.Since(startTime))
 }
 
+// warmInBackground loads the state of the rules of the organizations that have state in the instance store, unless
+// the state of a rule was already loaded because the rule was evaluated, so that the state can be read before the
+// rules are evaluated.
+func (st *Manager) warmInBackground(ctx context.Context, rulesReader RuleReader) {
+	startTime := time.Now()
+	orgIds, err := st.instanceStore.FetchOrgIds(ctx)
+	if err != nil {
+		st.log.Error("Unable to fetch orgIds", "error", err)
+		return
+	}
+	for _, orgId := range orgIds {
+		alertRules, err := rulesReader.ListAlertRules(ctx, &ngModels.ListAlertRulesQuery{OrgID: orgId})
+		if err != nil {
+			st.log.Error("Unable to fetch rules to warm their state", "org_id", orgId, "error", err)
+			continue
+		}
+		for _, rule := range alertRules {
+			if ctx.Err() != nil {
+				return
+			}
+			st.warmRuleIfNeeded(ctx, rule)
+		}
+	}
+	st.log.Info("State of rules has been loaded in the background", "duration", time.Since(startTime))
+}
+
+// WarmRule replaces the cached state of the rule with the state persisted in the instance store.
+// It is used when the evaluation of a rule moves to this instance from another instance of the cluster.
+func (st *Manager) WarmRule(ctx context.Context, rule *ngModels.AlertRule) {
+	if st.instanceStore == nil {
+		return
+	}
+	st.warmingMtx.Lock()
+	defer st.warmingMtx.Unlock()
+	st.warmRule(ctx, rule)
+}
+
+// warmRule loads the state of the rule, it must be called with warmingMtx held. The rule is marked as warmed
+// even if loading fails, so that an evaluation that ran meanwhile is not overwritten by a later load.
+func (st *Manager) warmRule(ctx context.Context, rule *ngModels.AlertRule) {
+	defer st.setWarmed(rule.GetKey(), true)
+	logger := st.log.FromContext(ctx).New(rule.GetKey().LogContext()...)
+	alertInstances, err := st.instanceStore.ListAlertInstances(ctx, &ngModels.ListAlertInstancesQuery{
+		RuleOrgID: rule.OrgID,
+		RuleUID:   rule.UID,
+	})
+	if err != nil {
+		logger.Error("Unable to fetch previous state of the rule", "error", err)
+		return
+	}
+
+	rs := &ruleStates{states: make(map[data.Fingerprint]*State, len(alertInstances))}
+	for _, entry := range alertInstances {
+		s := st.stateFromInstance(entry, rule)
+		rs.states[s.CacheID] = s
+	}
+	st.cache.setRuleStates(rule.GetKey(), rs)
+	logger.Debug("State of the rule has been loaded", "states", len(rs.states))
+}
+
+// RefreshRules replaces the cached state of the rules with the state in the instance store. It is used for the rules
+// that other instances of the cluster evaluate, so that their state can still be read from this instance.
+// The state of the rules of an organization is read with a single query.
+func (st *Manager) RefreshRules(ctx context.Context, rules []*ngModels.AlertRule) {
+	if st.instanceStore == nil || len(rules) == 0 {
+		return
+	}
+	byOrg := make(map[int64]map[string]*ngModels.AlertRule)
+	for _, rule := range rules {
+		if byOrg[rule.OrgID] == nil {
+			byOrg[rule.OrgID] = make(map[string]*ngModels.AlertRule)
+		}
+		byOrg[rule.OrgID][rule.UID] = rule
+	}
+	for orgID, orgRules := range byOrg {
+		query := &ngModels.ListAlertInstancesQuery{RuleOrgID: orgID}
+		if len(orgRules) == 1 {
+			for uid := range orgRules {
+				query.RuleUID = uid
+			}
+		}
+		alertInstances, err := st.instanceStore.ListAlertInstances(ctx, query)
+		if err != nil {
+			st.log.FromContext(ctx).Error("Unable to refresh the state of rules", "org_id", orgID, "error", err)
+			continue
+		}
+		states := make(map[string]*ruleStates, len(orgRules))
+		for uid := range orgRules {
+			states[uid] = &ruleStates{states: make(map[data.Fingerprint]*State)}
+		}
+		for _, entry := range alertInstances {
+			rule, ok := orgRules[entry.RuleUID]
+			if !ok {
+				continue
+			}
+			s := st.stateFromInstance(entry, rule)
+			states[entry.RuleUID].states[s.CacheID] = s
+		}
+		for uid, rs := range states {
+			st.cache.setRuleStates(ngModels.AlertRuleKey{OrgID: orgID, UID: uid}, rs)
+		}
+	}
+}
+
+// ForgetRule removes the state of the rule from the cache but, unlike DeleteStateByRuleUID, keeps it in the
+// instance store and does not resolve the alerts. It is used for deleted rules that another instance of the cluster
+// evaluated, which resolves their alerts.
+func (st *Manager) ForgetRule(ruleKey ngModels.AlertRuleKey) {
+	st.warmingMtx.Lock()
+	defer st.warmingMtx.Unlock()
+	st.cache.removeByRuleUID(ruleKey.OrgID, ruleKey.UID)
+	st.setWarmed(ruleKey, false)
+}
+
+// warmRuleIfNeeded loads the state of the rule if the state is loaded lazily and was not loaded yet.
+func (st *Manager) warmRuleIfNeeded(ctx context.Context, rule *ngModels.AlertRule) {
+	if !st.warmRulesLazily || st.instanceStore == nil || st.isWarmed(rule.GetKey()) {
+		return
+	}
+	st.warmingMtx.Lock()
+	defer st.warmingMtx.Unlock()
+	if st.isWarmed(rule.GetKey()) {
+		return
+	}
+	st.warmRule(ctx, rule)
+}
+
+func (st *Manager) isWarmed(key ngModels.AlertRuleKey) bool {
+	st.warmedMtx.Lock()
+	defer st.warmedMtx.Unlock()
+	_, ok := st.warmedRules[key]
+	return ok
+}
+
+func (st *Manager) setWarmed(key ngModels.AlertRuleKey, warmed bool) {
+	st.warmedMtx.Lock()
+	defer st.warmedMtx.Unlock()
+	if warmed {
+		st.warmedRules[key] = struct{}{}
+	} else {
+		delete(st.warmedRules, key)
+	}
+}
+
+func (st *Manager) stateFromInstance(entry *ngModels.AlertInstance, rule *ngModels.AlertRule) *State {
+	var resultFp data.Fingerprint
+	if entry.ResultFingerprint != "" {
+		fp, err := strconv.ParseUint(entry.ResultFingerprint, 16, 64)
+		if err != nil {
+			st.log.Error("Failed to parse result fingerprint of alert instance", "error", err, "ruleUID", entry.RuleUID)
+		}
+		resultFp = data.Fingerprint(fp)
+	}
+	return &State{
+		AlertRuleUID:         entry.RuleUID,
+		OrgID:                entry.RuleOrgID,
+		CacheID:              entry.Labels.Fingerprint(),
+		Labels:               map[string]string(entry.Labels),
+		State:                translateInstanceState(entry.CurrentState),
+		StateReason:          entry.CurrentReason,
+		LastEvaluationString: "",
+		StartsAt:             entry.CurrentStateSince,
+		EndsAt:               entry.CurrentStateEnd,
+		LastEvaluationTime:   entry.LastEvalTime,
+		Annotations:          rule.Annotations,
+		ResultFingerprint:    resultFp,
+		ResolvedAt:           entry.ResolvedAt,
+		LastSentAt:           entry.LastSentAt,
+	}
+}
+
 func (st *Manager) Get(orgID int64, alertRuleUID string, stateId data.Fingerprint) *State {
 	return st.cache.get(orgID, alertRuleUID, stateId)
 }
@@ -237,9 +407,20 @@ func (st *Manager) DeleteStateByRuleUID(ctx context.Context, ruleKey ngModels.Al
 	logger := st.log.FromContext(ctx)
 	logger.Debug("Resetting state of the rule")
 
+	st.warmingMtx.Lock()
 	states := st.cache.removeByRuleUID(ruleKey.OrgID, ruleKey.UID)
+	warmed := st.isWarmed(ruleKey)
+	st.setWarmed(ruleKey, false)
+	st.warmingMtx.Unlock()
 
 	if len(states) == 0 {
+		// The state of a rule that was never evaluated by this instance is not in the cache when it is loaded lazily,
+		// but it can still be in the instance store.
+		if st.warmRulesLazily && !warmed && st.instanceStore != nil {
+			if err := st.instanceStore.DeleteAlertInstancesByRule(ctx, ruleKey); err != nil {
+				logger.Error("Failed to delete states that belong to a rule from database", "error", err)
+			}
+		}
 		return nil
 	}
 
@@ -323,6 +504,7 @@ func (st *Manager) ProcessEvalResults(
 
 	logger := st.log.FromContext(ctx)
 	logger.Debug("State manager processing evaluation results", "resultCount", len(results))
+	st.warmRuleIfNeeded(ctx, alertRule)
 	states := st.setNextStateForRule(ctx, alertRule, results, extraLabels, logger)
 
 	staleStates := st.deleteStaleStatesFromCache(ctx, logger, evaluatedAt, alertRule)
@@ -9,6 +9,7 @@ import (
 	"math/rand"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -24,17 +25,21 @@ import (
 
 	"github.com/grafana/grafana/pkg/expr"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
 	"github.com/grafana/grafana/pkg/infra/tracing"
 	"github.com/grafana/grafana/pkg/services/annotations"
 	"github.com/grafana/grafana/pkg/services/annotations/annotationstest"
 	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	acfakes "github.com/grafana/grafana/pkg/services/ngalert/accesscontrol/fakes"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/state"
 	"github.com/grafana/grafana/pkg/services/ngalert/state/historian"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
 	"github.com/grafana/grafana/pkg/services/ngalert/tests"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
 	"github.com/grafana/grafana/pkg/tests/testsuite"
 	"github.com/grafana/grafana/pkg/util"
 )
@@ -245,6 +250,180 @@ func TestWarmStateCache(t *testing.T) {
 	})
 }
 
+func TestWarmRulesLazily(t *testing.T) {
+	ctx := context.Background()
+	evaluationTime := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	instanceStore := store.NewRemoteCacheInstanceStore(remotecache.NewFakeCacheStorage(), featuremgmt.WithFeatures(), log.NewNopLogger())
+	rule := models.RuleGen.With(models.RuleGen.WithOrgID(1), models.RuleGen.WithInterval(time.Minute)).GenerateRef()
+
+	labels := models.InstanceLabels{"test1": "testValue1"}
+	_, hash, _ := labels.StringAndHash()
+	require.NoError(t, instanceStore.SaveAlertInstance(ctx, models.AlertInstance{
+		AlertInstanceKey:  models.AlertInstanceKey{RuleOrgID: rule.OrgID, RuleUID: rule.UID, LabelsHash: hash},
+		Labels:            labels,
+		CurrentState:      models.InstanceStateFiring,
+		LastEvalTime:      evaluationTime,
+		CurrentStateSince: evaluationTime.Add(-time.Minute),
+		CurrentStateEnd:   evaluationTime.Add(time.Minute),
+	}))
+
+	st := state.NewManager(state.ManagerCfg{
+		Metrics:         metrics.NewNGAlert(prometheus.NewPedanticRegistry()).GetStateMetrics(),
+		InstanceStore:   instanceStore,
+		Images:          &state.NoopImageService{},
+		Clock:           clock.NewMock(),
+		Historian:       &state.FakeHistorian{},
+		Tracer:          tracing.InitializeTracerForTest(),
+		Log:             log.New("ngalert.state.manager"),
+		WarmRulesLazily: true,
+	}, state.NewNoopPersister())
+	// The rule is not returned by the rule reader, so its state is not loaded in the background either.
+	rules := &listedRuleReader{listed: make(chan struct{})}
+	st.Warm(ctx, rules)
+	require.Empty(t, st.GetStatesForRuleUID(rule.OrgID, rule.UID), "state should not be loaded on startup")
+	<-rules.listed
+
+	st.ProcessEvalResults(ctx, evaluationTime, rule, eval.Results{{
+		Instance:    data.Labels{"test2": "testValue2"},
+		State:       eval.Normal,
+		EvaluatedAt: evaluationTime,
+	}}, nil, nil)
+	loaded := st.Get(rule.OrgID, rule.UID, labels.Fingerprint())
+	require.NotNil(t, loaded, "state should be loaded when the rule is first evaluated")
+	require.Equal(t, eval.Alerting, loaded.State)
+
+	t.Run("state of a rule that was not evaluated is deleted from the store", func(t *testing.T) {
+		other := models.RuleGen.With(models.RuleGen.WithOrgID(1)).GenerateRef()
+		require.NoError(t, instanceStore.SaveAlertInstance(ctx, models.AlertInstance{
+			AlertInstanceKey: models.AlertInstanceKey{RuleOrgID: other.OrgID, RuleUID: other.UID, LabelsHash: hash},
+			Labels:           labels,
+			CurrentState:     models.InstanceStateFiring,
+		}))
+		st.DeleteStateByRuleUID(ctx, other.GetKey(), models.StateReasonRuleDeleted)
+		instances, err := instanceStore.ListAlertInstances(ctx, &models.ListAlertInstancesQuery{RuleOrgID: other.OrgID, RuleUID: other.UID})
+		require.NoError(t, err)
+		require.Empty(t, instances)
+	})
+}
+
+// listedRuleReader returns no rules and closes listed when rules are listed for the first time.
+type listedRuleReader struct {
+	listed chan struct{}
+	once   sync.Once
+}
+
+func (r *listedRuleReader) ListAlertRules(_ context.Context, _ *models.ListAlertRulesQuery) (models.RulesGroup, error) {
+	r.once.Do(func() { close(r.listed) })
+	return nil, nil
+}
+
+func TestWarmRulesInBackground(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	instanceStore := store.NewRemoteCacheInstanceStore(remotecache.NewFakeCacheStorage(), featuremgmt.WithFeatures(), log.NewNopLogger())
+	rule := models.RuleGen.With(models.RuleGen.WithOrgID(1)).GenerateRef()
+	rules := fakes.NewRuleStore(t)
+	rules.PutRule(ctx, rule)
+
+	labels := models.InstanceLabels{"test1": "testValue1"}
+	_, hash, _ := labels.StringAndHash()
+	require.NoError(t, instanceStore.SaveAlertInstance(ctx, models.AlertInstance{
+		AlertInstanceKey: models.AlertInstanceKey{RuleOrgID: rule.OrgID, RuleUID: rule.UID, LabelsHash: hash},
+		Labels:           labels,
+		CurrentState:     models.InstanceStateFiring,
+	}))
+
+	st := state.NewManager(state.ManagerCfg{
+		Metrics:         metrics.NewNGAlert(prometheus.NewPedanticRegistry()).GetStateMetrics(),
+		InstanceStore:   instanceStore,
+		Images:          &state.NoopImageService{},
+		Clock:           clock.NewMock(),
+		Historian:       &state.FakeHistorian{},
+		Tracer:          tracing.InitializeTracerForTest(),
+		Log:             log.New("ngalert.state.manager"),
+		WarmRulesLazily: true,
+	}, state.NewNoopPersister())
+	st.Warm(ctx, rules)
+
+	require.Eventually(t, func() bool {
+		return st.Get(rule.OrgID, rule.UID, labels.Fingerprint()) != nil
+	}, 5*time.Second, 10*time.Millisecond, "state of a rule that was not evaluated should be loaded in the background")
+}
+
+func TestWarmRulesInBackgroundWhileEvaluating(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	evaluationTime := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	instanceStore := &slowInstanceStore{
+		InstanceStore: store.NewRemoteCacheInstanceStore(remotecache.NewFakeCacheStorage(), featuremgmt.WithFeatures(), log.NewNopLogger()),
+		listing:       make(chan struct{}),
+		release:       make(chan struct{}),
+	}
+	rule := models.RuleGen.With(models.RuleGen.WithOrgID(1), models.RuleGen.WithInterval(time.Minute)).GenerateRef()
+	rules := fakes.NewRuleStore(t)
+	rules.PutRule(ctx, rule)
+
+	labels := models.InstanceLabels{"test1": "testValue1"}
+	_, hash, _ := labels.StringAndHash()
+	require.NoError(t, instanceStore.SaveAlertInstance(ctx, models.AlertInstance{
+		AlertInstanceKey: models.AlertInstanceKey{RuleOrgID: rule.OrgID, RuleUID: rule.UID, LabelsHash: hash},
+		Labels:           labels,
+		CurrentState:     models.InstanceStateFiring,
+		LastEvalTime:     evaluationTime,
+	}))
+
+	st := state.NewManager(state.ManagerCfg{
+		Metrics:         metrics.NewNGAlert(prometheus.NewPedanticRegistry()).GetStateMetrics(),
+		InstanceStore:   instanceStore,
+		Images:          &state.NoopImageService{},
+		Clock:           clock.NewMock(),
+		Historian:       &state.FakeHistorian{},
+		Tracer:          tracing.InitializeTracerForTest(),
+		Log:             log.New("ngalert.state.manager"),
+		WarmRulesLazily: true,
+	}, state.NewNoopPersister())
+	st.Warm(ctx, rules)
+	<-instanceStore.listing
+
+	evaluated := make(chan struct{})
+	go func() {
+		defer close(evaluated)
+		st.ProcessEvalResults(ctx, evaluationTime.Add(time.Minute), rule, eval.Results{{
+			Instance:    data.Labels{"test2": "testValue2"},
+			State:       eval.Alerting,
+			EvaluatedAt: evaluationTime.Add(time.Minute),
+		}}, nil, nil)
+	}()
+	select {
+	case <-evaluated:
+		t.Fatal("evaluation should wait for the state of the rule to be loaded")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(instanceStore.release)
+	<-evaluated
+
+	var evaluatedLabels []string
+	for _, s := range st.GetStatesForRuleUID(rule.OrgID, rule.UID) {
+		evaluatedLabels = append(evaluatedLabels, s.Labels["test1"]+s.Labels["test2"])
+	}
+	require.ElementsMatch(t, []string{"testValue1", "testValue2"}, evaluatedLabels, "state of the evaluation should not be overwritten by the loaded state")
+}
+
+// slowInstanceStore blocks listing alert instances until release is closed, and closes listing when they are
+// listed for the first time.
+type slowInstanceStore struct {
+	state.InstanceStore
+	listing chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (s *slowInstanceStore) ListAlertInstances(ctx context.Context, cmd *models.ListAlertInstancesQuery) ([]*models.AlertInstance, error) {
+	s.once.Do(func() { close(s.listing) })
+	<-s.release
+	return s.InstanceStore.ListAlertInstances(ctx, cmd)
+}
+
 func TestDashboardAnnotations(t *testing.T) {
 	evaluationTime, err := time.Parse("2006-01-02", "2022-01-01")
 	require.NoError(t, err)
@@ -17,6 +17,35 @@ type InstanceStore interface {
 	FullSync(ctx context.Context, instances []models.AlertInstance) error
 }
 
+// RuleInstanceWriter is implemented by instance stores that save the changes to the instances of a rule at once.
+// SyncStatePersister uses it instead of saving and deleting the instances one by one.
+type RuleInstanceWriter interface {
+	SaveRuleInstances(ctx context.Context, key models.AlertRuleKey, instances []models.AlertInstance, deleted []models.AlertInstanceKey) error
+}
+
+// CopyInstances replaces the alert instances in the destination store with the alert instances of all
+// organizations in the source store. It returns the number of copied instances.
+func CopyInstances(ctx context.Context, from, to InstanceStore) (int, error) {
+	orgIDs, err := from.FetchOrgIds(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var instances []models.AlertInstance
+	for _, orgID := range orgIDs {
+		orgInstances, err := from.ListAlertInstances(ctx, &models.ListAlertInstancesQuery{RuleOrgID: orgID})
+		if err != nil {
+			return 0, err
+		}
+		for _, instance := range orgInstances {
+			instances = append(instances, *instance)
+		}
+	}
+	if err := to.FullSync(ctx, instances); err != nil {
+		return 0, err
+	}
+	return len(instances), nil
+}
+
 // RuleReader represents the ability to fetch alert rules.
 type RuleReader interface {
 	ListAlertRules(ctx context.Context, query *models.ListAlertRulesQuery) (models.RulesGroup, error)
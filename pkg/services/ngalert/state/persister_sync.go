@@ -36,6 +36,12 @@ func (a *SyncStatePersister) Async(_ context.Context, _ *cache) {
 
 // Sync persists the state transitions to the database. It deletes stale states and saves the current states.
 func (a *SyncStatePersister) Sync(ctx context.Context, span trace.Span, allStates StateTransitions) {
+	if w, ok := a.store.(RuleInstanceWriter); ok {
+		a.saveRuleStates(ctx, w, allStates)
+		span.AddEvent("updated database")
+		return
+	}
+
 	staleStates := allStates.StaleStates()
 	if len(staleStates) > 0 {
 		a.deleteAlertStates(ctx, staleStates)
@@ -79,34 +85,12 @@ func (a *SyncStatePersister) saveAlertStates(ctx context.Context, states ...Stat
 	saveState := func(ctx context.Context, idx int) error {
 		s := states[idx]
 
-		// Do not save stale state to database.
-		if s.IsStale() {
-			return nil
-		}
-
-		// Do not save normal state to database and remove transition to Normal state but keep mapped states
-		if a.doNotSaveNormalState && IsNormalStateWithNoReason(s.State) && !s.Changed() {
+		instance, ok := a.instanceToSave(s)
+		if !ok {
 			return nil
 		}
 
-		key, err := s.GetAlertInstanceKey()
-		if err != nil {
-			a.log.Error("Failed to create a key for alert state to save it to database. The state will be ignored ", "cacheID", s.CacheID, "error", err, "labels", s.Labels.String())
-			return nil
-		}
-		instance := ngModels.AlertInstance{
-			AlertInstanceKey:  key,
-			Labels:            ngModels.InstanceLabels(s.Labels),
-			CurrentState:      ngModels.InstanceStateType(s.State.State.String()),
-			CurrentReason:     s.StateReason,
-			LastEvalTime:      s.LastEvaluationTime,
-			CurrentStateSince: s.StartsAt,
-			CurrentStateEnd:   s.EndsAt,
-			ResolvedAt:        s.ResolvedAt,
-			LastSentAt:        s.LastSentAt,
-		}
-
-		err = a.store.SaveAlertInstance(ctx, instance)
+		err := a.store.SaveAlertInstance(ctx, instance)
 		if err != nil {
 			a.log.Error("Failed to save alert state", "labels", s.Labels.String(), "state", s.State, "error", err)
 			return nil
@@ -119,3 +103,67 @@ func (a *SyncStatePersister) saveAlertStates(ctx context.Context, states ...Stat
 	_ = concurrency.ForEachJob(ctx, len(states), a.maxStateSaveConcurrency, saveState)
 	a.log.Debug("Saving alert states done", "count", len(states), "max_state_save_concurrency", a.maxStateSaveConcurrency, "duration", time.Since(start))
 }
+
+// saveRuleStates saves the states of a rule and deletes its stale states with one write.
+func (a *SyncStatePersister) saveRuleStates(ctx context.Context, w RuleInstanceWriter, states StateTransitions) {
+	if len(states) == 0 {
+		return
+	}
+	ruleKey := ngModels.AlertRuleKey{OrgID: states[0].OrgID, UID: states[0].AlertRuleUID}
+	var instances []ngModels.AlertInstance
+	var deleted []ngModels.AlertInstanceKey
+	for _, s := range states {
+		if s.IsStale() {
+			key, err := s.GetAlertInstanceKey()
+			if err != nil {
+				a.log.Error("Failed to delete alert instance with invalid labels", "cacheID", s.CacheID, "labels", s.Labels.String(), "error", err)
+				continue
+			}
+			deleted = append(deleted, key)
+			continue
+		}
+		if instance, ok := a.instanceToSave(s); ok {
+			instances = append(instances, instance)
+		}
+	}
+	if len(instances) == 0 && len(deleted) == 0 {
+		return
+	}
+
+	start := time.Now()
+	if err := w.SaveRuleInstances(ctx, ruleKey, instances, deleted); err != nil {
+		a.log.Error("Failed to save alert states", "rule_uid", ruleKey.UID, "error", err)
+		return
+	}
+	a.log.Debug("Saving alert states done", "count", len(instances), "deleted", len(deleted), "duration", time.Since(start))
+}
+
+// instanceToSave returns the alert instance of the state, and false if the state must not be saved.
+func (a *SyncStatePersister) instanceToSave(s StateTransition) (ngModels.AlertInstance, bool) {
+	// Do not save stale state to database.
+	if s.IsStale() {
+		return ngModels.AlertInstance{}, false
+	}
+
+	// Do not save normal state to database and remove transition to Normal state but keep mapped states
+	if a.doNotSaveNormalState && IsNormalStateWithNoReason(s.State) && !s.Changed() {
+		return ngModels.AlertInstance{}, false
+	}
+
+	key, err := s.GetAlertInstanceKey()
+	if err != nil {
+		a.log.Error("Failed to create a key for alert state to save it to database. The state will be ignored ", "cacheID", s.CacheID, "error", err, "labels", s.Labels.String())
+		return ngModels.AlertInstance{}, false
+	}
+	return ngModels.AlertInstance{
+		AlertInstanceKey:  key,
+		Labels:            ngModels.InstanceLabels(s.Labels),
+		CurrentState:      ngModels.InstanceStateType(s.State.State.String()),
+		CurrentReason:     s.StateReason,
+		LastEvalTime:      s.LastEvaluationTime,
+		CurrentStateSince: s.StartsAt,
+		CurrentStateEnd:   s.EndsAt,
+		ResolvedAt:        s.ResolvedAt,
+		LastSentAt:        s.LastSentAt,
+	}, true
+}
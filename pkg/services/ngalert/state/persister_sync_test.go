@@ -101,3 +101,56 @@ func TestSyncPersister_saveAlertStates(t *testing.T) {
 		}
 	})
 }
+
+type fakeRuleInstanceWriter struct {
+	FakeInstanceStore
+	saved   map[ngmodels.AlertRuleKey][]ngmodels.AlertInstance
+	deleted map[ngmodels.AlertRuleKey][]ngmodels.AlertInstanceKey
+}
+
+func (f *fakeRuleInstanceWriter) SaveRuleInstances(_ context.Context, key ngmodels.AlertRuleKey, instances []ngmodels.AlertInstance, deleted []ngmodels.AlertInstanceKey) error {
+	f.saved[key] = append(f.saved[key], instances...)
+	f.deleted[key] = append(f.deleted[key], deleted...)
+	return nil
+}
+
+func TestSyncPersister_saveRuleStates(t *testing.T) {
+	ruleKey := ngmodels.AlertRuleKey{OrgID: 1, UID: "rule"}
+	newTransition := func(s eval.State, reason string, previous eval.State, labelPrefix string) StateTransition {
+		return StateTransition{
+			State: &State{
+				OrgID:        ruleKey.OrgID,
+				AlertRuleUID: ruleKey.UID,
+				State:        s,
+				StateReason:  reason,
+				Labels:       ngmodels.GenerateAlertLabels(5, labelPrefix),
+			},
+			PreviousState: previous,
+		}
+	}
+	alerting := newTransition(eval.Alerting, "", eval.Pending, "alerting-")
+	normal := newTransition(eval.Normal, "", eval.Normal, "normal-")
+	stale := newTransition(eval.Normal, ngmodels.StateReasonMissingSeries, eval.Alerting, "stale-")
+
+	trace := tracing.NewNoopTracerProvider().Tracer("test")
+	_, span := trace.Start(context.Background(), "")
+	st := &fakeRuleInstanceWriter{
+		saved:   map[ngmodels.AlertRuleKey][]ngmodels.AlertInstance{},
+		deleted: map[ngmodels.AlertRuleKey][]ngmodels.AlertInstanceKey{},
+	}
+	syncStatePersister := NewSyncStatePersisiter(&logtest.Fake{}, ManagerCfg{
+		InstanceStore:           st,
+		MaxStateSaveConcurrency: 1,
+		DoNotSaveNormalState:    true,
+	})
+	syncStatePersister.Sync(context.Background(), span, StateTransitions{alerting, normal, stale})
+
+	require.Empty(t, st.RecordedOps(), "instances should be saved with one write per rule")
+	alertingKey, err := alerting.GetAlertInstanceKey()
+	require.NoError(t, err)
+	staleKey, err := stale.GetAlertInstanceKey()
+	require.NoError(t, err)
+	require.Len(t, st.saved[ruleKey], 1)
+	require.Equal(t, alertingKey, st.saved[ruleKey][0].AlertInstanceKey)
+	require.Equal(t, []ngmodels.AlertInstanceKey{staleKey}, st.deleted[ruleKey])
+}
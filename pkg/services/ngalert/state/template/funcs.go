@@ -2,12 +2,19 @@ package template
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/url"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/prometheus/common/model"
 )
 
 type query struct {
@@ -23,6 +30,22 @@ const (
 	RemoveLabelsReFuncName   = "removeLabelsRe"
 	TableLinkFuncName        = "tableLink"
 	MergeLabelValuesFuncName = "mergeLabelValues"
+
+	HumanizeFuncName           = "humanize"
+	Humanize1024FuncName       = "humanize1024"
+	HumanizeDurationFuncName   = "humanizeDuration"
+	HumanizePercentageFuncName = "humanizePercentage"
+	HumanizeTimestampFuncName  = "humanizeTimestamp"
+	ToTimeFuncName             = "toTime"
+
+	ContainsFuncName   = "contains"
+	HasPrefixFuncName  = "hasPrefix"
+	HasSuffixFuncName  = "hasSuffix"
+	TrimSpaceFuncName  = "trimSpace"
+	TrimPrefixFuncName = "trimPrefix"
+	TrimSuffixFuncName = "trimSuffix"
+	ReplaceFuncName    = "replace"
+	TruncateFuncName   = "truncate"
 )
 
 var (
@@ -34,9 +57,30 @@ var (
 		RemoveLabelsReFuncName:   removeLabelsReFunc,
 		TableLinkFuncName:        tableLinkFunc,
 		MergeLabelValuesFuncName: mergeLabelValuesFunc,
+
+		// The humanize functions replace the ones of Prometheus so they also accept
+		// the values of expressions, such as $values.A, and durations.
+		HumanizeFuncName:           humanizeFunc,
+		Humanize1024FuncName:       humanize1024Func,
+		HumanizeDurationFuncName:   humanizeDurationFunc,
+		HumanizePercentageFuncName: humanizePercentageFunc,
+		HumanizeTimestampFuncName:  humanizeTimestampFunc,
+		ToTimeFuncName:             toTimeFunc,
+
+		// The string functions take the string last so they can be used in pipelines.
+		ContainsFuncName:   func(substr, s string) bool { return strings.Contains(s, substr) },
+		HasPrefixFuncName:  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		HasSuffixFuncName:  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		TrimSpaceFuncName:  strings.TrimSpace,
+		TrimPrefixFuncName: func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		TrimSuffixFuncName: func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		ReplaceFuncName:    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		TruncateFuncName:   truncateFunc,
 	}
 )
 
+var errNaNOrInf = errors.New("value is NaN or Inf")
+
 // filterLabelsFunc removes all labels that do not match the string.
 func filterLabelsFunc(m Labels, match string) Labels {
 	res := make(Labels)
@@ -131,3 +175,187 @@ func mergeLabelValuesFunc(values map[string]Value) Labels {
 	}
 	return res
 }
+
+// toFloat converts the argument of a humanize function to a float. Durations are converted to seconds.
+func toFloat(i any) (float64, error) {
+	switch v := i.(type) {
+	case Value:
+		return v.Value, nil
+	case *Value:
+		return v.Value, nil
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case time.Duration:
+		return v.Seconds(), nil
+	default:
+		return 0, fmt.Errorf("can't convert %T to float", v)
+	}
+}
+
+// humanizeFunc formats the number with a metric prefix, such as 1.5k or 20m.
+func humanizeFunc(i any) (string, error) {
+	v, err := toFloat(i)
+	if err != nil {
+		return "", err
+	}
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Sprintf("%.4g", v), nil
+	}
+	if math.Abs(v) >= 1 {
+		prefix := ""
+		for _, p := range []string{"k", "M", "G", "T", "P", "E", "Z", "Y"} {
+			if math.Abs(v) < 1000 {
+				break
+			}
+			prefix = p
+			v /= 1000
+		}
+		return fmt.Sprintf("%.4g%s", v, prefix), nil
+	}
+	prefix := ""
+	for _, p := range []string{"m", "u", "n", "p", "f", "a", "z", "y"} {
+		if math.Abs(v) >= 1 {
+			break
+		}
+		prefix = p
+		v *= 1000
+	}
+	return fmt.Sprintf("%.4g%s", v, prefix), nil
+}
+
+// humanize1024Func formats the number with a binary prefix, such as 1.5Ki or 20Mi.
+func humanize1024Func(i any) (string, error) {
+	v, err := toFloat(i)
+	if err != nil {
+		return "", err
+	}
+	if math.Abs(v) <= 1 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Sprintf("%.4g", v), nil
+	}
+	prefix := ""
+	for _, p := range []string{"ki", "Mi", "Gi", "Ti", "Pi", "Ei", "Zi", "Yi"} {
+		if math.Abs(v) < 1024 {
+			break
+		}
+		prefix = p
+		v /= 1024
+	}
+	return fmt.Sprintf("%.4g%s", v, prefix), nil
+}
+
+// humanizeDurationFunc formats the number of seconds as a duration, such as 1d 2h 3m 4s.
+func humanizeDurationFunc(i any) (string, error) {
+	v, err := toFloat(i)
+	if err != nil {
+		return "", err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Sprintf("%.4g", v), nil
+	}
+	if v == 0 {
+		return fmt.Sprintf("%.4gs", v), nil
+	}
+	if math.Abs(v) >= 1 {
+		sign := ""
+		if v < 0 {
+			sign = "-"
+			v = -v
+		}
+		duration := int64(v)
+		seconds := duration % 60
+		minutes := (duration / 60) % 60
+		hours := (duration / 60 / 60) % 24
+		days := duration / 60 / 60 / 24
+		// For days to minutes, we display seconds as an integer.
+		if days != 0 {
+			return fmt.Sprintf("%s%dd %dh %dm %ds", sign, days, hours, minutes, seconds), nil
+		}
+		if hours != 0 {
+			return fmt.Sprintf("%s%dh %dm %ds", sign, hours, minutes, seconds), nil
+		}
+		if minutes != 0 {
+			return fmt.Sprintf("%s%dm %ds", sign, minutes, seconds), nil
+		}
+		// For seconds, we display 4 significant digits.
+		return fmt.Sprintf("%s%.4gs", sign, v), nil
+	}
+	prefix := ""
+	for _, p := range []string{"m", "u", "n", "p", "f", "a", "z", "y"} {
+		if math.Abs(v) >= 1 {
+			break
+		}
+		prefix = p
+		v *= 1000
+	}
+	return fmt.Sprintf("%.4g%ss", v, prefix), nil
+}
+
+// humanizePercentageFunc formats the ratio as a percentage, such as 0.5 as 50%.
+func humanizePercentageFunc(i any) (string, error) {
+	v, err := toFloat(i)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%.4g%%", v*100), nil
+}
+
+// humanizeTimestampFunc formats the Unix timestamp in seconds as a time.
+func humanizeTimestampFunc(i any) (string, error) {
+	v, err := toFloat(i)
+	if err != nil {
+		return "", err
+	}
+	t, err := floatToTime(v)
+	if errors.Is(err, errNaNOrInf) {
+		return fmt.Sprintf("%.4g", v), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(t), nil
+}
+
+// toTimeFunc converts the Unix timestamp in seconds to a time.
+func toTimeFunc(i any) (*time.Time, error) {
+	v, err := toFloat(i)
+	if err != nil {
+		return nil, err
+	}
+	return floatToTime(v)
+}
+
+func floatToTime(v float64) (*time.Time, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, errNaNOrInf
+	}
+	timestamp := v * 1e9
+	if timestamp > math.MaxInt64 || timestamp < math.MinInt64 {
+		return nil, fmt.Errorf("%v cannot be represented as a nanoseconds timestamp since it overflows int64", v)
+	}
+	t := model.TimeFromUnixNano(int64(timestamp)).Time().UTC()
+	return &t, nil
+}
+
+// truncateFunc returns the first n characters of the string. It does not split multi-byte characters.
+func truncateFunc(n int, s string) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n])
+}
@@ -0,0 +1,96 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+)
+
+// QueryFunc runs the query of the query template function at the given time.
+type QueryFunc func(ctx context.Context, query string, ts time.Time) (promql.Vector, error)
+
+type queryFuncKey struct{}
+
+// WithQueryFunc returns a copy of the context that runs the queries of templates expanded with it using f.
+func WithQueryFunc(ctx context.Context, f QueryFunc) context.Context {
+	return context.WithValue(ctx, queryFuncKey{}, f)
+}
+
+func queryFuncFromContext(ctx context.Context) (QueryFunc, bool) {
+	f, ok := ctx.Value(queryFuncKey{}).(QueryFunc)
+	return f, ok && f != nil
+}
+
+type queryCacheKey struct {
+	query string
+	ts    int64
+}
+
+type queryCacheEntry struct {
+	done   chan struct{}
+	vector promql.Vector
+	err    error
+	// canceled is true if the query failed because the context of its caller was done. Such results are not cached.
+	canceled bool
+}
+
+// NewCachedQueryFunc returns a QueryFunc that runs each distinct query once and returns the same result to all
+// callers, so expanding the templates of many alert instances runs a query once.
+// Each query is cancelled after the timeout, and at most maxQueries distinct queries are run.
+// Queries that fail because the context of their caller is done are not cached, and run again by the next caller.
+// Queries that time out are cached like any other error, so a slow query is not run again for each alert instance.
+// A cached QueryFunc is meant to be used for a single evaluation of a rule.
+func NewCachedQueryFunc(f QueryFunc, timeout time.Duration, maxQueries int) QueryFunc {
+	var (
+		mtx     sync.Mutex
+		entries = make(map[queryCacheKey]*queryCacheEntry)
+	)
+	return func(ctx context.Context, query string, ts time.Time) (promql.Vector, error) {
+		key := queryCacheKey{query: query, ts: ts.UnixNano()}
+		for {
+			mtx.Lock()
+			entry, ok := entries[key]
+			if ok {
+				mtx.Unlock()
+				select {
+				case <-entry.done:
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+				if entry.canceled {
+					continue
+				}
+				return entry.vector, entry.err
+			}
+			if len(entries) >= maxQueries {
+				mtx.Unlock()
+				return nil, fmt.Errorf("templates of the rule run more than %d distinct queries", maxQueries)
+			}
+			entry = &queryCacheEntry{done: make(chan struct{})}
+			entries[key] = entry
+			mtx.Unlock()
+
+			entry.vector, entry.err = runQuery(ctx, f, query, ts, timeout)
+			if entry.err != nil && ctx.Err() != nil {
+				entry.canceled = true
+				mtx.Lock()
+				delete(entries, key)
+				mtx.Unlock()
+			}
+			close(entry.done)
+			return entry.vector, entry.err
+		}
+	}
+}
+
+func runQuery(ctx context.Context, f QueryFunc, query string, ts time.Time, timeout time.Duration) (promql.Vector, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return f(ctx, query, ts)
+}
@@ -0,0 +1,173 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+)
+
+func TestExpandQuery(t *testing.T) {
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	evaluatedAt := time.Unix(1700000000, 0)
+
+	queryFunc := func(_ context.Context, q string, ts time.Time) (promql.Vector, error) {
+		require.Equal(t, evaluatedAt, ts)
+		if q == "error" {
+			return nil, errors.New("query failed")
+		}
+		return promql.Vector{
+			{Metric: labels.FromStrings("instance", "b"), F: 2},
+			{Metric: labels.FromStrings("instance", "a"), F: 1},
+		}, nil
+	}
+
+	t.Run("query returns no results without a query function", func(t *testing.T) {
+		v, err := Expand(context.Background(), "test", `{{ query "up" | len }}`, NewData(nil, eval.Result{}), externalURL, evaluatedAt)
+		require.NoError(t, err)
+		require.Equal(t, "0", v)
+	})
+
+	t.Run("query runs the query function of the context", func(t *testing.T) {
+		ctx := WithQueryFunc(context.Background(), queryFunc)
+		text := `{{ range query "up" | sortByLabel "instance" }}{{ .Labels.instance }}={{ .Value }} {{ end }}{{ with query "up" }}{{ . | first | value }}{{ end }}`
+		v, err := Expand(ctx, "test", text, NewData(data.Labels{}, eval.Result{}), externalURL, evaluatedAt)
+		require.NoError(t, err)
+		require.Equal(t, "a=1 b=2 2", v)
+	})
+
+	t.Run("query errors fail the expansion", func(t *testing.T) {
+		ctx := WithQueryFunc(context.Background(), queryFunc)
+		_, err := Expand(ctx, "test", `{{ query "error" }}`, NewData(data.Labels{}, eval.Result{}), externalURL, evaluatedAt)
+		require.ErrorContains(t, err, "query failed")
+	})
+}
+
+func TestNewCachedQueryFunc(t *testing.T) {
+	ts := time.Now()
+
+	t.Run("runs each distinct query once", func(t *testing.T) {
+		var mtx sync.Mutex
+		calls := map[string]int{}
+		f := NewCachedQueryFunc(func(_ context.Context, q string, _ time.Time) (promql.Vector, error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			calls[q]++
+			return promql.Vector{{F: float64(len(q))}}, nil
+		}, time.Second, 10)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := f(context.Background(), "up", ts)
+				require.NoError(t, err)
+				require.Equal(t, 2.0, v[0].F)
+			}()
+		}
+		wg.Wait()
+		_, err := f(context.Background(), "down", ts)
+		require.NoError(t, err)
+		require.Equal(t, map[string]int{"up": 1, "down": 1}, calls)
+	})
+
+	t.Run("caches errors", func(t *testing.T) {
+		calls := 0
+		f := NewCachedQueryFunc(func(context.Context, string, time.Time) (promql.Vector, error) {
+			calls++
+			return nil, errors.New("failed")
+		}, time.Second, 10)
+		for i := 0; i < 3; i++ {
+			_, err := f(context.Background(), "up", ts)
+			require.EqualError(t, err, "failed")
+		}
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("does not cache context errors", func(t *testing.T) {
+		calls := 0
+		f := NewCachedQueryFunc(func(ctx context.Context, _ string, _ time.Time) (promql.Vector, error) {
+			calls++
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("failed to run query: %w", err)
+			}
+			return promql.Vector{{F: 1}}, nil
+		}, time.Second, 1)
+
+		canceled, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := f(canceled, "up", ts)
+		require.ErrorIs(t, err, context.Canceled)
+
+		// the query runs again and a canceled query does not count towards the limit.
+		v, err := f(context.Background(), "up", ts)
+		require.NoError(t, err)
+		require.Equal(t, 1.0, v[0].F)
+		_, err = f(context.Background(), "up", ts)
+		require.NoError(t, err)
+		require.Equal(t, 2, calls)
+	})
+
+	t.Run("cancels queries after the timeout", func(t *testing.T) {
+		f := NewCachedQueryFunc(func(ctx context.Context, _ string, _ time.Time) (promql.Vector, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}, 10*time.Millisecond, 10)
+		_, err := f(context.Background(), "up", ts)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("caches queries that time out", func(t *testing.T) {
+		var mtx sync.Mutex
+		calls := 0
+		f := NewCachedQueryFunc(func(ctx context.Context, _ string, _ time.Time) (promql.Vector, error) {
+			mtx.Lock()
+			calls++
+			mtx.Unlock()
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}, 10*time.Millisecond, 1)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := f(context.Background(), "up", ts)
+				require.ErrorIs(t, err, context.DeadlineExceeded)
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, 1, calls)
+
+		// the query that timed out counts towards the limit.
+		_, err := f(context.Background(), "down", ts)
+		require.EqualError(t, err, "templates of the rule run more than 1 distinct queries")
+	})
+
+	t.Run("limits the number of distinct queries", func(t *testing.T) {
+		f := NewCachedQueryFunc(func(context.Context, string, time.Time) (promql.Vector, error) {
+			return nil, nil
+		}, time.Second, 2)
+		_, err := f(context.Background(), "a", ts)
+		require.NoError(t, err)
+		_, err = f(context.Background(), "b", ts)
+		require.NoError(t, err)
+		_, err = f(context.Background(), "a", ts)
+		require.NoError(t, err)
+		_, err = f(context.Background(), "c", ts)
+		require.EqualError(t, err, "templates of the rule run more than 2 distinct queries")
+	})
+}
@@ -104,10 +104,13 @@ func Expand(ctx context.Context, name, tmpl string, data Data, externalURL *url.
 	name = "__alert_" + name
 	// add variables for the labels and values to the beginning of the template
 	tmpl = "{{- $labels := .Labels -}}{{- $values := .Values -}}{{- $value := .Value -}}" + tmpl
-	// ctx and queryFunc are no-ops as `query()` is not supported in Grafana
+	// `query()` returns no results unless the context has a function to run queries, such as during the evaluation of a rule.
 	queryFunc := func(context.Context, string, time.Time) (promql.Vector, error) {
 		return nil, nil
 	}
+	if f, ok := queryFuncFromContext(ctx); ok {
+		queryFunc = template.QueryFunc(f)
+	}
 	tm := model.Time(timestamp.FromTime(evaluatedAt))
 	// Use missingkey=invalid so missing data shows <no value> instead of the type's default value
 	options := []string{"missingkey=invalid"}
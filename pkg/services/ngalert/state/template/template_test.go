@@ -431,6 +431,34 @@ func TestExpandTemplate(t *testing.T) {
 		name:     "check that safeHtml doesn't error or panic",
 		text:     "{{ \"<b>\" | safeHtml }}",
 		expected: "<b>",
+	}, {
+		name: "values can be humanized without .Value",
+		text: "{{ humanizeDuration $values.A }}:{{ humanizePercentage $values.B }}:{{ humanize1024 $values.C }}",
+		alertInstance: eval.Result{
+			Values: map[string]eval.NumberValueCapture{
+				"A": {Var: "A", Value: util.Pointer(3661.0)},
+				"B": {Var: "B", Value: util.Pointer(0.25)},
+				"C": {Var: "C", Value: util.Pointer(2048.0)},
+			},
+		},
+		expected: "1h 1m 1s:25%:2ki",
+	}, {
+		name:     "toTime converts a timestamp in seconds",
+		text:     `{{ (toTime "1700000000").Format "2006-01-02" }}`,
+		expected: "2023-11-14",
+	}, {
+		name:     "humanizeTimestamp formats a timestamp in seconds",
+		text:     `{{ humanizeTimestamp 1700000000 }}`,
+		expected: "2023-11-14 22:13:20 +0000 UTC",
+	}, {
+		name:     "string functions can be used in pipelines",
+		text:     `{{ $labels.instance | trimSuffix ":9100" | replace "." "-" | truncate 7 }}:{{ $labels.instance | hasPrefix "db" }}:{{ $labels.instance | contains "prod" }}:{{ "  x " | trimSpace }}`,
+		labels:   data.Labels{"instance": "db1.prod.local:9100"},
+		expected: "db1-pro:true:true:x",
+	}, {
+		name:     "truncate does not split multi-byte characters",
+		text:     `{{ "héllo" | truncate 2 }}`,
+		expected: "hé",
 	},
 	}
 
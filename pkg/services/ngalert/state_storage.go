@@ -0,0 +1,91 @@
+package ngalert
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	stateStorageKVNamespace = "alerting"
+	stateStorageKVKey       = "state_storage"
+)
+
+// stateStorage is the store of the alert instances that the state manager uses.
+type stateStorage struct {
+	store state.InstanceStore
+	// name is the storage that is actually used, which is the database if the configured storage is not available.
+	name string
+}
+
+// configureStateStorage returns the store of the alert instances for the state_storage setting.
+// The storage falls back to the database if the remote cache is not Redis or cannot be reached.
+// If the storage differs from the storage used when Grafana was last started, the alert instances are copied
+// from the previous storage, so that the state of the alerts is kept when switching between storages.
+func configureStateStorage(ctx context.Context, cfg *setting.Cfg, dbStore *store.DBstore, cache remotecache.CacheStorage, kv kvstore.KVStore, features featuremgmt.FeatureToggles, logger log.Logger) stateStorage {
+	var remoteStore *store.RemoteCacheInstanceStore
+	if cache != nil && cfg.RemoteCacheOptions != nil && cfg.RemoteCacheOptions.Name == "redis" {
+		remoteStore = store.NewRemoteCacheInstanceStore(cache, features, log.New("ngalert.state.remotecache"))
+		if err := remoteStore.Ping(ctx); err != nil {
+			logger.Error("Failed to connect to the remote cache to load the alert state", "error", err)
+			remoteStore = nil
+		}
+	}
+
+	storage := stateStorage{store: dbStore, name: setting.StateStorageDatabase}
+	if cfg.UnifiedAlerting.StateStorage == setting.StateStorageRedis {
+		if remoteStore != nil {
+			storage = stateStorage{store: remoteStore, name: setting.StateStorageRedis}
+		} else {
+			logger.Warn("Alert state is saved in the database because the remote cache is not a reachable Redis server", "state_storage", cfg.UnifiedAlerting.StateStorage)
+		}
+	}
+
+	kvStore := kvstore.WithNamespace(kv, 0, stateStorageKVNamespace)
+	previous, ok, err := kvStore.Get(ctx, stateStorageKVKey)
+	if err != nil {
+		logger.Error("Failed to get the previous storage of the alert state", "error", err)
+		return storage
+	}
+	// The alert state was saved in the database before the storage was configurable.
+	if !ok {
+		previous = setting.StateStorageDatabase
+	}
+	if previous == storage.name {
+		if !ok {
+			setStateStorage(ctx, kvStore, storage.name, logger)
+		}
+		return storage
+	}
+
+	var from state.InstanceStore = dbStore
+	if previous == setting.StateStorageRedis {
+		if remoteStore == nil {
+			logger.Warn("Alert state cannot be copied from the previous storage because it is not available", "previous", previous, "current", storage.name)
+			setStateStorage(ctx, kvStore, storage.name, logger)
+			return storage
+		}
+		from = remoteStore
+	}
+	count, err := state.CopyInstances(ctx, from, storage.store)
+	if err != nil {
+		// The storage is not recorded so that the copy is retried on the next start.
+		logger.Error("Failed to copy the alert state to the new storage", "previous", previous, "current", storage.name, "error", err)
+		return storage
+	}
+	logger.Info("Alert state copied to the new storage", "previous", previous, "current", storage.name, "instances", count)
+	setStateStorage(ctx, kvStore, storage.name, logger)
+	return storage
+}
+
+func setStateStorage(ctx context.Context, kvStore *kvstore.NamespacedKVStore, name string, logger log.Logger) {
+	if err := kvStore.Set(ctx, stateStorageKVKey, name); err != nil {
+		logger.Error("Failed to save the storage of the alert state", "error", err)
+	}
+}
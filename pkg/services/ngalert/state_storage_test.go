@@ -0,0 +1,101 @@
+package ngalert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestIntegrationConfigureStateStorage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	features := featuremgmt.WithFeatures()
+	dbStore := &store.DBstore{SQLStore: db.InitTestDB(t), FeatureToggles: features, Logger: log.NewNopLogger()}
+	cache := remotecache.NewFakeCacheStorage()
+	kv := kvstore.NewFakeKVStore()
+	newCfg := func(storage string, remoteCache string) *setting.Cfg {
+		cfg := setting.NewCfg()
+		cfg.UnifiedAlerting.StateStorage = storage
+		cfg.RemoteCacheOptions = &setting.RemoteCacheOptions{Name: remoteCache}
+		return cfg
+	}
+	storedStorage := func(t *testing.T) string {
+		t.Helper()
+		name, ok, err := kvstore.WithNamespace(kv, 0, stateStorageKVNamespace).Get(ctx, stateStorageKVKey)
+		require.NoError(t, err)
+		require.True(t, ok)
+		return name
+	}
+	listAll := func(t *testing.T, st interface {
+		ListAlertInstances(context.Context, *models.ListAlertInstancesQuery) ([]*models.AlertInstance, error)
+	}) []*models.AlertInstance {
+		t.Helper()
+		instances, err := st.ListAlertInstances(ctx, &models.ListAlertInstancesQuery{RuleOrgID: 1})
+		require.NoError(t, err)
+		return instances
+	}
+
+	labels := models.InstanceLabels{"label": "a"}
+	_, hash, err := labels.StringAndHash()
+	require.NoError(t, err)
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, dbStore.SaveAlertInstance(ctx, models.AlertInstance{
+		AlertInstanceKey:  models.AlertInstanceKey{RuleOrgID: 1, RuleUID: "rule", LabelsHash: hash},
+		Labels:            labels,
+		CurrentState:      models.InstanceStateFiring,
+		LastEvalTime:      now,
+		CurrentStateSince: now,
+		CurrentStateEnd:   now.Add(time.Minute),
+	}))
+
+	t.Run("database storage is recorded without copying", func(t *testing.T) {
+		storage := configureStateStorage(ctx, newCfg(setting.StateStorageDatabase, "redis"), dbStore, cache, kv, features, log.NewNopLogger())
+		require.Equal(t, setting.StateStorageDatabase, storage.name)
+		require.Equal(t, setting.StateStorageDatabase, storedStorage(t))
+		require.Empty(t, cache.Storage)
+	})
+
+	t.Run("redis storage falls back to the database if the remote cache is not redis", func(t *testing.T) {
+		storage := configureStateStorage(ctx, newCfg(setting.StateStorageRedis, "database"), dbStore, cache, kv, features, log.NewNopLogger())
+		require.Equal(t, setting.StateStorageDatabase, storage.name)
+		require.Same(t, dbStore, storage.store)
+	})
+
+	t.Run("state is copied when the storage changes", func(t *testing.T) {
+		storage := configureStateStorage(ctx, newCfg(setting.StateStorageRedis, "redis"), dbStore, cache, kv, features, log.NewNopLogger())
+		require.Equal(t, setting.StateStorageRedis, storage.name)
+		require.Equal(t, setting.StateStorageRedis, storedStorage(t))
+		instances := listAll(t, storage.store)
+		require.Len(t, instances, 1)
+		require.Equal(t, models.InstanceStateFiring, instances[0].CurrentState)
+
+		require.NoError(t, storage.store.SaveAlertInstance(ctx, models.AlertInstance{
+			AlertInstanceKey:  models.AlertInstanceKey{RuleOrgID: 1, RuleUID: "rule", LabelsHash: hash},
+			Labels:            labels,
+			CurrentState:      models.InstanceStateNormal,
+			LastEvalTime:      now,
+			CurrentStateSince: now,
+			CurrentStateEnd:   now.Add(time.Minute),
+		}))
+
+		storage = configureStateStorage(ctx, newCfg(setting.StateStorageDatabase, "redis"), dbStore, cache, kv, features, log.NewNopLogger())
+		require.Equal(t, setting.StateStorageDatabase, storage.name)
+		require.Equal(t, setting.StateStorageDatabase, storedStorage(t))
+		instances = listAll(t, dbStore)
+		require.Len(t, instances, 1)
+		require.Equal(t, models.InstanceStateNormal, instances[0].CurrentState)
+	})
+}
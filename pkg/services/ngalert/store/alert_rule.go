@@ -103,6 +103,52 @@ func (st DBstore) GetAlertRuleByUID(ctx context.Context, query *ngmodels.GetAler
 	return result, err
 }
 
+// GetAlertRuleVersion returns the rule as it was defined at the given version. The definition comes from the
+// version history, the other fields, like the ID, come from the current rule.
+func (st DBstore) GetAlertRuleVersion(ctx context.Context, orgID int64, ruleUID string, version int64) (result *ngmodels.AlertRule, err error) {
+	err = st.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		current, err := getAlertRuleByUID(sess, ruleUID, orgID)
+		if err != nil {
+			return err
+		}
+		if current.Version == version {
+			result = current
+			return nil
+		}
+
+		ruleVersion := ngmodels.AlertRuleVersion{}
+		has, err := sess.Table("alert_rule_version").Where("rule_org_id = ? AND rule_uid = ? AND version = ?", orgID, ruleUID, version).Get(&ruleVersion)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return fmt.Errorf("%w: version %d of rule %s", ngmodels.ErrAlertRuleNotFound, version, ruleUID)
+		}
+
+		rule := *current
+		rule.Version = ruleVersion.Version
+		rule.Updated = ruleVersion.Created
+		rule.NamespaceUID = ruleVersion.RuleNamespaceUID
+		rule.RuleGroup = ruleVersion.RuleGroup
+		rule.RuleGroupIndex = ruleVersion.RuleGroupIndex
+		rule.Title = ruleVersion.Title
+		rule.Condition = ruleVersion.Condition
+		rule.Data = ruleVersion.Data
+		rule.IntervalSeconds = ruleVersion.IntervalSeconds
+		rule.Record = ruleVersion.Record
+		rule.NoDataState = ruleVersion.NoDataState
+		rule.ExecErrState = ruleVersion.ExecErrState
+		rule.For = ruleVersion.For
+		rule.Annotations = ruleVersion.Annotations
+		rule.Labels = ruleVersion.Labels
+		rule.IsPaused = ruleVersion.IsPaused
+		rule.NotificationSettings = ruleVersion.NotificationSettings
+		result = &rule
+		return nil
+	})
+	return result, err
+}
+
 // GetAlertRulesGroupByRuleUID is a handler for retrieving a group of alert rules from that database by UID and organisation ID of one of rules that belong to that group.
 func (st DBstore) GetAlertRulesGroupByRuleUID(ctx context.Context, query *ngmodels.GetAlertRulesGroupByRuleUIDQuery) (result []*ngmodels.AlertRule, err error) {
 	err = st.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
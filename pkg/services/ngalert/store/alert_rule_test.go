@@ -646,6 +646,55 @@ func TestIntegration_GetNamespaceByUID(t *testing.T) {
 	})
 }
 
+func TestIntegrationGetAlertRuleVersion(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	sqlStore := db.InitTestReplDB(t)
+	cfg := setting.NewCfg()
+	cfg.UnifiedAlerting.BaseInterval = 1 * time.Second
+	store := &DBstore{
+		SQLStore:      sqlStore,
+		FolderService: setupFolderService(t, sqlStore, cfg, featuremgmt.WithFeatures()),
+		Logger:        log.New("test-dbstore"),
+		Cfg:           cfg.UnifiedAlerting,
+	}
+	gen := models.RuleGen.With(models.RuleGen.WithOrgID(1), models.RuleGen.WithIntervalMatching(store.Cfg.BaseInterval))
+
+	ids, err := store.InsertAlertRules(context.Background(), []models.AlertRule{gen.Generate()})
+	require.NoError(t, err)
+	original, err := store.GetAlertRuleByUID(context.Background(), &models.GetAlertRuleByUIDQuery{OrgID: 1, UID: ids[0].UID})
+	require.NoError(t, err)
+
+	updated := models.CopyRule(original)
+	updated.Title = "updated-" + util.GenerateShortUID()
+	updated.Labels = map[string]string{"version": "2"}
+	require.NoError(t, store.UpdateAlertRules(context.Background(), []models.UpdateRule{{Existing: original, New: *updated}}))
+
+	t.Run("should return the current rule for its version", func(t *testing.T) {
+		rule, err := store.GetAlertRuleVersion(context.Background(), 1, original.UID, original.Version+1)
+		require.NoError(t, err)
+		require.Equal(t, updated.Title, rule.Title)
+		require.Equal(t, updated.Labels, rule.Labels)
+	})
+
+	t.Run("should return the definition of a previous version", func(t *testing.T) {
+		rule, err := store.GetAlertRuleVersion(context.Background(), 1, original.UID, original.Version)
+		require.NoError(t, err)
+		require.Equal(t, original.ID, rule.ID)
+		require.Equal(t, original.Version, rule.Version)
+		require.Equal(t, original.Title, rule.Title)
+		require.Equal(t, original.Labels, rule.Labels)
+		require.Equal(t, original.Condition, rule.Condition)
+	})
+
+	t.Run("should return not found for unknown version", func(t *testing.T) {
+		_, err := store.GetAlertRuleVersion(context.Background(), 1, original.UID, original.Version+10)
+		require.ErrorIs(t, err, models.ErrAlertRuleNotFound)
+	})
+}
+
 func TestIntegrationInsertAlertRules(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
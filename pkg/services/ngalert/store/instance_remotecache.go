@@ -0,0 +1,432 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+const (
+	remoteCacheInstanceKeyPrefix = "alerting:instances:"
+	// remoteCacheInstanceExpiration is how long the instances of a rule are kept after they were last written.
+	// The instances of a rule are written every time the rule is evaluated.
+	remoteCacheInstanceExpiration = 7 * 24 * time.Hour
+	// remoteCacheIndexRefreshInterval is how often the index of the rules with instances is merged with the rules
+	// this instance of Grafana wrote. This repairs the index if instances of Grafana in a cluster overwrote the
+	// changes of each other, and keeps it from expiring.
+	remoteCacheIndexRefreshInterval = time.Hour
+)
+
+// RemoteCacheInstanceStore is an instance store that keeps the alert instances in the remote cache, which is Redis
+// in large installations, instead of the alert_instance table. The instances of a rule are stored together under
+// one key, so that the state of a rule is written with one request per evaluation and loaded with one request.
+// The rules that have instances are kept in an index per organization, which is only needed to list all instances.
+type RemoteCacheInstanceStore struct {
+	cache    remotecache.CacheStorage
+	features featuremgmt.FeatureToggles
+	log      log.Logger
+
+	ruleLocksMtx sync.Mutex
+	// ruleLocks serializes the updates of the instances of a rule by this instance of Grafana. A lock is removed
+	// when it is released and nobody waits for it, so that deleted rules do not leave their locks behind.
+	ruleLocks map[models.AlertRuleKey]*ruleLock
+
+	indexMtx sync.Mutex
+	// index is the rules with instances that were written by this instance of Grafana, by organization.
+	index map[int64]map[string]struct{}
+	// indexUpdated is when the index of an organization was last written.
+	indexUpdated map[int64]time.Time
+}
+
+func NewRemoteCacheInstanceStore(cache remotecache.CacheStorage, features featuremgmt.FeatureToggles, log log.Logger) *RemoteCacheInstanceStore {
+	return &RemoteCacheInstanceStore{
+		cache:        cache,
+		features:     features,
+		log:          log,
+		ruleLocks:    make(map[models.AlertRuleKey]*ruleLock),
+		index:        make(map[int64]map[string]struct{}),
+		indexUpdated: make(map[int64]time.Time),
+	}
+}
+
+// remoteCacheInstance is an alert instance of a rule in the remote cache.
+type remoteCacheInstance struct {
+	Labels            models.InstanceLabels    `json:"labels"`
+	LabelsHash        string                   `json:"labelsHash"`
+	CurrentState      models.InstanceStateType `json:"state"`
+	CurrentReason     string                   `json:"reason,omitempty"`
+	CurrentStateSince time.Time                `json:"stateSince"`
+	CurrentStateEnd   time.Time                `json:"stateEnd"`
+	LastEvalTime      time.Time                `json:"lastEvalTime"`
+	LastSentAt        *time.Time               `json:"lastSentAt,omitempty"`
+	ResolvedAt        *time.Time               `json:"resolvedAt,omitempty"`
+	ResultFingerprint string                   `json:"resultFingerprint,omitempty"`
+}
+
+// Ping returns an error if the remote cache cannot be reached.
+func (st *RemoteCacheInstanceStore) Ping(ctx context.Context) error {
+	_, err := st.cache.Get(ctx, remoteCacheOrgsKey())
+	if err != nil && !errors.Is(err, remotecache.ErrCacheItemNotFound) {
+		return err
+	}
+	return nil
+}
+
+// FetchOrgIds returns the organizations that have alert instances.
+func (st *RemoteCacheInstanceStore) FetchOrgIds(ctx context.Context) ([]int64, error) {
+	var orgIDs []int64
+	if err := st.get(ctx, remoteCacheOrgsKey(), &orgIDs); err != nil {
+		return nil, err
+	}
+	return orgIDs, nil
+}
+
+// ListAlertInstances returns the alert instances of a rule, or of all rules of the organization if the query has
+// no rule UID.
+func (st *RemoteCacheInstanceStore) ListAlertInstances(ctx context.Context, cmd *models.ListAlertInstancesQuery) ([]*models.AlertInstance, error) {
+	ruleUIDs := []string{cmd.RuleUID}
+	if cmd.RuleUID == "" {
+		if err := st.get(ctx, remoteCacheRulesKey(cmd.RuleOrgID), &ruleUIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	skipNormal := st.features.IsEnabled(ctx, featuremgmt.FlagAlertingNoNormalState)
+	result := make([]*models.AlertInstance, 0)
+	for _, ruleUID := range ruleUIDs {
+		key := models.AlertRuleKey{OrgID: cmd.RuleOrgID, UID: ruleUID}
+		instances, err := st.getRuleInstances(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range instances {
+			if skipNormal && instance.CurrentState == models.InstanceStateNormal && instance.CurrentReason == "" {
+				continue
+			}
+			result = append(result, fromRemoteCacheInstance(key, instance))
+		}
+	}
+	return result, nil
+}
+
+// SaveAlertInstance saves an alert instance. Use SaveRuleInstances to save several instances of a rule.
+func (st *RemoteCacheInstanceStore) SaveAlertInstance(ctx context.Context, instance models.AlertInstance) error {
+	return st.SaveRuleInstances(ctx, models.AlertRuleKey{OrgID: instance.RuleOrgID, UID: instance.RuleUID}, []models.AlertInstance{instance}, nil)
+}
+
+// SaveRuleInstances saves and deletes alert instances of a rule with one read and one write of the instances
+// of the rule. The write is not incremental, all instances of the rule are written again. The remote cache has no
+// multi-key reads, so storing instances under separate keys would make loading the state of a rule take a request
+// per instance, and most instances change on every evaluation anyway because of their evaluation and end times.
+func (st *RemoteCacheInstanceStore) SaveRuleInstances(ctx context.Context, key models.AlertRuleKey, instances []models.AlertInstance, deleted []models.AlertInstanceKey) error {
+	for _, instance := range instances {
+		if err := models.ValidateAlertInstance(instance); err != nil {
+			return err
+		}
+		if instance.RuleOrgID != key.OrgID || instance.RuleUID != key.UID {
+			return fmt.Errorf("alert instance of rule %s does not belong to rule %s", instance.RuleUID, key.UID)
+		}
+	}
+
+	unlock := st.lockRule(key)
+	defer unlock()
+
+	existing, err := st.getRuleInstances(ctx, key)
+	if err != nil {
+		return err
+	}
+	byHash := make(map[string]remoteCacheInstance, len(existing)+len(instances))
+	for _, instance := range existing {
+		byHash[instance.LabelsHash] = instance
+	}
+	for _, k := range deleted {
+		delete(byHash, k.LabelsHash)
+	}
+	for _, instance := range instances {
+		byHash[instance.LabelsHash] = toRemoteCacheInstance(instance)
+	}
+	return st.setRuleInstances(ctx, key, byHash)
+}
+
+// DeleteAlertInstances deletes alert instances, with one read and one write per rule.
+func (st *RemoteCacheInstanceStore) DeleteAlertInstances(ctx context.Context, keys ...models.AlertInstanceKey) error {
+	byRule := make(map[models.AlertRuleKey][]models.AlertInstanceKey)
+	for _, k := range keys {
+		ruleKey := models.AlertRuleKey{OrgID: k.RuleOrgID, UID: k.RuleUID}
+		byRule[ruleKey] = append(byRule[ruleKey], k)
+	}
+	for ruleKey, ruleKeys := range byRule {
+		if err := st.SaveRuleInstances(ctx, ruleKey, nil, ruleKeys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteAlertInstancesByRule deletes all alert instances of a rule.
+func (st *RemoteCacheInstanceStore) DeleteAlertInstancesByRule(ctx context.Context, key models.AlertRuleKey) error {
+	unlock := st.lockRule(key)
+	defer unlock()
+
+	if err := st.cache.Delete(ctx, remoteCacheRuleKey(key)); err != nil && !errors.Is(err, remotecache.ErrCacheItemNotFound) {
+		return fmt.Errorf("failed to delete alert instances of rule %s: %w", key.UID, err)
+	}
+	return st.removeFromIndex(ctx, key)
+}
+
+// FullSync replaces all alert instances with the given ones.
+func (st *RemoteCacheInstanceStore) FullSync(ctx context.Context, instances []models.AlertInstance) error {
+	if len(instances) == 0 {
+		return nil
+	}
+
+	byRule := make(map[models.AlertRuleKey]map[string]remoteCacheInstance)
+	for _, instance := range instances {
+		if err := models.ValidateAlertInstance(instance); err != nil {
+			st.log.Warn("Failed to validate alert instance, skipping", "err", err, "rule_uid", instance.RuleUID)
+			continue
+		}
+		key := models.AlertRuleKey{OrgID: instance.RuleOrgID, UID: instance.RuleUID}
+		if byRule[key] == nil {
+			byRule[key] = make(map[string]remoteCacheInstance)
+		}
+		byRule[key][instance.LabelsHash] = toRemoteCacheInstance(instance)
+	}
+
+	// The instances of rules that are not in the index are not deleted, because they cannot be found.
+	orgIDs, err := st.FetchOrgIds(ctx)
+	if err != nil {
+		return err
+	}
+	for _, orgID := range orgIDs {
+		var ruleUIDs []string
+		if err := st.get(ctx, remoteCacheRulesKey(orgID), &ruleUIDs); err != nil {
+			return err
+		}
+		for _, ruleUID := range ruleUIDs {
+			key := models.AlertRuleKey{OrgID: orgID, UID: ruleUID}
+			if _, ok := byRule[key]; !ok {
+				if err := st.DeleteAlertInstancesByRule(ctx, key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for key, ruleInstances := range byRule {
+		unlock := st.lockRule(key)
+		err := st.setRuleInstances(ctx, key, ruleInstances)
+		unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ruleLock is the lock of a rule and the number of goroutines that hold or wait for it.
+type ruleLock struct {
+	mtx  sync.Mutex
+	refs int
+}
+
+func (st *RemoteCacheInstanceStore) lockRule(key models.AlertRuleKey) func() {
+	st.ruleLocksMtx.Lock()
+	l, ok := st.ruleLocks[key]
+	if !ok {
+		l = &ruleLock{}
+		st.ruleLocks[key] = l
+	}
+	l.refs++
+	st.ruleLocksMtx.Unlock()
+
+	l.mtx.Lock()
+	return func() {
+		l.mtx.Unlock()
+		st.ruleLocksMtx.Lock()
+		defer st.ruleLocksMtx.Unlock()
+		l.refs--
+		if l.refs == 0 {
+			delete(st.ruleLocks, key)
+		}
+	}
+}
+
+func (st *RemoteCacheInstanceStore) getRuleInstances(ctx context.Context, key models.AlertRuleKey) ([]remoteCacheInstance, error) {
+	var instances []remoteCacheInstance
+	if err := st.get(ctx, remoteCacheRuleKey(key), &instances); err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+func (st *RemoteCacheInstanceStore) setRuleInstances(ctx context.Context, key models.AlertRuleKey, byHash map[string]remoteCacheInstance) error {
+	if len(byHash) == 0 {
+		if err := st.cache.Delete(ctx, remoteCacheRuleKey(key)); err != nil && !errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return fmt.Errorf("failed to delete alert instances of rule %s: %w", key.UID, err)
+		}
+		return nil
+	}
+	instances := make([]remoteCacheInstance, 0, len(byHash))
+	for _, instance := range byHash {
+		instances = append(instances, instance)
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].LabelsHash < instances[j].LabelsHash
+	})
+	if err := st.set(ctx, remoteCacheRuleKey(key), instances); err != nil {
+		return fmt.Errorf("failed to save alert instances of rule %s: %w", key.UID, err)
+	}
+	return st.addToIndex(ctx, key)
+}
+
+// addToIndex adds the rule to the index of the rules with instances, if it was not added recently.
+func (st *RemoteCacheInstanceStore) addToIndex(ctx context.Context, key models.AlertRuleKey) error {
+	st.indexMtx.Lock()
+	defer st.indexMtx.Unlock()
+
+	rules, ok := st.index[key.OrgID]
+	if !ok {
+		rules = make(map[string]struct{})
+		st.index[key.OrgID] = rules
+	}
+	_, known := rules[key.UID]
+	rules[key.UID] = struct{}{}
+	if known && time.Since(st.indexUpdated[key.OrgID]) < remoteCacheIndexRefreshInterval {
+		return nil
+	}
+	return st.writeIndex(ctx, key.OrgID, "")
+}
+
+// removeFromIndex removes the rule from the index of the rules with instances.
+func (st *RemoteCacheInstanceStore) removeFromIndex(ctx context.Context, key models.AlertRuleKey) error {
+	st.indexMtx.Lock()
+	defer st.indexMtx.Unlock()
+
+	delete(st.index[key.OrgID], key.UID)
+	return st.writeIndex(ctx, key.OrgID, key.UID)
+}
+
+// writeIndex merges the index of the organization in the remote cache with the rules written by this instance of
+// Grafana, except for the removed rule. The caller must hold indexMtx.
+func (st *RemoteCacheInstanceStore) writeIndex(ctx context.Context, orgID int64, removed string) error {
+	var ruleUIDs []string
+	if err := st.get(ctx, remoteCacheRulesKey(orgID), &ruleUIDs); err != nil {
+		return err
+	}
+	merged := make(map[string]struct{}, len(ruleUIDs)+len(st.index[orgID]))
+	for _, uid := range ruleUIDs {
+		merged[uid] = struct{}{}
+	}
+	for uid := range st.index[orgID] {
+		merged[uid] = struct{}{}
+	}
+	delete(merged, removed)
+	ruleUIDs = make([]string, 0, len(merged))
+	for uid := range merged {
+		ruleUIDs = append(ruleUIDs, uid)
+	}
+	sort.Strings(ruleUIDs)
+	if err := st.set(ctx, remoteCacheRulesKey(orgID), ruleUIDs); err != nil {
+		return fmt.Errorf("failed to save index of alert instances: %w", err)
+	}
+
+	var orgIDs []int64
+	if err := st.get(ctx, remoteCacheOrgsKey(), &orgIDs); err != nil {
+		return err
+	}
+	found := false
+	for _, id := range orgIDs {
+		if id == orgID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		orgIDs = append(orgIDs, orgID)
+		sort.Slice(orgIDs, func(i, j int) bool { return orgIDs[i] < orgIDs[j] })
+	}
+	if err := st.set(ctx, remoteCacheOrgsKey(), orgIDs); err != nil {
+		return fmt.Errorf("failed to save index of alert instances: %w", err)
+	}
+	st.indexUpdated[orgID] = time.Now()
+	return nil
+}
+
+// get unmarshals the value of the key into v. It leaves v unchanged if the key does not exist.
+func (st *RemoteCacheInstanceStore) get(ctx context.Context, key string, v any) error {
+	value, err := st.cache.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get %s from remote cache: %w", key, err)
+	}
+	if err := json.Unmarshal(value, v); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", key, err)
+	}
+	return nil
+}
+
+func (st *RemoteCacheInstanceStore) set(ctx context.Context, key string, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return st.cache.Set(ctx, key, value, remoteCacheInstanceExpiration)
+}
+
+func remoteCacheOrgsKey() string {
+	return remoteCacheInstanceKeyPrefix + "orgs"
+}
+
+func remoteCacheRulesKey(orgID int64) string {
+	return fmt.Sprintf("%s%d:rules", remoteCacheInstanceKeyPrefix, orgID)
+}
+
+func remoteCacheRuleKey(key models.AlertRuleKey) string {
+	return fmt.Sprintf("%s%d:rule:%s", remoteCacheInstanceKeyPrefix, key.OrgID, key.UID)
+}
+
+func toRemoteCacheInstance(instance models.AlertInstance) remoteCacheInstance {
+	return remoteCacheInstance{
+		Labels:            instance.Labels,
+		LabelsHash:        instance.LabelsHash,
+		CurrentState:      instance.CurrentState,
+		CurrentReason:     instance.CurrentReason,
+		CurrentStateSince: instance.CurrentStateSince,
+		CurrentStateEnd:   instance.CurrentStateEnd,
+		LastEvalTime:      instance.LastEvalTime,
+		LastSentAt:        instance.LastSentAt,
+		ResolvedAt:        instance.ResolvedAt,
+		ResultFingerprint: instance.ResultFingerprint,
+	}
+}
+
+func fromRemoteCacheInstance(key models.AlertRuleKey, instance remoteCacheInstance) *models.AlertInstance {
+	return &models.AlertInstance{
+		AlertInstanceKey: models.AlertInstanceKey{
+			RuleOrgID:  key.OrgID,
+			RuleUID:    key.UID,
+			LabelsHash: instance.LabelsHash,
+		},
+		Labels:            instance.Labels,
+		CurrentState:      instance.CurrentState,
+		CurrentReason:     instance.CurrentReason,
+		CurrentStateSince: instance.CurrentStateSince,
+		CurrentStateEnd:   instance.CurrentStateEnd,
+		LastEvalTime:      instance.LastEvalTime,
+		LastSentAt:        instance.LastSentAt,
+		ResolvedAt:        instance.ResolvedAt,
+		ResultFingerprint: instance.ResultFingerprint,
+	}
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestRemoteCacheInstanceStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	newInstance := func(orgID int64, ruleUID string, labelValue string, state models.InstanceStateType) models.AlertInstance {
+		labels := models.InstanceLabels{"label": labelValue}
+		_, hash, err := labels.StringAndHash()
+		require.NoError(t, err)
+		return models.AlertInstance{
+			AlertInstanceKey:  models.AlertInstanceKey{RuleOrgID: orgID, RuleUID: ruleUID, LabelsHash: hash},
+			Labels:            labels,
+			CurrentState:      state,
+			LastEvalTime:      now,
+			CurrentStateSince: now.Add(-time.Minute),
+			CurrentStateEnd:   now.Add(time.Minute),
+			LastSentAt:        &now,
+		}
+	}
+	listRule := func(t *testing.T, st *RemoteCacheInstanceStore, orgID int64, ruleUID string) []*models.AlertInstance {
+		t.Helper()
+		instances, err := st.ListAlertInstances(ctx, &models.ListAlertInstancesQuery{RuleOrgID: orgID, RuleUID: ruleUID})
+		require.NoError(t, err)
+		return instances
+	}
+
+	t.Run("saved instances are listed by rule and organization", func(t *testing.T) {
+		st := NewRemoteCacheInstanceStore(remotecache.NewFakeCacheStorage(), featuremgmt.WithFeatures(), log.NewNopLogger())
+		firing := newInstance(1, "rule-1", "a", models.InstanceStateFiring)
+		normal := newInstance(1, "rule-1", "b", models.InstanceStateNormal)
+		require.NoError(t, st.SaveRuleInstances(ctx, models.AlertRuleKey{OrgID: 1, UID: "rule-1"}, []models.AlertInstance{firing, normal}, nil))
+		require.NoError(t, st.SaveAlertInstance(ctx, newInstance(1, "rule-2", "a", models.InstanceStatePending)))
+		require.NoError(t, st.SaveAlertInstance(ctx, newInstance(2, "rule-3", "a", models.InstanceStateFiring)))
+
+		instances := listRule(t, st, 1, "rule-1")
+		require.Len(t, instances, 2)
+		require.Contains(t, instances, &firing)
+		require.Contains(t, instances, &normal)
+
+		require.Len(t, listRule(t, st, 1, ""), 3)
+		orgIDs, err := st.FetchOrgIds(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []int64{1, 2}, orgIDs)
+	})
+
+	t.Run("normal instances are not listed if the feature toggle is enabled", func(t *testing.T) {
+		st := NewRemoteCacheInstanceStore(remotecache.NewFakeCacheStorage(), featuremgmt.WithFeatures(featuremgmt.FlagAlertingNoNormalState), log.NewNopLogger())
+		require.NoError(t, st.SaveAlertInstance(ctx, newInstance(1, "rule-1", "a", models.InstanceStateNormal)))
+		require.NoError(t, st.SaveAlertInstance(ctx, newInstance(1, "rule-1", "b", models.InstanceStateFiring)))
+		instances := listRule(t, st, 1, "rule-1")
+		require.Len(t, instances, 1)
+		require.Equal(t, models.InstanceStateFiring, instances[0].CurrentState)
+	})
+
+	t.Run("save updates and deletes instances of the rule", func(t *testing.T) {
+		st := NewRemoteCacheInstanceStore(remotecache.NewFakeCacheStorage(), featuremgmt.WithFeatures(), log.NewNopLogger())
+		key := models.AlertRuleKey{OrgID: 1, UID: "rule-1"}
+		a := newInstance(1, "rule-1", "a", models.InstanceStatePending)
+		b := newInstance(1, "rule-1", "b", models.InstanceStateFiring)
+		require.NoError(t, st.SaveRuleInstances(ctx, key, []models.AlertInstance{a, b}, nil))
+
+		a.CurrentState = models.InstanceStateFiring
+		require.NoError(t, st.SaveRuleInstances(ctx, key, []models.AlertInstance{a}, []models.AlertInstanceKey{b.AlertInstanceKey}))
+		require.Equal(t, []*models.AlertInstance{&a}, listRule(t, st, 1, "rule-1"))
+
+		require.NoError(t, st.DeleteAlertInstances(ctx, a.AlertInstanceKey))
+		require.Empty(t, listRule(t, st, 1, "rule-1"))
+	})
+
+	t.Run("instances of another rule are rejected", func(t *testing.T) {
+		st := NewRemoteCacheInstanceStore(remotecache.NewFakeCacheStorage(), featuremgmt.WithFeatures(), log.NewNopLogger())
+		err := st.SaveRuleInstances(ctx, models.AlertRuleKey{OrgID: 1, UID: "rule-1"}, []models.AlertInstance{newInstance(1, "rule-2", "a", models.InstanceStateFiring)}, nil)
+		require.Error(t, err)
+	})
+
+	t.Run("delete by rule removes the rule from the index", func(t *testing.T) {
+		st := NewRemoteCacheInstanceStore(remotecache.NewFakeCacheStorage(), featuremgmt.WithFeatures(), log.NewNopLogger())
+		require.NoError(t, st.SaveAlertInstance(ctx, newInstance(1, "rule-1", "a", models.InstanceStateFiring)))
+		require.NoError(t, st.SaveAlertInstance(ctx, newInstance(1, "rule-2", "a", models.InstanceStateFiring)))
+
+		require.NoError(t, st.DeleteAlertInstancesByRule(ctx, models.AlertRuleKey{OrgID: 1, UID: "rule-1"}))
+		instances := listRule(t, st, 1, "")
+		require.Len(t, instances, 1)
+		require.Equal(t, "rule-2", instances[0].RuleUID)
+		require.Empty(t, st.ruleLocks, "locks of rules should be removed when they are released")
+	})
+
+	t.Run("index is shared by stores that use the same cache", func(t *testing.T) {
+		cache := remotecache.NewFakeCacheStorage()
+		st1 := NewRemoteCacheInstanceStore(cache, featuremgmt.WithFeatures(), log.NewNopLogger())
+		st2 := NewRemoteCacheInstanceStore(cache, featuremgmt.WithFeatures(), log.NewNopLogger())
+		require.NoError(t, st1.SaveAlertInstance(ctx, newInstance(1, "rule-1", "a", models.InstanceStateFiring)))
+		require.NoError(t, st2.SaveAlertInstance(ctx, newInstance(1, "rule-2", "a", models.InstanceStateFiring)))
+		require.Len(t, listRule(t, st1, 1, ""), 2)
+	})
+
+	t.Run("full sync replaces all instances", func(t *testing.T) {
+		st := NewRemoteCacheInstanceStore(remotecache.NewFakeCacheStorage(), featuremgmt.WithFeatures(), log.NewNopLogger())
+		require.NoError(t, st.SaveAlertInstance(ctx, newInstance(1, "rule-1", "a", models.InstanceStateFiring)))
+		require.NoError(t, st.SaveAlertInstance(ctx, newInstance(1, "rule-2", "a", models.InstanceStateFiring)))
+
+		synced := newInstance(1, "rule-2", "b", models.InstanceStatePending)
+		require.NoError(t, st.FullSync(ctx, []models.AlertInstance{synced}))
+		require.Equal(t, []*models.AlertInstance{&synced}, listRule(t, st, 1, ""))
+	})
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+type ruleLintRecord struct {
+	ID           int64     `xorm:"pk autoincr 'id'"`
+	OrgID        int64     `xorm:"org_id"`
+	RuleUID      string    `xorm:"rule_uid"`
+	NamespaceUID string    `xorm:"namespace_uid"`
+	RuleGroup    string    `xorm:"rule_group"`
+	Score        int       `xorm:"score"`
+	Issues       string    `xorm:"issues"`
+	Linted       time.Time `xorm:"linted"`
+}
+
+func (r ruleLintRecord) TableName() string {
+	return "alert_rule_lint"
+}
+
+// ListRuleLintResults returns the stored results of the rule linter that match the query, ordered by rule UID.
+func (st DBstore) ListRuleLintResults(ctx context.Context, query *models.ListRuleLintResultsQuery) ([]models.RuleLintResult, error) {
+	var result []models.RuleLintResult
+	err := st.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		q := sess.Table(ruleLintRecord{})
+		if query.OrgID >= 0 {
+			q = q.Where("org_id = ?", query.OrgID)
+		}
+		if len(query.RuleUIDs) > 0 {
+			q = q.In("rule_uid", query.RuleUIDs)
+		}
+		var records []ruleLintRecord
+		if err := q.Asc("rule_uid").Find(&records); err != nil {
+			return fmt.Errorf("failed to list rule lint results: %w", err)
+		}
+		result = make([]models.RuleLintResult, 0, len(records))
+		for _, rec := range records {
+			var issues []models.RuleLintIssue
+			if err := json.Unmarshal([]byte(rec.Issues), &issues); err != nil {
+				return fmt.Errorf("failed to unmarshal issues of rule %s: %w", rec.RuleUID, err)
+			}
+			result = append(result, models.RuleLintResult{
+				OrgID:        rec.OrgID,
+				RuleUID:      rec.RuleUID,
+				NamespaceUID: rec.NamespaceUID,
+				RuleGroup:    rec.RuleGroup,
+				Issues:       issues,
+				Score:        rec.Score,
+				Linted:       rec.Linted,
+			})
+		}
+		return nil
+	})
+	return result, err
+}
+
+// SaveRuleLintResults replaces the stored results of the rule linter for the rules of the given results.
+func (st DBstore) SaveRuleLintResults(ctx context.Context, results []models.RuleLintResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		for _, r := range results {
+			issues, err := json.Marshal(r.Issues)
+			if err != nil {
+				return fmt.Errorf("failed to marshal issues of rule %s: %w", r.RuleUID, err)
+			}
+			if _, err := sess.Where("org_id = ? AND rule_uid = ?", r.OrgID, r.RuleUID).Delete(&ruleLintRecord{}); err != nil {
+				return fmt.Errorf("failed to delete rule lint result: %w", err)
+			}
+			if _, err := sess.Insert(&ruleLintRecord{
+				OrgID:        r.OrgID,
+				RuleUID:      r.RuleUID,
+				NamespaceUID: r.NamespaceUID,
+				RuleGroup:    r.RuleGroup,
+				Score:        r.Score,
+				Issues:       string(issues),
+				Linted:       r.Linted,
+			}); err != nil {
+				return fmt.Errorf("failed to insert rule lint result: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteRuleLintResults deletes the stored results of the rule linter for the given rules.
+func (st DBstore) DeleteRuleLintResults(ctx context.Context, orgID int64, ruleUIDs ...string) error {
+	if len(ruleUIDs) == 0 {
+		return nil
+	}
+	return st.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		if _, err := sess.Where("org_id = ?", orgID).In("rule_uid", ruleUIDs).Delete(&ruleLintRecord{}); err != nil {
+			return fmt.Errorf("failed to delete rule lint results: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestIntegrationRuleLintResults(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	store := &DBstore{
+		SQLStore: db.InitTestDB(t),
+		Logger:   log.NewNopLogger(),
+	}
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	issues := []models.RuleLintIssue{
+		{Check: models.RuleLintCheckMissingDataSource, Severity: models.RuleLintSeverityError, Message: "missing", RefID: "A"},
+	}
+	rule1 := models.RuleGen.With(models.RuleGen.WithOrgID(1)).GenerateRef()
+	rule2 := models.RuleGen.With(models.RuleGen.WithOrgID(1)).GenerateRef()
+	otherOrg := models.RuleGen.With(models.RuleGen.WithOrgID(2)).GenerateRef()
+
+	require.NoError(t, store.SaveRuleLintResults(ctx, []models.RuleLintResult{
+		models.NewRuleLintResult(rule1, issues, now),
+		models.NewRuleLintResult(rule2, nil, now),
+		models.NewRuleLintResult(otherOrg, nil, now),
+	}))
+
+	results, err := store.ListRuleLintResults(ctx, &models.ListRuleLintResultsQuery{OrgID: 1, RuleUIDs: []string{rule1.UID}})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, rule1.UID, results[0].RuleUID)
+	require.Equal(t, rule1.NamespaceUID, results[0].NamespaceUID)
+	require.Equal(t, rule1.RuleGroup, results[0].RuleGroup)
+	require.Equal(t, issues, results[0].Issues)
+	require.Equal(t, 60, results[0].Score)
+	require.True(t, now.Equal(results[0].Linted))
+
+	t.Run("saving replaces the result of the rule", func(t *testing.T) {
+		require.NoError(t, store.SaveRuleLintResults(ctx, []models.RuleLintResult{models.NewRuleLintResult(rule1, nil, now.Add(time.Hour))}))
+		results, err := store.ListRuleLintResults(ctx, &models.ListRuleLintResultsQuery{OrgID: 1})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		for _, r := range results {
+			require.Empty(t, r.Issues)
+			require.Equal(t, models.RuleLintMaxScore, r.Score)
+		}
+	})
+
+	t.Run("deleting removes only the results of the given rules", func(t *testing.T) {
+		require.NoError(t, store.DeleteRuleLintResults(ctx, 1, rule1.UID, rule2.UID))
+		results, err := store.ListRuleLintResults(ctx, &models.ListRuleLintResultsQuery{OrgID: 1})
+		require.NoError(t, err)
+		require.Empty(t, results)
+		results, err = store.ListRuleLintResults(ctx, &models.ListRuleLintResultsQuery{OrgID: 2})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+	})
+
+	t.Run("listing with a negative org returns the results of all orgs", func(t *testing.T) {
+		require.NoError(t, store.SaveRuleLintResults(ctx, []models.RuleLintResult{models.NewRuleLintResult(rule1, nil, now)}))
+		results, err := store.ListRuleLintResults(ctx, &models.ListRuleLintResultsQuery{OrgID: -1})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+	})
+}
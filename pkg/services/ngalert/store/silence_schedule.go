@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/timeinterval"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+type silenceScheduleRecord struct {
+	ID            int64     `xorm:"pk autoincr 'id'"`
+	OrgID         int64     `xorm:"org_id"`
+	UID           string    `xorm:"uid"`
+	Title         string    `xorm:"title"`
+	Comment       string    `xorm:"'comment'"`
+	Matchers      string    `xorm:"matchers"`
+	Cron          string    `xorm:"cron"`
+	Duration      int64     `xorm:"duration"`
+	Location      string    `xorm:"location"`
+	TimeIntervals string    `xorm:"time_intervals"`
+	IsPaused      bool      `xorm:"is_paused"`
+	Silences      string    `xorm:"silences"`
+	CreatedBy     string    `xorm:"created_by"`
+	Created       time.Time `xorm:"'created'"`
+	UpdatedBy     string    `xorm:"updated_by"`
+	Updated       time.Time `xorm:"'updated'"`
+	Version       int64     `xorm:"'version'"`
+}
+
+func (r silenceScheduleRecord) TableName() string {
+	return "alert_silence_schedule"
+}
+
+// GetSilenceSchedule returns the silence schedule with the UID. It returns models.ErrSilenceScheduleNotFound
+// if the schedule does not exist.
+func (st DBstore) GetSilenceSchedule(ctx context.Context, orgID int64, uid string) (*models.SilenceSchedule, error) {
+	var result *models.SilenceSchedule
+	err := st.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		var rec silenceScheduleRecord
+		exists, err := sess.Where("org_id = ? AND uid = ?", orgID, uid).Get(&rec)
+		if err != nil {
+			return fmt.Errorf("failed to get silence schedule: %w", err)
+		}
+		if !exists {
+			return models.ErrSilenceScheduleNotFound.Errorf("silence schedule %s not found", uid)
+		}
+		result, err = silenceScheduleFromRecord(rec)
+		return err
+	})
+	return result, err
+}
+
+// ListSilenceSchedules returns the silence schedules that match the query, ordered by title.
+func (st DBstore) ListSilenceSchedules(ctx context.Context, query *models.ListSilenceSchedulesQuery) ([]*models.SilenceSchedule, error) {
+	var result []*models.SilenceSchedule
+	err := st.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		q := sess.Table(silenceScheduleRecord{})
+		if query.OrgID > 0 {
+			q = q.Where("org_id = ?", query.OrgID)
+		}
+		if query.ExcludePaused {
+			q = q.Where("is_paused = ?", st.SQLStore.GetDialect().BooleanStr(false))
+		}
+		var records []silenceScheduleRecord
+		if err := q.Asc("org_id", "title").Find(&records); err != nil {
+			return fmt.Errorf("failed to list silence schedules: %w", err)
+		}
+		result = make([]*models.SilenceSchedule, 0, len(records))
+		for _, rec := range records {
+			s, err := silenceScheduleFromRecord(rec)
+			if err != nil {
+				return err
+			}
+			result = append(result, s)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// InsertSilenceSchedule inserts a silence schedule. A UID is generated if the schedule does not have one.
+func (st DBstore) InsertSilenceSchedule(ctx context.Context, s *models.SilenceSchedule) error {
+	return st.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		if s.UID == "" {
+			s.UID = util.GenerateShortUID()
+		}
+		s.Version = 1
+		rec, err := silenceScheduleToRecord(s)
+		if err != nil {
+			return err
+		}
+		if _, err := sess.Insert(&rec); err != nil {
+			if st.SQLStore.GetDialect().IsUniqueConstraintViolation(err) {
+				return models.ErrSilenceScheduleExists.Errorf("silence schedule with the same UID or title already exists")
+			}
+			return fmt.Errorf("failed to insert silence schedule: %w", err)
+		}
+		return nil
+	})
+}
+
+// UpdateSilenceSchedule updates a silence schedule if its version did not change since it was read, and increments
+// the version. It returns models.ErrSilenceScheduleConflict if the schedule was changed or deleted in the meantime.
+func (st DBstore) UpdateSilenceSchedule(ctx context.Context, s *models.SilenceSchedule) error {
+	return st.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		rec, err := silenceScheduleToRecord(s)
+		if err != nil {
+			return err
+		}
+		rec.Version = s.Version + 1
+		updated, err := sess.Where("org_id = ? AND uid = ? AND version = ?", s.OrgID, s.UID, s.Version).Omit("id").AllCols().Update(&rec)
+		if err != nil {
+			if st.SQLStore.GetDialect().IsUniqueConstraintViolation(err) {
+				return models.ErrSilenceScheduleExists.Errorf("silence schedule with the same title already exists")
+			}
+			return fmt.Errorf("failed to update silence schedule: %w", err)
+		}
+		if updated == 0 {
+			return models.ErrSilenceScheduleConflict.Errorf("silence schedule %s version %d was changed or deleted", s.UID, s.Version)
+		}
+		s.Version = rec.Version
+		return nil
+	})
+}
+
+// DeleteSilenceSchedule deletes a silence schedule. It does nothing if the schedule does not exist.
+func (st DBstore) DeleteSilenceSchedule(ctx context.Context, orgID int64, uid string) error {
+	return st.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		if _, err := sess.Where("org_id = ? AND uid = ?", orgID, uid).Delete(&silenceScheduleRecord{}); err != nil {
+			return fmt.Errorf("failed to delete silence schedule: %w", err)
+		}
+		return nil
+	})
+}
+
+func silenceScheduleToRecord(s *models.SilenceSchedule) (silenceScheduleRecord, error) {
+	matchers, err := json.Marshal(s.Matchers)
+	if err != nil {
+		return silenceScheduleRecord{}, fmt.Errorf("failed to marshal matchers: %w", err)
+	}
+	intervals := s.TimeIntervals
+	if intervals == nil {
+		intervals = []timeinterval.TimeInterval{}
+	}
+	timeIntervals, err := json.Marshal(intervals)
+	if err != nil {
+		return silenceScheduleRecord{}, fmt.Errorf("failed to marshal time intervals: %w", err)
+	}
+	scheduled := s.Silences
+	if scheduled == nil {
+		scheduled = []models.ScheduledSilence{}
+	}
+	silences, err := json.Marshal(scheduled)
+	if err != nil {
+		return silenceScheduleRecord{}, fmt.Errorf("failed to marshal silences: %w", err)
+	}
+	return silenceScheduleRecord{
+		OrgID:         s.OrgID,
+		UID:           s.UID,
+		Title:         s.Title,
+		Comment:       s.Comment,
+		Matchers:      string(matchers),
+		Cron:          s.Cron,
+		Duration:      int64(s.Duration.Seconds()),
+		Location:      s.Location,
+		TimeIntervals: string(timeIntervals),
+		IsPaused:      s.IsPaused,
+		Silences:      string(silences),
+		CreatedBy:     s.CreatedBy,
+		Created:       s.Created,
+		UpdatedBy:     s.UpdatedBy,
+		Updated:       s.Updated,
+		Version:       s.Version,
+	}, nil
+}
+
+func silenceScheduleFromRecord(rec silenceScheduleRecord) (*models.SilenceSchedule, error) {
+	s := &models.SilenceSchedule{
+		UID:       rec.UID,
+		OrgID:     rec.OrgID,
+		Title:     rec.Title,
+		Comment:   rec.Comment,
+		Cron:      rec.Cron,
+		Duration:  time.Duration(rec.Duration) * time.Second,
+		Location:  rec.Location,
+		IsPaused:  rec.IsPaused,
+		CreatedBy: rec.CreatedBy,
+		Created:   rec.Created,
+		UpdatedBy: rec.UpdatedBy,
+		Updated:   rec.Updated,
+		Version:   rec.Version,
+	}
+	var matchers amv2.Matchers
+	if err := json.Unmarshal([]byte(rec.Matchers), &matchers); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal matchers of silence schedule %s: %w", rec.UID, err)
+	}
+	s.Matchers = matchers
+	if err := json.Unmarshal([]byte(rec.TimeIntervals), &s.TimeIntervals); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal time intervals of silence schedule %s: %w", rec.UID, err)
+	}
+	if len(s.TimeIntervals) == 0 {
+		s.TimeIntervals = nil
+	}
+	if err := json.Unmarshal([]byte(rec.Silences), &s.Silences); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal silences of silence schedule %s: %w", rec.UID, err)
+	}
+	return s, nil
+}
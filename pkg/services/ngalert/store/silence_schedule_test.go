@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func TestIntegrationSilenceScheduleStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	store := &DBstore{
+		SQLStore: db.InitTestDB(t),
+		Logger:   log.NewNopLogger(),
+	}
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	newSchedule := func(orgID int64, title string) *models.SilenceSchedule {
+		return &models.SilenceSchedule{
+			OrgID:    orgID,
+			Title:    title,
+			Comment:  "Weekly database maintenance",
+			Matchers: amv2.Matchers{{Name: util.Pointer("team"), Value: util.Pointer("db"), IsRegex: util.Pointer(false)}},
+			TimeIntervals: []timeinterval.TimeInterval{{
+				Weekdays: []timeinterval.WeekdayRange{{InclusiveRange: timeinterval.InclusiveRange{Begin: 6, End: 6}}},
+			}},
+			CreatedBy: "user-uid",
+			Created:   now,
+			UpdatedBy: "user-uid",
+			Updated:   now,
+		}
+	}
+
+	t.Run("inserted schedule is returned", func(t *testing.T) {
+		s := newSchedule(1, "insert")
+		require.NoError(t, store.InsertSilenceSchedule(ctx, s))
+		require.NotEmpty(t, s.UID)
+		require.Equal(t, int64(1), s.Version)
+
+		got, err := store.GetSilenceSchedule(ctx, 1, s.UID)
+		require.NoError(t, err)
+		require.Equal(t, s.Title, got.Title)
+		require.Equal(t, s.Comment, got.Comment)
+		require.Equal(t, s.Matchers, got.Matchers)
+		require.Equal(t, s.TimeIntervals, got.TimeIntervals)
+		require.Empty(t, got.Silences)
+		require.True(t, now.Equal(got.Created))
+
+		_, err = store.GetSilenceSchedule(ctx, 2, s.UID)
+		require.ErrorIs(t, err, models.ErrSilenceScheduleNotFound)
+	})
+
+	t.Run("title is unique in the organization", func(t *testing.T) {
+		require.NoError(t, store.InsertSilenceSchedule(ctx, newSchedule(1, "unique")))
+		require.ErrorIs(t, store.InsertSilenceSchedule(ctx, newSchedule(1, "unique")), models.ErrSilenceScheduleExists)
+		require.NoError(t, store.InsertSilenceSchedule(ctx, newSchedule(2, "unique")))
+	})
+
+	t.Run("update fails if the version changed", func(t *testing.T) {
+		s := newSchedule(1, "update")
+		require.NoError(t, store.InsertSilenceSchedule(ctx, s))
+
+		stale := *s
+		s.Silences = []models.ScheduledSilence{{ID: "silence", StartsAt: now, EndsAt: now.Add(time.Hour)}}
+		require.NoError(t, store.UpdateSilenceSchedule(ctx, s))
+		require.Equal(t, int64(2), s.Version)
+
+		require.ErrorIs(t, store.UpdateSilenceSchedule(ctx, &stale), models.ErrSilenceScheduleConflict)
+
+		got, err := store.GetSilenceSchedule(ctx, 1, s.UID)
+		require.NoError(t, err)
+		require.Len(t, got.Silences, 1)
+		require.Equal(t, "silence", got.Silences[0].ID)
+	})
+
+	t.Run("list excludes paused schedules", func(t *testing.T) {
+		paused := newSchedule(3, "paused")
+		paused.IsPaused = true
+		require.NoError(t, store.InsertSilenceSchedule(ctx, paused))
+		require.NoError(t, store.InsertSilenceSchedule(ctx, newSchedule(3, "active")))
+
+		all, err := store.ListSilenceSchedules(ctx, &models.ListSilenceSchedulesQuery{OrgID: 3})
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		require.Equal(t, "active", all[0].Title)
+
+		active, err := store.ListSilenceSchedules(ctx, &models.ListSilenceSchedulesQuery{OrgID: 3, ExcludePaused: true})
+		require.NoError(t, err)
+		require.Len(t, active, 1)
+		require.Equal(t, "active", active[0].Title)
+	})
+
+	t.Run("deleted schedule is not found", func(t *testing.T) {
+		s := newSchedule(1, "delete")
+		require.NoError(t, store.InsertSilenceSchedule(ctx, s))
+		require.NoError(t, store.DeleteSilenceSchedule(ctx, 1, s.UID))
+		_, err := store.GetSilenceSchedule(ctx, 1, s.UID)
+		require.ErrorIs(t, err, models.ErrSilenceScheduleNotFound)
+	})
+}
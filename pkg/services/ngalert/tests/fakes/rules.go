@@ -21,10 +21,12 @@ type RuleStore struct {
 	t   *testing.T
 	mtx sync.Mutex
 	// OrgID -> RuleGroup -> Namespace -> Rules
-	Rules       map[int64][]*models.AlertRule
-	Hook        func(cmd any) error // use Hook if you need to intercept some query and return an error
-	RecordedOps []any
-	Folders     map[int64][]*folder.Folder
+	Rules map[int64][]*models.AlertRule
+	// RuleVersions contains previous versions of the rules in Rules, by OrgID.
+	RuleVersions map[int64][]*models.AlertRule
+	Hook         func(cmd any) error // use Hook if you need to intercept some query and return an error
+	RecordedOps  []any
+	Folders      map[int64][]*folder.Folder
 }
 
 type GenericRecordedQuery struct {
@@ -144,6 +146,21 @@ func (f *RuleStore) GetAlertRuleByUID(_ context.Context, q *models.GetAlertRuleB
 	return nil, models.ErrAlertRuleNotFound
 }
 
+func (f *RuleStore) GetAlertRuleVersion(_ context.Context, orgID int64, ruleUID string, version int64) (*models.AlertRule, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.RecordedOps = append(f.RecordedOps, GenericRecordedQuery{
+		Name:   "GetAlertRuleVersion",
+		Params: []any{orgID, ruleUID, version},
+	})
+	for _, rule := range slices.Concat(f.Rules[orgID], f.RuleVersions[orgID]) {
+		if rule.UID == ruleUID && rule.Version == version {
+			return rule, nil
+		}
+	}
+	return nil, models.ErrAlertRuleNotFound
+}
+
 func (f *RuleStore) GetAlertRulesGroupByRuleUID(_ context.Context, q *models.GetAlertRulesGroupByRuleUIDQuery) ([]*models.AlertRule, error) {
 	f.mtx.Lock()
 	defer f.mtx.Unlock()
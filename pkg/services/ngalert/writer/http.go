@@ -0,0 +1,81 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+)
+
+// maxErrorBodySize is the maximum number of bytes of a failed response that are included in the returned error.
+const maxErrorBodySize = 1024
+
+func validateHTTPTarget(rawURL string, username, password string, timeout time.Duration) error {
+	if username != "" && password == "" {
+		return fmt.Errorf("basic auth password is required if username is set")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid URL: %q must be absolute", rawURL)
+	}
+
+	if timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+
+	return nil
+}
+
+func newHTTPClient(httpClientProvider HttpClientProvider, tracer tracing.Tracer, username, password string, headers map[string]string) (*http.Client, error) {
+	h := make(http.Header)
+	for k, v := range headers {
+		h.Add(k, v)
+	}
+	return httpClientProvider.New(httpclient.Options{
+		Middlewares: []httpclient.Middleware{
+			httpclient.TracingMiddleware(tracer),
+		},
+		BasicAuth: createAuthOpts(username, password),
+		Header:    h,
+	})
+}
+
+// post sends the body to the URL and returns the status code of the response.
+// Responses with a status code other than 2xx are returned as an error.
+func post(ctx context.Context, client *http.Client, timeout time.Duration, url, contentType string, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "grafana-recording-rule")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,15 @@
+package writer
+
+import (
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+)
+
+// newTestClientProvider returns a provider that only applies the authentication and headers of the client options.
+func newTestClientProvider() *httpclient.Provider {
+	return httpclient.NewProvider(httpclient.ProviderOptions{
+		Middlewares: []httpclient.Middleware{
+			httpclient.BasicAuthenticationMiddleware(),
+			httpclient.CustomHeadersMiddleware(),
+		},
+	})
+}
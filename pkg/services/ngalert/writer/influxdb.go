@@ -0,0 +1,149 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const influxDBBackendType = "influxdb"
+
+// influxDBValueField is the name of the field that holds the value of a recorded point.
+const influxDBValueField = "value"
+
+var (
+	measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+	tagEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+)
+
+// InfluxDBWriter writes the results of recording rules to an InfluxDB line protocol endpoint.
+// The metric name is the measurement, labels are tags and the value is written to the field "value".
+type InfluxDBWriter struct {
+	client  *http.Client
+	url     string
+	timeout time.Duration
+	clock   clock.Clock
+	logger  log.Logger
+	metrics *metrics.RemoteWriter
+}
+
+func NewInfluxDBWriter(
+	settings setting.RecordingRuleInfluxDBSettings,
+	httpClientProvider HttpClientProvider,
+	clock clock.Clock,
+	tracer tracing.Tracer,
+	l log.Logger,
+	metrics *metrics.RemoteWriter,
+) (*InfluxDBWriter, error) {
+	if err := validateHTTPTarget(settings.URL, settings.BasicAuthUsername, settings.BasicAuthPassword, settings.Timeout); err != nil {
+		return nil, err
+	}
+
+	// The token takes precedence over basic auth as both use the Authorization header.
+	username, password := settings.BasicAuthUsername, settings.BasicAuthPassword
+	var headers map[string]string
+	if settings.Token != "" {
+		username, password = "", ""
+		headers = map[string]string{"Authorization": "Token " + settings.Token}
+	}
+	cl, err := newHTTPClient(httpClientProvider, tracer, username, password, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InfluxDBWriter{
+		client:  cl,
+		url:     settings.URL,
+		timeout: settings.Timeout,
+		clock:   clock,
+		logger:  l,
+		metrics: metrics,
+	}, nil
+}
+
+// Write writes the given frames to the InfluxDB endpoint.
+func (w InfluxDBWriter) Write(ctx context.Context, name string, t time.Time, frames data.Frames, extraLabels map[string]string) error {
+	l := w.logger.FromContext(ctx)
+	ruleKey, found := models.RuleKeyFromContext(ctx)
+	if !found {
+		// sanity check, this should never happen
+		return fmt.Errorf("rule key not found in context")
+	}
+	lvs := []string{fmt.Sprint(ruleKey.OrgID), influxDBBackendType}
+
+	points, err := PointsFromFrames(name, t, frames, extraLabels)
+	if err != nil {
+		return err
+	}
+
+	var body strings.Builder
+	for _, p := range points {
+		// InfluxDB does not support NaN and infinite values.
+		if math.IsNaN(p.Metric.V) || math.IsInf(p.Metric.V, 0) {
+			l.Debug("Skipping point with unsupported value", "name", name, "value", p.Metric.V)
+			continue
+		}
+		body.WriteString(EncodeLineProtocol(p))
+		body.WriteByte('\n')
+	}
+	if body.Len() == 0 {
+		return nil
+	}
+
+	l.Debug("Writing metric", "name", name)
+	writeStart := w.clock.Now()
+	statusCode, writeErr := post(ctx, w.client, w.timeout, w.url, "text/plain; charset=utf-8", []byte(body.String()))
+	w.metrics.WriteDuration.WithLabelValues(lvs...).Observe(w.clock.Now().Sub(writeStart).Seconds())
+
+	lvs = append(lvs, fmt.Sprint(statusCode))
+	w.metrics.WritesTotal.WithLabelValues(lvs...).Inc()
+
+	if writeErr != nil {
+		return fmt.Errorf("failed to write points: %w", writeErr)
+	}
+
+	return nil
+}
+
+// EncodeLineProtocol returns the point in the InfluxDB line protocol, without the trailing newline.
+// Tags are sorted by key and tags with empty values are omitted, as InfluxDB does not accept them.
+func EncodeLineProtocol(p Point) string {
+	keys := make([]string, 0, len(p.Labels))
+	for k, v := range p.Labels {
+		if k == "" || v == "" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(measurementEscaper.Replace(p.Name))
+	for _, k := range keys {
+		b.WriteByte(',')
+		b.WriteString(tagEscaper.Replace(k))
+		b.WriteByte('=')
+		b.WriteString(tagEscaper.Replace(p.Labels[k]))
+	}
+	b.WriteByte(' ')
+	b.WriteString(influxDBValueField)
+	b.WriteByte('=')
+	b.WriteString(strconv.FormatFloat(p.Metric.V, 'g', -1, 64))
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatInt(p.Metric.T.UnixNano(), 10))
+	return b.String()
+}
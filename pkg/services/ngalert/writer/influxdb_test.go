@@ -0,0 +1,136 @@
+package writer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestEncodeLineProtocol(t *testing.T) {
+	ts := time.Unix(1700000000, 5)
+	for _, tc := range []struct {
+		name     string
+		point    Point
+		expected string
+	}{
+		{
+			name:     "no labels",
+			point:    Point{Name: "up", Metric: Metric{T: ts, V: 1}},
+			expected: "up value=1 1700000000000000005",
+		},
+		{
+			name:     "labels are sorted and empty values are omitted",
+			point:    Point{Name: "up", Labels: map[string]string{"job": "grafana", "empty": "", "instance": "a"}, Metric: Metric{T: ts, V: 0.5}},
+			expected: "up,instance=a,job=grafana value=0.5 1700000000000000005",
+		},
+		{
+			name:     "special characters are escaped",
+			point:    Point{Name: "my metric,a", Labels: map[string]string{"path key": "a=b,c d"}, Metric: Metric{T: ts, V: -2.5e10}},
+			expected: `my\ metric\,a,path\ key=a\=b\,c\ d value=-2.5e+10 1700000000000000005`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, EncodeLineProtocol(tc.point))
+		})
+	}
+}
+
+func TestNewInfluxDBWriter(t *testing.T) {
+	provider := newTestClientProvider()
+	for _, tc := range []struct {
+		name     string
+		settings setting.RecordingRuleInfluxDBSettings
+		err      bool
+	}{
+		{
+			name:     "valid settings",
+			settings: setting.RecordingRuleInfluxDBSettings{URL: "http://localhost:8086/api/v2/write", Timeout: time.Second},
+		},
+		{
+			name:     "relative URL",
+			settings: setting.RecordingRuleInfluxDBSettings{URL: "/api/v2/write", Timeout: time.Second},
+			err:      true,
+		},
+		{
+			name:     "username without password",
+			settings: setting.RecordingRuleInfluxDBSettings{URL: "http://localhost:8086/write", BasicAuthUsername: "user", Timeout: time.Second},
+			err:      true,
+		},
+		{
+			name:     "zero timeout",
+			settings: setting.RecordingRuleInfluxDBSettings{URL: "http://localhost:8086/write"},
+			err:      true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewInfluxDBWriter(tc.settings, provider, clock.New(), tracing.InitializeTracerForTest(), log.NewNopLogger(), metrics.NewRemoteWriterMetrics(prometheus.NewRegistry()))
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestInfluxDBWriter_Write(t *testing.T) {
+	var (
+		status  = http.StatusNoContent
+		body    string
+		headers http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		body = string(b)
+		headers = r.Header.Clone()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	writer, err := NewInfluxDBWriter(
+		setting.RecordingRuleInfluxDBSettings{URL: srv.URL + "/api/v2/write?bucket=metrics", Token: "secret", Timeout: time.Second},
+		newTestClientProvider(),
+		clock.New(),
+		tracing.InitializeTracerForTest(),
+		log.NewNopLogger(),
+		metrics.NewRemoteWriterMetrics(prometheus.NewRegistry()),
+	)
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	frames := frameGenFromLabels(t, data.FrameTypeNumericWide, []map[string]string{{"foo": "1"}})
+	value := extractValue(t, frames, map[string]string{"foo": "1"}, data.FrameTypeNumericWide)
+	ctx := ngmodels.WithRuleKey(context.Background(), ngmodels.GenerateRuleKey(1))
+
+	t.Run("writes points in line protocol", func(t *testing.T) {
+		require.NoError(t, writer.Write(ctx, "test", now, frames, map[string]string{"extra": "label"}))
+
+		expected := EncodeLineProtocol(Point{Name: "test", Labels: map[string]string{"extra": "label", "foo": "1"}, Metric: Metric{T: now, V: value}}) + "\n"
+		require.Equal(t, expected, body)
+		require.Equal(t, "Token secret", headers.Get("Authorization"))
+		require.Equal(t, "text/plain; charset=utf-8", headers.Get("Content-Type"))
+	})
+
+	t.Run("returns error when the endpoint fails", func(t *testing.T) {
+		status = http.StatusBadRequest
+		t.Cleanup(func() { status = http.StatusNoContent })
+
+		err := writer.Write(ctx, "test", now, frames, nil)
+		require.ErrorContains(t, err, "unexpected status code 400")
+	})
+}
@@ -0,0 +1,146 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const otlpBackendType = "otlp"
+
+// otlpScopeName is the name of the instrumentation scope of the metrics written by recording rules.
+const otlpScopeName = "grafana-recording-rules"
+
+// OTLPWriter writes the results of recording rules as gauges to an OTLP/HTTP metrics endpoint.
+type OTLPWriter struct {
+	client  *http.Client
+	url     string
+	timeout time.Duration
+	clock   clock.Clock
+	logger  log.Logger
+	metrics *metrics.RemoteWriter
+}
+
+func NewOTLPWriter(
+	settings setting.RecordingRuleOTLPSettings,
+	httpClientProvider HttpClientProvider,
+	clock clock.Clock,
+	tracer tracing.Tracer,
+	l log.Logger,
+	metrics *metrics.RemoteWriter,
+) (*OTLPWriter, error) {
+	if err := validateHTTPTarget(settings.URL, "", "", settings.Timeout); err != nil {
+		return nil, err
+	}
+
+	cl, err := newHTTPClient(httpClientProvider, tracer, "", "", settings.CustomHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OTLPWriter{
+		client:  cl,
+		url:     settings.URL,
+		timeout: settings.Timeout,
+		clock:   clock,
+		logger:  l,
+		metrics: metrics,
+	}, nil
+}
+
+// Write writes the given frames to the OTLP endpoint.
+func (w OTLPWriter) Write(ctx context.Context, name string, t time.Time, frames data.Frames, extraLabels map[string]string) error {
+	l := w.logger.FromContext(ctx)
+	ruleKey, found := models.RuleKeyFromContext(ctx)
+	if !found {
+		// sanity check, this should never happen
+		return fmt.Errorf("rule key not found in context")
+	}
+	lvs := []string{fmt.Sprint(ruleKey.OrgID), otlpBackendType}
+
+	points, err := PointsFromFrames(name, t, frames, extraLabels)
+	if err != nil {
+		return err
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	body, err := proto.Marshal(otlpRequestFromPoints(name, points))
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics: %w", err)
+	}
+
+	l.Debug("Writing metric", "name", name)
+	writeStart := w.clock.Now()
+	statusCode, writeErr := post(ctx, w.client, w.timeout, w.url, "application/x-protobuf", body)
+	w.metrics.WriteDuration.WithLabelValues(lvs...).Observe(w.clock.Now().Sub(writeStart).Seconds())
+
+	lvs = append(lvs, fmt.Sprint(statusCode))
+	w.metrics.WritesTotal.WithLabelValues(lvs...).Inc()
+
+	if writeErr != nil {
+		return fmt.Errorf("failed to write metrics: %w", writeErr)
+	}
+
+	return nil
+}
+
+// otlpRequestFromPoints returns an export request with a single gauge that has a data point for every point.
+func otlpRequestFromPoints(name string, points []Point) *colmetricpb.ExportMetricsServiceRequest {
+	dataPoints := make([]*metricpb.NumberDataPoint, 0, len(points))
+	for _, p := range points {
+		keys := make([]string, 0, len(p.Labels))
+		for k := range p.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		attrs := make([]*commonpb.KeyValue, 0, len(keys))
+		for _, k := range keys {
+			attrs = append(attrs, &commonpb.KeyValue{
+				Key:   k,
+				Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: p.Labels[k]}},
+			})
+		}
+		dataPoints = append(dataPoints, &metricpb.NumberDataPoint{
+			Attributes:   attrs,
+			TimeUnixNano: uint64(p.Metric.T.UnixNano()),
+			Value:        &metricpb.NumberDataPoint_AsDouble{AsDouble: p.Metric.V},
+		})
+	}
+
+	return &colmetricpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricpb.ResourceMetrics{{
+			Resource: &resourcepb.Resource{
+				Attributes: []*commonpb.KeyValue{{
+					Key:   "service.name",
+					Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "grafana"}},
+				}},
+			},
+			ScopeMetrics: []*metricpb.ScopeMetrics{{
+				Scope: &commonpb.InstrumentationScope{Name: otlpScopeName},
+				Metrics: []*metricpb.Metric{{
+					Name: name,
+					Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: dataPoints}},
+				}},
+			}},
+		}},
+	}
+}
@@ -0,0 +1,77 @@
+package writer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestOTLPWriter_Write(t *testing.T) {
+	var (
+		request *colmetricpb.ExportMetricsServiceRequest
+		headers http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		request = &colmetricpb.ExportMetricsServiceRequest{}
+		require.NoError(t, proto.Unmarshal(b, request))
+		headers = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	writer, err := NewOTLPWriter(
+		setting.RecordingRuleOTLPSettings{URL: srv.URL + "/v1/metrics", CustomHeaders: map[string]string{"X-Scope-OrgID": "1"}, Timeout: time.Second},
+		newTestClientProvider(),
+		clock.New(),
+		tracing.InitializeTracerForTest(),
+		log.NewNopLogger(),
+		metrics.NewRemoteWriterMetrics(prometheus.NewRegistry()),
+	)
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	series := []map[string]string{{"foo": "1"}, {"foo": "2"}}
+	frames := frameGenFromLabels(t, data.FrameTypeNumericMulti, series)
+	ctx := ngmodels.WithRuleKey(context.Background(), ngmodels.GenerateRuleKey(1))
+
+	require.NoError(t, writer.Write(ctx, "test", now, frames, map[string]string{"extra": "label"}))
+
+	require.Equal(t, "application/x-protobuf", headers.Get("Content-Type"))
+	require.Equal(t, "1", headers.Get("X-Scope-OrgID"))
+	require.Len(t, request.ResourceMetrics, 1)
+	require.Len(t, request.ResourceMetrics[0].ScopeMetrics, 1)
+	scope := request.ResourceMetrics[0].ScopeMetrics[0]
+	require.Equal(t, otlpScopeName, scope.Scope.Name)
+	require.Len(t, scope.Metrics, 1)
+	require.Equal(t, "test", scope.Metrics[0].Name)
+
+	points := scope.Metrics[0].GetGauge().GetDataPoints()
+	require.Len(t, points, len(series))
+	for i, p := range points {
+		require.Equal(t, uint64(now.UnixNano()), p.TimeUnixNano)
+		require.Equal(t, extractValue(t, frames, series[i], data.FrameTypeNumericMulti), p.GetAsDouble())
+		attrs := map[string]string{}
+		for _, kv := range p.Attributes {
+			attrs[kv.Key] = kv.Value.GetStringValue()
+		}
+		require.Equal(t, map[string]string{"extra": "label", "foo": series[i]["foo"]}, attrs)
+	}
+}
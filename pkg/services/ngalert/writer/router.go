@@ -0,0 +1,66 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// Writer writes the result of a recording rule to a storage.
+type Writer interface {
+	Write(ctx context.Context, name string, t time.Time, frames data.Frames, extraLabels map[string]string) error
+}
+
+// TargetRouter writes the result of a recording rule to the writer of the target selected by the rule.
+// Rules that do not select a target are written to the default target.
+type TargetRouter struct {
+	defaultTarget models.RecordTarget
+	writers       map[models.RecordTarget]Writer
+}
+
+func NewTargetRouter(defaultTarget models.RecordTarget, writers map[models.RecordTarget]Writer) (*TargetRouter, error) {
+	if _, ok := writers[defaultTarget]; !ok {
+		return nil, fmt.Errorf("default recording rule target %q is not configured", defaultTarget)
+	}
+	return &TargetRouter{
+		defaultTarget: defaultTarget,
+		writers:       writers,
+	}, nil
+}
+
+// Resolve returns the target the results are written to when a rule selects the given target.
+func (r *TargetRouter) Resolve(target models.RecordTarget) models.RecordTarget {
+	if target == "" {
+		return r.defaultTarget
+	}
+	return target
+}
+
+// Write writes the given frames to the target stored in the context.
+func (r *TargetRouter) Write(ctx context.Context, name string, t time.Time, frames data.Frames, extraLabels map[string]string) error {
+	target, _ := models.RecordTargetFromContext(ctx)
+	target = r.Resolve(target)
+	w, ok := r.writers[target]
+	if !ok {
+		return fmt.Errorf("recording rule target %q is not configured", target)
+	}
+	return w.Write(ctx, name, t, frames, extraLabels)
+}
+
+// Run runs the background maintenance of the writers that need it, such as compaction, until the context is canceled.
+func (r *TargetRouter) Run(ctx context.Context) error {
+	g, ctx := errgroup.WithContext(ctx)
+	for _, w := range r.writers {
+		if runner, ok := w.(interface{ Run(context.Context) error }); ok {
+			g.Go(func() error {
+				return runner.Run(ctx)
+			})
+		}
+	}
+	return g.Wait()
+}
@@ -0,0 +1,48 @@
+package writer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestTargetRouter(t *testing.T) {
+	var written []ngmodels.RecordTarget
+	writerFor := func(target ngmodels.RecordTarget) Writer {
+		return FakeWriter{WriteFunc: func(context.Context, string, time.Time, data.Frames, map[string]string) error {
+			written = append(written, target)
+			return nil
+		}}
+	}
+
+	t.Run("fails when the default target is not configured", func(t *testing.T) {
+		_, err := NewTargetRouter(ngmodels.RecordTargetSQL, map[ngmodels.RecordTarget]Writer{
+			ngmodels.RecordTargetPrometheus: writerFor(ngmodels.RecordTargetPrometheus),
+		})
+		require.Error(t, err)
+	})
+
+	router, err := NewTargetRouter(ngmodels.RecordTargetPrometheus, map[ngmodels.RecordTarget]Writer{
+		ngmodels.RecordTargetPrometheus: writerFor(ngmodels.RecordTargetPrometheus),
+		ngmodels.RecordTargetSQL:        writerFor(ngmodels.RecordTargetSQL),
+	})
+	require.NoError(t, err)
+
+	write := func(target ngmodels.RecordTarget) error {
+		return router.Write(ngmodels.WithRecordTarget(context.Background(), target), "test", time.Now(), nil, nil)
+	}
+
+	require.NoError(t, write(""))
+	require.NoError(t, write(ngmodels.RecordTargetSQL))
+	require.NoError(t, router.Write(context.Background(), "test", time.Now(), nil, nil))
+	require.ErrorContains(t, write(ngmodels.RecordTargetOTLP), "not configured")
+
+	require.Equal(t, []ngmodels.RecordTarget{ngmodels.RecordTargetPrometheus, ngmodels.RecordTargetSQL, ngmodels.RecordTargetPrometheus}, written)
+	require.Equal(t, ngmodels.RecordTargetPrometheus, router.Resolve(""))
+	require.Equal(t, ngmodels.RecordTargetOTLP, router.Resolve(ngmodels.RecordTargetOTLP))
+}
@@ -0,0 +1,305 @@
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	ngac "github.com/grafana/grafana/pkg/services/ngalert/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/recordedsamples"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	// sqlInsertBatchSize keeps the number of bound parameters of a single insert statement below the limits of all supported databases.
+	sqlInsertBatchSize = 50
+	// MaxRecordedSamplesQuerySize is the maximum number of samples read from the database to serve a single query.
+	MaxRecordedSamplesQuerySize = 100000
+)
+
+// recordedSample is a row of the alert_rule_recorded_sample table.
+type recordedSample struct {
+	ID         int64   `xorm:"pk autoincr 'id'"`
+	OrgID      int64   `xorm:"org_id"`
+	RuleUID    string  `xorm:"rule_uid"`
+	Metric     string  `xorm:"metric"`
+	Labels     string  `xorm:"labels"`
+	LabelsHash string  `xorm:"labels_hash"`
+	SampledAt  int64   `xorm:"sampled_at"`
+	Value      float64 `xorm:"value"`
+}
+
+func (recordedSample) TableName() string {
+	return "alert_rule_recorded_sample"
+}
+
+// SQLWriter writes the results of recording rules to a table of the Grafana database.
+// The samples can be read with SQLReader, which is what the Grafana data source uses.
+type SQLWriter struct {
+	db     db.DB
+	cfg    setting.RecordingRuleSQLSettings
+	clock  clock.Clock
+	logger log.Logger
+}
+
+func NewSQLWriter(cfg setting.RecordingRuleSQLSettings, store db.DB, clock clock.Clock, l log.Logger) *SQLWriter {
+	return &SQLWriter{
+		db:     store,
+		cfg:    cfg,
+		clock:  clock,
+		logger: l,
+	}
+}
+
+// Write writes the given frames to the database.
+func (w *SQLWriter) Write(ctx context.Context, name string, t time.Time, frames data.Frames, extraLabels map[string]string) error {
+	l := w.logger.FromContext(ctx)
+	ruleKey, found := models.RuleKeyFromContext(ctx)
+	if !found {
+		// sanity check, this should never happen
+		return fmt.Errorf("rule key not found in context")
+	}
+
+	points, err := PointsFromFrames(name, t, frames, extraLabels)
+	if err != nil {
+		return err
+	}
+
+	samples := make([]recordedSample, 0, len(points))
+	for _, p := range points {
+		// Not all databases can store NaN and infinite values.
+		if math.IsNaN(p.Metric.V) || math.IsInf(p.Metric.V, 0) {
+			l.Debug("Skipping point with unsupported value", "name", name, "value", p.Metric.V)
+			continue
+		}
+		lbs := data.Labels(p.Labels)
+		encoded, err := json.Marshal(lbs)
+		if err != nil {
+			return fmt.Errorf("failed to encode labels: %w", err)
+		}
+		samples = append(samples, recordedSample{
+			OrgID:      ruleKey.OrgID,
+			RuleUID:    ruleKey.UID,
+			Metric:     p.Name,
+			Labels:     string(encoded),
+			LabelsHash: lbs.Fingerprint().String(),
+			SampledAt:  p.Metric.T.UnixMilli(),
+			Value:      p.Metric.V,
+		})
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+
+	l.Debug("Writing metric", "name", name)
+	return w.db.InTransaction(ctx, func(ctx context.Context) error {
+		return w.db.WithDbSession(ctx, func(sess *db.Session) error {
+			for i := 0; i < len(samples); i += sqlInsertBatchSize {
+				batch := samples[i:min(i+sqlInsertBatchSize, len(samples))]
+				if _, err := sess.Insert(&batch); err != nil {
+					return fmt.Errorf("failed to write samples: %w", err)
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Run periodically removes samples that are past retention, until the context is canceled.
+func (w *SQLWriter) Run(ctx context.Context) error {
+	if w.cfg.CompactionInterval <= 0 || w.cfg.Retention <= 0 {
+		return nil
+	}
+	ticker := w.clock.Ticker(w.cfg.CompactionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			deleted, err := w.Compact(ctx)
+			if err != nil {
+				w.logger.Error("Failed to compact recorded samples", "error", err)
+				continue
+			}
+			w.logger.Debug("Compacted recorded samples", "deleted", deleted)
+		}
+	}
+}
+
+// Compact deletes samples that are past retention. It returns the number of deleted samples.
+func (w *SQLWriter) Compact(ctx context.Context) (int64, error) {
+	if w.cfg.Retention <= 0 {
+		return 0, nil
+	}
+	var deleted int64
+	err := w.db.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec("DELETE FROM alert_rule_recorded_sample WHERE sampled_at < ?", w.clock.Now().Add(-w.cfg.Retention).UnixMilli())
+		if err != nil {
+			return fmt.Errorf("failed to delete expired samples: %w", err)
+		}
+		deleted, err = res.RowsAffected()
+		return err
+	})
+	return deleted, err
+}
+
+// folderAccess checks whether a user can read the rules of a folder.
+type folderAccess interface {
+	HasAccessInFolder(ctx context.Context, user identity.Requester, rule models.Namespaced) (bool, error)
+}
+
+// SQLReader reads the samples written by SQLWriter. It implements recordedsamples.Reader.
+type SQLReader struct {
+	db     db.DB
+	access folderAccess
+}
+
+func ProvideSQLReader(store db.DB, ac accesscontrol.AccessControl) *SQLReader {
+	return NewSQLReader(store, ngac.NewRuleService(ac))
+}
+
+func NewSQLReader(store db.DB, access folderAccess) *SQLReader {
+	return &SQLReader{
+		db:     store,
+		access: access,
+	}
+}
+
+// QuerySamples reads the samples of a metric written by recording rules with the "sql" target.
+// Samples written by rules in folders the user cannot read, and by rules that no longer exist, are not returned.
+func (r *SQLReader) QuerySamples(ctx context.Context, user identity.Requester, query recordedsamples.Query) (data.Frames, error) {
+	if query.Metric == "" {
+		return nil, fmt.Errorf("metric is required")
+	}
+
+	ruleUIDs, err := r.readableRules(ctx, user, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(ruleUIDs) == 0 {
+		return data.Frames{}, nil
+	}
+
+	var samples []recordedSample
+	err = r.db.WithDbSession(ctx, func(sess *db.Session) error {
+		q := sess.Table(recordedSample{}.TableName()).
+			Where("org_id = ? AND metric = ?", query.OrgID, query.Metric).
+			In("rule_uid", ruleUIDs)
+		if !query.From.IsZero() {
+			q = q.And("sampled_at >= ?", query.From.UnixMilli())
+		}
+		if !query.To.IsZero() {
+			q = q.And("sampled_at <= ?", query.To.UnixMilli())
+		}
+		return q.Asc("sampled_at", "id").Limit(MaxRecordedSamplesQuerySize + 1).Find(&samples)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recorded samples: %w", err)
+	}
+	if len(samples) > MaxRecordedSamplesQuerySize {
+		return nil, fmt.Errorf("query matches more than %d samples, select a shorter time range", MaxRecordedSamplesQuerySize)
+	}
+
+	type series struct {
+		labels data.Labels
+		times  []time.Time
+		values []float64
+	}
+	bySeries := map[string]*series{}
+	for _, s := range samples {
+		sr, ok := bySeries[s.LabelsHash]
+		if !ok {
+			var lbs data.Labels
+			if err := json.Unmarshal([]byte(s.Labels), &lbs); err != nil {
+				return nil, fmt.Errorf("failed to decode labels of series %s: %w", s.LabelsHash, err)
+			}
+			// Series that do not match are kept as nil so their labels are decoded only once.
+			if matchesLabels(lbs, query.Matchers) {
+				sr = &series{labels: lbs}
+			}
+			bySeries[s.LabelsHash] = sr
+		}
+		if sr == nil {
+			continue
+		}
+		sr.times = append(sr.times, time.UnixMilli(s.SampledAt).UTC())
+		sr.values = append(sr.values, s.Value)
+	}
+
+	hashes := make([]string, 0, len(bySeries))
+	for h, sr := range bySeries {
+		if sr != nil {
+			hashes = append(hashes, h)
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bySeries[hashes[i]].labels.String() < bySeries[hashes[j]].labels.String()
+	})
+
+	frames := make(data.Frames, 0, len(hashes))
+	for _, h := range hashes {
+		sr := bySeries[h]
+		frame := data.NewFrame(query.Metric,
+			data.NewField(data.TimeSeriesTimeFieldName, nil, sr.times),
+			data.NewField(data.TimeSeriesValueFieldName, sr.labels, sr.values),
+		)
+		frame.SetMeta(&data.FrameMeta{Type: data.FrameTypeTimeSeriesMulti, TypeVersion: data.FrameTypeVersion{0, 1}})
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// readableRules returns the UIDs of the rules that wrote the metric and are in folders the user can read.
+func (r *SQLReader) readableRules(ctx context.Context, user identity.Requester, query recordedsamples.Query) ([]string, error) {
+	var rules []struct {
+		UID          string `xorm:"uid"`
+		NamespaceUID string `xorm:"namespace_uid"`
+	}
+	err := r.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.SQL(
+			"SELECT uid, namespace_uid FROM alert_rule WHERE org_id = ? AND uid IN (SELECT DISTINCT rule_uid FROM alert_rule_recorded_sample WHERE org_id = ? AND metric = ?)",
+			query.OrgID, query.OrgID, query.Metric,
+		).Find(&rules)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rules of recorded samples: %w", err)
+	}
+
+	readable := map[string]bool{}
+	result := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		ok, checked := readable[rule.NamespaceUID]
+		if !checked {
+			ok, err = r.access.HasAccessInFolder(ctx, user, models.Namespace{UID: rule.NamespaceUID})
+			if err != nil {
+				return nil, err
+			}
+			readable[rule.NamespaceUID] = ok
+		}
+		if ok {
+			result = append(result, rule.UID)
+		}
+	}
+	return result, nil
+}
+
+func matchesLabels(lbs data.Labels, matchers map[string]string) bool {
+	for k, v := range matchers {
+		if lbs[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package writer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/recordedsamples"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+type fakeFolderAccess struct {
+	readable map[string]bool
+}
+
+func (f fakeFolderAccess) HasAccessInFolder(_ context.Context, _ identity.Requester, rule ngmodels.Namespaced) (bool, error) {
+	return f.readable[rule.GetNamespaceUID()], nil
+}
+
+func TestIntegrationSQLWriter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := db.InitTestDB(t)
+	clk := clock.NewMock()
+	clk.Set(now)
+	writer := NewSQLWriter(setting.RecordingRuleSQLSettings{Enabled: true, Retention: time.Hour}, store, clk, log.NewNopLogger())
+	reader := NewSQLReader(store, fakeFolderAccess{readable: map[string]bool{"allowed": true}})
+	usr := &user.SignedInUser{OrgID: 1}
+
+	gen := ngmodels.RuleGen.With(ngmodels.RuleGen.WithOrgID(1))
+	rule := gen.With(gen.WithNamespaceUID("allowed")).GenerateRef()
+	hidden := gen.With(gen.WithNamespaceUID("denied")).GenerateRef()
+	insertRules(t, store, rule, hidden)
+
+	series := []map[string]string{{"foo": "1"}, {"foo": "2"}}
+	frames := frameGenFromLabels(t, data.FrameTypeNumericMulti, series)
+	ruleKey := rule.GetKey()
+	ctx := ngmodels.WithRuleKey(context.Background(), ruleKey)
+
+	require.NoError(t, writer.Write(ctx, "test", now.Add(-2*time.Hour), frames, nil))
+	require.NoError(t, writer.Write(ctx, "test", now.Add(-time.Minute), frames, nil))
+	require.NoError(t, writer.Write(ctx, "test", now, frames, nil))
+
+	hiddenCtx := ngmodels.WithRuleKey(context.Background(), hidden.GetKey())
+	require.NoError(t, writer.Write(hiddenCtx, "test", now, frameGenFromLabels(t, data.FrameTypeNumericMulti, []map[string]string{{"foo": "3"}}), nil))
+	require.NoError(t, writer.Write(hiddenCtx, "hidden", now, frames, nil))
+
+	t.Run("query returns a frame per series", func(t *testing.T) {
+		result, err := reader.QuerySamples(context.Background(), usr, recordedsamples.Query{
+			OrgID:  ruleKey.OrgID,
+			Metric: "test",
+			From:   now.Add(-time.Hour),
+			To:     now,
+		})
+		require.NoError(t, err)
+		require.Len(t, result, len(series))
+		for i, frame := range result {
+			require.Equal(t, 2, frame.Rows())
+			require.Equal(t, data.Labels(series[i]), frame.Fields[1].Labels)
+			require.Equal(t, now.Add(-time.Minute), frame.Fields[0].At(0))
+			require.Equal(t, extractValue(t, frames, series[i], data.FrameTypeNumericMulti), frame.Fields[1].At(1))
+		}
+	})
+
+	t.Run("query filters by labels and organization", func(t *testing.T) {
+		result, err := reader.QuerySamples(context.Background(), usr, recordedsamples.Query{
+			OrgID:    ruleKey.OrgID,
+			Metric:   "test",
+			Matchers: map[string]string{"foo": "2"},
+		})
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		require.Equal(t, 3, result[0].Rows())
+
+		result, err = reader.QuerySamples(context.Background(), usr, recordedsamples.Query{OrgID: ruleKey.OrgID + 1, Metric: "test"})
+		require.NoError(t, err)
+		require.Empty(t, result)
+	})
+
+	t.Run("query skips samples of rules in folders the user cannot read", func(t *testing.T) {
+		result, err := reader.QuerySamples(context.Background(), usr, recordedsamples.Query{OrgID: ruleKey.OrgID, Metric: "test", Matchers: map[string]string{"foo": "3"}})
+		require.NoError(t, err)
+		require.Empty(t, result)
+
+		result, err = reader.QuerySamples(context.Background(), usr, recordedsamples.Query{OrgID: ruleKey.OrgID, Metric: "hidden"})
+		require.NoError(t, err)
+		require.Empty(t, result)
+	})
+
+	t.Run("compaction deletes samples past retention", func(t *testing.T) {
+		deleted, err := writer.Compact(context.Background())
+		require.NoError(t, err)
+		require.EqualValues(t, len(series), deleted)
+
+		result, err := reader.QuerySamples(context.Background(), usr, recordedsamples.Query{OrgID: ruleKey.OrgID, Metric: "test"})
+		require.NoError(t, err)
+		require.Len(t, result, len(series))
+		require.Equal(t, 2, result[0].Rows())
+	})
+}
+
+func insertRules(t *testing.T, store db.DB, rules ...*ngmodels.AlertRule) {
+	t.Helper()
+	err := store.WithDbSession(context.Background(), func(sess *db.Session) error {
+		for _, rule := range rules {
+			rule.ID = 0
+			if _, err := sess.Insert(rule); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
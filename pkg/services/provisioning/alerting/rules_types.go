@@ -258,11 +258,17 @@ func (nsV1 *NotificationSettingsV1) mapToModel() (models.NotificationSettings, e
 type RecordV1 struct {
 	Metric values.StringValue `json:"metric" yaml:"metric"`
 	From   values.StringValue `json:"from" yaml:"from"`
+	Target values.StringValue `json:"target" yaml:"target"`
 }
 
 func (record *RecordV1) mapToModel() (models.Record, error) {
+	target, err := models.ParseRecordTarget(record.Target.Value())
+	if err != nil {
+		return models.Record{}, err
+	}
 	return models.Record{
 		Metric: record.Metric.Value(),
 		From:   record.From.Value(),
+		Target: target,
 	}, nil
 }
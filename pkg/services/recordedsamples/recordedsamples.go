@@ -0,0 +1,27 @@
+package recordedsamples
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+)
+
+// Reader reads the samples that Grafana-managed recording rules with the "sql" target write to the Grafana database.
+type Reader interface {
+	// QuerySamples returns a frame per series of the metric in the multi-frame time series format.
+	// Only the samples written by rules in folders the user can read are returned.
+	QuerySamples(ctx context.Context, user identity.Requester, query Query) (data.Frames, error)
+}
+
+// Query selects the samples of a metric.
+type Query struct {
+	OrgID  int64
+	Metric string
+	// Matchers are label values the series must have.
+	Matchers map[string]string
+	From     time.Time
+	To       time.Time
+}
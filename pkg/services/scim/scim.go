@@ -0,0 +1,169 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+)
+
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaBulkRequest           = "urn:ietf:params:scim:api:messages:2.0:BulkRequest"
+	SchemaBulkResponse          = "urn:ietf:params:scim:api:messages:2.0:BulkResponse"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+)
+
+const (
+	ResourceTypeUser  = "User"
+	ResourceTypeGroup = "Group"
+)
+
+// ActionProvision allows to provision the users and teams of an organization with SCIM.
+const ActionProvision = "scim:provision"
+
+const detailMessage = `{{ .Public.detail }}`
+
+// The message IDs of the errors end with the scimType of RFC 7644 section 3.12.
+var (
+	ErrNotFound = errutil.NotFound("scim.notFound").
+			MustTemplate(detailMessage, errutil.WithPublic(detailMessage))
+	ErrUniqueness = errutil.Conflict("scim.uniqueness").
+			MustTemplate(detailMessage, errutil.WithPublic(detailMessage))
+	ErrInvalidFilter = errutil.BadRequest("scim.invalidFilter").
+				MustTemplate(detailMessage, errutil.WithPublic(detailMessage))
+	ErrInvalidSyntax = errutil.BadRequest("scim.invalidSyntax").
+				MustTemplate(detailMessage, errutil.WithPublic(detailMessage))
+	ErrInvalidPath = errutil.BadRequest("scim.invalidPath").
+			MustTemplate(detailMessage, errutil.WithPublic(detailMessage))
+	ErrNoTarget = errutil.BadRequest("scim.noTarget").
+			MustTemplate(detailMessage, errutil.WithPublic(detailMessage))
+	ErrInvalidValue = errutil.BadRequest("scim.invalidValue").
+			MustTemplate(detailMessage, errutil.WithPublic(detailMessage))
+	ErrMutability = errutil.BadRequest("scim.mutability").
+			MustTemplate(detailMessage, errutil.WithPublic(detailMessage))
+	ErrTooMany = errutil.BadRequest("scim.tooMany").
+			MustTemplate(detailMessage, errutil.WithPublic(detailMessage))
+)
+
+// ErrorData returns the template data of a SCIM error with a detail message.
+func ErrorData(format string, args ...any) errutil.TemplateData {
+	return errutil.TemplateData{
+		Public: map[string]any{
+			"detail": fmt.Sprintf(format, args...),
+		},
+	}
+}
+
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// MultiValue is an element of a multi-valued attribute, like emails or members.
+type MultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// User is a Grafana user. Users are global, they are listed and provisioned
+// in the organization of the service account.
+type User struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	UserName    string       `json:"userName"`
+	Name        *Name        `json:"name,omitempty"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Emails      []MultiValue `json:"emails,omitempty"`
+	// Active is false for disabled users. It is left unchanged when omitted.
+	Active *bool `json:"active,omitempty"`
+	// Password is never returned.
+	Password string `json:"password,omitempty"`
+	// Roles holds the organization role of the user.
+	Roles []MultiValue `json:"roles,omitempty"`
+	// Groups are the teams of the user, they are read-only.
+	Groups []MultiValue `json:"groups,omitempty"`
+	Meta   *Meta        `json:"meta,omitempty"`
+}
+
+// Group is a team of the organization.
+type Group struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []MultiValue `json:"members,omitempty"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type BulkRequest struct {
+	Schemas []string `json:"schemas"`
+	// FailOnErrors is the number of errors after which the remaining operations are skipped.
+	FailOnErrors int             `json:"failOnErrors,omitempty"`
+	Operations   []BulkOperation `json:"Operations"`
+}
+
+type BulkOperation struct {
+	Method string          `json:"method"`
+	BulkID string          `json:"bulkId,omitempty"`
+	Path   string          `json:"path"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+type BulkResponse struct {
+	Schemas    []string                `json:"schemas"`
+	Operations []BulkOperationResponse `json:"Operations"`
+}
+
+type BulkOperationResponse struct {
+	Method   string `json:"method"`
+	BulkID   string `json:"bulkId,omitempty"`
+	Location string `json:"location,omitempty"`
+	Status   string `json:"status"`
+	Response any    `json:"response,omitempty"`
+}
+
+type ErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
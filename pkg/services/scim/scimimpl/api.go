@@ -0,0 +1,497 @@
+package scimimpl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/scim"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+const contentType = "application/scim+json"
+
+// maxBodySize limits the body of the requests other than bulk requests.
+const maxBodySize = 1 << 20
+
+var errServiceAccountRequired = errutil.Forbidden("scim.serviceAccountRequired").
+	MustTemplate(`{{ .Public.detail }}`, errutil.WithPublic(`{{ .Public.detail }}`))
+
+type api struct {
+	service       *Service
+	accessControl accesscontrol.AccessControl
+	routeRegister routing.RouteRegister
+}
+
+func newAPI(service *Service, accessControl accesscontrol.AccessControl, routeRegister routing.RouteRegister) *api {
+	return &api{
+		service:       service,
+		accessControl: accessControl,
+		routeRegister: routeRegister,
+	}
+}
+
+func (a *api) registerAPIEndpoints() {
+	authorize := accesscontrol.Middleware(a.accessControl)
+	reqProvisioner := authorize(accesscontrol.EvalPermission(scim.ActionProvision))
+
+	a.routeRegister.Group("/api/scim/v2", func(r routing.RouteRegister) {
+		r.Get("/ServiceProviderConfig", routing.Wrap(a.getServiceProviderConfig))
+		r.Get("/ResourceTypes", routing.Wrap(a.getResourceTypes))
+		r.Get("/Schemas", routing.Wrap(a.getSchemas))
+
+		r.Get("/Users", routing.Wrap(a.listUsers))
+		r.Post("/Users", routing.Wrap(a.createUser))
+		r.Get("/Users/:id", routing.Wrap(a.getUser))
+		r.Put("/Users/:id", routing.Wrap(a.replaceUser))
+		r.Patch("/Users/:id", routing.Wrap(a.patchUser))
+		r.Delete("/Users/:id", routing.Wrap(a.deleteUser))
+
+		r.Get("/Groups", routing.Wrap(a.listGroups))
+		r.Post("/Groups", routing.Wrap(a.createGroup))
+		r.Get("/Groups/:id", routing.Wrap(a.getGroup))
+		r.Put("/Groups/:id", routing.Wrap(a.replaceGroup))
+		r.Patch("/Groups/:id", routing.Wrap(a.patchGroup))
+		r.Delete("/Groups/:id", routing.Wrap(a.deleteGroup))
+
+		r.Post("/Bulk", routing.Wrap(a.bulk))
+	}, middleware.ReqSignedInNoAnonymous, a.reqServiceAccount, reqProvisioner)
+}
+
+// reqServiceAccount only allows service accounts, identity providers
+// authenticate with the token of a dedicated service account.
+func (a *api) reqServiceAccount(c *contextmodel.ReqContext) {
+	if c.SignedInUser.GetID().IsType(identity.TypeServiceAccount) {
+		return
+	}
+	a.errorResponse(c.Req.Context(), errServiceAccountRequired.Build(scim.ErrorData("SCIM requests must be authenticated with a service account token"))).WriteTo(c)
+}
+
+func (a *api) getServiceProviderConfig(c *contextmodel.ReqContext) response.Response {
+	cfg := a.service.cfg.AuthSCIM
+	return scimJSON(http.StatusOK, map[string]any{
+		"schemas":        []string{scim.SchemaServiceProviderConfig},
+		"patch":          map[string]any{"supported": true},
+		"bulk":           map[string]any{"supported": true, "maxOperations": cfg.BulkMaxOperations, "maxPayloadSize": cfg.BulkMaxPayloadSize},
+		"filter":         map[string]any{"supported": true, "maxResults": cfg.MaxResults},
+		"changePassword": map[string]any{"supported": true},
+		"sort":           map[string]any{"supported": false},
+		"etag":           map[string]any{"supported": false},
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "Service account token",
+			"description": "Authentication with the token of a Grafana service account",
+			"primary":     true,
+		}},
+		"meta": map[string]any{"resourceType": "ServiceProviderConfig", "location": a.service.cfg.AppURL + "api/scim/v2/ServiceProviderConfig"},
+	})
+}
+
+func (a *api) getResourceTypes(c *contextmodel.ReqContext) response.Response {
+	resourceTypes := []any{
+		resourceType(scim.ResourceTypeUser, "/Users", scim.SchemaUser),
+		resourceType(scim.ResourceTypeGroup, "/Groups", scim.SchemaGroup),
+	}
+	return scimJSON(http.StatusOK, &scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: len(resourceTypes),
+		StartIndex:   1,
+		ItemsPerPage: len(resourceTypes),
+		Resources:    resourceTypes,
+	})
+}
+
+func resourceType(name, endpoint, schema string) map[string]any {
+	return map[string]any{
+		"schemas":  []string{scim.SchemaResourceType},
+		"id":       name,
+		"name":     name,
+		"endpoint": endpoint,
+		"schema":   schema,
+	}
+}
+
+func (a *api) getSchemas(c *contextmodel.ReqContext) response.Response {
+	schemas := []any{
+		map[string]any{"schemas": []string{scim.SchemaSchema}, "id": scim.SchemaUser, "name": scim.ResourceTypeUser},
+		map[string]any{"schemas": []string{scim.SchemaSchema}, "id": scim.SchemaGroup, "name": scim.ResourceTypeGroup},
+	}
+	return scimJSON(http.StatusOK, &scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: len(schemas),
+		StartIndex:   1,
+		ItemsPerPage: len(schemas),
+		Resources:    schemas,
+	})
+}
+
+func (a *api) listUsers(c *contextmodel.ReqContext) response.Response {
+	query, err := a.parseListQuery(c)
+	if err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	result, err := a.service.ListUsers(c.Req.Context(), c.SignedInUser.GetOrgID(), query)
+	if err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	return a.listResponse(c, result)
+}
+
+func (a *api) getUser(c *contextmodel.ReqContext) response.Response {
+	result, err := a.service.GetUser(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"])
+	if err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	return a.resourceResponse(c, http.StatusOK, result)
+}
+
+func (a *api) createUser(c *contextmodel.ReqContext) response.Response {
+	resource := &scim.User{}
+	if err := decodeBody(c, resource, maxBodySize); err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	result, err := a.service.CreateUser(c.Req.Context(), c.SignedInUser.GetOrgID(), resource)
+	if err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	return a.resourceResponse(c, http.StatusCreated, result)
+}
+
+func (a *api) replaceUser(c *contextmodel.ReqContext) response.Response {
+	resource := &scim.User{}
+	if err := decodeBody(c, resource, maxBodySize); err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	result, err := a.service.ReplaceUser(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"], resource)
+	if err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	return a.resourceResponse(c, http.StatusOK, result)
+}
+
+func (a *api) patchUser(c *contextmodel.ReqContext) response.Response {
+	patch := &scim.PatchRequest{}
+	if err := decodeBody(c, patch, maxBodySize); err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	result, err := a.service.PatchUser(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"], patch.Operations)
+	if err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	return a.resourceResponse(c, http.StatusOK, result)
+}
+
+func (a *api) deleteUser(c *contextmodel.ReqContext) response.Response {
+	if err := a.service.DeleteUser(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"]); err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	return response.Empty(http.StatusNoContent)
+}
+
+func (a *api) listGroups(c *contextmodel.ReqContext) response.Response {
+	query, err := a.parseListQuery(c)
+	if err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	result, err := a.service.ListGroups(c.Req.Context(), c.SignedInUser.GetOrgID(), query)
+	if err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	return a.listResponse(c, result)
+}
+
+func (a *api) getGroup(c *contextmodel.ReqContext) response.Response {
+	result, err := a.service.GetGroup(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"])
+	if err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	return a.resourceResponse(c, http.StatusOK, result)
+}
+
+func (a *api) createGroup(c *contextmodel.ReqContext) response.Response {
+	resource := &scim.Group{}
+	if err := decodeBody(c, resource, maxBodySize); err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	result, err := a.service.CreateGroup(c.Req.Context(), c.SignedInUser.GetOrgID(), resource)
+	if err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	return a.resourceResponse(c, http.StatusCreated, result)
+}
+
+func (a *api) replaceGroup(c *contextmodel.ReqContext) response.Response {
+	resource := &scim.Group{}
+	if err := decodeBody(c, resource, maxBodySize); err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	result, err := a.service.ReplaceGroup(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"], resource)
+	if err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	return a.resourceResponse(c, http.StatusOK, result)
+}
+
+func (a *api) patchGroup(c *contextmodel.ReqContext) response.Response {
+	patch := &scim.PatchRequest{}
+	if err := decodeBody(c, patch, maxBodySize); err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	result, err := a.service.PatchGroup(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"], patch.Operations)
+	if err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	return a.resourceResponse(c, http.StatusOK, result)
+}
+
+func (a *api) deleteGroup(c *contextmodel.ReqContext) response.Response {
+	if err := a.service.DeleteGroup(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"]); err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	return response.Empty(http.StatusNoContent)
+}
+
+func (a *api) bulk(c *contextmodel.ReqContext) response.Response {
+	cfg := a.service.cfg.AuthSCIM
+	if c.Req.ContentLength > cfg.BulkMaxPayloadSize {
+		return payloadTooLarge(fmt.Sprintf("the payload exceeds %d bytes", cfg.BulkMaxPayloadSize))
+	}
+	request := &scim.BulkRequest{}
+	if err := decodeBody(c, request, cfg.BulkMaxPayloadSize); err != nil {
+		if errors.Is(err, errPayloadTooLarge) {
+			return payloadTooLarge(fmt.Sprintf("the payload exceeds %d bytes", cfg.BulkMaxPayloadSize))
+		}
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	if len(request.Operations) > cfg.BulkMaxOperations {
+		return payloadTooLarge(fmt.Sprintf("the request has more than %d operations", cfg.BulkMaxOperations))
+	}
+	return scimJSON(http.StatusOK, a.service.Bulk(c.Req.Context(), c.SignedInUser.GetOrgID(), request))
+}
+
+// payloadTooLarge is returned when a bulk request exceeds the limits of the
+// service provider configuration (RFC 7644 section 3.7.4).
+func payloadTooLarge(detail string) response.Response {
+	return scimJSON(http.StatusRequestEntityTooLarge, &scim.ErrorResponse{
+		Schemas: []string{scim.SchemaError},
+		Status:  strconv.Itoa(http.StatusRequestEntityTooLarge),
+		Detail:  detail,
+	})
+}
+
+func (a *api) parseListQuery(c *contextmodel.ReqContext) (listQuery, error) {
+	maxResults := a.service.cfg.AuthSCIM.MaxResults
+	query := listQuery{startIndex: 1, count: maxResults}
+
+	if raw := c.Query("filter"); raw != "" {
+		f, err := parseFilter(raw)
+		if err != nil {
+			return query, err
+		}
+		query.filter = f
+	}
+	if raw := c.Query("startIndex"); raw != "" {
+		startIndex, err := strconv.Atoi(raw)
+		if err != nil {
+			return query, scim.ErrInvalidValue.Build(scim.ErrorData("invalid startIndex %q", raw))
+		}
+		// A startIndex lower than 1 is interpreted as 1.
+		if startIndex > 1 {
+			query.startIndex = startIndex
+		}
+	}
+	if raw := c.Query("count"); raw != "" {
+		count, err := strconv.Atoi(raw)
+		if err != nil {
+			return query, scim.ErrInvalidValue.Build(scim.ErrorData("invalid count %q", raw))
+		}
+		// A negative count is interpreted as 0, a count above the maximum as the maximum.
+		query.count = min(max(count, 0), maxResults)
+	}
+
+	attributes, excluded := splitAttributes(c.Query("attributes")), splitAttributes(c.Query("excludedAttributes"))
+	query.excludeMembers = containsAttribute(excluded, "members") || (len(attributes) > 0 && !containsAttribute(attributes, "members"))
+	return query, nil
+}
+
+func (a *api) listResponse(c *contextmodel.ReqContext, result *scim.ListResponse) response.Response {
+	attributes, excluded := splitAttributes(c.Query("attributes")), splitAttributes(c.Query("excludedAttributes"))
+	for i, r := range result.Resources {
+		projected, err := project(r, attributes, excluded)
+		if err != nil {
+			return a.errorResponse(c.Req.Context(), err)
+		}
+		result.Resources[i] = projected
+	}
+	return scimJSON(http.StatusOK, result)
+}
+
+func (a *api) resourceResponse(c *contextmodel.ReqContext, status int, resource any) response.Response {
+	projected, err := project(resource, splitAttributes(c.Query("attributes")), splitAttributes(c.Query("excludedAttributes")))
+	if err != nil {
+		return a.errorResponse(c.Req.Context(), err)
+	}
+	resp := scimJSON(status, projected)
+	var location string
+	switch r := resource.(type) {
+	case *scim.User:
+		location = r.Meta.Location
+	case *scim.Group:
+		location = r.Meta.Location
+	}
+	if status == http.StatusCreated && location != "" {
+		resp.SetHeader("Location", location)
+	}
+	return resp
+}
+
+// errorResponse converts an error to a SCIM error (RFC 7644 section 3.12).
+func (a *api) errorResponse(ctx context.Context, err error) *response.NormalResponse {
+	status, body := a.service.errorResponse(ctx, err)
+	return scimJSON(status, body)
+}
+
+func (s *Service) errorResponse(ctx context.Context, err error) (int, *scim.ErrorResponse) {
+	body := &scim.ErrorResponse{Schemas: []string{scim.SchemaError}}
+	var grafanaErr errutil.Error
+	if !errors.As(err, &grafanaErr) {
+		s.log.FromContext(ctx).Error("SCIM request failed", "error", err)
+		body.Status = strconv.Itoa(http.StatusInternalServerError)
+		body.Detail = "Internal server error"
+		return http.StatusInternalServerError, body
+	}
+
+	public := grafanaErr.Public()
+	if public.StatusCode >= http.StatusInternalServerError {
+		s.log.FromContext(ctx).Error("SCIM request failed", "error", err)
+	}
+	body.Status = strconv.Itoa(public.StatusCode)
+	body.Detail = public.Message
+	// The scimType is only defined for bad requests and conflicts.
+	isClientError := public.StatusCode == http.StatusBadRequest || public.StatusCode == http.StatusConflict
+	if scimType, ok := strings.CutPrefix(public.MessageID, "scim."); ok && isClientError {
+		body.SCIMType = scimType
+	}
+	return public.StatusCode, body
+}
+
+var errPayloadTooLarge = errors.New("payload too large")
+
+// decodeBody decodes a JSON body, web.Bind is not used since identity
+// providers send the application/scim+json content type.
+func decodeBody(c *contextmodel.ReqContext, v any, limit int64) error {
+	body, err := io.ReadAll(io.LimitReader(c.Req.Body, limit+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > limit {
+		return errPayloadTooLarge
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return scim.ErrInvalidSyntax.Build(scim.ErrorData("invalid request body: %s", err))
+	}
+	return nil
+}
+
+func scimJSON(status int, body any) *response.NormalResponse {
+	return response.JSON(status, body).SetHeader("Content-Type", contentType)
+}
+
+func splitAttributes(raw string) []string {
+	var attributes []string
+	for _, attr := range strings.Split(raw, ",") {
+		if attr = strings.TrimSpace(stripSchema(attr)); attr != "" {
+			attributes = append(attributes, attr)
+		}
+	}
+	return attributes
+}
+
+func containsAttribute(attributes []string, attr string) bool {
+	for _, a := range attributes {
+		if strings.EqualFold(strings.Split(a, ".")[0], attr) {
+			return true
+		}
+	}
+	return false
+}
+
+// project applies the attributes and excludedAttributes parameters to a
+// resource (RFC 7644 section 3.9). The id and schemas are always returned.
+func project(resource any, attributes, excluded []string) (any, error) {
+	if len(attributes) == 0 && len(excluded) == 0 {
+		return resource, nil
+	}
+	m, err := toMap(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(attributes) > 0 {
+		projected := map[string]any{}
+		for _, alwaysReturned := range []string{"id", "schemas"} {
+			if v, ok := m[alwaysReturned]; ok {
+				projected[alwaysReturned] = v
+			}
+		}
+		for _, attr := range attributes {
+			name, sub, _ := strings.Cut(attr, ".")
+			key, ok := findKey(m, name)
+			if !ok {
+				continue
+			}
+			if sub == "" {
+				projected[key] = m[key]
+				continue
+			}
+			parent, ok := m[key].(map[string]any)
+			if !ok {
+				continue
+			}
+			subKey, ok := findKey(parent, sub)
+			if !ok {
+				continue
+			}
+			target, ok := projected[key].(map[string]any)
+			if !ok {
+				target = map[string]any{}
+				projected[key] = target
+			}
+			target[subKey] = parent[subKey]
+		}
+		m = projected
+	}
+
+	for _, attr := range excluded {
+		name, sub, _ := strings.Cut(attr, ".")
+		if strings.EqualFold(name, "id") || strings.EqualFold(name, "schemas") {
+			continue
+		}
+		key, ok := findKey(m, name)
+		if !ok {
+			continue
+		}
+		if sub == "" {
+			delete(m, key)
+			continue
+		}
+		if parent, ok := m[key].(map[string]any); ok {
+			if subKey, ok := findKey(parent, sub); ok {
+				delete(parent, subKey)
+			}
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,186 @@
+package scimimpl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/scim"
+)
+
+// bulkIDReference matches the references to resources created earlier in a
+// bulk request, like "bulkId:qwerty".
+var bulkIDReference = regexp.MustCompile(`bulkId:([^"/\s]+)`)
+
+// Bulk runs the operations of a bulk request (RFC 7644 section 3.7) in order.
+// An operation can reference a resource created by a previous operation with
+// its bulkId. Processing stops once failOnErrors operations have failed.
+func (s *Service) Bulk(ctx context.Context, orgID int64, request *scim.BulkRequest) *scim.BulkResponse {
+	response := &scim.BulkResponse{
+		Schemas:    []string{scim.SchemaBulkResponse},
+		Operations: make([]scim.BulkOperationResponse, 0, len(request.Operations)),
+	}
+	created := map[string]string{}
+	errorCount := 0
+	for _, operation := range request.Operations {
+		result := s.bulkOperation(ctx, orgID, operation, created)
+		response.Operations = append(response.Operations, result)
+		if status, _ := strconv.Atoi(result.Status); status >= http.StatusBadRequest {
+			errorCount++
+			if request.FailOnErrors > 0 && errorCount >= request.FailOnErrors {
+				break
+			}
+		}
+	}
+	return response
+}
+
+func (s *Service) bulkOperation(ctx context.Context, orgID int64, operation scim.BulkOperation, created map[string]string) scim.BulkOperationResponse {
+	method := strings.ToUpper(operation.Method)
+	result := scim.BulkOperationResponse{Method: method, BulkID: operation.BulkID}
+	fail := func(err error) scim.BulkOperationResponse {
+		status, errResponse := s.errorResponse(ctx, err)
+		result.Status = strconv.Itoa(status)
+		result.Response = errResponse
+		return result
+	}
+
+	if method == http.MethodPost && operation.BulkID == "" {
+		return fail(scim.ErrInvalidSyntax.Build(scim.ErrorData("bulkId is required for POST operations")))
+	}
+	path, err := resolveBulkIDs(operation.Path, created)
+	if err != nil {
+		return fail(err)
+	}
+	data, err := resolveBulkIDs(string(operation.Data), created)
+	if err != nil {
+		return fail(err)
+	}
+
+	resourceType, id, err := parseBulkPath(method, path)
+	if err != nil {
+		return fail(err)
+	}
+
+	var resourceID string
+	status := http.StatusOK
+	switch resourceType {
+	case scim.ResourceTypeUser:
+		var user *scim.User
+		switch method {
+		case http.MethodPost:
+			status = http.StatusCreated
+			if user, err = decodeBulkData[scim.User](data); err == nil {
+				user, err = s.CreateUser(ctx, orgID, user)
+			}
+		case http.MethodPut:
+			if user, err = decodeBulkData[scim.User](data); err == nil {
+				user, err = s.ReplaceUser(ctx, orgID, id, user)
+			}
+		case http.MethodPatch:
+			var patch *scim.PatchRequest
+			if patch, err = decodeBulkData[scim.PatchRequest](data); err == nil {
+				user, err = s.PatchUser(ctx, orgID, id, patch.Operations)
+			}
+		case http.MethodDelete:
+			status = http.StatusNoContent
+			err = s.DeleteUser(ctx, orgID, id)
+			resourceID = id
+		}
+		if user != nil {
+			resourceID = user.ID
+		}
+	case scim.ResourceTypeGroup:
+		var group *scim.Group
+		switch method {
+		case http.MethodPost:
+			status = http.StatusCreated
+			if group, err = decodeBulkData[scim.Group](data); err == nil {
+				group, err = s.CreateGroup(ctx, orgID, group)
+			}
+		case http.MethodPut:
+			if group, err = decodeBulkData[scim.Group](data); err == nil {
+				group, err = s.ReplaceGroup(ctx, orgID, id, group)
+			}
+		case http.MethodPatch:
+			var patch *scim.PatchRequest
+			if patch, err = decodeBulkData[scim.PatchRequest](data); err == nil {
+				group, err = s.PatchGroup(ctx, orgID, id, patch.Operations)
+			}
+		case http.MethodDelete:
+			status = http.StatusNoContent
+			err = s.DeleteGroup(ctx, orgID, id)
+			resourceID = id
+		}
+		if group != nil {
+			resourceID = group.ID
+		}
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	if method == http.MethodPost {
+		created[operation.BulkID] = resourceID
+	}
+	if parsed, err := strconv.ParseInt(resourceID, 10, 64); err == nil {
+		result.Location = s.location(resourceType, parsed)
+	}
+	result.Status = strconv.Itoa(status)
+	return result
+}
+
+// parseBulkPath returns the resource type and ID of the path of an operation,
+// like /Users or /Groups/1.
+func parseBulkPath(method, path string) (string, string, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	var resourceType string
+	switch parts[0] {
+	case "Users":
+		resourceType = scim.ResourceTypeUser
+	case "Groups":
+		resourceType = scim.ResourceTypeGroup
+	default:
+		return "", "", scim.ErrInvalidPath.Build(scim.ErrorData("unsupported path %q", path))
+	}
+
+	switch method {
+	case http.MethodPost:
+		if len(parts) != 1 {
+			return "", "", scim.ErrInvalidPath.Build(scim.ErrorData("POST operations require a path like /%ss", resourceType))
+		}
+		return resourceType, "", nil
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		if len(parts) != 2 {
+			return "", "", scim.ErrInvalidPath.Build(scim.ErrorData("%s operations require a path like /%ss/{id}", method, resourceType))
+		}
+		return resourceType, parts[1], nil
+	default:
+		return "", "", scim.ErrInvalidSyntax.Build(scim.ErrorData("unsupported method %q", method))
+	}
+}
+
+func resolveBulkIDs(value string, created map[string]string) (string, error) {
+	var err error
+	resolved := bulkIDReference.ReplaceAllStringFunc(value, func(reference string) string {
+		bulkID := strings.TrimPrefix(reference, "bulkId:")
+		id, ok := created[bulkID]
+		if !ok && err == nil {
+			err = scim.ErrInvalidValue.Build(scim.ErrorData("bulkId %s does not reference a resource created by a previous operation", bulkID))
+		}
+		return id
+	})
+	return resolved, err
+}
+
+func decodeBulkData[T any](data string) (*T, error) {
+	resource := new(T)
+	if err := json.NewDecoder(bytes.NewBufferString(data)).Decode(resource); err != nil {
+		return nil, scim.ErrInvalidSyntax.Build(scim.ErrorData("invalid data: %s", err))
+	}
+	return resource, nil
+}
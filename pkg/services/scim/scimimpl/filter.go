@@ -0,0 +1,464 @@
+package scimimpl
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+
+	"github.com/grafana/grafana/pkg/services/scim"
+)
+
+// Filters are evaluated against the JSON representation of resources, as
+// defined in RFC 7644 section 3.4.2.2. Attribute names are case insensitive and
+// so are string comparisons, except for the attributes in caseExactAttributes.
+
+var caseExactAttributes = map[string]bool{"id": true, "externalid": true}
+
+type filter interface {
+	match(resource map[string]any) bool
+}
+
+type logicalFilter struct {
+	and         bool
+	left, right filter
+}
+
+func (f *logicalFilter) match(resource map[string]any) bool {
+	if f.and {
+		return f.left.match(resource) && f.right.match(resource)
+	}
+	return f.left.match(resource) || f.right.match(resource)
+}
+
+type notFilter struct {
+	filter filter
+}
+
+func (f *notFilter) match(resource map[string]any) bool {
+	return !f.filter.match(resource)
+}
+
+// valuePathFilter matches when an element of a multi-valued attribute matches
+// the filter, like emails[type eq "work" and value co "@example.com"].
+type valuePathFilter struct {
+	attr   string
+	filter filter
+}
+
+func (f *valuePathFilter) match(resource map[string]any) bool {
+	for _, v := range attributeValues(resource, f.attr) {
+		if elem, ok := v.(map[string]any); ok && f.filter.match(elem) {
+			return true
+		}
+	}
+	return false
+}
+
+type compareFilter struct {
+	attr  string
+	op    string
+	value any
+}
+
+func (f *compareFilter) match(resource map[string]any) bool {
+	values := attributeValues(resource, f.attr)
+	if f.op == "pr" {
+		for _, v := range values {
+			if present(v) {
+				return true
+			}
+		}
+		return false
+	}
+	if f.op == "ne" {
+		return !(&compareFilter{attr: f.attr, op: "eq", value: f.value}).match(resource)
+	}
+	caseExact := caseExactAttributes[strings.ToLower(f.attr)]
+	for _, v := range values {
+		// Complex values are compared by their value sub-attribute.
+		if elem, ok := v.(map[string]any); ok {
+			v = lookup(elem, "value")
+		}
+		if compare(v, f.op, f.value, caseExact) {
+			return true
+		}
+	}
+	return false
+}
+
+func present(v any) bool {
+	switch value := v.(type) {
+	case nil:
+		return false
+	case string:
+		return value != ""
+	case []any:
+		return len(value) > 0
+	case map[string]any:
+		return len(value) > 0
+	default:
+		return true
+	}
+}
+
+func compare(actual any, op string, expected any, caseExact bool) bool {
+	switch e := expected.(type) {
+	case nil:
+		return op == "eq" && !present(actual)
+	case bool:
+		a, ok := actual.(bool)
+		return ok && op == "eq" && a == e
+	case float64:
+		a, ok := actual.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return a == e
+		case "gt":
+			return a > e
+		case "ge":
+			return a >= e
+		case "lt":
+			return a < e
+		case "le":
+			return a <= e
+		}
+		return false
+	case string:
+		a, ok := actual.(string)
+		if !ok {
+			return false
+		}
+		if !caseExact {
+			a, e = strings.ToLower(a), strings.ToLower(e)
+		}
+		switch op {
+		case "eq":
+			return a == e
+		case "co":
+			return strings.Contains(a, e)
+		case "sw":
+			return strings.HasPrefix(a, e)
+		case "ew":
+			return strings.HasSuffix(a, e)
+		case "gt":
+			return a > e
+		case "ge":
+			return a >= e
+		case "lt":
+			return a < e
+		case "le":
+			return a <= e
+		}
+	}
+	return false
+}
+
+// attributeValues returns the values of an attribute path like name.givenName,
+// flattening multi-valued attributes.
+func attributeValues(resource map[string]any, path string) []any {
+	path = stripSchema(path)
+	values := []any{resource}
+	for _, name := range strings.Split(path, ".") {
+		next := make([]any, 0, len(values))
+		for _, v := range values {
+			elem, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+			switch child := lookup(elem, name).(type) {
+			case nil:
+			case []any:
+				next = append(next, child...)
+			default:
+				next = append(next, child)
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// stripSchema removes the schema URN of fully qualified attribute names.
+func stripSchema(path string) string {
+	if i := strings.LastIndex(path, ":"); i >= 0 && strings.HasPrefix(strings.ToLower(path), "urn:") {
+		return path[i+1:]
+	}
+	return path
+}
+
+func lookup(resource map[string]any, name string) any {
+	if key, ok := findKey(resource, name); ok {
+		return resource[key]
+	}
+	return nil
+}
+
+func findKey(resource map[string]any, name string) (string, bool) {
+	if _, ok := resource[name]; ok {
+		return name, true
+	}
+	for key := range resource {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return name, false
+}
+
+var filterOperators = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true, "pr": true,
+}
+
+type filterToken struct {
+	value  string
+	quoted bool
+}
+
+func tokenizeFilter(input string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			tokens = append(tokens, filterToken{value: string(c)})
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(input); end++ {
+				if input[end] == '\\' {
+					end++
+				} else if input[end] == '"' {
+					break
+				}
+			}
+			if end >= len(input) {
+				return nil, scim.ErrInvalidFilter.Build(scim.ErrorData("unterminated string at position %d", i))
+			}
+			var s string
+			if err := json.Unmarshal([]byte(input[i:end+1]), &s); err != nil {
+				return nil, scim.ErrInvalidFilter.Build(scim.ErrorData("invalid string at position %d", i))
+			}
+			tokens = append(tokens, filterToken{value: s, quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(input) && !unicode.IsSpace(rune(input[end])) && !strings.ContainsRune("()[]\"", rune(input[end])) {
+				end++
+			}
+			tokens = append(tokens, filterToken{value: input[i:end]})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func parseFilter(input string) (filter, error) {
+	tokens, err := tokenizeFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, scim.ErrInvalidFilter.Build(scim.ErrorData("unexpected %q", p.tokens[p.pos].value))
+	}
+	return f, nil
+}
+
+func (p *filterParser) peekKeyword(keyword string) bool {
+	return p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].value, keyword)
+}
+
+func (p *filterParser) next() (filterToken, error) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, scim.ErrInvalidFilter.Build(scim.ErrorData("unexpected end of filter"))
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *filterParser) expect(keyword string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.quoted || t.value != keyword {
+		return scim.ErrInvalidFilter.Build(scim.ErrorData("expected %q, got %q", keyword, t.value))
+	}
+	return nil
+}
+
+func (p *filterParser) parseOr() (filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filter, error) {
+	if p.peekKeyword("not") {
+		p.pos++
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return &notFilter{filter: f}, nil
+	}
+	if p.peekKeyword("(") {
+		p.pos++
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	return p.parseAttributeExpression()
+}
+
+func (p *filterParser) parseAttributeExpression() (filter, error) {
+	attr, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if attr.quoted || strings.ContainsAny(attr.value, "()[]") {
+		return nil, scim.ErrInvalidFilter.Build(scim.ErrorData("expected attribute, got %q", attr.value))
+	}
+
+	if p.peekKeyword("[") {
+		p.pos++
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &valuePathFilter{attr: attr.value, filter: f}, nil
+	}
+
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	operator := strings.ToLower(op.value)
+	if op.quoted || !filterOperators[operator] {
+		return nil, scim.ErrInvalidFilter.Build(scim.ErrorData("unknown operator %q", op.value))
+	}
+	if operator == "pr" {
+		return &compareFilter{attr: attr.value, op: operator}, nil
+	}
+
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	f := &compareFilter{attr: attr.value, op: operator}
+	if value.quoted {
+		f.value = value.value
+		return f, nil
+	}
+	// Unquoted values are true, false, null or numbers, as in JSON.
+	if err := json.Unmarshal([]byte(value.value), &f.value); err != nil {
+		return nil, scim.ErrInvalidFilter.Build(scim.ErrorData("invalid value %q", value.value))
+	}
+	if _, ok := f.value.(string); ok {
+		return nil, scim.ErrInvalidFilter.Build(scim.ErrorData("invalid value %q", value.value))
+	}
+	return f, nil
+}
+
+// filterQuery returns a value the resources matching the filter must contain
+// in one of the searchable attributes, to narrow the search in the database.
+func filterQuery(f filter, searchable ...string) string {
+	switch node := f.(type) {
+	case *compareFilter:
+		value, ok := node.value.(string)
+		if !ok || (node.op != "eq" && node.op != "co" && node.op != "sw" && node.op != "ew") {
+			return ""
+		}
+		attr := strings.ToLower(stripSchema(node.attr))
+		for _, s := range searchable {
+			if attr == s {
+				return value
+			}
+		}
+	case *logicalFilter:
+		if !node.and {
+			return ""
+		}
+		if q := filterQuery(node.left, searchable...); q != "" {
+			return q
+		}
+		return filterQuery(node.right, searchable...)
+	}
+	return ""
+}
+
+// filterExternalID returns the external ID the resources matching the filter must have.
+func filterExternalID(f filter) (string, bool) {
+	switch node := f.(type) {
+	case *compareFilter:
+		value, ok := node.value.(string)
+		if ok && node.op == "eq" && strings.EqualFold(stripSchema(node.attr), "externalId") {
+			return value, true
+		}
+	case *logicalFilter:
+		if !node.and {
+			return "", false
+		}
+		if id, ok := filterExternalID(node.left); ok {
+			return id, true
+		}
+		return filterExternalID(node.right)
+	}
+	return "", false
+}
@@ -0,0 +1,108 @@
+package scimimpl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/scim"
+)
+
+var testUserResource = map[string]any{
+	"schemas":     []any{scim.SchemaUser},
+	"id":          "2",
+	"externalId":  "00u1abc",
+	"userName":    "Jane.Doe@example.com",
+	"displayName": "Jane Doe",
+	"name":        map[string]any{"givenName": "Jane", "familyName": "Doe"},
+	"active":      true,
+	"emails": []any{
+		map[string]any{"value": "jane@example.com", "type": "work", "primary": true},
+		map[string]any{"value": "jane@home.example.com", "type": "home"},
+	},
+	"meta": map[string]any{"created": "2024-01-02T10:00:00Z"},
+}
+
+func TestFilter(t *testing.T) {
+	testCases := []struct {
+		filter   string
+		expected bool
+	}{
+		{filter: `userName eq "jane.doe@example.com"`, expected: true},
+		{filter: `USERNAME Eq "JANE.DOE@EXAMPLE.COM"`, expected: true},
+		{filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName eq "jane.doe@example.com"`, expected: true},
+		{filter: `userName ne "jane.doe@example.com"`, expected: false},
+		{filter: `userName sw "jane"`, expected: true},
+		{filter: `userName ew "@example.com"`, expected: true},
+		{filter: `displayName co "ne D"`, expected: true},
+		{filter: `externalId eq "00u1abc"`, expected: true},
+		{filter: `externalId eq "00U1ABC"`, expected: false},
+		{filter: `name.familyName eq "doe"`, expected: true},
+		{filter: `emails.value eq "jane@home.example.com"`, expected: true},
+		{filter: `emails[type eq "work" and value eq "jane@example.com"]`, expected: true},
+		{filter: `emails[type eq "home" and value eq "jane@example.com"]`, expected: false},
+		{filter: `active eq true`, expected: true},
+		{filter: `active eq false`, expected: false},
+		{filter: `title pr`, expected: false},
+		{filter: `displayName pr`, expected: true},
+		{filter: `meta.created gt "2024-01-01T00:00:00Z"`, expected: true},
+		{filter: `meta.created lt "2024-01-01T00:00:00Z"`, expected: false},
+		{filter: `userName eq "john" or displayName eq "jane doe"`, expected: true},
+		{filter: `userName eq "john" or displayName eq "jane doe" and active eq false`, expected: false},
+		{filter: `(userName eq "john" or displayName eq "jane doe") and active eq true`, expected: true},
+		{filter: `not (userName eq "john")`, expected: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.filter, func(t *testing.T) {
+			f, err := parseFilter(tc.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, f.match(testUserResource))
+		})
+	}
+}
+
+func TestFilter_Invalid(t *testing.T) {
+	for _, filter := range []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName is "jane"`,
+		`userName eq "jane`,
+		`userName eq jane`,
+		`(userName eq "jane"`,
+		`userName eq "jane" and`,
+		`emails[type eq "work"`,
+	} {
+		t.Run(filter, func(t *testing.T) {
+			_, err := parseFilter(filter)
+			require.ErrorIs(t, err, scim.ErrInvalidFilter.Base)
+		})
+	}
+}
+
+func TestFilterQuery(t *testing.T) {
+	testCases := []struct {
+		filter             string
+		expectedQuery      string
+		expectedExternalID string
+	}{
+		{filter: `userName eq "jane"`, expectedQuery: "jane"},
+		{filter: `displayName sw "ja" and active eq true`, expectedQuery: "ja"},
+		{filter: `userName eq "jane" or userName eq "john"`},
+		{filter: `not (userName eq "jane")`},
+		{filter: `userName gt "jane"`},
+		{filter: `externalId eq "00u1abc"`, expectedExternalID: "00u1abc"},
+		{filter: `active eq true and externalId eq "00u1abc"`, expectedExternalID: "00u1abc"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.filter, func(t *testing.T) {
+			f, err := parseFilter(tc.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, filterQuery(f, "username", "displayname"))
+			externalID, ok := filterExternalID(f)
+			assert.Equal(t, tc.expectedExternalID != "", ok)
+			assert.Equal(t, tc.expectedExternalID, externalID)
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package scimimpl
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/scim"
+	"github.com/grafana/grafana/pkg/services/team"
+)
+
+func (s *Service) groupResource(t *team.TeamDTO, external *externalID, members []*team.TeamMemberDTO) *scim.Group {
+	resource := &scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          strconv.FormatInt(t.ID, 10),
+		DisplayName: t.Name,
+		Meta: &scim.Meta{
+			ResourceType: scim.ResourceTypeGroup,
+			Location:     s.location(scim.ResourceTypeGroup, t.ID),
+		},
+	}
+	if external != nil {
+		resource.ExternalID = external.ExternalID
+		created, updated := external.Created, external.Updated
+		resource.Meta.Created, resource.Meta.LastModified = &created, &updated
+	}
+	resource.Members = s.memberValues(members)
+	return resource
+}
+
+func (s *Service) memberValues(members []*team.TeamMemberDTO) []scim.MultiValue {
+	var values []scim.MultiValue
+	for _, m := range members {
+		values = append(values, scim.MultiValue{
+			Value:   strconv.FormatInt(m.UserID, 10),
+			Display: m.Login,
+			Ref:     s.location(scim.ResourceTypeUser, m.UserID),
+		})
+	}
+	return values
+}
+
+func (s *Service) getTeam(ctx context.Context, orgID, teamID int64) (*team.TeamDTO, error) {
+	t, err := s.teamService.GetTeamByID(ctx, &team.GetTeamByIDQuery{OrgID: orgID, ID: teamID, SignedInUser: backgroundUser(orgID)})
+	if err != nil {
+		if errors.Is(err, team.ErrTeamNotFound) {
+			return nil, scim.ErrNotFound.Build(scim.ErrorData("Group %d not found", teamID))
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *Service) getTeamMembers(ctx context.Context, orgID, teamID int64) ([]*team.TeamMemberDTO, error) {
+	return s.teamService.GetTeamMembers(ctx, &team.GetTeamMembersQuery{OrgID: orgID, TeamID: teamID, SignedInUser: backgroundUser(orgID)})
+}
+
+func (s *Service) GetGroup(ctx context.Context, orgID int64, id string) (*scim.Group, error) {
+	teamID, err := parseID(scim.ResourceTypeGroup, id)
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.getTeam(ctx, orgID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	externalIDs, err := s.store.getExternalIDs(ctx, orgID, scim.ResourceTypeGroup, []int64{teamID})
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.getTeamMembers(ctx, orgID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return s.groupResource(t, externalIDs[teamID], members), nil
+}
+
+func (s *Service) ListGroups(ctx context.Context, orgID int64, query listQuery) (*scim.ListResponse, error) {
+	search := &team.SearchTeamsQuery{OrgID: orgID, SignedInUser: backgroundUser(orgID)}
+	if query.filter != nil {
+		search.Query = filterQuery(query.filter, "displayname")
+	}
+	if externalID, ok := filterExternalID(query.filter); ok {
+		ids, err := s.store.findByExternalID(ctx, orgID, scim.ResourceTypeGroup, externalID)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return listResponse([]*scim.Group{}, 0, query), nil
+		}
+		search.TeamIds = ids
+	}
+	result, err := s.teamService.SearchTeams(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	externalIDs, err := s.store.listExternalIDs(ctx, orgID, scim.ResourceTypeGroup)
+	if err != nil {
+		return nil, err
+	}
+	withMembers := filterReferences(query, "members")
+
+	matching := make([]*scim.Group, 0, len(result.Teams))
+	for _, t := range result.Teams {
+		var members []*team.TeamMemberDTO
+		if withMembers {
+			if members, err = s.getTeamMembers(ctx, orgID, t.ID); err != nil {
+				return nil, err
+			}
+		}
+		resource := s.groupResource(t, externalIDs[t.ID], members)
+		if query.filter != nil {
+			m, err := toMap(resource)
+			if err != nil {
+				return nil, err
+			}
+			if !query.filter.match(m) {
+				continue
+			}
+		}
+		matching = append(matching, resource)
+	}
+
+	resources := page(matching, query)
+	if !withMembers && !query.excludeMembers {
+		// Members are only loaded for the returned groups.
+		for _, r := range resources {
+			teamID, _ := strconv.ParseInt(r.ID, 10, 64)
+			members, err := s.getTeamMembers(ctx, orgID, teamID)
+			if err != nil {
+				return nil, err
+			}
+			r.Members = s.memberValues(members)
+		}
+	}
+	return listResponse(resources, len(matching), query), nil
+}
+
+func parseGroup(resource *scim.Group) (string, error) {
+	name := strings.TrimSpace(resource.DisplayName)
+	if name == "" {
+		return "", scim.ErrInvalidValue.Build(scim.ErrorData("displayName is required"))
+	}
+	return name, nil
+}
+
+func (s *Service) CreateGroup(ctx context.Context, orgID int64, resource *scim.Group) (*scim.Group, error) {
+	name, err := parseGroup(resource)
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.teamService.CreateTeam(ctx, name, "", orgID)
+	if err != nil {
+		if errors.Is(err, team.ErrTeamNameTaken) {
+			return nil, scim.ErrUniqueness.Build(scim.ErrorData("Group %s already exists", name))
+		}
+		return nil, err
+	}
+	if err := s.setMembers(ctx, orgID, t.ID, resource.Members); err != nil {
+		return nil, err
+	}
+	if err := s.store.setExternalID(ctx, orgID, scim.ResourceTypeGroup, t.ID, resource.ExternalID, false, s.now()); err != nil {
+		return nil, err
+	}
+	s.log.FromContext(ctx).Info("Provisioned team", "orgId", orgID, "teamId", t.ID, "name", name)
+	return s.GetGroup(ctx, orgID, strconv.FormatInt(t.ID, 10))
+}
+
+func (s *Service) ReplaceGroup(ctx context.Context, orgID int64, id string, resource *scim.Group) (*scim.Group, error) {
+	teamID, err := parseID(scim.ResourceTypeGroup, id)
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.getTeam(ctx, orgID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	name, err := parseGroup(resource)
+	if err != nil {
+		return nil, err
+	}
+	if name != t.Name {
+		if err := s.teamService.UpdateTeam(ctx, &team.UpdateTeamCommand{ID: teamID, OrgID: orgID, Name: name, Email: t.Email}); err != nil {
+			if errors.Is(err, team.ErrTeamNameTaken) {
+				return nil, scim.ErrUniqueness.Build(scim.ErrorData("Group %s already exists", name))
+			}
+			return nil, err
+		}
+	}
+	if err := s.setMembers(ctx, orgID, teamID, resource.Members); err != nil {
+		return nil, err
+	}
+	if err := s.store.setExternalID(ctx, orgID, scim.ResourceTypeGroup, teamID, resource.ExternalID, false, s.now()); err != nil {
+		return nil, err
+	}
+	return s.GetGroup(ctx, orgID, id)
+}
+
+func (s *Service) PatchGroup(ctx context.Context, orgID int64, id string, operations []scim.PatchOperation) (*scim.Group, error) {
+	current, err := s.GetGroup(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	m, err := toMap(current)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPatch(m, operations); err != nil {
+		return nil, err
+	}
+	patched := &scim.Group{}
+	if err := fromMap(m, patched); err != nil {
+		return nil, err
+	}
+	return s.ReplaceGroup(ctx, orgID, id, patched)
+}
+
+func (s *Service) DeleteGroup(ctx context.Context, orgID int64, id string) error {
+	teamID, err := parseID(scim.ResourceTypeGroup, id)
+	if err != nil {
+		return err
+	}
+	if err := s.teamService.DeleteTeam(ctx, &team.DeleteTeamCommand{OrgID: orgID, ID: teamID}); err != nil {
+		if errors.Is(err, team.ErrTeamNotFound) {
+			return scim.ErrNotFound.Build(scim.ErrorData("Group %d not found", teamID))
+		}
+		return err
+	}
+	if err := s.accesscontrolService.DeleteTeamPermissions(ctx, orgID, teamID); err != nil {
+		return err
+	}
+	if err := s.store.deleteExternalID(ctx, orgID, scim.ResourceTypeGroup, teamID); err != nil {
+		return err
+	}
+	s.log.FromContext(ctx).Info("Deprovisioned team", "orgId", orgID, "teamId", teamID)
+	return nil
+}
+
+// setMembers updates the members of a team. Existing members keep their
+// permission, new members are added as members and not as admins.
+func (s *Service) setMembers(ctx context.Context, orgID, teamID int64, members []scim.MultiValue) error {
+	current, err := s.getTeamMembers(ctx, orgID, teamID)
+	if err != nil {
+		return err
+	}
+	desired := make(map[int64]bool, len(members))
+	for _, m := range members {
+		userID, err := strconv.ParseInt(m.Value, 10, 64)
+		if err != nil {
+			return scim.ErrInvalidValue.Build(scim.ErrorData("invalid member %q", m.Value))
+		}
+		desired[userID] = true
+	}
+
+	var commands []accesscontrol.SetResourcePermissionCommand
+	for _, m := range current {
+		if desired[m.UserID] {
+			delete(desired, m.UserID)
+			continue
+		}
+		commands = append(commands, accesscontrol.SetResourcePermissionCommand{UserID: m.UserID, Permission: ""})
+	}
+	for userID := range desired {
+		if _, err := s.getOrgUser(ctx, orgID, userID); err != nil {
+			if errors.Is(err, scim.ErrNotFound.Base) {
+				return scim.ErrInvalidValue.Build(scim.ErrorData("member %d is not a user of the organization", userID))
+			}
+			return err
+		}
+		commands = append(commands, accesscontrol.SetResourcePermissionCommand{UserID: userID, Permission: team.MemberPermissionName})
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+	_, err = s.teamPermissionsService.SetPermissions(ctx, orgID, strconv.FormatInt(teamID, 10), commands...)
+	return err
+}
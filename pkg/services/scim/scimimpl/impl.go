@@ -0,0 +1,164 @@
+package scimimpl
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/auth"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/scim"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+var provisionerRole = accesscontrol.RoleDTO{
+	Name:        "fixed:scim:provisioner",
+	DisplayName: "SCIM provisioner",
+	Description: "Provision the users and teams of the organization with SCIM. Assign it to the service account used by the identity provider.",
+	Group:       "SCIM",
+	Permissions: []accesscontrol.Permission{
+		{Action: scim.ActionProvision},
+	},
+}
+
+// Service implements a SCIM 2.0 server (RFC 7643 and RFC 7644) provisioning
+// the users and teams of the organization of the calling service account.
+type Service struct {
+	cfg                    *setting.Cfg
+	log                    log.Logger
+	store                  *store
+	userService            user.Service
+	orgService             org.Service
+	teamService            team.Service
+	teamPermissionsService accesscontrol.TeamPermissionsService
+	accesscontrolService   accesscontrol.Service
+	authTokenService       auth.UserTokenService
+	now                    func() time.Time
+}
+
+func ProvideService(
+	cfg *setting.Cfg, sqlStore db.DB, userService user.Service, orgService org.Service, teamService team.Service,
+	teamPermissionsService accesscontrol.TeamPermissionsService, accesscontrolService accesscontrol.Service,
+	accessControl accesscontrol.AccessControl, authTokenService auth.UserTokenService, routeRegister routing.RouteRegister,
+) (*Service, error) {
+	s := &Service{
+		cfg:                    cfg,
+		log:                    log.New("scim"),
+		store:                  &store{db: sqlStore},
+		userService:            userService,
+		orgService:             orgService,
+		teamService:            teamService,
+		teamPermissionsService: teamPermissionsService,
+		accesscontrolService:   accesscontrolService,
+		authTokenService:       authTokenService,
+		now:                    time.Now,
+	}
+
+	if !cfg.AuthSCIM.Enabled {
+		return s, nil
+	}
+
+	if err := accesscontrolService.DeclareFixedRoles(accesscontrol.RoleRegistration{Role: provisionerRole}); err != nil {
+		return nil, err
+	}
+
+	api := newAPI(s, accessControl, routeRegister)
+	api.registerAPIEndpoints()
+
+	return s, nil
+}
+
+// listQuery holds the parameters of a list request.
+type listQuery struct {
+	filter filter
+	// startIndex is the 1-based index of the first result.
+	startIndex int
+	count      int
+	// excludeMembers skips loading the members of groups.
+	excludeMembers bool
+}
+
+// backgroundUser is used to query teams and members, the calling service
+// account is authorized to provision all of them.
+func backgroundUser(orgID int64) identity.Requester {
+	return accesscontrol.BackgroundUser("scim", orgID, org.RoleAdmin, []accesscontrol.Permission{
+		{Action: accesscontrol.ActionTeamsRead, Scope: accesscontrol.ScopeTeamsAll},
+		{Action: accesscontrol.ActionOrgUsersRead, Scope: accesscontrol.ScopeUsersAll},
+	})
+}
+
+func (s *Service) location(resourceType string, id int64) string {
+	return s.cfg.AppURL + "api/scim/v2/" + resourceType + "s/" + strconv.FormatInt(id, 10)
+}
+
+func parseID(resourceType, id string) (int64, error) {
+	parsed, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || parsed <= 0 {
+		return 0, scim.ErrNotFound.Build(scim.ErrorData("%s %s not found", resourceType, id))
+	}
+	return parsed, nil
+}
+
+// page returns the results of a list request from all matching results.
+func page[T any](results []T, query listQuery) []T {
+	start := query.startIndex - 1
+	if start >= len(results) {
+		return []T{}
+	}
+	end := len(results)
+	if query.count < end-start {
+		end = start + query.count
+	}
+	return results[start:end]
+}
+
+// toMap returns the JSON representation of a resource, which filters and
+// patch operations are applied to.
+func toMap(resource any) (map[string]any, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]any{}
+	return m, json.Unmarshal(data, &m)
+}
+
+func fromMap(m map[string]any, resource any) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, resource); err != nil {
+		return scim.ErrInvalidValue.Build(scim.ErrorData("invalid resource: %s", err))
+	}
+	return nil
+}
+
+func filterReferences(query listQuery, attr string) bool {
+	if query.filter == nil {
+		return false
+	}
+	return referencesAttribute(query.filter, attr)
+}
+
+func referencesAttribute(f filter, attr string) bool {
+	switch node := f.(type) {
+	case *logicalFilter:
+		return referencesAttribute(node.left, attr) || referencesAttribute(node.right, attr)
+	case *notFilter:
+		return referencesAttribute(node.filter, attr)
+	case *valuePathFilter:
+		return strings.EqualFold(strings.Split(stripSchema(node.attr), ".")[0], attr)
+	case *compareFilter:
+		return strings.EqualFold(strings.Split(stripSchema(node.attr), ".")[0], attr)
+	}
+	return false
+}
@@ -0,0 +1,294 @@
+package scimimpl
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/actest"
+	"github.com/grafana/grafana/pkg/services/auth/authtest"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgimpl"
+	"github.com/grafana/grafana/pkg/services/quota/quotatest"
+	"github.com/grafana/grafana/pkg/services/scim"
+	"github.com/grafana/grafana/pkg/services/supportbundles/supportbundlestest"
+	"github.com/grafana/grafana/pkg/services/team/teamimpl"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/userimpl"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func setupTestService(t *testing.T) (*Service, int64) {
+	t.Helper()
+	sqlStore := db.InitTestDB(t)
+	cfg := setting.NewCfg()
+	cfg.AppURL = "http://localhost:3000/"
+	cfg.AuthSCIM = setting.AuthSCIMSettings{
+		DefaultOrgRole:     string(org.RoleViewer),
+		MaxResults:         100,
+		BulkMaxOperations:  10,
+		BulkMaxPayloadSize: 1 << 20,
+	}
+
+	tracer := tracing.InitializeTracerForTest()
+	orgService, err := orgimpl.ProvideService(sqlStore, cfg, quotatest.New(false, nil))
+	require.NoError(t, err)
+	teamService, err := teamimpl.ProvideService(sqlStore, cfg, tracer)
+	require.NoError(t, err)
+	userService, err := userimpl.ProvideService(sqlStore, orgService, cfg, teamService, localcache.ProvideService(), tracer,
+		quotatest.New(false, nil), supportbundlestest.NewFakeBundleService())
+	require.NoError(t, err)
+
+	// SCIM is disabled so no API gets registered, the service is called directly.
+	s, err := ProvideService(cfg, sqlStore, userService, orgService, teamService, nil, actest.FakeService{}, nil,
+		authtest.NewFakeUserAuthTokenService(), nil)
+	require.NoError(t, err)
+
+	// Organizations always have an admin, users with other roles can be removed.
+	orgID, err := orgService.GetOrCreate(context.Background(), "scim")
+	require.NoError(t, err)
+	admin, err := userService.Create(context.Background(), &user.CreateUserCommand{Login: "admin", Email: "admin@localhost", SkipOrgSetup: true})
+	require.NoError(t, err)
+	require.NoError(t, orgService.AddOrgUser(context.Background(), &org.AddOrgUserCommand{OrgID: orgID, UserID: admin.ID, Role: org.RoleAdmin}))
+	return s, orgID
+}
+
+func testUser(userName, externalID string) *scim.User {
+	return &scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ExternalID:  externalID,
+		UserName:    userName,
+		Name:        &scim.Name{GivenName: "Jane", FamilyName: "Doe"},
+		Emails:      []scim.MultiValue{{Value: userName + "@example.com", Primary: true}},
+		DisplayName: "",
+	}
+}
+
+func TestIntegrationUsers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	s, orgID := setupTestService(t)
+
+	created, err := s.CreateUser(ctx, orgID, testUser("jane", "00u1"))
+	require.NoError(t, err)
+	assert.Equal(t, "jane", created.UserName)
+	assert.Equal(t, "00u1", created.ExternalID)
+	assert.Equal(t, "Jane Doe", created.DisplayName)
+	assert.True(t, *created.Active)
+	assert.Equal(t, []scim.MultiValue{{Value: string(org.RoleViewer), Primary: true}}, created.Roles)
+	assert.Equal(t, "http://localhost:3000/api/scim/v2/Users/"+created.ID, created.Meta.Location)
+
+	t.Run("should refuse duplicate users", func(t *testing.T) {
+		_, err := s.CreateUser(ctx, orgID, testUser("jane", "00u2"))
+		require.ErrorIs(t, err, scim.ErrUniqueness.Base)
+	})
+
+	t.Run("should list users with a filter", func(t *testing.T) {
+		_, err := s.CreateUser(ctx, orgID, testUser("john", "00u3"))
+		require.NoError(t, err)
+
+		for filter, expected := range map[string][]string{
+			`userName eq "JANE"`:                       {"jane"},
+			`externalId eq "00u3"`:                     {"john"},
+			`emails[value ew "@example.com"]`:          {"jane", "john"},
+			`userName eq "jane" and active eq false`:   {},
+			`userName eq "jane" or userName eq "john"`: {"jane", "john"},
+		} {
+			f, err := parseFilter(filter)
+			require.NoError(t, err)
+			result, err := s.ListUsers(ctx, orgID, listQuery{filter: f, startIndex: 1, count: 100})
+			require.NoError(t, err)
+			userNames := []string{}
+			for _, r := range result.Resources {
+				userNames = append(userNames, r.(*scim.User).UserName)
+			}
+			assert.ElementsMatch(t, expected, userNames, filter)
+			assert.Equal(t, len(expected), result.TotalResults, filter)
+		}
+
+		f, err := parseFilter(`emails.value ew "@example.com"`)
+		require.NoError(t, err)
+		result, err := s.ListUsers(ctx, orgID, listQuery{filter: f, startIndex: 2, count: 1})
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.TotalResults)
+		assert.Equal(t, 1, result.ItemsPerPage)
+		assert.Equal(t, "john", result.Resources[0].(*scim.User).UserName)
+	})
+
+	t.Run("should deactivate and change the role of users with patch", func(t *testing.T) {
+		patched, err := s.PatchUser(ctx, orgID, created.ID, []scim.PatchOperation{
+			patchOperation("replace", "", `{"active": "False"}`),
+			patchOperation("replace", "roles", `[{"value": "Editor", "primary": true}]`),
+		})
+		require.NoError(t, err)
+		assert.False(t, *patched.Active)
+		assert.Equal(t, string(org.RoleEditor), patched.Roles[0].Value)
+		assert.Equal(t, "00u1", patched.ExternalID)
+	})
+
+	t.Run("should refuse invalid roles", func(t *testing.T) {
+		_, err := s.PatchUser(ctx, orgID, created.ID, []scim.PatchOperation{
+			patchOperation("replace", "roles", `[{"value": "Owner"}]`),
+		})
+		require.ErrorIs(t, err, scim.ErrInvalidValue.Base)
+	})
+
+	t.Run("should delete users", func(t *testing.T) {
+		require.NoError(t, s.DeleteUser(ctx, orgID, created.ID))
+		_, err := s.GetUser(ctx, orgID, created.ID)
+		require.ErrorIs(t, err, scim.ErrNotFound.Base)
+		_, err = s.userService.GetByLogin(ctx, &user.GetUserByLoginQuery{LoginOrEmail: "jane"})
+		require.ErrorIs(t, err, user.ErrUserNotFound)
+	})
+
+	t.Run("should not find users of other organizations", func(t *testing.T) {
+		otherOrgID, err := s.orgService.GetOrCreate(ctx, "other")
+		require.NoError(t, err)
+		result, err := s.ListUsers(ctx, otherOrgID, listQuery{startIndex: 1, count: 100})
+		require.NoError(t, err)
+		assert.Empty(t, result.Resources)
+	})
+}
+
+func TestIntegrationUsersOfOtherOrganizations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	s, orgID := setupTestService(t)
+	otherOrgID, err := s.orgService.GetOrCreate(ctx, "other")
+	require.NoError(t, err)
+
+	shared, err := s.userService.Create(ctx, &user.CreateUserCommand{Login: "jane", Email: "jane@example.com", Name: "Jane Doe", SkipOrgSetup: true})
+	require.NoError(t, err)
+	for _, id := range []int64{orgID, otherOrgID} {
+		require.NoError(t, s.orgService.AddOrgUser(ctx, &org.AddOrgUserCommand{OrgID: id, UserID: shared.ID, Role: org.RoleViewer}))
+	}
+	id := strconv.FormatInt(shared.ID, 10)
+
+	t.Run("should change the role and the external ID", func(t *testing.T) {
+		patched, err := s.PatchUser(ctx, orgID, id, []scim.PatchOperation{
+			patchOperation("replace", "roles", `[{"value": "Editor", "primary": true}]`),
+			patchOperation("replace", "externalId", `"00u1"`),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, string(org.RoleEditor), patched.Roles[0].Value)
+		assert.Equal(t, "00u1", patched.ExternalID)
+	})
+
+	t.Run("should refuse changes of attributes shared by organizations", func(t *testing.T) {
+		for _, op := range []scim.PatchOperation{
+			patchOperation("replace", "userName", `"john"`),
+			patchOperation("replace", "emails", `[{"value": "john@example.com", "primary": true}]`),
+			patchOperation("replace", "displayName", `"John Doe"`),
+			patchOperation("add", "password", `"secret-password"`),
+		} {
+			_, err := s.PatchUser(ctx, orgID, id, []scim.PatchOperation{op})
+			require.ErrorIs(t, err, scim.ErrMutability.Base, op.Path)
+		}
+	})
+
+	t.Run("should remove deactivated users from the organization", func(t *testing.T) {
+		patched, err := s.PatchUser(ctx, orgID, id, []scim.PatchOperation{patchOperation("replace", "active", `false`)})
+		require.NoError(t, err)
+		assert.False(t, *patched.Active)
+
+		_, err = s.GetUser(ctx, orgID, id)
+		require.ErrorIs(t, err, scim.ErrNotFound.Base)
+		usr, err := s.userService.GetByID(ctx, &user.GetUserByIDQuery{ID: shared.ID})
+		require.NoError(t, err)
+		assert.False(t, usr.IsDisabled)
+		_, err = s.GetUser(ctx, otherOrgID, id)
+		require.NoError(t, err)
+	})
+
+	t.Run("should change provisioned users that joined other organizations", func(t *testing.T) {
+		created, err := s.CreateUser(ctx, orgID, testUser("john", ""))
+		require.NoError(t, err)
+		createdID, err := strconv.ParseInt(created.ID, 10, 64)
+		require.NoError(t, err)
+		require.NoError(t, s.orgService.AddOrgUser(ctx, &org.AddOrgUserCommand{OrgID: otherOrgID, UserID: createdID, Role: org.RoleViewer}))
+
+		patched, err := s.PatchUser(ctx, orgID, created.ID, []scim.PatchOperation{
+			patchOperation("replace", "displayName", `"John Doe"`),
+			patchOperation("replace", "active", `false`),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "John Doe", patched.DisplayName)
+		assert.False(t, *patched.Active)
+	})
+}
+
+func TestIntegrationBulk(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	s, orgID := setupTestService(t)
+
+	data := func(v any) json.RawMessage {
+		raw, err := json.Marshal(v)
+		require.NoError(t, err)
+		return raw
+	}
+
+	response := s.Bulk(ctx, orgID, &scim.BulkRequest{
+		FailOnErrors: 2,
+		Operations: []scim.BulkOperation{
+			{Method: http.MethodPost, BulkID: "jane", Path: "/Users", Data: data(testUser("jane", ""))},
+			{Method: http.MethodPatch, Path: "/Users/bulkId:jane", Data: data(scim.PatchRequest{
+				Operations: []scim.PatchOperation{patchOperation("replace", "displayName", `"Jane D."`)},
+			})},
+			{Method: http.MethodPost, BulkID: "duplicate", Path: "/Users", Data: data(testUser("jane", ""))},
+			{Method: http.MethodDelete, Path: "/Users/bulkId:unknown"},
+			{Method: http.MethodDelete, Path: "/Users/bulkId:jane"},
+		},
+	})
+
+	require.Len(t, response.Operations, 4, "processing should stop after two errors")
+	assert.Equal(t, "201", response.Operations[0].Status)
+	assert.NotEmpty(t, response.Operations[0].Location)
+	assert.Equal(t, "200", response.Operations[1].Status)
+	assert.Equal(t, response.Operations[0].Location, response.Operations[1].Location)
+	assert.Equal(t, "409", response.Operations[2].Status)
+	assert.Equal(t, "uniqueness", response.Operations[2].Response.(*scim.ErrorResponse).SCIMType)
+	assert.Equal(t, "400", response.Operations[3].Status)
+
+	f, err := parseFilter(`userName eq "jane"`)
+	require.NoError(t, err)
+	result, err := s.ListUsers(ctx, orgID, listQuery{filter: f, startIndex: 1, count: 100})
+	require.NoError(t, err)
+	require.Len(t, result.Resources, 1)
+	assert.Equal(t, "Jane D.", result.Resources[0].(*scim.User).DisplayName)
+}
+
+func TestParseBulkPath(t *testing.T) {
+	resourceType, id, err := parseBulkPath(http.MethodPut, "/Groups/12")
+	require.NoError(t, err)
+	assert.Equal(t, scim.ResourceTypeGroup, resourceType)
+	assert.Equal(t, "12", id)
+
+	_, _, err = parseBulkPath(http.MethodPost, "/Users/12")
+	require.ErrorIs(t, err, scim.ErrInvalidPath.Base)
+	_, _, err = parseBulkPath(http.MethodDelete, "/Users")
+	require.ErrorIs(t, err, scim.ErrInvalidPath.Base)
+	_, _, err = parseBulkPath(http.MethodDelete, "/Schemas/1")
+	require.ErrorIs(t, err, scim.ErrInvalidPath.Base)
+	_, _, err = parseBulkPath(http.MethodGet, "/Users/1")
+	require.ErrorIs(t, err, scim.ErrInvalidSyntax.Base)
+}
@@ -0,0 +1,271 @@
+package scimimpl
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/scim"
+)
+
+// PATCH operations (RFC 7644 section 3.5.2) are applied to the JSON
+// representation of a resource, the result then replaces the resource.
+
+var multiValuedAttributes = map[string]bool{"emails": true, "roles": true, "groups": true, "members": true}
+
+type patchPath struct {
+	attr   string
+	filter filter
+	sub    string
+}
+
+func parsePatchPath(path string) (*patchPath, error) {
+	path = strings.TrimSpace(stripSchema(path))
+	if path == "" {
+		return nil, scim.ErrInvalidPath.Build(scim.ErrorData("empty path"))
+	}
+	p := &patchPath{attr: path}
+	if start := strings.Index(path, "["); start >= 0 {
+		end := strings.LastIndex(path, "]")
+		if end < start {
+			return nil, scim.ErrInvalidPath.Build(scim.ErrorData("invalid path %q", path))
+		}
+		f, err := parseFilter(path[start+1 : end])
+		if err != nil {
+			return nil, scim.ErrInvalidPath.Build(scim.ErrorData("invalid filter in path %q", path))
+		}
+		p.attr, p.filter = path[:start], f
+		rest := path[end+1:]
+		if rest != "" {
+			if !strings.HasPrefix(rest, ".") || len(rest) == 1 {
+				return nil, scim.ErrInvalidPath.Build(scim.ErrorData("invalid path %q", path))
+			}
+			p.sub = rest[1:]
+		}
+	} else if i := strings.Index(path, "."); i >= 0 {
+		p.attr, p.sub = path[:i], path[i+1:]
+	}
+	if p.attr == "" || strings.ContainsAny(p.attr, " .") || strings.ContainsAny(p.sub, " .[]") {
+		return nil, scim.ErrInvalidPath.Build(scim.ErrorData("invalid path %q", path))
+	}
+	return p, nil
+}
+
+func applyPatch(resource map[string]any, operations []scim.PatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return scim.ErrInvalidSyntax.Build(scim.ErrorData("unknown operation %q", operation.Op))
+		}
+
+		var value any
+		if len(operation.Value) > 0 {
+			if err := json.Unmarshal(operation.Value, &value); err != nil {
+				return scim.ErrInvalidValue.Build(scim.ErrorData("invalid value: %s", err))
+			}
+		}
+
+		if operation.Path == "" {
+			if op == "remove" {
+				return scim.ErrNoTarget.Build(scim.ErrorData("remove operation requires a path"))
+			}
+			attributes, ok := value.(map[string]any)
+			if !ok {
+				return scim.ErrInvalidValue.Build(scim.ErrorData("operation without path requires an object value"))
+			}
+			for name, v := range attributes {
+				// Some identity providers send read-only attributes like the id
+				// with the changes.
+				if checkMutability(stripSchema(name)) != nil {
+					continue
+				}
+				path, err := parsePatchPath(name)
+				if err != nil {
+					return err
+				}
+				if err := applyOperation(resource, op, path, v); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		path, err := parsePatchPath(operation.Path)
+		if err != nil {
+			return err
+		}
+		if err := applyOperation(resource, op, path, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyOperation(resource map[string]any, op string, path *patchPath, value any) error {
+	if err := checkMutability(path.attr); err != nil {
+		return err
+	}
+	key, _ := findKey(resource, path.attr)
+	value = normalizeValue(path, value)
+
+	if path.filter != nil {
+		return applyFilteredOperation(resource, key, op, path, value)
+	}
+
+	if path.sub != "" {
+		parent, ok := resource[key].(map[string]any)
+		if !ok {
+			if op == "remove" {
+				return nil
+			}
+			parent = map[string]any{}
+			resource[key] = parent
+		}
+		subKey, _ := findKey(parent, path.sub)
+		if op == "remove" {
+			delete(parent, subKey)
+		} else {
+			parent[subKey] = value
+		}
+		return nil
+	}
+
+	_, isMulti := resource[key].([]any)
+	isMulti = isMulti || multiValuedAttributes[strings.ToLower(key)]
+	switch {
+	case op == "remove" && isMulti && value != nil:
+		// Remove the given elements, like members with value [{"value": "1"}].
+		resource[key] = removeElements(asList(resource[key]), asList(value))
+	case op == "remove":
+		delete(resource, key)
+	case op == "add" && isMulti:
+		resource[key] = appendElements(asList(resource[key]), asList(value))
+	case isMulti:
+		resource[key] = asList(value)
+	default:
+		resource[key] = value
+	}
+	return nil
+}
+
+func applyFilteredOperation(resource map[string]any, key, op string, path *patchPath, value any) error {
+	elements := asList(resource[key])
+	result := make([]any, 0, len(elements))
+	matched := false
+	for _, e := range elements {
+		elem, ok := e.(map[string]any)
+		if !ok || !path.filter.match(elem) {
+			result = append(result, e)
+			continue
+		}
+		matched = true
+		switch {
+		case op == "remove" && path.sub == "":
+			continue
+		case op == "remove":
+			subKey, _ := findKey(elem, path.sub)
+			delete(elem, subKey)
+		case path.sub != "":
+			subKey, _ := findKey(elem, path.sub)
+			elem[subKey] = value
+		default:
+			replacement, ok := value.(map[string]any)
+			if !ok {
+				return scim.ErrInvalidValue.Build(scim.ErrorData("value of %s must be an object", key))
+			}
+			for k, v := range replacement {
+				elem[k] = v
+			}
+		}
+		result = append(result, elem)
+	}
+	if !matched && op != "remove" {
+		return scim.ErrNoTarget.Build(scim.ErrorData("no value of %s matches the filter", key))
+	}
+	resource[key] = result
+	return nil
+}
+
+func checkMutability(attr string) error {
+	switch strings.ToLower(attr) {
+	case "id", "meta", "schemas", "groups":
+		return scim.ErrMutability.Build(scim.ErrorData("attribute %s is read-only", attr))
+	}
+	return nil
+}
+
+// normalizeValue converts boolean strings some identity providers send, like
+// "False", to booleans.
+func normalizeValue(path *patchPath, value any) any {
+	attr := path.attr
+	if path.sub != "" {
+		attr = path.sub
+	}
+	if s, ok := value.(string); ok && (strings.EqualFold(attr, "active") || strings.EqualFold(attr, "primary")) {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	if m, ok := value.(map[string]any); ok && path.sub == "" && path.filter == nil {
+		for k, v := range m {
+			m[k] = normalizeValue(&patchPath{attr: k}, v)
+		}
+	}
+	return value
+}
+
+func asList(value any) []any {
+	switch v := value.(type) {
+	case nil:
+		return []any{}
+	case []any:
+		return v
+	default:
+		return []any{v}
+	}
+}
+
+func elementValue(e any) (string, bool) {
+	elem, ok := e.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	value, ok := lookup(elem, "value").(string)
+	return value, ok
+}
+
+func appendElements(elements, added []any) []any {
+	existing := make(map[string]bool, len(elements))
+	for _, e := range elements {
+		if v, ok := elementValue(e); ok {
+			existing[v] = true
+		}
+	}
+	for _, e := range added {
+		if v, ok := elementValue(e); ok {
+			if existing[v] {
+				continue
+			}
+			existing[v] = true
+		}
+		elements = append(elements, e)
+	}
+	return elements
+}
+
+func removeElements(elements, removed []any) []any {
+	values := make(map[string]bool, len(removed))
+	for _, e := range removed {
+		if v, ok := elementValue(e); ok {
+			values[v] = true
+		}
+	}
+	result := make([]any, 0, len(elements))
+	for _, e := range elements {
+		if v, ok := elementValue(e); ok && values[v] {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
@@ -0,0 +1,151 @@
+package scimimpl
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/scim"
+)
+
+func testGroupResource() map[string]any {
+	return map[string]any{
+		"schemas":     []any{scim.SchemaGroup},
+		"id":          "1",
+		"displayName": "Engineering",
+		"members": []any{
+			map[string]any{"value": "2", "display": "jane"},
+			map[string]any{"value": "3", "display": "john"},
+		},
+	}
+}
+
+func patchOperation(op, path, value string) scim.PatchOperation {
+	operation := scim.PatchOperation{Op: op, Path: path}
+	if value != "" {
+		operation.Value = json.RawMessage(value)
+	}
+	return operation
+}
+
+func TestApplyPatch(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		resource   func() map[string]any
+		operations []scim.PatchOperation
+		expected   map[string]any
+	}{
+		{
+			desc:       "should replace an attribute",
+			resource:   testGroupResource,
+			operations: []scim.PatchOperation{patchOperation("replace", "displayName", `"Platform"`)},
+			expected:   map[string]any{"displayName": "Platform"},
+		},
+		{
+			desc:       "should replace attributes without path",
+			resource:   testGroupResource,
+			operations: []scim.PatchOperation{patchOperation("Replace", "", `{"id": "1", "displayName": "Platform"}`)},
+			expected:   map[string]any{"id": "1", "displayName": "Platform"},
+		},
+		{
+			desc:       "should add members without duplicates",
+			resource:   testGroupResource,
+			operations: []scim.PatchOperation{patchOperation("add", "members", `[{"value": "3"}, {"value": "4"}]`)},
+			expected: map[string]any{"members": []any{
+				map[string]any{"value": "2", "display": "jane"},
+				map[string]any{"value": "3", "display": "john"},
+				map[string]any{"value": "4"},
+			}},
+		},
+		{
+			desc:       "should remove members with a filter",
+			resource:   testGroupResource,
+			operations: []scim.PatchOperation{patchOperation("remove", `members[value eq "2"]`, "")},
+			expected: map[string]any{"members": []any{
+				map[string]any{"value": "3", "display": "john"},
+			}},
+		},
+		{
+			desc:       "should remove members with a value",
+			resource:   testGroupResource,
+			operations: []scim.PatchOperation{patchOperation("remove", "members", `[{"value": "3"}]`)},
+			expected: map[string]any{"members": []any{
+				map[string]any{"value": "2", "display": "jane"},
+			}},
+		},
+		{
+			desc:       "should remove all members",
+			resource:   testGroupResource,
+			operations: []scim.PatchOperation{patchOperation("remove", "members", "")},
+			expected:   map[string]any{"members": nil},
+		},
+		{
+			desc:       "should replace all members",
+			resource:   testGroupResource,
+			operations: []scim.PatchOperation{patchOperation("replace", "members", `[{"value": "5"}]`)},
+			expected:   map[string]any{"members": []any{map[string]any{"value": "5"}}},
+		},
+		{
+			desc: "should convert boolean strings",
+			resource: func() map[string]any {
+				return map[string]any{"userName": "jane", "active": true}
+			},
+			operations: []scim.PatchOperation{patchOperation("replace", "", `{"active": "False"}`)},
+			expected:   map[string]any{"active": false},
+		},
+		{
+			desc: "should replace a sub-attribute of a filtered value",
+			resource: func() map[string]any {
+				return map[string]any{"emails": []any{map[string]any{"value": "jane@example.com", "type": "work"}}}
+			},
+			operations: []scim.PatchOperation{patchOperation("replace", `emails[type eq "work"].value`, `"jane.doe@example.com"`)},
+			expected: map[string]any{"emails": []any{
+				map[string]any{"value": "jane.doe@example.com", "type": "work"},
+			}},
+		},
+		{
+			desc: "should add a sub-attribute",
+			resource: func() map[string]any {
+				return map[string]any{"userName": "jane"}
+			},
+			operations: []scim.PatchOperation{patchOperation("add", "name.givenName", `"Jane"`)},
+			expected:   map[string]any{"name": map[string]any{"givenName": "Jane"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			resource := tc.resource()
+			require.NoError(t, applyPatch(resource, tc.operations))
+			for key, expected := range tc.expected {
+				if expected == nil {
+					assert.NotContains(t, resource, key)
+					continue
+				}
+				assert.Equal(t, expected, resource[key])
+			}
+		})
+	}
+}
+
+func TestApplyPatch_Errors(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		operation scim.PatchOperation
+		expected  error
+	}{
+		{desc: "unknown operation", operation: patchOperation("move", "displayName", `"x"`), expected: scim.ErrInvalidSyntax.Base},
+		{desc: "read-only attribute", operation: patchOperation("replace", "id", `"2"`), expected: scim.ErrMutability.Base},
+		{desc: "remove without path", operation: patchOperation("remove", "", ""), expected: scim.ErrNoTarget.Base},
+		{desc: "no matching value", operation: patchOperation("replace", `members[value eq "9"].display`, `"x"`), expected: scim.ErrNoTarget.Base},
+		{desc: "invalid path", operation: patchOperation("replace", `members[value eq "2"`, `"x"`), expected: scim.ErrInvalidPath.Base},
+		{desc: "invalid value", operation: patchOperation("replace", "", `"x"`), expected: scim.ErrInvalidValue.Base},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := applyPatch(testGroupResource(), []scim.PatchOperation{tc.operation})
+			require.ErrorIs(t, err, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package scimimpl
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+// externalID is the identifier of a provisioned user or team in the identity provider.
+// Provisioned is set for resources created with SCIM in the organization, their
+// rows are kept without an external ID.
+type externalID struct {
+	ID           int64     `xorm:"pk autoincr 'id'"`
+	OrgID        int64     `xorm:"org_id"`
+	ResourceType string    `xorm:"resource_type"`
+	ResourceID   int64     `xorm:"resource_id"`
+	ExternalID   string    `xorm:"external_id"`
+	Provisioned  bool      `xorm:"provisioned"`
+	Created      time.Time `xorm:"'created'"`
+	Updated      time.Time `xorm:"'updated'"`
+}
+
+func (e externalID) TableName() string {
+	return "scim_external_id"
+}
+
+type store struct {
+	db db.DB
+}
+
+func (s *store) getExternalIDs(ctx context.Context, orgID int64, resourceType string, resourceIDs []int64) (map[int64]*externalID, error) {
+	ids := make(map[int64]*externalID, len(resourceIDs))
+	if len(resourceIDs) == 0 {
+		return ids, nil
+	}
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var rows []*externalID
+		if err := sess.Where("org_id = ? AND resource_type = ?", orgID, resourceType).In("resource_id", resourceIDs).Find(&rows); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			ids[row.ResourceID] = row
+		}
+		return nil
+	})
+	return ids, err
+}
+
+func (s *store) listExternalIDs(ctx context.Context, orgID int64, resourceType string) (map[int64]*externalID, error) {
+	ids := make(map[int64]*externalID)
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var rows []*externalID
+		if err := sess.Where("org_id = ? AND resource_type = ?", orgID, resourceType).Find(&rows); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			ids[row.ResourceID] = row
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// findByExternalID returns the IDs of the resources with an external ID.
+func (s *store) findByExternalID(ctx context.Context, orgID int64, resourceType, id string) ([]int64, error) {
+	var resourceIDs []int64
+	if id == "" {
+		return resourceIDs, nil
+	}
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Table("scim_external_id").Where("org_id = ? AND resource_type = ? AND external_id = ?", orgID, resourceType, id).
+			Cols("resource_id").Find(&resourceIDs)
+	})
+	return resourceIDs, err
+}
+
+// setExternalID sets the external ID of a resource, an empty ID removes it. The
+// resource is marked as provisioned in the organization when provisioned is true.
+func (s *store) setExternalID(ctx context.Context, orgID int64, resourceType string, resourceID int64, id string, provisioned bool, now time.Time) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		existing := externalID{}
+		ok, err := sess.Where("org_id = ? AND resource_type = ? AND resource_id = ?", orgID, resourceType, resourceID).Get(&existing)
+		if err != nil {
+			return err
+		}
+		switch {
+		case id == "" && ok && !existing.Provisioned:
+			_, err = sess.ID(existing.ID).Delete(&externalID{})
+		case id == "" && !ok && !provisioned:
+		case ok && existing.ExternalID == id:
+		case ok:
+			existing.ExternalID = id
+			existing.Updated = now
+			_, err = sess.ID(existing.ID).Cols("external_id", "updated").Update(&existing)
+		default:
+			_, err = sess.Insert(&externalID{
+				OrgID:        orgID,
+				ResourceType: resourceType,
+				ResourceID:   resourceID,
+				ExternalID:   id,
+				Provisioned:  provisioned,
+				Created:      now,
+				Updated:      now,
+			})
+		}
+		return err
+	})
+}
+
+func (s *store) deleteExternalID(ctx context.Context, orgID int64, resourceType string, resourceID int64) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Where("org_id = ? AND resource_type = ? AND resource_id = ?", orgID, resourceType, resourceID).Delete(&externalID{})
+		return err
+	})
+}
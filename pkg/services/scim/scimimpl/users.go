@@ -0,0 +1,457 @@
+package scimimpl
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/scim"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func (s *Service) userResource(u *org.OrgUserDTO, external *externalID, teams []*team.TeamDTO) *scim.User {
+	active := !u.IsDisabled
+	created, updated := u.Created, u.Updated
+	resource := &scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          strconv.FormatInt(u.UserID, 10),
+		UserName:    u.Login,
+		DisplayName: u.Name,
+		Active:      &active,
+		Roles:       []scim.MultiValue{{Value: u.Role, Primary: true}},
+		Meta: &scim.Meta{
+			ResourceType: scim.ResourceTypeUser,
+			Created:      &created,
+			LastModified: &updated,
+			Location:     s.location(scim.ResourceTypeUser, u.UserID),
+		},
+	}
+	if u.Name != "" {
+		resource.Name = &scim.Name{Formatted: u.Name}
+	}
+	if u.Email != "" {
+		resource.Emails = []scim.MultiValue{{Value: u.Email, Type: "work", Primary: true}}
+	}
+	if external != nil {
+		resource.ExternalID = external.ExternalID
+	}
+	resource.Groups = s.groupValues(teams)
+	return resource
+}
+
+func (s *Service) groupValues(teams []*team.TeamDTO) []scim.MultiValue {
+	var values []scim.MultiValue
+	for _, t := range teams {
+		values = append(values, scim.MultiValue{
+			Value:   strconv.FormatInt(t.ID, 10),
+			Display: t.Name,
+			Ref:     s.location(scim.ResourceTypeGroup, t.ID),
+		})
+	}
+	return values
+}
+
+func (s *Service) getTeamsByUser(ctx context.Context, orgID, userID int64) ([]*team.TeamDTO, error) {
+	return s.teamService.GetTeamsByUser(ctx, &team.GetTeamsByUserQuery{OrgID: orgID, UserID: userID, SignedInUser: backgroundUser(orgID)})
+}
+
+func (s *Service) getOrgUser(ctx context.Context, orgID, userID int64) (*org.OrgUserDTO, error) {
+	result, err := s.orgService.SearchOrgUsers(ctx, &org.SearchOrgUsersQuery{OrgID: orgID, UserID: userID, DontEnforceAccessControl: true})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.OrgUsers) == 0 {
+		return nil, scim.ErrNotFound.Build(scim.ErrorData("User %d not found", userID))
+	}
+	return result.OrgUsers[0], nil
+}
+
+func (s *Service) GetUser(ctx context.Context, orgID int64, id string) (*scim.User, error) {
+	userID, err := parseID(scim.ResourceTypeUser, id)
+	if err != nil {
+		return nil, err
+	}
+	orgUser, err := s.getOrgUser(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	externalIDs, err := s.store.getExternalIDs(ctx, orgID, scim.ResourceTypeUser, []int64{userID})
+	if err != nil {
+		return nil, err
+	}
+	teams, err := s.getTeamsByUser(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.userResource(orgUser, externalIDs[userID], teams), nil
+}
+
+func (s *Service) ListUsers(ctx context.Context, orgID int64, query listQuery) (*scim.ListResponse, error) {
+	search := &org.SearchOrgUsersQuery{OrgID: orgID, DontEnforceAccessControl: true}
+	if query.filter != nil {
+		search.Query = filterQuery(query.filter, "username", "emails", "emails.value", "displayname", "name.formatted")
+	}
+	if externalID, ok := filterExternalID(query.filter); ok {
+		ids, err := s.store.findByExternalID(ctx, orgID, scim.ResourceTypeUser, externalID)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return listResponse([]*scim.User{}, 0, query), nil
+		}
+		search.UserID = ids[0]
+	}
+	result, err := s.orgService.SearchOrgUsers(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	externalIDs, err := s.store.listExternalIDs(ctx, orgID, scim.ResourceTypeUser)
+	if err != nil {
+		return nil, err
+	}
+	withGroups := filterReferences(query, "groups")
+
+	matching := make([]*scim.User, 0, len(result.OrgUsers))
+	for _, u := range result.OrgUsers {
+		var teams []*team.TeamDTO
+		if withGroups {
+			if teams, err = s.getTeamsByUser(ctx, orgID, u.UserID); err != nil {
+				return nil, err
+			}
+		}
+		resource := s.userResource(u, externalIDs[u.UserID], teams)
+		if query.filter != nil {
+			m, err := toMap(resource)
+			if err != nil {
+				return nil, err
+			}
+			if !query.filter.match(m) {
+				continue
+			}
+		}
+		matching = append(matching, resource)
+	}
+
+	resources := page(matching, query)
+	if !withGroups {
+		// Groups are only loaded for the returned users.
+		for _, r := range resources {
+			userID, _ := strconv.ParseInt(r.ID, 10, 64)
+			teams, err := s.getTeamsByUser(ctx, orgID, userID)
+			if err != nil {
+				return nil, err
+			}
+			r.Groups = s.groupValues(teams)
+		}
+	}
+	return listResponse(resources, len(matching), query), nil
+}
+
+func listResponse[T any](resources []T, total int, query listQuery) *scim.ListResponse {
+	response := &scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   query.startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    make([]any, 0, len(resources)),
+	}
+	for _, r := range resources {
+		response.Resources = append(response.Resources, r)
+	}
+	return response
+}
+
+// userAttributes are the Grafana attributes of a SCIM user.
+type userAttributes struct {
+	login    string
+	email    string
+	name     string
+	role     org.RoleType
+	password string
+}
+
+func (s *Service) parseUser(resource *scim.User) (*userAttributes, error) {
+	attrs := &userAttributes{
+		login:    strings.TrimSpace(resource.UserName),
+		name:     resource.DisplayName,
+		password: resource.Password,
+	}
+	if attrs.login == "" {
+		return nil, scim.ErrInvalidValue.Build(scim.ErrorData("userName is required"))
+	}
+	if attrs.name == "" && resource.Name != nil {
+		attrs.name = resource.Name.Formatted
+		if attrs.name == "" {
+			attrs.name = strings.TrimSpace(resource.Name.GivenName + " " + resource.Name.FamilyName)
+		}
+	}
+	attrs.email = primaryValue(resource.Emails)
+	if role := primaryValue(resource.Roles); role != "" {
+		attrs.role = org.RoleType(role)
+		if !attrs.role.IsValid() {
+			return nil, scim.ErrInvalidValue.Build(scim.ErrorData("invalid role %q", role))
+		}
+	}
+	return attrs, nil
+}
+
+func primaryValue(values []scim.MultiValue) string {
+	for _, v := range values {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+func (s *Service) CreateUser(ctx context.Context, orgID int64, resource *scim.User) (*scim.User, error) {
+	attrs, err := s.parseUser(resource)
+	if err != nil {
+		return nil, err
+	}
+	if attrs.role == "" {
+		attrs.role = org.RoleType(s.cfg.AuthSCIM.DefaultOrgRole)
+	}
+
+	usr, err := s.userService.Create(ctx, &user.CreateUserCommand{
+		Login:        attrs.login,
+		Email:        attrs.email,
+		Name:         attrs.name,
+		Password:     user.Password(attrs.password),
+		IsDisabled:   resource.Active != nil && !*resource.Active,
+		SkipOrgSetup: true,
+	})
+	if err != nil {
+		if errors.Is(err, user.ErrUserAlreadyExists) {
+			return nil, scim.ErrUniqueness.Build(scim.ErrorData("User %s already exists", attrs.login))
+		}
+		return nil, err
+	}
+
+	if err := s.orgService.AddOrgUser(ctx, &org.AddOrgUserCommand{OrgID: orgID, UserID: usr.ID, Role: attrs.role}); err != nil {
+		if deleteErr := s.userService.Delete(ctx, &user.DeleteUserCommand{UserID: usr.ID}); deleteErr != nil {
+			s.log.FromContext(ctx).Error("Failed to delete user after failing to add it to the organization", "userId", usr.ID, "error", deleteErr)
+		}
+		return nil, err
+	}
+
+	if err := s.store.setExternalID(ctx, orgID, scim.ResourceTypeUser, usr.ID, resource.ExternalID, true, s.now()); err != nil {
+		return nil, err
+	}
+	s.log.FromContext(ctx).Info("Provisioned user", "orgId", orgID, "userId", usr.ID, "login", usr.Login)
+	return s.GetUser(ctx, orgID, strconv.FormatInt(usr.ID, 10))
+}
+
+// ReplaceUser updates a user with the attributes of a resource. The role is
+// left unchanged when the resource has no roles. Users are shared by all
+// organizations, so the login, email, name and password of users the organization
+// doesn't own can't be changed and deactivating them only removes them from the
+// organization.
+func (s *Service) ReplaceUser(ctx context.Context, orgID int64, id string, resource *scim.User) (*scim.User, error) {
+	userID, err := parseID(scim.ResourceTypeUser, id)
+	if err != nil {
+		return nil, err
+	}
+	orgUser, err := s.getOrgUser(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := s.parseUser(resource)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkProvisionable(ctx, userID); err != nil {
+		return nil, err
+	}
+	owned, err := s.ownsUser(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if owned {
+		if err := s.updateUser(ctx, orgID, orgUser, attrs, resource.Active); err != nil {
+			return nil, err
+		}
+	} else {
+		if !strings.EqualFold(attrs.login, orgUser.Login) || !strings.EqualFold(attrs.email, orgUser.Email) || attrs.name != orgUser.Name || attrs.password != "" {
+			return nil, scim.ErrMutability.Build(scim.ErrorData("the login, email, name and password of users of other organizations can't be changed"))
+		}
+		if resource.Active != nil && *resource.Active && orgUser.IsDisabled {
+			return nil, scim.ErrMutability.Build(scim.ErrorData("users of other organizations can't be activated"))
+		}
+		if resource.Active != nil && !*resource.Active {
+			if _, err := s.removeOrgUser(ctx, orgID, userID, false); err != nil {
+				return nil, err
+			}
+			s.log.FromContext(ctx).Info("Removed deactivated user of other organizations", "orgId", orgID, "userId", userID)
+			orgUser.IsDisabled = true
+			return s.userResource(orgUser, nil, nil), nil
+		}
+	}
+
+	if attrs.role != "" && string(attrs.role) != orgUser.Role {
+		if err := s.orgService.UpdateOrgUser(ctx, &org.UpdateOrgUserCommand{OrgID: orgID, UserID: userID, Role: attrs.role}); err != nil {
+			if errors.Is(err, org.ErrLastOrgAdmin) {
+				return nil, scim.ErrInvalidValue.Build(scim.ErrorData("cannot change the role of the last organization admin"))
+			}
+			return nil, err
+		}
+	}
+
+	if err := s.store.setExternalID(ctx, orgID, scim.ResourceTypeUser, userID, resource.ExternalID, false, s.now()); err != nil {
+		return nil, err
+	}
+	return s.GetUser(ctx, orgID, id)
+}
+
+// updateUser changes the attributes of a user owned by the organization.
+func (s *Service) updateUser(ctx context.Context, orgID int64, orgUser *org.OrgUserDTO, attrs *userAttributes, active *bool) error {
+	if err := s.checkUnique(ctx, orgUser.UserID, attrs); err != nil {
+		return err
+	}
+
+	cmd := &user.UpdateUserCommand{UserID: orgUser.UserID, Login: attrs.login, Email: attrs.email, Name: attrs.name}
+	if active != nil {
+		disabled := !*active
+		cmd.IsDisabled = &disabled
+	}
+	if attrs.password != "" {
+		password := user.Password(attrs.password)
+		cmd.Password = &password
+	}
+	if err := s.userService.Update(ctx, cmd); err != nil {
+		return err
+	}
+	if cmd.IsDisabled != nil && *cmd.IsDisabled && !orgUser.IsDisabled {
+		if err := s.authTokenService.RevokeAllUserTokens(ctx, orgUser.UserID); err != nil {
+			return err
+		}
+		s.log.FromContext(ctx).Info("Deactivated user", "orgId", orgID, "userId", orgUser.UserID)
+	}
+	return nil
+}
+
+func (s *Service) PatchUser(ctx context.Context, orgID int64, id string, operations []scim.PatchOperation) (*scim.User, error) {
+	current, err := s.GetUser(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	m, err := toMap(current)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPatch(m, operations); err != nil {
+		return nil, err
+	}
+	patched := &scim.User{}
+	if err := fromMap(m, patched); err != nil {
+		return nil, err
+	}
+	return s.ReplaceUser(ctx, orgID, id, patched)
+}
+
+// DeleteUser removes a user from the organization. Users without any other
+// organization are deleted.
+func (s *Service) DeleteUser(ctx context.Context, orgID int64, id string) error {
+	userID, err := parseID(scim.ResourceTypeUser, id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.getOrgUser(ctx, orgID, userID); err != nil {
+		return err
+	}
+	if err := s.checkProvisionable(ctx, userID); err != nil {
+		return err
+	}
+
+	deleted, err := s.removeOrgUser(ctx, orgID, userID, true)
+	if err != nil {
+		return err
+	}
+	s.log.FromContext(ctx).Info("Deprovisioned user", "orgId", orgID, "userId", userID, "deleted", deleted)
+	return nil
+}
+
+// removeOrgUser removes a user from the organization with its permissions and
+// external ID. With deleteOrphaned, users without any other organization are
+// deleted, it returns whether the user was deleted.
+func (s *Service) removeOrgUser(ctx context.Context, orgID, userID int64, deleteOrphaned bool) (bool, error) {
+	cmd := &org.RemoveOrgUserCommand{OrgID: orgID, UserID: userID, ShouldDeleteOrphanedUser: deleteOrphaned}
+	if err := s.orgService.RemoveOrgUser(ctx, cmd); err != nil {
+		if errors.Is(err, org.ErrLastOrgAdmin) {
+			return false, scim.ErrInvalidValue.Build(scim.ErrorData("cannot remove the last organization admin"))
+		}
+		return false, err
+	}
+
+	permissionsOrgID := orgID
+	if cmd.UserWasDeleted {
+		permissionsOrgID = accesscontrol.GlobalOrgID
+		if err := s.authTokenService.RevokeAllUserTokens(ctx, userID); err != nil {
+			return false, err
+		}
+	}
+	if err := s.accesscontrolService.DeleteUserPermissions(ctx, permissionsOrgID, userID); err != nil {
+		s.log.FromContext(ctx).Warn("Failed to delete permissions for user", "userId", userID, "orgId", permissionsOrgID, "error", err)
+	}
+	if err := s.store.deleteExternalID(ctx, orgID, scim.ResourceTypeUser, userID); err != nil {
+		return false, err
+	}
+	return cmd.UserWasDeleted, nil
+}
+
+// checkProvisionable rejects changes to server administrators, the service
+// account of an organization must not be able to take over or lock them out.
+func (s *Service) checkProvisionable(ctx context.Context, userID int64) error {
+	usr, err := s.userService.GetByID(ctx, &user.GetUserByIDQuery{ID: userID})
+	if err != nil {
+		return err
+	}
+	if usr.IsAdmin {
+		return scim.ErrMutability.Build(scim.ErrorData("server administrators can't be provisioned"))
+	}
+	return nil
+}
+
+// ownsUser reports whether the organization may change the attributes that
+// users share between organizations. It owns the users it provisioned and the
+// users that aren't a member of any other organization.
+func (s *Service) ownsUser(ctx context.Context, orgID, userID int64) (bool, error) {
+	externalIDs, err := s.store.getExternalIDs(ctx, orgID, scim.ResourceTypeUser, []int64{userID})
+	if err != nil {
+		return false, err
+	}
+	if e := externalIDs[userID]; e != nil && e.Provisioned {
+		return true, nil
+	}
+	orgs, err := s.orgService.GetUserOrgList(ctx, &org.GetUserOrgListQuery{UserID: userID})
+	if err != nil {
+		return false, err
+	}
+	return len(orgs) == 1 && orgs[0].OrgID == orgID, nil
+}
+
+func (s *Service) checkUnique(ctx context.Context, userID int64, attrs *userAttributes) error {
+	for _, loginOrEmail := range []string{attrs.login, attrs.email} {
+		if loginOrEmail == "" {
+			continue
+		}
+		other, err := s.userService.GetByLogin(ctx, &user.GetUserByLoginQuery{LoginOrEmail: loginOrEmail})
+		if err != nil {
+			if errors.Is(err, user.ErrUserNotFound) {
+				continue
+			}
+			return err
+		}
+		if other.ID != userID {
+			return scim.ErrUniqueness.Build(scim.ErrorData("User %s already exists", loginOrEmail))
+		}
+	}
+	return nil
+}
@@ -152,6 +152,7 @@ func (s *ServiceAccountsStoreImpl) UpdateServiceAccount(
 func ServiceAccountDeletions(dialect migrator.Dialect) []string {
 	deletes := []string{
 		"DELETE FROM api_key WHERE service_account_id = ?",
+		"DELETE FROM service_account_federated_credential WHERE service_account_id = ?",
 	}
 	deletes = append(deletes, serviceAccountDeletions(dialect)...)
 	return deletes
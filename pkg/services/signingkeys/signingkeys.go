@@ -25,11 +25,16 @@ const (
 type Service interface {
 	// GetJWKS returns the JSON Web Key Set (JWKS) with all the keys that can be used to verify tokens (public keys)
 	GetJWKS(ctx context.Context) (jose.JSONWebKeySet, error)
+	// GetOrCreatePrivateKey returns the active private key of a key prefix and its key ID. A new key
+	// becomes active when the active key is due for rotation.
 	GetOrCreatePrivateKey(ctx context.Context, keyPrefix string, alg jose.SignatureAlgorithm) (string, crypto.Signer, error)
 }
 
 type SigningKey struct {
-	KeyID      string                  `xorm:"key_id"`
+	KeyID string `xorm:"key_id"`
+	// KeyPrefix identifies the keys rotated together, it is empty for keys
+	// created before keys were rotated.
+	KeyPrefix  string                  `xorm:"key_prefix"`
 	PrivateKey []byte                  `xorm:"private_key"`
 	AddedAt    time.Time               `xorm:"added_at"`
 	ExpiresAt  *time.Time              `xorm:"expires_at"`
@@ -0,0 +1,73 @@
+package signingkeysimpl
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "grafana"
+	metricsSubSystem = "signingkeys"
+)
+
+type metrics struct {
+	rotations            *prometheus.CounterVec
+	failedRotations      *prometheus.CounterVec
+	lastRotationTime     *prometheus.GaugeVec
+	removedKeys          prometheus.Counter
+	publishedKeys        prometheus.Gauge
+	backgroundRunsFailed prometheus.Counter
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	m := &metrics{
+		rotations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "signingkeys_rotations_total",
+			Help:      "Number of signing key rotations",
+		}, []string{"key_prefix", "trigger"}),
+		failedRotations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "signingkeys_failed_rotations_total",
+			Help:      "Number of failed signing key rotations",
+		}, []string{"key_prefix"}),
+		lastRotationTime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "signingkeys_last_rotation_timestamp_seconds",
+			Help:      "Time of the last signing key rotation",
+		}, []string{"key_prefix"}),
+		removedKeys: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "signingkeys_removed_keys_total",
+			Help:      "Number of expired signing keys removed",
+		}),
+		publishedKeys: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "signingkeys_published_keys",
+			Help:      "Number of public keys in the JWKS",
+		}),
+		backgroundRunsFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "signingkeys_failed_background_runs_total",
+			Help:      "Number of failed runs of the scheduled rotation",
+		}),
+	}
+
+	if reg != nil {
+		reg.MustRegister(
+			m.rotations,
+			m.failedRotations,
+			m.lastRotationTime,
+			m.removedKeys,
+			m.publishedKeys,
+			m.backgroundRunsFailed,
+		)
+	}
+
+	return m
+}
@@ -0,0 +1,160 @@
+package signingkeysimpl
+
+import (
+	"context"
+	"crypto"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+
+	"github.com/grafana/grafana/pkg/services/signingkeys"
+)
+
+const (
+	// rotationCheckInterval is how often the background job rotates the keys
+	// due for rotation and removes the expired keys.
+	rotationCheckInterval = 10 * time.Minute
+
+	triggerOnDemand  = "on_demand"
+	triggerScheduled = "scheduled"
+)
+
+// activeKey is the key used for signing by a key prefix.
+type activeKey struct {
+	keyID   string
+	signer  crypto.Signer
+	addedAt time.Time
+}
+
+func activeKeyCacheKey(keyPrefix string, alg jose.SignatureAlgorithm) string {
+	return "active-" + keyPrefix + "-" + string(alg)
+}
+
+// rotatedKeyID returns the ID of a key of a prefix added at a given time.
+func rotatedKeyID(keyPrefix string, alg jose.SignatureAlgorithm, addedAt time.Time) string {
+	return keyPrefix + "-" + addedAt.UTC().Format("20060102150405") + "-" + strings.ToLower(string(alg))
+}
+
+func (s *Service) rotationDue(addedAt time.Time) bool {
+	return !time.Now().Before(addedAt.Add(s.cfg.RotationInterval))
+}
+
+// expiresAt returns when a key is removed from the JWKS. A key is used for signing
+// during the rotation interval, then its public key is published for the grace period
+// so tokens signed before the rotation can still be verified.
+func (s *Service) expiresAt(addedAt time.Time) time.Time {
+	return addedAt.Add(s.cfg.RotationInterval + s.cfg.GracePeriod)
+}
+
+// Run rotates the keys due for rotation, even when no token is signed, and removes
+// the expired keys.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(rotationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.rotateScheduled(ctx)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Service) rotateScheduled(ctx context.Context) {
+	// the lock makes sure a single instance rotates the keys
+	err := s.serverLock.LockAndExecute(ctx, "rotate signing keys", rotationCheckInterval/2, func(ctx context.Context) {
+		if err := s.rotateDueKeys(ctx); err != nil {
+			s.metrics.backgroundRunsFailed.Inc()
+			s.log.Error("Failed to rotate signing keys", "err", err)
+		}
+		if err := s.removeExpiredKeys(ctx); err != nil {
+			s.metrics.backgroundRunsFailed.Inc()
+			s.log.Error("Failed to remove expired signing keys", "err", err)
+		}
+	})
+
+	if err != nil {
+		s.log.Error("Failed to lock and execute signing key rotation", "err", err)
+	}
+}
+
+// rotateDueKeys rotates the prefixes whose newest key is due for rotation.
+func (s *Service) rotateDueKeys(ctx context.Context) error {
+	keys, err := s.store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	newest := map[string]signingkeys.SigningKey{}
+	for _, key := range keys {
+		// keys without prefix were created before keys were rotated, they expire on their own
+		if key.KeyPrefix == "" {
+			continue
+		}
+		id := key.KeyPrefix + "/" + string(key.Alg)
+		if current, ok := newest[id]; !ok || key.AddedAt.After(current.AddedAt) {
+			newest[id] = key
+		}
+	}
+
+	for _, key := range newest {
+		if !s.rotationDue(key.AddedAt) {
+			continue
+		}
+		previous := key
+		if _, _, err := s.rotate(ctx, key.KeyPrefix, key.Alg, &previous, triggerScheduled); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rotate adds a new active key to a key prefix. The previous key stays in the
+// JWKS until it expires.
+func (s *Service) rotate(ctx context.Context, keyPrefix string, alg jose.SignatureAlgorithm,
+	previous *signingkeys.SigningKey, trigger string) (string, crypto.Signer, error) {
+	addedAt := time.Now()
+	keyID := rotatedKeyID(keyPrefix, alg, addedAt)
+
+	signer, err := s.addPrivateKey(ctx, keyID, keyPrefix, alg, addedAt, false)
+	if err != nil {
+		s.metrics.failedRotations.WithLabelValues(keyPrefix).Inc()
+		s.log.Error("Failed to rotate signing key", "keyPrefix", keyPrefix, "err", err)
+		return "", nil, err
+	}
+
+	s.metrics.rotations.WithLabelValues(keyPrefix, trigger).Inc()
+	s.metrics.lastRotationTime.WithLabelValues(keyPrefix).Set(float64(addedAt.Unix()))
+
+	auditArgs := []any{"keyPrefix", keyPrefix, "keyId", keyID, "alg", alg, "trigger", trigger,
+		"expiresAt", s.expiresAt(addedAt), "kmsProvider", s.kmsProviderID}
+	if previous != nil {
+		auditArgs = append(auditArgs, "previousKeyId", previous.KeyID, "previousKeyExpiresAt", previous.ExpiresAt)
+	}
+	s.auditLog.Info("Signing key rotated", auditArgs...)
+
+	s.localCache.Set(activeKeyCacheKey(keyPrefix, alg), &activeKey{keyID: keyID, signer: signer, addedAt: addedAt}, privateKeyTTL)
+	return keyID, signer, nil
+}
+
+func (s *Service) removeExpiredKeys(ctx context.Context) error {
+	keyIDs, err := s.store.DeleteExpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, keyID := range keyIDs {
+		s.localCache.Delete(keyID)
+		s.auditLog.Info("Expired signing key removed", "keyId", keyID)
+	}
+	s.metrics.removedKeys.Add(float64(len(keyIDs)))
+
+	if len(keyIDs) > 0 {
+		s.invalidateJWKSCache(ctx)
+	}
+	return nil
+}
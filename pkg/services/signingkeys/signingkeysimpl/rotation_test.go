@@ -0,0 +1,173 @@
+package signingkeysimpl
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	secretstest "github.com/grafana/grafana/pkg/services/secrets/fakes"
+	"github.com/grafana/grafana/pkg/services/signingkeys"
+	"github.com/grafana/grafana/pkg/services/signingkeys/signingkeystore"
+)
+
+func setupRotationTest(t *testing.T) (*Service, *signingkeystore.FakeStore) {
+	t.Helper()
+	store := signingkeystore.NewFakeStore()
+	return &Service{
+		cfg:            testSettings,
+		log:            log.NewNopLogger(),
+		auditLog:       log.NewNopLogger(),
+		metrics:        newMetrics(nil),
+		store:          store,
+		secretsService: secretstest.NewFakeSecretsService(),
+		remoteCache:    remotecache.NewFakeCacheStorage(),
+		localCache:     localcache.New(privateKeyTTL, 10*time.Hour),
+	}, store
+}
+
+// addKey adds a key of a prefix added at a given time.
+func addKey(t *testing.T, svc *Service, store *signingkeystore.FakeStore, keyID, keyPrefix string, addedAt time.Time) {
+	t.Helper()
+	expiresAt := svc.expiresAt(addedAt)
+	_, err := store.Add(context.Background(), &signingkeys.SigningKey{
+		KeyID:      keyID,
+		KeyPrefix:  keyPrefix,
+		PrivateKey: getPrivateKey(t, svc),
+		AddedAt:    addedAt,
+		ExpiresAt:  &expiresAt,
+		Alg:        jose.ES256,
+	}, false)
+	require.NoError(t, err)
+}
+
+func jwksKeyIDs(t *testing.T, svc *Service) []string {
+	t.Helper()
+	jwks, err := svc.GetJWKS(context.Background())
+	require.NoError(t, err)
+	keyIDs := make([]string, 0, len(jwks.Keys))
+	for _, key := range jwks.Keys {
+		keyIDs = append(keyIDs, key.KeyID)
+	}
+	return keyIDs
+}
+
+func TestEmbeddedKeyService_RotateOnDemand(t *testing.T) {
+	svc, store := setupRotationTest(t)
+	now := time.Now()
+
+	t.Run("should use the active key", func(t *testing.T) {
+		addKey(t, svc, store, "test-active", "test", now.Add(-time.Hour))
+
+		keyID, _, err := svc.GetOrCreatePrivateKey(context.Background(), "test", jose.ES256)
+		require.NoError(t, err)
+		assert.Equal(t, "test-active", keyID)
+	})
+
+	t.Run("should rotate the active key when due", func(t *testing.T) {
+		svc, store := setupRotationTest(t)
+		addKey(t, svc, store, "test-due", "test", now.Add(-testSettings.RotationInterval-time.Minute))
+
+		keyID, _, err := svc.GetOrCreatePrivateKey(context.Background(), "test", jose.ES256)
+		require.NoError(t, err)
+		assert.NotEqual(t, "test-due", keyID)
+		assert.True(t, strings.HasPrefix(keyID, "test-"))
+
+		// the previous key stays in the JWKS during the grace period
+		assert.ElementsMatch(t, []string{"test-due", keyID}, jwksKeyIDs(t, svc))
+
+		// the new key is used until it is due
+		keyID2, _, err := svc.GetOrCreatePrivateKey(context.Background(), "test", jose.ES256)
+		require.NoError(t, err)
+		assert.Equal(t, keyID, keyID2)
+	})
+
+	t.Run("should not use keys of other prefixes", func(t *testing.T) {
+		keyID, _, err := svc.GetOrCreatePrivateKey(context.Background(), "other", jose.ES256)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(keyID, "other-"))
+	})
+}
+
+func TestEmbeddedKeyService_RotateScheduled(t *testing.T) {
+	svc, store := setupRotationTest(t)
+	now := time.Now()
+
+	addKey(t, svc, store, "due-old", "due", now.Add(-2*testSettings.RotationInterval))
+	addKey(t, svc, store, "due-newest", "due", now.Add(-testSettings.RotationInterval-time.Minute))
+	addKey(t, svc, store, "active", "active", now.Add(-time.Hour))
+	// keys created before rotation have no prefix and are left to expire
+	addKey(t, svc, store, "legacy-2024-01-es256", "", now.Add(-testSettings.RotationInterval-time.Minute))
+
+	require.NoError(t, svc.rotateDueKeys(context.Background()))
+
+	require.Len(t, store.Keys, 5)
+	active, err := store.GetActive(context.Background(), "due", jose.ES256)
+	require.NoError(t, err)
+	assert.NotEqual(t, "due-newest", active.KeyID)
+	active, err = store.GetActive(context.Background(), "active", jose.ES256)
+	require.NoError(t, err)
+	assert.Equal(t, "active", active.KeyID)
+
+	t.Run("should remove expired keys", func(t *testing.T) {
+		require.NoError(t, svc.remoteCache.Set(context.Background(), jwksCacheKey, []byte("{}"), 0))
+
+		require.NoError(t, svc.removeExpiredKeys(context.Background()))
+
+		assert.NotContains(t, store.Keys, "due-old")
+		assert.Contains(t, store.Keys, "due-newest")
+		assert.Len(t, store.Keys, 4)
+		// the JWKS cache is invalidated
+		_, err := svc.remoteCache.Get(context.Background(), jwksCacheKey)
+		require.Error(t, err)
+	})
+}
+
+type fakeKMSProvider struct{}
+
+func (fakeKMSProvider) Encrypt(_ context.Context, blob []byte) ([]byte, error) {
+	return append([]byte("fake-kms:"), blob...), nil
+}
+
+func (fakeKMSProvider) Decrypt(_ context.Context, blob []byte) ([]byte, error) {
+	return bytes.TrimPrefix(blob, []byte("fake-kms:")), nil
+}
+
+func TestEmbeddedKeyService_KMSProvider(t *testing.T) {
+	svc, store := setupRotationTest(t)
+
+	// keys encrypted by the secrets service can still be decrypted
+	addKey(t, svc, store, "secrets-key", "secrets", time.Now())
+
+	svc.kmsProviders = map[secrets.ProviderID]secrets.Provider{"fake.v1": fakeKMSProvider{}}
+	svc.kmsProviderID = "fake.v1"
+
+	keyID, signer, err := svc.GetOrCreatePrivateKey(context.Background(), "test", jose.ES256)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(store.Keys[keyID].PrivateKey), "kms:fake.v1:"))
+
+	// the key is decrypted with the KMS provider
+	svc.localCache.Flush()
+	decoded, err := svc.getPrivateKey(context.Background(), keyID)
+	require.NoError(t, err)
+	assert.Equal(t, signer.Public(), decoded.Public())
+
+	_, err = svc.getPrivateKey(context.Background(), "secrets-key")
+	require.NoError(t, err)
+
+	t.Run("should fail when the provider of a key is not configured", func(t *testing.T) {
+		svc.localCache.Flush()
+		svc.kmsProviders = map[secrets.ProviderID]secrets.Provider{}
+		_, err := svc.getPrivateKey(context.Background(), keyID)
+		require.Error(t, err)
+	})
+}
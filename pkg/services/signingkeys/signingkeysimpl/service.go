@@ -1,6 +1,7 @@
 package signingkeysimpl
 
 import (
+	"bytes"
 	"context"
 	"crypto"
 	"crypto/ecdsa"
@@ -11,11 +12,13 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/go-jose/go-jose/v3"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/api/routing"
@@ -23,23 +26,43 @@ import (
 	"github.com/grafana/grafana/pkg/infra/localcache"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/kmsproviders"
 	"github.com/grafana/grafana/pkg/services/secrets"
 	"github.com/grafana/grafana/pkg/services/signingkeys"
 	"github.com/grafana/grafana/pkg/services/signingkeys/signingkeystore"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 var _ signingkeys.Service = new(Service)
 
-func ProvideEmbeddedSigningKeysService(dbStore db.DB, secretsService secrets.Service,
-	remoteCache remotecache.CacheStorage, routerRegister routing.RouteRegister,
+func ProvideEmbeddedSigningKeysService(cfg *setting.Cfg, dbStore db.DB, secretsService secrets.Service,
+	kmsProvidersService kmsproviders.Service, remoteCache remotecache.CacheStorage, routerRegister routing.RouteRegister,
+	serverLock *serverlock.ServerLockService, reg prometheus.Registerer,
 ) (*Service, error) {
 	s := &Service{
+		cfg:            cfg.SigningKeys,
 		log:            log.New("auth.key_service"),
+		auditLog:       log.New("auth.key_service.audit"),
 		store:          signingkeystore.NewSigningKeyStore(dbStore),
 		secretsService: secretsService,
 		remoteCache:    remoteCache,
 		localCache:     localcache.New(1*time.Hour, 1*time.Hour),
+		serverLock:     serverLock,
+		metrics:        newMetrics(reg),
+	}
+
+	if cfg.SigningKeys.KMSProvider != "" {
+		providers, err := kmsProvidersService.Provide()
+		if err != nil {
+			return nil, err
+		}
+		s.kmsProviderID = kmsproviders.NormalizeProviderID(secrets.ProviderID(cfg.SigningKeys.KMSProvider))
+		if _, ok := providers[s.kmsProviderID]; !ok {
+			return nil, fmt.Errorf("signing keys KMS provider %q is not configured", cfg.SigningKeys.KMSProvider)
+		}
+		s.kmsProviders = providers
 	}
 
 	s.registerAPIEndpoints(routerRegister)
@@ -50,13 +73,23 @@ func ProvideEmbeddedSigningKeysService(dbStore db.DB, secretsService secrets.Ser
 // Service provides functionality for managing signing keys used to sign and verify JWT tokens for
 // the OSS version of Grafana.
 //
-// The service is under active development and is not yet ready for production use.
+// Each key prefix has an active key used for signing. A new key becomes active once the active key
+// is older than the rotation interval, the public keys of previous keys stay in the JWKS during the
+// grace period.
 type Service struct {
+	cfg            setting.SigningKeysSettings
 	log            log.Logger
+	auditLog       log.Logger
 	store          signingkeystore.SigningStore
 	secretsService secrets.Service
 	remoteCache    remotecache.CacheStorage
 	localCache     *localcache.CacheService
+	serverLock     *serverlock.ServerLockService
+	metrics        *metrics
+	// kmsProviders are set when the private keys are encrypted with the KMS provider kmsProviderID
+	// instead of the secrets service.
+	kmsProviders  map[secrets.ProviderID]secrets.Provider
+	kmsProviderID secrets.ProviderID
 }
 
 const (
@@ -85,6 +118,8 @@ func (s *Service) GetJWKS(ctx context.Context) (jose.JSONWebKeySet, error) {
 		return jwks, err
 	}
 
+	s.metrics.publishedKeys.Set(float64(len(jwks.Keys)))
+
 	// cache jwks
 	jwksBytes, err := json.Marshal(jwks)
 	if err == nil {
@@ -114,9 +149,8 @@ func (s *Service) buildJWKS(ctx context.Context, keys []signingkeys.SigningKey)
 	return jwks, nil
 }
 
-// GetOrCreatePrivateKey returns the private key with the specified key ID. If the key does not exist, it will be
-// created with the specified algorithm.
-// The key will be automatically rotated at the beginning of each month. The previous key will be kept for 30 days.
+// GetOrCreatePrivateKey returns the active private key of the specified key prefix and its key ID. If there
+// is no active key or if the active key is due for rotation, a new key is created with the specified algorithm.
 func (s *Service) GetOrCreatePrivateKey(ctx context.Context,
 	keyPrefix string, alg jose.SignatureAlgorithm) (string, crypto.Signer, error) {
 	if alg != jose.ES256 {
@@ -124,25 +158,35 @@ func (s *Service) GetOrCreatePrivateKey(ctx context.Context,
 		return "", nil, signingkeys.ErrKeyGenerationFailed.Errorf("Only ES256 is supported: %v", alg)
 	}
 
-	keyID := keyMonthScopedID(keyPrefix, alg)
-	signer, err := s.getPrivateKey(ctx, keyID)
-	if err == nil {
-		return keyID, signer, nil
+	if cached, ok := s.localCache.Get(activeKeyCacheKey(keyPrefix, alg)); ok {
+		active := cached.(*activeKey)
+		if !s.rotationDue(active.addedAt) {
+			return active.keyID, active.signer, nil
+		}
 	}
 
-	// we only want to create a new signing key if none exits for keyID
-	if !errors.Is(err, signingkeys.ErrSigningKeyNotFound) {
+	key, err := s.store.GetActive(ctx, keyPrefix, alg)
+	if err != nil && !errors.Is(err, signingkeys.ErrSigningKeyNotFound) {
 		return "", nil, err
 	}
 
-	s.log.Debug("Private key not found, generating new key", "keyID", keyID, "err", err)
+	if err == nil && !s.rotationDue(key.AddedAt) {
+		signer, err := s.getPrivateKey(ctx, key.KeyID)
+		if err != nil {
+			return "", nil, err
+		}
+		s.localCache.Set(activeKeyCacheKey(keyPrefix, alg), &activeKey{keyID: key.KeyID, signer: signer, addedAt: key.AddedAt}, privateKeyTTL)
+		return key.KeyID, signer, nil
+	}
 
-	signer, err = s.addPrivateKey(ctx, keyID, alg, false)
-	if err != nil {
-		return "", nil, err
+	// we only want to create a new signing key if there is no active key or if it is due for rotation
+	var previous *signingkeys.SigningKey
+	if err == nil {
+		previous = key
 	}
+	s.log.Debug("Active private key not found or due for rotation, generating new key", "keyPrefix", keyPrefix)
 
-	return keyID, signer, nil
+	return s.rotate(ctx, keyPrefix, alg, previous, triggerOnDemand)
 }
 
 func (s *Service) getPrivateKey(ctx context.Context, keyID string) (crypto.Signer, error) {
@@ -164,7 +208,7 @@ func (s *Service) getPrivateKey(ctx context.Context, keyID string) (crypto.Signe
 	return singer, nil
 }
 
-func (s *Service) addPrivateKey(ctx context.Context, keyID string, alg jose.SignatureAlgorithm, force bool) (crypto.Signer, error) {
+func (s *Service) addPrivateKey(ctx context.Context, keyID, keyPrefix string, alg jose.SignatureAlgorithm, addedAt time.Time, force bool) (crypto.Signer, error) {
 	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
 		s.log.Error("Error generating private key", "err", err)
@@ -177,14 +221,14 @@ func (s *Service) addPrivateKey(ctx context.Context, keyID string, alg jose.Sign
 		return nil, err
 	}
 
-	now := time.Now()
-	expiry := now.Add(30 * 24 * time.Hour)
+	expiry := s.expiresAt(addedAt)
 	key, err := s.store.Add(ctx, &signingkeys.SigningKey{
 		KeyID:      keyID,
+		KeyPrefix:  keyPrefix,
 		PrivateKey: encoded,
 		ExpiresAt:  &expiry,
 		Alg:        alg,
-		AddedAt:    now,
+		AddedAt:    addedAt,
 	}, force)
 
 	if err != nil && !errors.Is(err, signingkeys.ErrSigningKeyAlreadyExists) {
@@ -199,13 +243,16 @@ func (s *Service) addPrivateKey(ctx context.Context, keyID string, alg jose.Sign
 	// invalidate local cache
 	s.localCache.Delete(keyID)
 
-	// invalidate cache
+	s.invalidateJWKSCache(ctx)
+
+	return signer, nil
+}
+
+func (s *Service) invalidateJWKSCache(ctx context.Context) {
 	if err := s.remoteCache.Delete(ctx, jwksCacheKey); err != nil {
 		// not a critical error, key might not be in cache
 		s.log.Debug("Failed to invalidate JWKS cache", "err", err)
 	}
-
-	return signer, nil
 }
 
 func (s *Service) encodePrivateKey(ctx context.Context, privateKey crypto.Signer) ([]byte, error) {
@@ -221,6 +268,10 @@ func (s *Service) encodePrivateKey(ctx context.Context, privateKey crypto.Signer
 		Bytes: pKeyBytes,
 	})
 
+	if s.kmsProviderID != "" {
+		return s.encryptWithKMSProvider(ctx, privateKeyPEM)
+	}
+
 	encrypted, err := s.secretsService.Encrypt(ctx, privateKeyPEM, secrets.WithoutScope())
 	if err != nil {
 		return nil, err
@@ -231,19 +282,45 @@ func (s *Service) encodePrivateKey(ctx context.Context, privateKey crypto.Signer
 	return encoded, nil
 }
 
-func (s *Service) decodePrivateKey(ctx context.Context, privateKey []byte) (crypto.Signer, error) {
-	// Bail out if empty string since it'll cause a segfault in Decrypt
-	if len(privateKey) == 0 {
-		return nil, errors.New("private key is empty")
+// kmsPrefix marks the private keys encrypted with a KMS provider, they are stored
+// as kms:<provider ID>:<base64 encoded ciphertext>.
+const kmsPrefix = "kms:"
+
+func (s *Service) encryptWithKMSProvider(ctx context.Context, privateKeyPEM []byte) ([]byte, error) {
+	encrypted, err := s.kmsProviders[s.kmsProviderID].Encrypt(ctx, privateKeyPEM)
+	if err != nil {
+		return nil, err
 	}
 
-	payload := make([]byte, base64.StdEncoding.DecodedLen(len(privateKey)))
-	_, err := base64.StdEncoding.Decode(payload, privateKey)
+	return []byte(kmsPrefix + string(s.kmsProviderID) + ":" + base64.StdEncoding.EncodeToString(encrypted)), nil
+}
+
+func (s *Service) decryptWithKMSProvider(ctx context.Context, privateKey []byte) ([]byte, error) {
+	providerID, payload, ok := strings.Cut(strings.TrimPrefix(string(privateKey), kmsPrefix), ":")
+	if !ok {
+		return nil, errors.New("invalid KMS encrypted private key")
+	}
+
+	provider, ok := s.kmsProviders[secrets.ProviderID(providerID)]
+	if !ok {
+		return nil, fmt.Errorf("KMS provider %q of the private key is not configured", providerID)
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(payload)
 	if err != nil {
 		return nil, err
 	}
 
-	decrypted, err := s.secretsService.Decrypt(ctx, payload)
+	return provider.Decrypt(ctx, encrypted)
+}
+
+func (s *Service) decodePrivateKey(ctx context.Context, privateKey []byte) (crypto.Signer, error) {
+	// Bail out if empty string since it'll cause a segfault in Decrypt
+	if len(privateKey) == 0 {
+		return nil, errors.New("private key is empty")
+	}
+
+	decrypted, err := s.decryptPrivateKey(ctx, privateKey)
 	if err != nil {
 		return nil, err
 	}
@@ -269,9 +346,18 @@ func (s *Service) decodePrivateKey(ctx context.Context, privateKey []byte) (cryp
 	return assertedKey, nil
 }
 
-func keyMonthScopedID(keyPrefix string, alg jose.SignatureAlgorithm) string {
-	keyID := keyPrefix + "-" + time.Now().UTC().Format("2006-01") + "-" + strings.ToLower(string(alg))
-	return keyID
+func (s *Service) decryptPrivateKey(ctx context.Context, privateKey []byte) ([]byte, error) {
+	if bytes.HasPrefix(privateKey, []byte(kmsPrefix)) {
+		return s.decryptWithKMSProvider(ctx, privateKey)
+	}
+
+	payload := make([]byte, base64.StdEncoding.DecodedLen(len(privateKey)))
+	_, err := base64.StdEncoding.Decode(payload, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.secretsService.Decrypt(ctx, payload)
 }
 
 func (s *Service) registerAPIEndpoints(router routing.RouteRegister) {
@@ -6,7 +6,6 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
-	"fmt"
 	"io"
 	"net/http"
 	"testing"
@@ -24,6 +23,7 @@ import (
 	secretstest "github.com/grafana/grafana/pkg/services/secrets/fakes"
 	"github.com/grafana/grafana/pkg/services/signingkeys/signingkeystore"
 	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/web/webtest"
 )
 
@@ -34,6 +34,11 @@ ielIkb6/Ys51o7KjHxtANhPesw==
 -----END PRIVATE KEY-----`
 )
 
+var testSettings = setting.SigningKeysSettings{
+	RotationInterval: 24 * time.Hour,
+	GracePeriod:      time.Hour,
+}
+
 func getPrivateKey(t *testing.T, svc *Service) []byte {
 	pemBlock, _ := pem.Decode([]byte(privateKeyPem))
 	privateKey, err := x509.ParsePKCS8PrivateKey(pemBlock.Bytes)
@@ -46,7 +51,10 @@ func getPrivateKey(t *testing.T, svc *Service) []byte {
 
 func TestEmbeddedKeyService_GetJWKS_OnlyPublicKeyShared(t *testing.T) {
 	svc := &Service{
+		cfg:            testSettings,
 		log:            log.NewNopLogger(),
+		auditLog:       log.NewNopLogger(),
+		metrics:        newMetrics(nil),
 		store:          signingkeystore.NewFakeStore(),
 		secretsService: secretstest.NewFakeSecretsService(),
 		remoteCache:    remotecache.NewFakeCacheStorage(),
@@ -85,16 +93,16 @@ func TestEmbeddedKeyService_GetJWKS_OnlyPublicKeyShared(t *testing.T) {
 func TestEmbeddedKeyService_GetOrCreatePrivateKey(t *testing.T) {
 	cacheStorage := remotecache.NewFakeCacheStorage()
 	svc := &Service{
+		cfg:            testSettings,
 		log:            log.NewNopLogger(),
+		auditLog:       log.NewNopLogger(),
+		metrics:        newMetrics(nil),
 		store:          signingkeystore.NewFakeStore(),
 		secretsService: secretstest.NewFakeSecretsService(),
 		remoteCache:    cacheStorage,
 		localCache:     localcache.New(privateKeyTTL, 10*time.Hour),
 	}
 
-	wantedKeyID := keyMonthScopedID("test", jose.ES256)
-	assert.Equal(t, wantedKeyID, fmt.Sprintf("test-%s-es256", time.Now().UTC().Format("2006-01")))
-
 	err := cacheStorage.Set(context.Background(), jwksCacheKey, []byte("invalid"), 0)
 	require.NoError(t, err)
 
@@ -107,9 +115,10 @@ func TestEmbeddedKeyService_GetOrCreatePrivateKey(t *testing.T) {
 	require.Len(t, cacheStorage.Storage, 1)
 
 	// first call should generate a key
-	_, key, err := svc.GetOrCreatePrivateKey(context.Background(), "test", jose.ES256)
+	wantedKeyID, key, err := svc.GetOrCreatePrivateKey(context.Background(), "test", jose.ES256)
 	require.NoError(t, err)
 	require.NotNil(t, key)
+	assert.Regexp(t, `^test-\d{14}-es256$`, wantedKeyID)
 
 	// new key is generated, so jwks cache should be voided
 	require.Len(t, cacheStorage.Storage, 0)
@@ -133,7 +142,10 @@ func TestExposeJWKS(t *testing.T) {
 	mockStore := signingkeystore.NewFakeStore()
 	cacheStorage := remotecache.NewFakeCacheStorage()
 	svc := &Service{
+		cfg:            testSettings,
 		log:            log.NewNopLogger(),
+		auditLog:       log.NewNopLogger(),
+		metrics:        newMetrics(nil),
 		store:          mockStore,
 		remoteCache:    cacheStorage,
 		secretsService: secretstest.NewFakeSecretsService(),
@@ -3,6 +3,7 @@ package signingkeystore
 import (
 	"context"
 	"crypto"
+	"time"
 
 	"github.com/go-jose/go-jose/v3"
 	"github.com/grafana/grafana/pkg/services/signingkeys"
@@ -50,3 +51,33 @@ func (s *FakeStore) Get(ctx context.Context, keyID string) (*signingkeys.Signing
 
 	return nil, signingkeys.ErrSigningKeyNotFound
 }
+
+func (s *FakeStore) GetActive(ctx context.Context, keyPrefix string, alg jose.SignatureAlgorithm) (*signingkeys.SigningKey, error) {
+	var active *signingkeys.SigningKey
+	for _, key := range s.Keys {
+		if key.KeyPrefix != keyPrefix || key.Alg != alg || (key.ExpiresAt != nil && key.ExpiresAt.Before(time.Now())) {
+			continue
+		}
+		if active == nil || key.AddedAt.After(active.AddedAt) {
+			k := key
+			active = &k
+		}
+	}
+
+	if active == nil {
+		return nil, signingkeys.ErrSigningKeyNotFound
+	}
+
+	return active, nil
+}
+
+func (s *FakeStore) DeleteExpired(ctx context.Context) ([]string, error) {
+	var keyIDs []string
+	for keyID, key := range s.Keys {
+		if key.ExpiresAt != nil && key.ExpiresAt.Before(time.Now()) {
+			keyIDs = append(keyIDs, keyID)
+			delete(s.Keys, keyID)
+		}
+	}
+	return keyIDs, nil
+}
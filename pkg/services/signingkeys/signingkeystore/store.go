@@ -24,6 +24,10 @@ type SigningStore interface {
 	Add(ctx context.Context, key *signingkeys.SigningKey, force bool) (*signingkeys.SigningKey, error)
 	// Get returns the signing key with the specified key ID
 	Get(ctx context.Context, keyID string) (*signingkeys.SigningKey, error)
+	// GetActive returns the newest non expired key of a key prefix
+	GetActive(ctx context.Context, keyPrefix string, alg jose.SignatureAlgorithm) (*signingkeys.SigningKey, error)
+	// DeleteExpired removes the expired keys and returns their key IDs
+	DeleteExpired(ctx context.Context) ([]string, error)
 }
 
 var _ SigningStore = (*Store)(nil)
@@ -79,16 +83,16 @@ func (s *Store) Add(ctx context.Context, key *signingkeys.SigningKey, force bool
 		}
 
 		if !exists {
-			_, err = tx.Exec("INSERT INTO signing_key (key_id, private_key, added_at, alg, expires_at) VALUES (?, ?, ?, ?, ?)",
-				key.KeyID, key.PrivateKey, key.AddedAt, key.Alg, key.ExpiresAt,
+			_, err = tx.Exec("INSERT INTO signing_key (key_id, key_prefix, private_key, added_at, alg, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+				key.KeyID, key.KeyPrefix, key.PrivateKey, key.AddedAt, key.Alg, key.ExpiresAt,
 			)
 			result = key
 			return err
 		}
 
 		if force || (existingKey.ExpiresAt != nil && existingKey.ExpiresAt.Before(time.Now())) {
-			_, err = tx.Exec("UPDATE signing_key SET private_key = ?, added_at = ?, alg = ?, expires_at = ? WHERE key_id = ?",
-				key.PrivateKey, key.AddedAt, key.Alg, key.ExpiresAt, key.KeyID)
+			_, err = tx.Exec("UPDATE signing_key SET key_prefix = ?, private_key = ?, added_at = ?, alg = ?, expires_at = ? WHERE key_id = ?",
+				key.KeyPrefix, key.PrivateKey, key.AddedAt, key.Alg, key.ExpiresAt, key.KeyID)
 
 			result = key
 			return err
@@ -143,6 +147,46 @@ func (s *Store) Get(ctx context.Context, keyID string) (*signingkeys.SigningKey,
 	return &key, nil
 }
 
+// GetActive implements SigningStore.
+func (s *Store) GetActive(ctx context.Context, keyPrefix string, alg jose.SignatureAlgorithm) (*signingkeys.SigningKey, error) {
+	key := signingkeys.SigningKey{}
+	err := s.dbStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		exists, err := dbSession.SQL("SELECT * FROM signing_key WHERE key_prefix = ? AND alg = ? AND (expires_at IS NULL OR expires_at > ?) ORDER BY added_at DESC",
+			keyPrefix, alg, time.Now()).Get(&key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return signingkeys.ErrSigningKeyNotFound.Errorf("No active key found for prefix: %s", keyPrefix)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// DeleteExpired implements SigningStore.
+func (s *Store) DeleteExpired(ctx context.Context) ([]string, error) {
+	var keyIDs []string
+	err := s.dbStore.WithTransactionalDbSession(ctx, func(tx *sqlstore.DBSession) error {
+		now := time.Now()
+		if err := tx.SQL("SELECT key_id FROM signing_key WHERE expires_at IS NOT NULL AND expires_at < ?", now).Find(&keyIDs); err != nil {
+			return err
+		}
+		if len(keyIDs) == 0 {
+			return nil
+		}
+		_, err := tx.Exec("DELETE FROM signing_key WHERE expires_at IS NOT NULL AND expires_at < ?", now)
+		return err
+	})
+
+	return keyIDs, err
+}
+
 // cleanupExpiredKeys removes expired keys from the database that have expired more than 61 days ago
 func (s *Store) cleanupExpiredKeys(ctx context.Context) error {
 	err := s.dbStore.WithTransactionalDbSession(ctx, func(tx *sqlstore.DBSession) error {
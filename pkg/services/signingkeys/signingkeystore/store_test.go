@@ -70,4 +70,43 @@ func TestIntegrationSigningKeyStore(t *testing.T) {
 		require.NoError(t, err)
 		require.Len(t, keys, 2)
 	})
+	t.Run("GetActive should return the newest non expired key of a prefix", func(t *testing.T) {
+		now := time.Now().UTC()
+		expired, later := now.Add(-time.Minute), now.Add(time.Hour)
+		for _, key := range []signingkeys.SigningKey{
+			{KeyID: "id-1", KeyPrefix: "id", AddedAt: now.Add(-2 * time.Hour), ExpiresAt: &later},
+			{KeyID: "id-2", KeyPrefix: "id", AddedAt: now.Add(-time.Hour), ExpiresAt: &later},
+			{KeyID: "id-3", KeyPrefix: "id", AddedAt: now, ExpiresAt: &expired},
+			{KeyID: "other-1", KeyPrefix: "other", AddedAt: now, ExpiresAt: &later},
+		} {
+			key := key
+			key.PrivateKey = []byte{}
+			key.Alg = "ES256"
+			_, err := store.Add(ctx, &key, false)
+			require.NoError(t, err)
+		}
+
+		key, err := store.GetActive(ctx, "id", "ES256")
+		require.NoError(t, err)
+		assert.Equal(t, "id-2", key.KeyID)
+
+		_, err = store.GetActive(ctx, "id", "RS256")
+		require.ErrorIs(t, err, signingkeys.ErrSigningKeyNotFound)
+		_, err = store.GetActive(ctx, "unknown", "ES256")
+		require.ErrorIs(t, err, signingkeys.ErrSigningKeyNotFound)
+	})
+
+	t.Run("DeleteExpired should remove the expired keys", func(t *testing.T) {
+		keyIDs, err := store.DeleteExpired(ctx)
+		require.NoError(t, err)
+		// key 3 might already be removed by the cleanup of keys expired long ago
+		assert.Contains(t, keyIDs, "id-3")
+		assert.NotContains(t, keyIDs, "id-2")
+
+		_, err = store.Get(ctx, "id-2")
+		require.NoError(t, err)
+		keyIDs, err = store.DeleteExpired(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, keyIDs)
+	})
 }
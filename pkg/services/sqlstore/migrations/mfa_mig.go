@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addMFAMigrations(mg *Migrator) {
+	factorV1 := Table{
+		Name: "user_mfa_factor",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "type", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "name", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "secret", Type: DB_Text, Nullable: true},
+			{Name: "credential_id", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "public_key", Type: DB_Text, Nullable: true},
+			{Name: "sign_count", Type: DB_BigInt, Nullable: false},
+			{Name: "last_step", Type: DB_BigInt, Nullable: false},
+			{Name: "confirmed", Type: DB_Bool, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "last_used", Type: DB_DateTime, Nullable: true},
+		},
+		Indices: []*Index{
+			{Cols: []string{"user_id"}},
+		},
+	}
+
+	mg.AddMigration("create user_mfa_factor table v1", NewAddTableMigration(factorV1))
+	mg.AddMigration("add index user_mfa_factor.user_id", NewAddIndexMigration(factorV1, factorV1.Indices[0]))
+
+	recoveryCodeV1 := Table{
+		Name: "user_mfa_recovery_code",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "code_hash", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "salt", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"user_id"}},
+		},
+	}
+
+	mg.AddMigration("create user_mfa_recovery_code table v1", NewAddTableMigration(recoveryCodeV1))
+	mg.AddMigration("add index user_mfa_recovery_code.user_id", NewAddIndexMigration(recoveryCodeV1, recoveryCodeV1.Indices[0]))
+
+	sessionV1 := Table{
+		Name: "user_mfa_session",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "token_id", Type: DB_BigInt, Nullable: false},
+			{Name: "method", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "verified", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"token_id"}, Type: UniqueIndex},
+			{Cols: []string{"user_id"}},
+		},
+	}
+
+	mg.AddMigration("create user_mfa_session table v1", NewAddTableMigration(sessionV1))
+	mg.AddMigration("add index user_mfa_session.token_id", NewAddIndexMigration(sessionV1, sessionV1.Indices[0]))
+	mg.AddMigration("add index user_mfa_session.user_id", NewAddIndexMigration(sessionV1, sessionV1.Indices[1]))
+
+	policyV1 := Table{
+		Name: "mfa_policy",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "role", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "role"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create mfa_policy table v1", NewAddTableMigration(policyV1))
+	mg.AddMigration("add index mfa_policy.org_id-role", NewAddIndexMigration(policyV1, policyV1.Indices[0]))
+}
@@ -125,6 +125,22 @@ func (oss *OSSMigrations) AddMigration(mg *Migrator) {
 	ualert.AddRecordingRuleColumns(mg)
 
 	ualert.AddStateResolvedAtColumns(mg)
+
+	ualert.AddStateHistoryTable(mg)
+
+	ualert.AddRecordedSampleTable(mg)
+
+	ualert.AddSilenceScheduleTable(mg)
+
+	ualert.AddRuleLintTable(mg)
+
+	addLivePipelineMigrations(mg)
+
+	addMFAMigrations(mg)
+
+	addSCIMMigrations(mg)
+
+	addWorkloadIdentityMigrations(mg)
 }
 
 func addStarMigrations(mg *Migrator) {
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addSCIMMigrations(mg *Migrator) {
+	externalIDV1 := Table{
+		Name: "scim_external_id",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "resource_type", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "resource_id", Type: DB_BigInt, Nullable: false},
+			{Name: "external_id", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "resource_type", "resource_id"}, Type: UniqueIndex},
+			{Cols: []string{"org_id", "resource_type", "external_id"}},
+		},
+	}
+
+	mg.AddMigration("create scim_external_id table v1", NewAddTableMigration(externalIDV1))
+	mg.AddMigration("add unique index scim_external_id.org_id-resource_type-resource_id", NewAddIndexMigration(externalIDV1, externalIDV1.Indices[0]))
+	mg.AddMigration("add index scim_external_id.org_id-resource_type-external_id", NewAddIndexMigration(externalIDV1, externalIDV1.Indices[1]))
+	mg.AddMigration("add provisioned column to scim_external_id", NewAddColumnMigration(externalIDV1, &Column{
+		Name: "provisioned", Type: DB_Bool, Nullable: false, Default: "0",
+	}))
+}
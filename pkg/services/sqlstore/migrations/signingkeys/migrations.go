@@ -20,4 +20,12 @@ func AddMigration(mg *migrator.Migrator) {
 
 	mg.AddMigration("create signing_key table", migrator.NewAddTableMigration(signingKeysV1))
 	mg.AddMigration("add unique index signing_key.key_id", migrator.NewAddIndexMigration(signingKeysV1, signingKeysV1.Indices[0]))
+
+	// The prefix of the key ID identifies the keys rotated together, the newest key of a prefix is used for signing.
+	mg.AddMigration("add key_prefix column to signing_key", migrator.NewAddColumnMigration(signingKeysV1, &migrator.Column{
+		Name: "key_prefix", Type: migrator.DB_NVarchar, Length: 190, Nullable: true,
+	}))
+	mg.AddMigration("add index signing_key.key_prefix", migrator.NewAddIndexMigration(signingKeysV1, &migrator.Index{
+		Cols: []string{"key_prefix", "added_at"},
+	}))
 }
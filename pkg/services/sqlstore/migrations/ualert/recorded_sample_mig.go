@@ -0,0 +1,30 @@
+package ualert
+
+import "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+// AddRecordedSampleTable creates the table used by the "sql" target of recording rules.
+func AddRecordedSampleTable(mg *migrator.Migrator) {
+	recordedSample := migrator.Table{
+		Name: "alert_rule_recorded_sample",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "rule_uid", Type: migrator.DB_NVarchar, Length: UIDMaxLength, Nullable: false},
+			{Name: "metric", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			// labels contains the labels of the series, encoded as a JSON object.
+			{Name: "labels", Type: migrator.DB_Text, Nullable: false},
+			// labels_hash identifies the series within the metric.
+			{Name: "labels_hash", Type: migrator.DB_NVarchar, Length: UIDMaxLength, Nullable: false},
+			{Name: "sampled_at", Type: migrator.DB_BigInt, Nullable: false}, // Unix time in milliseconds.
+			{Name: "value", Type: migrator.DB_Double, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id", "metric", "sampled_at"}, Type: migrator.IndexType},
+			{Cols: []string{"sampled_at"}, Type: migrator.IndexType},
+		},
+	}
+
+	mg.AddMigration("create alert_rule_recorded_sample table", migrator.NewAddTableMigration(recordedSample))
+	mg.AddMigration("add index in alert_rule_recorded_sample on org_id, metric and sampled_at columns", migrator.NewAddIndexMigration(recordedSample, recordedSample.Indices[0]))
+	mg.AddMigration("add index in alert_rule_recorded_sample on sampled_at column", migrator.NewAddIndexMigration(recordedSample, recordedSample.Indices[1]))
+}
@@ -0,0 +1,29 @@
+package ualert
+
+import "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+// AddRuleLintTable creates the table of the results of the rule linter.
+func AddRuleLintTable(mg *migrator.Migrator) {
+	ruleLint := migrator.Table{
+		Name: "alert_rule_lint",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "rule_uid", Type: migrator.DB_NVarchar, Length: UIDMaxLength, Nullable: false},
+			{Name: "namespace_uid", Type: migrator.DB_NVarchar, Length: UIDMaxLength, Nullable: false},
+			{Name: "rule_group", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "score", Type: migrator.DB_Int, Nullable: false},
+			// issues contains the problems found by the linter, encoded as JSON.
+			{Name: "issues", Type: migrator.DB_Text, Nullable: false},
+			{Name: "linted", Type: migrator.DB_DateTime, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id", "rule_uid"}, Type: migrator.UniqueIndex},
+			{Cols: []string{"org_id", "namespace_uid"}, Type: migrator.IndexType},
+		},
+	}
+
+	mg.AddMigration("create alert_rule_lint table", migrator.NewAddTableMigration(ruleLint))
+	mg.AddMigration("add unique index in alert_rule_lint on org_id and rule_uid columns", migrator.NewAddIndexMigration(ruleLint, ruleLint.Indices[0]))
+	mg.AddMigration("add index in alert_rule_lint on org_id and namespace_uid columns", migrator.NewAddIndexMigration(ruleLint, ruleLint.Indices[1]))
+}
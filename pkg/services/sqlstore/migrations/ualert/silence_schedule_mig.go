@@ -0,0 +1,40 @@
+package ualert
+
+import "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+// AddSilenceScheduleTable creates the table of recurring silences.
+func AddSilenceScheduleTable(mg *migrator.Migrator) {
+	silenceSchedule := migrator.Table{
+		Name: "alert_silence_schedule",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "uid", Type: migrator.DB_NVarchar, Length: UIDMaxLength, Nullable: false},
+			{Name: "title", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "comment", Type: migrator.DB_Text, Nullable: false},
+			// matchers contains the matchers of the silences, encoded as JSON.
+			{Name: "matchers", Type: migrator.DB_Text, Nullable: false},
+			{Name: "cron", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "duration", Type: migrator.DB_BigInt, Nullable: false}, // Seconds.
+			{Name: "location", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			// time_intervals contains the time intervals of the silences, encoded as JSON.
+			{Name: "time_intervals", Type: migrator.DB_Text, Nullable: false},
+			{Name: "is_paused", Type: migrator.DB_Bool, Nullable: false, Default: "0"},
+			// silences contains the IDs and periods of the silences that were created in the Alertmanager, encoded as JSON.
+			{Name: "silences", Type: migrator.DB_Text, Nullable: false},
+			{Name: "created_by", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "created", Type: migrator.DB_DateTime, Nullable: false},
+			{Name: "updated_by", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "updated", Type: migrator.DB_DateTime, Nullable: false},
+			{Name: "version", Type: migrator.DB_BigInt, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id", "uid"}, Type: migrator.UniqueIndex},
+			{Cols: []string{"org_id", "title"}, Type: migrator.UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create alert_silence_schedule table", migrator.NewAddTableMigration(silenceSchedule))
+	mg.AddMigration("add unique index in alert_silence_schedule on org_id and uid columns", migrator.NewAddIndexMigration(silenceSchedule, silenceSchedule.Indices[0]))
+	mg.AddMigration("add unique index in alert_silence_schedule on org_id and title columns", migrator.NewAddIndexMigration(silenceSchedule, silenceSchedule.Indices[1]))
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addWorkloadIdentityMigrations(mg *Migrator) {
+	federatedCredentialV1 := Table{
+		Name: "service_account_federated_credential",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "service_account_id", Type: DB_BigInt, Nullable: false},
+			{Name: "name", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "issuer", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "jwks_url", Type: DB_Text, Nullable: true},
+			{Name: "audience", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "subject", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "claim_matchers", Type: DB_Text, Nullable: true},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "service_account_id", "name"}, Type: UniqueIndex},
+			{Cols: []string{"issuer"}},
+		},
+	}
+
+	mg.AddMigration("create service_account_federated_credential table v1", NewAddTableMigration(federatedCredentialV1))
+	mg.AddMigration("add unique index service_account_federated_credential.org_id-service_account_id-name", NewAddIndexMigration(federatedCredentialV1, federatedCredentialV1.Indices[0]))
+	mg.AddMigration("add index service_account_federated_credential.issuer", NewAddIndexMigration(federatedCredentialV1, federatedCredentialV1.Indices[1]))
+}
@@ -0,0 +1,77 @@
+package workloadidentity
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+)
+
+const (
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	TokenTypeJWT           = "urn:ietf:params:oauth:token-type:jwt"
+	TokenTypeIDToken       = "urn:ietf:params:oauth:token-type:id_token"
+	TokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+var (
+	ErrCredentialNotFound = errutil.NotFound("workload-identity.credential-not-found", errutil.WithPublicMessage("Federated credential not found"))
+	ErrCredentialExists   = errutil.Conflict("workload-identity.credential-exists", errutil.WithPublicMessage("A federated credential with this name already exists"))
+	ErrInvalidCredential  = errutil.BadRequest("workload-identity.invalid-credential")
+	ErrInvalidToken       = errutil.Unauthorized("workload-identity.invalid-token", errutil.WithPublicMessage("Invalid workload identity token"))
+)
+
+// Service manages the federated credentials of service accounts. A federated credential trusts
+// the tokens of an external identity provider, like Kubernetes or GitHub Actions, to act as a service account.
+type Service interface {
+	// ListCredentials returns the federated credentials of a service account.
+	ListCredentials(ctx context.Context, orgID, serviceAccountID int64) ([]*FederatedCredential, error)
+	// CreateCredential adds a federated credential to a service account.
+	CreateCredential(ctx context.Context, cmd *CreateCredentialCommand) (*FederatedCredential, error)
+	// DeleteCredential removes a federated credential of a service account.
+	DeleteCredential(ctx context.Context, orgID, serviceAccountID, credentialID int64) error
+	// ExchangeToken verifies an external token and returns a short-lived token of the
+	// service account of the matching federated credential.
+	ExchangeToken(ctx context.Context, subjectToken string) (*Token, error)
+}
+
+// FederatedCredential maps the tokens of an external issuer to a service account.
+type FederatedCredential struct {
+	ID               int64  `xorm:"pk autoincr 'id'" json:"id"`
+	OrgID            int64  `xorm:"org_id" json:"orgId"`
+	ServiceAccountID int64  `xorm:"service_account_id" json:"serviceAccountId"`
+	Name             string `xorm:"name" json:"name"`
+	// Issuer is the iss claim of the tokens, their signing keys are discovered from the issuer.
+	Issuer string `xorm:"issuer" json:"issuer"`
+	// JWKSURL is the URL of the signing keys of issuers without discovery document.
+	JWKSURL string `xorm:"jwks_url" json:"jwksUrl,omitempty"`
+	// Audience must be one of the aud claims of the tokens.
+	Audience string `xorm:"audience" json:"audience"`
+	// Subject is matched with the sub claim of the tokens, * matches any characters.
+	Subject string `xorm:"subject" json:"subject"`
+	// ClaimMatchers are additional JMESPath expressions matched with the tokens, * matches any characters.
+	ClaimMatchers map[string]string `xorm:"claim_matchers" json:"claimMatchers,omitempty"`
+	Created       time.Time         `xorm:"created" json:"created"`
+	Updated       time.Time         `xorm:"updated" json:"updated"`
+}
+
+func (c FederatedCredential) TableName() string {
+	return "service_account_federated_credential"
+}
+
+type CreateCredentialCommand struct {
+	OrgID            int64             `json:"-"`
+	ServiceAccountID int64             `json:"-"`
+	Name             string            `json:"name"`
+	Issuer           string            `json:"issuer"`
+	JWKSURL          string            `json:"jwksUrl"`
+	Audience         string            `json:"audience"`
+	Subject          string            `json:"subject"`
+	ClaimMatchers    map[string]string `json:"claimMatchers"`
+}
+
+// Token is a short-lived token of a service account, issued in exchange for an external token.
+type Token struct {
+	AccessToken string
+	ExpiresIn   time.Duration
+}
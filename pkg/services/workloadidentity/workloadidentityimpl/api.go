@@ -0,0 +1,150 @@
+package workloadidentityimpl
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/serviceaccounts"
+	"github.com/grafana/grafana/pkg/services/workloadidentity"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+type api struct {
+	service       *Service
+	accessControl accesscontrol.AccessControl
+	routeRegister routing.RouteRegister
+}
+
+func newAPI(service *Service, accessControl accesscontrol.AccessControl, routeRegister routing.RouteRegister) *api {
+	return &api{
+		service:       service,
+		accessControl: accessControl,
+		routeRegister: routeRegister,
+	}
+}
+
+func (a *api) registerAPIEndpoints() {
+	authorize := accesscontrol.Middleware(a.accessControl)
+
+	// the exchange is authenticated by the subject token
+	a.routeRegister.Post("/api/workload-identity/token", routing.Wrap(a.exchangeToken))
+
+	a.routeRegister.Group("/api/serviceaccounts/:serviceAccountId/federated-credentials", func(r routing.RouteRegister) {
+		r.Get("/", authorize(accesscontrol.EvalPermission(serviceaccounts.ActionRead, serviceaccounts.ScopeID)), routing.Wrap(a.listCredentials))
+		r.Post("/", authorize(accesscontrol.EvalPermission(serviceaccounts.ActionWrite, serviceaccounts.ScopeID)), routing.Wrap(a.createCredential))
+		r.Delete("/:credentialId", authorize(accesscontrol.EvalPermission(serviceaccounts.ActionWrite, serviceaccounts.ScopeID)), routing.Wrap(a.deleteCredential))
+	}, middleware.ReqSignedInNoAnonymous)
+}
+
+func (a *api) listCredentials(c *contextmodel.ReqContext) response.Response {
+	serviceAccountID, err := strconv.ParseInt(web.Params(c.Req)[":serviceAccountId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Service Account ID is invalid", err)
+	}
+	credentials, err := a.service.ListCredentials(c.Req.Context(), c.SignedInUser.GetOrgID(), serviceAccountID)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to list federated credentials", err)
+	}
+	return response.JSON(http.StatusOK, credentials)
+}
+
+func (a *api) createCredential(c *contextmodel.ReqContext) response.Response {
+	serviceAccountID, err := strconv.ParseInt(web.Params(c.Req)[":serviceAccountId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Service Account ID is invalid", err)
+	}
+	cmd := workloadidentity.CreateCredentialCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "Bad request data", err)
+	}
+	cmd.OrgID = c.SignedInUser.GetOrgID()
+	cmd.ServiceAccountID = serviceAccountID
+
+	credential, err := a.service.CreateCredential(c.Req.Context(), &cmd)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to create federated credential", err)
+	}
+	return response.JSON(http.StatusCreated, credential)
+}
+
+func (a *api) deleteCredential(c *contextmodel.ReqContext) response.Response {
+	serviceAccountID, err := strconv.ParseInt(web.Params(c.Req)[":serviceAccountId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Service Account ID is invalid", err)
+	}
+	credentialID, err := strconv.ParseInt(web.Params(c.Req)[":credentialId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "credentialId is invalid", err)
+	}
+	if err := a.service.DeleteCredential(c.Req.Context(), c.SignedInUser.GetOrgID(), serviceAccountID, credentialID); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to delete federated credential", err)
+	}
+	return response.Success("Federated credential deleted")
+}
+
+// tokenResponse is the response of a token exchange, see RFC 8693.
+type tokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+}
+
+// tokenErrorResponse is the error response of a token exchange, see RFC 6749.
+type tokenErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// exchangeToken exchanges an external token for a short-lived token of a service account.
+func (a *api) exchangeToken(c *contextmodel.ReqContext) response.Response {
+	if err := c.Req.ParseForm(); err != nil {
+		return tokenError(http.StatusBadRequest, "invalid_request", "Request must be form encoded")
+	}
+	form := c.Req.PostForm
+
+	if form.Get("grant_type") != workloadidentity.GrantTypeTokenExchange {
+		return tokenError(http.StatusBadRequest, "unsupported_grant_type", "Only token exchange is supported")
+	}
+
+	subjectToken := form.Get("subject_token")
+	if subjectToken == "" {
+		return tokenError(http.StatusBadRequest, "invalid_request", "subject_token is required")
+	}
+	switch form.Get("subject_token_type") {
+	case workloadidentity.TokenTypeJWT, workloadidentity.TokenTypeIDToken:
+	default:
+		return tokenError(http.StatusBadRequest, "invalid_request", "subject_token_type must be a JWT or an ID token")
+	}
+	if requested := form.Get("requested_token_type"); requested != "" && requested != workloadidentity.TokenTypeAccessToken {
+		return tokenError(http.StatusBadRequest, "invalid_request", "Only access tokens can be requested")
+	}
+
+	token, err := a.service.ExchangeToken(c.Req.Context(), subjectToken)
+	if err != nil {
+		if errors.Is(err, workloadidentity.ErrInvalidToken) {
+			a.service.log.FromContext(c.Req.Context()).Warn("Token exchange rejected", "error", err)
+			return tokenError(http.StatusBadRequest, "invalid_grant", "The subject token is not trusted")
+		}
+		a.service.log.FromContext(c.Req.Context()).Error("Token exchange failed", "error", err)
+		return tokenError(http.StatusInternalServerError, "server_error", "")
+	}
+
+	return response.JSON(http.StatusOK, tokenResponse{
+		AccessToken:     token.AccessToken,
+		IssuedTokenType: workloadidentity.TokenTypeAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int64(token.ExpiresIn.Seconds()),
+	}).SetHeader("Cache-Control", "no-store")
+}
+
+func tokenError(status int, code, description string) response.Response {
+	return response.JSON(status, tokenErrorResponse{Error: code, ErrorDescription: description}).
+		SetHeader("Cache-Control", "no-store")
+}
@@ -0,0 +1,62 @@
+package workloadidentityimpl
+
+import (
+	"context"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/authn"
+)
+
+const bearerPrefix = "Bearer "
+
+var _ authn.Client = new(Client)
+
+// Client authenticates service accounts with the tokens issued by an exchange, or with
+// external tokens matching a federated credential.
+type Client struct {
+	service *Service
+}
+
+func (c *Client) Name() string {
+	return authn.ClientWorkloadIdentity
+}
+
+func (c *Client) IsEnabled() bool {
+	return c.service.cfg.WorkloadIdentity.Enabled
+}
+
+func (c *Client) Authenticate(ctx context.Context, r *authn.Request) (*authn.Identity, error) {
+	return c.service.authenticate(ctx, r, bearerToken(r))
+}
+
+// Test only accepts bearer tokens that are JWTs of a known issuer, API keys, service
+// account tokens and the tokens of other issuers are left to the other clients.
+func (c *Client) Test(ctx context.Context, r *authn.Request) bool {
+	token := bearerToken(r)
+	if token == "" {
+		return false
+	}
+
+	_, issuer, err := parseToken(token)
+	if err != nil {
+		return false
+	}
+	return c.service.isKnownIssuer(ctx, issuer)
+}
+
+// Priority runs the client before the API key client, which accepts any bearer token.
+func (c *Client) Priority() uint {
+	return 25
+}
+
+func bearerToken(r *authn.Request) string {
+	if r.HTTPRequest == nil {
+		return ""
+	}
+
+	header := r.HTTPRequest.Header.Get("Authorization")
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(header, bearerPrefix)
+}
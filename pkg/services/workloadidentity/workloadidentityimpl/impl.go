@@ -0,0 +1,488 @@
+package workloadidentityimpl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/serviceaccounts"
+	"github.com/grafana/grafana/pkg/services/signingkeys"
+	"github.com/grafana/grafana/pkg/services/workloadidentity"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const (
+	// keyPrefix separates the signing keys of exchanged tokens from the keys of ID tokens,
+	// so an ID token is never accepted as an access token.
+	keyPrefix = "workload-identity"
+	// tokenAudience is the aud claim of exchanged tokens.
+	tokenAudience = "grafana-workload-identity"
+	// tokenType is the typ header of exchanged tokens, see RFC 9068.
+	tokenType = "at+jwt"
+	clockSkew = time.Minute
+	// issuersCacheTTL is how long the issuers of the federated credentials are cached. Credentials
+	// created on other instances trust their issuer on this instance after at most this long.
+	issuersCacheTTL = time.Minute
+)
+
+var errOrgMismatch = errutil.Unauthorized("workload-identity.org-mismatch",
+	errutil.WithPublicMessage("The service account does not belong to the requested organization"))
+
+var _ workloadidentity.Service = (*Service)(nil)
+
+type Service struct {
+	cfg         *setting.Cfg
+	log         log.Logger
+	store       *store
+	saService   serviceaccounts.Service
+	signingKeys signingkeys.Service
+	keys        *issuerKeys
+	issuers     issuersCache
+	now         func() time.Time
+}
+
+// issuersCache holds the issuers trusted by federated credentials, so that the tokens of other
+// issuers are skipped without querying the database.
+type issuersCache struct {
+	mu      sync.Mutex
+	issuers map[string]struct{}
+	expires time.Time
+}
+
+func ProvideService(
+	cfg *setting.Cfg, sqlStore db.DB, saService serviceaccounts.Service, signingKeys signingkeys.Service,
+	authnService authn.Service, accessControl accesscontrol.AccessControl, routeRegister routing.RouteRegister,
+) *Service {
+	s := &Service{
+		cfg:         cfg,
+		log:         log.New("workload-identity"),
+		store:       &store{db: sqlStore},
+		saService:   saService,
+		signingKeys: signingKeys,
+		keys:        newIssuerKeys(),
+		now:         time.Now,
+	}
+
+	if cfg.WorkloadIdentity.Enabled {
+		authnService.RegisterClient(&Client{service: s})
+
+		api := newAPI(s, accessControl, routeRegister)
+		api.registerAPIEndpoints()
+	}
+
+	return s
+}
+
+func (s *Service) ListCredentials(ctx context.Context, orgID, serviceAccountID int64) ([]*workloadidentity.FederatedCredential, error) {
+	return s.store.listCredentials(ctx, orgID, serviceAccountID)
+}
+
+func (s *Service) CreateCredential(ctx context.Context, cmd *workloadidentity.CreateCredentialCommand) (*workloadidentity.FederatedCredential, error) {
+	if err := s.validateCredential(cmd); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.saService.RetrieveServiceAccount(ctx, cmd.OrgID, cmd.ServiceAccountID); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	credential := &workloadidentity.FederatedCredential{
+		OrgID:            cmd.OrgID,
+		ServiceAccountID: cmd.ServiceAccountID,
+		Name:             cmd.Name,
+		Issuer:           cmd.Issuer,
+		JWKSURL:          cmd.JWKSURL,
+		Audience:         cmd.Audience,
+		Subject:          cmd.Subject,
+		ClaimMatchers:    cmd.ClaimMatchers,
+		Created:          now,
+		Updated:          now,
+	}
+	if err := s.store.insertCredential(ctx, credential); err != nil {
+		return nil, err
+	}
+	s.invalidateIssuers()
+
+	s.log.Info("Federated credential created", "orgId", cmd.OrgID, "serviceAccountId", cmd.ServiceAccountID,
+		"credentialId", credential.ID, "issuer", cmd.Issuer, "subject", cmd.Subject)
+	return credential, nil
+}
+
+func (s *Service) validateCredential(cmd *workloadidentity.CreateCredentialCommand) error {
+	cmd.Name = strings.TrimSpace(cmd.Name)
+	if cmd.Name == "" {
+		return invalidCredential("Name is required")
+	}
+
+	if !isHTTPSURL(cmd.Issuer) {
+		return invalidCredential("Issuer must be an https URL")
+	}
+	if allowed := s.cfg.WorkloadIdentity.AllowedIssuers; len(allowed) > 0 && !slices.Contains(allowed, cmd.Issuer) {
+		return invalidCredential("Issuer is not allowed")
+	}
+	if cmd.JWKSURL != "" && !isHTTPSURL(cmd.JWKSURL) {
+		return invalidCredential("JWKS URL must be an https URL")
+	}
+
+	if cmd.Audience == "" {
+		return invalidCredential("Audience is required")
+	}
+	// an empty subject would match every token of the issuer, a wildcard has to be explicit
+	if cmd.Subject == "" {
+		return invalidCredential("Subject is required")
+	}
+
+	for expression, pattern := range cmd.ClaimMatchers {
+		if pattern == "" {
+			return invalidCredential(fmt.Sprintf("Claim matcher %s has no value", expression))
+		}
+		if _, err := util.SearchJSONForStringAttr(expression, map[string]any{}); err != nil {
+			return invalidCredential(fmt.Sprintf("Claim matcher %s is not a valid JMESPath expression", expression))
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) DeleteCredential(ctx context.Context, orgID, serviceAccountID, credentialID int64) error {
+	if err := s.store.deleteCredential(ctx, orgID, serviceAccountID, credentialID); err != nil {
+		return err
+	}
+	s.invalidateIssuers()
+
+	s.log.Info("Federated credential deleted", "orgId", orgID, "serviceAccountId", serviceAccountID, "credentialId", credentialID)
+	return nil
+}
+
+func (s *Service) ExchangeToken(ctx context.Context, subjectToken string) (*workloadidentity.Token, error) {
+	parsed, issuer, err := parseToken(subjectToken)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, claims, err := s.verifyExternalToken(ctx, parsed, issuer, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkServiceAccount(ctx, credential.OrgID, credential.ServiceAccountID); err != nil {
+		return nil, err
+	}
+
+	token, err := s.signToken(ctx, credential.OrgID, credential.ServiceAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Token exchanged", "orgId", credential.OrgID, "serviceAccountId", credential.ServiceAccountID,
+		"credentialId", credential.ID, "issuer", claims.Issuer, "subject", claims.Subject)
+	return token, nil
+}
+
+// authenticate returns the service account identity of a token, either a token issued by an
+// exchange or an external token presented directly.
+func (s *Service) authenticate(ctx context.Context, r *authn.Request, rawToken string) (*authn.Identity, error) {
+	parsed, issuer, err := parseToken(rawToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var orgID, serviceAccountID int64
+	if issuer == s.issuer() {
+		orgID, serviceAccountID, err = s.verifyToken(ctx, parsed)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// the request may select the organization, so that the credentials of other organizations
+		// trusting the same issuer do not take precedence.
+		credential, claims, err := s.verifyExternalToken(ctx, parsed, issuer, r.OrgID)
+		if err != nil {
+			return nil, err
+		}
+		s.log.FromContext(ctx).Debug("Authenticated external token", "credentialId", credential.ID, "subject", claims.Subject)
+		orgID, serviceAccountID = credential.OrgID, credential.ServiceAccountID
+	}
+
+	if err := s.checkServiceAccount(ctx, orgID, serviceAccountID); err != nil {
+		return nil, err
+	}
+
+	if r.OrgID != 0 && r.OrgID != orgID {
+		return nil, errOrgMismatch.Errorf("service account %d belongs to org %d, not %d", serviceAccountID, orgID, r.OrgID)
+	}
+	r.OrgID = orgID
+
+	return &authn.Identity{
+		ID:              identity.NewTypedID(identity.TypeServiceAccount, serviceAccountID),
+		OrgID:           orgID,
+		AuthenticatedBy: login.WorkloadIdentityModule,
+		ClientParams:    authn.ClientParams{FetchSyncedUser: true, SyncPermissions: true},
+	}, nil
+}
+
+// verifyExternalToken verifies a token of an external issuer and returns the first federated
+// credential, oldest first, matching its claims. Only the credentials of orgID are matched
+// unless it is 0.
+func (s *Service) verifyExternalToken(ctx context.Context, parsed *jwt.JSONWebToken, issuer string, orgID int64) (*workloadidentity.FederatedCredential, *jwt.Claims, error) {
+	credentials, err := s.store.listCredentialsByIssuer(ctx, issuer, orgID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(credentials) == 0 {
+		return nil, nil, workloadidentity.ErrInvalidToken.Errorf("no federated credential trusts issuer %s", issuer)
+	}
+
+	// Credentials of different organizations may trust the same issuer with different keys,
+	// a credential only matches a token verified with its own keys.
+	type verification struct {
+		claims *jwt.Claims
+		rest   map[string]any
+		err    error
+	}
+	verified := map[string]verification{}
+
+	var lastErr error
+	for _, credential := range credentials {
+		result, ok := verified[credential.JWKSURL]
+		if !ok {
+			claims, rest, err := s.verifyIssuerSignature(ctx, parsed, credential)
+			result = verification{claims: claims, rest: rest, err: err}
+			verified[credential.JWKSURL] = result
+		}
+		if result.err != nil {
+			lastErr = result.err
+			continue
+		}
+
+		if matchCredential(credential, result.claims, result.rest) {
+			return credential, result.claims, nil
+		}
+	}
+
+	if lastErr != nil {
+		return nil, nil, workloadidentity.ErrInvalidToken.Errorf("failed to verify token of issuer %s: %w", issuer, lastErr)
+	}
+	return nil, nil, workloadidentity.ErrInvalidToken.Errorf("no federated credential of issuer %s matches the token", issuer)
+}
+
+func (s *Service) verifyIssuerSignature(ctx context.Context, parsed *jwt.JSONWebToken, credential *workloadidentity.FederatedCredential) (*jwt.Claims, map[string]any, error) {
+	key, err := s.keys.get(ctx, credential, parsed.Headers[0].KeyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	claims := &jwt.Claims{}
+	rest := map[string]any{}
+	if err := parsed.Claims(key, claims, &rest); err != nil {
+		return nil, nil, err
+	}
+
+	if claims.Expiry == nil {
+		return nil, nil, errors.New("token has no expiry")
+	}
+	if err := claims.ValidateWithLeeway(jwt.Expected{Issuer: credential.Issuer, Time: s.now()}, clockSkew); err != nil {
+		return nil, nil, err
+	}
+
+	return claims, rest, nil
+}
+
+func matchCredential(credential *workloadidentity.FederatedCredential, claims *jwt.Claims, rest map[string]any) bool {
+	if !claims.Audience.Contains(credential.Audience) || !matchPattern(credential.Subject, claims.Subject) {
+		return false
+	}
+
+	for expression, pattern := range credential.ClaimMatchers {
+		value, err := util.SearchJSONForStringAttr(expression, rest)
+		if err != nil || value == "" || !matchPattern(pattern, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Service) checkServiceAccount(ctx context.Context, orgID, serviceAccountID int64) error {
+	serviceAccount, err := s.saService.RetrieveServiceAccount(ctx, orgID, serviceAccountID)
+	if err != nil {
+		if errors.Is(err, serviceaccounts.ErrServiceAccountNotFound) {
+			return workloadidentity.ErrInvalidToken.Errorf("service account %d not found", serviceAccountID)
+		}
+		return err
+	}
+
+	if serviceAccount.IsDisabled {
+		return workloadidentity.ErrInvalidToken.Errorf("service account %d is disabled", serviceAccountID)
+	}
+	return nil
+}
+
+// signToken issues a short-lived access token of a service account.
+func (s *Service) signToken(ctx context.Context, orgID, serviceAccountID int64) (*workloadidentity.Token, error) {
+	keyID, key, err := s.signingKeys.GetOrCreatePrivateKey(ctx, keyPrefix, jose.ES256)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, &jose.SignerOptions{
+		ExtraHeaders: map[jose.HeaderKey]any{
+			"kid":           keyID,
+			jose.HeaderType: tokenType,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	lifetime := s.cfg.WorkloadIdentity.TokenLifetime
+	claims := jwt.Claims{
+		Issuer:    s.issuer(),
+		Subject:   identity.NewTypedID(identity.TypeServiceAccount, serviceAccountID).String(),
+		Audience:  jwt.Audience{tokenAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Expiry:    jwt.NewNumericDate(now.Add(lifetime)),
+		ID:        util.GenerateShortUID(),
+	}
+
+	token, err := jwt.Signed(signer).Claims(claims).Claims(tokenClaims{OrgID: orgID}).CompactSerialize()
+	if err != nil {
+		return nil, err
+	}
+
+	return &workloadidentity.Token{AccessToken: token, ExpiresIn: lifetime}, nil
+}
+
+type tokenClaims struct {
+	OrgID int64 `json:"org_id"`
+}
+
+// verifyToken verifies a token issued by an exchange and returns its org and service account.
+func (s *Service) verifyToken(ctx context.Context, parsed *jwt.JSONWebToken) (int64, int64, error) {
+	header := parsed.Headers[0]
+	if typ, _ := header.ExtraHeaders[jose.HeaderType].(string); typ != tokenType || !strings.HasPrefix(header.KeyID, keyPrefix+"-") {
+		return 0, 0, workloadidentity.ErrInvalidToken.Errorf("token is not a workload identity access token")
+	}
+
+	jwks, err := s.signingKeys.GetJWKS(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	keys := jwks.Key(header.KeyID)
+	if len(keys) == 0 {
+		return 0, 0, workloadidentity.ErrInvalidToken.Errorf("unknown signing key %s", header.KeyID)
+	}
+
+	claims := jwt.Claims{}
+	extra := tokenClaims{}
+	if err := parsed.Claims(keys[0].Public(), &claims, &extra); err != nil {
+		return 0, 0, workloadidentity.ErrInvalidToken.Errorf("failed to verify token: %w", err)
+	}
+
+	expected := jwt.Expected{Issuer: s.issuer(), Audience: jwt.Audience{tokenAudience}, Time: s.now()}
+	if err := claims.ValidateWithLeeway(expected, clockSkew); err != nil {
+		return 0, 0, workloadidentity.ErrInvalidToken.Errorf("invalid token claims: %w", err)
+	}
+
+	id, err := identity.ParseTypedID(claims.Subject)
+	if err != nil || !id.IsType(identity.TypeServiceAccount) {
+		return 0, 0, workloadidentity.ErrInvalidToken.Errorf("invalid token subject %s", claims.Subject)
+	}
+	serviceAccountID, err := id.ParseInt()
+	if err != nil {
+		return 0, 0, workloadidentity.ErrInvalidToken.Errorf("invalid token subject %s", claims.Subject)
+	}
+
+	return extra.OrgID, serviceAccountID, nil
+}
+
+func (s *Service) issuer() string {
+	return s.cfg.AppURL
+}
+
+// isKnownIssuer reports whether tokens of an issuer can be authenticated: the issuer is this
+// instance, an allowed issuer or the issuer of a federated credential.
+func (s *Service) isKnownIssuer(ctx context.Context, issuer string) bool {
+	if issuer == s.issuer() || slices.Contains(s.cfg.WorkloadIdentity.AllowedIssuers, issuer) {
+		return true
+	}
+
+	s.issuers.mu.Lock()
+	defer s.issuers.mu.Unlock()
+
+	now := s.now()
+	if now.After(s.issuers.expires) {
+		issuers, err := s.store.listIssuers(ctx)
+		if err != nil {
+			// the token is authenticated anyway, which reports the error
+			s.log.FromContext(ctx).Warn("Failed to list the issuers of federated credentials", "error", err)
+			return true
+		}
+		s.issuers.issuers = make(map[string]struct{}, len(issuers))
+		for _, i := range issuers {
+			s.issuers.issuers[i] = struct{}{}
+		}
+		s.issuers.expires = now.Add(issuersCacheTTL)
+	}
+
+	_, ok := s.issuers.issuers[issuer]
+	return ok
+}
+
+func (s *Service) invalidateIssuers() {
+	s.issuers.mu.Lock()
+	defer s.issuers.mu.Unlock()
+	s.issuers.expires = time.Time{}
+}
+
+// parseToken parses a signed JWT without verifying it and returns its issuer.
+func parseToken(rawToken string) (*jwt.JSONWebToken, string, error) {
+	parsed, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return nil, "", workloadidentity.ErrInvalidToken.Errorf("failed to parse token: %w", err)
+	}
+	if len(parsed.Headers) != 1 {
+		return nil, "", workloadidentity.ErrInvalidToken.Errorf("token must have exactly one signature")
+	}
+
+	claims := jwt.Claims{}
+	if err := parsed.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return nil, "", workloadidentity.ErrInvalidToken.Errorf("failed to read token claims: %w", err)
+	}
+	if claims.Issuer == "" {
+		return nil, "", workloadidentity.ErrInvalidToken.Errorf("token has no issuer")
+	}
+
+	return parsed, claims.Issuer, nil
+}
+
+func isHTTPSURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+func invalidCredential(msg string) error {
+	err := workloadidentity.ErrInvalidCredential.Errorf("invalid federated credential: %s", msg)
+	err.PublicMessage = msg
+	return err
+}
@@ -0,0 +1,455 @@
+package workloadidentityimpl
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/serviceaccounts"
+	satests "github.com/grafana/grafana/pkg/services/serviceaccounts/tests"
+	"github.com/grafana/grafana/pkg/services/signingkeys/signingkeystest"
+	"github.com/grafana/grafana/pkg/services/workloadidentity"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+const (
+	testAppURL   = "https://grafana.example.com/"
+	testAudience = "https://grafana.example.com"
+	testSubject  = "repo:grafana/deploy:ref:refs/heads/main"
+)
+
+// testIssuer is an OpenID provider serving its discovery document and signing keys.
+type testIssuer struct {
+	server *httptest.Server
+	key    *ecdsa.PrivateKey
+	keyID  string
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	issuer := &testIssuer{key: key, keyID: "issuer-key"}
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer.server.URL,
+			"jwks_uri": issuer.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: key.Public(), KeyID: issuer.keyID, Algorithm: string(jose.ES256), Use: "sig"},
+		}})
+	})
+	issuer.server = httptest.NewTLSServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testIssuer) sign(t *testing.T, key *ecdsa.PrivateKey, claims jwt.Claims, extra map[string]any) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", i.keyID))
+	require.NoError(t, err)
+
+	token, err := jwt.Signed(signer).Claims(claims).Claims(extra).CompactSerialize()
+	require.NoError(t, err)
+	return token
+}
+
+func (i *testIssuer) token(t *testing.T, subject string, extra map[string]any) string {
+	return i.sign(t, i.key, i.claims(subject), extra)
+}
+
+func (i *testIssuer) claims(subject string) jwt.Claims {
+	now := time.Now()
+	return jwt.Claims{
+		Issuer:   i.server.URL,
+		Subject:  subject,
+		Audience: jwt.Audience{testAudience},
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(now.Add(5 * time.Minute)),
+	}
+}
+
+func setupTestService(t *testing.T, issuer *testIssuer) (*Service, *satests.FakeServiceAccountService) {
+	t.Helper()
+	sqlStore := db.InitTestDB(t)
+	cfg := setting.NewCfg()
+	cfg.AppURL = testAppURL
+	cfg.WorkloadIdentity = setting.WorkloadIdentitySettings{TokenLifetime: 15 * time.Minute}
+
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyID := keyPrefix + "-20240101000000-es256"
+	signingKeys := &signingkeystest.FakeSigningKeysService{
+		ExpectedKeyID:  keyID,
+		ExpectedSinger: signingKey,
+		ExpectedJSONWebKeySet: jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: signingKey.Public(), KeyID: keyID, Algorithm: string(jose.ES256), Use: "sig"},
+		}},
+	}
+
+	saService := &satests.FakeServiceAccountService{
+		ExpectedServiceAccountProfile: &serviceaccounts.ServiceAccountProfileDTO{Id: 10, OrgId: 1},
+	}
+
+	// Workload identity is disabled so no client or API gets registered, the client is created directly.
+	s := ProvideService(cfg, sqlStore, saService, signingKeys, nil, nil, nil)
+	if issuer != nil {
+		s.keys.client = issuer.server.Client()
+	}
+	return s, saService
+}
+
+func createCredential(t *testing.T, s *Service, issuer *testIssuer, claimMatchers map[string]string) *workloadidentity.FederatedCredential {
+	t.Helper()
+	credential, err := s.CreateCredential(context.Background(), &workloadidentity.CreateCredentialCommand{
+		OrgID:            1,
+		ServiceAccountID: 10,
+		Name:             "deploy",
+		Issuer:           issuer.server.URL,
+		Audience:         testAudience,
+		Subject:          "repo:grafana/deploy:*",
+		ClaimMatchers:    claimMatchers,
+	})
+	require.NoError(t, err)
+	return credential
+}
+
+func bearerRequest(token string, orgID int64) *authn.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/dashboards", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return &authn.Request{HTTPRequest: req, OrgID: orgID}
+}
+
+func TestIntegrationCreateCredential(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	issuer := newTestIssuer(t)
+
+	valid := func() *workloadidentity.CreateCredentialCommand {
+		return &workloadidentity.CreateCredentialCommand{
+			OrgID:            1,
+			ServiceAccountID: 10,
+			Name:             "deploy",
+			Issuer:           issuer.server.URL,
+			Audience:         testAudience,
+			Subject:          testSubject,
+			ClaimMatchers:    map[string]string{"repository_owner": "grafana"},
+		}
+	}
+
+	invalid := []struct {
+		desc   string
+		modify func(cmd *workloadidentity.CreateCredentialCommand)
+	}{
+		{desc: "without name", modify: func(cmd *workloadidentity.CreateCredentialCommand) { cmd.Name = " " }},
+		{desc: "with http issuer", modify: func(cmd *workloadidentity.CreateCredentialCommand) { cmd.Issuer = "http://issuer.example.com" }},
+		{desc: "with http JWKS URL", modify: func(cmd *workloadidentity.CreateCredentialCommand) { cmd.JWKSURL = "http://issuer.example.com/keys" }},
+		{desc: "without audience", modify: func(cmd *workloadidentity.CreateCredentialCommand) { cmd.Audience = "" }},
+		{desc: "without subject", modify: func(cmd *workloadidentity.CreateCredentialCommand) { cmd.Subject = "" }},
+		{desc: "with empty claim matcher", modify: func(cmd *workloadidentity.CreateCredentialCommand) {
+			cmd.ClaimMatchers = map[string]string{"repository_owner": ""}
+		}},
+		{desc: "with invalid claim matcher", modify: func(cmd *workloadidentity.CreateCredentialCommand) {
+			cmd.ClaimMatchers = map[string]string{"repository[": "grafana"}
+		}},
+	}
+	for _, tt := range invalid {
+		t.Run("should reject credential "+tt.desc, func(t *testing.T) {
+			s, _ := setupTestService(t, issuer)
+			cmd := valid()
+			tt.modify(cmd)
+			_, err := s.CreateCredential(context.Background(), cmd)
+			assert.ErrorIs(t, err, workloadidentity.ErrInvalidCredential)
+		})
+	}
+
+	t.Run("should reject issuer that is not allowed", func(t *testing.T) {
+		s, _ := setupTestService(t, issuer)
+		s.cfg.WorkloadIdentity.AllowedIssuers = []string{"https://token.actions.githubusercontent.com"}
+		_, err := s.CreateCredential(context.Background(), valid())
+		assert.ErrorIs(t, err, workloadidentity.ErrInvalidCredential)
+	})
+
+	t.Run("should reject credential of unknown service account", func(t *testing.T) {
+		s, saService := setupTestService(t, issuer)
+		saService.ExpectedErr = serviceaccounts.ErrServiceAccountNotFound.Errorf("not found")
+		_, err := s.CreateCredential(context.Background(), valid())
+		assert.ErrorIs(t, err, serviceaccounts.ErrServiceAccountNotFound)
+	})
+
+	t.Run("should create, list and delete credentials", func(t *testing.T) {
+		s, _ := setupTestService(t, issuer)
+		ctx := context.Background()
+
+		created, err := s.CreateCredential(ctx, valid())
+		require.NoError(t, err)
+
+		_, err = s.CreateCredential(ctx, valid())
+		assert.ErrorIs(t, err, workloadidentity.ErrCredentialExists)
+
+		credentials, err := s.ListCredentials(ctx, 1, 10)
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+		assert.Equal(t, created.ID, credentials[0].ID)
+		assert.Equal(t, map[string]string{"repository_owner": "grafana"}, credentials[0].ClaimMatchers)
+
+		credentials, err = s.ListCredentials(ctx, 2, 10)
+		require.NoError(t, err)
+		assert.Empty(t, credentials)
+
+		assert.ErrorIs(t, s.DeleteCredential(ctx, 2, 10, created.ID), workloadidentity.ErrCredentialNotFound)
+		require.NoError(t, s.DeleteCredential(ctx, 1, 10, created.ID))
+		assert.ErrorIs(t, s.DeleteCredential(ctx, 1, 10, created.ID), workloadidentity.ErrCredentialNotFound)
+	})
+}
+
+func TestIntegrationExchangeToken(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	issuer := newTestIssuer(t)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	t.Run("should exchange token matching a credential", func(t *testing.T) {
+		s, _ := setupTestService(t, issuer)
+		createCredential(t, s, issuer, map[string]string{"repository_owner": "grafana"})
+
+		token, err := s.ExchangeToken(context.Background(), issuer.token(t, testSubject, map[string]any{"repository_owner": "grafana"}))
+		require.NoError(t, err)
+		assert.Equal(t, 15*time.Minute, token.ExpiresIn)
+
+		client := &Client{service: s}
+		r := bearerRequest(token.AccessToken, 0)
+		require.True(t, client.Test(context.Background(), r))
+		id, err := client.Authenticate(context.Background(), r)
+		require.NoError(t, err)
+		assert.Equal(t, identity.NewTypedID(identity.TypeServiceAccount, 10), id.ID)
+		assert.Equal(t, int64(1), id.OrgID)
+		assert.Equal(t, int64(1), r.OrgID)
+	})
+
+	invalid := []struct {
+		desc  string
+		token func(t *testing.T) string
+	}{
+		{desc: "of another subject", token: func(t *testing.T) string {
+			return issuer.token(t, "repo:grafana/other:ref:refs/heads/main", map[string]any{"repository_owner": "grafana"})
+		}},
+		{desc: "not matching the claim matchers", token: func(t *testing.T) string {
+			return issuer.token(t, testSubject, map[string]any{"repository_owner": "other"})
+		}},
+		{desc: "without the matched claim", token: func(t *testing.T) string {
+			return issuer.token(t, testSubject, nil)
+		}},
+		{desc: "of another audience", token: func(t *testing.T) string {
+			claims := issuer.claims(testSubject)
+			claims.Audience = jwt.Audience{"other"}
+			return issuer.sign(t, issuer.key, claims, map[string]any{"repository_owner": "grafana"})
+		}},
+		{desc: "that is expired", token: func(t *testing.T) string {
+			claims := issuer.claims(testSubject)
+			claims.Expiry = jwt.NewNumericDate(time.Now().Add(-10 * time.Minute))
+			return issuer.sign(t, issuer.key, claims, map[string]any{"repository_owner": "grafana"})
+		}},
+		{desc: "without expiry", token: func(t *testing.T) string {
+			claims := issuer.claims(testSubject)
+			claims.Expiry = nil
+			return issuer.sign(t, issuer.key, claims, map[string]any{"repository_owner": "grafana"})
+		}},
+		{desc: "signed with another key", token: func(t *testing.T) string {
+			return issuer.sign(t, otherKey, issuer.claims(testSubject), map[string]any{"repository_owner": "grafana"})
+		}},
+		{desc: "of an untrusted issuer", token: func(t *testing.T) string {
+			claims := issuer.claims(testSubject)
+			claims.Issuer = "https://untrusted.example.com"
+			return issuer.sign(t, issuer.key, claims, map[string]any{"repository_owner": "grafana"})
+		}},
+		{desc: "that is not a JWT", token: func(t *testing.T) string {
+			return "glsa_token"
+		}},
+	}
+	for _, tt := range invalid {
+		t.Run("should reject token "+tt.desc, func(t *testing.T) {
+			s, _ := setupTestService(t, issuer)
+			createCredential(t, s, issuer, map[string]string{"repository_owner": "grafana"})
+
+			_, err := s.ExchangeToken(context.Background(), tt.token(t))
+			assert.ErrorIs(t, err, workloadidentity.ErrInvalidToken)
+		})
+	}
+
+	t.Run("should reject token of a disabled service account", func(t *testing.T) {
+		s, saService := setupTestService(t, issuer)
+		createCredential(t, s, issuer, nil)
+		saService.ExpectedServiceAccountProfile = &serviceaccounts.ServiceAccountProfileDTO{Id: 10, OrgId: 1, IsDisabled: true}
+
+		_, err := s.ExchangeToken(context.Background(), issuer.token(t, testSubject, nil))
+		assert.ErrorIs(t, err, workloadidentity.ErrInvalidToken)
+	})
+
+	t.Run("should not match credential whose keys do not verify the token", func(t *testing.T) {
+		s, _ := setupTestService(t, issuer)
+		_, err := s.CreateCredential(context.Background(), &workloadidentity.CreateCredentialCommand{
+			OrgID:            1,
+			ServiceAccountID: 10,
+			Name:             "other-keys",
+			Issuer:           issuer.server.URL,
+			JWKSURL:          issuer.server.URL + "/missing",
+			Audience:         testAudience,
+			Subject:          "*",
+		})
+		require.NoError(t, err)
+
+		_, err = s.ExchangeToken(context.Background(), issuer.token(t, testSubject, nil))
+		assert.ErrorIs(t, err, workloadidentity.ErrInvalidToken)
+	})
+}
+
+func TestIntegrationClient(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	issuer := newTestIssuer(t)
+
+	t.Run("should authenticate external token directly", func(t *testing.T) {
+		s, _ := setupTestService(t, issuer)
+		createCredential(t, s, issuer, nil)
+
+		client := &Client{service: s}
+		r := bearerRequest(issuer.token(t, testSubject, nil), 1)
+		require.True(t, client.Test(context.Background(), r))
+		id, err := client.Authenticate(context.Background(), r)
+		require.NoError(t, err)
+		assert.Equal(t, identity.NewTypedID(identity.TypeServiceAccount, 10), id.ID)
+		assert.True(t, id.ClientParams.SyncPermissions)
+	})
+
+	t.Run("should reject service account of another org", func(t *testing.T) {
+		s, _ := setupTestService(t, issuer)
+		createCredential(t, s, issuer, nil)
+
+		client := &Client{service: s}
+		_, err := client.Authenticate(context.Background(), bearerRequest(issuer.token(t, testSubject, nil), 2))
+		assert.ErrorIs(t, err, workloadidentity.ErrInvalidToken)
+	})
+
+	t.Run("should only match credentials of the requested org", func(t *testing.T) {
+		s, _ := setupTestService(t, issuer)
+		createCredential(t, s, issuer, nil)
+		_, err := s.CreateCredential(context.Background(), &workloadidentity.CreateCredentialCommand{
+			OrgID:            2,
+			ServiceAccountID: 20,
+			Name:             "deploy",
+			Issuer:           issuer.server.URL,
+			Audience:         testAudience,
+			Subject:          "repo:grafana/deploy:*",
+		})
+		require.NoError(t, err)
+
+		client := &Client{service: s}
+		r := bearerRequest(issuer.token(t, testSubject, nil), 2)
+		id, err := client.Authenticate(context.Background(), r)
+		require.NoError(t, err)
+		assert.Equal(t, identity.NewTypedID(identity.TypeServiceAccount, 20), id.ID)
+		assert.Equal(t, int64(2), id.OrgID)
+
+		// without an org, the oldest credential matches
+		id, err = client.Authenticate(context.Background(), bearerRequest(issuer.token(t, testSubject, nil), 0))
+		require.NoError(t, err)
+		assert.Equal(t, identity.NewTypedID(identity.TypeServiceAccount, 10), id.ID)
+	})
+
+	t.Run("should reject Grafana token that is not an exchanged token", func(t *testing.T) {
+		s, _ := setupTestService(t, issuer)
+		_, key, err := s.signingKeys.GetOrCreatePrivateKey(context.Background(), keyPrefix, jose.ES256)
+		require.NoError(t, err)
+
+		// an ID token has the jwt type and is signed with another key prefix
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key},
+			(&jose.SignerOptions{}).WithType("jwt").WithHeader("kid", "id-token-20240101000000-es256"))
+		require.NoError(t, err)
+		token, err := jwt.Signed(signer).Claims(jwt.Claims{
+			Issuer:   testAppURL,
+			Subject:  identity.NewTypedID(identity.TypeServiceAccount, 10).String(),
+			Audience: jwt.Audience{tokenAudience},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}).CompactSerialize()
+		require.NoError(t, err)
+
+		_, err = (&Client{service: s}).Authenticate(context.Background(), bearerRequest(token, 0))
+		assert.ErrorIs(t, err, workloadidentity.ErrInvalidToken)
+	})
+
+	t.Run("should only test tokens of known issuers", func(t *testing.T) {
+		s, _ := setupTestService(t, issuer)
+		client := &Client{service: s}
+		r := bearerRequest(issuer.token(t, testSubject, nil), 1)
+		assert.False(t, client.Test(context.Background(), r))
+
+		credential := createCredential(t, s, issuer, nil)
+		assert.True(t, client.Test(context.Background(), r))
+
+		require.NoError(t, s.DeleteCredential(context.Background(), credential.OrgID, credential.ServiceAccountID, credential.ID))
+		assert.False(t, client.Test(context.Background(), r))
+
+		s.cfg.WorkloadIdentity.AllowedIssuers = []string{issuer.server.URL}
+		assert.True(t, client.Test(context.Background(), r))
+	})
+
+	t.Run("should only test bearer JWTs", func(t *testing.T) {
+		s, _ := setupTestService(t, nil)
+		client := &Client{service: s}
+		assert.False(t, client.Test(context.Background(), bearerRequest("glsa_token", 0)))
+		assert.False(t, client.Test(context.Background(), &authn.Request{HTTPRequest: httptest.NewRequest(http.MethodGet, "/", nil)}))
+	})
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		match   bool
+	}{
+		{pattern: "system:serviceaccount:monitoring:agent", value: "system:serviceaccount:monitoring:agent", match: true},
+		{pattern: "system:serviceaccount:monitoring:agent", value: "system:serviceaccount:monitoring:agent2", match: false},
+		{pattern: "system:serviceaccount:monitoring:*", value: "system:serviceaccount:monitoring:agent", match: true},
+		{pattern: "system:serviceaccount:monitoring:*", value: "system:serviceaccount:default:agent", match: false},
+		{pattern: "repo:grafana/*:ref:refs/heads/main", value: "repo:grafana/deploy:ref:refs/heads/main", match: true},
+		{pattern: "repo:grafana/*:ref:refs/heads/main", value: "repo:grafana/deploy:ref:refs/heads/dev", match: false},
+		{pattern: "*:*:main", value: "repo:x:main", match: true},
+		{pattern: "a*a", value: "a", match: false},
+		{pattern: "*", value: "", match: true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.match, matchPattern(tt.pattern, tt.value), "pattern %q value %q", tt.pattern, tt.value)
+	}
+}
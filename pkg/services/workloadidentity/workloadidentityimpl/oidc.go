@@ -0,0 +1,129 @@
+package workloadidentityimpl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	authlib "github.com/grafana/authlib/authn"
+
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/services/workloadidentity"
+)
+
+const (
+	discoveryPath     = "/.well-known/openid-configuration"
+	discoveryCacheTTL = time.Hour
+	httpTimeout       = 10 * time.Second
+)
+
+// issuerKeys retrieves the signing keys of external issuers. The keys are discovered
+// from the OpenID configuration of the issuers unless a credential sets a JWKS URL.
+type issuerKeys struct {
+	client   *http.Client
+	jwksURLs *localcache.CacheService
+
+	mu         sync.Mutex
+	retrievers map[string]authlib.KeyRetriever
+}
+
+func newIssuerKeys() *issuerKeys {
+	return &issuerKeys{
+		client:     &http.Client{Timeout: httpTimeout},
+		jwksURLs:   localcache.New(discoveryCacheTTL, discoveryCacheTTL),
+		retrievers: map[string]authlib.KeyRetriever{},
+	}
+}
+
+func (k *issuerKeys) get(ctx context.Context, credential *workloadidentity.FederatedCredential, keyID string) (*jose.JSONWebKey, error) {
+	jwksURL := credential.JWKSURL
+	if jwksURL == "" {
+		var err error
+		if jwksURL, err = k.discoverJWKSURL(ctx, credential.Issuer); err != nil {
+			return nil, err
+		}
+	}
+
+	return k.retriever(jwksURL).Get(ctx, keyID)
+}
+
+// retriever returns the key retriever of a JWKS URL, it caches the keys and refreshes them
+// when a token is signed with an unknown key.
+func (k *issuerKeys) retriever(jwksURL string) authlib.KeyRetriever {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	retriever, ok := k.retrievers[jwksURL]
+	if !ok {
+		retriever = authlib.NewKeyRetriever(authlib.KeyRetrieverConfig{SigningKeysURL: jwksURL},
+			authlib.WithHTTPClientKeyRetrieverOpt(k.client))
+		k.retrievers[jwksURL] = retriever
+	}
+	return retriever
+}
+
+func (k *issuerKeys) discoverJWKSURL(ctx context.Context, issuer string) (string, error) {
+	if cached, ok := k.jwksURLs.Get(issuer); ok {
+		return cached.(string), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(issuer, "/")+discoveryPath, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch OpenID configuration of %s: %w", issuer, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch OpenID configuration of %s: status %d", issuer, resp.StatusCode)
+	}
+
+	var configuration struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&configuration); err != nil {
+		return "", fmt.Errorf("failed to decode OpenID configuration of %s: %w", issuer, err)
+	}
+
+	// the configuration must be published by the issuer it describes
+	if configuration.Issuer != issuer || configuration.JWKSURI == "" {
+		return "", fmt.Errorf("invalid OpenID configuration of %s", issuer)
+	}
+
+	k.jwksURLs.Set(issuer, configuration.JWKSURI, discoveryCacheTTL)
+	return configuration.JWKSURI, nil
+}
+
+// matchPattern matches a value with a pattern where * matches any characters.
+func matchPattern(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+
+	prefix, suffix := parts[0], parts[len(parts)-1]
+	if !strings.HasPrefix(value, prefix) {
+		return false
+	}
+	value = value[len(prefix):]
+
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(value, part)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(part):]
+	}
+
+	return strings.HasSuffix(value, suffix)
+}
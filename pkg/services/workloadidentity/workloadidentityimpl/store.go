@@ -0,0 +1,73 @@
+package workloadidentityimpl
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/workloadidentity"
+)
+
+type store struct {
+	db db.DB
+}
+
+func (s *store) listCredentials(ctx context.Context, orgID, serviceAccountID int64) ([]*workloadidentity.FederatedCredential, error) {
+	credentials := make([]*workloadidentity.FederatedCredential, 0)
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("org_id = ? AND service_account_id = ?", orgID, serviceAccountID).Asc("id").Find(&credentials)
+	})
+	return credentials, err
+}
+
+// listCredentialsByIssuer returns the credentials trusting an issuer, oldest first. The credentials
+// are of all organizations when orgID is 0.
+func (s *store) listCredentialsByIssuer(ctx context.Context, issuer string, orgID int64) ([]*workloadidentity.FederatedCredential, error) {
+	credentials := make([]*workloadidentity.FederatedCredential, 0)
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		q := sess.Where("issuer = ?", issuer)
+		if orgID != 0 {
+			q = q.And("org_id = ?", orgID)
+		}
+		return q.Asc("id").Find(&credentials)
+	})
+	return credentials, err
+}
+
+// listIssuers returns the issuers trusted by the credentials of all organizations.
+func (s *store) listIssuers(ctx context.Context) ([]string, error) {
+	issuers := make([]string, 0)
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Table(workloadidentity.FederatedCredential{}).Distinct("issuer").Find(&issuers)
+	})
+	return issuers, err
+}
+
+func (s *store) insertCredential(ctx context.Context, credential *workloadidentity.FederatedCredential) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		exists, err := sess.Where("org_id = ? AND service_account_id = ? AND name = ?",
+			credential.OrgID, credential.ServiceAccountID, credential.Name).Exist(&workloadidentity.FederatedCredential{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			return workloadidentity.ErrCredentialExists.Errorf("federated credential %s already exists", credential.Name)
+		}
+
+		_, err = sess.Insert(credential)
+		return err
+	})
+}
+
+func (s *store) deleteCredential(ctx context.Context, orgID, serviceAccountID, id int64) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		n, err := sess.Where("org_id = ? AND service_account_id = ? AND id = ?", orgID, serviceAccountID, id).
+			Delete(&workloadidentity.FederatedCredential{})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return workloadidentity.ErrCredentialNotFound.Errorf("federated credential %d not found", id)
+		}
+		return nil
+	})
+}
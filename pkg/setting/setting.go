@@ -255,6 +255,9 @@ type Cfg struct {
 	// Auth proxy settings
 	AuthProxy AuthProxySettings
 
+	// Client certificate settings
+	AuthClientCert AuthClientCertSettings
+
 	// OAuth
 	OAuthAutoLogin                bool
 	OAuthCookieMaxAge             int
@@ -263,6 +266,18 @@ type Cfg struct {
 	JWTAuth    AuthJWTSettings
 	ExtJWTAuth ExtJWTSettings
 
+	// Multi-factor authentication
+	AuthMFA AuthMFASettings
+
+	// SCIM provisioning
+	AuthSCIM AuthSCIMSettings
+
+	// Workload identity federation
+	WorkloadIdentity WorkloadIdentitySettings
+
+	// Signing keys
+	SigningKeys SigningKeysSettings
+
 	// SSO Settings Auth
 	SSOSettingsReloadInterval        time.Duration
 	SSOSettingsConfigurableProviders map[string]bool
@@ -409,6 +424,12 @@ type Cfg struct {
 
 	// ExpressionsEnabled specifies whether expressions are enabled.
 	ExpressionsEnabled bool
+	// SQLExpressionMaxInputRows is the maximum number of rows a SQL expression can read from all its inputs. 0 means unlimited.
+	SQLExpressionMaxInputRows int64
+	// SQLExpressionMaxOutputRows is the maximum number of rows a SQL expression can return. 0 means unlimited.
+	SQLExpressionMaxOutputRows int64
+	// SQLExpressionMaxMemoryMB is the memory limit of the SQL engine used to run a single SQL expression. 0 means unlimited.
+	SQLExpressionMaxMemoryMB int64
 
 	ImageUploadProvider string
 
@@ -422,9 +443,15 @@ type Cfg struct {
 	// LiveHAEngineAddress is a connection address for Live HA engine.
 	LiveHAEngineAddress  string
 	LiveHAEnginePassword string
+	// LivePipelineStorage is a type of storage of Live pipeline channel rules
+	// and write configs. The pipeline is disabled if not set.
+	LivePipelineStorage string
 	// LiveAllowedOrigins is a set of origins accepted by Live. If not provided
 	// then Live uses AppURL as the only allowed origin.
 	LiveAllowedOrigins []string
+	// LivePushMaxBodySize is the maximum size in bytes of the body of a push request
+	// after decompression. 0 means no limit.
+	LivePushMaxBodySize int64
 
 	// Grafana.com URL, used for OAuth redirect.
 	GrafanaComURL string
@@ -733,6 +760,9 @@ func (cfg *Cfg) readAnnotationSettings() error {
 func (cfg *Cfg) readExpressionsSettings() {
 	expressions := cfg.Raw.Section("expressions")
 	cfg.ExpressionsEnabled = expressions.Key("enabled").MustBool(true)
+	cfg.SQLExpressionMaxInputRows = expressions.Key("sql_expression_max_input_rows").MustInt64(100000)
+	cfg.SQLExpressionMaxOutputRows = expressions.Key("sql_expression_max_output_rows").MustInt64(100000)
+	cfg.SQLExpressionMaxMemoryMB = expressions.Key("sql_expression_max_memory_mb").MustInt64(256)
 }
 
 type AnnotationCleanupSettings struct {
@@ -1220,6 +1250,11 @@ func (cfg *Cfg) parseINIFile(iniFile *ini.File) error {
 	cfg.readAuthJWTSettings()
 	cfg.readAuthExtJWTSettings()
 	cfg.readAuthProxySettings()
+	cfg.readAuthClientCertSettings()
+	cfg.readAuthMFASettings()
+	cfg.readAuthSCIMSettings()
+	cfg.readWorkloadIdentitySettings()
+	cfg.readSigningKeysSettings()
 	cfg.readSessionConfig()
 	if err := cfg.readSmtpSettings(); err != nil {
 		return err
@@ -2003,6 +2038,16 @@ func (cfg *Cfg) readLiveSettings(iniFile *ini.File) error {
 	}
 	cfg.LiveHAEngineAddress = section.Key("ha_engine_address").MustString("127.0.0.1:6379")
 	cfg.LiveHAEnginePassword = section.Key("ha_engine_password").MustString("")
+	cfg.LivePipelineStorage = section.Key("pipeline_storage").MustString("")
+	switch cfg.LivePipelineStorage {
+	case "", "file", "database":
+	default:
+		return fmt.Errorf("unsupported live pipeline storage type: %s", cfg.LivePipelineStorage)
+	}
+	cfg.LivePushMaxBodySize = section.Key("push_max_body_size").MustInt64(10 * 1024 * 1024)
+	if cfg.LivePushMaxBodySize < 0 {
+		return fmt.Errorf("unexpected value %d for [live] push_max_body_size", cfg.LivePushMaxBodySize)
+	}
 
 	allowedOrigins := section.Key("allowed_origins").MustString("")
 	origins := strings.Split(allowedOrigins, ",")
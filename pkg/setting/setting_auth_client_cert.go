@@ -0,0 +1,63 @@
+package setting
+
+import "github.com/grafana/grafana/pkg/util"
+
+const (
+	ClientCertAttributeCommonName = "common_name"
+	ClientCertAttributeEmail      = "email"
+	ClientCertAttributeDNSSAN     = "dns_san"
+	ClientCertAttributeURISAN     = "uri_san"
+)
+
+type AuthClientCertSettings struct {
+	Enabled bool
+	// CAFile is the PEM bundle of the certificate authorities client certificates are verified with.
+	CAFile string
+	// HeaderName is the header a TLS terminating proxy forwards the client certificate in.
+	HeaderName string
+	// HeaderAcceptList is the list of proxy IPs or networks allowed to forward a client certificate.
+	HeaderAcceptList string
+	// LoginAttribute and EmailAttribute are the certificate attributes users are looked up by.
+	LoginAttribute string
+	EmailAttribute string
+	// ServiceAccountURIPrefix is the prefix of the URI SANs identifying service accounts,
+	// the rest of the URI is the login of the service account.
+	ServiceAccountURIPrefix string
+	// OrgMapping maps the organizational units of the certificate subject to organizations and roles.
+	OrgMapping      []string
+	SkipOrgRoleSync bool
+	AutoSignUp      bool
+}
+
+func (cfg *Cfg) readAuthClientCertSettings() {
+	clientCertSettings := AuthClientCertSettings{}
+	section := cfg.Raw.Section("auth.client_cert")
+	clientCertSettings.Enabled = section.Key("enabled").MustBool(false)
+	clientCertSettings.CAFile = valueAsString(section, "ca_file", "")
+	clientCertSettings.HeaderName = valueAsString(section, "header_name", "")
+	clientCertSettings.HeaderAcceptList = valueAsString(section, "header_accept_list", "")
+	clientCertSettings.LoginAttribute = valueAsString(section, "login_attribute", ClientCertAttributeCommonName)
+	clientCertSettings.EmailAttribute = valueAsString(section, "email_attribute", ClientCertAttributeEmail)
+	clientCertSettings.ServiceAccountURIPrefix = valueAsString(section, "service_account_uri_prefix", "")
+	clientCertSettings.OrgMapping = util.SplitString(valueAsString(section, "org_mapping", ""))
+	clientCertSettings.SkipOrgRoleSync = section.Key("skip_org_role_sync").MustBool(false)
+	clientCertSettings.AutoSignUp = section.Key("auto_sign_up").MustBool(false)
+
+	clientCertSettings.LoginAttribute = cfg.validClientCertAttribute("login_attribute", clientCertSettings.LoginAttribute, ClientCertAttributeCommonName)
+	clientCertSettings.EmailAttribute = cfg.validClientCertAttribute("email_attribute", clientCertSettings.EmailAttribute, ClientCertAttributeEmail)
+
+	if clientCertSettings.Enabled && clientCertSettings.CAFile == "" {
+		cfg.Logger.Warn("auth.client_cert is enabled without ca_file, client certificates cannot be verified")
+	}
+
+	cfg.AuthClientCert = clientCertSettings
+}
+
+func (cfg *Cfg) validClientCertAttribute(key, attribute, fallback string) string {
+	switch attribute {
+	case ClientCertAttributeCommonName, ClientCertAttributeEmail, ClientCertAttributeDNSSAN, ClientCertAttributeURISAN:
+		return attribute
+	}
+	cfg.Logger.Warn("Invalid auth.client_cert attribute, falling back to the default", "key", key, "attribute", attribute, "default", fallback)
+	return fallback
+}
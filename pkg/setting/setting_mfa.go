@@ -0,0 +1,39 @@
+package setting
+
+import (
+	"net/url"
+	"time"
+)
+
+type AuthMFASettings struct {
+	Enabled bool
+	// Issuer is the name of the TOTP issuer shown in authenticator apps.
+	Issuer string
+	// ChallengeTTL is how long a user has to complete the second factor after the password.
+	ChallengeTTL time.Duration
+	// WebAuthnRPID is the relying party ID of WebAuthn credentials.
+	WebAuthnRPID string
+	// WebAuthnOrigin is the origin expected in WebAuthn client data.
+	WebAuthnOrigin string
+}
+
+func (cfg *Cfg) readAuthMFASettings() {
+	mfaSettings := AuthMFASettings{}
+	section := cfg.Raw.Section("auth.mfa")
+	mfaSettings.Enabled = section.Key("enabled").MustBool(false)
+	mfaSettings.Issuer = valueAsString(section, "issuer", "Grafana")
+	mfaSettings.ChallengeTTL = section.Key("challenge_ttl").MustDuration(5 * time.Minute)
+	mfaSettings.WebAuthnRPID = valueAsString(section, "webauthn_rp_id", "")
+	mfaSettings.WebAuthnOrigin = valueAsString(section, "webauthn_origin", "")
+
+	if mfaSettings.WebAuthnRPID == "" {
+		mfaSettings.WebAuthnRPID = cfg.Domain
+	}
+	if mfaSettings.WebAuthnOrigin == "" {
+		if appURL, err := url.Parse(cfg.AppURL); err == nil {
+			mfaSettings.WebAuthnOrigin = appURL.Scheme + "://" + appURL.Host
+		}
+	}
+
+	cfg.AuthMFA = mfaSettings
+}
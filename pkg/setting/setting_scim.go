@@ -0,0 +1,32 @@
+package setting
+
+import "github.com/grafana/grafana/pkg/apimachinery/identity"
+
+type AuthSCIMSettings struct {
+	Enabled bool
+	// DefaultOrgRole is the role of provisioned users that don't have a role attribute.
+	DefaultOrgRole string
+	// MaxResults is the maximum number of resources returned by a list request.
+	MaxResults int
+	// BulkMaxOperations is the maximum number of operations of a bulk request.
+	BulkMaxOperations int
+	// BulkMaxPayloadSize is the maximum size in bytes of a bulk request.
+	BulkMaxPayloadSize int64
+}
+
+func (cfg *Cfg) readAuthSCIMSettings() {
+	scimSettings := AuthSCIMSettings{}
+	section := cfg.Raw.Section("auth.scim")
+	scimSettings.Enabled = section.Key("enabled").MustBool(false)
+	scimSettings.DefaultOrgRole = valueAsString(section, "default_org_role", string(identity.RoleViewer))
+	scimSettings.MaxResults = section.Key("max_results").MustInt(100)
+	scimSettings.BulkMaxOperations = section.Key("bulk_max_operations").MustInt(100)
+	scimSettings.BulkMaxPayloadSize = section.Key("bulk_max_payload_size").MustInt64(1048576)
+
+	if !identity.RoleType(scimSettings.DefaultOrgRole).IsValid() {
+		cfg.Logger.Warn("Invalid auth.scim default_org_role, falling back to Viewer", "role", scimSettings.DefaultOrgRole)
+		scimSettings.DefaultOrgRole = string(identity.RoleViewer)
+	}
+
+	cfg.AuthSCIM = scimSettings
+}
@@ -0,0 +1,35 @@
+package setting
+
+import "time"
+
+const minSigningKeysDuration = time.Hour
+
+type SigningKeysSettings struct {
+	// RotationInterval is how long a signing key is used before a new key becomes active.
+	RotationInterval time.Duration
+	// GracePeriod is how long the public key of a rotated key stays in the JWKS.
+	GracePeriod time.Duration
+	// KMSProvider is the ID of the KMS provider encrypting the private keys,
+	// the secrets service is used when empty.
+	KMSProvider string
+}
+
+func (cfg *Cfg) readSigningKeysSettings() {
+	signingKeysSettings := SigningKeysSettings{}
+	section := cfg.Raw.Section("signing_keys")
+	signingKeysSettings.RotationInterval = section.Key("rotation_interval").MustDuration(30 * 24 * time.Hour)
+	signingKeysSettings.GracePeriod = section.Key("grace_period").MustDuration(24 * time.Hour)
+	signingKeysSettings.KMSProvider = valueAsString(section, "kms_provider", "")
+
+	// Tokens signed before a rotation must stay verifiable while verifiers cache the JWKS.
+	if signingKeysSettings.RotationInterval < minSigningKeysDuration {
+		cfg.Logger.Warn("signing_keys rotation_interval is too short, using the minimum", "rotation_interval", signingKeysSettings.RotationInterval, "minimum", minSigningKeysDuration)
+		signingKeysSettings.RotationInterval = minSigningKeysDuration
+	}
+	if signingKeysSettings.GracePeriod < minSigningKeysDuration {
+		cfg.Logger.Warn("signing_keys grace_period is too short, using the minimum", "grace_period", signingKeysSettings.GracePeriod, "minimum", minSigningKeysDuration)
+		signingKeysSettings.GracePeriod = minSigningKeysDuration
+	}
+
+	cfg.SigningKeys = signingKeysSettings
+}
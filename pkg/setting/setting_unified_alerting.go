@@ -46,6 +46,9 @@ const (
 }
 `
 	evaluatorDefaultEvaluationTimeout       = 30 * time.Second
+	templateDefaultQueryTimeout             = 10 * time.Second
+	templateDefaultMaxQueries               = 20
+	importDefaultMaxSizeBytes               = 10 * 1024 * 1024
 	schedulerDefaultAdminConfigPollInterval = time.Minute
 	schedulerDefaultExecuteAlerts           = true
 	schedulerDefaultMaxAttempts             = 1
@@ -65,8 +68,27 @@ const (
 	lokiDefaultMaxQueryLength      = 721 * time.Hour // 30d1h, matches the default value in Loki
 	defaultRecordingRequestTimeout = 10 * time.Second
 	lokiDefaultMaxQuerySize        = 65536 // 64kb
+	sqlHistoryDefaultRetention     = 30 * 24 * time.Hour
+	sqlHistoryDefaultCompaction    = 10 * time.Minute
+	sqlHistoryDefaultMaxQuerySize  = 5000
+	prometheusHistoryDefaultMetric = "GRAFANA_ALERTS"
+
+	recordingRulesDefaultTarget        = "prometheus"
+	recordingRulesSQLDefaultRetention  = 15 * 24 * time.Hour
+	recordingRulesSQLDefaultCompaction = 10 * time.Minute
 )
 
+const (
+	// StateStorageDatabase persists the state of alert instances in the alert_instance table of the database.
+	StateStorageDatabase = "database"
+	// StateStorageRedis persists the state of alert instances in the remote cache, which must be Redis.
+	StateStorageRedis = "redis"
+)
+
+// recordingRulesTargets are the names of the writers that recording rules can write to.
+// Keep in sync with models.RecordTargets.
+var recordingRulesTargets = []string{"prometheus", "influxdb", "otlp", "sql"}
+
 type UnifiedAlertingSettings struct {
 	AdminConfigPollInterval         time.Duration
 	AlertmanagerConfigPollInterval  time.Duration
@@ -90,10 +112,13 @@ type UnifiedAlertingSettings struct {
 	HARedisMaxConns                 int
 	HARedisTLSEnabled               bool
 	HARedisTLSConfig                dstls.ClientConfig
+	HAEvaluationSharding            bool
 	MaxAttempts                     int64
 	MinInterval                     time.Duration
 	EvaluationTimeout               time.Duration
 	EvaluationResultLimit           int
+	TemplateQueryTimeout            time.Duration
+	TemplateMaxQueries              int
 	DisableJitter                   bool
 	ExecuteAlerts                   bool
 	DefaultConfiguration            string
@@ -115,12 +140,17 @@ type UnifiedAlertingSettings struct {
 	MaxStateSaveConcurrency   int
 	StatePeriodicSaveInterval time.Duration
 	RulesPerRuleGroupLimit    int64
+	// StateStorage is where the state of alert instances is persisted, either StateStorageDatabase or StateStorageRedis.
+	StateStorage string
 
 	// Retention period for Alertmanager notification log entries.
 	NotificationLogRetention time.Duration
 
 	// Duration for which a resolved alert state transition will continue to be sent to the Alertmanager.
 	ResolvedAlertRetention time.Duration
+
+	// ImportMaxSizeBytes is the maximum size of a document imported with the provisioning import API. Zero means no limit.
+	ImportMaxSizeBytes int64
 }
 
 type RecordingRuleSettings struct {
@@ -129,6 +159,42 @@ type RecordingRuleSettings struct {
 	BasicAuthPassword string
 	CustomHeaders     map[string]string
 	Timeout           time.Duration
+	// DefaultTarget is the writer used by recording rules that do not specify a target.
+	DefaultTarget string
+	// DatasourceUID is the UID of the data source that queries the metrics written to URL.
+	DatasourceUID string
+	InfluxDB      RecordingRuleInfluxDBSettings
+	OTLP          RecordingRuleOTLPSettings
+	SQL           RecordingRuleSQLSettings
+}
+
+// RecordingRuleInfluxDBSettings configures writing of recording rule results to an InfluxDB line protocol endpoint.
+type RecordingRuleInfluxDBSettings struct {
+	// URL is the full write URL, including the database or bucket parameters, e.g. http://localhost:8086/api/v2/write?org=grafana&bucket=metrics.
+	URL               string
+	Token             string
+	BasicAuthUsername string
+	BasicAuthPassword string
+	Timeout           time.Duration
+	// DatasourceUID is the UID of the data source that queries the written measurements.
+	DatasourceUID string
+}
+
+// RecordingRuleOTLPSettings configures writing of recording rule results to an OTLP/HTTP metrics endpoint.
+type RecordingRuleOTLPSettings struct {
+	// URL is the full metrics URL, e.g. http://localhost:4318/v1/metrics.
+	URL           string
+	CustomHeaders map[string]string
+	Timeout       time.Duration
+	// DatasourceUID is the UID of the data source that queries the written metrics.
+	DatasourceUID string
+}
+
+// RecordingRuleSQLSettings configures writing of recording rule results to the Grafana database.
+type RecordingRuleSQLSettings struct {
+	Enabled            bool
+	Retention          time.Duration
+	CompactionInterval time.Duration
 }
 
 // RemoteAlertmanagerSettings contains the configuration needed
@@ -168,6 +234,23 @@ type UnifiedAlertingStateHistorySettings struct {
 	MultiPrimary          string
 	MultiSecondaries      []string
 	ExternalLabels        map[string]string
+	// SQLRetention is how long the "sql" backend keeps state history entries. Zero keeps them forever.
+	SQLRetention time.Duration
+	// SQLOrgRetention overrides SQLRetention for specific organizations.
+	SQLOrgRetention map[int64]time.Duration
+	// SQLMaxEntriesPerOrg limits the number of entries the "sql" backend keeps per organization. Zero means no limit.
+	SQLMaxEntriesPerOrg   int
+	SQLCompactionInterval time.Duration
+	SQLMaxQuerySize       int
+	// PrometheusWriteURL and the rest of Prometheus* settings configure the "prometheus" backend
+	// that writes alert states as time series using the remote-write protocol.
+	// PrometheusQueryURL is the base URL of the Prometheus HTTP API used to read the history back.
+	PrometheusWriteURL          string
+	PrometheusQueryURL          string
+	PrometheusBasicAuthUsername string
+	PrometheusBasicAuthPassword string
+	PrometheusTimeout           time.Duration
+	PrometheusMetricName        string
 }
 
 // IsEnabled returns true if UnifiedAlertingSettings.Enabled is either nil or true.
@@ -276,6 +359,7 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 	uaCfg.HARedisTLSConfig.InsecureSkipVerify = ua.Key("ha_redis_tls_insecure_skip_verify").MustBool(false)
 	uaCfg.HARedisTLSConfig.CipherSuites = ua.Key("ha_redis_tls_cipher_suites").MustString("")
 	uaCfg.HARedisTLSConfig.MinVersion = ua.Key("ha_redis_tls_min_version").MustString("")
+	uaCfg.HAEvaluationSharding = ua.Key("ha_evaluation_sharding").MustBool(false)
 
 	// TODO load from ini file
 	uaCfg.DefaultConfiguration = alertmanagerDefaultConfiguration
@@ -305,6 +389,15 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 
 	uaCfg.MaxAttempts = ua.Key("max_attempts").MustInt64(schedulerDefaultMaxAttempts)
 
+	uaCfg.TemplateQueryTimeout, err = gtime.ParseDuration(valueAsString(ua, "template_query_timeout", templateDefaultQueryTimeout.String()))
+	if err != nil {
+		return fmt.Errorf("failed to parse setting 'template_query_timeout' as duration: %w", err)
+	}
+	uaCfg.TemplateMaxQueries = ua.Key("template_max_queries").MustInt(templateDefaultMaxQueries)
+	if uaCfg.TemplateMaxQueries < 0 {
+		return fmt.Errorf("setting 'template_max_queries' is invalid, only 0 or a positive integer are allowed")
+	}
+
 	uaCfg.BaseInterval = SchedulerBaseInterval
 
 	// TODO: This was promoted from a feature toggle and is now the default behavior.
@@ -417,6 +510,20 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 		MultiSecondaries:      splitTrim(stateHistory.Key("secondaries").MustString(""), ","),
 		ExternalLabels:        stateHistoryLabels.KeysHash(),
 	}
+	uaCfgStateHistory.SQLRetention = stateHistory.Key("sql_retention").MustDuration(sqlHistoryDefaultRetention)
+	uaCfgStateHistory.SQLMaxEntriesPerOrg = stateHistory.Key("sql_max_entries_per_org").MustInt(0)
+	uaCfgStateHistory.SQLCompactionInterval = stateHistory.Key("sql_compaction_interval").MustDuration(sqlHistoryDefaultCompaction)
+	uaCfgStateHistory.SQLMaxQuerySize = stateHistory.Key("sql_max_query_size").MustInt(sqlHistoryDefaultMaxQuerySize)
+	uaCfgStateHistory.PrometheusWriteURL = stateHistory.Key("prometheus_remote_write_url").MustString("")
+	uaCfgStateHistory.PrometheusQueryURL = stateHistory.Key("prometheus_query_url").MustString("")
+	uaCfgStateHistory.PrometheusBasicAuthUsername = stateHistory.Key("prometheus_basic_auth_username").MustString("")
+	uaCfgStateHistory.PrometheusBasicAuthPassword = stateHistory.Key("prometheus_basic_auth_password").MustString("")
+	uaCfgStateHistory.PrometheusTimeout = stateHistory.Key("prometheus_timeout").MustDuration(defaultRecordingRequestTimeout)
+	uaCfgStateHistory.PrometheusMetricName = stateHistory.Key("prometheus_metric_name").MustString(prometheusHistoryDefaultMetric)
+	uaCfgStateHistory.SQLOrgRetention, err = readOrgDurations(iniFile.Section("unified_alerting.state_history.sql_org_retention"))
+	if err != nil {
+		return fmt.Errorf("invalid state history retention: %w", err)
+	}
 	uaCfg.StateHistory = uaCfgStateHistory
 
 	rr := iniFile.Section("recording_rules")
@@ -425,6 +532,7 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 		BasicAuthUsername: rr.Key("basic_auth_username").MustString(""),
 		BasicAuthPassword: rr.Key("basic_auth_password").MustString(""),
 		Timeout:           rr.Key("timeout").MustDuration(defaultRecordingRequestTimeout),
+		DatasourceUID:     rr.Key("datasource_uid").MustString(""),
 	}
 
 	rrHeaders := iniFile.Section("recording_rules.custom_headers")
@@ -433,6 +541,36 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 	for _, key := range rrHeadersKeys {
 		uaCfgRecordingRules.CustomHeaders[key.Name()] = key.Value()
 	}
+	uaCfgRecordingRules.DefaultTarget = rr.Key("default_target").In(recordingRulesDefaultTarget, recordingRulesTargets)
+
+	rrInflux := iniFile.Section("recording_rules.influxdb")
+	uaCfgRecordingRules.InfluxDB = RecordingRuleInfluxDBSettings{
+		URL:               rrInflux.Key("url").MustString(""),
+		Token:             rrInflux.Key("token").MustString(""),
+		BasicAuthUsername: rrInflux.Key("basic_auth_username").MustString(""),
+		BasicAuthPassword: rrInflux.Key("basic_auth_password").MustString(""),
+		Timeout:           rrInflux.Key("timeout").MustDuration(defaultRecordingRequestTimeout),
+		DatasourceUID:     rrInflux.Key("datasource_uid").MustString(""),
+	}
+
+	rrOTLP := iniFile.Section("recording_rules.otlp")
+	uaCfgRecordingRules.OTLP = RecordingRuleOTLPSettings{
+		URL:           rrOTLP.Key("url").MustString(""),
+		Timeout:       rrOTLP.Key("timeout").MustDuration(defaultRecordingRequestTimeout),
+		DatasourceUID: rrOTLP.Key("datasource_uid").MustString(""),
+	}
+	rrOTLPHeadersKeys := iniFile.Section("recording_rules.otlp.custom_headers").Keys()
+	uaCfgRecordingRules.OTLP.CustomHeaders = make(map[string]string, len(rrOTLPHeadersKeys))
+	for _, key := range rrOTLPHeadersKeys {
+		uaCfgRecordingRules.OTLP.CustomHeaders[key.Name()] = key.Value()
+	}
+
+	rrSQL := iniFile.Section("recording_rules.sql")
+	uaCfgRecordingRules.SQL = RecordingRuleSQLSettings{
+		Enabled:            rrSQL.Key("enabled").MustBool(false),
+		Retention:          rrSQL.Key("retention").MustDuration(recordingRulesSQLDefaultRetention),
+		CompactionInterval: rrSQL.Key("compaction_interval").MustDuration(recordingRulesSQLDefaultCompaction),
+	}
 
 	uaCfg.RecordingRules = uaCfgRecordingRules
 
@@ -443,6 +581,11 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 		return err
 	}
 
+	uaCfg.StateStorage = valueAsString(ua, "state_storage", StateStorageDatabase)
+	if uaCfg.StateStorage != StateStorageDatabase && uaCfg.StateStorage != StateStorageRedis {
+		return fmt.Errorf("setting 'state_storage' is invalid, only %q and %q are allowed", StateStorageDatabase, StateStorageRedis)
+	}
+
 	uaCfg.NotificationLogRetention, err = gtime.ParseDuration(valueAsString(ua, "notification_log_retention", (5 * 24 * time.Hour).String()))
 	if err != nil {
 		return err
@@ -453,6 +596,11 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 		return err
 	}
 
+	uaCfg.ImportMaxSizeBytes = ua.Key("import_max_size_bytes").MustInt64(importDefaultMaxSizeBytes)
+	if uaCfg.ImportMaxSizeBytes < 0 {
+		return fmt.Errorf("setting 'import_max_size_bytes' is invalid, only 0 or a positive integer are allowed")
+	}
+
 	cfg.UnifiedAlerting = uaCfg
 	return nil
 }
@@ -461,6 +609,23 @@ func GetAlertmanagerDefaultConfiguration() string {
 	return alertmanagerDefaultConfiguration
 }
 
+// readOrgDurations parses a section in which every key is an organization ID and the value is a duration.
+func readOrgDurations(section *ini.Section) (map[int64]time.Duration, error) {
+	result := make(map[int64]time.Duration, len(section.Keys()))
+	for _, key := range section.Keys() {
+		orgID, err := strconv.ParseInt(key.Name(), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not a valid organization ID", key.Name())
+		}
+		d, err := gtime.ParseDuration(key.Value())
+		if err != nil {
+			return nil, fmt.Errorf("value of key %q is not a valid duration: %w", key.Name(), err)
+		}
+		result[orgID] = d
+	}
+	return result, nil
+}
+
 func splitTrim(s string, sep string) []string {
 	spl := strings.Split(s, sep)
 	for i := range spl {
@@ -346,3 +346,56 @@ func TestHARedisTLSSettings(t *testing.T) {
 	require.Equal(t, cipherSuites, cfg.UnifiedAlerting.HARedisTLSConfig.CipherSuites)
 	require.Equal(t, minVersion, cfg.UnifiedAlerting.HARedisTLSConfig.MinVersion)
 }
+
+func TestRecordingRulesTargetSettings(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.ReadUnifiedAlertingSettings(ini.Empty()))
+
+		require.Equal(t, "prometheus", cfg.UnifiedAlerting.RecordingRules.DefaultTarget)
+		require.False(t, cfg.UnifiedAlerting.RecordingRules.SQL.Enabled)
+		require.Equal(t, recordingRulesSQLDefaultRetention, cfg.UnifiedAlerting.RecordingRules.SQL.Retention)
+		require.Equal(t, defaultRecordingRequestTimeout, cfg.UnifiedAlerting.RecordingRules.InfluxDB.Timeout)
+		require.Equal(t, defaultRecordingRequestTimeout, cfg.UnifiedAlerting.RecordingRules.OTLP.Timeout)
+	})
+
+	t.Run("targets", func(t *testing.T) {
+		f := ini.Empty()
+		rr, err := f.NewSection("recording_rules")
+		require.NoError(t, err)
+		_, err = rr.NewKey("default_target", "sql")
+		require.NoError(t, err)
+		influx, err := f.NewSection("recording_rules.influxdb")
+		require.NoError(t, err)
+		_, err = influx.NewKey("url", "http://localhost:8086/api/v2/write?org=grafana&bucket=metrics")
+		require.NoError(t, err)
+		_, err = influx.NewKey("token", "secret")
+		require.NoError(t, err)
+		otlp, err := f.NewSection("recording_rules.otlp")
+		require.NoError(t, err)
+		_, err = otlp.NewKey("url", "http://localhost:4318/v1/metrics")
+		require.NoError(t, err)
+		otlpHeaders, err := f.NewSection("recording_rules.otlp.custom_headers")
+		require.NoError(t, err)
+		_, err = otlpHeaders.NewKey("X-Scope-OrgID", "1")
+		require.NoError(t, err)
+		sql, err := f.NewSection("recording_rules.sql")
+		require.NoError(t, err)
+		_, err = sql.NewKey("enabled", "true")
+		require.NoError(t, err)
+		_, err = sql.NewKey("retention", "24h")
+		require.NoError(t, err)
+
+		cfg := NewCfg()
+		require.NoError(t, cfg.ReadUnifiedAlertingSettings(f))
+
+		rrCfg := cfg.UnifiedAlerting.RecordingRules
+		require.Equal(t, "sql", rrCfg.DefaultTarget)
+		require.Equal(t, "http://localhost:8086/api/v2/write?org=grafana&bucket=metrics", rrCfg.InfluxDB.URL)
+		require.Equal(t, "secret", rrCfg.InfluxDB.Token)
+		require.Equal(t, "http://localhost:4318/v1/metrics", rrCfg.OTLP.URL)
+		require.Equal(t, map[string]string{"X-Scope-OrgID": "1"}, rrCfg.OTLP.CustomHeaders)
+		require.True(t, rrCfg.SQL.Enabled)
+		require.Equal(t, 24*time.Hour, rrCfg.SQL.Retention)
+	})
+}
@@ -0,0 +1,30 @@
+package setting
+
+import (
+	"time"
+
+	"github.com/grafana/grafana/pkg/util"
+)
+
+type WorkloadIdentitySettings struct {
+	Enabled bool
+	// TokenLifetime is the lifetime of the tokens issued in exchange for external tokens.
+	TokenLifetime time.Duration
+	// AllowedIssuers restricts the issuers of federated credentials, any issuer is allowed when empty.
+	AllowedIssuers []string
+}
+
+func (cfg *Cfg) readWorkloadIdentitySettings() {
+	workloadIdentitySettings := WorkloadIdentitySettings{}
+	section := cfg.Raw.Section("auth.workload_identity")
+	workloadIdentitySettings.Enabled = section.Key("enabled").MustBool(false)
+	workloadIdentitySettings.TokenLifetime = section.Key("token_lifetime").MustDuration(15 * time.Minute)
+	workloadIdentitySettings.AllowedIssuers = util.SplitString(valueAsString(section, "allowed_issuers", ""))
+
+	if workloadIdentitySettings.TokenLifetime < time.Minute || workloadIdentitySettings.TokenLifetime > 24*time.Hour {
+		cfg.Logger.Warn("Invalid auth.workload_identity token_lifetime, falling back to 15m", "tokenLifetime", workloadIdentitySettings.TokenLifetime)
+		workloadIdentitySettings.TokenLifetime = 15 * time.Minute
+	}
+
+	cfg.WorkloadIdentity = workloadIdentitySettings
+}
@@ -12,9 +12,11 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/recordedsamples"
 	"github.com/grafana/grafana/pkg/services/searchV2"
 	"github.com/grafana/grafana/pkg/services/store"
 	testdatasource "github.com/grafana/grafana/pkg/tsdb/grafana-testdata-datasource"
@@ -51,15 +53,16 @@ var (
 	)
 )
 
-func ProvideService(search searchV2.SearchService, store store.StorageService) *Service {
-	return newService(search, store)
+func ProvideService(search searchV2.SearchService, store store.StorageService, recordedSamples recordedsamples.Reader) *Service {
+	return newService(search, store, recordedSamples)
 }
 
-func newService(search searchV2.SearchService, store store.StorageService) *Service {
+func newService(search searchV2.SearchService, store store.StorageService, recordedSamples recordedsamples.Reader) *Service {
 	s := &Service{
-		search: search,
-		store:  store,
-		log:    log.New("grafanads"),
+		search:          search,
+		store:           store,
+		recordedSamples: recordedSamples,
+		log:             log.New("grafanads"),
 	}
 
 	return s
@@ -67,9 +70,10 @@ func newService(search searchV2.SearchService, store store.StorageService) *Serv
 
 // Service exists regardless of user settings
 type Service struct {
-	search searchV2.SearchService
-	store  store.StorageService
-	log    log.Logger
+	search          searchV2.SearchService
+	store           store.StorageService
+	recordedSamples recordedsamples.Reader
+	log             log.Logger
 }
 
 func DataSourceModel(orgId int64) *datasources.DataSource {
@@ -97,6 +101,8 @@ func (s *Service) QueryData(ctx context.Context, req *backend.QueryDataRequest)
 			response.Responses[q.RefID] = s.doReadQuery(ctx, q)
 		case queryTypeSearch:
 			response.Responses[q.RefID] = s.doSearchQuery(ctx, req, q)
+		case queryTypeRecordedMetric:
+			response.Responses[q.RefID] = s.doRecordedMetricQuery(ctx, req, q)
 		default:
 			response.Responses[q.RefID] = backend.DataResponse{
 				Error: fmt.Errorf("unknown query type"),
@@ -202,6 +208,41 @@ func (s *Service) doSearchQuery(ctx context.Context, req *backend.QueryDataReque
 	return *s.search.DoDashboardQuery(ctx, req.PluginContext.User, req.PluginContext.OrgID, m.Search)
 }
 
+func (s *Service) doRecordedMetricQuery(ctx context.Context, req *backend.QueryDataRequest, query backend.DataQuery) backend.DataResponse {
+	if s.recordedSamples == nil {
+		return backend.DataResponse{
+			Error: fmt.Errorf("recorded metrics are not available"),
+		}
+	}
+
+	m := recordedMetricQueryModel{}
+	err := json.Unmarshal(query.JSON, &m)
+	if err != nil {
+		return backend.DataResponse{
+			Error: err,
+		}
+	}
+
+	user, err := identity.GetRequester(ctx)
+	if err != nil {
+		return backend.DataResponse{
+			Error: fmt.Errorf("failed to get the user of the query: %w", err),
+		}
+	}
+
+	frames, err := s.recordedSamples.QuerySamples(ctx, user, recordedsamples.Query{
+		OrgID:    req.PluginContext.OrgID,
+		Metric:   m.Metric,
+		Matchers: m.Matchers,
+		From:     query.TimeRange.From,
+		To:       query.TimeRange.To,
+	})
+	return backend.DataResponse{
+		Frames: frames,
+		Error:  err,
+	}
+}
+
 type requestModel struct {
 	QueryType string                  `json:"queryType"`
 	Search    searchV2.DashboardQuery `json:"search,omitempty"`
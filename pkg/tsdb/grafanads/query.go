@@ -14,6 +14,9 @@ const (
 	// currently only .csv files are supported,
 	// other file types will eventually be supported (parquet, etc)
 	queryTypeRead = "read"
+
+	// QueryTypeRecordedMetric reads a metric written by Grafana-managed recording rules to the Grafana database
+	queryTypeRecordedMetric = "recordedMetric"
 )
 
 type listQueryModel struct {
@@ -22,3 +25,9 @@ type listQueryModel struct {
 type readQueryModel struct {
 	Path string `json:"path"`
 }
+
+type recordedMetricQueryModel struct {
+	Metric string `json:"metric"`
+	// Matchers are label values the returned series must have
+	Matchers map[string]string `json:"matchers,omitempty"`
+}